@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/benaskins/aurelia/internal/audit"
+	"github.com/spf13/cobra"
+)
+
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Show the audit log of secret operations and lifecycle actions",
+	Long: `Show recent entries from the daemon's audit log: secret reads, writes,
+deletes and rotations, and service starts, stops, restarts, deploys and
+reloads, with the actor that requested them. Entries in rotated log files are
+included.
+
+--since takes a duration back from now, such as 24h, or an RFC 3339 time.
+With --json each entry is printed as a line of JSON, as in audit.log.
+
+Over TCP this needs a write-scoped token.
+
+Examples:
+  aurelia audit
+  aurelia audit --action service_restart --service api
+  aurelia audit --key chat/api-key --since 24h`,
+	Args: cobra.NoArgs,
+	RunE: runAudit,
+}
+
+func init() {
+	auditCmd.Flags().IntP("lines", "n", 50, "number of recent entries to show")
+	auditCmd.Flags().String("action", "", "only show entries with this action, e.g. secret_read or service_deploy")
+	auditCmd.Flags().String("service", "", "only show entries for this service")
+	auditCmd.Flags().String("key", "", "only show entries for this secret key")
+	auditCmd.Flags().String("since", "", "only show entries since a duration ago (24h) or an RFC 3339 time")
+	rootCmd.AddCommand(auditCmd)
+}
+
+func runAudit(cmd *cobra.Command, _ []string) error {
+	jsonOut, _ := cmd.Flags().GetBool("json")
+	n, _ := cmd.Flags().GetInt("lines")
+	action, _ := cmd.Flags().GetString("action")
+	service, _ := cmd.Flags().GetString("service")
+	key, _ := cmd.Flags().GetString("key")
+	since, _ := cmd.Flags().GetString("since")
+
+	f := audit.Filter{Action: audit.Action(action), Service: service, Key: key, Limit: n}
+	if since != "" {
+		t, err := parseSince(since, time.Now())
+		if err != nil {
+			return err
+		}
+		f.Since = t
+	}
+
+	api, err := apiClient(cmd)
+	if err != nil {
+		return err
+	}
+	entries, err := api.AuditEntries(cmd.Context(), f)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		printAuditEntry(e, jsonOut)
+	}
+	return nil
+}
+
+// parseSince parses --since as a duration before now or an RFC 3339 time.
+func parseSince(s string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(s); err == nil && d >= 0 {
+		return now.Add(-d), nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("--since: expected a duration such as 24h or an RFC 3339 time, got %q", s)
+	}
+	return t, nil
+}
+
+// printAuditEntry prints one entry, as a line of JSON when jsonOut is set.
+func printAuditEntry(e audit.Entry, jsonOut bool) {
+	if jsonOut {
+		data, _ := json.Marshal(e)
+		fmt.Println(string(data))
+		return
+	}
+	dash := func(s string) string {
+		if s == "" {
+			return "-"
+		}
+		return s
+	}
+	line := fmt.Sprintf("%s  %-16s %-20s %-24s %s", e.Timestamp.Local().Format("2006-01-02 15:04:05"),
+		e.Action, dash(e.Service), dash(e.Key), dash(e.Actor))
+	if e.Error != "" {
+		line += "  error: " + e.Error
+	}
+	fmt.Println(strings.TrimRight(line, " "))
+}
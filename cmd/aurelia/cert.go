@@ -188,7 +188,7 @@ func runCertRenew(cmd *cobra.Command, _ []string) error {
 
 	// Reload traefik to pick up the new cert
 	fmt.Print("Reloading traefik...")
-	if _, err := apiPost("/v1/services/infra-traefik/restart"); err != nil {
+	if err := restartTraefik(cmd); err != nil {
 		fmt.Printf(" failed: %v\n", err)
 		fmt.Println("Restart traefik manually: aurelia restart infra-traefik")
 	} else {
@@ -198,6 +198,15 @@ func runCertRenew(cmd *cobra.Command, _ []string) error {
 	return nil
 }
 
+// restartTraefik restarts the daemon's traefik service.
+func restartTraefik(cmd *cobra.Command) error {
+	api, err := apiClient(cmd)
+	if err != nil {
+		return err
+	}
+	return api.RestartService(cmd.Context(), "infra-traefik")
+}
+
 func runCertIssue(cmd *cobra.Command, _ []string) error {
 	ttl, _ := cmd.Flags().GetString("ttl")
 	role, _ := cmd.Flags().GetString("role")
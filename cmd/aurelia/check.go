@@ -46,7 +46,11 @@ func runCheck(cmd *cobra.Command, args []string) error {
 	if info.IsDir() {
 		yamlFiles, _ := filepath.Glob(filepath.Join(target, "*.yaml"))
 		ymlFiles, _ := filepath.Glob(filepath.Join(target, "*.yml"))
-		files = append(yamlFiles, ymlFiles...)
+		for _, f := range append(yamlFiles, ymlFiles...) {
+			if !spec.IsDefaultsFile(f) {
+				files = append(files, f)
+			}
+		}
 		if len(files) == 0 {
 			return fmt.Errorf("no YAML files found in %s", target)
 		}
@@ -57,11 +61,13 @@ func runCheck(cmd *cobra.Command, args []string) error {
 	var results []checkResult
 	var failed int
 	for _, path := range files {
-		s, err := spec.Load(path)
+		specs, err := spec.LoadFile(path)
 		if err != nil {
 			results = append(results, checkResult{Path: path, Valid: false, Error: err.Error()})
 			failed++
-		} else {
+			continue
+		}
+		for _, s := range specs {
 			results = append(results, checkResult{Path: path, Name: s.Service.Name, Type: string(s.Service.Type), Valid: true})
 		}
 	}
@@ -5,79 +5,55 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"net"
 	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
+	"sort"
 	"strconv"
 	"strings"
+	"syscall"
 	"text/tabwriter"
 	"time"
 
+	"github.com/benaskins/aurelia/internal/client"
 	"github.com/benaskins/aurelia/internal/config"
 	"github.com/benaskins/aurelia/internal/daemon"
 	"github.com/benaskins/aurelia/internal/driver"
 	"github.com/benaskins/aurelia/internal/gpu"
+	"github.com/benaskins/aurelia/internal/health"
 	"github.com/benaskins/aurelia/internal/node"
 	"github.com/benaskins/aurelia/internal/spec"
 	"github.com/spf13/cobra"
 )
 
-func apiClient() (*http.Client, error) {
-	socketPath, err := defaultSocketPath()
-	if err != nil {
-		return nil, err
-	}
-	return &http.Client{
-		Timeout: 30 * time.Second,
-		Transport: &http.Transport{
-			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
-				return net.Dial("unix", socketPath)
-			},
-		},
-	}, nil
-}
-
-func apiGet(path string, v any) error {
-	client, err := apiClient()
-	if err != nil {
-		return err
-	}
-	resp, err := client.Get("http://aurelia" + path)
-	if err != nil {
-		return fmt.Errorf("connecting to daemon: %w (is aurelia daemon running?)", err)
+// apiClient returns a client for the daemon's TCP API at --host, with the
+// bearer token from --token or --token-file, or for the local daemon's Unix
+// socket when --host is unset.
+func apiClient(cmd *cobra.Command) (*client.Client, error) {
+	host, _ := cmd.Flags().GetString("host")
+	token, _ := cmd.Flags().GetString("token")
+	tokenFile, _ := cmd.Flags().GetString("token-file")
+
+	if host == "" {
+		if token != "" || tokenFile != "" {
+			return nil, fmt.Errorf("--token and --token-file require --host")
+		}
+		socketPath, err := defaultSocketPath()
+		if err != nil {
+			return nil, err
+		}
+		return client.NewUnix(socketPath), nil
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode >= 400 {
-		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
-		return fmt.Errorf("API error %d: %s", resp.StatusCode, body)
+	if token == "" && tokenFile == "" {
+		return nil, fmt.Errorf("--host requires --token or --token-file")
 	}
-
-	return json.NewDecoder(resp.Body).Decode(v)
-}
-
-func apiPost(path string) (map[string]any, error) {
-	client, err := apiClient()
+	token, err := config.Node{Name: host, Addr: host, Token: token, TokenFile: tokenFile}.LoadToken()
 	if err != nil {
 		return nil, err
 	}
-	resp, err := client.Post("http://aurelia"+path, "application/json", nil)
-	if err != nil {
-		return nil, fmt.Errorf("connecting to daemon: %w (is aurelia daemon running?)", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode >= 400 {
-		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
-		return nil, fmt.Errorf("API error %d: %s", resp.StatusCode, string(body))
-	}
-
-	var result map[string]any
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("decoding response: %w", err)
-	}
-
-	return result, nil
+	return client.NewTCP(host, token), nil
 }
 
 // resolveNodeClient returns a node.Client if --node is set, or nil for local.
@@ -97,126 +73,243 @@ func resolveNodeClient(cmd *cobra.Command) (*node.Client, error) {
 var statusCmd = &cobra.Command{
 	Use:   "status",
 	Short: "Show service status",
+	Long: "Show service status. With --watch, redraw the table every --interval until\n" +
+		"interrupted.",
 	RunE: func(cmd *cobra.Command, args []string) error {
 		jsonOut, _ := cmd.Flags().GetBool("json")
+		watch, _ := cmd.Flags().GetBool("watch")
+		interval, _ := cmd.Flags().GetDuration("interval")
+		if watch && jsonOut {
+			return fmt.Errorf("--watch and --json can't be combined")
+		}
+		if interval <= 0 {
+			return fmt.Errorf("--interval must be positive")
+		}
 
 		// If --node is set, query that specific remote node directly
 		remote, err := resolveNodeClient(cmd)
 		if err != nil {
 			return err
 		}
-
-		var states []daemon.ServiceState
-		if remote != nil {
-			raw, err := remote.Status()
-			if err != nil {
+		var api *client.Client
+		if remote == nil {
+			if api, err = apiClient(cmd); err != nil {
 				return err
 			}
-			if err := json.Unmarshal(raw, &states); err != nil {
-				return fmt.Errorf("decoding status: %w", err)
-			}
-			// Stamp node name on each state
-			for i := range states {
-				if states[i].Node == "" {
-					states[i].Node = remote.Name
-				}
-			}
-		} else {
-			// Use cluster endpoint to aggregate all nodes
-			var clusterResp struct {
-				Services []daemon.ServiceState `json:"services"`
-				Peers    map[string]string     `json:"peers"`
-			}
-			if err := apiGet("/v1/cluster/services", &clusterResp); err != nil {
-				// Fall back to local-only if cluster endpoint not available
-				if err := apiGet("/v1/services", &states); err != nil {
-					return err
-				}
-			} else {
-				states = clusterResp.Services
-			}
+		}
+		fetch := func(ctx context.Context) ([]daemon.ServiceState, error) {
+			return fetchStatus(ctx, api, remote)
 		}
 
+		if watch {
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			return watchStatus(ctx, os.Stdout, interval, fetch, gpu.QueryNow)
+		}
+
+		states, err := fetch(cmd.Context())
+		if err != nil {
+			return err
+		}
 		if jsonOut {
 			return printJSON(states)
 		}
+		renderStatus(os.Stdout, states, gpu.QueryNow())
 
-		if len(states) == 0 {
-			fmt.Println("No services")
-			return nil
+		// Spec drift check (local only, skip for remote queries)
+		if remote == nil && len(states) > 0 {
+			checkSpecDrift()
 		}
+		return nil
+	},
+}
 
-		// Determine if we should show the NODE column
-		hasNodes := false
-		for _, s := range states {
-			if s.Node != "" {
-				hasNodes = true
-				break
+// fetchStatus returns the service states from the remote node when set,
+// otherwise from api across the cluster, falling back to the local daemon's
+// services if the cluster endpoint isn't available.
+func fetchStatus(ctx context.Context, api *client.Client, remote *node.Client) ([]daemon.ServiceState, error) {
+	var states []daemon.ServiceState
+	if remote != nil {
+		raw, err := remote.Status()
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(raw, &states); err != nil {
+			return nil, fmt.Errorf("decoding status: %w", err)
+		}
+		// Stamp node name on each state
+		for i := range states {
+			if states[i].Node == "" {
+				states[i].Node = remote.Name
 			}
 		}
+		return states, nil
+	}
 
-		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-		if hasNodes {
-			fmt.Fprintln(w, "NODE\tSERVICE\tTYPE\tSTATE\tHEALTH\tPID\tPORT\tUPTIME\tRESTARTS")
+	// Use cluster endpoint to aggregate all nodes
+	var clusterResp struct {
+		Services []daemon.ServiceState `json:"services"`
+		Peers    map[string]string     `json:"peers"`
+	}
+	if err := api.Get(ctx, "/v1/cluster/services", &clusterResp); err != nil {
+		// Fall back to local-only if cluster endpoint not available
+		return api.ListServices(ctx)
+	}
+	return clusterResp.Services, nil
+}
+
+// clearScreen moves the cursor home and clears the terminal.
+const clearScreen = "\033[H\033[2J"
+
+// watchStatus redraws the status table every interval until ctx is done. A
+// failed fetch is shown in place of the table and retried on the next tick,
+// so a daemon restart doesn't end the watch.
+func watchStatus(ctx context.Context, w io.Writer, interval time.Duration,
+	fetch func(context.Context) ([]daemon.ServiceState, error), gpuInfo func() gpu.Info) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		states, err := fetch(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		fmt.Fprint(w, clearScreen)
+		fmt.Fprintf(w, "Every %s: aurelia status    %s\n\n", interval, time.Now().Format("15:04:05"))
+		if err != nil {
+			fmt.Fprintf(w, "error: %v\n", err)
 		} else {
-			fmt.Fprintln(w, "SERVICE\tTYPE\tSTATE\tHEALTH\tPID\tPORT\tUPTIME\tRESTARTS")
+			renderStatus(w, states, gpuInfo())
 		}
-		for _, s := range states {
-			pid := "-"
-			if s.PID > 0 {
-				pid = fmt.Sprintf("%d", s.PID)
-			}
-			port := "-"
-			if s.Port > 0 {
-				port = fmt.Sprintf("%d", s.Port)
-			}
-			uptime := "-"
-			if s.Uptime != "" {
-				uptime = s.Uptime
-			}
-			health := string(s.Health)
-			if health == "" {
-				health = "-"
+
+		select {
+		case <-ctx.Done():
+			fmt.Fprintln(w)
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// renderStatus writes the status table for states, followed by details for
+// failed, waiting and degraded services and a GPU summary line if gpuInfo
+// names a GPU.
+func renderStatus(out io.Writer, states []daemon.ServiceState, gpuInfo gpu.Info) {
+	if len(states) == 0 {
+		fmt.Fprintln(out, "No services")
+		return
+	}
+
+	// Determine if we should show the NODE column
+	hasNodes := false
+	for _, s := range states {
+		if s.Node != "" {
+			hasNodes = true
+			break
+		}
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	if hasNodes {
+		fmt.Fprintln(w, "NODE\tSERVICE\tTYPE\tSTATE\tHEALTH\tPID\tPORT\tUPTIME\tRESTARTS")
+	} else {
+		fmt.Fprintln(w, "SERVICE\tTYPE\tSTATE\tHEALTH\tPID\tPORT\tUPTIME\tRESTARTS")
+	}
+	for _, s := range states {
+		pid := "-"
+		if s.PID > 0 {
+			pid = fmt.Sprintf("%d", s.PID)
+		}
+		port := "-"
+		if s.Port > 0 {
+			port = fmt.Sprintf("%d", s.Port)
+		}
+		uptime := "-"
+		if s.Uptime != "" {
+			uptime = s.Uptime
+		}
+		health := string(s.Health)
+		if health == "" {
+			health = "-"
+		}
+		if s.Warming {
+			health = "starting (grace)"
+		}
+		if s.Flapping {
+			health += " (flapping)"
+		}
+		if s.Degraded {
+			health += " (degraded)"
+		}
+		state := string(s.State)
+		if s.Waiting != "" {
+			state = "waiting"
+		} else if s.StateReason != "" && s.State != driver.StateRunning {
+			state += " (" + s.StateReason + ")"
+		}
+		if hasNodes {
+			nodeName := s.Node
+			if nodeName == "" {
+				nodeName = "-"
 			}
-			if hasNodes {
-				nodeName := s.Node
-				if nodeName == "" {
-					nodeName = "-"
-				}
-				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%d\n",
-					nodeName, s.Name, s.Type, s.State, health, pid, port, uptime, s.RestartCount)
-			} else {
-				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%d\n",
-					s.Name, s.Type, s.State, health, pid, port, uptime, s.RestartCount)
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%d\n",
+				nodeName, s.Name, s.Type, state, health, pid, port, uptime, s.RestartCount)
+		} else {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%d\n",
+				s.Name, s.Type, state, health, pid, port, uptime, s.RestartCount)
+		}
+	}
+	w.Flush()
+
+	// Show details for failed services
+	for _, s := range states {
+		if s.State == driver.StateFailed {
+			detail := fmt.Sprintf("\n%s: exit %d", s.Name, s.LastExitCode)
+			if s.LastError != "" {
+				detail += fmt.Sprintf(" — %s", s.LastError)
 			}
+			fmt.Fprintln(out, detail)
 		}
-		w.Flush()
+	}
 
-		// Show details for failed services
-		for _, s := range states {
-			if s.State == driver.StateFailed {
-				detail := fmt.Sprintf("\n%s: exit %d", s.Name, s.LastExitCode)
-				if s.LastError != "" {
-					detail += fmt.Sprintf(" — %s", s.LastError)
-				}
-				fmt.Println(detail)
-			}
+	// Show what held-back services are waiting for
+	for _, s := range states {
+		if s.Waiting != "" {
+			fmt.Fprintf(out, "\n%s: waiting — %s\n", s.Name, s.Waiting)
 		}
+	}
 
-		// GPU summary line
-		gpuInfo := gpu.QueryNow()
-		if gpuInfo.Name != "" {
-			fmt.Printf("\nGPU: %s | VRAM: %.1f/%.1f GB | Thermal: %s\n",
-				gpuInfo.Name, gpuInfo.AllocatedGB(), gpuInfo.RecommendedMaxGB(), gpuInfo.ThermalState)
+	// Show which hard dependencies are dragging degraded services down
+	for _, s := range states {
+		if !s.Degraded {
+			continue
+		}
+		var unhealthy []string
+		for _, dh := range s.DependencyHealth {
+			if dh.Health == health.StatusUnhealthy {
+				unhealthy = append(unhealthy, dh.Name)
+			}
 		}
+		fmt.Fprintf(out, "\n%s: degraded — unhealthy dependencies: %s\n", s.Name, strings.Join(unhealthy, ", "))
+	}
 
-		// Spec drift check (local only, skip for remote queries)
-		if remote == nil {
-			checkSpecDrift()
+	// Warn about processes left running by the last stop
+	for _, s := range states {
+		if len(s.OrphanedChildren) == 0 {
+			continue
+		}
+		pids := make([]string, len(s.OrphanedChildren))
+		for i, pid := range s.OrphanedChildren {
+			pids[i] = strconv.Itoa(pid)
 		}
+		fmt.Fprintf(out, "\n%s: orphaned children detected — PIDs %s still running\n", s.Name, strings.Join(pids, ", "))
+	}
 
-		return nil
-	},
+	// GPU summary line
+	if gpuInfo.Name != "" {
+		fmt.Fprintf(out, "\nGPU: %s | VRAM: %.1f/%.1f GB | Thermal: %s\n",
+			gpuInfo.Name, gpuInfo.AllocatedGB(), gpuInfo.RecommendedMaxGB(), gpuInfo.ThermalState)
+	}
 }
 
 // up command
@@ -231,31 +324,41 @@ var upCmd = &cobra.Command{
 			return err
 		}
 
+		wait, _ := cmd.Flags().GetDuration("wait")
+		if wait > 0 && (remote != nil || len(args) == 0) {
+			return fmt.Errorf("--wait requires service names and is not supported with --node")
+		}
+
 		if len(args) == 0 {
 			if remote != nil {
 				return remote.ReloadService()
 			}
 			// Start all — reload picks up everything
-			result, err := apiPost("/v1/reload")
-			if err != nil {
+			return runReload(cmd, jsonOut)
+		}
+
+		var api *client.Client
+		if remote == nil {
+			if api, err = apiClient(cmd); err != nil {
 				return err
 			}
-			if jsonOut {
-				return printJSON(result)
-			}
-			fmt.Printf("Services loaded: %v\n", result)
-			return nil
 		}
 
 		var results []map[string]any
+		failed := 0
 		for _, name := range args {
 			var opErr error
+			status := "starting"
 			if remote != nil {
 				opErr = remote.StartService(name)
+			} else if wait > 0 {
+				_, opErr = api.StartServiceWait(cmd.Context(), name, wait)
+				status = "ready"
 			} else {
-				_, opErr = apiPost(fmt.Sprintf("/v1/services/%s/start", name))
+				opErr = api.StartService(cmd.Context(), name)
 			}
 			if opErr != nil {
+				failed++
 				if jsonOut {
 					results = append(results, map[string]any{"service": name, "error": opErr.Error()})
 				} else {
@@ -264,13 +367,19 @@ var upCmd = &cobra.Command{
 				continue
 			}
 			if jsonOut {
-				results = append(results, map[string]any{"service": name, "status": "starting"})
+				results = append(results, map[string]any{"service": name, "status": status})
 			} else {
-				fmt.Printf("%s: starting\n", name)
+				fmt.Printf("%s: %s\n", name, status)
 			}
 		}
 		if jsonOut {
-			return printJSON(results)
+			if err := printJSON(results); err != nil {
+				return err
+			}
+		}
+		// Without --wait, per-service errors are reported but not fatal
+		if wait > 0 && failed > 0 {
+			return fmt.Errorf("%d of %d services not ready", failed, len(args))
 		}
 		return nil
 	},
@@ -288,10 +397,17 @@ var downCmd = &cobra.Command{
 			return err
 		}
 
+		var api *client.Client
+		if remote == nil {
+			if api, err = apiClient(cmd); err != nil {
+				return err
+			}
+		}
+
 		if len(args) == 0 && remote == nil {
 			// Stop all local
-			var states []daemon.ServiceState
-			if err := apiGet("/v1/services", &states); err != nil {
+			states, err := api.ListServices(cmd.Context())
+			if err != nil {
 				return err
 			}
 			for _, s := range states {
@@ -305,7 +421,7 @@ var downCmd = &cobra.Command{
 			if remote != nil {
 				opErr = remote.StopService(name)
 			} else {
-				_, opErr = apiPost(fmt.Sprintf("/v1/services/%s/stop", name))
+				opErr = api.StopService(cmd.Context(), name)
 			}
 			if opErr != nil {
 				if jsonOut {
@@ -341,6 +457,15 @@ var restartCmd = &cobra.Command{
 		}
 
 		if remote != nil {
+			if w, _ := cmd.Flags().GetDuration("wait"); w > 0 {
+				return fmt.Errorf("--wait is not supported with --node")
+			}
+			if withDeps, _ := cmd.Flags().GetBool("with-deps"); withDeps {
+				return fmt.Errorf("--with-deps is not supported with --node")
+			}
+			if rolling, _ := cmd.Flags().GetBool("rolling"); rolling {
+				return fmt.Errorf("--rolling is not supported with --node")
+			}
 			if err := remote.RestartService(args[0]); err != nil {
 				return err
 			}
@@ -351,24 +476,75 @@ var restartCmd = &cobra.Command{
 			return nil
 		}
 
-		result, err := apiPost(fmt.Sprintf("/v1/services/%s/restart", args[0]))
+		api, err := apiClient(cmd)
 		if err != nil {
 			return err
 		}
+
+		if withDeps, _ := cmd.Flags().GetBool("with-deps"); withDeps {
+			if err := api.RestartServiceWithDeps(cmd.Context(), args[0]); err != nil {
+				return err
+			}
+			if jsonOut {
+				return printJSON(map[string]string{"status": "restarted"})
+			}
+			fmt.Printf("%s: restarted with dependents\n", args[0])
+			return nil
+		}
+
+		if rolling, _ := cmd.Flags().GetBool("rolling"); rolling {
+			if err := api.RollingRestartService(cmd.Context(), args[0]); err != nil {
+				return err
+			}
+			if jsonOut {
+				return printJSON(map[string]string{"status": "restarted"})
+			}
+			fmt.Printf("%s: restarted replica by replica\n", args[0])
+			return nil
+		}
+
+		wait, _ := cmd.Flags().GetDuration("wait")
+		if wait > 0 {
+			state, err := api.RestartServiceWait(cmd.Context(), args[0], wait)
+			if jsonOut {
+				result := map[string]any{"status": "ready", "state": state}
+				if err != nil {
+					result = map[string]any{"error": err.Error(), "state": state}
+				}
+				printJSON(result)
+			}
+			if err != nil {
+				return err
+			}
+			if !jsonOut {
+				fmt.Printf("%s: ready\n", args[0])
+			}
+			return nil
+		}
+
+		if err := api.RestartService(cmd.Context(), args[0]); err != nil {
+			return err
+		}
 		if jsonOut {
-			return printJSON(result)
+			return printJSON(map[string]string{"status": "restarting"})
 		}
-		fmt.Printf("%s: %v\n", args[0], result["status"])
+		fmt.Printf("%s: restarting\n", args[0])
 		return nil
 	},
 }
 
 // deploy command
 var deployCmd = &cobra.Command{
-	Use:   "deploy <service>",
+	Use:   "deploy <service> | --all",
 	Short: "Zero-downtime deploy a service",
-	Long:  "Performs a blue-green deploy: starts new instance, verifies health, switches routing, drains old.",
-	Args:  cobra.ExactArgs(1),
+	Long: "Performs a blue-green deploy: starts new instance, verifies health, switches routing, drains old.\n" +
+		"With --all, deploys every routed service in dependency order.",
+	Args: func(cmd *cobra.Command, args []string) error {
+		if all, _ := cmd.Flags().GetBool("all"); all {
+			return cobra.NoArgs(cmd, args)
+		}
+		return cobra.ExactArgs(1)(cmd, args)
+	},
 	RunE: func(cmd *cobra.Command, args []string) error {
 		jsonOut, _ := cmd.Flags().GetBool("json")
 		remote, err := resolveNodeClient(cmd)
@@ -376,6 +552,13 @@ var deployCmd = &cobra.Command{
 			return err
 		}
 
+		if all, _ := cmd.Flags().GetBool("all"); all {
+			if remote != nil {
+				return fmt.Errorf("--all is not supported with --node")
+			}
+			return runDeployAll(cmd, jsonOut)
+		}
+
 		if remote != nil {
 			if err := remote.DeployService(args[0]); err != nil {
 				return err
@@ -387,38 +570,186 @@ var deployCmd = &cobra.Command{
 			return nil
 		}
 
-		drain, _ := cmd.Flags().GetString("drain")
-		path := fmt.Sprintf("/v1/services/%s/deploy", args[0])
-		if drain != "" {
-			path += "?drain=" + drain
+		drain, err := drainFlag(cmd)
+		if err != nil {
+			return err
 		}
-		client, err := apiClient()
+		api, err := apiClient(cmd)
 		if err != nil {
 			return err
 		}
-		client.Timeout = 5 * time.Minute // deploy can take a while
-		resp, err := client.Post("http://aurelia"+path, "application/json", nil)
+
+		if jsonOut {
+			if err := api.DeployService(cmd.Context(), args[0], drain); err != nil {
+				return fmt.Errorf("deploy failed: %w", err)
+			}
+			return printJSON(map[string]string{"status": "deployed"})
+		}
+
+		query := url.Values{"stream": {"true"}}
+		if drain > 0 {
+			query.Set("drain", drain.String())
+		}
+		path := fmt.Sprintf("/v1/services/%s/deploy?%s", url.PathEscape(args[0]), query.Encode())
+		ctx, cancel := context.WithTimeout(cmd.Context(), 5*time.Minute) // deploy can take a while
+		defer cancel()
+		resp, err := api.Stream(ctx, http.MethodPost, path, nil)
 		if err != nil {
-			return fmt.Errorf("connecting to daemon: %w (is aurelia daemon running?)", err)
+			return fmt.Errorf("deploy failed: %w", err)
 		}
 		defer resp.Body.Close()
+		return printDeployEvents(resp.Body)
+	},
+}
+
+// printDeployEvents prints a streamed deploy's events as they arrive and
+// returns an error if the deploy failed.
+func printDeployEvents(r io.Reader) error {
+	dec := json.NewDecoder(r)
+	for {
+		var ev struct {
+			daemon.DeployEvent
+			Error string `json:"error"`
+			Code  string `json:"code"`
+		}
+		if err := dec.Decode(&ev); err != nil {
+			if err == io.EOF {
+				return fmt.Errorf("deploy stream ended without a result")
+			}
+			return fmt.Errorf("reading deploy events: %w", err)
+		}
+
+		switch ev.Step {
+		case "failed":
+			if ev.Code != "" {
+				return fmt.Errorf("deploy failed (%s): %s", ev.Code, ev.Error)
+			}
+			return fmt.Errorf("deploy failed: %s", ev.Error)
+		case "deployed":
+			fmt.Printf("%s: deployed\n", ev.Service)
+			return nil
+		}
+
+		var detail []string
+		if ev.Port != 0 {
+			detail = append(detail, fmt.Sprintf("port %d", ev.Port))
+		}
+		if ev.PID != 0 {
+			detail = append(detail, fmt.Sprintf("pid %d", ev.PID))
+		}
+		if len(detail) > 0 {
+			fmt.Printf("%s: %s (%s)\n", ev.Service, ev.Step, strings.Join(detail, ", "))
+		} else {
+			fmt.Printf("%s: %s\n", ev.Service, ev.Step)
+		}
+	}
+}
 
-		if resp.StatusCode >= 400 {
-			body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
-			return fmt.Errorf("deploy failed: %s", body)
+// rollback command
+var rollbackCmd = &cobra.Command{
+	Use:   "rollback <service>",
+	Short: "Roll a service back to its previous deploy",
+	Long:  "Blue-green redeploys the image (containers) or command (native) that ran before the last deploy, and switches routing back to it.",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		jsonOut, _ := cmd.Flags().GetBool("json")
+		remote, err := resolveNodeClient(cmd)
+		if err != nil {
+			return err
 		}
 
-		var result map[string]any
-		json.NewDecoder(resp.Body).Decode(&result)
+		var result daemon.RollbackResult
+		if remote != nil {
+			raw, err := remote.RollbackService(args[0])
+			if err != nil {
+				return err
+			}
+			if err := json.Unmarshal(raw, &result); err != nil {
+				return fmt.Errorf("decoding rollback result: %w", err)
+			}
+		} else {
+			drain, err := drainFlag(cmd)
+			if err != nil {
+				return err
+			}
+			api, err := apiClient(cmd)
+			if err != nil {
+				return err
+			}
+			r, err := api.RollbackService(cmd.Context(), args[0], drain)
+			if err != nil {
+				return fmt.Errorf("rollback failed: %w", err)
+			}
+			result = *r
+		}
 
 		if jsonOut {
 			return printJSON(result)
 		}
-		fmt.Printf("%s: %v\n", args[0], result["status"])
+		target := result.Image
+		if target == "" {
+			target = result.Command
+		}
+		if result.Warning != "" {
+			fmt.Printf("%s: %s (%s)\n", result.Service, result.Status, result.Warning)
+			return nil
+		}
+		fmt.Printf("%s: %s to %s\n", result.Service, result.Status, target)
 		return nil
 	},
 }
 
+func runDeployAll(cmd *cobra.Command, jsonOut bool) error {
+	drain, err := drainFlag(cmd)
+	if err != nil {
+		return err
+	}
+	continueOnError, _ := cmd.Flags().GetBool("continue-on-error")
+
+	api, err := apiClient(cmd)
+	if err != nil {
+		return err
+	}
+	result, err := api.DeployAll(cmd.Context(), drain, continueOnError)
+	if err != nil {
+		return fmt.Errorf("deploy failed: %w", err)
+	}
+
+	if jsonOut {
+		if err := printJSON(result); err != nil {
+			return err
+		}
+	} else {
+		if len(result.Services) == 0 {
+			fmt.Println("No routed services to deploy")
+		}
+		for _, step := range result.Services {
+			if step.Error != "" {
+				fmt.Printf("%s: %s: %s\n", step.Service, step.Status, step.Error)
+			} else {
+				fmt.Printf("%s: %s\n", step.Service, step.Status)
+			}
+		}
+	}
+	if !result.Success {
+		return fmt.Errorf("deploy failed")
+	}
+	return nil
+}
+
+// drainFlag returns the --drain duration, or zero for the daemon default.
+func drainFlag(cmd *cobra.Command) (time.Duration, error) {
+	drain, _ := cmd.Flags().GetString("drain")
+	if drain == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(drain)
+	if err != nil {
+		return 0, fmt.Errorf("invalid --drain %q: %w", drain, err)
+	}
+	return d, nil
+}
+
 // reload command
 var reloadCmd = &cobra.Command{
 	Use:   "reload",
@@ -426,32 +757,231 @@ var reloadCmd = &cobra.Command{
 	Long:  "Re-read spec files and reconcile: start new services, stop removed ones.",
 	RunE: func(cmd *cobra.Command, args []string) error {
 		jsonOut, _ := cmd.Flags().GetBool("json")
+		return runReload(cmd, jsonOut)
+	},
+}
+
+// runReload asks the daemon to reload its specs and prints what changed.
+func runReload(cmd *cobra.Command, jsonOut bool) error {
+	api, err := apiClient(cmd)
+	if err != nil {
+		return err
+	}
+	result, err := api.Reload(cmd.Context())
+	if err != nil {
+		return err
+	}
+
+	if jsonOut {
+		return printJSON(result)
+	}
+	if len(result.Added) > 0 {
+		fmt.Printf("Added: %v\n", result.Added)
+	}
+	if len(result.Removed) > 0 {
+		fmt.Printf("Removed: %v\n", result.Removed)
+	}
+	if len(result.Restarted) > 0 {
+		fmt.Printf("Restarted: %v\n", result.Restarted)
+	}
+	if len(result.ImageDrift) > 0 {
+		fmt.Printf("Image changed (deploy to pick up): %v\n", result.ImageDrift)
+	}
+	if len(result.Added) == 0 && len(result.Removed) == 0 && len(result.Restarted) == 0 {
+		fmt.Println("No changes")
+	}
+	return nil
+}
 
-		result, err := apiPost("/v1/reload")
+// info command
+var maintenanceCmd = &cobra.Command{
+	Use:   "maintenance",
+	Short: "Release all services so the daemon can be restarted without stopping them",
+	Long: `Put the daemon in maintenance mode: supervision and health checks stop for
+every service, but the processes keep running and their state is kept, so the
+next daemon to start adopts them. The daemon keeps serving the API but refuses
+to start, stop or change services until it is restarted.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		api, err := apiClient(cmd)
 		if err != nil {
 			return err
 		}
+		if err := api.EnterMaintenance(cmd.Context()); err != nil {
+			return err
+		}
+		fmt.Println("Maintenance mode: services released. Restart the daemon to resume supervision.")
+		return nil
+	},
+}
 
-		if jsonOut {
-			return printJSON(result)
+var infoCmd = &cobra.Command{
+	Use:   "info",
+	Short: "Show daemon version, uptime, and configuration",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		jsonOut, _ := cmd.Flags().GetBool("json")
+
+		api, err := apiClient(cmd)
+		if err != nil {
+			return err
+		}
+		info, err := api.Info(cmd.Context())
+		if err != nil {
+			return err
 		}
 
-		if added, ok := result["added"]; ok {
-			fmt.Printf("Added: %v\n", added)
+		if jsonOut {
+			return printJSON(info)
 		}
-		if removed, ok := result["removed"]; ok {
-			fmt.Printf("Removed: %v\n", removed)
+
+		printInfo(info)
+		return nil
+	},
+}
+
+var summaryCmd = &cobra.Command{
+	Use:   "summary",
+	Short: "Show a one-line rollup of service health",
+	Long: `Print a single line counting running, stopped, failed and unhealthy
+services, naming any that need attention, and the GPU thermal state when
+known. The line starts with "ok" or "attention".`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		jsonOut, _ := cmd.Flags().GetBool("json")
+
+		api, err := apiClient(cmd)
+		if err != nil {
+			return err
 		}
-		if restarted, ok := result["restarted"]; ok {
-			fmt.Printf("Restarted: %v\n", restarted)
+		sum, err := api.Summary(cmd.Context())
+		if err != nil {
+			return err
 		}
-		if result["added"] == nil && result["removed"] == nil && result["restarted"] == nil {
-			fmt.Println("No changes")
+
+		if jsonOut {
+			return printJSON(sum)
 		}
+		fmt.Println(formatSummary(sum))
 		return nil
 	},
 }
 
+// formatSummary renders a status summary as one line, e.g.
+// "attention: 3/5 running, 1 failed, 1 unhealthy (db, web), thermal serious".
+func formatSummary(sum daemon.StatusSummary) string {
+	status := "ok"
+	if !sum.OK {
+		status = "attention"
+	}
+	parts := []string{fmt.Sprintf("%d/%d running", sum.Running, sum.Total)}
+	if sum.Stopped > 0 {
+		parts = append(parts, fmt.Sprintf("%d stopped", sum.Stopped))
+	}
+	if sum.Completed > 0 {
+		parts = append(parts, fmt.Sprintf("%d completed", sum.Completed))
+	}
+	if sum.Disabled > 0 {
+		parts = append(parts, fmt.Sprintf("%d disabled", sum.Disabled))
+	}
+	if sum.Failed > 0 {
+		parts = append(parts, fmt.Sprintf("%d failed", sum.Failed))
+	}
+	if sum.Unhealthy > 0 {
+		parts = append(parts, fmt.Sprintf("%d unhealthy", sum.Unhealthy))
+	}
+	line := status + ": " + strings.Join(parts, ", ")
+	if len(sum.Attention) > 0 {
+		line += " (" + strings.Join(sum.Attention, ", ") + ")"
+	}
+	if sum.ThermalState != "" {
+		line += ", thermal " + sum.ThermalState
+	}
+	return line
+}
+
+func printInfo(info daemon.Info) {
+	fmt.Printf("Version:      %s\n", info.Version)
+	fmt.Printf("Started:      %s\n", info.StartedAt.Local().Format(time.RFC3339))
+	fmt.Printf("Uptime:       %s\n", info.Uptime)
+	fmt.Printf("Spec Dir:     %s\n", info.SpecDir)
+	fmt.Printf("Routing:      %v\n", info.RoutingEnabled)
+	fmt.Printf("TCP API:      %v\n", info.TCPAPIEnabled)
+	fmt.Printf("Port Range:   %d-%d\n", info.PortMin, info.PortMax)
+	if info.Maintenance {
+		fmt.Println("Maintenance:  true (services released)")
+	}
+
+	states := make([]string, 0, len(info.Services))
+	total := 0
+	for st, n := range info.Services {
+		states = append(states, string(st))
+		total += n
+	}
+	sort.Strings(states)
+	fmt.Printf("Services:     %d\n", total)
+	for _, st := range states {
+		fmt.Printf("  %-11s %d\n", st+":", info.Services[driver.State(st)])
+	}
+}
+
+// routes command
+var routesCmd = &cobra.Command{
+	Use:   "routes",
+	Short: "Show the routes aurelia generated for Traefik",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		jsonOut, _ := cmd.Flags().GetBool("json")
+
+		api, err := apiClient(cmd)
+		if err != nil {
+			return err
+		}
+		var info daemon.RoutingInfo
+		if err := api.Get(cmd.Context(), "/v1/routing", &info); err != nil {
+			return err
+		}
+
+		if jsonOut {
+			return printJSON(info)
+		}
+
+		if !info.Enabled {
+			fmt.Println("Routing is not enabled")
+			return nil
+		}
+		outputs := info.Outputs
+		if len(outputs) == 0 {
+			outputs = []string{info.OutputPath} // daemon predates the outputs field
+		}
+		fmt.Printf("Config: %s\n\n", strings.Join(outputs, ", "))
+		if len(info.Routes) == 0 {
+			fmt.Println("No routes")
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "SERVICE\tPROTOCOL\tHOSTNAME\tPORT\tTLS\tTLS OPTIONS")
+		for _, rt := range info.Routes {
+			protocol := rt.Protocol
+			if protocol == "" {
+				protocol = "http"
+			}
+			hostname := rt.Hostname
+			if len(rt.Hostnames) > 1 {
+				hostname = strings.Join(rt.Hostnames, ",")
+			}
+			hostname += rt.PathPrefix
+			if hostname == "" {
+				hostname = "* (" + rt.EntryPoint + ")"
+			}
+			tlsOptions := rt.TLSOptions
+			if tlsOptions == "" {
+				tlsOptions = "-"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%v\t%s\n", rt.Name, protocol, hostname, rt.Port, rt.TLS, tlsOptions)
+		}
+		return w.Flush()
+	},
+}
+
 // logs command
 var shipCmd = &cobra.Command{
 	Use:   "ship <service>",
@@ -474,7 +1004,11 @@ var shipCmd = &cobra.Command{
 				return fmt.Errorf("decoding ship result: %w", err)
 			}
 		} else {
-			r, err := apiShip(args[0])
+			api, err := apiClient(cmd)
+			if err != nil {
+				return err
+			}
+			r, err := api.Ship(cmd.Context(), args[0])
 			if err != nil {
 				return err
 			}
@@ -493,24 +1027,6 @@ var shipCmd = &cobra.Command{
 	},
 }
 
-func apiShip(name string) (*daemon.ShipResult, error) {
-	client, err := apiClient()
-	if err != nil {
-		return nil, err
-	}
-	resp, err := client.Post("http://aurelia/v1/services/"+name+"/ship", "application/json", nil)
-	if err != nil {
-		return nil, fmt.Errorf("connecting to daemon: %w (is aurelia daemon running?)", err)
-	}
-	defer resp.Body.Close()
-
-	var result daemon.ShipResult
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("decoding response: %w", err)
-	}
-	return &result, nil
-}
-
 func printShipResult(r daemon.ShipResult) {
 	fmt.Printf("Shipping %s\n\n", r.Service)
 	for _, step := range r.Steps {
@@ -556,7 +1072,11 @@ var inspectCmd = &cobra.Command{
 				return fmt.Errorf("decoding inspect response: %w", err)
 			}
 		} else {
-			if err := apiGet("/v1/services/"+args[0]+"/inspect", &si); err != nil {
+			api, err := apiClient(cmd)
+			if err != nil {
+				return err
+			}
+			if si, err = api.InspectService(cmd.Context(), args[0]); err != nil {
 				return err
 			}
 		}
@@ -591,11 +1111,20 @@ func printInspect(si daemon.ServiceInspect) {
 	}
 	if si.Image != "" {
 		fmt.Printf("\nImage:        %s\n", si.Image)
+		if si.ImageDigest != "" {
+			fmt.Printf("Digest:       %s\n", si.ImageDigest)
+		}
+		if si.ImageDrift {
+			fmt.Println("              tag now points to a newer image; deploy to pick it up")
+		}
 	}
 
 	if si.Routing != nil {
 		fmt.Println("\nRouting:")
-		fmt.Printf("  Hostname:   %s\n", si.Routing.Hostname)
+		if !si.Routing.IsHTTP() {
+			fmt.Printf("  Protocol:   %s\n", si.Routing.Protocol)
+		}
+		fmt.Printf("  Hostname:   %s\n", strings.Join(si.Routing.AllHostnames(), ", "))
 		fmt.Printf("  TLS:        %v\n", si.Routing.TLS)
 	}
 
@@ -604,6 +1133,9 @@ func printInspect(si daemon.ServiceInspect) {
 		if len(si.Dependencies.After) > 0 {
 			fmt.Printf("  After:      %s\n", strings.Join(si.Dependencies.After, ", "))
 		}
+		if len(si.Dependencies.Before) > 0 {
+			fmt.Printf("  Before:     %s\n", strings.Join(si.Dependencies.Before, ", "))
+		}
 		if len(si.Dependencies.Requires) > 0 {
 			fmt.Printf("  Requires:   %s\n", strings.Join(si.Dependencies.Requires, ", "))
 		}
@@ -650,44 +1182,6 @@ func printInspect(si daemon.ServiceInspect) {
 	}
 }
 
-var logsCmd = &cobra.Command{
-	Use:   "logs <service>",
-	Short: "Show recent log output for a service",
-	Args:  cobra.ExactArgs(1),
-	RunE: func(cmd *cobra.Command, args []string) error {
-		jsonOut, _ := cmd.Flags().GetBool("json")
-		n, _ := cmd.Flags().GetInt("lines")
-		remote, err := resolveNodeClient(cmd)
-		if err != nil {
-			return err
-		}
-
-		var lines []string
-		if remote != nil {
-			lines, err = remote.Logs(args[0], n)
-			if err != nil {
-				return err
-			}
-		} else {
-			var resp struct {
-				Lines []string `json:"lines"`
-			}
-			if err := apiGet(fmt.Sprintf("/v1/services/%s/logs?n=%s", args[0], strconv.Itoa(n)), &resp); err != nil {
-				return err
-			}
-			lines = resp.Lines
-		}
-
-		if jsonOut {
-			return printJSON(map[string]any{"lines": lines})
-		}
-		for _, line := range lines {
-			fmt.Println(line)
-		}
-		return nil
-	},
-}
-
 // checkSpecDrift loads the daemon config, resolves the source spec directory,
 // and prints a warning if any deployed specs have drifted from source.
 func checkSpecDrift() {
@@ -726,8 +1220,19 @@ func checkSpecDrift() {
 }
 
 func init() {
-	logsCmd.Flags().IntP("lines", "n", 50, "number of lines to show")
+	statusCmd.Flags().BoolP("watch", "w", false, "redraw the status table periodically until interrupted")
+	statusCmd.Flags().Duration("interval", 2*time.Second, "refresh interval for --watch")
 	deployCmd.Flags().String("drain", "5s", "drain period before stopping old instance")
+	rollbackCmd.Flags().String("drain", "5s", "drain period before stopping the current instance")
+	deployCmd.Flags().Bool("all", false, "deploy every routed service in dependency order")
+	deployCmd.Flags().Bool("continue-on-error", false, "with --all, keep deploying after a service fails")
+	for _, c := range []*cobra.Command{upCmd, restartCmd} {
+		c.Flags().Duration("wait", 0, "wait up to this long for the service to be running and healthy")
+		c.Flags().Lookup("wait").NoOptDefVal = daemon.DefaultReadyTimeout.String()
+	}
+	restartCmd.Flags().Bool("with-deps", false, "also restart hard dependents, in dependency order, once the service is ready")
+	restartCmd.Flags().Bool("rolling", false, "restart one replica at a time, waiting for each to be ready before the next")
+	restartCmd.MarkFlagsMutuallyExclusive("wait", "with-deps", "rolling")
 
 	rootCmd.AddCommand(statusCmd)
 	rootCmd.AddCommand(inspectCmd)
@@ -736,6 +1241,10 @@ func init() {
 	rootCmd.AddCommand(downCmd)
 	rootCmd.AddCommand(restartCmd)
 	rootCmd.AddCommand(deployCmd)
+	rootCmd.AddCommand(rollbackCmd)
 	rootCmd.AddCommand(reloadCmd)
-	rootCmd.AddCommand(logsCmd)
+	rootCmd.AddCommand(maintenanceCmd)
+	rootCmd.AddCommand(infoCmd)
+	rootCmd.AddCommand(summaryCmd)
+	rootCmd.AddCommand(routesCmd)
 }
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/benaskins/aurelia/internal/api"
+	"github.com/benaskins/aurelia/internal/daemon"
+	"github.com/benaskins/aurelia/internal/driver"
+	"github.com/benaskins/aurelia/internal/gpu"
+	"github.com/benaskins/aurelia/internal/health"
+)
+
+// startTCPDaemon runs a daemon with one service behind an authenticated TCP
+// API and returns the API address and its bearer token file.
+func startTCPDaemon(t *testing.T) (addr, tokenPath string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	spec := "service:\n  name: alpha\n  type: native\n  command: \"sleep 30\"\n"
+	if err := os.WriteFile(filepath.Join(dir, "alpha.yaml"), []byte(spec), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	d := daemon.NewDaemon(dir)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	if err := d.Start(ctx); err != nil {
+		t.Fatalf("daemon start: %v", err)
+	}
+	t.Cleanup(func() { d.Stop(5 * time.Second) })
+
+	srv := api.NewServer(d, nil, "test")
+	tokenPath = filepath.Join(t.TempDir(), "api.token")
+	if err := srv.GenerateToken(tokenPath); err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	addr = ln.Addr().String()
+	ln.Close() // free the port for ListenTCP
+	go srv.ListenTCP(addr)
+	t.Cleanup(func() { srv.Shutdown(context.Background()) })
+
+	for i := 0; i < 50; i++ {
+		if conn, err := net.Dial("tcp", addr); err == nil {
+			conn.Close()
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return addr, tokenPath
+}
+
+// runCLI executes the root command with args, clearing the connection flags
+// left over from previous runs.
+func runCLI(args ...string) error {
+	for _, name := range []string{"host", "token", "token-file"} {
+		f := rootCmd.PersistentFlags().Lookup(name)
+		f.Value.Set(f.DefValue)
+		f.Changed = false
+	}
+	rootCmd.SetArgs(args)
+	return rootCmd.Execute()
+}
+
+func TestCLIRemoteHostWithToken(t *testing.T) {
+	addr, tokenPath := startTCPDaemon(t)
+	token, err := os.ReadFile(tokenPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := runCLI("info", "--host", addr, "--token", strings.TrimSpace(string(token))); err != nil {
+		t.Errorf("info with --token: %v", err)
+	}
+	if err := runCLI("restart", "alpha", "--host", addr, "--token-file", tokenPath); err != nil {
+		t.Errorf("restart with --token-file: %v", err)
+	}
+}
+
+func TestCLIRemoteHostRejectsBadToken(t *testing.T) {
+	addr, _ := startTCPDaemon(t)
+
+	err := runCLI("info", "--host", addr, "--token", "wrong-token")
+	if err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("expected 401 with wrong token, got %v", err)
+	}
+
+	err = runCLI("info", "--host", addr)
+	if err == nil || !strings.Contains(err.Error(), "--host requires --token") {
+		t.Errorf("expected missing token error, got %v", err)
+	}
+}
+
+func TestCLITokenRequiresHost(t *testing.T) {
+	err := runCLI("info", "--token", "abc")
+	if err == nil || !strings.Contains(err.Error(), "require --host") {
+		t.Errorf("expected --host required error, got %v", err)
+	}
+}
+
+func TestFormatSummary(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		sum  daemon.StatusSummary
+		want string
+	}{
+		{daemon.StatusSummary{Total: 2, Running: 2, OK: true}, "ok: 2/2 running"},
+		{daemon.StatusSummary{Total: 4, Running: 2, Stopped: 1, Completed: 1, OK: true, ThermalState: "nominal"},
+			"ok: 2/4 running, 1 stopped, 1 completed, thermal nominal"},
+		{daemon.StatusSummary{Total: 3, Running: 2, Disabled: 1, OK: true}, "ok: 2/3 running, 1 disabled"},
+		{daemon.StatusSummary{Total: 5, Running: 3, Stopped: 1, Failed: 1, Unhealthy: 1, Attention: []string{"db", "web"}},
+			"attention: 3/5 running, 1 stopped, 1 failed, 1 unhealthy (db, web)"},
+		{daemon.StatusSummary{Total: 1, Running: 1, ThermalState: "critical"},
+			"attention: 1/1 running, thermal critical"},
+	}
+	for _, tt := range tests {
+		if got := formatSummary(tt.sum); got != tt.want {
+			t.Errorf("formatSummary(%+v) = %q, want %q", tt.sum, got, tt.want)
+		}
+	}
+}
+
+func TestRenderStatus(t *testing.T) {
+	states := []daemon.ServiceState{
+		{Name: "api", Type: "native", State: driver.StateRunning, Health: health.StatusHealthy, PID: 4242, Port: 8080, Uptime: "5m0s", RestartCount: 1},
+		{Name: "worker", Type: "native", State: driver.StateFailed, LastExitCode: 2, LastError: "boom", StateReason: daemon.ReasonPolicyExhausted},
+		{Name: "llm", Type: "native", State: driver.StateStarting, Waiting: "VRAM: 8.0 GB needed, 2.5 GB free"},
+		{Name: "batch", Type: "native", State: driver.StateDisabled, Health: health.StatusUnknown},
+	}
+	var buf bytes.Buffer
+	renderStatus(&buf, states, gpu.Info{Name: "Apple M2", ThermalState: "nominal"})
+	out := buf.String()
+
+	for _, want := range []string{
+		"SERVICE  TYPE",
+		"api      native  running",
+		"4242",
+		"failed (policy_exhausted)",
+		"worker: exit 2 — boom",
+		"llm: waiting — VRAM: 8.0 GB needed",
+		"batch    native  disabled",
+		"GPU: Apple M2",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in output:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "NODE") {
+		t.Errorf("expected no NODE column without node names:\n%s", out)
+	}
+
+	buf.Reset()
+	renderStatus(&buf, nil, gpu.Info{})
+	if got := buf.String(); got != "No services\n" {
+		t.Errorf("empty status = %q", got)
+	}
+}
+
+func TestWatchStatusRedraws(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	calls := 0
+	fetch := func(context.Context) ([]daemon.ServiceState, error) {
+		calls++
+		switch calls {
+		case 2:
+			return nil, errors.New("daemon unavailable")
+		case 3:
+			cancel()
+		}
+		return []daemon.ServiceState{{Name: "api", Type: "native", State: driver.StateRunning}}, nil
+	}
+
+	var buf bytes.Buffer
+	if err := watchStatus(ctx, &buf, 10*time.Millisecond, fetch, func() gpu.Info { return gpu.Info{} }); err != nil {
+		t.Fatalf("watchStatus: %v", err)
+	}
+	out := buf.String()
+	if n := strings.Count(out, clearScreen); n != 2 {
+		t.Errorf("expected 2 redraws before cancel, got %d:\n%s", n, out)
+	}
+	if !strings.Contains(out, "error: daemon unavailable") {
+		t.Errorf("expected the fetch error shown, got:\n%s", out)
+	}
+	if strings.Count(out, "api") != 1 {
+		t.Errorf("expected the table drawn once, got:\n%s", out)
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/benaskins/aurelia/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "View and change the daemon config file",
+}
+
+var configGetCmd = &cobra.Command{
+	Use:   "get [key]",
+	Short: "Print a config value, or every known key with no argument",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path := config.DefaultPath()
+		if len(args) == 1 {
+			v, ok, err := config.Get(path, args[0])
+			if err != nil {
+				return err
+			}
+			if ok {
+				fmt.Println(v)
+			}
+			return nil
+		}
+		for _, key := range config.Keys {
+			v, ok, err := config.Get(path, key)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				v = "(default)"
+			}
+			fmt.Printf("%-24s %s\n", key, v)
+		}
+		return nil
+	},
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value>",
+	Short: "Set a config value (an empty value restores the default)",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := config.Set(config.DefaultPath(), args[0], args[1]); err != nil {
+			return err
+		}
+		if liveConfigKeys[args[0]] {
+			fmt.Fprintln(os.Stderr, "A running daemon applies the change automatically.")
+		} else {
+			fmt.Fprintln(os.Stderr, "Restart the daemon to apply the change.")
+		}
+		return nil
+	},
+}
+
+var configPathCmd = &cobra.Command{
+	Use:   "path",
+	Short: "Print the config file path",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Println(config.DefaultPath())
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(configSetCmd)
+	configCmd.AddCommand(configPathCmd)
+	rootCmd.AddCommand(configCmd)
+}
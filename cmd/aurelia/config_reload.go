@@ -0,0 +1,84 @@
+package main
+
+import (
+	"log/slog"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/benaskins/aurelia/internal/config"
+)
+
+// liveConfigKeys are the config keys the daemon applies while running; a
+// change to any other key is logged as needing a restart.
+var liveConfigKeys = map[string]bool{
+	"log_level":              true,
+	"routing_output":         true,
+	"routing_entrypoint":     true,
+	"routing_entrypoint_tls": true,
+}
+
+// routingConfigurer is the part of the daemon config reloads change.
+type routingConfigurer interface {
+	SetRoutingOutput(outputs ...string) error
+	SetRoutingEntryPoints(plain, tls string) error
+}
+
+// configReloader applies config file changes to a running daemon. Keys set
+// by a command-line flag keep the flag's value.
+type configReloader struct {
+	d      routingConfigurer
+	pinned map[string]bool // config keys overridden by flags
+}
+
+// apply applies the reloadable differences between old and cur and logs
+// the keys that changed but only take effect on restart.
+func (r *configReloader) apply(old, cur *config.Config) {
+	if old.LogLevel != cur.LogLevel {
+		if level, err := cur.Level(); err != nil {
+			slog.Error("config reload: ignoring log_level", "error", err)
+		} else {
+			slog.SetLogLoggerLevel(level)
+			slog.Info("config reload: log level changed", "level", level)
+		}
+	}
+
+	if !slices.Equal(old.RoutingOutput, cur.RoutingOutput) && !r.pinned["routing_output"] {
+		if len(cur.RoutingOutput) == 0 {
+			slog.Warn("config reload: routing_output removed; restart the daemon to disable routing")
+		} else if err := r.d.SetRoutingOutput(cur.RoutingOutput...); err != nil {
+			slog.Warn("config reload: routing_output not applied; restart the daemon to apply it", "error", err)
+		} else {
+			slog.Info("config reload: routing output moved", "outputs", cur.RoutingOutput)
+		}
+	}
+
+	if old.RoutingEntryPoint != cur.RoutingEntryPoint || old.RoutingEntryPointTLS != cur.RoutingEntryPointTLS {
+		if err := r.d.SetRoutingEntryPoints(cur.RoutingEntryPoint, cur.RoutingEntryPointTLS); err != nil {
+			slog.Warn("config reload: routing entrypoints not applied", "error", err)
+		} else {
+			slog.Info("config reload: routing entrypoints changed", "plain", cur.RoutingEntryPoint, "tls", cur.RoutingEntryPointTLS)
+		}
+	}
+
+	if keys := restartKeys(old, cur); len(keys) > 0 {
+		slog.Warn("config reload: changes need a daemon restart to take effect", "keys", strings.Join(keys, ", "))
+	}
+}
+
+// restartKeys returns the YAML keys that differ between old and cur and
+// aren't applied while running.
+func restartKeys(old, cur *config.Config) []string {
+	var keys []string
+	ov, cv := reflect.ValueOf(*old), reflect.ValueOf(*cur)
+	for i := range ov.NumField() {
+		key, _, _ := strings.Cut(ov.Type().Field(i).Tag.Get("yaml"), ",")
+		if liveConfigKeys[key] {
+			continue
+		}
+		if !reflect.DeepEqual(ov.Field(i).Interface(), cv.Field(i).Interface()) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
@@ -9,6 +9,7 @@ import (
 	"os"
 	"os/signal"
 	"path/filepath"
+	"slices"
 	"syscall"
 	"time"
 
@@ -30,14 +31,22 @@ var daemonCmd = &cobra.Command{
 
 var (
 	apiAddr       string
-	routingOutput string
+	routingOutput []string
 	daemonForce   bool
+	noHealthWait  bool
+	noWatch       bool
+	specDirs      []string
+	specOverride  bool
 )
 
 func init() {
 	daemonCmd.Flags().StringVar(&apiAddr, "api-addr", "", "Optional TCP address for API (e.g. 127.0.0.1:9090)")
-	daemonCmd.Flags().StringVar(&routingOutput, "routing-output", "", "Path to write Traefik dynamic config (enables routing)")
+	daemonCmd.Flags().StringArrayVar(&routingOutput, "routing-output", nil, "Path to write Traefik dynamic config, or stdout (enables routing, repeatable)")
 	daemonCmd.Flags().BoolVar(&daemonForce, "force", false, "Bypass launchd safety check for manual daemon start")
+	daemonCmd.Flags().BoolVar(&noHealthWait, "no-health-wait", false, "Start services without waiting for dependencies to become healthy")
+	daemonCmd.Flags().StringArrayVar(&specDirs, "spec-dir", nil, "Extra directory to load specs from after ~/.aurelia/services (repeatable)")
+	daemonCmd.Flags().BoolVar(&specOverride, "spec-override", false, "Let a later spec directory replace a same-named service instead of failing")
+	daemonCmd.Flags().BoolVar(&noWatch, "no-watch", false, "Don't reload when spec files change (use aurelia reload)")
 	rootCmd.AddCommand(daemonCmd)
 }
 
@@ -62,13 +71,18 @@ func runDaemon(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return fmt.Errorf("loading config %s: %w", cfgPath, err)
 	}
+	level, err := cfg.Level()
+	if err != nil {
+		return fmt.Errorf("loading config %s: %w", cfgPath, err)
+	}
+	slog.SetLogLoggerLevel(level)
 
 	// CLI flags override config file values
-	if routingOutput == "" && cfg.RoutingOutput != "" {
+	if len(routingOutput) == 0 && len(cfg.RoutingOutput) > 0 {
 		routingOutput = cfg.RoutingOutput
-		slog.Info("routing-output from config file", "path", routingOutput)
-	} else if routingOutput != "" {
-		slog.Info("routing-output from CLI flag", "path", routingOutput)
+		slog.Info("routing-output from config file", "outputs", routingOutput)
+	} else if len(routingOutput) > 0 {
+		slog.Info("routing-output from CLI flag", "outputs", routingOutput)
 	}
 
 	if apiAddr == "" && cfg.APIAddr != "" {
@@ -85,7 +99,7 @@ func runDaemon(cmd *cobra.Command, args []string) error {
 
 	// Set up signal handling
 	sigCh := make(chan os.Signal, 1)
-	signal.Notify(sigCh, syscall.SIGTERM, syscall.SIGINT)
+	signal.Notify(sigCh, syscall.SIGTERM, syscall.SIGINT, syscall.SIGHUP)
 
 	// Create daemon — secrets are injected after OpenBao is running
 	stateDir := filepath.Dir(specDir)
@@ -94,9 +108,16 @@ func runDaemon(cmd *cobra.Command, args []string) error {
 	if secretsErr == nil {
 		opts = append(opts, daemon.WithSecrets(secrets))
 	}
-	if routingOutput != "" {
-		opts = append(opts, daemon.WithRouting(routingOutput))
-		slog.Info("routing enabled", "output", routingOutput)
+	if auditLog, err := newAuditLog(); err == nil {
+		defer auditLog.Close()
+		opts = append(opts, daemon.WithAuditLog(auditLog))
+	} else {
+		slog.Warn("lifecycle audit log unavailable", "error", err)
+	}
+	if len(routingOutput) > 0 {
+		opts = append(opts, daemon.WithRouting(routingOutput...),
+			daemon.WithRoutingEntryPoints(cfg.RoutingEntryPoint, cfg.RoutingEntryPointTLS))
+		slog.Info("routing enabled", "outputs", routingOutput)
 	}
 	// Load TLS config if configured (used for both peer connections and TCP listener)
 	var serverTLS *crypto_tls.Config
@@ -106,6 +127,9 @@ func runDaemon(cmd *cobra.Command, args []string) error {
 		if err != nil {
 			return fmt.Errorf("loading TLS config: %w", err)
 		}
+		if cfg.TLS.RequireClientCert {
+			serverTLS.ClientAuth = crypto_tls.RequireAndVerifyClientCert
+		}
 		// Peer TLS uses the same cert/key as client cert for mTLS
 		peerTLS, err = api.LoadPeerTLSConfig(cfg.TLS.Cert, cfg.TLS.Key, cfg.TLS.CA)
 		if err != nil {
@@ -114,6 +138,66 @@ func runDaemon(cmd *cobra.Command, args []string) error {
 		slog.Info("TLS configured for API and peer connections")
 	}
 
+	if cfg.MaxParallelStarts > 0 {
+		opts = append(opts, daemon.WithMaxParallelStarts(cfg.MaxParallelStarts))
+	}
+	if noHealthWait || (cfg.StartupHealthWait != nil && !*cfg.StartupHealthWait) {
+		opts = append(opts, daemon.WithStartupHealthWait(false))
+	}
+	if cfg.ExternalHealthWait > 0 {
+		opts = append(opts, daemon.WithExternalHealthWait(cfg.ExternalHealthWait))
+	}
+	if extra := append(slices.Clone(cfg.SpecDirs), specDirs...); len(extra) > 0 {
+		for _, dir := range extra {
+			info, err := os.Stat(dir)
+			if err != nil {
+				return fmt.Errorf("spec dir: %w", err)
+			}
+			if !info.IsDir() {
+				return fmt.Errorf("spec dir %s: not a directory", dir)
+			}
+		}
+		opts = append(opts, daemon.WithExtraSpecDirs(extra...))
+		slog.Info("extra spec directories configured", "dirs", extra)
+	}
+	if specOverride || cfg.SpecOverride {
+		opts = append(opts, daemon.WithSpecOverride(true))
+	}
+	if noWatch || (cfg.WatchSpecs != nil && !*cfg.WatchSpecs) {
+		opts = append(opts, daemon.WithSpecWatch(false))
+	}
+	if cfg.WatchDebounce > 0 {
+		opts = append(opts, daemon.WithWatchDebounce(cfg.WatchDebounce))
+	}
+	if cfg.StrictSpecs != nil && !*cfg.StrictSpecs {
+		opts = append(opts, daemon.WithStrictSpecs(false))
+	}
+	if cfg.ContainerPrefix != "" {
+		if err := cfg.ValidateContainerPrefix(); err != nil {
+			return err
+		}
+		opts = append(opts, daemon.WithContainerPrefix(cfg.ContainerPrefix))
+		slog.Info("container name prefix configured", "prefix", cfg.ContainerPrefix)
+	}
+	if len(cfg.GlobalEnv) > 0 {
+		opts = append(opts, daemon.WithGlobalEnv(cfg.GlobalEnv))
+		slog.Info("global environment configured", "vars", len(cfg.GlobalEnv))
+	}
+	if r := cfg.DefaultRestart; r != nil {
+		if err := r.Validate(); err != nil {
+			return fmt.Errorf("default_restart: %w", err)
+		}
+		if r.Policy == "oneshot" {
+			return fmt.Errorf("default_restart: policy oneshot needs a health block, so it can't be a default")
+		}
+		opts = append(opts, daemon.WithDefaultRestart(r))
+		slog.Info("default restart policy configured", "policy", r.Policy)
+	}
+	if len(cfg.PortExclusions) > 0 {
+		opts = append(opts, daemon.WithPortExclusions(cfg.PortExclusions))
+		slog.Info("port exclusions configured", "ports", cfg.PortExclusions)
+	}
+
 	// Wire up spec source for drift detection
 	if specSource := cfg.SpecSourceDir(); specSource != "" {
 		opts = append(opts, daemon.WithSpecSource(specSource))
@@ -201,11 +285,27 @@ func runDaemon(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	// Start GPU observer before the daemon, which consults it for services
+	// that need VRAM
+	gpuObs := gpu.NewObserver(5 * time.Second)
+	gpuObs.Start(ctx)
+	opts = append(opts, daemon.WithGPU(gpuObs))
+
 	d := daemon.NewDaemon(specDir, opts...)
 	if err := d.Start(ctx); err != nil {
 		return fmt.Errorf("starting daemon: %w", err)
 	}
 
+	// Apply config file edits that don't need a restart
+	reloader := &configReloader{d: d, pinned: map[string]bool{
+		"routing_output": cmd.Flags().Changed("routing-output"),
+	}}
+	go func() {
+		if err := config.Watch(ctx, cfgPath, cfg, reloader.apply); err != nil {
+			slog.Warn("config watcher not started", "path", cfgPath, "error", err)
+		}
+	}()
+
 	// If secrets backend wasn't available at startup (e.g., OpenBao not yet
 	// running), wait for it to come up and inject secrets into the daemon.
 	if secretsErr != nil && cfg.OpenBao != nil {
@@ -238,14 +338,13 @@ func runDaemon(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("creating socket dir: %w", err)
 	}
 
-	// Start GPU observer
-	gpuObs := gpu.NewObserver(5 * time.Second)
-	gpuObs.Start(ctx)
-
-	srv := api.NewServer(d, gpuObs)
+	srv := api.NewServer(d, gpuObs, version)
 	if cfg.NodeName != "" {
 		srv.SetNodeName(cfg.NodeName)
 	}
+	if cfg.APIReadTimeout > 0 {
+		srv.SetReadTimeout(cfg.APIReadTimeout)
+	}
 	if cfg.LaminaRoot != "" {
 		srv.SetLaminaRoot(cfg.LaminaRoot)
 		slog.Info("lamina workspace configured", "root", cfg.LaminaRoot)
@@ -293,6 +392,9 @@ func runDaemon(cmd *cobra.Command, args []string) error {
 		if err := srv.GenerateToken(tokenPath); err != nil {
 			return fmt.Errorf("generating API token: %w", err)
 		}
+		if err := srv.LoadTokens(filepath.Join(filepath.Dir(socketPath), "api-tokens")); err != nil {
+			return fmt.Errorf("loading API tokens: %w", err)
+		}
 		if serverTLS != nil {
 			go func() {
 				if err := srv.ListenTLS(apiAddr, serverTLS); err != nil {
@@ -311,17 +413,8 @@ func runDaemon(cmd *cobra.Command, args []string) error {
 
 	slog.Info("aurelia daemon ready")
 
-	// Wait for signal or error
-	var receivedSig os.Signal
-	select {
-	case sig := <-sigCh:
-		slog.Info("received signal, shutting down", "signal", sig)
-		receivedSig = sig
-	case err := <-errCh:
-		if err != nil {
-			slog.Error("API server error", "error", err)
-		}
-	}
+	// Wait for a shutdown signal or error; SIGHUP reloads specs in place
+	receivedSig := waitForShutdown(ctx, d, sigCh, errCh)
 
 	// Graceful shutdown — differentiate SIGTERM (orphan children) vs SIGINT (full teardown)
 	if receivedSig == syscall.SIGTERM {
@@ -342,6 +435,67 @@ func runDaemon(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// reloader is the part of the daemon the signal loop needs.
+type reloader interface {
+	Reload(ctx context.Context) (*daemon.ReloadResult, error)
+}
+
+// waitForShutdown blocks until a shutdown signal arrives or the API server
+// fails, returning the signal (nil on API error). SIGHUP triggers a spec
+// reload and keeps waiting. Reloads run off the signal loop, one at a time,
+// so a slow one doesn't hold up shutdown; SIGHUPs arriving during a reload
+// are folded into one more after it.
+func waitForShutdown(ctx context.Context, d reloader, sigCh <-chan os.Signal, errCh <-chan error) os.Signal {
+	reloadCh := make(chan struct{}, 1)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case <-reloadCh:
+				reloadSpecs(ctx, d)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case sig := <-sigCh:
+			if sig == syscall.SIGHUP {
+				slog.Info("received SIGHUP, reloading specs")
+				select {
+				case reloadCh <- struct{}{}:
+				default: // a reload is already queued
+				}
+				continue
+			}
+			slog.Info("received signal, shutting down", "signal", sig)
+			return sig
+		case err := <-errCh:
+			if err != nil {
+				slog.Error("API server error", "error", err)
+			}
+			return nil
+		}
+	}
+}
+
+// reloadSpecs reloads d's specs and logs the outcome.
+func reloadSpecs(ctx context.Context, d reloader) {
+	result, err := d.Reload(ctx)
+	if err != nil {
+		slog.Error("reload failed", "error", err)
+		return
+	}
+	slog.Info("reload complete",
+		"added", result.Added,
+		"removed", result.Removed,
+		"restarted", result.Restarted,
+		"image_drift", result.ImageDrift)
+}
+
 func defaultSocketPath() (string, error) {
 	dir, err := aureliaHome()
 	if err != nil {
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/benaskins/aurelia/internal/config"
+	"github.com/benaskins/aurelia/internal/daemon"
+)
+
+func TestWaitForShutdownReloadsOnSIGHUP(t *testing.T) {
+	dir := t.TempDir()
+	d := daemon.NewDaemon(dir)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := d.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer d.Stop(5 * time.Second)
+
+	spec := []byte("service:\n  name: added\n  type: native\n  command: \"sleep 10\"\n")
+	if err := os.WriteFile(filepath.Join(dir, "added.yaml"), spec, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGHUP)
+	defer signal.Stop(sigCh)
+
+	done := make(chan os.Signal, 1)
+	go func() {
+		done <- waitForShutdown(ctx, d, sigCh, make(chan error))
+	}()
+
+	if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatalf("sending SIGHUP: %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := d.ServiceState("added"); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("service was not loaded after SIGHUP")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	// SIGHUP must not end the wait; a shutdown signal still does.
+	select {
+	case sig := <-done:
+		t.Fatalf("waitForShutdown returned %v after SIGHUP", sig)
+	default:
+	}
+	sigCh <- syscall.SIGTERM
+	select {
+	case sig := <-done:
+		if sig != syscall.SIGTERM {
+			t.Errorf("expected SIGTERM, got %v", sig)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("waitForShutdown did not return after SIGTERM")
+	}
+}
+
+// slowReloader blocks each reload until release is closed, recording how
+// many ran at once.
+type slowReloader struct {
+	started chan struct{}
+	release chan struct{}
+	running atomic.Int32
+	maxSeen atomic.Int32
+}
+
+func (r *slowReloader) Reload(ctx context.Context) (*daemon.ReloadResult, error) {
+	n := r.running.Add(1)
+	defer r.running.Add(-1)
+	for {
+		m := r.maxSeen.Load()
+		if n <= m || r.maxSeen.CompareAndSwap(m, n) {
+			break
+		}
+	}
+	r.started <- struct{}{}
+	<-r.release
+	return &daemon.ReloadResult{}, nil
+}
+
+func TestWaitForShutdownDoesNotWaitForReload(t *testing.T) {
+	r := &slowReloader{started: make(chan struct{}, 4), release: make(chan struct{})}
+	defer close(r.release)
+	sigCh := make(chan os.Signal, 4)
+	done := make(chan os.Signal, 1)
+	go func() {
+		done <- waitForShutdown(context.Background(), r, sigCh, make(chan error))
+	}()
+
+	sigCh <- syscall.SIGHUP
+	select {
+	case <-r.started:
+	case <-time.After(2 * time.Second):
+		t.Fatal("SIGHUP did not start a reload")
+	}
+	sigCh <- syscall.SIGHUP
+	sigCh <- syscall.SIGHUP
+	sigCh <- syscall.SIGTERM
+	select {
+	case sig := <-done:
+		if sig != syscall.SIGTERM {
+			t.Errorf("expected SIGTERM, got %v", sig)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("SIGTERM waited for the reload to finish")
+	}
+	if n := r.maxSeen.Load(); n != 1 {
+		t.Errorf("expected one reload at a time, saw %d", n)
+	}
+}
+
+type fakeRouting struct{ output chan []string }
+
+func (f *fakeRouting) SetRoutingOutput(outputs ...string) error {
+	f.output <- outputs
+	return nil
+}
+
+func (f *fakeRouting) SetRoutingEntryPoints(plain, tls string) error { return nil }
+
+func TestConfigReloadAppliesLogLevel(t *testing.T) {
+	prev := slog.SetLogLoggerLevel(slog.LevelInfo)
+	t.Cleanup(func() { slog.SetLogLoggerLevel(prev) })
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("routing_output: /tmp/a.yaml\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	routing := &fakeRouting{output: make(chan []string, 1)}
+	reloader := &configReloader{d: routing}
+	started := make(chan struct{})
+	go func() {
+		close(started)
+		config.Watch(ctx, path, cfg, reloader.apply)
+	}()
+	<-started
+	time.Sleep(100 * time.Millisecond) // let the watcher register
+
+	if slog.Default().Enabled(ctx, slog.LevelDebug) {
+		t.Fatal("debug logging enabled before the change")
+	}
+	if err := os.WriteFile(path, []byte("routing_output: [/tmp/b.yaml, stdout]\nlog_level: debug\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case got := <-routing.output:
+		if !slices.Equal(got, []string{"/tmp/b.yaml", "stdout"}) {
+			t.Errorf("routing output = %q, want [/tmp/b.yaml stdout]", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("config change not applied")
+	}
+	if !slog.Default().Enabled(ctx, slog.LevelDebug) {
+		t.Error("expected debug logging enabled after setting log_level: debug")
+	}
+}
+
+func TestConfigReloadKeepsPinnedRoutingOutput(t *testing.T) {
+	routing := &fakeRouting{output: make(chan []string, 1)}
+	reloader := &configReloader{d: routing, pinned: map[string]bool{"routing_output": true}}
+	reloader.apply(&config.Config{RoutingOutput: config.Outputs{"/tmp/a.yaml"}}, &config.Config{RoutingOutput: config.Outputs{"/tmp/b.yaml"}})
+	select {
+	case got := <-routing.output:
+		t.Errorf("flag-set routing output replaced with %q", got)
+	default:
+	}
+}
+
+func TestRestartKeys(t *testing.T) {
+	old := &config.Config{APIAddr: "127.0.0.1:9090", LogLevel: "info"}
+	cur := &config.Config{APIAddr: "127.0.0.1:9191", LogLevel: "debug", MaxParallelStarts: 2}
+	got := restartKeys(old, cur)
+	want := []string{"api_addr", "max_parallel_starts"}
+	if !slices.Equal(got, want) {
+		t.Errorf("restartKeys = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/benaskins/aurelia/internal/config"
+	"github.com/benaskins/aurelia/internal/daemon"
+	"github.com/benaskins/aurelia/internal/driver"
+	"github.com/benaskins/aurelia/internal/keychain"
+	"github.com/benaskins/aurelia/internal/port"
+	"github.com/benaskins/aurelia/internal/routing"
+	"github.com/benaskins/aurelia/internal/spec"
+	"github.com/spf13/cobra"
+)
+
+// doctorTimeout bounds each check that talks to another process.
+const doctorTimeout = 5 * time.Second
+
+// Doctor check outcomes.
+const (
+	doctorPass = "pass"
+	doctorWarn = "warn"
+	doctorFail = "fail"
+)
+
+// doctorCheck is the outcome of one aurelia doctor check.
+type doctorCheck struct {
+	Name   string `json:"name"`
+	Status string `json:"status"` // pass, warn or fail
+	Detail string `json:"detail"`
+}
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check the environment and configuration for common problems",
+	Long: `Run a series of checks for problems that otherwise show up as confusing
+failures: the daemon socket, the spec directories and specs, Docker (when
+any service is a container), the dynamic port range, the secrets backend,
+and the routing output.
+
+Each check prints pass, warn or fail. Exits non-zero if any check fails.`,
+	Args: cobra.NoArgs,
+	RunE: runDoctor,
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+}
+
+func runDoctor(cmd *cobra.Command, args []string) error {
+	jsonOut, _ := cmd.Flags().GetBool("json")
+
+	cfgPath := config.DefaultPath()
+	cfg, err := config.Load(cfgPath)
+	if err != nil {
+		return fmt.Errorf("loading config %s: %w", cfgPath, err)
+	}
+	socketPath, err := defaultSocketPath()
+	if err != nil {
+		return err
+	}
+
+	var checks []doctorCheck
+	daemonCheck := checkDaemonSocket(socketPath)
+	checks = append(checks, daemonCheck)
+
+	var loadOpts []spec.LoadOption
+	if cfg.StrictSpecs != nil && !*cfg.StrictSpecs {
+		loadOpts = append(loadOpts, spec.AllowUnknownFields())
+	}
+	if cfg.SpecOverride {
+		loadOpts = append(loadOpts, spec.OverrideDuplicates())
+	}
+	dirs := append([]string{defaultSpecDir()}, cfg.SpecDirs...)
+	specCheck, specs := checkSpecDirs(dirs, loadOpts...)
+	checks = append(checks, specCheck)
+
+	ctx, cancel := context.WithTimeout(cmd.Context(), doctorTimeout)
+	defer cancel()
+	checks = append(checks, checkDocker(ctx, specs, driver.PingDocker))
+
+	// The running daemon knows its real range; otherwise assume the default
+	portMin, portMax := daemon.DefaultPortMin, daemon.DefaultPortMax
+	if daemonCheck.Status == doctorPass {
+		infoCtx, cancel := context.WithTimeout(cmd.Context(), doctorTimeout)
+		defer cancel()
+		if api, err := apiClient(cmd); err == nil {
+			if info, err := api.Info(infoCtx); err == nil {
+				portMin, portMax = info.PortMin, info.PortMax
+			}
+		}
+	}
+	checks = append(checks, checkPortRange(portMin, portMax, cfg.PortExclusions, specs))
+
+	checks = append(checks, checkSecrets(specs, func() (keychain.Store, error) {
+		dir, err := aureliaHome()
+		if err != nil {
+			return nil, err
+		}
+		return resolveBackend(dir)
+	}))
+	if len(cfg.RoutingOutput) == 0 {
+		checks = append(checks, checkRoutingOutput(""))
+	}
+	for _, output := range cfg.RoutingOutput {
+		checks = append(checks, checkRoutingOutput(output))
+	}
+
+	failed := 0
+	for _, c := range checks {
+		if c.Status == doctorFail {
+			failed++
+		}
+	}
+
+	if jsonOut {
+		if err := printJSON(map[string]any{"checks": checks, "ok": failed == 0}); err != nil {
+			return err
+		}
+	} else {
+		for _, c := range checks {
+			fmt.Printf("%-4s  %-9s %s\n", c.Status, c.Name, c.Detail)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d check(s) failed", failed)
+	}
+	return nil
+}
+
+// checkDaemonSocket reports whether a daemon answers on the API socket. A
+// socket file nobody is listening on is left behind by a daemon that
+// crashed, and fails; no socket at all only warns, as the daemon may simply
+// not be running yet.
+func checkDaemonSocket(socketPath string) doctorCheck {
+	c := doctorCheck{Name: "daemon"}
+	if _, err := os.Stat(socketPath); err != nil {
+		c.Status, c.Detail = doctorWarn, fmt.Sprintf("not running (no socket at %s)", socketPath)
+		return c
+	}
+	conn, err := net.DialTimeout("unix", socketPath, doctorTimeout)
+	if err != nil {
+		c.Status, c.Detail = doctorFail, fmt.Sprintf("stale socket %s: %v (remove it, or restart the daemon)", socketPath, err)
+		return c
+	}
+	conn.Close()
+	c.Status, c.Detail = doctorPass, "reachable at "+socketPath
+	return c
+}
+
+// checkSpecDirs loads every spec directory as the daemon would, returning the
+// specs it found.
+func checkSpecDirs(dirs []string, opts ...spec.LoadOption) (doctorCheck, []*spec.ServiceSpec) {
+	c := doctorCheck{Name: "specs"}
+	for _, dir := range dirs {
+		if _, err := os.ReadDir(dir); err != nil {
+			c.Status, c.Detail = doctorFail, fmt.Sprintf("spec dir not readable: %v", err)
+			return c, nil
+		}
+	}
+	specs, err := spec.LoadDirs(dirs, opts...)
+	if err != nil {
+		c.Status, c.Detail = doctorFail, err.Error()
+		return c, nil
+	}
+	if len(specs) == 0 {
+		c.Status, c.Detail = doctorWarn, fmt.Sprintf("no services defined in %s", strings.Join(dirs, ", "))
+		return c, specs
+	}
+	c.Status, c.Detail = doctorPass, fmt.Sprintf("%d valid service(s) in %d dir(s)", len(specs), len(dirs))
+	return c, specs
+}
+
+// checkDocker pings Docker when any spec is a container service.
+func checkDocker(ctx context.Context, specs []*spec.ServiceSpec, ping func(context.Context) error) doctorCheck {
+	c := doctorCheck{Name: "docker"}
+	n := 0
+	for _, s := range specs {
+		if s.Service.Type == "container" {
+			n++
+		}
+	}
+	if n == 0 {
+		c.Status, c.Detail = doctorPass, "not needed (no container services)"
+		return c
+	}
+	if err := ping(ctx); err != nil {
+		c.Status, c.Detail = doctorFail, fmt.Sprintf("%d container service(s) but Docker is unreachable: %v", n, err)
+		return c
+	}
+	c.Status, c.Detail = doctorPass, fmt.Sprintf("reachable (%d container service(s))", n)
+	return c
+}
+
+// checkPortRange compares the dynamic port range with the services that need
+// a port from it. Each can briefly hold a second port during a blue-green
+// deploy, so a range with room for fewer than twice as many only warns.
+func checkPortRange(portMin, portMax int, exclusions []int, specs []*spec.ServiceSpec) doctorCheck {
+	c := doctorCheck{Name: "ports"}
+	if portMin <= 0 || portMax > 65535 || portMin > portMax {
+		c.Status, c.Detail = doctorFail, fmt.Sprintf("invalid port range %d-%d", portMin, portMax)
+		return c
+	}
+	capacity := port.NewAllocator(portMin, portMax, exclusions...).Capacity()
+	dynamic := 0
+	for _, s := range specs {
+		if s.NeedsDynamicPort() {
+			dynamic++
+		}
+	}
+	detail := fmt.Sprintf("%d port(s) in %d-%d for %d dynamic-port service(s)", capacity, portMin, portMax, dynamic)
+	switch {
+	case capacity < dynamic:
+		c.Status = doctorFail
+	case capacity < 2*dynamic:
+		c.Status, detail = doctorWarn, detail+"; too few for every one to deploy at once"
+	default:
+		c.Status = doctorPass
+	}
+	c.Detail = detail
+	return c
+}
+
+// checkSecrets opens the secrets backend and lists its keys. A failure only
+// warns unless some spec needs secrets.
+func checkSecrets(specs []*spec.ServiceSpec, open func() (keychain.Store, error)) doctorCheck {
+	c := doctorCheck{Name: "secrets"}
+	var users []string
+	for _, s := range specs {
+		if len(s.Secrets) > 0 || len(s.DockerSecrets) > 0 {
+			users = append(users, s.Service.Name)
+		}
+	}
+	slices.Sort(users)
+
+	store, err := open()
+	if err == nil {
+		_, err = store.List()
+	}
+	switch {
+	case err == nil:
+		c.Status, c.Detail = doctorPass, "secret store accessible"
+	case len(users) > 0:
+		c.Status, c.Detail = doctorFail, fmt.Sprintf("secret store not accessible, needed by %s: %v", strings.Join(users, ", "), err)
+	default:
+		c.Status, c.Detail = doctorWarn, fmt.Sprintf("secret store not accessible: %v", err)
+	}
+	return c
+}
+
+// checkRoutingOutput checks the Traefik config file can be written, by
+// creating and removing a temporary file beside it.
+func checkRoutingOutput(path string) doctorCheck {
+	c := doctorCheck{Name: "routing"}
+	switch path {
+	case "":
+		c.Status, c.Detail = doctorPass, "not configured"
+		return c
+	case routing.StdoutOutput:
+		c.Status, c.Detail = doctorPass, "written to stdout"
+		return c
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), ".aurelia-doctor-*")
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			err = fmt.Errorf("directory %s does not exist", filepath.Dir(path))
+		}
+		c.Status, c.Detail = doctorFail, fmt.Sprintf("can't write %s: %v", path, err)
+		return c
+	}
+	f.Close()
+	os.Remove(f.Name())
+	c.Status, c.Detail = doctorPass, "writable: "+path
+	return c
+}
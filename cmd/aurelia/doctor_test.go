@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/benaskins/aurelia/internal/keychain"
+	"github.com/benaskins/aurelia/internal/spec"
+)
+
+func TestCheckDaemonSocket(t *testing.T) {
+	dir, err := os.MkdirTemp("", "doctor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	sock := filepath.Join(dir, "aurelia.sock") // short enough for a unix socket
+
+	if c := checkDaemonSocket(sock); c.Status != doctorWarn {
+		t.Errorf("no socket: expected warn, got %+v", c)
+	}
+
+	ln, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := checkDaemonSocket(sock); c.Status != doctorPass {
+		t.Errorf("listening: expected pass, got %+v", c)
+	}
+
+	// Closing the listener removes the socket, so leave a plain file behind
+	ln.Close()
+	if err := os.WriteFile(sock, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if c := checkDaemonSocket(sock); c.Status != doctorFail || !strings.Contains(c.Detail, "stale") {
+		t.Errorf("stale socket: expected fail, got %+v", c)
+	}
+}
+
+func TestCheckSpecDirs(t *testing.T) {
+	dir := t.TempDir()
+	if c, _ := checkSpecDirs([]string{dir}); c.Status != doctorWarn {
+		t.Errorf("empty dir: expected warn, got %+v", c)
+	}
+
+	writeFile := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile("api.yaml", "service:\n  name: api\n  type: native\n  command: sleep 30\n")
+	c, specs := checkSpecDirs([]string{dir})
+	if c.Status != doctorPass || len(specs) != 1 {
+		t.Errorf("valid spec: expected pass with 1 spec, got %+v, %d specs", c, len(specs))
+	}
+
+	writeFile("bad.yaml", "service:\n  name: bad\n  type: bogus\n")
+	if c, _ := checkSpecDirs([]string{dir}); c.Status != doctorFail {
+		t.Errorf("invalid spec: expected fail, got %+v", c)
+	}
+
+	if c, _ := checkSpecDirs([]string{filepath.Join(dir, "missing")}); c.Status != doctorFail {
+		t.Errorf("missing dir: expected fail, got %+v", c)
+	}
+}
+
+func TestCheckDocker(t *testing.T) {
+	native := &spec.ServiceSpec{Service: spec.Service{Name: "api", Type: "native"}}
+	container := &spec.ServiceSpec{Service: spec.Service{Name: "db", Type: "container"}}
+	down := func(context.Context) error { return errors.New("connection refused") }
+	up := func(context.Context) error { return nil }
+
+	if c := checkDocker(context.Background(), []*spec.ServiceSpec{native}, down); c.Status != doctorPass {
+		t.Errorf("no containers: expected pass without pinging, got %+v", c)
+	}
+	if c := checkDocker(context.Background(), []*spec.ServiceSpec{native, container}, down); c.Status != doctorFail {
+		t.Errorf("docker down: expected fail, got %+v", c)
+	}
+	if c := checkDocker(context.Background(), []*spec.ServiceSpec{container}, up); c.Status != doctorPass {
+		t.Errorf("docker up: expected pass, got %+v", c)
+	}
+}
+
+func TestCheckPortRange(t *testing.T) {
+	var specs []*spec.ServiceSpec
+	for range 3 {
+		specs = append(specs, &spec.ServiceSpec{Network: &spec.Network{}})
+	}
+	specs = append(specs, &spec.ServiceSpec{Network: &spec.Network{Port: 8080}})
+
+	tests := []struct {
+		name       string
+		min, max   int
+		exclusions []int
+		want       string
+	}{
+		{"roomy", 20000, 32000, nil, doctorPass},
+		{"exactly double", 20000, 20005, nil, doctorPass},
+		{"no room to deploy", 20000, 20003, nil, doctorWarn},
+		{"too small", 20000, 20001, nil, doctorFail},
+		{"too small after exclusions", 20000, 20003, []int{20000, 20001}, doctorFail},
+		{"inverted", 20000, 19000, nil, doctorFail},
+	}
+	for _, tt := range tests {
+		if c := checkPortRange(tt.min, tt.max, tt.exclusions, specs); c.Status != tt.want {
+			t.Errorf("%s: expected %s, got %+v", tt.name, tt.want, c)
+		}
+	}
+}
+
+func TestCheckSecrets(t *testing.T) {
+	plain := &spec.ServiceSpec{Service: spec.Service{Name: "api"}}
+	withSecrets := &spec.ServiceSpec{
+		Service: spec.Service{Name: "db"},
+		Secrets: map[string]spec.SecretRef{"PASSWORD": {Secret: "db/password"}},
+	}
+	broken := func() (keychain.Store, error) { return nil, errors.New("keychain locked") }
+
+	if c := checkSecrets([]*spec.ServiceSpec{plain}, broken); c.Status != doctorWarn {
+		t.Errorf("unused store: expected warn, got %+v", c)
+	}
+	if c := checkSecrets([]*spec.ServiceSpec{plain, withSecrets}, broken); c.Status != doctorFail || !strings.Contains(c.Detail, "db") {
+		t.Errorf("needed store: expected fail naming db, got %+v", c)
+	}
+	working := func() (keychain.Store, error) { return keychain.NewMemoryStore(), nil }
+	if c := checkSecrets([]*spec.ServiceSpec{withSecrets}, working); c.Status != doctorPass {
+		t.Errorf("working store: expected pass, got %+v", c)
+	}
+}
+
+func TestCheckRoutingOutput(t *testing.T) {
+	if c := checkRoutingOutput(""); c.Status != doctorPass {
+		t.Errorf("unset: expected pass, got %+v", c)
+	}
+	dir := t.TempDir()
+	if c := checkRoutingOutput(filepath.Join(dir, "aurelia.yaml")); c.Status != doctorPass {
+		t.Errorf("writable: expected pass, got %+v", c)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("expected the probe file to be removed, found %d entries", len(entries))
+	}
+	if c := checkRoutingOutput(filepath.Join(dir, "missing", "aurelia.yaml")); c.Status != doctorFail {
+		t.Errorf("missing dir: expected fail, got %+v", c)
+	}
+}
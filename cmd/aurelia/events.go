@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/benaskins/aurelia/internal/client"
+	"github.com/benaskins/aurelia/internal/daemon"
+	"github.com/spf13/cobra"
+)
+
+var eventsCmd = &cobra.Command{
+	Use:   "events",
+	Short: "Show what the daemon has been doing",
+	Long: `Show the daemon's recent lifecycle events: service starts, exits,
+restarts and stops, health transitions, reloads and deploys.
+
+The daemon keeps the most recent events in memory; they do not survive a
+daemon restart. With --follow, new events are printed as they happen.
+
+Examples:
+  aurelia events
+  aurelia events --service api -n 20
+  aurelia events -f`,
+	Args: cobra.NoArgs,
+	RunE: runEvents,
+}
+
+func init() {
+	eventsCmd.Flags().IntP("lines", "n", 50, "number of recent events to show")
+	eventsCmd.Flags().String("service", "", "only show events for this service")
+	eventsCmd.Flags().BoolP("follow", "f", false, "keep printing new events as they happen")
+	rootCmd.AddCommand(eventsCmd)
+}
+
+func runEvents(cmd *cobra.Command, _ []string) error {
+	jsonOut, _ := cmd.Flags().GetBool("json")
+	n, _ := cmd.Flags().GetInt("lines")
+	service, _ := cmd.Flags().GetString("service")
+	follow, _ := cmd.Flags().GetBool("follow")
+
+	api, err := apiClient(cmd)
+	if err != nil {
+		return err
+	}
+	events, err := api.Events(cmd.Context(), service, n)
+	if err != nil {
+		return err
+	}
+
+	if jsonOut && !follow {
+		return printJSON(map[string]any{"events": events})
+	}
+	for _, ev := range events {
+		printEvent(ev, jsonOut)
+	}
+	if !follow {
+		return nil
+	}
+	return followEvents(cmd.Context(), api, service, jsonOut)
+}
+
+// followEvents prints events from the daemon's event stream until it closes.
+func followEvents(ctx context.Context, api *client.Client, service string, jsonOut bool) error {
+	path := "/v1/events/stream"
+	if service != "" {
+		path += "?" + url.Values{"service": {service}}.Encode()
+	}
+	// The stream stays open until interrupted
+	resp, err := api.Stream(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		var ev daemon.Event
+		if err := json.Unmarshal([]byte(data), &ev); err != nil {
+			return fmt.Errorf("reading event stream: %w", err)
+		}
+		printEvent(ev, jsonOut)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("reading event stream: %w", err)
+	}
+	return fmt.Errorf("event stream closed by daemon")
+}
+
+// printEvent prints one event, as a line of JSON when jsonOut is set.
+func printEvent(ev daemon.Event, jsonOut bool) {
+	if jsonOut {
+		data, _ := json.Marshal(ev)
+		fmt.Println(string(data))
+		return
+	}
+	service := ev.Service
+	if service == "" {
+		service = "-"
+	}
+	line := fmt.Sprintf("%s  %-20s %-14s", ev.Time.Local().Format("2006-01-02 15:04:05"), service, ev.Type)
+	if ev.Detail != "" {
+		line += " " + ev.Detail
+	}
+	fmt.Println(strings.TrimRight(line, " "))
+}
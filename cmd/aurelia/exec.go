@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var execCmd = &cobra.Command{
+	Use:   "exec <service> -- <command> [args...]",
+	Short: "Run a command in a service's context",
+	Long: `Run a command in the context of a managed service, streaming its output.
+
+For container services the command runs inside the running container
+(like docker exec). For native services it runs on this host with the
+service's environment (including PORT and secrets) and working directory.
+Only available against the local daemon.
+
+Examples:
+  aurelia exec api -- env
+  aurelia exec postgres -- psql -U postgres -c 'select 1'`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: runExec,
+}
+
+func init() {
+	rootCmd.AddCommand(execCmd)
+}
+
+func runExec(cmd *cobra.Command, args []string) error {
+	name, argv := args[0], args[1:]
+
+	api, err := apiClient(cmd)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(map[string]any{"argv": argv})
+	if err != nil {
+		return err
+	}
+
+	// The command's runtime is unbounded; output streams until it exits
+	resp, err := api.Stream(cmd.Context(), http.MethodPost, "/v1/services/"+url.PathEscape(name)+"/exec",
+		bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if _, err := io.Copy(os.Stdout, resp.Body); err != nil {
+		return fmt.Errorf("reading output: %w", err)
+	}
+
+	// Trailers are only populated once the body has been fully read
+	if msg := resp.Trailer.Get("X-Exec-Error"); msg != "" {
+		return fmt.Errorf("exec failed: %s", msg)
+	}
+	if code := resp.Trailer.Get("X-Exit-Code"); code != "0" {
+		return fmt.Errorf("command exited %s", code)
+	}
+	return nil
+}
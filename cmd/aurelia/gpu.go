@@ -1,7 +1,9 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/benaskins/aurelia/internal/gpu"
 	"github.com/spf13/cobra"
@@ -10,8 +12,13 @@ import (
 var gpuCmd = &cobra.Command{
 	Use:   "gpu",
 	Short: "Show GPU status",
+	Long: "Shows the local GPU's current state. With --watch, prints the daemon's recent\n" +
+		"GPU samples and then each new one as it is taken.",
 	RunE: func(cmd *cobra.Command, args []string) error {
 		jsonOut, _ := cmd.Flags().GetBool("json")
+		if watch, _ := cmd.Flags().GetBool("watch"); watch {
+			return watchGPU(cmd, jsonOut)
+		}
 		info := gpu.QueryNow()
 
 		if jsonOut {
@@ -33,6 +40,51 @@ var gpuCmd = &cobra.Command{
 	},
 }
 
+// watchGPU prints the daemon's GPU history, then polls for new samples at
+// the observer's interval until interrupted.
+func watchGPU(cmd *cobra.Command, jsonOut bool) error {
+	api, err := apiClient(cmd)
+	if err != nil {
+		return err
+	}
+	ctx := cmd.Context()
+
+	var last time.Time
+	for {
+		series, err := api.GPUHistory(ctx, last)
+		if err != nil {
+			return err
+		}
+		for _, info := range series.Samples {
+			printGPUSample(info, jsonOut)
+			last = info.Timestamp
+		}
+
+		interval, err := time.ParseDuration(series.Interval)
+		if err != nil || interval <= 0 {
+			interval = 5 * time.Second
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(interval):
+		}
+	}
+}
+
+// printGPUSample prints one sample, as a line of JSON when jsonOut is set.
+func printGPUSample(info gpu.Info, jsonOut bool) {
+	if jsonOut {
+		data, _ := json.Marshal(info)
+		fmt.Println(string(data))
+		return
+	}
+	fmt.Printf("%s  %5.1f / %.1f GB  %5.1f%%  %s\n",
+		info.Timestamp.Local().Format("2006-01-02 15:04:05"),
+		info.AllocatedGB(), info.RecommendedMaxGB(), info.UsagePercent, info.ThermalState)
+}
+
 func init() {
+	gpuCmd.Flags().BoolP("watch", "w", false, "print the daemon's recent GPU samples and follow new ones")
 	rootCmd.AddCommand(gpuCmd)
 }
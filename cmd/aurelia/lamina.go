@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"os"
 
 	"github.com/benaskins/aurelia/internal/node"
@@ -41,7 +42,7 @@ func runLamina(cmd *cobra.Command, args []string) error {
 
 	if remote == nil {
 		// Local execution — call the local daemon API
-		resp, err := postLaminaLocal(args)
+		resp, err := postLaminaLocal(cmd, args)
 		if err != nil {
 			return err
 		}
@@ -56,8 +57,8 @@ func runLamina(cmd *cobra.Command, args []string) error {
 	return printLaminaResponse(resp, jsonOut)
 }
 
-func postLaminaLocal(args []string) (*node.LaminaResponse, error) {
-	client, err := apiClient()
+func postLaminaLocal(cmd *cobra.Command, args []string) (*node.LaminaResponse, error) {
+	api, err := apiClient(cmd)
 	if err != nil {
 		return nil, err
 	}
@@ -67,16 +68,9 @@ func postLaminaLocal(args []string) (*node.LaminaResponse, error) {
 		return nil, err
 	}
 
-	resp, err := client.Post("http://aurelia/v1/lamina", "application/json",
-		bytes.NewReader(body))
-	if err != nil {
-		return nil, fmt.Errorf("connecting to daemon: %w (is aurelia daemon running?)", err)
-	}
-	defer resp.Body.Close()
-
 	var result node.LaminaResponse
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("decoding response: %w", err)
+	if err := api.Do(cmd.Context(), http.MethodPost, "/v1/lamina", bytes.NewReader(body), &result); err != nil {
+		return nil, err
 	}
 	return &result, nil
 }
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/benaskins/aurelia/internal/client"
+	"github.com/benaskins/aurelia/internal/logbuf"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var logsCmd = &cobra.Command{
+	Use:   "logs [service...]",
+	Short: "Show recent log output for services",
+	Long: `Show the recent log output of one or more services.
+
+With several services, or --all, each line is prefixed with the service it
+came from (--prefix=false turns that off, --prefix turns it on for a single
+service). Prefixes are coloured when writing to a terminal, unless NO_COLOR
+is set.
+
+With --follow, lines keep being printed as the services write them,
+interleaved in the order they arrive, until interrupted.
+
+With --json, each line is printed as a JSON object with the service, the
+time it was written and the line: {"service":"api","ts":"...","line":"..."}.
+
+Examples:
+  aurelia logs api
+  aurelia logs api worker -f
+  aurelia logs --all -f --grep error`,
+	RunE: runLogs,
+}
+
+func init() {
+	logsCmd.Flags().IntP("lines", "n", 50, "number of lines to show")
+	logsCmd.Flags().String("grep", "", "only show lines containing this substring")
+	logsCmd.Flags().Bool("regex", false, "treat --grep as a regular expression")
+	logsCmd.Flags().String("since", "", "only show lines from this long ago (e.g. 15m) or since an RFC 3339 time")
+	logsCmd.Flags().Bool("all", false, "show logs from every service")
+	logsCmd.Flags().BoolP("follow", "f", false, "keep printing new lines as they are written")
+	logsCmd.Flags().Bool("prefix", false, "prefix each line with the service name (default on for several services)")
+	rootCmd.AddCommand(logsCmd)
+}
+
+func runLogs(cmd *cobra.Command, args []string) error {
+	jsonOut, _ := cmd.Flags().GetBool("json")
+	n, _ := cmd.Flags().GetInt("lines")
+	grep, _ := cmd.Flags().GetString("grep")
+	regex, _ := cmd.Flags().GetBool("regex")
+	since, _ := cmd.Flags().GetString("since")
+	all, _ := cmd.Flags().GetBool("all")
+	follow, _ := cmd.Flags().GetBool("follow")
+	if all && len(args) > 0 {
+		return fmt.Errorf("--all can't be combined with service names")
+	}
+	if !all && len(args) == 0 {
+		return fmt.Errorf("name at least one service, or use --all")
+	}
+
+	remote, err := resolveNodeClient(cmd)
+	if err != nil {
+		return err
+	}
+	if remote != nil {
+		if grep != "" || since != "" {
+			return fmt.Errorf("--grep and --since are not supported with --node")
+		}
+		if all || follow || len(args) > 1 {
+			return fmt.Errorf("--all, --follow and several services are not supported with --node")
+		}
+		if jsonOut {
+			lines, err := remote.LogEntries(args[0], n)
+			if err != nil {
+				return err
+			}
+			for _, line := range lines {
+				fmt.Println(jsonLogLine(args[0], line))
+			}
+			return nil
+		}
+		lines, err := remote.Logs(args[0], n)
+		if err != nil {
+			return err
+		}
+		for _, line := range lines {
+			fmt.Println(line)
+		}
+		return nil
+	}
+
+	api, err := apiClient(cmd)
+	if err != nil {
+		return err
+	}
+	services := args
+	if all {
+		states, err := api.ListServices(cmd.Context())
+		if err != nil {
+			return err
+		}
+		services = nil
+		for _, st := range states {
+			services = append(services, st.Name)
+		}
+		sort.Strings(services)
+	}
+	opts := client.LogsOptions{
+		Lines: n,
+		Grep:  grep,
+		Regex: regex,
+		Since: since,
+	}
+
+	recent := make(map[string][]logbuf.Line, len(services))
+	for _, name := range services {
+		lines, err := api.LogEntries(cmd.Context(), name, opts)
+		if err != nil {
+			return err
+		}
+		recent[name] = lines
+	}
+
+	// A single service's lines print as they are unless --prefix is given
+	prefix := len(services) > 1
+	if cmd.Flags().Changed("prefix") {
+		prefix, _ = cmd.Flags().GetBool("prefix")
+	}
+	format := newLogPrefixer(services, prefix, colorOutput()).format
+	if jsonOut {
+		format = jsonLogLine
+	}
+	for _, name := range services {
+		for _, line := range recent[name] {
+			fmt.Println(format(name, line))
+		}
+	}
+	if !follow {
+		return nil
+	}
+
+	return mergeLogs(cmd.Context(), os.Stdout, services, format, func(ctx context.Context, name string, emit func(logbuf.Line)) error {
+		return streamLogs(ctx, api, name, opts, emit)
+	})
+}
+
+// logSource delivers a service's log lines to emit until ctx is done or the
+// stream ends.
+type logSource func(ctx context.Context, service string, emit func(line logbuf.Line)) error
+
+// mergeLogs runs source for every service at once and writes their lines to
+// w as they arrive, each through format, until all the sources have
+// returned. Lines from one service stay in order. A source that returns
+// because ctx is done is not an error.
+func mergeLogs(ctx context.Context, w io.Writer, services []string, format func(service string, line logbuf.Line) string, source logSource) error {
+	type logLine struct {
+		service string
+		line    logbuf.Line
+	}
+	merged := make(chan logLine)
+	errs := make([]error, len(services))
+
+	var wg sync.WaitGroup
+	for i, name := range services {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := source(ctx, name, func(line logbuf.Line) { merged <- logLine{name, line} })
+			if err != nil && ctx.Err() == nil {
+				errs[i] = fmt.Errorf("%s: %w", name, err)
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(merged)
+	}()
+
+	for l := range merged {
+		fmt.Fprintln(w, format(l.service, l.line))
+	}
+	return errors.Join(errs...)
+}
+
+// streamLogs reads a service's log stream from the daemon, passing each line
+// to emit with the time it was written, until the stream closes or ctx is
+// done.
+func streamLogs(ctx context.Context, api *client.Client, name string, opts client.LogsOptions, emit func(logbuf.Line)) error {
+	params := url.Values{"timestamps": {"true"}}
+	if opts.Grep != "" {
+		params.Set("grep", opts.Grep)
+		if opts.Regex {
+			params.Set("regex", "true")
+		}
+	}
+	path := "/v1/services/" + url.PathEscape(name) + "/logs/stream?" + params.Encode()
+	// The stream stays open until interrupted
+	resp, err := api.Stream(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		var line logbuf.Line
+		if err := json.Unmarshal([]byte(data), &line); err != nil {
+			return fmt.Errorf("reading log stream: %w", err)
+		}
+		emit(line)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("reading log stream: %w", err)
+	}
+	return fmt.Errorf("log stream closed by daemon")
+}
+
+// logColors are the ANSI colours given to service prefixes in turn.
+var logColors = []string{"36", "33", "32", "35", "34", "96", "93", "92", "95", "94"}
+
+// logPrefixer formats log lines for display, prefixed with the service name
+// padded to the longest name so the lines line up.
+type logPrefixer struct {
+	prefix bool
+	width  int
+	colors map[string]string // service to ANSI colour, nil for no colour
+}
+
+func newLogPrefixer(services []string, prefix, color bool) *logPrefixer {
+	p := &logPrefixer{prefix: prefix}
+	for _, name := range services {
+		p.width = max(p.width, len(name))
+	}
+	if color {
+		p.colors = make(map[string]string, len(services))
+		for i, name := range services {
+			p.colors[name] = logColors[i%len(logColors)]
+		}
+	}
+	return p
+}
+
+func (p *logPrefixer) format(service string, line logbuf.Line) string {
+	if !p.prefix {
+		return line.Text
+	}
+	prefix := fmt.Sprintf("%-*s |", p.width, service)
+	if c, ok := p.colors[service]; ok {
+		prefix = "\033[" + c + "m" + prefix + "\033[0m"
+	}
+	return prefix + " " + line.Text
+}
+
+// logEnvelope is the JSON object --json prints for each log line.
+type logEnvelope struct {
+	Service string    `json:"service"`
+	Time    time.Time `json:"ts"`
+	Line    string    `json:"line"`
+}
+
+// jsonLogLine formats a line as a JSON object naming its service and when it
+// was written.
+func jsonLogLine(service string, line logbuf.Line) string {
+	data, _ := json.Marshal(logEnvelope{Service: service, Time: line.Time, Line: line.Text})
+	return string(data)
+}
+
+// colorOutput reports whether stdout is a terminal that should get colour.
+func colorOutput() bool {
+	return os.Getenv("NO_COLOR") == "" && term.IsTerminal(int(os.Stdout.Fd()))
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/benaskins/aurelia/internal/logbuf"
+)
+
+func TestMergeLogs(t *testing.T) {
+	t.Parallel()
+
+	// Mock sources: api logs three lines, db two and then fails
+	source := func(ctx context.Context, service string, emit func(logbuf.Line)) error {
+		switch service {
+		case "api":
+			for i := 1; i <= 3; i++ {
+				emit(logbuf.Line{Text: fmt.Sprintf("request %d", i)})
+			}
+			return nil
+		case "db":
+			emit(logbuf.Line{Text: "ready"})
+			emit(logbuf.Line{Text: "checkpoint"})
+			return errors.New("stream closed")
+		}
+		return nil
+	}
+
+	var out bytes.Buffer
+	p := newLogPrefixer([]string{"api", "db"}, true, false)
+	err := mergeLogs(context.Background(), &out, []string{"api", "db"}, p.format, source)
+	if err == nil || !strings.Contains(err.Error(), "db: stream closed") {
+		t.Errorf("expected db's error, got %v", err)
+	}
+
+	got := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(got) != 5 {
+		t.Fatalf("expected 5 lines, got %q", got)
+	}
+	// Each service's lines keep their order, whatever the interleaving
+	var api, db []string
+	for _, line := range got {
+		switch {
+		case strings.HasPrefix(line, "api | "):
+			api = append(api, line)
+		case strings.HasPrefix(line, "db  | "):
+			db = append(db, line)
+		default:
+			t.Errorf("unexpected line %q", line)
+		}
+	}
+	if !slices.Equal(api, []string{"api | request 1", "api | request 2", "api | request 3"}) {
+		t.Errorf("api lines out of order: %q", api)
+	}
+	if !slices.Equal(db, []string{"db  | ready", "db  | checkpoint"}) {
+		t.Errorf("db lines out of order: %q", db)
+	}
+}
+
+func TestMergeLogsCancelled(t *testing.T) {
+	t.Parallel()
+
+	// Sources that run until cancelled end the merge without an error
+	ctx, cancel := context.WithCancel(context.Background())
+	source := func(ctx context.Context, service string, emit func(logbuf.Line)) error {
+		emit(logbuf.Line{Text: "hello"})
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	lines := make(chan string, 2)
+	format := func(service string, line logbuf.Line) string {
+		lines <- service
+		return line.Text
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- mergeLogs(ctx, &bytes.Buffer{}, []string{"a", "b"}, format, source)
+	}()
+	<-lines
+	<-lines
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("expected no error after cancel, got %v", err)
+	}
+}
+
+func TestLogPrefixer(t *testing.T) {
+	t.Parallel()
+	services := []string{"api", "postgres"}
+	hi := logbuf.Line{Text: "hi"}
+
+	if got := newLogPrefixer(services, false, true).format("api", hi); got != "hi" {
+		t.Errorf("without prefix got %q", got)
+	}
+	if got := newLogPrefixer(services, true, false).format("api", hi); got != "api      | hi" {
+		t.Errorf("padded prefix got %q", got)
+	}
+
+	p := newLogPrefixer(services, true, true)
+	api, pg := p.format("api", hi), p.format("postgres", hi)
+	if api != "\033[36mapi      |\033[0m hi" {
+		t.Errorf("coloured prefix got %q", api)
+	}
+	if pg[:5] == api[:5] {
+		t.Errorf("expected different colours per service, got %q and %q", api, pg)
+	}
+}
+
+func TestJSONLogLine(t *testing.T) {
+	t.Parallel()
+
+	ts := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	out := jsonLogLine("api", logbuf.Line{Time: ts, Text: `GET /health "ok"`})
+
+	var got map[string]any
+	if err := json.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("expected a well-formed JSON object, got %q: %v", out, err)
+	}
+	want := map[string]any{"service": "api", "ts": "2025-01-01T12:00:00Z", "line": `GET /health "ok"`}
+	if len(got) != len(want) {
+		t.Errorf("got fields %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s = %v, want %v", k, got[k], v)
+		}
+	}
+	if strings.Contains(out, "\n") {
+		t.Errorf("expected one line per entry, got %q", out)
+	}
+}
@@ -23,6 +23,10 @@ containers with dependency ordering, health checks, and automatic restarts.
 func init() {
 	rootCmd.PersistentFlags().Bool("json", false, "Output in JSON format")
 	rootCmd.PersistentFlags().String("node", "", "Target a specific node for the command")
+	rootCmd.PersistentFlags().String("host", "", "Daemon TCP API address (host:port) to use instead of the local socket")
+	rootCmd.PersistentFlags().String("token", "", "Bearer token for --host")
+	rootCmd.PersistentFlags().String("token-file", "", "File containing the bearer token for --host")
+	rootCmd.MarkFlagsMutuallyExclusive("token", "token-file")
 }
 
 func printJSON(v any) error {
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/benaskins/aurelia/internal/daemon"
+	"github.com/spf13/cobra"
+)
+
+var psCmd = &cobra.Command{
+	Use:   "ps",
+	Short: "List services in a script-friendly format",
+	Long: `List the local daemon's services, one line per service, for use in scripts.
+
+--format takes either a comma-separated list of columns, printed separated by
+tabs under a header line (drop it with --no-header), or a Go template executed
+for each service's state, e.g. '{{.Name}} {{.Port}}'. Template fields are those
+of the JSON from 'aurelia status --json', with Go names (Name, State, PID, ...).
+
+Columns: ` + strings.Join(psColumnNames(), ", ") + `
+
+Examples:
+  aurelia ps
+  aurelia ps --format name,port --no-header
+  aurelia ps --format '{{.Name}}={{.State}}'`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, _ := cmd.Flags().GetString("format")
+		noHeader, _ := cmd.Flags().GetBool("no-header")
+
+		api, err := apiClient(cmd)
+		if err != nil {
+			return err
+		}
+		states, err := api.ListServices(cmd.Context())
+		if err != nil {
+			return err
+		}
+		return renderPS(os.Stdout, states, format, !noHeader)
+	},
+}
+
+func init() {
+	psCmd.Flags().String("format", "name,state,health,pid,port", "comma-separated columns, or a Go template")
+	psCmd.Flags().Bool("no-header", false, "omit the header line for a column list")
+	rootCmd.AddCommand(psCmd)
+}
+
+// psColumn is one column 'aurelia ps' can print. Empty values print as "-",
+// like in 'aurelia status', so every line has the same number of fields.
+type psColumn struct {
+	name  string
+	value func(daemon.ServiceState) string
+}
+
+var psColumns = []psColumn{
+	{"name", func(s daemon.ServiceState) string { return s.Name }},
+	{"node", func(s daemon.ServiceState) string { return s.Node }},
+	{"type", func(s daemon.ServiceState) string { return s.Type }},
+	{"state", func(s daemon.ServiceState) string { return string(s.State) }},
+	{"reason", func(s daemon.ServiceState) string { return s.StateReason }},
+	{"health", func(s daemon.ServiceState) string { return string(s.Health) }},
+	{"pid", func(s daemon.ServiceState) string { return psInt(s.PID) }},
+	{"port", func(s daemon.ServiceState) string { return psInt(s.Port) }},
+	{"uptime", func(s daemon.ServiceState) string { return s.Uptime }},
+	{"restarts", func(s daemon.ServiceState) string { return strconv.Itoa(s.RestartCount) }},
+	{"exit_code", func(s daemon.ServiceState) string { return strconv.Itoa(s.LastExitCode) }},
+}
+
+func psInt(n int) string {
+	if n <= 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
+
+func psColumnNames() []string {
+	names := make([]string, len(psColumns))
+	for i, c := range psColumns {
+		names[i] = c.name
+	}
+	return names
+}
+
+// renderPS writes one line per state to out, as the Go template format when
+// it contains an action, otherwise as format's comma-separated columns
+// separated by tabs, under a header line if header is set.
+func renderPS(out io.Writer, states []daemon.ServiceState, format string, header bool) error {
+	if strings.Contains(format, "{{") {
+		tmpl, err := template.New("ps").Option("missingkey=error").Parse(format)
+		if err != nil {
+			return fmt.Errorf("parsing --format template: %w", err)
+		}
+		for _, s := range states {
+			if err := tmpl.Execute(out, s); err != nil {
+				return fmt.Errorf("executing --format template: %w", err)
+			}
+			fmt.Fprintln(out)
+		}
+		return nil
+	}
+
+	var cols []psColumn
+	for _, name := range strings.Split(format, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		found := false
+		for _, c := range psColumns {
+			if c.name == name {
+				cols = append(cols, c)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("unknown column %q (available: %s)", name, strings.Join(psColumnNames(), ", "))
+		}
+	}
+
+	fields := make([]string, len(cols))
+	if header {
+		for i, c := range cols {
+			fields[i] = strings.ToUpper(c.name)
+		}
+		fmt.Fprintln(out, strings.Join(fields, "\t"))
+	}
+	for _, s := range states {
+		for i, c := range cols {
+			if fields[i] = c.value(s); fields[i] == "" {
+				fields[i] = "-"
+			}
+		}
+		fmt.Fprintln(out, strings.Join(fields, "\t"))
+	}
+	return nil
+}
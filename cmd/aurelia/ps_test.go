@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/benaskins/aurelia/internal/daemon"
+	"github.com/benaskins/aurelia/internal/driver"
+	"github.com/benaskins/aurelia/internal/health"
+)
+
+func TestRenderPS(t *testing.T) {
+	t.Parallel()
+
+	states := []daemon.ServiceState{
+		{Name: "api", Type: "native", State: driver.StateRunning, Health: health.StatusHealthy, PID: 4242, Port: 8080, RestartCount: 1},
+		{Name: "worker", Type: "container", State: driver.StateFailed, StateReason: daemon.ReasonPolicyExhausted, LastExitCode: 2},
+	}
+
+	tests := []struct {
+		name   string
+		format string
+		header bool
+		want   string
+	}{
+		{
+			name:   "default columns",
+			format: "name,state,health,pid,port",
+			header: true,
+			want:   "NAME\tSTATE\tHEALTH\tPID\tPORT\napi\trunning\thealthy\t4242\t8080\nworker\tfailed\t-\t-\t-\n",
+		},
+		{
+			name:   "no header",
+			format: "name, Port",
+			want:   "api\t8080\nworker\t-\n",
+		},
+		{
+			name:   "reason and counts",
+			format: "name,reason,restarts,exit_code",
+			want:   "api\t-\t1\t0\nworker\tpolicy_exhausted\t0\t2\n",
+		},
+		{
+			name:   "template",
+			format: "{{.Name}}={{.State}} pid:{{.PID}}",
+			header: true,
+			want:   "api=running pid:4242\nworker=failed pid:0\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := renderPS(&buf, states, tt.format, tt.header); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("got:\n%q\nwant:\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderPSErrors(t *testing.T) {
+	t.Parallel()
+
+	states := []daemon.ServiceState{{Name: "api"}}
+	var buf bytes.Buffer
+	if err := renderPS(&buf, states, "name,memory", true); err == nil || !strings.Contains(err.Error(), `unknown column "memory"`) {
+		t.Errorf("expected unknown column error, got %v", err)
+	}
+	if err := renderPS(&buf, states, "{{.Name", true); err == nil {
+		t.Error("expected error for unparsable template")
+	}
+	if err := renderPS(&buf, states, "{{.Memory}}", true); err == nil {
+		t.Error("expected error for unknown template field")
+	}
+}
@@ -91,3 +91,24 @@ func TestSecretGetFallsBackToDirect(t *testing.T) {
 		t.Fatal("socket should not exist")
 	}
 }
+
+func TestNewAuditLogToleratesBrokenConfig(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	dir := filepath.Join(home, ".aurelia")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "config.yaml"), []byte("audit: [unclosed\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := newAuditLog()
+	if err != nil {
+		t.Fatalf("newAuditLog with a broken config: %v", err)
+	}
+	l.Close()
+	if _, err := os.Stat(filepath.Join(dir, "audit.log")); err != nil {
+		t.Errorf("expected the audit log to be opened: %v", err)
+	}
+}
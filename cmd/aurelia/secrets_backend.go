@@ -26,7 +26,7 @@ func newSecretStore(actor string) (*keychain.AuditedStore, error) {
 		return nil, fmt.Errorf("creating directory: %w", err)
 	}
 
-	auditLog, err := audit.NewLogger(filepath.Join(dir, "audit.log"))
+	auditLog, err := newAuditLog()
 	if err != nil {
 		return nil, err
 	}
@@ -43,6 +43,28 @@ func newSecretStore(actor string) (*keychain.AuditedStore, error) {
 	return keychain.NewAuditedStore(inner, auditLog, meta, actor), nil
 }
 
+// newAuditLog opens the audit log shared by secret operations and the
+// daemon's lifecycle actions, rotated as set by the config's audit block.
+// A config that doesn't load only costs the rotation settings: the log is
+// opened with the defaults and a warning.
+func newAuditLog() (*audit.Logger, error) {
+	dir, err := aureliaHome()
+	if err != nil {
+		return nil, fmt.Errorf("finding aurelia home: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("creating directory: %w", err)
+	}
+	cfgPath := config.DefaultPath()
+	var opts []audit.Option
+	if cfg, err := config.Load(cfgPath); err != nil {
+		slog.Warn("audit log: config not loaded, using default rotation", "config", cfgPath, "error", err)
+	} else {
+		opts = cfg.Audit.Options()
+	}
+	return audit.NewLogger(filepath.Join(dir, "audit.log"), opts...)
+}
+
 // resolveBackend picks the best available secrets backend.
 // When OpenBao is configured, it is required — no silent fallback to Keychain.
 func resolveBackend(stateDir string) (keychain.Store, error) {
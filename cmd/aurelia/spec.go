@@ -0,0 +1,44 @@
+package main
+
+import (
+	"net/url"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var specCmd = &cobra.Command{
+	Use:   "spec <service>",
+	Short: "Print the spec the daemon loaded for a service",
+	Long: `Print the spec the daemon is running a service from, as YAML: the spec file
+after defaults.yaml, the default restart policy and environment expansion.
+Secrets are shown as the references in the spec, never their values.
+
+Use --json for the same spec as JSON.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		jsonOut, _ := cmd.Flags().GetBool("json")
+
+		api, err := apiClient(cmd)
+		if err != nil {
+			return err
+		}
+		if jsonOut {
+			var sp map[string]any
+			if err := api.Get(cmd.Context(), "/v1/services/"+url.PathEscape(args[0])+"/spec", &sp); err != nil {
+				return err
+			}
+			return printJSON(sp)
+		}
+		data, err := api.ServiceSpec(cmd.Context(), args[0])
+		if err != nil {
+			return err
+		}
+		_, err = os.Stdout.Write(data)
+		return err
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(specCmd)
+}
@@ -22,8 +22,12 @@ var tokenRotateCmd = &cobra.Command{
 			path += "?force=true"
 		}
 
-		result, err := apiPost(path)
+		api, err := apiClient(cmd)
 		if err != nil {
+			return err
+		}
+		var result map[string]any
+		if err := api.Post(cmd.Context(), path, &result); err != nil {
 			return fmt.Errorf("token rotation failed: %w", err)
 		}
 
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/benaskins/aurelia/internal/daemon"
+	"github.com/benaskins/aurelia/internal/driver"
+	"github.com/benaskins/aurelia/internal/health"
+	"github.com/spf13/cobra"
+)
+
+// waitPollInterval is how often aurelia wait checks the service.
+const waitPollInterval = 500 * time.Millisecond
+
+var waitCmd = &cobra.Command{
+	Use:   "wait <service>",
+	Short: "Wait until a service is healthy, running or stopped",
+	Long: `Block until a service reaches a state, for scripts and CI.
+
+--for healthy (the default) waits until the service is running and its health
+check passes; a service without a health check only has to be running.
+--for running waits until the process is up, and --for stopped until it is
+down.
+
+Exits non-zero if the timeout passes first, or if the service fails or stops
+for good (state_reason set) while waiting for healthy or running.
+
+Examples:
+  aurelia wait api
+  aurelia wait db --for running --timeout 2m
+  aurelia down api && aurelia wait api --for stopped`,
+	Args: cobra.ExactArgs(1),
+	RunE: runWait,
+}
+
+func init() {
+	waitCmd.Flags().String("for", "healthy", "state to wait for: healthy, running or stopped")
+	waitCmd.Flags().Duration("timeout", daemon.DefaultReadyTimeout, "give up after this long")
+	rootCmd.AddCommand(waitCmd)
+}
+
+func runWait(cmd *cobra.Command, args []string) error {
+	jsonOut, _ := cmd.Flags().GetBool("json")
+	cond, _ := cmd.Flags().GetString("for")
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+	switch cond {
+	case "healthy", "running", "stopped":
+	default:
+		return fmt.Errorf("invalid --for %q: want healthy, running or stopped", cond)
+	}
+	if timeout <= 0 {
+		return fmt.Errorf("--timeout must be positive")
+	}
+
+	api, err := apiClient(cmd)
+	if err != nil {
+		return err
+	}
+	name := args[0]
+
+	// Services without a health check count as healthy once running
+	hasHealth := false
+	if cond == "healthy" {
+		si, err := api.InspectService(cmd.Context(), name)
+		if err != nil {
+			return err
+		}
+		hasHealth = si.HealthCheck != nil
+	}
+
+	ctx, cancel := context.WithTimeout(cmd.Context(), timeout)
+	defer cancel()
+	st, err := waitFor(ctx, waitPollInterval, cond, hasHealth, func(ctx context.Context) (daemon.ServiceState, error) {
+		return api.GetService(ctx, name)
+	})
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("%s not %s after %s (state %s, health %s)", name, cond, timeout, st.State, st.Health)
+	}
+	if err != nil {
+		return err
+	}
+
+	if jsonOut {
+		return printJSON(map[string]any{"status": cond, "state": st})
+	}
+	fmt.Printf("%s: %s\n", name, cond)
+	return nil
+}
+
+// waitFor polls fetch every interval until cond holds for the service,
+// returning its last state. It returns ctx's error once ctx is done, and an
+// error as soon as the service can no longer reach cond by itself.
+func waitFor(ctx context.Context, interval time.Duration, cond string, hasHealth bool,
+	fetch func(context.Context) (daemon.ServiceState, error)) (daemon.ServiceState, error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var last daemon.ServiceState
+	for {
+		st, err := fetch(ctx)
+		if ctx.Err() != nil {
+			return last, ctx.Err()
+		}
+		if err != nil {
+			return last, err
+		}
+		last = st
+		if done, err := conditionMet(cond, st, hasHealth); done || err != nil {
+			return st, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return last, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// conditionMet reports whether st satisfies cond ("healthy", "running" or
+// "stopped"). For healthy and running it returns an error if the service has
+// completed, is disabled, or failed or stopped with a reason and so won't
+// come back without help. A failure without a reason is a crash the restart
+// policy may yet recover from, so the wait goes on.
+func conditionMet(cond string, st daemon.ServiceState, hasHealth bool) (bool, error) {
+	if cond == "stopped" {
+		return st.State == driver.StateStopped || st.State == driver.StateFailed || st.State == driver.StateCompleted || st.State == driver.StateDisabled, nil
+	}
+
+	switch {
+	case st.State == driver.StateDisabled:
+		return false, fmt.Errorf("%s is disabled", st.Name)
+	case st.State == driver.StateCompleted:
+		return false, fmt.Errorf("%s completed and won't restart", st.Name)
+	case st.State == driver.StateFailed && st.StateReason != "":
+		if st.LastError != "" {
+			return false, fmt.Errorf("%s failed (%s): %s", st.Name, st.StateReason, st.LastError)
+		}
+		return false, fmt.Errorf("%s failed (%s)", st.Name, st.StateReason)
+	case st.State == driver.StateStopped && st.StateReason != "":
+		return false, fmt.Errorf("%s stopped (%s)", st.Name, st.StateReason)
+	case st.State != driver.StateRunning:
+		return false, nil
+	case cond == "healthy" && hasHealth:
+		return st.Health == health.StatusHealthy, nil
+	}
+	return true, nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/benaskins/aurelia/internal/daemon"
+	"github.com/benaskins/aurelia/internal/driver"
+	"github.com/benaskins/aurelia/internal/health"
+)
+
+func TestConditionMet(t *testing.T) {
+	t.Parallel()
+
+	running := daemon.ServiceState{Name: "api", State: driver.StateRunning, Health: health.StatusUnknown}
+	healthy := daemon.ServiceState{Name: "api", State: driver.StateRunning, Health: health.StatusHealthy}
+	starting := daemon.ServiceState{Name: "api", State: driver.StateStarting}
+	stopped := daemon.ServiceState{Name: "api", State: driver.StateStopped}
+	gaveUp := daemon.ServiceState{Name: "api", State: driver.StateStopped, StateReason: daemon.ReasonPolicyExhausted}
+	failed := daemon.ServiceState{Name: "api", State: driver.StateFailed, StateReason: daemon.ReasonPolicyExhausted, LastError: "exit status 1"}
+	crashed := daemon.ServiceState{Name: "api", State: driver.StateFailed, LastError: "exit status 1"}
+	completed := daemon.ServiceState{Name: "api", State: driver.StateCompleted, StateReason: daemon.ReasonCleanExit}
+
+	tests := []struct {
+		name      string
+		cond      string
+		st        daemon.ServiceState
+		hasHealth bool
+		want      bool
+		wantErr   bool
+	}{
+		{"healthy: healthy", "healthy", healthy, true, true, false},
+		{"healthy: running, check not passed yet", "healthy", running, true, false, false},
+		{"healthy: running, no health check", "healthy", running, false, true, false},
+		{"healthy: starting", "healthy", starting, true, false, false},
+		{"healthy: stopped, not started yet", "healthy", stopped, true, false, false},
+		{"healthy: stopped for good", "healthy", gaveUp, true, false, true},
+		{"healthy: failed", "healthy", failed, true, false, true},
+		{"healthy: crashed, restart pending", "healthy", crashed, true, false, false},
+		{"running: running", "running", running, true, true, false},
+		{"running: starting", "running", starting, false, false, false},
+		{"running: failed", "running", failed, false, false, true},
+		{"running: crashed, restart pending", "running", crashed, false, false, false},
+		{"running: completed", "running", completed, false, false, true},
+		{"stopped: stopped", "stopped", stopped, false, true, false},
+		{"stopped: stopped with reason", "stopped", gaveUp, false, true, false},
+		{"stopped: failed", "stopped", failed, false, true, false},
+		{"stopped: completed", "stopped", completed, false, true, false},
+		{"stopped: running", "stopped", running, false, false, false},
+	}
+	for _, tt := range tests {
+		got, err := conditionMet(tt.cond, tt.st, tt.hasHealth)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("%s: got %v, %v; want %v, error %v", tt.name, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestWaitFor(t *testing.T) {
+	t.Parallel()
+
+	// The service comes up on the third poll
+	seq := []driver.State{driver.StateStarting, driver.StateStarting, driver.StateRunning}
+	polls := 0
+	fetch := func(context.Context) (daemon.ServiceState, error) {
+		st := daemon.ServiceState{Name: "api", State: seq[min(polls, len(seq)-1)]}
+		polls++
+		return st, nil
+	}
+	st, err := waitFor(context.Background(), time.Millisecond, "running", false, fetch)
+	if err != nil || st.State != driver.StateRunning || polls != 3 {
+		t.Errorf("got %s after %d polls, err %v; want running after 3", st.State, polls, err)
+	}
+
+	// It never comes up, so the wait times out with the last state
+	polls = 0
+	seq = []driver.State{driver.StateStarting}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	st, err = waitFor(ctx, time.Millisecond, "running", false, fetch)
+	if !errors.Is(err, context.DeadlineExceeded) || st.State != driver.StateStarting {
+		t.Errorf("got %s, err %v; want starting and a deadline error", st.State, err)
+	}
+}
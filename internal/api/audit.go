@@ -0,0 +1,55 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/benaskins/aurelia/internal/audit"
+	"github.com/benaskins/aurelia/internal/daemon"
+)
+
+// maxAuditEntries bounds the n parameter of GET /v1/audit.
+const maxAuditEntries = 10000
+
+// auditEntries returns audit log entries, oldest first, in the audit.log
+// line format. ?action=, ?service= and ?key= keep only matching entries,
+// ?since= (RFC 3339) those at or after a time, and ?n= the most recent n
+// (default 100). Entries are decoded into audit.Entry and encoded again, so
+// nothing but its fields, which hold secret keys and never values, is
+// returned. TCP clients need a write-scoped token, as for secrets.
+func (s *Server) auditEntries(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	f := audit.Filter{
+		Action:  audit.Action(q.Get("action")),
+		Service: q.Get("service"),
+		Key:     q.Get("key"),
+		Limit:   100,
+	}
+	if qn := q.Get("n"); qn != "" {
+		if parsed, err := strconv.Atoi(qn); err == nil && parsed > 0 {
+			f.Limit = min(parsed, maxAuditEntries)
+		}
+	}
+	if since := q.Get("since"); since != "" {
+		t, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, CodeBadRequest, "since must be an RFC 3339 time")
+			return
+		}
+		f.Since = t
+	}
+
+	entries, err := s.daemon.AuditEntries(f)
+	if errors.Is(err, daemon.ErrAuditDisabled) {
+		writeError(w, http.StatusServiceUnavailable, CodeNotConfigured, "audit log not configured")
+		return
+	}
+	if err != nil {
+		s.logger.Error("auditEntries: failed to read audit log", "error", err)
+		writeDaemonError(w, r, http.StatusInternalServerError, CodeInternal, "failed to read audit log", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"entries": entries})
+}
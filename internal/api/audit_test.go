@@ -0,0 +1,113 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/benaskins/aurelia/internal/audit"
+	"github.com/benaskins/aurelia/internal/daemon"
+)
+
+func TestAuditEndpoint(t *testing.T) {
+	auditLog, err := audit.NewLogger(filepath.Join(t.TempDir(), "audit.log"))
+	if err != nil {
+		t.Fatalf("NewLogger: %v", err)
+	}
+	t.Cleanup(func() { auditLog.Close() })
+
+	start := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	for i, e := range []audit.Entry{
+		{Action: audit.ActionSecretRead, Key: "chat/db-url", Service: "chat", Actor: "daemon"},
+		{Action: audit.ActionServiceRestart, Service: "chat", Actor: "local"},
+		{Action: audit.ActionServiceRestart, Service: "api", Actor: "token:0a1b2c3d"},
+		{Action: audit.ActionSecretWrite, Key: "api/token", Actor: "cli"},
+	} {
+		e.Timestamp = start.Add(time.Duration(i) * time.Hour)
+		auditLog.Log(e)
+	}
+
+	_, client := setupTestServer(t, map[string]string{
+		"web.yaml": `
+service:
+  name: web
+  type: native
+  command: "sleep 30"
+`,
+	}, daemon.WithAuditLog(auditLog))
+
+	get := func(query url.Values) []audit.Entry {
+		t.Helper()
+		resp, err := client.Get("http://aurelia/v1/audit?" + query.Encode())
+		if err != nil {
+			t.Fatalf("GET audit: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("GET audit?%s: expected 200, got %d", query.Encode(), resp.StatusCode)
+		}
+		var body struct {
+			Entries []audit.Entry `json:"entries"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatalf("decoding: %v", err)
+		}
+		return body.Entries
+	}
+	summary := func(entries []audit.Entry) string {
+		var parts []string
+		for _, e := range entries {
+			// Entries newer than the fixtures are the daemon's own
+			if e.Timestamp.Before(start.Add(24 * time.Hour)) {
+				parts = append(parts, string(e.Action)+":"+e.Service+":"+e.Key)
+			}
+		}
+		return strings.Join(parts, " ")
+	}
+
+	tests := []struct {
+		name  string
+		query url.Values
+		want  string
+	}{
+		{"action", url.Values{"action": {"service_restart"}}, "service_restart:chat: service_restart:api:"},
+		{"service", url.Values{"service": {"chat"}}, "secret_read:chat:chat/db-url service_restart:chat:"},
+		{"action and service", url.Values{"action": {"service_restart"}, "service": {"api"}}, "service_restart:api:"},
+		{"key", url.Values{"key": {"api/token"}}, "secret_write::api/token"},
+		{"since", url.Values{"since": {start.Add(2 * time.Hour).Format(time.RFC3339)}, "action": {"service_restart"}}, "service_restart:api:"},
+		{"most recent", url.Values{"service": {"chat"}, "n": {"1"}}, "service_restart:chat:"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := summary(get(tt.query)); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	resp, err := client.Get("http://aurelia/v1/audit?since=yesterday")
+	if err != nil {
+		t.Fatalf("GET audit: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("invalid since: expected 400, got %d", resp.StatusCode)
+	}
+}
+
+func TestAuditEndpointNotConfigured(t *testing.T) {
+	_, client := setupTestServer(t, nil)
+
+	resp, err := client.Get("http://aurelia/v1/audit")
+	if err != nil {
+		t.Fatalf("GET audit: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 without an audit log, got %d", resp.StatusCode)
+	}
+}
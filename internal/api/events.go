@@ -0,0 +1,77 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/benaskins/aurelia/internal/daemon"
+)
+
+// maxEvents bounds the n parameter of GET /v1/events.
+const maxEvents = 1000
+
+// events returns the daemon event log, oldest first. ?service= keeps only
+// one service's events and ?n= the most recent n (default 100).
+func (s *Server) events(w http.ResponseWriter, r *http.Request) {
+	n := 100
+	if qn := r.URL.Query().Get("n"); qn != "" {
+		if parsed, err := strconv.Atoi(qn); err == nil && parsed > 0 {
+			n = min(parsed, maxEvents)
+		}
+	}
+	service := r.URL.Query().Get("service")
+
+	events := []daemon.Event{}
+	for _, ev := range s.daemon.Events() {
+		if service == "" || ev.Service == service {
+			events = append(events, ev)
+		}
+	}
+	if len(events) > n {
+		events = events[len(events)-n:]
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"events": events})
+}
+
+// eventsStream sends events as they are recorded as server-sent events, one
+// JSON Event per "data:" line, until the client disconnects or the server
+// shuts down. ?service= keeps only one service's events.
+func (s *Server) eventsStream(w http.ResponseWriter, r *http.Request) {
+	service := r.URL.Query().Get("service")
+
+	events, cancel := s.daemon.WatchEvents()
+	defer cancel()
+
+	// The stream stays open past the server's write timeout
+	rc := http.NewResponseController(w)
+	rc.SetWriteDeadline(time.Time{})
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	out := &streamWriter{w: w, rc: rc}
+	// Send the headers now so clients know the subscription is live
+	rc.Flush()
+
+	for {
+		select {
+		case ev := <-events:
+			if service != "" && ev.Service != service {
+				continue
+			}
+			data, err := json.Marshal(ev)
+			if err != nil {
+				continue
+			}
+			if _, err := fmt.Fprintf(out, "data: %s\n\n", data); err != nil {
+				return
+			}
+		case <-r.Context().Done():
+			return
+		case <-s.closing:
+			return
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/benaskins/aurelia/internal/daemon"
+)
+
+func TestEventsEndpoint(t *testing.T) {
+	_, client := setupTestServer(t, map[string]string{
+		"web.yaml": `
+service:
+  name: web
+  type: native
+  command: "sleep 30"
+`,
+		"worker.yaml": `
+service:
+  name: worker
+  type: native
+  command: "sleep 30"
+`,
+	})
+
+	resp, err := client.Get("http://aurelia/v1/events?service=web")
+	if err != nil {
+		t.Fatalf("GET events: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Events []daemon.Event `json:"events"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(body.Events) != 1 {
+		t.Fatalf("expected 1 event for web, got %+v", body.Events)
+	}
+	if ev := body.Events[0]; ev.Service != "web" || ev.Type != daemon.EventStarted {
+		t.Errorf("expected web started event, got %+v", ev)
+	}
+}
+
+func TestEventsStream(t *testing.T) {
+	_, client := setupTestServer(t, map[string]string{
+		"web.yaml": `
+service:
+  name: web
+  type: native
+  command: "sleep 30"
+`,
+	})
+
+	resp, err := client.Get("http://aurelia/v1/events/stream?service=web")
+	if err != nil {
+		t.Fatalf("GET events stream: %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("expected event-stream content type, got %q", ct)
+	}
+
+	stopResp, err := client.Post("http://aurelia/v1/services/web/stop", "application/json", nil)
+	if err != nil {
+		t.Fatalf("POST stop: %v", err)
+	}
+	stopResp.Body.Close()
+
+	events := make(chan daemon.Event, 16)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+			if !ok {
+				continue
+			}
+			var ev daemon.Event
+			if json.Unmarshal([]byte(data), &ev) == nil {
+				events <- ev
+			}
+		}
+		close(events)
+	}()
+
+	select {
+	case ev, ok := <-events:
+		if !ok {
+			t.Fatal("stream closed before an event arrived")
+		}
+		if ev.Service != "web" || ev.Type != daemon.EventStopped {
+			t.Errorf("expected web stopped event, got %+v", ev)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for streamed event")
+	}
+}
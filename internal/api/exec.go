@@ -0,0 +1,94 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// execRequest is the body of POST /v1/services/{name}/exec.
+type execRequest struct {
+	Argv []string `json:"argv"`
+}
+
+// execExitCodeTrailer carries the command's exit code once its output has
+// been streamed; execErrorTrailer is set if exec failed part-way through.
+const (
+	execExitCodeTrailer = "X-Exit-Code"
+	execErrorTrailer    = "X-Exec-Error"
+)
+
+// streamWriter flushes each write to the client and records whether any
+// output has been sent, after which errors can only be reported in trailers.
+type streamWriter struct {
+	mu      sync.Mutex
+	w       http.ResponseWriter
+	rc      *http.ResponseController
+	started bool
+}
+
+func (sw *streamWriter) Write(p []byte) (int, error) {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	sw.started = true
+	n, err := sw.w.Write(p)
+	if err == nil {
+		sw.rc.Flush()
+	}
+	return n, err
+}
+
+func (sw *streamWriter) wrote() bool {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	return sw.started
+}
+
+// execInService runs a command in a service's context and streams its
+// combined output as text/plain; the exit code follows in a trailer. It is
+// only served on the local Unix socket — peers holding the API token must
+// not gain a shell on this host.
+func (s *Server) execInService(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if !isUnixSocket(r) {
+		writeError(w, http.StatusForbidden, CodeForbidden, "exec is only available on the local socket")
+		return
+	}
+	if s.isExternalGuard(w, name, "exec in") {
+		return
+	}
+
+	var req execRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, fmt.Sprintf("invalid request body: %v", err))
+		return
+	}
+	if len(req.Argv) == 0 {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "argv required (e.g. [\"ls\", \"-la\"])")
+		return
+	}
+
+	// Commands may run well past the server's write timeout
+	rc := http.NewResponseController(w)
+	rc.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Trailer", execExitCodeTrailer+", "+execErrorTrailer)
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	out := &streamWriter{w: w, rc: rc}
+
+	s.logger.Info("exec in service", "service", name, "argv", req.Argv)
+	code, err := s.daemon.ExecInService(r.Context(), name, req.Argv, out)
+	if err != nil && !out.wrote() {
+		w.Header().Del("Trailer")
+		s.logger.Error("execInService: failed to exec", "service", name, "error", err)
+		writeDaemonError(w, r, http.StatusBadRequest, CodeOperationFailed, "failed to exec in service", err)
+		return
+	}
+	if err != nil {
+		w.Header().Set(execErrorTrailer, err.Error())
+	}
+	w.Header().Set(execExitCodeTrailer, strconv.Itoa(code))
+}
@@ -0,0 +1,70 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/benaskins/aurelia/internal/daemon"
+	"github.com/benaskins/aurelia/internal/gpu"
+)
+
+func TestGPUHistory(t *testing.T) {
+	obs := gpu.NewObserver(10 * time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	obs.Start(ctx)
+	defer obs.Stop()
+
+	srv := NewServer(daemon.NewDaemon(t.TempDir()), obs, "test")
+	ts := httptest.NewServer(srv.server.Handler)
+	defer ts.Close()
+
+	getHistory := func(query string) (int, gpu.Series) {
+		t.Helper()
+		resp, err := http.Get(ts.URL + "/v1/gpu/history" + query)
+		if err != nil {
+			t.Fatalf("GET /v1/gpu/history: %v", err)
+		}
+		defer resp.Body.Close()
+		var series gpu.Series
+		json.NewDecoder(resp.Body).Decode(&series)
+		return resp.StatusCode, series
+	}
+
+	var series gpu.Series
+	deadline := time.Now().Add(2 * time.Second)
+	for len(series.Samples) < 3 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected samples to accumulate, got %d", len(series.Samples))
+		}
+		time.Sleep(20 * time.Millisecond)
+		_, series = getHistory("")
+	}
+	if series.Interval != "10ms" {
+		t.Errorf("expected interval 10ms, got %q", series.Interval)
+	}
+	for i := 1; i < len(series.Samples); i++ {
+		if !series.Samples[i].Timestamp.After(series.Samples[i-1].Timestamp) {
+			t.Errorf("samples not in time order at %d", i)
+		}
+	}
+
+	last := series.Samples[len(series.Samples)-1].Timestamp
+	code, newer := getHistory("?since=" + last.Format(time.RFC3339Nano))
+	if code != http.StatusOK {
+		t.Fatalf("since: expected 200, got %d", code)
+	}
+	for _, s := range newer.Samples {
+		if !s.Timestamp.After(last) {
+			t.Errorf("since returned a sample from %v, not after %v", s.Timestamp, last)
+		}
+	}
+
+	if code, _ := getHistory("?since=yesterday"); code != http.StatusBadRequest {
+		t.Errorf("invalid since: expected 400, got %d", code)
+	}
+}
@@ -45,33 +45,25 @@ const laminaExecTimeout = 5 * time.Minute
 
 func (s *Server) laminaExec(w http.ResponseWriter, r *http.Request) {
 	if s.laminaRoot == "" {
-		writeJSON(w, http.StatusServiceUnavailable, map[string]string{
-			"error": "lamina_root not configured",
-		})
+		writeError(w, http.StatusServiceUnavailable, CodeNotConfigured, "lamina_root not configured")
 		return
 	}
 
 	var req laminaRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{
-			"error": fmt.Sprintf("invalid request body: %v", err),
-		})
+		writeError(w, http.StatusBadRequest, CodeBadRequest, fmt.Sprintf("invalid request body: %v", err))
 		return
 	}
 
 	if len(req.Args) == 0 {
-		writeJSON(w, http.StatusBadRequest, map[string]string{
-			"error": "args required (e.g. [\"repo\", \"status\"])",
-		})
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "args required (e.g. [\"repo\", \"status\"])")
 		return
 	}
 
 	// Validate subcommand against allowlist
 	subcmd := req.Args[0]
 	if !allowedCommands[subcmd] {
-		writeJSON(w, http.StatusForbidden, map[string]string{
-			"error": fmt.Sprintf("command %q not allowed", subcmd),
-		})
+		writeError(w, http.StatusForbidden, CodeForbidden, fmt.Sprintf("command %q not allowed", subcmd))
 		return
 	}
 
@@ -13,7 +13,7 @@ import (
 func laminaServer(t *testing.T, laminaRoot string) *Server {
 	t.Helper()
 	d := daemon.NewDaemon(t.TempDir())
-	srv := NewServer(d, nil)
+	srv := NewServer(d, nil, "test")
 	srv.SetLaminaRoot(laminaRoot)
 	return srv
 }
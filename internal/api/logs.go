@@ -0,0 +1,68 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// serviceLogsStream sends a service's log lines as they are written as
+// server-sent events, one JSON string per "data:" line, until the client
+// disconnects or the server shuts down. It follows the service across
+// restarts. ?grep= and ?regex=true filter lines as for the logs endpoint.
+// With ?timestamps=true each event is instead an object with the line and
+// the time it was written.
+func (s *Server) serviceLogsStream(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	timestamps := r.URL.Query().Get("timestamps") == "true"
+	q, err := parseLogQuery(r, time.Now())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	lines, err := s.daemon.FollowServiceLogs(ctx, name)
+	if err != nil {
+		writeDaemonError(w, r, http.StatusNotFound, CodeServiceNotFound, "service not found", err)
+		return
+	}
+
+	// The stream stays open past the server's write timeout
+	rc := http.NewResponseController(w)
+	rc.SetWriteDeadline(time.Time{})
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	out := &streamWriter{w: w, rc: rc}
+	// Send the headers now so clients know the subscription is live
+	rc.Flush()
+
+	for {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				return
+			}
+			if q.Match != nil && !q.Match(line.Text) {
+				continue
+			}
+			var v any = line.Text
+			if timestamps {
+				v = line
+			}
+			data, err := json.Marshal(v)
+			if err != nil {
+				continue
+			}
+			if _, err := fmt.Fprintf(out, "data: %s\n\n", data); err != nil {
+				return
+			}
+		case <-s.closing:
+			return
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServiceLogsStream(t *testing.T) {
+	// Logs a numbered line, alternately tick and tock, every 50ms
+	script := filepath.Join(t.TempDir(), "ticker.sh")
+	content := "#!/bin/sh\ni=0\nwhile true; do i=$((i+1)); echo tick $i; echo tock $i; sleep 0.05; done\n"
+	if err := os.WriteFile(script, []byte(content), 0755); err != nil {
+		t.Fatal(err)
+	}
+	_, client := setupTestServer(t, map[string]string{
+		"ticker.yaml": fmt.Sprintf(`
+service:
+  name: ticker
+  type: native
+  command: %s
+`, script),
+	})
+
+	resp, err := client.Get("http://aurelia/v1/services/ticker/logs/stream?grep=tock")
+	if err != nil {
+		t.Fatalf("GET logs stream: %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("expected event-stream content type, got %q", ct)
+	}
+
+	lines := make(chan string, 16)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+			if !ok {
+				continue
+			}
+			var line string
+			if json.Unmarshal([]byte(data), &line) == nil {
+				lines <- line
+			}
+		}
+	}()
+
+	for i := 0; i < 3; i++ {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				t.Fatal("stream closed early")
+			}
+			if !strings.HasPrefix(line, "tock ") {
+				t.Errorf("expected only tock lines, got %q", line)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for a streamed line")
+		}
+	}
+}
+
+func TestServiceLogsStreamNotFound(t *testing.T) {
+	_, client := setupTestServer(t, nil)
+
+	resp, err := client.Get("http://aurelia/v1/services/nope/logs/stream")
+	if err != nil {
+		t.Fatalf("GET logs stream: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", resp.StatusCode)
+	}
+}
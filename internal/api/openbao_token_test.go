@@ -22,7 +22,7 @@ func TestOpenbaoTokenRequiresMTLS(t *testing.T) {
 	}
 	t.Cleanup(func() { d.Stop(5 * time.Second) })
 
-	srv := NewServer(d, nil)
+	srv := NewServer(d, nil, "test")
 
 	// Request without peer identity (simulates CLI/bearer token client)
 	req := httptest.NewRequest("POST", "/v1/openbao/token", nil)
@@ -46,7 +46,7 @@ func TestOpenbaoTokenRejectsUnknownNode(t *testing.T) {
 	}
 	t.Cleanup(func() { d.Stop(5 * time.Second) })
 
-	srv := NewServer(d, nil)
+	srv := NewServer(d, nil, "test")
 
 	// Configure vendor with known nodes (no "rogue")
 	fakeBao := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -82,7 +82,7 @@ func TestOpenbaoTokenVendsForKnownNode(t *testing.T) {
 	}
 	t.Cleanup(func() { d.Stop(5 * time.Second) })
 
-	srv := NewServer(d, nil)
+	srv := NewServer(d, nil, "test")
 
 	// Fake OpenBao that returns a token
 	fakeBao := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -156,7 +156,7 @@ func TestOpenbaoTokenNotConfigured(t *testing.T) {
 	}
 	t.Cleanup(func() { d.Stop(5 * time.Second) })
 
-	srv := NewServer(d, nil)
+	srv := NewServer(d, nil, "test")
 	// No vendor configured
 
 	req := httptest.NewRequest("POST", "/v1/openbao/token", nil)
@@ -42,9 +42,7 @@ func (rl *rateLimitMiddleware) handler(next http.Handler) http.Handler {
 		entry := rl.getLimiter(key)
 		if !entry.limiter.Allow() {
 			w.Header().Set("Retry-After", "1")
-			writeJSON(w, http.StatusTooManyRequests, map[string]string{
-				"error": "rate limit exceeded",
-			})
+			writeError(w, http.StatusTooManyRequests, CodeRateLimited, "rate limit exceeded")
 			return
 		}
 		next.ServeHTTP(w, r)
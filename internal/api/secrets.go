@@ -19,23 +19,19 @@ func (s *Server) SetSecretCache(cache *keychain.CachedStore) {
 func (s *Server) secretsList(w http.ResponseWriter, r *http.Request) {
 	peer := PeerIdentity(r.Context())
 	if peer == "" || peer == "cli" {
-		writeJSON(w, http.StatusForbidden, map[string]string{
-			"error": "bulk secret list requires mTLS authentication",
-		})
+		writeError(w, http.StatusForbidden, CodeForbidden, "bulk secret list requires mTLS authentication")
 		return
 	}
 
 	if s.secretCache == nil {
-		writeJSON(w, http.StatusServiceUnavailable, map[string]string{
-			"error": "secret cache not configured",
-		})
+		writeError(w, http.StatusServiceUnavailable, CodeNotConfigured, "secret cache not configured")
 		return
 	}
 
 	keys, err := s.secretCache.List()
 	if err != nil {
 		s.logger.Error("secret list failed", "error", err)
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "list failed"})
+		writeError(w, http.StatusInternalServerError, CodeInternal, "list failed")
 		return
 	}
 
@@ -58,9 +54,7 @@ func (s *Server) secretsList(w http.ResponseWriter, r *http.Request) {
 func (s *Server) cacheInvalidate(w http.ResponseWriter, r *http.Request) {
 	peer := PeerIdentity(r.Context())
 	if peer == "" || peer == "cli" {
-		writeJSON(w, http.StatusForbidden, map[string]string{
-			"error": "cache invalidation requires mTLS authentication",
-		})
+		writeError(w, http.StatusForbidden, CodeForbidden, "cache invalidation requires mTLS authentication")
 		return
 	}
 
@@ -73,7 +67,7 @@ func (s *Server) cacheInvalidate(w http.ResponseWriter, r *http.Request) {
 		Key string `json:"key"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid body"})
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "invalid body")
 		return
 	}
 
@@ -90,7 +84,7 @@ func (s *Server) cacheInvalidate(w http.ResponseWriter, r *http.Request) {
 
 func (s *Server) secretGet(w http.ResponseWriter, r *http.Request) {
 	if s.secretCache == nil {
-		http.Error(w, `{"error":"secret cache not configured"}`, http.StatusServiceUnavailable)
+		writeError(w, http.StatusServiceUnavailable, CodeNotConfigured, "secret cache not configured")
 		return
 	}
 
@@ -98,11 +92,11 @@ func (s *Server) secretGet(w http.ResponseWriter, r *http.Request) {
 	val, err := s.secretCache.Get(key)
 	if err != nil {
 		if errors.Is(err, keychain.ErrNotFound) {
-			http.Error(w, `{"error":"not found"}`, http.StatusNotFound)
+			writeError(w, http.StatusNotFound, CodeSecretNotFound, "not found")
 			return
 		}
 		s.logger.Error("secret get failed", "key", key, "error", err)
-		http.Error(w, `{"error":"internal error"}`, http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, CodeInternal, "internal error")
 		return
 	}
 
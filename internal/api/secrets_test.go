@@ -85,7 +85,7 @@ func newTestServerDirect(t *testing.T) *Server {
 		t.Fatalf("daemon start: %v", err)
 	}
 	t.Cleanup(func() { d.Stop(5 * time.Second) })
-	return NewServer(d, nil)
+	return NewServer(d, nil, "test")
 }
 
 func TestSecretsListRequiresMTLS(t *testing.T) {
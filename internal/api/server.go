@@ -3,30 +3,34 @@ package api
 import (
 	"context"
 	"crypto/rand"
-	"crypto/subtle"
 	"crypto/tls"
 	"crypto/x509"
 	"embed"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/fs"
 	"log/slog"
 	"net"
 	"net/http"
 	"os"
+	"regexp"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/benaskins/aurelia/internal/audit"
 	"github.com/benaskins/aurelia/internal/config"
 	"github.com/benaskins/aurelia/internal/daemon"
 	"github.com/benaskins/aurelia/internal/gpu"
 	"github.com/benaskins/aurelia/internal/health"
 	"github.com/benaskins/aurelia/internal/keychain"
+	"github.com/benaskins/aurelia/internal/logbuf"
 	"github.com/benaskins/aurelia/internal/node"
 	"github.com/benaskins/aurelia/internal/sysinfo"
+	"golang.org/x/net/netutil"
 )
 
 //go:embed ui
@@ -34,66 +38,99 @@ var uiFS embed.FS
 
 // Server serves the aurelia REST API over a Unix socket.
 type Server struct {
-	daemon      *daemon.Daemon
-	gpu         *gpu.Observer
-	listener    net.Listener
-	server      *http.Server
-	tcpServer   *http.Server // separate server for TCP with auth middleware
-	logger      *slog.Logger
-	token       string // bearer token for TCP auth (empty = no auth)
-	prevToken   string // previous token during rotation (valid until rotation completes)
-	tokenPath   string // path to token file on disk
-	tokenMu     sync.RWMutex
-	nodeName    string // local node name for stamping on service states
-	laminaRoot  string // workspace root for lamina CLI execution
-	configPath  string // path to config file for token updates
-	rateLimiter *rateLimitMiddleware
-	tokenVendor *keychain.BaoTokenVendor
-	knownNodes  map[string]bool // valid peer CNs for token vending
-	pkiIssuer   *keychain.BaoPKIIssuer
-	secretCache *keychain.CachedStore
+	daemon       *daemon.Daemon
+	gpu          *gpu.Observer
+	listener     net.Listener
+	server       *http.Server
+	tcpServer    *http.Server // separate server for TCP with auth middleware
+	logger       *slog.Logger
+	token        string        // bearer token for TCP auth (empty = no auth)
+	prevToken    string        // previous token during rotation (valid until rotation completes)
+	primaryScope string        // scope of token and prevToken
+	scopedTokens []scopedToken // additional tokens from the tokens file
+	tokenPath    string        // path to token file on disk
+	tokenMu      sync.RWMutex
+	version      string // aurelia build version, reported by /v1/info
+	nodeName     string // local node name for stamping on service states
+	laminaRoot   string // workspace root for lamina CLI execution
+	configPath   string // path to config file for token updates
+	rateLimiter  *rateLimitMiddleware
+	tokenVendor  *keychain.BaoTokenVendor
+	knownNodes   map[string]bool // valid peer CNs for token vending
+	pkiIssuer    *keychain.BaoPKIIssuer
+	secretCache  *keychain.CachedStore
+	readTimeout  time.Duration // budget for the plain read endpoints
+
+	// closing is closed on Shutdown to end long-lived event streams, which
+	// would otherwise hold Shutdown open until its context expires
+	closing   chan struct{}
+	closeOnce sync.Once
 }
 
 // NewServer creates an API server backed by the given daemon.
 // The GPU observer is optional — if nil, /v1/gpu returns empty.
-func NewServer(d *daemon.Daemon, gpuObs *gpu.Observer) *Server {
+// The version string is reported by /v1/info.
+func NewServer(d *daemon.Daemon, gpuObs *gpu.Observer, version string) *Server {
 	s := &Server{
 		daemon:      d,
 		gpu:         gpuObs,
+		version:     version,
 		logger:      slog.With("component", "api"),
 		rateLimiter: newRateLimitMiddleware(),
+		readTimeout: readRequestTimeout,
+		closing:     make(chan struct{}),
 	}
 
+	// Plain reads are wrapped in s.read to give up after readTimeout; streams
+	// and everything that changes state run up to the server's WriteTimeout.
+	// Lifecycle operations on one service are wrapped in s.serviceOp so they
+	// run one at a time
+
 	mux := http.NewServeMux()
-	mux.HandleFunc("GET /v1/services", s.listServices)
-	mux.HandleFunc("GET /v1/services/{name}/inspect", s.inspectService)
-	mux.HandleFunc("GET /v1/services/{name}/health", s.serviceHealth)
-	mux.HandleFunc("GET /v1/services/{name}/deps", s.serviceDeps)
-	mux.HandleFunc("GET /v1/services/{name}", s.getService)
-	mux.HandleFunc("POST /v1/services/{name}/start", s.startService)
-	mux.HandleFunc("POST /v1/services/{name}/stop", s.stopService)
-	mux.HandleFunc("POST /v1/services/{name}/restart", s.restartService)
-	mux.HandleFunc("POST /v1/services/{name}/deploy", s.deployService)
-	mux.HandleFunc("POST /v1/services/{name}/ship", s.shipService)
-	mux.HandleFunc("DELETE /v1/services/{name}", s.removeService)
-	mux.HandleFunc("GET /v1/services/{name}/logs", s.serviceLogs)
-	mux.HandleFunc("GET /v1/graph", s.graph)
+	mux.HandleFunc("GET /v1/services", s.read(s.listServices))
+	mux.HandleFunc("POST /v1/services", s.createService)
+	mux.HandleFunc("PUT /v1/services", s.applyServices)
+	mux.HandleFunc("GET /v1/services/{name}/inspect", s.read(s.inspectService))
+	mux.HandleFunc("GET /v1/services/{name}/health", s.read(s.serviceHealth))
+	mux.HandleFunc("GET /v1/services/{name}/deps", s.read(s.serviceDeps))
+	mux.HandleFunc("GET /v1/services/{name}/spec", s.read(s.getServiceSpec))
+	mux.HandleFunc("GET /v1/services/{name}", s.read(s.getService))
+	mux.HandleFunc("POST /v1/services/{name}/start", s.serviceOp(s.startService))
+	mux.HandleFunc("POST /v1/services/{name}/stop", s.serviceOp(s.stopService))
+	mux.HandleFunc("POST /v1/services/{name}/restart", s.serviceOp(s.restartService))
+	mux.HandleFunc("POST /v1/services/{name}/deploy", s.serviceOp(s.deployService))
+	mux.HandleFunc("POST /v1/services/{name}/rollback", s.serviceOp(s.rollbackService))
+	mux.HandleFunc("POST /v1/services/{name}/ship", s.serviceOp(s.shipService))
+	mux.HandleFunc("POST /v1/deploy", s.deployAll)
+	mux.HandleFunc("DELETE /v1/services/{name}", s.serviceOp(s.removeService))
+	mux.HandleFunc("GET /v1/services/{name}/logs", s.read(s.serviceLogs))
+	mux.HandleFunc("GET /v1/services/{name}/logs/stream", s.serviceLogsStream)
+	mux.HandleFunc("POST /v1/services/{name}/exec", s.execInService)
+	mux.HandleFunc("GET /v1/graph", s.read(s.graph))
+	mux.HandleFunc("GET /v1/events", s.read(s.events))
+	mux.HandleFunc("GET /v1/audit", s.read(s.auditEntries))
+	mux.HandleFunc("GET /v1/events/stream", s.eventsStream)
+	mux.HandleFunc("GET /v1/routing", s.read(s.routing))
 	mux.HandleFunc("POST /v1/reload", s.reload)
-	mux.HandleFunc("GET /v1/gpu", s.gpuInfo)
-	mux.HandleFunc("GET /v1/system", s.systemInfo)
-	mux.HandleFunc("GET /v1/health", s.health)
+	mux.HandleFunc("POST /v1/maintenance", s.maintenance)
+	mux.HandleFunc("GET /v1/gpu", s.read(s.gpuInfo))
+	mux.HandleFunc("GET /v1/gpu/history", s.read(s.gpuHistory))
+	mux.HandleFunc("GET /v1/system", s.read(s.systemInfo))
+	mux.HandleFunc("GET /v1/health", s.read(s.health))
+	mux.HandleFunc("GET /v1/summary", s.read(s.summary))
+	mux.HandleFunc("GET /v1/info", s.read(s.info))
 
 	// Cluster endpoints — aggregate across peers
-	mux.HandleFunc("GET /v1/cluster/services", s.clusterListServices)
-	mux.HandleFunc("GET /v1/cluster/graph", s.clusterGraph)
-	mux.HandleFunc("GET /v1/cluster/services/{name}/logs", s.clusterServiceLogs)
+	mux.HandleFunc("GET /v1/cluster/services", s.read(s.clusterListServices))
+	mux.HandleFunc("GET /v1/cluster/graph", s.read(s.clusterGraph))
+	mux.HandleFunc("GET /v1/cluster/services/{name}/logs", s.read(s.clusterServiceLogs))
 	mux.HandleFunc("POST /v1/cluster/services/{name}/{action}", s.clusterServiceAction)
 
 	// Secret cache (local socket)
-	mux.HandleFunc("GET /v1/secrets/{key}", s.secretGet)
+	mux.HandleFunc("GET /v1/secrets/{key}", s.read(s.secretGet))
 
 	// Tessera: bulk secret fetch and cache invalidation (mTLS-only)
-	mux.HandleFunc("GET /v1/secrets", s.secretsList)
+	mux.HandleFunc("GET /v1/secrets", s.read(s.secretsList))
 	mux.HandleFunc("POST /v1/cache/invalidate", s.cacheInvalidate)
 
 	// Lamina workspace CLI execution
@@ -126,7 +163,7 @@ func NewServer(d *daemon.Daemon, gpuObs *gpu.Observer) *Server {
 	})
 
 	s.server = &http.Server{
-		Handler:           mux,
+		Handler:           localActor(mux),
 		ReadTimeout:       30 * time.Second,
 		WriteTimeout:      5 * time.Minute, // deploy endpoint blocks for health checks + drain
 		ReadHeaderTimeout: 10 * time.Second,
@@ -138,8 +175,16 @@ func NewServer(d *daemon.Daemon, gpuObs *gpu.Observer) *Server {
 
 // GenerateToken loads an existing bearer token from tokenPath, or creates a
 // new one if the file does not exist. This ensures tokens are stable across
-// daemon restarts so peer nodes don't need re-configuration.
-func (s *Server) GenerateToken(tokenPath string) error {
+// daemon restarts so peer nodes don't need re-configuration. The token has
+// ScopeWrite unless another scope is given.
+func (s *Server) GenerateToken(tokenPath string, scope ...string) error {
+	s.primaryScope = ScopeWrite
+	if len(scope) > 0 {
+		if !validScope(scope[0]) {
+			return fmt.Errorf("unknown token scope %q", scope[0])
+		}
+		s.primaryScope = scope[0]
+	}
 	s.tokenPath = tokenPath
 	// Reuse existing token if present
 	if data, err := os.ReadFile(tokenPath); err == nil {
@@ -201,19 +246,18 @@ func (s *Server) SetConfigPath(path string) {
 	s.configPath = path
 }
 
-// validToken returns true if the provided token matches either the current or previous token.
+// validToken returns true if the provided token matches the current or
+// previous token, or a loaded scoped token.
 func (s *Server) validToken(provided string) bool {
-	s.tokenMu.RLock()
-	defer s.tokenMu.RUnlock()
-	if subtle.ConstantTimeCompare([]byte(provided), []byte(s.token)) == 1 {
-		return true
-	}
-	if s.prevToken != "" && subtle.ConstantTimeCompare([]byte(provided), []byte(s.prevToken)) == 1 {
-		return true
-	}
-	return false
+	_, ok := s.tokenScope(provided)
+	return ok
 }
 
+// maxConnections caps the open connections on each listener. Past it, new
+// connections wait in the accept backlog, so a client that opens many can't
+// exhaust the daemon's file descriptors or goroutines.
+const maxConnections = 256
+
 // ListenUnix starts the server on a Unix socket.
 func (s *Server) ListenUnix(path string) error {
 	ln, err := net.Listen("unix", path)
@@ -224,6 +268,7 @@ func (s *Server) ListenUnix(path string) error {
 		ln.Close()
 		return fmt.Errorf("setting socket permissions: %w", err)
 	}
+	ln = netutil.LimitListener(ln, maxConnections)
 	s.listener = ln
 	s.logger.Info("API listening", "socket", path)
 	return s.server.Serve(ln)
@@ -251,6 +296,7 @@ func (s *Server) ListenTCP(addr string) error {
 	if err != nil {
 		return err
 	}
+	ln = netutil.LimitListener(ln, maxConnections)
 	s.logger.Info("API listening", "addr", addr)
 
 	// Wrap with rate limit + auth + audit middleware for TCP connections
@@ -334,7 +380,10 @@ func LoadPeerTLSConfig(certFile, keyFile, caFile string) (*tls.Config, error) {
 
 // ListenTLS starts the server on a TLS-encrypted TCP address.
 // Clients presenting a valid client certificate (mTLS) are authenticated by cert CN.
-// Clients without a client certificate must provide a bearer token.
+// Clients without a client certificate must provide a bearer token. If tlsConfig
+// requires client certificates (tls.RequireAndVerifyClientCert), connections
+// without one are refused during the handshake and every request must also
+// carry a bearer token.
 func (s *Server) ListenTLS(addr string, tlsConfig *tls.Config) error {
 	if s.token == "" {
 		return fmt.Errorf("TLS API requires authentication; call GenerateToken first")
@@ -349,14 +398,20 @@ func (s *Server) ListenTLS(addr string, tlsConfig *tls.Config) error {
 		}
 	}
 
-	ln, err := tls.Listen("tcp", addr, tlsConfig)
+	ln, err := net.Listen("tcp", addr)
 	if err != nil {
 		return err
 	}
-	s.logger.Info("API listening (TLS)", "addr", addr)
+	// Limit beneath TLS: http.Server only sees r.TLS on a *tls.Conn
+	ln = tls.NewListener(netutil.LimitListener(ln, maxConnections), tlsConfig)
+	s.logger.Info("API listening (TLS)", "addr", addr, "require_client_cert", tlsConfig.ClientAuth == tls.RequireAndVerifyClientCert)
 
+	auth := s.requireAuth
+	if tlsConfig.ClientAuth == tls.RequireAndVerifyClientCert {
+		auth = s.requireCertAndToken
+	}
 	s.tcpServer = &http.Server{
-		Handler:           s.rateLimiter.handler(s.requireAuth(s.auditLog(s.server.Handler))),
+		Handler:           s.rateLimiter.handler(auth(s.auditLog(s.server.Handler))),
 		ReadTimeout:       s.server.ReadTimeout,
 		WriteTimeout:      s.server.WriteTimeout,
 		ReadHeaderTimeout: s.server.ReadHeaderTimeout,
@@ -375,26 +430,51 @@ func (s *Server) requireAuth(next http.Handler) http.Handler {
 		if r.TLS != nil && len(r.TLS.PeerCertificates) > 0 {
 			cn := r.TLS.PeerCertificates[0].Subject.CommonName
 			ctx := context.WithValue(r.Context(), peerIdentityKey, cn)
+			ctx = audit.WithActor(ctx, "peer:"+cn)
 			next.ServeHTTP(w, r.WithContext(ctx))
 			return
 		}
 
 		// Fall back to bearer token
-		auth := r.Header.Get("Authorization")
-		if !strings.HasPrefix(auth, "Bearer ") {
-			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		if !s.authorizeToken(w, r) {
 			return
 		}
-		provided := strings.TrimPrefix(auth, "Bearer ")
-		if !s.validToken(provided) {
-			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		ctx := context.WithValue(r.Context(), peerIdentityKey, "cli")
+		ctx = audit.WithActor(ctx, tokenActor(r))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// requireCertAndToken returns middleware for listeners that require client
+// certificates: the bearer token is checked as an additional layer, and the
+// peer identity is the cert CN.
+func (s *Server) requireCertAndToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+			writeError(w, http.StatusUnauthorized, CodeUnauthorized, "client certificate required")
 			return
 		}
-		ctx := context.WithValue(r.Context(), peerIdentityKey, "cli")
+		if !s.authorizeToken(w, r) {
+			return
+		}
+		cn := r.TLS.PeerCertificates[0].Subject.CommonName
+		ctx := context.WithValue(r.Context(), peerIdentityKey, cn)
+		ctx = audit.WithActor(ctx, "peer:"+cn)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// localActor returns middleware that audits requests no listener has
+// identified, i.e. those on the Unix socket, as the actor "local".
+func localActor(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if audit.ActorFrom(r.Context()) == "" {
+			r = r.WithContext(audit.WithActor(r.Context(), "local"))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
 type contextKey string
 
 const peerIdentityKey contextKey = "peer_identity"
@@ -446,25 +526,20 @@ func (w *statusWriter) WriteHeader(code int) {
 	w.ResponseWriter.WriteHeader(code)
 }
 
-// requireToken returns middleware that validates the Authorization header.
+// requireToken returns middleware that validates the Authorization header
+// and the token's scope.
 func (s *Server) requireToken(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		auth := r.Header.Get("Authorization")
-		if !strings.HasPrefix(auth, "Bearer ") {
-			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
-			return
-		}
-		provided := strings.TrimPrefix(auth, "Bearer ")
-		if !s.validToken(provided) {
-			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		if !s.authorizeToken(w, r) {
 			return
 		}
-		next.ServeHTTP(w, r)
+		next.ServeHTTP(w, r.WithContext(audit.WithActor(r.Context(), tokenActor(r))))
 	})
 }
 
 // Shutdown gracefully shuts down both the Unix and TCP API servers.
 func (s *Server) Shutdown(ctx context.Context) error {
+	s.closeOnce.Do(func() { close(s.closing) })
 	err := s.server.Shutdown(ctx)
 	if s.tcpServer != nil {
 		if tcpErr := s.tcpServer.Shutdown(ctx); tcpErr != nil && err == nil {
@@ -484,7 +559,7 @@ func (s *Server) getService(w http.ResponseWriter, r *http.Request) {
 	state, err := s.daemon.ServiceState(name)
 	if err != nil {
 		s.logger.Warn("getService: service not found", "service", name, "error", err)
-		writeJSON(w, http.StatusNotFound, map[string]string{"error": errorMessage("service not found", err, r)})
+		writeDaemonError(w, r, http.StatusNotFound, CodeServiceNotFound, "service not found", err)
 		return
 	}
 	writeJSON(w, http.StatusOK, state)
@@ -495,7 +570,7 @@ func (s *Server) inspectService(w http.ResponseWriter, r *http.Request) {
 	inspect, err := s.daemon.InspectService(name)
 	if err != nil {
 		s.logger.Warn("inspectService: service not found", "service", name, "error", err)
-		writeJSON(w, http.StatusNotFound, map[string]string{"error": errorMessage("service not found", err, r)})
+		writeDaemonError(w, r, http.StatusNotFound, CodeServiceNotFound, "service not found", err)
 		return
 	}
 	writeJSON(w, http.StatusOK, inspect)
@@ -505,7 +580,7 @@ func (s *Server) serviceHealth(w http.ResponseWriter, r *http.Request) {
 	name := r.PathValue("name")
 	state, err := s.daemon.ServiceState(name)
 	if err != nil {
-		writeJSON(w, http.StatusNotFound, map[string]string{"error": errorMessage("service not found", err, r)})
+		writeDaemonError(w, r, http.StatusNotFound, CodeServiceNotFound, "service not found", err)
 		return
 	}
 	history, _ := s.daemon.ServiceHealthHistory(name)
@@ -529,7 +604,7 @@ func (s *Server) serviceDeps(w http.ResponseWriter, r *http.Request) {
 	name := r.PathValue("name")
 	deps, err := s.daemon.ServiceDeps(name)
 	if err != nil {
-		writeJSON(w, http.StatusNotFound, map[string]string{"error": errorMessage("service not found", err, r)})
+		writeDaemonError(w, r, http.StatusNotFound, CodeServiceNotFound, "service not found", err)
 		return
 	}
 	writeJSON(w, http.StatusOK, deps)
@@ -537,22 +612,43 @@ func (s *Server) serviceDeps(w http.ResponseWriter, r *http.Request) {
 
 func (s *Server) isExternalGuard(w http.ResponseWriter, name, action string) bool {
 	if s.daemon.IsExternal(name) {
-		writeJSON(w, http.StatusBadRequest, map[string]string{
-			"error": fmt.Sprintf("cannot %s external service %q", action, name),
-		})
+		writeError(w, http.StatusBadRequest, CodeExternalNotAllowed, fmt.Sprintf("cannot %s external service %q", action, name))
 		return true
 	}
 	return false
 }
 
+// serviceOp wraps a lifecycle handler so it holds the service's operation
+// lock while it runs. A request for a service that another start, stop,
+// restart, deploy or reload is working on gets a 409 rather than racing it.
+func (s *Server) serviceOp(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		unlock, err := s.daemon.LockService(r.PathValue("name"))
+		if err != nil {
+			writeError(w, http.StatusConflict, CodeServiceBusy, err.Error())
+			return
+		}
+		defer unlock()
+		h(w, r)
+	}
+}
+
 func (s *Server) startService(w http.ResponseWriter, r *http.Request) {
 	name := r.PathValue("name")
 	if s.isExternalGuard(w, name, "start") {
 		return
 	}
+	wait, ok := readyWait(w, r)
+	if !ok {
+		return
+	}
 	if err := s.daemon.StartService(r.Context(), name); err != nil {
 		s.logger.Error("startService: failed to start service", "service", name, "error", err)
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": errorMessage("failed to start service", err, r)})
+		writeDaemonError(w, r, http.StatusBadRequest, CodeOperationFailed, "failed to start service", err)
+		return
+	}
+	if wait > 0 {
+		s.writeReady(w, r, name, wait)
 		return
 	}
 	writeJSON(w, http.StatusAccepted, map[string]string{"status": "starting"})
@@ -563,9 +659,9 @@ func (s *Server) stopService(w http.ResponseWriter, r *http.Request) {
 	if s.isExternalGuard(w, name, "stop") {
 		return
 	}
-	if err := s.daemon.StopService(name, daemon.DefaultStopTimeout); err != nil {
+	if err := s.daemon.StopService(r.Context(), name, daemon.DefaultStopTimeout); err != nil {
 		s.logger.Error("stopService: failed to stop service", "service", name, "error", err)
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": errorMessage("failed to stop service", err, r)})
+		writeDaemonError(w, r, http.StatusBadRequest, CodeOperationFailed, "failed to stop service", err)
 		return
 	}
 	writeJSON(w, http.StatusAccepted, map[string]string{"status": "stopping"})
@@ -578,7 +674,7 @@ func (s *Server) removeService(w http.ResponseWriter, r *http.Request) {
 	}
 	if err := s.daemon.RemoveService(name, daemon.DefaultStopTimeout); err != nil {
 		s.logger.Error("removeService: failed to remove service", "service", name, "error", err)
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": errorMessage("failed to remove service", err, r)})
+		writeDaemonError(w, r, http.StatusBadRequest, CodeOperationFailed, "failed to remove service", err)
 		return
 	}
 	writeJSON(w, http.StatusOK, map[string]string{"status": "removed"})
@@ -589,41 +685,222 @@ func (s *Server) restartService(w http.ResponseWriter, r *http.Request) {
 	if s.isExternalGuard(w, name, "restart") {
 		return
 	}
-	if err := s.daemon.RestartService(name, daemon.DefaultStopTimeout); err != nil {
+	wait, ok := readyWait(w, r)
+	if !ok {
+		return
+	}
+	cascade := r.URL.Query().Get("cascade") == "true"
+	rolling := r.URL.Query().Get("rolling") == "true"
+	if cascade && rolling {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "cascade and rolling cannot be combined")
+		return
+	}
+	restart := s.daemon.RestartService
+	switch {
+	case cascade:
+		restart = s.daemon.RestartServiceWithDeps
+	case rolling:
+		restart = s.daemon.RollingRestartService
+	}
+	if err := restart(r.Context(), name, daemon.DefaultStopTimeout); err != nil {
 		s.logger.Error("restartService: failed to restart service", "service", name, "error", err)
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": errorMessage("failed to restart service", err, r)})
+		writeDaemonError(w, r, http.StatusBadRequest, CodeOperationFailed, "failed to restart service", err)
+		return
+	}
+	if (cascade || rolling) && wait == 0 {
+		// The cascade or roll has already waited for everything to be ready
+		writeJSON(w, http.StatusOK, map[string]string{"status": "restarted"})
+		return
+	}
+	if wait > 0 {
+		s.writeReady(w, r, name, wait)
 		return
 	}
 	writeJSON(w, http.StatusAccepted, map[string]string{"status": "restarting"})
 }
 
+// maxReadyWait caps the ?wait= parameter on start and restart.
+const maxReadyWait = 10 * time.Minute
+
+// readyWait parses the optional ?wait= parameter: a duration, or "true" for
+// daemon.DefaultReadyTimeout. Zero means respond without waiting. Writes a
+// 400 and returns false if the value is invalid.
+func readyWait(w http.ResponseWriter, r *http.Request) (time.Duration, bool) {
+	v := r.URL.Query().Get("wait")
+	if v == "" || v == "false" {
+		return 0, true
+	}
+	if v == "true" {
+		return daemon.DefaultReadyTimeout, true
+	}
+	wait, err := time.ParseDuration(v)
+	if err != nil || wait < 0 {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, fmt.Sprintf("invalid wait %q: want a duration like 30s", v))
+		return 0, false
+	}
+	return min(wait, maxReadyWait), true
+}
+
+// writeReady blocks until the service is running and healthy, then responds
+// with its state — 200 when ready, 504 with the last state on timeout, or
+// 503 as soon as the service goes down for good.
+func (s *Server) writeReady(w http.ResponseWriter, r *http.Request, name string, wait time.Duration) {
+	// The wait may run past the server's write timeout
+	http.NewResponseController(w).SetWriteDeadline(time.Now().Add(wait + 10*time.Second))
+
+	st, err := s.daemon.WaitForReady(r.Context(), name, wait)
+	if err != nil {
+		status := http.StatusGatewayTimeout
+		if errors.Is(err, daemon.ErrServiceDown) {
+			status = http.StatusServiceUnavailable
+		}
+		writeJSON(w, status, map[string]any{"error": err.Error(), "code": CodeNotReady, "state": st})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"status": "ready", "state": st})
+}
+
 func (s *Server) deployService(w http.ResponseWriter, r *http.Request) {
 	name := r.PathValue("name")
 	if s.isExternalGuard(w, name, "deploy") {
 		return
 	}
-	drain := daemon.DefaultDrainTimeout
-	if d := r.URL.Query().Get("drain"); d != "" {
-		if parsed, err := time.ParseDuration(d); err == nil && parsed > 0 {
-			drain = parsed
-		}
-	}
+	drain := drainTimeout(r)
 	s.logger.Info("deploy request", "service", name, "drain", drain)
-	if err := s.daemon.DeployService(name, drain); err != nil {
+	if r.URL.Query().Get("stream") == "true" {
+		s.streamDeploy(w, r, name, drain)
+		return
+	}
+	if err := s.daemon.DeployService(r.Context(), name, drain); err != nil {
 		s.logger.Error("deployService: failed to deploy service", "service", name, "error", err)
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": errorMessage("failed to deploy service", err, r)})
+		writeDaemonError(w, r, http.StatusBadRequest, CodeOperationFailed, "failed to deploy service", err)
 		return
 	}
 	writeJSON(w, http.StatusOK, map[string]string{"status": "deployed"})
 }
 
+// Steps of the final event in a streamed deploy.
+const (
+	deployResultDeployed = "deployed"
+	deployResultFailed   = "failed"
+)
+
+// streamDeploy runs a deploy and streams its progress as newline-delimited
+// JSON DeployEvents. A final event with step "deployed" or "failed" (carrying
+// the error) ends the stream.
+func (s *Server) streamDeploy(w http.ResponseWriter, r *http.Request, name string, drain time.Duration) {
+	type streamEvent struct {
+		daemon.DeployEvent
+		Error string `json:"error,omitempty"`
+		Code  string `json:"code,omitempty"`
+	}
+
+	// Check the service exists so an unknown name still gets a plain 400
+	if _, err := s.daemon.ServiceState(name); err != nil {
+		writeDaemonError(w, r, http.StatusBadRequest, CodeOperationFailed, "failed to deploy service", err)
+		return
+	}
+
+	events, cancel := s.daemon.WatchDeploy(name)
+	defer cancel()
+
+	// Deploys may run past the server's write timeout
+	rc := http.NewResponseController(w)
+	rc.SetWriteDeadline(time.Time{})
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	enc := json.NewEncoder(&streamWriter{w: w, rc: rc})
+
+	done := make(chan error, 1)
+	go func() { done <- s.daemon.DeployService(r.Context(), name, drain) }()
+
+	for {
+		select {
+		case ev := <-events:
+			enc.Encode(streamEvent{DeployEvent: ev})
+		case err := <-done:
+			// Events are published before DeployService returns; flush any
+			// still buffered before the result
+			for drained := false; !drained; {
+				select {
+				case ev := <-events:
+					enc.Encode(streamEvent{DeployEvent: ev})
+				default:
+					drained = true
+				}
+			}
+			final := streamEvent{DeployEvent: daemon.DeployEvent{Service: name, Step: deployResultDeployed, Time: time.Now()}}
+			if err != nil {
+				s.logger.Error("deployService: failed to deploy service", "service", name, "error", err)
+				final.Step = deployResultFailed
+				final.Error = errorMessage("failed to deploy service", err, r)
+				final.Code = errorCode(err, CodeOperationFailed)
+			}
+			enc.Encode(final)
+			return
+		}
+	}
+}
+
+func (s *Server) rollbackService(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if s.isExternalGuard(w, name, "roll back") {
+		return
+	}
+	drain := drainTimeout(r)
+	s.logger.Info("rollback request", "service", name, "drain", drain)
+	result, err := s.daemon.RollbackService(name, drain)
+	if err != nil {
+		s.logger.Error("rollbackService: failed to roll back service", "service", name, "error", err)
+		writeDaemonError(w, r, http.StatusBadRequest, CodeOperationFailed, "failed to roll back service", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// maxDeployAllDuration is how long a deploy of every routed service may run,
+// one after another, before its response can no longer be written. It
+// matches how long the client waits.
+const maxDeployAllDuration = 30 * time.Minute
+
+func (s *Server) deployAll(w http.ResponseWriter, r *http.Request) {
+	drain := drainTimeout(r)
+	continueOnError := r.URL.Query().Get("continue_on_error") == "true"
+	s.logger.Info("deploy all request", "drain", drain, "continue_on_error", continueOnError)
+	// Deploying every service in turn can run well past the server's
+	// write timeout, which is sized for a single deploy
+	http.NewResponseController(w).SetWriteDeadline(time.Now().Add(maxDeployAllDuration))
+	result, err := s.daemon.DeployAll(r.Context(), drain, continueOnError)
+	if err != nil {
+		s.logger.Error("deployAll: failed", "error", err)
+		writeDaemonError(w, r, http.StatusBadRequest, CodeOperationFailed, "deploy failed", err)
+		return
+	}
+	status := http.StatusOK
+	if !result.Success {
+		status = http.StatusUnprocessableEntity
+	}
+	writeJSON(w, status, result)
+}
+
+// drainTimeout reads the optional drain query parameter, falling back to the
+// daemon default when it is missing or invalid.
+func drainTimeout(r *http.Request) time.Duration {
+	if d := r.URL.Query().Get("drain"); d != "" {
+		if parsed, err := time.ParseDuration(d); err == nil && parsed > 0 {
+			return parsed
+		}
+	}
+	return daemon.DefaultDrainTimeout
+}
+
 func (s *Server) shipService(w http.ResponseWriter, r *http.Request) {
 	name := r.PathValue("name")
 	s.logger.Info("ship request", "service", name)
-	result, err := s.daemon.ShipService(name)
+	result, err := s.daemon.ShipService(r.Context(), name)
 	if err != nil {
 		s.logger.Error("shipService: failed", "service", name, "error", err)
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": errorMessage("ship failed", err, r)})
+		writeDaemonError(w, r, http.StatusBadRequest, CodeOperationFailed, "ship failed", err)
 		return
 	}
 	status := http.StatusOK
@@ -642,23 +919,101 @@ func (s *Server) serviceLogs(w http.ResponseWriter, r *http.Request) {
 			n = min(parsed, maxLogLines)
 		}
 	}
-	lines, err := s.daemon.ServiceLogs(name, n)
+	q, err := parseLogQuery(r, time.Now())
 	if err != nil {
-		writeJSON(w, http.StatusNotFound, map[string]string{"error": errorMessage("service not found", err, r)})
+		writeError(w, http.StatusBadRequest, CodeBadRequest, err.Error())
 		return
 	}
-	writeJSON(w, http.StatusOK, map[string]any{"lines": lines})
+	q.Limit = n
+	lines, err := s.daemon.QueryServiceLogs(name, q)
+	if err != nil {
+		writeDaemonError(w, r, http.StatusNotFound, CodeServiceNotFound, "service not found", err)
+		return
+	}
+	// ?timestamps=true returns each line with the time it was written
+	if r.URL.Query().Get("timestamps") == "true" {
+		if lines == nil {
+			lines = []logbuf.Line{}
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"entries": lines})
+		return
+	}
+	texts := make([]string, len(lines))
+	for i, l := range lines {
+		texts[i] = l.Text
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"lines": texts})
 }
+
+// maxGrepPattern bounds the grep parameter. Go regexps run in linear time,
+// so this caps compile cost and per-line work rather than backtracking.
+const maxGrepPattern = 256
+
+// parseLogQuery reads the grep, regex and since filters of the logs endpoint.
+// grep is a substring, or a regular expression when regex=true. since is a
+// duration before now (e.g. 15m) or an RFC 3339 timestamp.
+func parseLogQuery(r *http.Request, now time.Time) (logbuf.Query, error) {
+	var q logbuf.Query
+	params := r.URL.Query()
+
+	if pattern := params.Get("grep"); pattern != "" {
+		if len(pattern) > maxGrepPattern {
+			return q, fmt.Errorf("grep pattern longer than %d bytes", maxGrepPattern)
+		}
+		if params.Get("regex") == "true" {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return q, fmt.Errorf("invalid grep regex: %w", err)
+			}
+			q.Match = re.MatchString
+		} else {
+			q.Match = func(line string) bool { return strings.Contains(line, pattern) }
+		}
+	}
+
+	if since := params.Get("since"); since != "" {
+		t, err := parseSince(since, now)
+		if err != nil {
+			return q, err
+		}
+		q.Since = t
+	}
+	return q, nil
+}
+
+// parseSince reads a since parameter: a duration before now (e.g. 15m) or an
+// RFC 3339 timestamp.
+func parseSince(since string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(since); err == nil && d >= 0 {
+		return now.Add(-d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, since); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid since %q: want a duration like 15m or an RFC 3339 time", since)
+}
+
 func (s *Server) reload(w http.ResponseWriter, r *http.Request) {
 	result, err := s.daemon.Reload(r.Context())
 	if err != nil {
 		s.logger.Error("reload: failed to reload daemon", "error", err)
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": errorMessage("reload failed", err, r)})
+		writeDaemonError(w, r, http.StatusInternalServerError, CodeInternal, "reload failed", err)
 		return
 	}
 	writeJSON(w, http.StatusOK, result)
 }
 
+// maintenance releases every service so the daemon can be restarted or the
+// host serviced without touching the processes; see Daemon.EnterMaintenance.
+func (s *Server) maintenance(w http.ResponseWriter, r *http.Request) {
+	if err := s.daemon.EnterMaintenance(daemon.DefaultStopTimeout); err != nil {
+		s.logger.Error("maintenance: failed to release services", "error", err)
+		writeDaemonError(w, r, http.StatusInternalServerError, CodeOperationFailed, "failed to enter maintenance mode", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "maintenance"})
+}
+
 func (s *Server) gpuInfo(w http.ResponseWriter, r *http.Request) {
 	if s.gpu == nil {
 		writeJSON(w, http.StatusOK, map[string]string{"status": "unavailable"})
@@ -667,10 +1022,36 @@ func (s *Server) gpuInfo(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, s.gpu.Info())
 }
 
+// gpuHistory returns the observer's retained samples, optionally only those
+// after ?since= (a duration like 5m or an RFC 3339 time).
+func (s *Server) gpuHistory(w http.ResponseWriter, r *http.Request) {
+	if s.gpu == nil {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "unavailable"})
+		return
+	}
+	var since time.Time
+	if v := r.URL.Query().Get("since"); v != "" {
+		t, err := parseSince(v, time.Now())
+		if err != nil {
+			writeError(w, http.StatusBadRequest, CodeBadRequest, err.Error())
+			return
+		}
+		since = t
+	}
+	samples := s.gpu.History(since)
+	if samples == nil {
+		samples = []gpu.Info{}
+	}
+	writeJSON(w, http.StatusOK, gpu.Series{
+		Interval: s.gpu.Interval().String(),
+		Samples:  samples,
+	})
+}
+
 func (s *Server) systemInfo(w http.ResponseWriter, r *http.Request) {
 	snap, err := sysinfo.Snapshot()
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
 		return
 	}
 	writeJSON(w, http.StatusOK, snap)
@@ -680,14 +1061,106 @@ func (s *Server) health(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
 }
 
+// summary returns the service counts, plus the GPU thermal state when the
+// observer has one. A serious or critical thermal state also clears ok.
+func (s *Server) summary(w http.ResponseWriter, r *http.Request) {
+	sum := s.daemon.StatusSummary()
+	if s.gpu != nil {
+		sum.ThermalState = s.gpu.Info().ThermalState
+		if sum.ThermalState == "serious" || sum.ThermalState == "critical" {
+			sum.OK = false
+		}
+	}
+	writeJSON(w, http.StatusOK, sum)
+}
+
+func (s *Server) info(w http.ResponseWriter, r *http.Request) {
+	info := s.daemon.Info()
+	info.Version = s.version
+	s.tokenMu.RLock()
+	info.TCPAPIEnabled = s.token != ""
+	s.tokenMu.RUnlock()
+	writeJSON(w, http.StatusOK, info)
+}
+
+func (s *Server) routing(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.daemon.Routing())
+}
+
 func writeJSON(w http.ResponseWriter, status int, v any) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
-	if err := json.NewEncoder(w).Encode(v); err != nil {
+	// A read that outlived its timeout has already had its 503 written
+	if err := json.NewEncoder(w).Encode(v); err != nil && !errors.Is(err, http.ErrHandlerTimeout) {
 		slog.Error("failed to encode JSON response", "error", err)
 	}
 }
 
+// Error codes returned in the "code" field of error responses. Unlike the
+// message, which may change and is generic for TCP clients, a code is
+// stable and safe for clients to switch on.
+const (
+	CodeBadRequest         = "bad_request"
+	CodeValidationFailed   = "validation_failed"
+	CodeServiceNotFound    = "service_not_found"
+	CodeServiceExists      = "service_exists"
+	CodeExternalNotAllowed = "external_not_allowed"
+	CodeDeployInProgress   = "deploy_in_progress"
+	CodeServiceBusy        = "service_busy"
+	CodeServiceDisabled    = "service_disabled"
+	CodeOperationFailed    = "operation_failed"
+	CodeNotReady           = "not_ready"
+	CodeNodeNotFound       = "node_not_found"
+	CodePeerError          = "peer_error"
+	CodeSecretNotFound     = "secret_not_found"
+	CodeUnauthorized       = "unauthorized"
+	CodeForbidden          = "forbidden"
+	CodeRateLimited        = "rate_limited"
+	CodeTooLarge           = "payload_too_large"
+	CodeNotConfigured      = "not_configured"
+	CodeMaintenance        = "maintenance"
+	CodeTimeout            = "timeout"
+	CodeInternal           = "internal_error"
+)
+
+// errorResponse is the body of every error response.
+type errorResponse struct {
+	Error string `json:"error"`
+	Code  string `json:"code"`
+}
+
+// writeError writes an error response with the given status, code and message.
+func writeError(w http.ResponseWriter, status int, code, msg string) {
+	writeJSON(w, status, errorResponse{Error: msg, Code: code})
+}
+
+// writeDaemonError writes an error returned by the daemon. The code comes
+// from errorCode, falling back to code for errors with no more specific
+// meaning; the message is generic for TCP clients as in errorMessage.
+func writeDaemonError(w http.ResponseWriter, r *http.Request, status int, code, generic string, err error) {
+	writeError(w, status, errorCode(err, code), errorMessage(generic, err, r))
+}
+
+// errorCode maps the daemon's sentinel errors to their codes, returning
+// fallback for any other error.
+func errorCode(err error, fallback string) string {
+	switch {
+	case errors.Is(err, daemon.ErrServiceNotFound):
+		return CodeServiceNotFound
+	case errors.Is(err, daemon.ErrServiceExists):
+		return CodeServiceExists
+	case errors.Is(err, daemon.ErrDeployInProgress):
+		return CodeDeployInProgress
+	case errors.Is(err, daemon.ErrServiceBusy):
+		return CodeServiceBusy
+	case errors.Is(err, daemon.ErrServiceDisabled):
+		return CodeServiceDisabled
+	case errors.Is(err, daemon.ErrMaintenance):
+		return CodeMaintenance
+	}
+	return fallback
+}
+
 // errorMessage returns the full error for Unix socket clients (already
 // authenticated by file permissions) or a generic message for TCP clients.
 func errorMessage(generic string, err error, r *http.Request) string {
@@ -709,6 +1182,14 @@ func (s *Server) SetNodeName(name string) {
 	s.nodeName = name
 }
 
+// SetReadTimeout sets how long the plain read endpoints run before giving
+// up with a timeout error. Values <= 0 keep the default (30s).
+func (s *Server) SetReadTimeout(timeout time.Duration) {
+	if timeout > 0 {
+		s.readTimeout = timeout
+	}
+}
+
 // SetLaminaRoot sets the workspace root for lamina CLI execution.
 func (s *Server) SetLaminaRoot(root string) {
 	s.laminaRoot = root
@@ -730,24 +1211,18 @@ func (s *Server) SetTokenVendor(vendor *keychain.BaoTokenVendor, nodes []config.
 func (s *Server) openbaoToken(w http.ResponseWriter, r *http.Request) {
 	peer := PeerIdentity(r.Context())
 	if peer == "" || peer == "cli" {
-		writeJSON(w, http.StatusForbidden, map[string]string{
-			"error": "token vending requires mTLS authentication",
-		})
+		writeError(w, http.StatusForbidden, CodeForbidden, "token vending requires mTLS authentication")
 		return
 	}
 
 	if s.tokenVendor == nil {
-		writeJSON(w, http.StatusServiceUnavailable, map[string]string{
-			"error": "token vending not configured",
-		})
+		writeError(w, http.StatusServiceUnavailable, CodeNotConfigured, "token vending not configured")
 		return
 	}
 
 	if !s.knownNodes[peer] {
 		s.logger.Warn("token vend rejected: unknown node", "peer", peer)
-		writeJSON(w, http.StatusForbidden, map[string]string{
-			"error": fmt.Sprintf("unknown node %q", peer),
-		})
+		writeError(w, http.StatusForbidden, CodeForbidden, fmt.Sprintf("unknown node %q", peer))
 		return
 	}
 
@@ -757,9 +1232,7 @@ func (s *Server) openbaoToken(w http.ResponseWriter, r *http.Request) {
 	resp, err := s.tokenVendor.VendToken([]string{policy}, ttl)
 	if err != nil {
 		s.logger.Error("token vend failed", "peer", peer, "error", err)
-		writeJSON(w, http.StatusBadGateway, map[string]string{
-			"error": "failed to create token",
-		})
+		writeError(w, http.StatusBadGateway, CodePeerError, "failed to create token")
 		return
 	}
 
@@ -786,33 +1259,25 @@ func (s *Server) SetPKIIssuer(issuer *keychain.BaoPKIIssuer, nodes []config.Node
 func (s *Server) pkiRenew(w http.ResponseWriter, r *http.Request) {
 	peer := PeerIdentity(r.Context())
 	if peer == "" || peer == "cli" {
-		writeJSON(w, http.StatusForbidden, map[string]string{
-			"error": "certificate renewal requires mTLS authentication",
-		})
+		writeError(w, http.StatusForbidden, CodeForbidden, "certificate renewal requires mTLS authentication")
 		return
 	}
 
 	if s.pkiIssuer == nil {
-		writeJSON(w, http.StatusServiceUnavailable, map[string]string{
-			"error": "PKI issuer not configured",
-		})
+		writeError(w, http.StatusServiceUnavailable, CodeNotConfigured, "PKI issuer not configured")
 		return
 	}
 
 	if !s.knownNodes[peer] {
 		s.logger.Warn("pki renew rejected: unknown node", "peer", peer)
-		writeJSON(w, http.StatusForbidden, map[string]string{
-			"error": fmt.Sprintf("unknown node %q", peer),
-		})
+		writeError(w, http.StatusForbidden, CodeForbidden, fmt.Sprintf("unknown node %q", peer))
 		return
 	}
 
 	cert, err := s.pkiIssuer.IssueNodeCert(peer, "72h")
 	if err != nil {
 		s.logger.Error("pki renew failed", "peer", peer, "error", err)
-		writeJSON(w, http.StatusBadGateway, map[string]string{
-			"error": "failed to issue certificate",
-		})
+		writeError(w, http.StatusBadGateway, CodePeerError, "failed to issue certificate")
 		return
 	}
 
@@ -826,24 +1291,18 @@ func (s *Server) pkiRenew(w http.ResponseWriter, r *http.Request) {
 func (s *Server) pkiIssue(w http.ResponseWriter, r *http.Request) {
 	peer := PeerIdentity(r.Context())
 	if peer == "" || peer == "cli" {
-		writeJSON(w, http.StatusForbidden, map[string]string{
-			"error": "certificate issuance requires mTLS authentication",
-		})
+		writeError(w, http.StatusForbidden, CodeForbidden, "certificate issuance requires mTLS authentication")
 		return
 	}
 
 	if s.pkiIssuer == nil {
-		writeJSON(w, http.StatusServiceUnavailable, map[string]string{
-			"error": "PKI issuer not configured",
-		})
+		writeError(w, http.StatusServiceUnavailable, CodeNotConfigured, "PKI issuer not configured")
 		return
 	}
 
 	if !s.knownNodes[peer] {
 		s.logger.Warn("pki issue rejected: unknown node", "peer", peer)
-		writeJSON(w, http.StatusForbidden, map[string]string{
-			"error": fmt.Sprintf("unknown node %q", peer),
-		})
+		writeError(w, http.StatusForbidden, CodeForbidden, fmt.Sprintf("unknown node %q", peer))
 		return
 	}
 
@@ -853,12 +1312,12 @@ func (s *Server) pkiIssue(w http.ResponseWriter, r *http.Request) {
 		TTL        string `json:"ttl"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "invalid request body")
 		return
 	}
 
 	if req.Role == "" || req.CommonName == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "role and common_name are required"})
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "role and common_name are required")
 		return
 	}
 	if req.TTL == "" {
@@ -868,9 +1327,7 @@ func (s *Server) pkiIssue(w http.ResponseWriter, r *http.Request) {
 	cert, err := s.pkiIssuer.Issue(req.Role, req.CommonName, req.TTL)
 	if err != nil {
 		s.logger.Error("pki issue failed", "peer", peer, "role", req.Role, "cn", req.CommonName, "error", err)
-		writeJSON(w, http.StatusBadGateway, map[string]string{
-			"error": "failed to issue certificate",
-		})
+		writeError(w, http.StatusBadGateway, CodePeerError, "failed to issue certificate")
 		return
 	}
 
@@ -1037,7 +1494,7 @@ func (s *Server) clusterServiceLogs(w http.ResponseWriter, r *http.Request) {
 	targetNode := r.URL.Query().Get("node")
 
 	if targetNode == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "node query parameter required"})
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "node query parameter required")
 		return
 	}
 
@@ -1057,7 +1514,7 @@ func (s *Server) clusterServiceLogs(w http.ResponseWriter, r *http.Request) {
 	if targetNode == nodeName {
 		lines, err := s.daemon.ServiceLogs(name, n)
 		if err != nil {
-			writeJSON(w, http.StatusNotFound, map[string]string{"error": errorMessage("service not found", err, r)})
+			writeDaemonError(w, r, http.StatusNotFound, CodeServiceNotFound, "service not found", err)
 			return
 		}
 		writeJSON(w, http.StatusOK, map[string]any{"lines": lines})
@@ -1068,13 +1525,13 @@ func (s *Server) clusterServiceLogs(w http.ResponseWriter, r *http.Request) {
 	peers := s.daemon.Peers()
 	peer, ok := peers[targetNode]
 	if !ok {
-		writeJSON(w, http.StatusNotFound, map[string]string{"error": fmt.Sprintf("node %q not found", targetNode)})
+		writeError(w, http.StatusNotFound, CodeNodeNotFound, fmt.Sprintf("node %q not found", targetNode))
 		return
 	}
 
 	lines, err := peer.Logs(name, n)
 	if err != nil {
-		writeJSON(w, http.StatusBadGateway, map[string]string{"error": err.Error()})
+		writeError(w, http.StatusBadGateway, CodePeerError, err.Error())
 		return
 	}
 	writeJSON(w, http.StatusOK, map[string]any{"lines": lines})
@@ -1086,7 +1543,7 @@ func (s *Server) clusterServiceAction(w http.ResponseWriter, r *http.Request) {
 	targetNode := r.URL.Query().Get("node")
 
 	if targetNode == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "node query parameter required"})
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "node query parameter required")
 		return
 	}
 
@@ -1104,7 +1561,7 @@ func (s *Server) clusterServiceAction(w http.ResponseWriter, r *http.Request) {
 	peers := s.daemon.Peers()
 	peer, ok := peers[targetNode]
 	if !ok {
-		writeJSON(w, http.StatusNotFound, map[string]string{"error": fmt.Sprintf("node %q not found", targetNode)})
+		writeError(w, http.StatusNotFound, CodeNodeNotFound, fmt.Sprintf("node %q not found", targetNode))
 		return
 	}
 
@@ -1120,34 +1577,39 @@ func (s *Server) clusterServiceAction(w http.ResponseWriter, r *http.Request) {
 	case "deploy":
 		err = peer.DeployService(name)
 	default:
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("unknown action %q", action)})
+		writeError(w, http.StatusBadRequest, CodeBadRequest, fmt.Sprintf("unknown action %q", action))
 		return
 	}
 
 	if err != nil {
-		writeJSON(w, http.StatusBadGateway, map[string]string{"error": err.Error()})
+		writeError(w, http.StatusBadGateway, CodePeerError, err.Error())
 		return
 	}
 	writeJSON(w, http.StatusOK, map[string]string{"status": action + "ing", "node": targetNode})
 }
 
 func (s *Server) routeLocalAction(w http.ResponseWriter, r *http.Request, name, action string) {
-	var err error
+	unlock, err := s.daemon.LockService(name)
+	if err != nil {
+		writeError(w, http.StatusConflict, CodeServiceBusy, err.Error())
+		return
+	}
+	defer unlock()
 	switch action {
 	case "start":
 		err = s.daemon.StartService(r.Context(), name)
 	case "stop":
-		err = s.daemon.StopService(name, daemon.DefaultStopTimeout)
+		err = s.daemon.StopService(r.Context(), name, daemon.DefaultStopTimeout)
 	case "restart":
-		err = s.daemon.RestartService(name, daemon.DefaultStopTimeout)
+		err = s.daemon.RestartService(r.Context(), name, daemon.DefaultStopTimeout)
 	case "deploy":
-		err = s.daemon.DeployService(name, daemon.DefaultDrainTimeout)
+		err = s.daemon.DeployService(r.Context(), name, daemon.DefaultDrainTimeout)
 	default:
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("unknown action %q", action)})
+		writeError(w, http.StatusBadRequest, CodeBadRequest, fmt.Sprintf("unknown action %q", action))
 		return
 	}
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		writeError(w, http.StatusBadRequest, errorCode(err, CodeOperationFailed), err.Error())
 		return
 	}
 	writeJSON(w, http.StatusOK, map[string]string{"status": action + "ing"})
@@ -1162,7 +1624,7 @@ func (s *Server) tokenRotate(w http.ResponseWriter, r *http.Request) {
 
 	newToken, err := s.RotateToken()
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		writeError(w, http.StatusInternalServerError, CodeInternal, err.Error())
 		return
 	}
 
@@ -1210,9 +1672,7 @@ func (s *Server) peerTokenUpdate(w http.ResponseWriter, r *http.Request) {
 	// Require mTLS (cert identity, not CLI bearer token)
 	peer := PeerIdentity(r.Context())
 	if peer == "" || peer == "cli" {
-		writeJSON(w, http.StatusForbidden, map[string]string{
-			"error": "peer token update requires mTLS authentication",
-		})
+		writeError(w, http.StatusForbidden, CodeForbidden, "peer token update requires mTLS authentication")
 		return
 	}
 
@@ -1221,11 +1681,11 @@ func (s *Server) peerTokenUpdate(w http.ResponseWriter, r *http.Request) {
 		Token string `json:"token"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "invalid request body")
 		return
 	}
 	if req.Node == "" || req.Token == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "node and token required"})
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "node and token required")
 		return
 	}
 
@@ -1236,9 +1696,7 @@ func (s *Server) peerTokenUpdate(w http.ResponseWriter, r *http.Request) {
 	}
 	if err := config.UpdateNodeToken(cfgPath, req.Node, req.Token); err != nil {
 		s.logger.Error("failed to update peer token", "peer", peer, "node", req.Node, "error", err)
-		writeJSON(w, http.StatusInternalServerError, map[string]string{
-			"error": errorMessage("failed to update token", err, r),
-		})
+		writeError(w, http.StatusInternalServerError, CodeInternal, errorMessage("failed to update token", err, r))
 		return
 	}
 
@@ -12,24 +12,30 @@ import (
 	"encoding/json"
 	"encoding/pem"
 	"fmt"
+	"io"
 	"log/slog"
 	"math/big"
 	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"syscall"
 	"testing"
 	"time"
 
+	"github.com/benaskins/aurelia/internal/audit"
 	"github.com/benaskins/aurelia/internal/config"
 	"github.com/benaskins/aurelia/internal/daemon"
+	"github.com/benaskins/aurelia/internal/driver"
 	"github.com/benaskins/aurelia/internal/keychain"
 	"github.com/benaskins/aurelia/internal/node"
 )
 
-func setupTestServer(t *testing.T, specs map[string]string) (*Server, *http.Client) {
+func setupTestServer(t *testing.T, specs map[string]string, opts ...daemon.Option) (*Server, *http.Client) {
 	t.Helper()
 
 	dir := t.TempDir()
@@ -39,7 +45,7 @@ func setupTestServer(t *testing.T, specs map[string]string) (*Server, *http.Clie
 		}
 	}
 
-	d := daemon.NewDaemon(dir)
+	d := daemon.NewDaemon(dir, opts...)
 	ctx, cancel := context.WithCancel(context.Background())
 	t.Cleanup(cancel)
 
@@ -51,7 +57,7 @@ func setupTestServer(t *testing.T, specs map[string]string) (*Server, *http.Clie
 	// Wait for processes to start
 	time.Sleep(100 * time.Millisecond)
 
-	srv := NewServer(d, nil)
+	srv := NewServer(d, nil, "test")
 
 	// Use a random Unix socket
 	sockPath := filepath.Join(t.TempDir(), "test.sock")
@@ -100,6 +106,215 @@ func TestHealthEndpoint(t *testing.T) {
 	}
 }
 
+func TestExecEndpointStreamsOutputAndExitCode(t *testing.T) {
+	_, client := setupTestServer(t, map[string]string{
+		"svc.yaml": `
+service:
+  name: test-svc
+  type: native
+  command: "sleep 30"
+
+env:
+  GREETING: hello
+`,
+	})
+
+	body := strings.NewReader(`{"argv": ["sh", "-c", "echo $GREETING; exit 3"]}`)
+	resp, err := client.Post("http://aurelia/v1/services/test-svc/exec", "application/json", body)
+	if err != nil {
+		t.Fatalf("POST exec: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+
+	out, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("reading body: %v", err)
+	}
+	if strings.TrimSpace(string(out)) != "hello" {
+		t.Errorf("expected output 'hello', got %q", out)
+	}
+	if code := resp.Trailer.Get("X-Exit-Code"); code != "3" {
+		t.Errorf("expected exit code trailer 3, got %q", code)
+	}
+
+	// Empty argv and unknown services are rejected before anything runs
+	resp2, err := client.Post("http://aurelia/v1/services/test-svc/exec", "application/json", strings.NewReader(`{"argv": []}`))
+	if err != nil {
+		t.Fatalf("POST exec: %v", err)
+	}
+	resp2.Body.Close()
+	if resp2.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 for empty argv, got %d", resp2.StatusCode)
+	}
+	resp3, err := client.Post("http://aurelia/v1/services/missing/exec", "application/json", strings.NewReader(`{"argv": ["true"]}`))
+	if err != nil {
+		t.Fatalf("POST exec: %v", err)
+	}
+	resp3.Body.Close()
+	if resp3.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 for unknown service, got %d", resp3.StatusCode)
+	}
+}
+
+func TestStartEndpointWaitsForReady(t *testing.T) {
+	_, client := setupTestServer(t, map[string]string{
+		"good.yaml": `
+service:
+  name: good
+  type: native
+  command: "sleep 30"
+
+health:
+  type: exec
+  command: "true"
+  interval: 100ms
+  timeout: 1s
+`,
+		"bad.yaml": `
+service:
+  name: bad
+  type: native
+  command: "sleep 30"
+
+health:
+  type: exec
+  command: "false"
+  interval: 100ms
+  timeout: 1s
+`,
+	})
+
+	startAndWait := func(name, wait string) (int, map[string]any) {
+		t.Helper()
+		stop, err := client.Post("http://aurelia/v1/services/"+name+"/stop", "application/json", nil)
+		if err != nil {
+			t.Fatalf("POST stop: %v", err)
+		}
+		stop.Body.Close()
+
+		resp, err := client.Post("http://aurelia/v1/services/"+name+"/start?wait="+wait, "application/json", nil)
+		if err != nil {
+			t.Fatalf("POST start: %v", err)
+		}
+		defer resp.Body.Close()
+		var body map[string]any
+		json.NewDecoder(resp.Body).Decode(&body)
+		return resp.StatusCode, body
+	}
+
+	code, body := startAndWait("good", "5s")
+	if code != http.StatusOK {
+		t.Fatalf("expected 200 for healthy service, got %d: %v", code, body)
+	}
+	st, _ := body["state"].(map[string]any)
+	if st["state"] != "running" || st["health"] != "healthy" {
+		t.Errorf("expected running/healthy state, got %v", body["state"])
+	}
+
+	code, body = startAndWait("bad", "300ms")
+	if code != http.StatusGatewayTimeout {
+		t.Fatalf("expected 504 for service that never gets healthy, got %d: %v", code, body)
+	}
+	if body["error"] == nil || body["state"] == nil {
+		t.Errorf("expected error and last state, got %v", body)
+	}
+
+	resp, err := client.Post("http://aurelia/v1/services/good/restart?wait=soon", "application/json", nil)
+	if err != nil {
+		t.Fatalf("POST restart: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 for invalid wait, got %d", resp.StatusCode)
+	}
+}
+
+func TestInfoEndpoint(t *testing.T) {
+	_, client := setupTestServer(t, map[string]string{
+		"svc.yaml": `
+service:
+  name: test-svc
+  type: native
+  command: "sleep 30"
+`,
+	})
+
+	getInfo := func() daemon.Info {
+		t.Helper()
+		resp, err := client.Get("http://aurelia/v1/info")
+		if err != nil {
+			t.Fatalf("GET /v1/info: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != 200 {
+			t.Fatalf("expected 200, got %d", resp.StatusCode)
+		}
+		var info daemon.Info
+		if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+			t.Fatalf("decoding info: %v", err)
+		}
+		return info
+	}
+
+	first := getInfo()
+	if first.Version != "test" {
+		t.Errorf("expected version 'test', got %q", first.Version)
+	}
+	if first.StartedAt.IsZero() {
+		t.Error("expected started_at to be set")
+	}
+	if first.SpecDir == "" {
+		t.Error("expected spec_dir to be set")
+	}
+	if first.Services[driver.StateRunning] != 1 {
+		t.Errorf("expected 1 running service, got %v", first.Services)
+	}
+	if first.RoutingEnabled || first.TCPAPIEnabled {
+		t.Errorf("expected routing and TCP API disabled, got routing=%v tcp=%v", first.RoutingEnabled, first.TCPAPIEnabled)
+	}
+	if first.PortMin == 0 || first.PortMax <= first.PortMin {
+		t.Errorf("unexpected port range %d-%d", first.PortMin, first.PortMax)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if second := getInfo(); second.UptimeSeconds <= first.UptimeSeconds {
+		t.Errorf("expected uptime to increase, got %v then %v", first.UptimeSeconds, second.UptimeSeconds)
+	}
+}
+
+func TestSummaryEndpoint(t *testing.T) {
+	_, client := setupTestServer(t, map[string]string{
+		"svc.yaml": `
+service:
+  name: test-svc
+  type: native
+  command: "sleep 30"
+`,
+	})
+
+	resp, err := client.Get("http://aurelia/v1/summary")
+	if err != nil {
+		t.Fatalf("GET /v1/summary: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	var sum daemon.StatusSummary
+	if err := json.NewDecoder(resp.Body).Decode(&sum); err != nil {
+		t.Fatalf("decoding summary: %v", err)
+	}
+	if sum.Total != 1 || sum.Running != 1 || !sum.OK || len(sum.Attention) != 0 {
+		t.Errorf("expected one running service and ok, got %+v", sum)
+	}
+	if sum.ThermalState != "" {
+		t.Errorf("expected no thermal state without a GPU observer, got %q", sum.ThermalState)
+	}
+}
+
 func TestListServices(t *testing.T) {
 	_, client := setupTestServer(t, map[string]string{
 		"svc.yaml": `
@@ -277,6 +492,183 @@ service:
 	}
 }
 
+func TestRestartServiceAudited(t *testing.T) {
+	auditPath := filepath.Join(t.TempDir(), "audit.log")
+	auditLog, err := audit.NewLogger(auditPath)
+	if err != nil {
+		t.Fatalf("NewLogger: %v", err)
+	}
+	t.Cleanup(func() { auditLog.Close() })
+
+	srv, client := setupTestServer(t, map[string]string{
+		"svc.yaml": `
+service:
+  name: audit-svc
+  type: native
+  command: "sleep 30"
+`,
+	}, daemon.WithAuditLog(auditLog))
+
+	lastEntry := func() audit.Entry {
+		t.Helper()
+		data, err := os.ReadFile(auditPath)
+		if err != nil {
+			t.Fatalf("reading audit log: %v", err)
+		}
+		lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+		var e audit.Entry
+		if err := json.Unmarshal([]byte(lines[len(lines)-1]), &e); err != nil {
+			t.Fatalf("parsing audit entry %q: %v", lines[len(lines)-1], err)
+		}
+		return e
+	}
+
+	// Over the Unix socket the actor is local
+	resp, err := client.Post("http://aurelia/v1/services/audit-svc/restart", "application/json", nil)
+	if err != nil {
+		t.Fatalf("POST restart: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d", resp.StatusCode)
+	}
+	e := lastEntry()
+	if e.Action != audit.ActionServiceRestart || e.Service != "audit-svc" || e.Actor != "local" || e.Error != "" {
+		t.Errorf("unexpected audit entry for a local restart: %+v", e)
+	}
+
+	// Over TCP the actor identifies the token without revealing it
+	if err := srv.GenerateToken(filepath.Join(t.TempDir(), "api.token")); err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+	ts := httptest.NewServer(srv.requireToken(srv.server.Handler))
+	t.Cleanup(ts.Close)
+	if code := doWithToken(t, http.MethodPost, ts.URL+"/v1/services/audit-svc/restart", srv.token); code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d", code)
+	}
+	e = lastEntry()
+	if e.Action != audit.ActionServiceRestart || e.Service != "audit-svc" || !strings.HasPrefix(e.Actor, "token:") {
+		t.Errorf("unexpected audit entry for a token restart: %+v", e)
+	}
+	if strings.Contains(e.Actor, srv.token) {
+		t.Errorf("audit actor %q reveals the token", e.Actor)
+	}
+}
+
+func TestConcurrentRestartsServiceBusy(t *testing.T) {
+	// The health check never passes, so a restart that waits for ready
+	// holds the service for the whole wait
+	_, client := setupTestServer(t, map[string]string{
+		"svc.yaml": `
+service:
+  name: busy-svc
+  type: native
+  command: "sleep 30"
+
+health:
+  type: exec
+  command: "false"
+  interval: 100ms
+  timeout: 1s
+`,
+	})
+
+	type result struct {
+		code int
+		body map[string]any
+	}
+	results := make(chan result, 2)
+	start := make(chan struct{})
+	for range 2 {
+		go func() {
+			<-start
+			resp, err := client.Post("http://aurelia/v1/services/busy-svc/restart?wait=1s", "application/json", nil)
+			if err != nil {
+				results <- result{}
+				return
+			}
+			defer resp.Body.Close()
+			var body map[string]any
+			json.NewDecoder(resp.Body).Decode(&body)
+			results <- result{resp.StatusCode, body}
+		}()
+	}
+	close(start)
+
+	var busy, done int
+	for range 2 {
+		r := <-results
+		switch r.code {
+		case http.StatusConflict:
+			busy++
+			if r.body["code"] != CodeServiceBusy {
+				t.Errorf("expected code %q on the busy response, got %v", CodeServiceBusy, r.body["code"])
+			}
+		case http.StatusGatewayTimeout:
+			done++
+		default:
+			t.Errorf("unexpected response %d: %v", r.code, r.body)
+		}
+	}
+	if busy != 1 || done != 1 {
+		t.Errorf("expected one restart to run and one to be busy, got %d run and %d busy", done, busy)
+	}
+
+	// Once the first restart finishes the service is free again
+	resp, err := client.Post("http://aurelia/v1/services/busy-svc/restart", "application/json", nil)
+	if err != nil {
+		t.Fatalf("POST restart: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		t.Errorf("expected 202 after the first restart finished, got %d", resp.StatusCode)
+	}
+}
+
+func TestRestartServiceCascade(t *testing.T) {
+	_, client := setupTestServer(t, map[string]string{
+		"db.yaml": `
+service:
+  name: db
+  type: native
+  command: "sleep 30"
+`,
+		"app.yaml": `
+service:
+  name: app
+  type: native
+  command: "sleep 30"
+dependencies:
+  after: [db]
+  requires: [db]
+`,
+	})
+	waitForServiceState(t, client, "app", driver.StateRunning)
+
+	resp, err := client.Post("http://aurelia/v1/services/db/restart?cascade=true", "application/json", nil)
+	if err != nil {
+		t.Fatalf("POST restart: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+
+	// The response comes after the dependents are back up
+	resp, err = client.Get("http://aurelia/v1/services/app")
+	if err != nil {
+		t.Fatalf("GET app: %v", err)
+	}
+	defer resp.Body.Close()
+	var st struct {
+		State driver.State `json:"state"`
+	}
+	json.NewDecoder(resp.Body).Decode(&st)
+	if st.State != driver.StateRunning {
+		t.Errorf("expected app running after cascade restart, got %s", st.State)
+	}
+}
+
 func TestReload(t *testing.T) {
 	_, client := setupTestServer(t, map[string]string{
 		"svc.yaml": `
@@ -374,7 +766,7 @@ func TestTCPAuthRequired(t *testing.T) {
 	}
 	t.Cleanup(func() { d.Stop(5 * time.Second) })
 
-	srv := NewServer(d, nil)
+	srv := NewServer(d, nil, "test")
 	tokenPath := filepath.Join(t.TempDir(), "api.token")
 	if err := srv.GenerateToken(tokenPath); err != nil {
 		t.Fatalf("GenerateToken: %v", err)
@@ -439,7 +831,7 @@ func TestTCPAuthRequired(t *testing.T) {
 }
 
 func TestTCPRequiresToken(t *testing.T) {
-	srv := NewServer(daemon.NewDaemon(t.TempDir()), nil)
+	srv := NewServer(daemon.NewDaemon(t.TempDir()), nil, "test")
 	err := srv.ListenTCP("127.0.0.1:0")
 	if err == nil {
 		t.Fatal("expected error when calling ListenTCP without GenerateToken")
@@ -480,6 +872,90 @@ service:
 	}
 }
 
+func TestServiceLogsFilters(t *testing.T) {
+	_, client := setupTestServer(t, map[string]string{
+		"svc.yaml": `
+service:
+  name: log-svc
+  type: native
+  command: "printf GET/a/200\\nGET/b/500\\nPOST/c/200\\n"
+`,
+	})
+	time.Sleep(200 * time.Millisecond)
+
+	getLines := func(query string) (int, []string) {
+		t.Helper()
+		resp, err := client.Get("http://aurelia/v1/services/log-svc/logs?" + query)
+		if err != nil {
+			t.Fatalf("GET logs: %v", err)
+		}
+		defer resp.Body.Close()
+		var result struct {
+			Lines []string `json:"lines"`
+		}
+		json.NewDecoder(resp.Body).Decode(&result)
+		return resp.StatusCode, result.Lines
+	}
+
+	if _, lines := getLines("grep=GET"); len(lines) != 2 || lines[0] != "GET/a/200" || lines[1] != "GET/b/500" {
+		t.Errorf("substring grep = %v", lines)
+	}
+	// Regex metacharacters are literal without regex=true
+	if _, lines := getLines("grep=" + url.QueryEscape("5..$")); len(lines) != 0 {
+		t.Errorf("expected literal substring match to find nothing, got %v", lines)
+	}
+	if _, lines := getLines("regex=true&grep=" + url.QueryEscape("5..$")); len(lines) != 1 || lines[0] != "GET/b/500" {
+		t.Errorf("regex grep = %v", lines)
+	}
+
+	if _, lines := getLines("since=1h"); len(lines) != 3 {
+		t.Errorf("expected all lines within the last hour, got %v", lines)
+	}
+	future := time.Now().Add(time.Hour).Format(time.RFC3339)
+	if _, lines := getLines("since=" + url.QueryEscape(future)); len(lines) != 0 {
+		t.Errorf("expected no lines since a future time, got %v", lines)
+	}
+
+	for _, bad := range []string{"since=yesterday", "regex=true&grep=" + url.QueryEscape("("), "grep=" + strings.Repeat("x", maxGrepPattern+1)} {
+		if code, _ := getLines(bad); code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", bad, code)
+		}
+	}
+}
+
+func TestServiceLogsTimestamps(t *testing.T) {
+	_, client := setupTestServer(t, map[string]string{
+		"svc.yaml": `
+service:
+  name: log-svc
+  type: native
+  command: "printf one\\ntwo\\n"
+`,
+	})
+	time.Sleep(200 * time.Millisecond)
+
+	resp, err := client.Get("http://aurelia/v1/services/log-svc/logs?timestamps=true&n=1")
+	if err != nil {
+		t.Fatalf("GET logs: %v", err)
+	}
+	defer resp.Body.Close()
+	var result struct {
+		Entries []struct {
+			Time time.Time `json:"ts"`
+			Line string    `json:"line"`
+		} `json:"entries"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatalf("decoding logs: %v", err)
+	}
+	if len(result.Entries) != 1 || result.Entries[0].Line != "two" {
+		t.Fatalf("expected the last line, got %+v", result.Entries)
+	}
+	if ts := result.Entries[0].Time; ts.IsZero() || time.Since(ts) > time.Minute {
+		t.Errorf("expected the time the line was written, got %v", ts)
+	}
+}
+
 func TestListenTCPNonLoopbackWarning(t *testing.T) {
 	d := daemon.NewDaemon(t.TempDir())
 	ctx, cancel := context.WithCancel(context.Background())
@@ -489,7 +965,7 @@ func TestListenTCPNonLoopbackWarning(t *testing.T) {
 	}
 	t.Cleanup(func() { d.Stop(5 * time.Second) })
 
-	srv := NewServer(d, nil)
+	srv := NewServer(d, nil, "test")
 	tokenPath := filepath.Join(t.TempDir(), "api.token")
 	if err := srv.GenerateToken(tokenPath); err != nil {
 		t.Fatalf("GenerateToken: %v", err)
@@ -516,7 +992,7 @@ func TestListenTCPLoopbackNoWarning(t *testing.T) {
 	}
 	t.Cleanup(func() { d.Stop(5 * time.Second) })
 
-	srv := NewServer(d, nil)
+	srv := NewServer(d, nil, "test")
 	tokenPath := filepath.Join(t.TempDir(), "api.token")
 	if err := srv.GenerateToken(tokenPath); err != nil {
 		t.Fatalf("GenerateToken: %v", err)
@@ -559,7 +1035,7 @@ func setupTestServerWithPeers(t *testing.T, specs map[string]string, peers []*no
 
 	time.Sleep(100 * time.Millisecond)
 
-	srv := NewServer(d, nil)
+	srv := NewServer(d, nil, "test")
 
 	// Use /tmp for socket to avoid macOS 104-char Unix socket path limit
 	sockDir, err := os.MkdirTemp("/tmp", "aurelia-test-*")
@@ -825,7 +1301,7 @@ func TestTLSAuthMTLSClient(t *testing.T) {
 	}
 	t.Cleanup(func() { d.Stop(5 * time.Second) })
 
-	srv := NewServer(d, nil)
+	srv := NewServer(d, nil, "test")
 	tokenPath := filepath.Join(t.TempDir(), "api.token")
 	if err := srv.GenerateToken(tokenPath); err != nil {
 		t.Fatalf("GenerateToken: %v", err)
@@ -907,6 +1383,104 @@ func TestTLSAuthMTLSClient(t *testing.T) {
 	}
 }
 
+func TestTLSRequireClientCert(t *testing.T) {
+	certs := generateTestCerts(t, "limen")
+	otherCerts := generateTestCerts(t, "intruder") // signed by a different CA
+
+	d := daemon.NewDaemon(t.TempDir())
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	if err := d.Start(ctx); err != nil {
+		t.Fatalf("daemon start: %v", err)
+	}
+	t.Cleanup(func() { d.Stop(5 * time.Second) })
+
+	srv := NewServer(d, nil, "test")
+	tokenPath := filepath.Join(t.TempDir(), "api.token")
+	if err := srv.GenerateToken(tokenPath); err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+	tokenBytes, _ := os.ReadFile(tokenPath)
+	token := strings.TrimSpace(string(tokenBytes))
+
+	serverTLS, err := LoadTLSConfig(certs.ServerCertPath, certs.ServerKeyPath, certs.CAPath)
+	if err != nil {
+		t.Fatalf("LoadTLSConfig: %v", err)
+	}
+	serverTLS.ClientAuth = tls.RequireAndVerifyClientCert
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	addr := ln.Addr().String()
+	ln.Close() // free the port for ListenTLS
+	go srv.ListenTLS(addr, serverTLS)
+	t.Cleanup(func() { srv.Shutdown(context.Background()) })
+	for i := 0; i < 20; i++ {
+		if conn, err := net.Dial("tcp", addr); err == nil {
+			conn.Close()
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	caPEM, _ := os.ReadFile(certs.CAPath)
+	caPool := x509.NewCertPool()
+	caPool.AppendCertsFromPEM(caPEM)
+	clientFor := func(certPath, keyPath string) *http.Client {
+		cfg := &tls.Config{RootCAs: caPool}
+		if certPath != "" {
+			cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+			if err != nil {
+				t.Fatalf("loading client cert: %v", err)
+			}
+			cfg.Certificates = []tls.Certificate{cert}
+		}
+		return &http.Client{Transport: &http.Transport{TLSClientConfig: cfg}}
+	}
+	get := func(c *http.Client, token string) (*http.Response, error) {
+		req, _ := http.NewRequest("GET", "https://"+addr+"/v1/health", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		return c.Do(req)
+	}
+
+	// No client cert: rejected during the handshake, even with a valid token
+	if resp, err := get(clientFor("", ""), token); err == nil {
+		resp.Body.Close()
+		t.Errorf("expected TLS error without client cert, got status %d", resp.StatusCode)
+	}
+
+	// Client cert from an untrusted CA: rejected during the handshake
+	if resp, err := get(clientFor(otherCerts.ClientCertPath, otherCerts.ClientKeyPath), token); err == nil {
+		resp.Body.Close()
+		t.Errorf("expected TLS error with untrusted client cert, got status %d", resp.StatusCode)
+	}
+
+	// Valid cert but no token: the token is still required
+	mtls := clientFor(certs.ClientCertPath, certs.ClientKeyPath)
+	resp, err := get(mtls, "")
+	if err != nil {
+		t.Fatalf("mTLS GET without token: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("mTLS without token: expected 401, got %d", resp.StatusCode)
+	}
+
+	// Valid cert and token
+	resp, err = get(mtls, token)
+	if err != nil {
+		t.Fatalf("mTLS GET with token: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("mTLS with token: expected 200, got %d", resp.StatusCode)
+	}
+}
+
 func TestTLSPeerIdentityFromCert(t *testing.T) {
 	certs := generateTestCerts(t, "hestia")
 
@@ -919,7 +1493,7 @@ func TestTLSPeerIdentityFromCert(t *testing.T) {
 	t.Cleanup(func() { d.Stop(5 * time.Second) })
 
 	// Custom handler that returns the peer identity
-	srv := NewServer(d, nil)
+	srv := NewServer(d, nil, "test")
 	tokenPath := filepath.Join(t.TempDir(), "api.token")
 	srv.GenerateToken(tokenPath)
 
@@ -997,7 +1571,7 @@ func TestAuditLogMiddleware(t *testing.T) {
 	}
 	t.Cleanup(func() { d.Stop(5 * time.Second) })
 
-	srv := NewServer(d, nil)
+	srv := NewServer(d, nil, "test")
 	tokenPath := filepath.Join(t.TempDir(), "api.token")
 	srv.GenerateToken(tokenPath)
 
@@ -1063,7 +1637,7 @@ func TestTokenRotationDualTokenWindow(t *testing.T) {
 	}
 	t.Cleanup(func() { d.Stop(5 * time.Second) })
 
-	srv := NewServer(d, nil)
+	srv := NewServer(d, nil, "test")
 	tokenPath := filepath.Join(t.TempDir(), "api.token")
 	srv.GenerateToken(tokenPath)
 	oldToken := srv.token
@@ -1108,7 +1682,7 @@ func TestPeerTokenUpdateRequiresMTLS(t *testing.T) {
 	}
 	t.Cleanup(func() { d.Stop(5 * time.Second) })
 
-	srv := NewServer(d, nil)
+	srv := NewServer(d, nil, "test")
 	tokenPath := filepath.Join(t.TempDir(), "api.token")
 	srv.GenerateToken(tokenPath)
 
@@ -1183,7 +1757,7 @@ func TestPKIRenewEndpoint(t *testing.T) {
 	baoStore := keychain.NewBaoStore(pkiSrv.URL, "test-token", "secret")
 	pkiIssuer := keychain.NewBaoPKIIssuer(baoStore, "pki_lamina")
 
-	srv := NewServer(d, nil)
+	srv := NewServer(d, nil, "test")
 	tokenPath := filepath.Join(t.TempDir(), "api.token")
 	srv.GenerateToken(tokenPath)
 	srv.SetPKIIssuer(pkiIssuer, []config.Node{
@@ -1341,7 +1915,7 @@ func TestTLSHotReload(t *testing.T) {
 	}
 	t.Cleanup(func() { d.Stop(5 * time.Second) })
 
-	srv := NewServer(d, nil)
+	srv := NewServer(d, nil, "test")
 	tokenPath := filepath.Join(t.TempDir(), "api.token")
 	srv.GenerateToken(tokenPath)
 
@@ -1393,7 +1967,7 @@ func TestTLSHotReload(t *testing.T) {
 }
 
 func TestListenTLSRequiresToken(t *testing.T) {
-	srv := NewServer(daemon.NewDaemon(t.TempDir()), nil)
+	srv := NewServer(daemon.NewDaemon(t.TempDir()), nil, "test")
 	tlsCfg := &tls.Config{}
 	err := srv.ListenTLS("127.0.0.1:0", tlsCfg)
 	if err == nil {
@@ -1559,6 +2133,222 @@ service:
 
 }
 
+func TestRoutingEndpoint(t *testing.T) {
+	routingPath := filepath.Join(t.TempDir(), "aurelia.yaml")
+	_, client := setupTestServer(t, map[string]string{
+		"chat.yaml": `
+service:
+  name: chat
+  type: native
+  command: "sleep 30"
+
+network:
+  port: 8090
+
+routing:
+  hostname: chat.example.local
+  tls: true
+  tls_options: mtls
+`,
+		"plain.yaml": `
+service:
+  name: plain
+  type: native
+  command: "sleep 30"
+
+network:
+  port: 8091
+`,
+	}, daemon.WithRouting(routingPath))
+
+	resp, err := client.Get("http://aurelia/v1/routing")
+	if err != nil {
+		t.Fatalf("GET /v1/routing: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+
+	var info daemon.RoutingInfo
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !info.Enabled || info.OutputPath != routingPath {
+		t.Errorf("expected routing enabled at %s, got %+v", routingPath, info)
+	}
+	if len(info.Routes) != 1 {
+		t.Fatalf("expected only the routed service, got %+v", info.Routes)
+	}
+	rt := info.Routes[0]
+	if rt.Name != "chat" || rt.Hostname != "chat.example.local" || rt.Port != 8090 || !rt.TLS || rt.TLSOptions != "mtls" {
+		t.Errorf("unexpected route: %+v", rt)
+	}
+}
+
+func TestDeployAllEndpoint(t *testing.T) {
+	_, client := setupTestServer(t, map[string]string{
+		"chat.yaml": `
+service:
+  name: chat
+  type: native
+  command: "sleep 30"
+
+network:
+  port: 0
+
+routing:
+  hostname: chat.example.local
+`,
+		"plain.yaml": `
+service:
+  name: plain
+  type: native
+  command: "sleep 30"
+`,
+	}, daemon.WithRouting(filepath.Join(t.TempDir(), "aurelia.yaml")), daemon.WithPortRange(27400, 27500))
+
+	resp, err := client.Post("http://aurelia/v1/deploy?drain=10ms", "application/json", nil)
+	if err != nil {
+		t.Fatalf("POST /v1/deploy: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+
+	var result daemon.DeployAllResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !result.Success || len(result.Services) != 1 {
+		t.Fatalf("expected one successful deploy, got %+v", result)
+	}
+	if step := result.Services[0]; step.Service != "chat" || step.Status != "deployed" {
+		t.Errorf("unexpected step: %+v", step)
+	}
+}
+
+func TestRollbackEndpoint(t *testing.T) {
+	_, client := setupTestServer(t, map[string]string{
+		"chat.yaml": `
+service:
+  name: chat
+  type: native
+  command: "sleep 30"
+
+network:
+  port: 0
+
+routing:
+  hostname: chat.example.local
+`,
+	}, daemon.WithStateDir(t.TempDir()), daemon.WithRouting(filepath.Join(t.TempDir(), "aurelia.yaml")), daemon.WithPortRange(27900, 28000))
+
+	// Nothing to roll back before the first deploy
+	resp, err := client.Post("http://aurelia/v1/services/chat/rollback", "application/json", nil)
+	if err != nil {
+		t.Fatalf("POST rollback: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 before any deploy, got %d", resp.StatusCode)
+	}
+
+	resp, err = client.Post("http://aurelia/v1/services/chat/deploy?drain=10ms", "application/json", nil)
+	if err != nil {
+		t.Fatalf("POST deploy: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 for deploy, got %d", resp.StatusCode)
+	}
+
+	// A native service whose command hasn't changed has nothing to roll back to
+	resp, err = client.Post("http://aurelia/v1/services/chat/rollback?drain=10ms", "application/json", nil)
+	if err != nil {
+		t.Fatalf("POST rollback: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	var result daemon.RollbackResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if result.Service != "chat" || result.Status != "unchanged" {
+		t.Errorf("unexpected result: %+v", result)
+	}
+}
+
+func TestDeployStreamEvents(t *testing.T) {
+	_, client := setupTestServer(t, map[string]string{
+		"chat.yaml": `
+service:
+  name: chat
+  type: native
+  command: "sleep 30"
+
+network:
+  port: 0
+
+routing:
+  hostname: chat.example.local
+`,
+	}, daemon.WithRouting(filepath.Join(t.TempDir(), "aurelia.yaml")), daemon.WithPortRange(28000, 28100))
+
+	resp, err := client.Post("http://aurelia/v1/services/chat/deploy?stream=true&drain=10ms", "application/json", nil)
+	if err != nil {
+		t.Fatalf("POST deploy: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Errorf("expected ndjson content type, got %q", ct)
+	}
+
+	var steps []string
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var ev struct {
+			daemon.DeployEvent
+			Error string `json:"error"`
+		}
+		if err := dec.Decode(&ev); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("decode event: %v", err)
+		}
+		if ev.Service != "chat" {
+			t.Errorf("event for wrong service: %+v", ev)
+		}
+		if ev.Error != "" {
+			t.Errorf("unexpected error event: %s", ev.Error)
+		}
+		steps = append(steps, ev.Step)
+	}
+
+	want := []string{
+		daemon.DeployStepPortAllocated,
+		daemon.DeployStepStarted,
+		daemon.DeployStepHealthy,
+		daemon.DeployStepRoutingSwitched,
+		daemon.DeployStepDraining,
+		daemon.DeployStepOldStopped,
+		daemon.DeployStepPromoted,
+		"deployed",
+	}
+	if !slices.Equal(steps, want) {
+		t.Errorf("deploy steps:\n got  %v\n want %v", steps, want)
+	}
+}
+
 func TestGraphEndpoint(t *testing.T) {
 	_, client := setupTestServer(t, map[string]string{
 		"db.yaml": `
@@ -1689,3 +2479,138 @@ service:
 		t.Errorf("expected 400 for non-existent, got %d", resp3.StatusCode)
 	}
 }
+
+func TestErrorCodes(t *testing.T) {
+	_, client := setupTestServer(t, map[string]string{
+		"svc.yaml": fmt.Sprintf(nativeSpec, "svc"),
+		"ext.yaml": "service:\n  name: ext-svc\n  type: external\n\nhealth:\n  type: tcp\n  port: 19876\n  interval: 1s\n  timeout: 500ms\n",
+	})
+
+	tests := []struct {
+		name         string
+		method, path string
+		body         string
+		status       int
+		code         string
+	}{
+		{"get unknown service", "GET", "/v1/services/missing", "", http.StatusNotFound, CodeServiceNotFound},
+		{"start unknown service", "POST", "/v1/services/missing/start", "", http.StatusBadRequest, CodeServiceNotFound},
+		{"remove unknown service", "DELETE", "/v1/services/missing", "", http.StatusBadRequest, CodeServiceNotFound},
+		{"start external service", "POST", "/v1/services/ext-svc/start", "", http.StatusBadRequest, CodeExternalNotAllowed},
+		{"invalid spec", "POST", "/v1/services", "service:\n  name: bad\n  type: native\n", http.StatusBadRequest, CodeValidationFailed},
+		{"duplicate service", "POST", "/v1/services", fmt.Sprintf(nativeSpec, "svc"), http.StatusConflict, CodeServiceExists},
+		{"empty apply", "PUT", "/v1/services", "", http.StatusBadRequest, CodeBadRequest},
+		{"invalid wait", "POST", "/v1/services/svc/restart?wait=soon", "", http.StatusBadRequest, CodeBadRequest},
+		{"secret cache unset", "GET", "/v1/secrets/key", "", http.StatusServiceUnavailable, CodeNotConfigured},
+		{"cluster logs without node", "GET", "/v1/cluster/services/svc/logs", "", http.StatusBadRequest, CodeBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, "http://aurelia"+tt.path, strings.NewReader(tt.body))
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("%s %s: %v", tt.method, tt.path, err)
+			}
+			defer resp.Body.Close()
+			var body errorResponse
+			if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+				t.Fatalf("decoding error body: %v", err)
+			}
+			if resp.StatusCode != tt.status || body.Code != tt.code {
+				t.Errorf("got %d %q, want %d %q (error %q)", resp.StatusCode, body.Code, tt.status, tt.code, body.Error)
+			}
+			if body.Error == "" {
+				t.Error("expected an error message alongside the code")
+			}
+		})
+	}
+}
+
+func TestMaintenance(t *testing.T) {
+	_, client := setupTestServer(t, map[string]string{
+		"svc.yaml": fmt.Sprintf(nativeSpec, "svc"),
+	})
+
+	waitForServiceState(t, client, "svc", driver.StateRunning)
+	resp, err := client.Get("http://aurelia/v1/services/svc")
+	if err != nil {
+		t.Fatalf("GET /v1/services/svc: %v", err)
+	}
+	var state daemon.ServiceState
+	json.NewDecoder(resp.Body).Decode(&state)
+	resp.Body.Close()
+	// Maintenance leaves the process running past the test daemon's Stop
+	t.Cleanup(func() { syscall.Kill(state.PID, syscall.SIGKILL) })
+
+	resp, err = client.Post("http://aurelia/v1/maintenance", "", nil)
+	if err != nil {
+		t.Fatalf("POST /v1/maintenance: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+
+	resp, err = client.Get("http://aurelia/v1/info")
+	if err != nil {
+		t.Fatalf("GET /v1/info: %v", err)
+	}
+	var info daemon.Info
+	json.NewDecoder(resp.Body).Decode(&info)
+	resp.Body.Close()
+	if !info.Maintenance {
+		t.Error("expected info to report maintenance")
+	}
+
+	resp, err = client.Post("http://aurelia/v1/services/svc/stop", "", nil)
+	if err != nil {
+		t.Fatalf("POST stop: %v", err)
+	}
+	var body errorResponse
+	json.NewDecoder(resp.Body).Decode(&body)
+	resp.Body.Close()
+	if body.Code != CodeMaintenance {
+		t.Errorf("expected %q stopping a service in maintenance, got %d %q", CodeMaintenance, resp.StatusCode, body.Code)
+	}
+	if err := syscall.Kill(state.PID, 0); err != nil {
+		t.Errorf("process %d not running after maintenance: %v", state.PID, err)
+	}
+}
+
+func TestErrorCodeFromDaemonErrors(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{fmt.Errorf("%w: svc", daemon.ErrServiceNotFound), CodeServiceNotFound},
+		{fmt.Errorf("%w: svc", daemon.ErrServiceExists), CodeServiceExists},
+		{fmt.Errorf("deploy: %w", fmt.Errorf("%w for %q", daemon.ErrDeployInProgress, "svc")), CodeDeployInProgress},
+		{fmt.Errorf("reload: %w", daemon.ErrMaintenance), CodeMaintenance},
+		{fmt.Errorf("%w for %q", daemon.ErrServiceBusy, "svc"), CodeServiceBusy},
+		{fmt.Errorf("something else"), CodeOperationFailed},
+	}
+	for _, tt := range tests {
+		if got := errorCode(tt.err, CodeOperationFailed); got != tt.want {
+			t.Errorf("errorCode(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestDaemonErrorGenericOverTCP(t *testing.T) {
+	err := fmt.Errorf("%w: secret-name", daemon.ErrServiceNotFound)
+	r := httptest.NewRequest("POST", "/v1/services/secret-name/start", nil)
+	r.RemoteAddr = "10.0.0.5:41234"
+	w := httptest.NewRecorder()
+	writeDaemonError(w, r, http.StatusBadRequest, CodeOperationFailed, "failed to start service", err)
+
+	var body errorResponse
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatalf("decoding error body: %v", err)
+	}
+	if body.Error != "failed to start service" {
+		t.Errorf("expected the generic message over TCP, got %q", body.Error)
+	}
+	if body.Code != CodeServiceNotFound {
+		t.Errorf("expected code %q over TCP, got %q", CodeServiceNotFound, body.Code)
+	}
+}
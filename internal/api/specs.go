@@ -0,0 +1,181 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/benaskins/aurelia/internal/daemon"
+	"gopkg.in/yaml.v3"
+)
+
+// maxSpecBytes bounds the body of POST /v1/services. PUT /v1/services takes
+// a whole set of specs and allows maxApplyBytes.
+const (
+	maxSpecBytes  = 1 << 20
+	maxApplyBytes = 8 << 20
+)
+
+// createService writes a new spec file from the request body and reloads so
+// the service starts. The body is a spec as it would appear in a file: YAML,
+// or JSON with Content-Type application/json.
+func (s *Server) createService(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxSpecBytes))
+	if err != nil {
+		writeError(w, http.StatusRequestEntityTooLarge, CodeTooLarge, "spec too large")
+		return
+	}
+	if mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mt == "application/json" {
+		if body, err = jsonToYAML(body); err != nil {
+			writeError(w, http.StatusBadRequest, CodeBadRequest, "invalid JSON: "+err.Error())
+			return
+		}
+	}
+
+	sp, err := s.daemon.CreateService(r.Context(), body)
+	if errors.Is(err, daemon.ErrServiceExists) {
+		writeError(w, http.StatusConflict, CodeServiceExists, err.Error())
+		return
+	}
+	if err != nil {
+		s.logger.Error("createService: failed", "error", err)
+		writeDaemonError(w, r, http.StatusBadRequest, CodeValidationFailed, "invalid service spec", err)
+		return
+	}
+	s.logger.Info("service created", "service", sp.Service.Name)
+	writeJSON(w, http.StatusCreated, map[string]string{"status": "created", "service": sp.Service.Name})
+}
+
+// applyServices replaces the managed set of specs with the ones in the
+// request body and reconciles, returning the ReloadResult. The body is a YAML
+// stream with one spec per document, or with Content-Type application/json
+// a JSON array of specs. An invalid member rejects the whole set.
+func (s *Server) applyServices(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxApplyBytes))
+	if err != nil {
+		writeError(w, http.StatusRequestEntityTooLarge, CodeTooLarge, "specs too large")
+		return
+	}
+	var docs [][]byte
+	if mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mt == "application/json" {
+		docs, err = splitJSONSpecs(body)
+	} else {
+		docs, err = splitYAMLSpecs(body)
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, err.Error())
+		return
+	}
+	if len(docs) == 0 {
+		// Applying nothing would stop every service; make that explicit.
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "no specs given")
+		return
+	}
+
+	result, err := s.daemon.ApplySpecs(r.Context(), docs)
+	if err != nil {
+		s.logger.Error("applyServices: failed", "error", err)
+		writeDaemonError(w, r, http.StatusBadRequest, CodeValidationFailed, "invalid service specs", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// getServiceSpec returns the spec the daemon loaded for a service: JSON
+// keyed like a spec file, or YAML with ?format=yaml. Secrets are shown as the
+// references in the spec; their values are not resolved.
+func (s *Server) getServiceSpec(w http.ResponseWriter, r *http.Request) {
+	sp, err := s.daemon.ServiceSpec(r.PathValue("name"))
+	if err != nil {
+		writeDaemonError(w, r, http.StatusNotFound, CodeServiceNotFound, "service not found", err)
+		return
+	}
+	data, err := yaml.Marshal(sp)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, CodeInternal, "marshaling spec: "+err.Error())
+		return
+	}
+
+	switch format := r.URL.Query().Get("format"); format {
+	case "yaml":
+		w.Header().Set("Content-Type", "application/yaml")
+		w.WriteHeader(http.StatusOK)
+		w.Write(data)
+	case "", "json":
+		// Go through YAML so the keys are the spec file's, not Go's
+		var doc map[string]any
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			writeError(w, http.StatusInternalServerError, CodeInternal, "converting spec: "+err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, doc)
+	default:
+		writeError(w, http.StatusBadRequest, CodeBadRequest, fmt.Sprintf("format must be json or yaml, got %q", format))
+	}
+}
+
+// splitJSONSpecs splits a JSON array of specs into YAML documents.
+func splitJSONSpecs(data []byte) ([][]byte, error) {
+	var items []json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, fmt.Errorf("invalid JSON: expected an array of specs: %w", err)
+	}
+	docs := make([][]byte, 0, len(items))
+	for i, item := range items {
+		doc, err := jsonToYAML(item)
+		if err != nil {
+			return nil, fmt.Errorf("spec %d: invalid JSON: %w", i, err)
+		}
+		docs = append(docs, doc)
+	}
+	return docs, nil
+}
+
+// splitYAMLSpecs splits a YAML stream into one document per spec, skipping
+// empty documents.
+func splitYAMLSpecs(data []byte) ([][]byte, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	var docs [][]byte
+	for i := 0; ; i++ {
+		var doc yaml.Node
+		err := dec.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			return docs, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("spec %d: invalid YAML: %w", i, err)
+		}
+		if len(doc.Content) == 0 {
+			continue
+		}
+		out, err := yaml.Marshal(&doc)
+		if err != nil {
+			return nil, fmt.Errorf("spec %d: %w", i, err)
+		}
+		docs = append(docs, out)
+	}
+}
+
+// jsonToYAML re-encodes a JSON document as block-style YAML, keeping its key
+// order, so specs created from JSON read like hand-written ones.
+func jsonToYAML(data []byte) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	clearStyle(&doc)
+	return yaml.Marshal(&doc)
+}
+
+// clearStyle drops the flow and quoting styles the YAML parser records for
+// JSON input; the encoder still quotes strings that need it.
+func clearStyle(n *yaml.Node) {
+	n.Style = 0
+	for _, c := range n.Content {
+		clearStyle(c)
+	}
+}
@@ -0,0 +1,404 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/benaskins/aurelia/internal/daemon"
+	"github.com/benaskins/aurelia/internal/driver"
+	"github.com/benaskins/aurelia/internal/keychain"
+	"gopkg.in/yaml.v3"
+)
+
+func postSpec(t *testing.T, client *http.Client, contentType, body string) (int, map[string]string) {
+	t.Helper()
+	resp, err := client.Post("http://aurelia/v1/services", contentType, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("POST /v1/services: %v", err)
+	}
+	defer resp.Body.Close()
+	var result map[string]string
+	json.NewDecoder(resp.Body).Decode(&result)
+	return resp.StatusCode, result
+}
+
+func waitForServiceState(t *testing.T, client *http.Client, name string, want driver.State) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		resp, err := client.Get("http://aurelia/v1/services/" + name)
+		if err == nil {
+			var st struct {
+				State driver.State `json:"state"`
+			}
+			json.NewDecoder(resp.Body).Decode(&st)
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusOK && st.State == want {
+				return
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("%s did not reach state %s", name, want)
+}
+
+func TestCreateServiceYAML(t *testing.T) {
+	srv, client := setupTestServer(t, nil)
+	specDir := srv.daemon.Info().SpecDir
+
+	code, result := postSpec(t, client, "application/yaml", `
+service:
+  name: created
+  type: native
+  command: "sleep 30"
+`)
+	if code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %v", code, result)
+	}
+	if result["service"] != "created" {
+		t.Errorf("expected service=created, got %q", result["service"])
+	}
+	if _, err := os.Stat(filepath.Join(specDir, "created.yaml")); err != nil {
+		t.Errorf("spec file not written: %v", err)
+	}
+	waitForServiceState(t, client, "created", driver.StateRunning)
+
+	// Same name again conflicts and leaves the file alone
+	code, _ = postSpec(t, client, "application/yaml", `
+service:
+  name: created
+  type: native
+  command: "sleep 60"
+`)
+	if code != http.StatusConflict {
+		t.Errorf("duplicate: expected 409, got %d", code)
+	}
+	data, _ := os.ReadFile(filepath.Join(specDir, "created.yaml"))
+	if !strings.Contains(string(data), "sleep 30") {
+		t.Errorf("duplicate create overwrote the spec file:\n%s", data)
+	}
+
+	// DELETE stops the service and takes its spec out of the spec dir
+	req, _ := http.NewRequest("DELETE", "http://aurelia/v1/services/created", nil)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("DELETE: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("DELETE: expected 200, got %d", resp.StatusCode)
+	}
+	if _, err := os.Stat(filepath.Join(specDir, "created.yaml")); !os.IsNotExist(err) {
+		t.Errorf("expected spec file removed, stat err = %v", err)
+	}
+	resp, err = client.Get("http://aurelia/v1/services/created")
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 after delete, got %d", resp.StatusCode)
+	}
+}
+
+func TestCreateServiceJSON(t *testing.T) {
+	srv, client := setupTestServer(t, nil)
+	specDir := srv.daemon.Info().SpecDir
+
+	code, result := postSpec(t, client, "application/json",
+		`{"service": {"name": "fromjson", "type": "native", "command": "sleep 30"}, "env": {"DEBUG": "true"}}`)
+	if code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %v", code, result)
+	}
+
+	data, err := os.ReadFile(filepath.Join(specDir, "fromjson.yaml"))
+	if err != nil {
+		t.Fatalf("reading spec file: %v", err)
+	}
+	if strings.Contains(string(data), "{") {
+		t.Errorf("expected block-style YAML, got:\n%s", data)
+	}
+	// The string "true" must stay a string, or the spec would not load
+	if !strings.Contains(string(data), `DEBUG: "true"`) {
+		t.Errorf("expected quoted string env value, got:\n%s", data)
+	}
+	waitForServiceState(t, client, "fromjson", driver.StateRunning)
+}
+
+func TestCreateServiceRejectsInvalid(t *testing.T) {
+	srv, client := setupTestServer(t, nil)
+	specDir := srv.daemon.Info().SpecDir
+
+	tests := []struct {
+		name, body string
+	}{
+		{"missing command", "service:\n  name: bad\n  type: native\n"},
+		{"unknown field", "service:\n  name: bad\n  type: native\n  command: sleep 1\n  comand: typo\n"},
+		{"path traversal", "service:\n  name: ../escape\n  type: native\n  command: sleep 1\n"},
+		{"two services", "service:\n  name: a\n  type: native\n  command: sleep 1\n---\nservice:\n  name: b\n  type: native\n  command: sleep 1\n"},
+		{"unknown dependency", "service:\n  name: bad\n  type: native\n  command: sleep 1\ndependencies:\n  requires: [missing]\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, result := postSpec(t, client, "application/yaml", tt.body)
+			if code != http.StatusBadRequest {
+				t.Errorf("expected 400, got %d: %v", code, result)
+			}
+		})
+	}
+
+	entries, _ := filepath.Glob(filepath.Join(specDir, "*.yaml"))
+	if len(entries) != 0 {
+		t.Errorf("rejected specs left files behind: %v", entries)
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(specDir), "escape.yaml")); err == nil {
+		t.Error("path traversal wrote outside the spec dir")
+	}
+}
+
+func putSpecs(t *testing.T, client *http.Client, contentType, body string) (int, []byte) {
+	t.Helper()
+	req, _ := http.NewRequest("PUT", "http://aurelia/v1/services", strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("PUT /v1/services: %v", err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, data
+}
+
+// specDirContents maps each entry in dir to its content ("" for directories).
+func specDirContents(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := make(map[string]string)
+	for _, e := range entries {
+		if e.IsDir() {
+			out[e.Name()+"/"] = ""
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		out[e.Name()] = string(data)
+	}
+	return out
+}
+
+const nativeSpec = "service:\n  name: %s\n  type: native\n  command: \"sleep 30\"\n"
+
+func TestApplyServices(t *testing.T) {
+	srv, client := setupTestServer(t, map[string]string{
+		"keep.yaml":     fmt.Sprintf(nativeSpec, "keep"),
+		"old.yaml":      fmt.Sprintf(nativeSpec, "old"),
+		"defaults.yaml": "env:\n  REGION: test\n",
+	})
+	specDir := srv.daemon.Info().SpecDir
+
+	body := fmt.Sprintf(nativeSpec, "keep") + "---\n" + fmt.Sprintf(nativeSpec, "fresh")
+	code, data := putSpecs(t, client, "application/yaml", body)
+	if code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", code, data)
+	}
+	var result daemon.ReloadResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		t.Fatalf("decoding result: %v", err)
+	}
+	if len(result.Added) != 1 || result.Added[0] != "fresh" {
+		t.Errorf("expected added [fresh], got %v", result.Added)
+	}
+	if len(result.Removed) != 1 || result.Removed[0] != "old" {
+		t.Errorf("expected removed [old], got %v", result.Removed)
+	}
+	if len(result.Restarted) != 0 {
+		t.Errorf("unchanged spec should not restart, got %v", result.Restarted)
+	}
+
+	got := specDirContents(t, specDir)
+	for _, want := range []string{"keep.yaml", "fresh.yaml", "defaults.yaml"} {
+		if _, ok := got[want]; !ok {
+			t.Errorf("expected %s in spec dir, got %v", want, got)
+		}
+	}
+	for name := range got {
+		if name == "old.yaml" || strings.HasPrefix(name, ".") {
+			t.Errorf("unexpected %s left in spec dir", name)
+		}
+	}
+	waitForServiceState(t, client, "fresh", driver.StateRunning)
+
+	// Applying the same set again is a no-op
+	code, data = putSpecs(t, client, "application/yaml", body)
+	if code != http.StatusOK {
+		t.Fatalf("re-apply: expected 200, got %d: %s", code, data)
+	}
+	if strings.TrimSpace(string(data)) != "{}" {
+		t.Errorf("re-apply should change nothing, got %s", data)
+	}
+}
+
+func TestApplyServicesJSON(t *testing.T) {
+	srv, client := setupTestServer(t, nil)
+	specDir := srv.daemon.Info().SpecDir
+
+	code, data := putSpecs(t, client, "application/json", `[
+		{"service": {"name": "one", "type": "native", "command": "sleep 30"}},
+		{"service": {"name": "two", "type": "native", "command": "sleep 30"},
+		 "dependencies": {"after": ["one"], "requires": ["one"]}}
+	]`)
+	if code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", code, data)
+	}
+	for _, name := range []string{"one.yaml", "two.yaml"} {
+		if _, err := os.Stat(filepath.Join(specDir, name)); err != nil {
+			t.Errorf("%s not written: %v", name, err)
+		}
+	}
+	waitForServiceState(t, client, "two", driver.StateRunning)
+
+	code, _ = putSpecs(t, client, "application/json", `[]`)
+	if code != http.StatusBadRequest {
+		t.Errorf("empty set: expected 400, got %d", code)
+	}
+}
+
+func TestApplyServicesInvalidMemberHasNoSideEffects(t *testing.T) {
+	srv, client := setupTestServer(t, map[string]string{
+		"keep.yaml": fmt.Sprintf(nativeSpec, "keep"),
+	})
+	specDir := srv.daemon.Info().SpecDir
+	waitForServiceState(t, client, "keep", driver.StateRunning)
+	before := specDirContents(t, specDir)
+
+	valid := fmt.Sprintf(nativeSpec, "fresh")
+	tests := []struct {
+		name, member string
+	}{
+		{"missing command", "service:\n  name: bad\n  type: native\n"},
+		{"unknown field", "service:\n  name: bad\n  type: native\n  command: sleep 1\n  comand: typo\n"},
+		{"duplicate name", valid},
+		{"unknown requirement", "service:\n  name: bad\n  type: native\n  command: sleep 1\ndependencies:\n  after: [missing]\n  requires: [missing]\n"},
+		{"dependency cycle", "service:\n  name: x\n  type: native\n  command: sleep 1\ndependencies:\n  after: [y]\n---\n" +
+			"service:\n  name: y\n  type: native\n  command: sleep 1\ndependencies:\n  after: [x]\n"},
+		{"invalid YAML", "service: [unclosed\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, data := putSpecs(t, client, "application/yaml", valid+"---\n"+tt.member)
+			if code != http.StatusBadRequest {
+				t.Errorf("expected 400, got %d: %s", code, data)
+			}
+		})
+	}
+
+	after := specDirContents(t, specDir)
+	if len(after) != len(before) {
+		t.Errorf("rejected apply changed the spec dir:\nbefore %v\nafter  %v", before, after)
+	}
+	for name, content := range before {
+		if after[name] != content {
+			t.Errorf("%s changed: %q -> %q", name, content, after[name])
+		}
+	}
+
+	resp, err := client.Get("http://aurelia/v1/services/fresh")
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("rejected apply started fresh: got %d", resp.StatusCode)
+	}
+	waitForServiceState(t, client, "keep", driver.StateRunning)
+}
+
+func TestGetServiceSpec(t *testing.T) {
+	store := keychain.NewMemoryStore()
+	store.Set("web-api-key", "s3cret-value")
+	srv, client := setupTestServer(t, map[string]string{
+		"web.yaml": `
+service:
+  name: web
+  type: native
+  command: "sleep 30"
+
+env:
+  HOME_DIR: ${HOME}
+
+secrets:
+  API_KEY:
+    secret: web-api-key
+`,
+	}, daemon.WithSecrets(store))
+
+	loaded, err := srv.daemon.ServiceSpec("web")
+	if err != nil {
+		t.Fatalf("ServiceSpec: %v", err)
+	}
+	want, err := yaml.Marshal(loaded)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := client.Get("http://aurelia/v1/services/web/spec?format=yaml")
+	if err != nil {
+		t.Fatalf("GET spec: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", resp.StatusCode, body)
+	}
+	if string(body) != string(want) {
+		t.Errorf("YAML spec differs from the loaded one:\n%s\nwant:\n%s", body, want)
+	}
+
+	resp, err = client.Get("http://aurelia/v1/services/web/spec")
+	if err != nil {
+		t.Fatalf("GET spec: %v", err)
+	}
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	var got struct {
+		Env     map[string]string            `json:"env"`
+		Secrets map[string]map[string]string `json:"secrets"`
+		Restart map[string]any               `json:"restart"`
+	}
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatalf("decoding JSON spec: %v\n%s", err, body)
+	}
+	if got.Env["HOME_DIR"] != os.Getenv("HOME") {
+		t.Errorf("expected expanded env, got %q", got.Env["HOME_DIR"])
+	}
+	if got.Secrets["API_KEY"]["secret"] != "web-api-key" {
+		t.Errorf("expected the secret reference, got %v", got.Secrets)
+	}
+	if got.Restart["policy"] != "on-failure" {
+		t.Errorf("expected the default restart policy, got %v", got.Restart)
+	}
+	if strings.Contains(string(body), "s3cret-value") {
+		t.Errorf("spec exposes the secret value:\n%s", body)
+	}
+
+	resp, err = client.Get("http://aurelia/v1/services/missing/spec")
+	if err != nil {
+		t.Fatalf("GET spec: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("unknown service: expected 404, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,29 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// readRequestTimeout is the default bound on the plain read endpoints. They only report state
+// the daemon already holds, so one that takes longer is stuck behind a lock
+// or a slow backend and should fail rather than hold the connection for the
+// server's WriteTimeout, which is sized for deploys.
+const readRequestTimeout = 30 * time.Second
+
+// timeoutBody is the JSON error written when a read request times out.
+var timeoutBody = func() string {
+	data, _ := json.Marshal(errorResponse{Error: "request timed out", Code: CodeTimeout})
+	return string(data)
+}()
+
+// read wraps a non-streaming read handler so it gives up after the server's
+// read timeout with a 503 and the timeout code. Streams must not be wrapped:
+// the timeout handler buffers the response and can't be flushed.
+func (s *Server) read(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		http.TimeoutHandler(h, s.readTimeout, timeoutBody).ServeHTTP(w, r)
+	}
+}
@@ -0,0 +1,129 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/benaskins/aurelia/internal/daemon"
+	"github.com/benaskins/aurelia/internal/keychain"
+)
+
+// slowStore delays every Get while slow is set.
+type slowStore struct {
+	*keychain.MemoryStore
+	slow  atomic.Bool
+	delay time.Duration
+}
+
+func (s *slowStore) Get(key string) (string, error) {
+	if s.slow.Load() {
+		time.Sleep(s.delay)
+	}
+	return s.MemoryStore.Get(key)
+}
+
+func TestReadTimeout(t *testing.T) {
+	dir := t.TempDir()
+	spec := `
+service:
+  name: chat
+  type: native
+  command: "sleep 30"
+
+network:
+  port: 0
+
+routing:
+  hostname: chat.example.local
+
+secrets:
+  API_KEY:
+    keychain: chat-api-key
+`
+	if err := os.WriteFile(filepath.Join(dir, "chat.yaml"), []byte(spec), 0644); err != nil {
+		t.Fatal(err)
+	}
+	store := &slowStore{MemoryStore: keychain.NewMemoryStore(), delay: 500 * time.Millisecond}
+	store.Set("chat-api-key", "secret")
+
+	d := daemon.NewDaemon(dir,
+		daemon.WithSecrets(store),
+		daemon.WithStateDir(t.TempDir()),
+		daemon.WithRouting(filepath.Join(t.TempDir(), "aurelia.yaml")),
+		daemon.WithPortRange(27900, 28000))
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	if err := d.Start(ctx); err != nil {
+		t.Fatalf("daemon start: %v", err)
+	}
+	t.Cleanup(func() { d.Stop(5 * time.Second) })
+
+	srv := NewServer(d, nil, "test")
+	srv.SetReadTimeout(100 * time.Millisecond)
+	ts := httptest.NewServer(srv.server.Handler)
+	defer ts.Close()
+
+	// Inspect resolves secrets, so a stuck secret store stalls it
+	store.slow.Store(true)
+	start := time.Now()
+	resp, err := http.Get(ts.URL + "/v1/services/chat/inspect")
+	if err != nil {
+		t.Fatalf("GET inspect: %v", err)
+	}
+	var body errorResponse
+	json.NewDecoder(resp.Body).Decode(&body)
+	resp.Body.Close()
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected inspect to time out quickly, took %v", elapsed)
+	}
+	if resp.StatusCode != http.StatusServiceUnavailable || body.Code != CodeTimeout {
+		t.Errorf("expected 503 with code %q, got %d %+v", CodeTimeout, resp.StatusCode, body)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("expected a JSON timeout body, got content type %q", ct)
+	}
+
+	// Deploys are not bound by the read timeout
+	store.slow.Store(false)
+	start = time.Now()
+	resp, err = http.Post(ts.URL+"/v1/services/chat/deploy?drain=300ms", "application/json", nil)
+	if err != nil {
+		t.Fatalf("POST deploy: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 for deploy, got %d", resp.StatusCode)
+	}
+	if elapsed := time.Since(start); elapsed < srv.readTimeout {
+		t.Errorf("expected the deploy to outlast the read timeout, took %v", elapsed)
+	}
+
+	// Fast reads are unaffected
+	resp, err = http.Get(ts.URL + "/v1/services/chat")
+	if err != nil {
+		t.Fatalf("GET service: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected 200 for a fast read, got %d", resp.StatusCode)
+	}
+}
+
+func TestSetReadTimeoutKeepsDefault(t *testing.T) {
+	srv := NewServer(daemon.NewDaemon(t.TempDir()), nil, "test")
+	srv.SetReadTimeout(0)
+	if srv.readTimeout != readRequestTimeout {
+		t.Errorf("zero timeout: got %v, want the default %v", srv.readTimeout, readRequestTimeout)
+	}
+	srv.SetReadTimeout(time.Minute)
+	if srv.readTimeout != time.Minute {
+		t.Errorf("got %v, want 1m", srv.readTimeout)
+	}
+}
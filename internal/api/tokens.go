@@ -0,0 +1,139 @@
+package api
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// Token scopes. A write token can also do everything a read token can.
+const (
+	ScopeRead  = "read"
+	ScopeWrite = "write"
+)
+
+// scopedToken is an additional bearer token loaded from a tokens file.
+type scopedToken struct {
+	token string
+	scope string
+}
+
+func validScope(scope string) bool {
+	return scope == ScopeRead || scope == ScopeWrite
+}
+
+// LoadTokens reads additional bearer tokens from path, replacing any loaded
+// before. Each non-blank line holds a scope and a token separated by
+// whitespace, e.g. "read 5f2c..."; lines starting with # are comments. A
+// missing file means no additional tokens.
+func (s *Server) LoadTokens(path string) error {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		s.setScopedTokens(nil)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("opening tokens file: %w", err)
+	}
+	defer f.Close()
+
+	var tokens []scopedToken
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) != 2 {
+			return fmt.Errorf("%s: line %d: expected \"<scope> <token>\"", path, line)
+		}
+		if !validScope(fields[0]) {
+			return fmt.Errorf("%s: line %d: unknown scope %q (want %q or %q)", path, line, fields[0], ScopeRead, ScopeWrite)
+		}
+		tokens = append(tokens, scopedToken{token: fields[1], scope: fields[0]})
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("reading tokens file: %w", err)
+	}
+
+	s.setScopedTokens(tokens)
+	s.logger.Info("API tokens loaded", "path", path, "count", len(tokens))
+	return nil
+}
+
+func (s *Server) setScopedTokens(tokens []scopedToken) {
+	s.tokenMu.Lock()
+	s.scopedTokens = tokens
+	s.tokenMu.Unlock()
+}
+
+// tokenScope returns the scope granted by the provided token, checking the
+// current and previous primary tokens and then the loaded scoped tokens.
+func (s *Server) tokenScope(provided string) (string, bool) {
+	s.tokenMu.RLock()
+	defer s.tokenMu.RUnlock()
+	primary := s.primaryScope
+	if primary == "" {
+		primary = ScopeWrite
+	}
+	if subtle.ConstantTimeCompare([]byte(provided), []byte(s.token)) == 1 {
+		return primary, true
+	}
+	if s.prevToken != "" && subtle.ConstantTimeCompare([]byte(provided), []byte(s.prevToken)) == 1 {
+		return primary, true
+	}
+	for _, t := range s.scopedTokens {
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(t.token)) == 1 {
+			return t.scope, true
+		}
+	}
+	return "", false
+}
+
+// requiredScope returns the scope a request needs: reads need ScopeRead,
+// except secret lookups and the audit log, and everything else needs
+// ScopeWrite.
+func requiredScope(r *http.Request) string {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return ScopeWrite
+	}
+	if strings.HasPrefix(r.URL.Path, "/v1/secrets") || r.URL.Path == "/v1/audit" {
+		return ScopeWrite
+	}
+	return ScopeRead
+}
+
+// tokenActor returns the audit actor for a request authorized by its bearer
+// token: "token:" and a short fingerprint that identifies the token without
+// revealing it.
+func tokenActor(r *http.Request) string {
+	sum := sha256.Sum256([]byte(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")))
+	return "token:" + hex.EncodeToString(sum[:4])
+}
+
+// authorizeToken validates the request's bearer token and checks that its
+// scope covers the request, writing a 401 or 403 and returning false if not.
+func (s *Server) authorizeToken(w http.ResponseWriter, r *http.Request) bool {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "unauthorized")
+		return false
+	}
+	scope, ok := s.tokenScope(strings.TrimPrefix(auth, "Bearer "))
+	if !ok {
+		writeError(w, http.StatusUnauthorized, CodeUnauthorized, "unauthorized")
+		return false
+	}
+	if need := requiredScope(r); scope != ScopeWrite && scope != need {
+		writeError(w, http.StatusForbidden, CodeForbidden, fmt.Sprintf("token scope %q does not permit this request (requires %q)", scope, need))
+		return false
+	}
+	return true
+}
@@ -0,0 +1,171 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/benaskins/aurelia/internal/daemon"
+)
+
+// setupScopedServer serves the API behind the bearer-token middleware with
+// the primary token and a tokens file holding the given lines.
+func setupScopedServer(t *testing.T, tokensFile string) (*Server, *httptest.Server) {
+	t.Helper()
+
+	dir := t.TempDir()
+	spec := "service:\n  name: svc\n  type: native\n  command: \"sleep 30\"\n"
+	if err := os.WriteFile(filepath.Join(dir, "svc.yaml"), []byte(spec), 0644); err != nil {
+		t.Fatal(err)
+	}
+	d := daemon.NewDaemon(dir)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	if err := d.Start(ctx); err != nil {
+		t.Fatalf("daemon start: %v", err)
+	}
+	t.Cleanup(func() { d.Stop(5 * time.Second) })
+
+	srv := NewServer(d, nil, "test")
+	if err := srv.GenerateToken(filepath.Join(t.TempDir(), "api.token")); err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+	tokensPath := filepath.Join(t.TempDir(), "api-tokens")
+	if err := os.WriteFile(tokensPath, []byte(tokensFile), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.LoadTokens(tokensPath); err != nil {
+		t.Fatalf("LoadTokens: %v", err)
+	}
+
+	ts := httptest.NewServer(srv.requireToken(srv.server.Handler))
+	t.Cleanup(ts.Close)
+	return srv, ts
+}
+
+func doWithToken(t *testing.T, method, url, token string) int {
+	t.Helper()
+	req, _ := http.NewRequest(method, url, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, url, err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestReadScopedTokenCannotWrite(t *testing.T) {
+	_, ts := setupScopedServer(t, "# dashboard\nread dashboard-token\n")
+
+	if code := doWithToken(t, "GET", ts.URL+"/v1/services", "dashboard-token"); code != http.StatusOK {
+		t.Errorf("GET /v1/services with read token: expected 200, got %d", code)
+	}
+	if code := doWithToken(t, "GET", ts.URL+"/v1/services/svc", "dashboard-token"); code != http.StatusOK {
+		t.Errorf("GET service with read token: expected 200, got %d", code)
+	}
+	for _, path := range []string{"/v1/services/svc/stop", "/v1/services/svc/deploy", "/v1/reload"} {
+		if code := doWithToken(t, "POST", ts.URL+path, "dashboard-token"); code != http.StatusForbidden {
+			t.Errorf("POST %s with read token: expected 403, got %d", path, code)
+		}
+	}
+	if code := doWithToken(t, "GET", ts.URL+"/v1/secrets/key", "dashboard-token"); code != http.StatusForbidden {
+		t.Errorf("GET secret with read token: expected 403, got %d", code)
+	}
+	if code := doWithToken(t, "GET", ts.URL+"/v1/audit", "dashboard-token"); code != http.StatusForbidden {
+		t.Errorf("GET audit with read token: expected 403, got %d", code)
+	}
+}
+
+func TestWriteScopedTokenCanWrite(t *testing.T) {
+	srv, ts := setupScopedServer(t, "write deploy-token\n")
+
+	if code := doWithToken(t, "GET", ts.URL+"/v1/services", "deploy-token"); code != http.StatusOK {
+		t.Errorf("GET with write token: expected 200, got %d", code)
+	}
+	if code := doWithToken(t, "POST", ts.URL+"/v1/services/svc/stop", "deploy-token"); code != http.StatusAccepted {
+		t.Errorf("POST stop with write token: expected 202, got %d", code)
+	}
+
+	// The primary token keeps full access
+	if code := doWithToken(t, "POST", ts.URL+"/v1/services/svc/start", srv.token); code != http.StatusAccepted {
+		t.Errorf("POST start with primary token: expected 202, got %d", code)
+	}
+	if code := doWithToken(t, "GET", ts.URL+"/v1/services", "unknown-token"); code != http.StatusUnauthorized {
+		t.Errorf("unknown token: expected 401, got %d", code)
+	}
+}
+
+func TestGenerateTokenWithScope(t *testing.T) {
+	srv := NewServer(daemon.NewDaemon(t.TempDir()), nil, "test")
+	if err := srv.GenerateToken(filepath.Join(t.TempDir(), "api.token"), ScopeRead); err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+	scope, ok := srv.tokenScope(srv.token)
+	if !ok || scope != ScopeRead {
+		t.Errorf("expected read scope, got %q (valid %v)", scope, ok)
+	}
+
+	if err := srv.GenerateToken(filepath.Join(t.TempDir(), "api.token"), "admin"); err == nil {
+		t.Error("expected error for unknown scope")
+	}
+}
+
+func TestLoadTokensErrors(t *testing.T) {
+	srv := NewServer(daemon.NewDaemon(t.TempDir()), nil, "test")
+
+	if err := srv.LoadTokens(filepath.Join(t.TempDir(), "missing")); err != nil {
+		t.Errorf("missing tokens file should load no tokens, got %v", err)
+	}
+
+	tests := []struct {
+		name, content, want string
+	}{
+		{"bad scope", "admin tok\n", `unknown scope "admin"`},
+		{"missing token", "\nread\n", "line 2"},
+		{"extra field", "read tok extra\n", "line 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "api-tokens")
+			os.WriteFile(path, []byte(tt.content), 0600)
+			err := srv.LoadTokens(path)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestAuthErrorCodes(t *testing.T) {
+	_, ts := setupScopedServer(t, "read dashboard-token\n")
+
+	tests := []struct {
+		token  string
+		status int
+		code   string
+	}{
+		{"unknown-token", http.StatusUnauthorized, CodeUnauthorized},
+		{"dashboard-token", http.StatusForbidden, CodeForbidden},
+	}
+	for _, tt := range tests {
+		req, _ := http.NewRequest("POST", ts.URL+"/v1/services/svc/stop", nil)
+		req.Header.Set("Authorization", "Bearer "+tt.token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("POST stop: %v", err)
+		}
+		var body errorResponse
+		json.NewDecoder(resp.Body).Decode(&body)
+		resp.Body.Close()
+		if resp.StatusCode != tt.status || body.Code != tt.code {
+			t.Errorf("token %q: got %d %q, want %d %q", tt.token, resp.StatusCode, body.Code, tt.status, tt.code)
+		}
+	}
+}
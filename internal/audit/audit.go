@@ -1,13 +1,20 @@
-// Package audit provides append-only structured logging for secret operations.
+// Package audit provides append-only structured logging for secret operations
+// and service lifecycle actions.
 //
-// Every secret access (read, write, delete, rotate) is recorded to an audit
-// log at ~/.aurelia/audit.log as newline-delimited JSON.
+// Every secret access (read, write, delete, rotate) and every start, stop,
+// restart, deploy and reload is recorded to an audit log at
+// ~/.aurelia/audit.log as newline-delimited JSON.
 package audit
 
 import (
+	"bufio"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
+	"slices"
 	"sync"
 	"time"
 )
@@ -20,34 +27,124 @@ const (
 	ActionSecretWrite  Action = "secret_write"
 	ActionSecretDelete Action = "secret_delete"
 	ActionSecretRotate Action = "secret_rotate"
+
+	ActionServiceStart   Action = "service_start"
+	ActionServiceStop    Action = "service_stop"
+	ActionServiceRestart Action = "service_restart"
+	ActionServiceDeploy  Action = "service_deploy"
+	ActionReload         Action = "reload"
 )
 
 // Entry is a single audit log record.
 type Entry struct {
 	Timestamp time.Time `json:"ts"`
 	Action    Action    `json:"action"`
-	Key       string    `json:"key"`
+	Key       string    `json:"key,omitempty"` // secret key, for secret actions
 	Service   string    `json:"service,omitempty"`
-	Actor     string    `json:"actor,omitempty"`   // "cli", "daemon", "rotation"
+	Actor     string    `json:"actor,omitempty"`   // "cli", "daemon", "rotation", "local", "token:<id>", "peer:<cn>"
 	Trigger   string    `json:"trigger,omitempty"` // "service_start", "manual", "hook"
 	Command   string    `json:"command,omitempty"` // rotation command if applicable
 	Error     string    `json:"error,omitempty"`
 }
 
-// Logger writes audit entries to an append-only file.
+type actorKey struct{}
+
+// WithActor returns a copy of ctx carrying the actor to record for actions
+// taken on its behalf.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFrom returns the actor set on ctx by WithActor, or "" if none.
+func ActorFrom(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
+
+// Default rotation limits.
+const (
+	DefaultMaxSize = 10 << 20 // bytes
+	DefaultKeep    = 5
+)
+
+// Logger writes audit entries to an append-only file. When the file would
+// grow past its size limit, or its first entry is older than its age limit,
+// it is rotated: audit.log becomes audit.log.1, audit.log.1 becomes
+// audit.log.2 and so on, keeping a fixed number of old files.
 type Logger struct {
-	mu   sync.Mutex
-	file *os.File
-	path string
+	mu      sync.Mutex
+	file    *os.File
+	path    string
+	born    time.Time // first entry in the live file, zero if unknown or empty
+	maxSize int64
+	maxAge  time.Duration
+	keep    int
+}
+
+// Option configures a Logger.
+type Option func(*Logger)
+
+// WithMaxSize rotates the log before a write would take it past n bytes
+// (default DefaultMaxSize). Zero or less disables size-based rotation.
+func WithMaxSize(n int64) Option {
+	return func(l *Logger) {
+		l.maxSize = n
+	}
+}
+
+// WithMaxAge rotates the log once its first entry is older than d. Zero, the
+// default, disables age-based rotation.
+func WithMaxAge(d time.Duration) Option {
+	return func(l *Logger) {
+		l.maxAge = d
+	}
+}
+
+// WithKeep sets how many rotated files are kept (default DefaultKeep). With
+// zero, rotated entries are discarded.
+func WithKeep(n int) Option {
+	return func(l *Logger) {
+		l.keep = max(n, 0)
+	}
 }
 
 // NewLogger creates or opens an audit log file for appending.
-func NewLogger(path string) (*Logger, error) {
-	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+func NewLogger(path string, opts ...Option) (*Logger, error) {
+	l := &Logger{path: path, maxSize: DefaultMaxSize, keep: DefaultKeep}
+	for _, opt := range opts {
+		opt(l)
+	}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// open opens the live file at l.path for appending, creating it if needed.
+func (l *Logger) open() error {
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("opening audit log: %w", err)
+	}
+	l.file = f
+	l.born = firstEntryTime(l.path)
+	return nil
+}
+
+// firstEntryTime returns the timestamp of the first entry in the file at
+// path, or the zero time if it is empty or unreadable.
+func firstEntryTime(path string) time.Time {
+	f, err := os.Open(path)
 	if err != nil {
-		return nil, fmt.Errorf("opening audit log: %w", err)
+		return time.Time{}
 	}
-	return &Logger{file: f, path: path}, nil
+	defer f.Close()
+	line, _ := bufio.NewReader(f).ReadBytes('\n')
+	var e Entry
+	if json.Unmarshal(line, &e) != nil {
+		return time.Time{}
+	}
+	return e.Timestamp
 }
 
 // Log writes an audit entry.
@@ -60,17 +157,151 @@ func (l *Logger) Log(entry Entry) error {
 	if err != nil {
 		return fmt.Errorf("marshaling audit entry: %w", err)
 	}
+	data = append(data, '\n')
 
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
-	if _, err := l.file.Write(append(data, '\n')); err != nil {
+	// Another logger on the same path, in this process or another, may
+	// have rotated the file away from under us
+	if err := l.reopenIfMoved(); err != nil {
+		return err
+	}
+	if l.shouldRotate(int64(len(data))) {
+		if err := l.rotate(); err != nil {
+			return err
+		}
+	}
+
+	if _, err := l.file.Write(data); err != nil {
 		return fmt.Errorf("writing audit entry: %w", err)
 	}
+	if l.born.IsZero() {
+		l.born = entry.Timestamp
+	}
 	return nil
 }
 
+// reopenIfMoved reopens l.path if the open file is no longer the one there,
+// or a previous reopen failed.
+func (l *Logger) reopenIfMoved() error {
+	if cur, err := l.file.Stat(); err == nil {
+		if st, err := os.Stat(l.path); err == nil && os.SameFile(cur, st) {
+			return nil
+		}
+	}
+	l.file.Close()
+	return l.open()
+}
+
+// shouldRotate reports whether the live file must be rotated before n more
+// bytes are written to it. An empty file is never rotated.
+func (l *Logger) shouldRotate(n int64) bool {
+	st, err := l.file.Stat()
+	if err != nil || st.Size() == 0 {
+		return false
+	}
+	if l.maxSize > 0 && st.Size()+n > l.maxSize {
+		return true
+	}
+	return l.maxAge > 0 && !l.born.IsZero() && time.Since(l.born) >= l.maxAge
+}
+
+// rotate shifts each kept file up one number, dropping the oldest, moves the
+// live file to path.1 and opens a new live file.
+func (l *Logger) rotate() error {
+	l.file.Close()
+	if l.keep == 0 {
+		os.Remove(l.path)
+	} else {
+		os.Remove(fmt.Sprintf("%s.%d", l.path, l.keep))
+		for i := l.keep - 1; i >= 1; i-- {
+			os.Rename(fmt.Sprintf("%s.%d", l.path, i), fmt.Sprintf("%s.%d", l.path, i+1))
+		}
+		os.Rename(l.path, l.path+".1")
+	}
+	return l.open()
+}
+
+// Path returns the path of the live log file.
+func (l *Logger) Path() string {
+	return l.path
+}
+
+// Filter selects audit entries. Zero fields match every entry.
+type Filter struct {
+	Action  Action
+	Service string
+	Key     string
+	Since   time.Time // entries at or after this time
+	Limit   int       // the most recent Limit matches (0 = all)
+}
+
+// Match reports whether e passes f.
+func (f Filter) Match(e Entry) bool {
+	return (f.Action == "" || e.Action == f.Action) &&
+		(f.Service == "" || e.Service == f.Service) &&
+		(f.Key == "" || e.Key == f.Key) &&
+		(f.Since.IsZero() || !e.Timestamp.Before(f.Since))
+}
+
+// Read returns the entries matching f from the log at path and its rotated
+// files, oldest first. Lines that aren't entries are skipped, and only
+// Entry's fields are kept from those that are. A missing log has no entries.
+// With a limit, only the latest matches are held while scanning, so reading
+// the tail of a large log doesn't load all of it.
+func Read(path string, f Filter) ([]Entry, error) {
+	files := []string{path}
+	for i := 1; ; i++ {
+		rotated := fmt.Sprintf("%s.%d", path, i)
+		if _, err := os.Stat(rotated); err != nil {
+			break
+		}
+		files = append(files, rotated)
+	}
+
+	entries := []Entry{}
+	next := 0 // with a limit, where the next match goes once entries is full
+	for i := len(files) - 1; i >= 0; i-- {
+		file, err := os.Open(files[i])
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading audit log: %w", err)
+		}
+		scanner := bufio.NewScanner(file)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+		for scanner.Scan() {
+			var e Entry
+			if json.Unmarshal(scanner.Bytes(), &e) != nil || e.Action == "" {
+				continue
+			}
+			switch {
+			case !f.Match(e):
+			case f.Limit <= 0 || len(entries) < f.Limit:
+				entries = append(entries, e)
+			default:
+				entries[next] = e
+				next = (next + 1) % f.Limit
+			}
+		}
+		err = scanner.Err()
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("reading audit log %s: %w", files[i], err)
+		}
+	}
+	// entries is a ring starting at next; rotate it back to oldest first
+	slices.Reverse(entries[:next])
+	slices.Reverse(entries[next:])
+	slices.Reverse(entries)
+	return entries, nil
+}
+
 // Close closes the audit log file.
 func (l *Logger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
 	return l.file.Close()
 }
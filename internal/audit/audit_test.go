@@ -2,6 +2,7 @@ package audit
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
@@ -117,3 +118,164 @@ func TestLoggerFilePermissions(t *testing.T) {
 		t.Errorf("expected 0600, got %o", perm)
 	}
 }
+
+func readKeys(t *testing.T, path string) []string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	var keys []string
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var e Entry
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("parsing %q: %v", line, err)
+		}
+		keys = append(keys, e.Key)
+	}
+	return keys
+}
+
+func TestLoggerRotatesBySize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	l, err := NewLogger(path, WithMaxSize(512), WithKeep(2))
+	if err != nil {
+		t.Fatalf("NewLogger: %v", err)
+	}
+	defer l.Close()
+
+	const n = 50
+	for i := range n {
+		if err := l.Log(Entry{Action: ActionSecretRead, Key: fmt.Sprintf("key-%02d", i)}); err != nil {
+			t.Fatalf("Log: %v", err)
+		}
+	}
+
+	for _, p := range []string{path, path + ".1", path + ".2"} {
+		info, err := os.Stat(p)
+		if err != nil {
+			t.Fatalf("expected %s: %v", filepath.Base(p), err)
+		}
+		if info.Size() > 512 {
+			t.Errorf("%s is %d bytes, over the 512 byte limit", filepath.Base(p), info.Size())
+		}
+		if perm := info.Mode().Perm(); perm != 0600 {
+			t.Errorf("%s: expected 0600, got %o", filepath.Base(p), perm)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("expected only 2 rotated files to be kept, got %s.3 (%v)", filepath.Base(path), err)
+	}
+
+	// The newest entries are in the live file, the ones before in .1
+	live := readKeys(t, path)
+	if got := live[len(live)-1]; got != fmt.Sprintf("key-%02d", n-1) {
+		t.Errorf("expected the last entry in the live file, got %q", got)
+	}
+	rotated := readKeys(t, path+".1")
+	if got, want := rotated[len(rotated)-1], fmt.Sprintf("key-%02d", n-1-len(live)); got != want {
+		t.Errorf("expected %s.1 to end with %q, got %q", filepath.Base(path), want, got)
+	}
+}
+
+func TestLoggerRotatesByAge(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	old, _ := NewLogger(path)
+	old.Log(Entry{Timestamp: time.Now().Add(-2 * time.Hour), Action: ActionSecretRead, Key: "old"})
+	old.Close()
+
+	l, err := NewLogger(path, WithMaxAge(time.Hour))
+	if err != nil {
+		t.Fatalf("NewLogger: %v", err)
+	}
+	defer l.Close()
+	l.Log(Entry{Action: ActionSecretRead, Key: "new"})
+	l.Log(Entry{Action: ActionSecretRead, Key: "newer"})
+
+	if got := strings.Join(readKeys(t, path), ","); got != "new,newer" {
+		t.Errorf("live file has %s, want new,newer", got)
+	}
+	if got := strings.Join(readKeys(t, path+".1"), ","); got != "old" {
+		t.Errorf("%s.1 has %s, want old", filepath.Base(path), got)
+	}
+}
+
+func TestLoggerFollowsRotationByAnotherLogger(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	rotating, _ := NewLogger(path, WithMaxSize(1))
+	defer rotating.Close()
+	other, _ := NewLogger(path)
+	defer other.Close()
+
+	rotating.Log(Entry{Action: ActionSecretRead, Key: "first"})
+	rotating.Log(Entry{Action: ActionSecretRead, Key: "second"})
+	other.Log(Entry{Action: ActionSecretRead, Key: "third"})
+
+	if got := strings.Join(readKeys(t, path), ","); got != "second,third" {
+		t.Errorf("live file has %s, want second,third", got)
+	}
+}
+
+func TestReadIncludesRotatedFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	l, _ := NewLogger(path, WithMaxSize(300))
+	defer l.Close()
+	for i := range 10 {
+		l.Log(Entry{Action: ActionSecretRead, Key: fmt.Sprintf("key-%d", i), Service: []string{"a", "b"}[i%2]})
+	}
+	if _, err := os.Stat(path + ".1"); err != nil {
+		t.Fatalf("expected the log to have rotated: %v", err)
+	}
+	os.WriteFile(path+".1", append(mustRead(t, path+".1"), "not json\n"...), 0600)
+
+	var keys []string
+	entries, err := Read(path, Filter{Service: "a"})
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	for _, e := range entries {
+		keys = append(keys, e.Key)
+	}
+	if got := strings.Join(keys, ","); got != "key-0,key-2,key-4,key-6,key-8" {
+		t.Errorf("got %s, want service a's entries oldest first", got)
+	}
+
+	entries, _ = Read(path, Filter{Limit: 2})
+	if len(entries) != 2 || entries[0].Key != "key-8" || entries[1].Key != "key-9" {
+		t.Errorf("expected the 2 most recent entries, got %+v", entries)
+	}
+
+	for _, tt := range []struct {
+		filter Filter
+		want   string
+	}{
+		{Filter{Limit: 3}, "key-7,key-8,key-9"},
+		{Filter{Service: "a", Limit: 2}, "key-6,key-8"},
+		{Filter{Service: "a", Limit: 20}, "key-0,key-2,key-4,key-6,key-8"},
+	} {
+		entries, err := Read(path, tt.filter)
+		if err != nil {
+			t.Fatalf("Read(%+v): %v", tt.filter, err)
+		}
+		keys = keys[:0]
+		for _, e := range entries {
+			keys = append(keys, e.Key)
+		}
+		if got := strings.Join(keys, ","); got != tt.want {
+			t.Errorf("Read(%+v) = %s, want %s", tt.filter, got, tt.want)
+		}
+	}
+
+	if entries, err := Read(filepath.Join(t.TempDir(), "missing.log"), Filter{}); err != nil || len(entries) != 0 {
+		t.Errorf("expected no entries for a missing log, got %v, %v", entries, err)
+	}
+}
+
+func mustRead(t *testing.T, path string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	return data
+}
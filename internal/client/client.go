@@ -0,0 +1,555 @@
+// Package client is a Go client for the aurelia daemon's REST API, over the
+// local Unix socket or over TCP with a bearer token.
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/benaskins/aurelia/internal/audit"
+	"github.com/benaskins/aurelia/internal/daemon"
+	"github.com/benaskins/aurelia/internal/gpu"
+	"github.com/benaskins/aurelia/internal/logbuf"
+)
+
+const (
+	// defaultTimeout bounds ordinary requests.
+	defaultTimeout = 30 * time.Second
+
+	// deployTimeout bounds a single deploy or rollback, which waits for
+	// health checks and the drain period.
+	deployTimeout = 5 * time.Minute
+
+	// deployAllTimeout bounds a deploy of every routed service in turn.
+	deployAllTimeout = 30 * time.Minute
+
+	// maxErrorBody caps how much of an error response is read.
+	maxErrorBody = 1 << 20
+)
+
+// Client talks to an aurelia daemon.
+type Client struct {
+	baseURL string // scheme and host; paths are appended
+	token   string // bearer token (TCP only)
+	http    *http.Client
+}
+
+// NewUnix creates a client for the daemon listening on the Unix socket at
+// socketPath (normally ~/.aurelia/aurelia.sock).
+func NewUnix(socketPath string) *Client {
+	return &Client{
+		baseURL: "http://aurelia",
+		http: &http.Client{
+			Timeout: defaultTimeout,
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					var d net.Dialer
+					return d.DialContext(ctx, "unix", socketPath)
+				},
+			},
+		},
+	}
+}
+
+// NewTCP creates a client for the daemon's TCP API at addr (host:port),
+// authenticating with the given bearer token.
+func NewTCP(addr, token string) *Client {
+	return &Client{
+		baseURL: "http://" + addr,
+		token:   token,
+		http:    &http.Client{Timeout: defaultTimeout},
+	}
+}
+
+// APIError is returned when the daemon responds with an error status.
+type APIError struct {
+	StatusCode int
+	Code       string // stable error code, e.g. "service_not_found"; empty if the body had none
+	Message    string // the daemon's error message, or the raw response body
+}
+
+func (e *APIError) Error() string {
+	if e.Code != "" {
+		return fmt.Sprintf("API error %d (%s): %s", e.StatusCode, e.Code, e.Message)
+	}
+	return fmt.Sprintf("API error %d: %s", e.StatusCode, e.Message)
+}
+
+// ListServices returns the state of every service on the daemon.
+func (c *Client) ListServices(ctx context.Context) ([]daemon.ServiceState, error) {
+	var states []daemon.ServiceState
+	if err := c.Get(ctx, "/v1/services", &states); err != nil {
+		return nil, err
+	}
+	return states, nil
+}
+
+// GetService returns the state of one service.
+func (c *Client) GetService(ctx context.Context, name string) (daemon.ServiceState, error) {
+	var st daemon.ServiceState
+	err := c.Get(ctx, servicePath(name, ""), &st)
+	return st, err
+}
+
+// InspectService returns the resolved config and runtime state of a service.
+func (c *Client) InspectService(ctx context.Context, name string) (daemon.ServiceInspect, error) {
+	var si daemon.ServiceInspect
+	err := c.Get(ctx, servicePath(name, "inspect"), &si)
+	return si, err
+}
+
+// ServiceSpec returns the spec the daemon loaded for a service, as YAML.
+func (c *Client) ServiceSpec(ctx context.Context, name string) ([]byte, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, servicePath(name, "spec")+"?format=yaml", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, connectError(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return nil, readAPIError(resp)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading response: %w", err)
+	}
+	return data, nil
+}
+
+// StartService starts a service without waiting for it to become ready.
+func (c *Client) StartService(ctx context.Context, name string) error {
+	return c.Post(ctx, servicePath(name, "start"), nil)
+}
+
+// StartServiceWait starts a service and blocks until it is running and
+// healthy or wait elapses. On timeout the error is an *APIError and the
+// returned state is the last one observed.
+func (c *Client) StartServiceWait(ctx context.Context, name string, wait time.Duration) (daemon.ServiceState, error) {
+	return c.postWait(ctx, servicePath(name, "start"), wait)
+}
+
+// StopService stops a service, and the services that hard-depend on it.
+func (c *Client) StopService(ctx context.Context, name string) error {
+	return c.Post(ctx, servicePath(name, "stop"), nil)
+}
+
+// RestartService restarts a service without waiting for it to become ready.
+func (c *Client) RestartService(ctx context.Context, name string) error {
+	return c.Post(ctx, servicePath(name, "restart"), nil)
+}
+
+// RestartServiceWait restarts a service and waits as for StartServiceWait.
+func (c *Client) RestartServiceWait(ctx context.Context, name string, wait time.Duration) (daemon.ServiceState, error) {
+	return c.postWait(ctx, servicePath(name, "restart"), wait)
+}
+
+// RestartServiceWithDeps restarts a service and its hard dependents in
+// dependency order, returning once they are all ready again.
+func (c *Client) RestartServiceWithDeps(ctx context.Context, name string) error {
+	ctx, cancel := context.WithTimeout(ctx, deployTimeout)
+	defer cancel()
+	return c.post(ctx, c.longRunning(), servicePath(name, "restart")+"?cascade=true", nil)
+}
+
+// RollingRestartService restarts a service one replica at a time, returning
+// once the last is ready again.
+func (c *Client) RollingRestartService(ctx context.Context, name string) error {
+	ctx, cancel := context.WithTimeout(ctx, deployTimeout)
+	defer cancel()
+	return c.post(ctx, c.longRunning(), servicePath(name, "restart")+"?rolling=true", nil)
+}
+
+// DeployService runs a blue-green deploy of a service, draining the old
+// instance for drain (zero uses the daemon's default).
+func (c *Client) DeployService(ctx context.Context, name string, drain time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, deployTimeout)
+	defer cancel()
+	return c.post(ctx, c.longRunning(), servicePath(name, "deploy")+drainQuery(drain), nil)
+}
+
+// RollbackService redeploys the instance replaced by a service's last deploy.
+func (c *Client) RollbackService(ctx context.Context, name string, drain time.Duration) (*daemon.RollbackResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, deployTimeout)
+	defer cancel()
+	var result daemon.RollbackResult
+	if err := c.post(ctx, c.longRunning(), servicePath(name, "rollback")+drainQuery(drain), &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// DeployAll deploys every routed service in dependency order. A deploy that
+// ran but did not fully succeed is reported in the result, not as an error.
+func (c *Client) DeployAll(ctx context.Context, drain time.Duration, continueOnError bool) (*daemon.DeployAllResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, deployAllTimeout)
+	defer cancel()
+
+	query := url.Values{}
+	if drain > 0 {
+		query.Set("drain", drain.String())
+	}
+	if continueOnError {
+		query.Set("continue_on_error", "true")
+	}
+	path := "/v1/deploy"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	var result daemon.DeployAllResult
+	if err := c.postResult(ctx, c.longRunning(), path, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Ship fetches, builds, deploys and notifies for a service. A pipeline step
+// that failed is reported in the result, not as an error.
+func (c *Client) Ship(ctx context.Context, name string) (*daemon.ShipResult, error) {
+	var result daemon.ShipResult
+	if err := c.postResult(ctx, c.http, servicePath(name, "ship"), &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Reload makes the daemon re-read its spec files and reconcile.
+func (c *Client) Reload(ctx context.Context) (*daemon.ReloadResult, error) {
+	var result daemon.ReloadResult
+	if err := c.Post(ctx, "/v1/reload", &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// EnterMaintenance puts the daemon in maintenance mode, releasing every
+// service without stopping it.
+func (c *Client) EnterMaintenance(ctx context.Context) error {
+	return c.Post(ctx, "/v1/maintenance", nil)
+}
+
+// LogsOptions selects log lines. The zero value returns the daemon's default
+// number of recent lines.
+type LogsOptions struct {
+	Lines int    // most recent matching lines to return
+	Grep  string // substring filter, or a regular expression with Regex
+	Regex bool
+	Since string // a duration before now (e.g. "15m") or an RFC 3339 time
+}
+
+// Logs returns a service's recent log lines.
+func (c *Client) Logs(ctx context.Context, name string, opts LogsOptions) ([]string, error) {
+	var resp struct {
+		Lines []string `json:"lines"`
+	}
+	if err := c.Get(ctx, logsPath(name, opts, nil), &resp); err != nil {
+		return nil, err
+	}
+	return resp.Lines, nil
+}
+
+// LogEntries returns a service's recent log lines with the time each was
+// written.
+func (c *Client) LogEntries(ctx context.Context, name string, opts LogsOptions) ([]logbuf.Line, error) {
+	var resp struct {
+		Entries []logbuf.Line `json:"entries"`
+	}
+	if err := c.Get(ctx, logsPath(name, opts, url.Values{"timestamps": {"true"}}), &resp); err != nil {
+		return nil, err
+	}
+	return resp.Entries, nil
+}
+
+// logsPath is the logs endpoint path for a service, with opts and any extra
+// parameters in its query.
+func logsPath(name string, opts LogsOptions, params url.Values) string {
+	if params == nil {
+		params = url.Values{}
+	}
+	if opts.Lines > 0 {
+		params.Set("n", strconv.Itoa(opts.Lines))
+	}
+	if opts.Grep != "" {
+		params.Set("grep", opts.Grep)
+		if opts.Regex {
+			params.Set("regex", "true")
+		}
+	}
+	if opts.Since != "" {
+		params.Set("since", opts.Since)
+	}
+	path := servicePath(name, "logs")
+	if len(params) > 0 {
+		path += "?" + params.Encode()
+	}
+	return path
+}
+
+// Summary returns counts of services by state and whether any need attention.
+func (c *Client) Summary(ctx context.Context) (daemon.StatusSummary, error) {
+	var sum daemon.StatusSummary
+	err := c.Get(ctx, "/v1/summary", &sum)
+	return sum, err
+}
+
+// Info returns the daemon's version, uptime and configuration summary.
+func (c *Client) Info(ctx context.Context) (daemon.Info, error) {
+	var info daemon.Info
+	err := c.Get(ctx, "/v1/info", &info)
+	return info, err
+}
+
+// GPUHistory returns the daemon's recent GPU samples, oldest first, keeping
+// only those taken after since when it is set.
+func (c *Client) GPUHistory(ctx context.Context, since time.Time) (*gpu.Series, error) {
+	path := "/v1/gpu/history"
+	if !since.IsZero() {
+		path += "?" + url.Values{"since": {since.Format(time.RFC3339Nano)}}.Encode()
+	}
+	var series gpu.Series
+	if err := c.Get(ctx, path, &series); err != nil {
+		return nil, err
+	}
+	return &series, nil
+}
+
+// Events returns the daemon's most recent n events, oldest first, keeping
+// only the named service's events when service is set.
+func (c *Client) Events(ctx context.Context, service string, n int) ([]daemon.Event, error) {
+	params := url.Values{}
+	if n > 0 {
+		params.Set("n", strconv.Itoa(n))
+	}
+	if service != "" {
+		params.Set("service", service)
+	}
+	path := "/v1/events"
+	if len(params) > 0 {
+		path += "?" + params.Encode()
+	}
+
+	var resp struct {
+		Events []daemon.Event `json:"events"`
+	}
+	if err := c.Get(ctx, path, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Events, nil
+}
+
+// AuditEntries fetches the daemon's audit log entries matching f, oldest
+// first. A zero f.Limit leaves the daemon's default.
+func (c *Client) AuditEntries(ctx context.Context, f audit.Filter) ([]audit.Entry, error) {
+	params := url.Values{}
+	if f.Action != "" {
+		params.Set("action", string(f.Action))
+	}
+	if f.Service != "" {
+		params.Set("service", f.Service)
+	}
+	if f.Key != "" {
+		params.Set("key", f.Key)
+	}
+	if !f.Since.IsZero() {
+		params.Set("since", f.Since.Format(time.RFC3339))
+	}
+	if f.Limit > 0 {
+		params.Set("n", strconv.Itoa(f.Limit))
+	}
+	path := "/v1/audit"
+	if len(params) > 0 {
+		path += "?" + params.Encode()
+	}
+
+	var resp struct {
+		Entries []audit.Entry `json:"entries"`
+	}
+	if err := c.Get(ctx, path, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Entries, nil
+}
+
+// Get sends a GET request for path and decodes the JSON response into v.
+func (c *Client) Get(ctx context.Context, path string, v any) error {
+	return c.do(ctx, c.http, http.MethodGet, path, nil, v)
+}
+
+// Post sends a bodyless POST request to path and decodes the JSON response
+// into v, which may be nil.
+func (c *Client) Post(ctx context.Context, path string, v any) error {
+	return c.post(ctx, c.http, path, v)
+}
+
+// Do sends a request with an optional JSON body and decodes the JSON
+// response into v, which may be nil. An error status is returned as an
+// *APIError.
+func (c *Client) Do(ctx context.Context, method, path string, body io.Reader, v any) error {
+	return c.do(ctx, c.http, method, path, body, v)
+}
+
+// Stream sends a request with no overall timeout and returns the response
+// for the caller to read and close, for endpoints that stream their output.
+// An error status is returned as an *APIError.
+func (c *Client) Stream(ctx context.Context, method, path string, body io.Reader) (*http.Response, error) {
+	req, err := c.newRequest(ctx, method, path, body)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.longRunning().Do(req)
+	if err != nil {
+		return nil, connectError(err)
+	}
+	if resp.StatusCode >= 400 {
+		defer resp.Body.Close()
+		return nil, readAPIError(resp)
+	}
+	return resp, nil
+}
+
+// longRunning returns an HTTP client sharing c's transport without the
+// per-request timeout; callers bound the request through its context.
+func (c *Client) longRunning() *http.Client {
+	hc := *c.http
+	hc.Timeout = 0
+	return &hc
+}
+
+func (c *Client) post(ctx context.Context, hc *http.Client, path string, v any) error {
+	return c.do(ctx, hc, http.MethodPost, path, nil, v)
+}
+
+func (c *Client) do(ctx context.Context, hc *http.Client, method, path string, body io.Reader, v any) error {
+	req, err := c.newRequest(ctx, method, path, body)
+	if err != nil {
+		return err
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return connectError(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return readAPIError(resp)
+	}
+	if v == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("decoding response: %w", err)
+	}
+	return nil
+}
+
+// postResult posts to an endpoint that reports partial failure as 422 with
+// a result body, decoding the result for both 200 and 422.
+func (c *Client) postResult(ctx context.Context, hc *http.Client, path string, v any) error {
+	req, err := c.newRequest(ctx, http.MethodPost, path, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return connectError(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 && resp.StatusCode != http.StatusUnprocessableEntity {
+		return readAPIError(resp)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("decoding response: %w", err)
+	}
+	return nil
+}
+
+// postWait posts to a start or restart endpoint with ?wait=, returning the
+// service state the daemon reports whether or not it became ready.
+func (c *Client) postWait(ctx context.Context, path string, wait time.Duration) (daemon.ServiceState, error) {
+	ctx, cancel := context.WithTimeout(ctx, wait+defaultTimeout)
+	defer cancel()
+
+	req, err := c.newRequest(ctx, http.MethodPost, path+"?wait="+wait.String(), nil)
+	if err != nil {
+		return daemon.ServiceState{}, err
+	}
+	resp, err := c.longRunning().Do(req)
+	if err != nil {
+		return daemon.ServiceState{}, connectError(err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		State daemon.ServiceState `json:"state"`
+		Error string              `json:"error"`
+		Code  string              `json:"code"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxErrorBody)).Decode(&result); err != nil {
+		return daemon.ServiceState{}, fmt.Errorf("decoding response (status %d): %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode >= 400 {
+		return result.State, &APIError{StatusCode: resp.StatusCode, Code: result.Code, Message: result.Error}
+	}
+	return result.State, nil
+}
+
+func (c *Client) newRequest(ctx context.Context, method, path string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	return req, nil
+}
+
+func connectError(err error) error {
+	return fmt.Errorf("connecting to daemon: %w (is aurelia daemon running?)", err)
+}
+
+// readAPIError builds an *APIError from an error response, preferring the
+// "error" and "code" fields of a JSON body.
+func readAPIError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	apiErr := &APIError{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(body))}
+	var e struct {
+		Error string `json:"error"`
+		Code  string `json:"code"`
+	}
+	if json.Unmarshal(body, &e) == nil && e.Error != "" {
+		apiErr.Message = e.Error
+		apiErr.Code = e.Code
+	}
+	return apiErr
+}
+
+// servicePath returns the API path of a service, or of one of its actions.
+func servicePath(name, action string) string {
+	p := "/v1/services/" + url.PathEscape(name)
+	if action != "" {
+		p += "/" + action
+	}
+	return p
+}
+
+func drainQuery(drain time.Duration) string {
+	if drain <= 0 {
+		return ""
+	}
+	return "?drain=" + drain.String()
+}
@@ -0,0 +1,234 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/benaskins/aurelia/internal/api"
+	"github.com/benaskins/aurelia/internal/daemon"
+	"github.com/benaskins/aurelia/internal/driver"
+)
+
+const sleepSpec = `
+service:
+  name: %s
+  type: native
+  command: "sleep 30"
+`
+
+// startDaemon starts a daemon over the given spec files and an API server
+// for it.
+func startDaemon(t *testing.T, specs map[string]string) *api.Server {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, content := range specs {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	d := daemon.NewDaemon(dir)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	if err := d.Start(ctx); err != nil {
+		t.Fatalf("daemon start: %v", err)
+	}
+	t.Cleanup(func() { d.Stop(5 * time.Second) })
+
+	srv := api.NewServer(d, nil, "test")
+	t.Cleanup(func() { srv.Shutdown(context.Background()) })
+	return srv
+}
+
+// setupUnix serves the API on a temporary Unix socket and returns a client
+// for it.
+func setupUnix(t *testing.T, specs map[string]string) *Client {
+	t.Helper()
+	srv := startDaemon(t, specs)
+
+	// Use /tmp for socket to avoid macOS 104-char Unix socket path limit
+	sockDir, err := os.MkdirTemp("/tmp", "aurelia-client-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(sockDir) })
+	sockPath := filepath.Join(sockDir, "t.sock")
+	go srv.ListenUnix(sockPath)
+	waitForListener(t, "unix", sockPath)
+
+	return NewUnix(sockPath)
+}
+
+func waitForListener(t *testing.T, network, addr string) {
+	t.Helper()
+	for i := 0; i < 50; i++ {
+		if conn, err := net.Dial(network, addr); err == nil {
+			conn.Close()
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("%s listener %s not ready", network, addr)
+}
+
+func waitForState(t *testing.T, c *Client, name string, want driver.State) daemon.ServiceState {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		st, err := c.GetService(context.Background(), name)
+		if err != nil {
+			t.Fatalf("GetService: %v", err)
+		}
+		if st.State == want {
+			return st
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%s: state %s, want %s", name, st.State, want)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestClientServiceLifecycle(t *testing.T) {
+	c := setupUnix(t, map[string]string{
+		"alpha.yaml": strings.ReplaceAll(sleepSpec, "%s", "alpha"),
+		"beta.yaml":  strings.ReplaceAll(sleepSpec, "%s", "beta"),
+	})
+	ctx := context.Background()
+
+	states, err := c.ListServices(ctx)
+	if err != nil {
+		t.Fatalf("ListServices: %v", err)
+	}
+	if len(states) != 2 {
+		t.Fatalf("expected 2 services, got %d", len(states))
+	}
+
+	st := waitForState(t, c, "alpha", driver.StateRunning)
+	if st.PID == 0 {
+		t.Error("expected running service to report a PID")
+	}
+
+	if err := c.StopService(ctx, "alpha"); err != nil {
+		t.Fatalf("StopService: %v", err)
+	}
+	waitForState(t, c, "alpha", driver.StateStopped)
+
+	st, err = c.StartServiceWait(ctx, "alpha", 5*time.Second)
+	if err != nil {
+		t.Fatalf("StartServiceWait: %v", err)
+	}
+	if st.State != driver.StateRunning {
+		t.Errorf("expected running after wait, got %s", st.State)
+	}
+
+	if err := c.RestartService(ctx, "beta"); err != nil {
+		t.Fatalf("RestartService: %v", err)
+	}
+	waitForState(t, c, "beta", driver.StateRunning)
+}
+
+func TestClientNotFound(t *testing.T) {
+	c := setupUnix(t, map[string]string{
+		"alpha.yaml": strings.ReplaceAll(sleepSpec, "%s", "alpha"),
+	})
+
+	_, err := c.GetService(context.Background(), "missing")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected *APIError, got %v", err)
+	}
+	if apiErr.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", apiErr.StatusCode)
+	}
+	if apiErr.Code != "service_not_found" {
+		t.Errorf("expected code service_not_found, got %q", apiErr.Code)
+	}
+	if strings.Contains(apiErr.Message, "{") {
+		t.Errorf("expected the daemon's message, not the raw body: %q", apiErr.Message)
+	}
+}
+
+func TestClientReloadLogsInfo(t *testing.T) {
+	c := setupUnix(t, map[string]string{
+		"alpha.yaml": strings.ReplaceAll(sleepSpec, "%s", "alpha"),
+	})
+	ctx := context.Background()
+
+	result, err := c.Reload(ctx)
+	if err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if len(result.Added)+len(result.Removed)+len(result.Restarted) != 0 {
+		t.Errorf("expected no changes for unchanged specs, got %+v", result)
+	}
+
+	if _, err := c.Logs(ctx, "alpha", LogsOptions{Lines: 10, Grep: "x"}); err != nil {
+		t.Errorf("Logs: %v", err)
+	}
+	if _, err := c.Logs(ctx, "missing", LogsOptions{}); err == nil {
+		t.Error("expected error for logs of unknown service")
+	}
+
+	info, err := c.Info(ctx)
+	if err != nil {
+		t.Fatalf("Info: %v", err)
+	}
+	if info.Version != "test" {
+		t.Errorf("expected version %q, got %q", "test", info.Version)
+	}
+}
+
+func TestClientTCPBearerToken(t *testing.T) {
+	srv := startDaemon(t, map[string]string{
+		"alpha.yaml": strings.ReplaceAll(sleepSpec, "%s", "alpha"),
+	})
+	tokenPath := filepath.Join(t.TempDir(), "api.token")
+	if err := srv.GenerateToken(tokenPath); err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+	token, err := os.ReadFile(tokenPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	addr := ln.Addr().String()
+	ln.Close() // free the port for ListenTCP
+	go srv.ListenTCP(addr)
+	waitForListener(t, "tcp", addr)
+
+	ctx := context.Background()
+	states, err := NewTCP(addr, strings.TrimSpace(string(token))).ListServices(ctx)
+	if err != nil {
+		t.Fatalf("ListServices with token: %v", err)
+	}
+	if len(states) != 1 || states[0].Name != "alpha" {
+		t.Errorf("unexpected services: %+v", states)
+	}
+
+	_, err = NewTCP(addr, "wrong-token").ListServices(ctx)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected 401 APIError with wrong token, got %v", err)
+	}
+}
+
+func TestClientConnectError(t *testing.T) {
+	c := NewUnix(filepath.Join(t.TempDir(), "absent.sock"))
+	_, err := c.ListServices(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "is aurelia daemon running?") {
+		t.Errorf("expected connection error, got %v", err)
+	}
+}
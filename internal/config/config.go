@@ -4,10 +4,15 @@ import (
 	"errors"
 	"fmt"
 	"io/fs"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
+	"time"
 
+	"github.com/benaskins/aurelia/internal/audit"
+	"github.com/benaskins/aurelia/internal/spec"
 	"gopkg.in/yaml.v3"
 )
 
@@ -39,6 +44,10 @@ type TLS struct {
 	Cert string `yaml:"cert"` // path to server certificate (PEM)
 	Key  string `yaml:"key"`  // path to server private key (PEM)
 	CA   string `yaml:"ca"`   // path to CA certificate for verifying client certs (PEM)
+
+	// RequireClientCert rejects TCP API connections without a client cert
+	// signed by CA at the TLS layer; requests must also carry a bearer token.
+	RequireClientCert bool `yaml:"require_client_cert,omitempty"`
 }
 
 // Configured returns true if all required TLS paths are set.
@@ -86,6 +95,32 @@ type Diagnose struct {
 	BaseURL      string `yaml:"base_url,omitempty"` // base URL for openai-compatible providers
 }
 
+// Audit sets when ~/.aurelia/audit.log is rotated and how many old files
+// are kept.
+type Audit struct {
+	MaxSize spec.ByteSize `yaml:"max_size,omitempty"` // rotate past this size, e.g. "10MB" (default 10MB)
+	MaxAge  time.Duration `yaml:"max_age,omitempty"`  // rotate once the oldest entry is this old (default: never)
+	Keep    int           `yaml:"keep,omitempty"`     // rotated files kept as audit.log.1, .2, ... (default 5)
+}
+
+// Options returns the audit.Logger options for a, which may be nil.
+func (a *Audit) Options() []audit.Option {
+	if a == nil {
+		return nil
+	}
+	var opts []audit.Option
+	if a.MaxSize > 0 {
+		opts = append(opts, audit.WithMaxSize(int64(a.MaxSize)))
+	}
+	if a.MaxAge > 0 {
+		opts = append(opts, audit.WithMaxAge(a.MaxAge))
+	}
+	if a.Keep > 0 {
+		opts = append(opts, audit.WithKeep(a.Keep))
+	}
+	return opts
+}
+
 // ServiceCertConfig describes a TLS certificate to auto-renew via the CA peer.
 type ServiceCertConfig struct {
 	Role     string `yaml:"role"`      // PKI role (server, client)
@@ -95,9 +130,47 @@ type ServiceCertConfig struct {
 	IsClient bool   `yaml:"is_client"` // true for client certs (client.crt), false for server (cert.crt)
 }
 
+// Outputs is a list of paths that may be written in YAML as a single string
+// or as a sequence of them.
+type Outputs []string
+
+// UnmarshalYAML accepts a string or a sequence of strings. An empty string
+// gives no outputs.
+func (o *Outputs) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		var s string
+		if err := value.Decode(&s); err != nil {
+			return err
+		}
+		*o = nil
+		if s != "" {
+			*o = Outputs{s}
+		}
+		return nil
+	}
+	var list []string
+	if err := value.Decode(&list); err != nil {
+		return err
+	}
+	*o = list
+	return nil
+}
+
+// MarshalYAML writes a single output as a string, the form it had before
+// lists were accepted.
+func (o Outputs) MarshalYAML() (any, error) {
+	switch len(o) {
+	case 0:
+		return "", nil
+	case 1:
+		return o[0], nil
+	}
+	return []string(o), nil
+}
+
 // Config holds persistent daemon configuration loaded from ~/.aurelia/config.yaml.
 type Config struct {
-	RoutingOutput string              `yaml:"routing_output"`
+	RoutingOutput Outputs             `yaml:"routing_output"` // Traefik config files, or "stdout"
 	APIAddr       string              `yaml:"api_addr"`
 	NodeName      string              `yaml:"node_name,omitempty"`
 	Nodes         []Node              `yaml:"nodes,omitempty"`
@@ -107,7 +180,99 @@ type Config struct {
 	OpenBao       *OpenBao            `yaml:"openbao,omitempty"`
 	OpenBaoPeer   *OpenBaoPeer        `yaml:"openbao_peer,omitempty"`
 	Diagnose      *Diagnose           `yaml:"diagnose,omitempty"`
+	Audit         *Audit              `yaml:"audit,omitempty"`
 	ServiceCerts  []ServiceCertConfig `yaml:"service_certs,omitempty"`
+
+	// MaxParallelStarts bounds concurrent service starts within a dependency
+	// level during daemon startup (0 = daemon default, 1 = sequential).
+	MaxParallelStarts int `yaml:"max_parallel_starts,omitempty"`
+
+	// StartupHealthWait, when set to false, stops daemon startup from blocking
+	// on dependency health: services start in order, best-effort. Unset
+	// means true.
+	StartupHealthWait *bool `yaml:"startup_health_wait,omitempty"`
+
+	// ExternalHealthWait is how long daemon startup waits for an external
+	// dependency to report healthy before starting its dependents anyway,
+	// e.g. "2m" (default 60s).
+	ExternalHealthWait time.Duration `yaml:"external_health_wait,omitempty"`
+
+	// StrictSpecs, when set to false, makes the daemon ignore spec keys it
+	// doesn't recognise instead of rejecting the spec. Unset means true.
+	StrictSpecs *bool `yaml:"strict_specs,omitempty"`
+
+	// RoutingEntryPoint and RoutingEntryPointTLS name the Traefik entrypoints
+	// for plain and TLS routes (defaults "web" and "websecure").
+	RoutingEntryPoint    string `yaml:"routing_entrypoint,omitempty"`
+	RoutingEntryPointTLS string `yaml:"routing_entrypoint_tls,omitempty"`
+
+	// PortExclusions lists ports inside the dynamic range that are reserved
+	// for other tooling and must never be allocated to services.
+	PortExclusions []int `yaml:"port_exclusions,omitempty"`
+
+	// LogLevel is the daemon's minimum log level: debug, info (the
+	// default), warn or error.
+	LogLevel string `yaml:"log_level,omitempty"`
+
+	// WatchSpecs, when set to false, stops the daemon reloading when spec
+	// files change; specs are then only re-read by `aurelia reload`. Unset
+	// means true.
+	WatchSpecs *bool `yaml:"watch_specs,omitempty"`
+
+	// WatchDebounce is how long the spec watcher waits after the last file
+	// change before reloading, e.g. "1s" (default 500ms).
+	WatchDebounce time.Duration `yaml:"watch_debounce,omitempty"`
+
+	// APIReadTimeout is how long the API's plain read endpoints run before
+	// giving up with a timeout error, e.g. "1m" (default 30s).
+	APIReadTimeout time.Duration `yaml:"api_read_timeout,omitempty"`
+
+	// SpecDirs lists extra directories to load specs from, after
+	// ~/.aurelia/services and before any --spec-dir flags.
+	SpecDirs []string `yaml:"spec_dirs,omitempty"`
+
+	// SpecOverride lets a later spec directory replace a service of the
+	// same name from an earlier one; by default the duplicate is an error.
+	SpecOverride bool `yaml:"spec_override,omitempty"`
+
+	// DefaultRestart is the restart policy for services whose spec has no
+	// restart block, after the spec directory's defaults file is merged in.
+	// Unset, such services restart on failure, up to three times.
+	DefaultRestart *spec.RestartPolicy `yaml:"default_restart,omitempty"`
+
+	// ContainerPrefix is prepended to a container service's name to name
+	// its Docker container (default "aurelia-"). Daemons sharing a Docker
+	// host need different prefixes.
+	ContainerPrefix string `yaml:"container_prefix,omitempty"`
+
+	// GlobalEnv is added to every service's environment, beneath the
+	// service's own env and secrets and the variables aurelia injects.
+	GlobalEnv map[string]string `yaml:"global_env,omitempty"`
+}
+
+// Level returns the parsed LogLevel, slog.LevelInfo if unset.
+func (c *Config) Level() (slog.Level, error) {
+	var level slog.Level
+	if c.LogLevel == "" {
+		return slog.LevelInfo, nil
+	}
+	if err := level.UnmarshalText([]byte(c.LogLevel)); err != nil {
+		return slog.LevelInfo, fmt.Errorf("log_level: expected debug, info, warn or error, got %q", c.LogLevel)
+	}
+	return level, nil
+}
+
+// containerPrefixRe matches what Docker accepts at the start of a container
+// name.
+var containerPrefixRe = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// ValidateContainerPrefix checks ContainerPrefix, if set, can start a Docker
+// container name.
+func (c *Config) ValidateContainerPrefix() error {
+	if c.ContainerPrefix != "" && !containerPrefixRe.MatchString(c.ContainerPrefix) {
+		return fmt.Errorf("container_prefix: expected letters, digits, '_', '.' or '-', starting with a letter or digit, got %q", c.ContainerPrefix)
+	}
+	return nil
 }
 
 // SpecSourceDir returns the source spec directory for drift detection.
@@ -233,9 +398,14 @@ func Load(path string) (*Config, error) {
 	if err := yaml.Unmarshal(data, cfg); err != nil {
 		return nil, err
 	}
-	cfg.RoutingOutput = os.ExpandEnv(cfg.RoutingOutput)
+	for i, output := range cfg.RoutingOutput {
+		cfg.RoutingOutput[i] = os.ExpandEnv(output)
+	}
 	cfg.APIAddr = os.ExpandEnv(cfg.APIAddr)
 	cfg.LaminaRoot = os.ExpandEnv(cfg.LaminaRoot)
 	cfg.SpecSource = os.ExpandEnv(cfg.SpecSource)
+	for i, dir := range cfg.SpecDirs {
+		cfg.SpecDirs[i] = os.ExpandEnv(dir)
+	}
 	return cfg, nil
 }
@@ -3,7 +3,11 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"slices"
 	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
 )
 
 func TestLoadValidConfig(t *testing.T) {
@@ -22,7 +26,7 @@ api_addr: 127.0.0.1:9090
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
-	if cfg.RoutingOutput != "/tmp/traefik/dynamic.yaml" {
+	if !slices.Equal(cfg.RoutingOutput, Outputs{"/tmp/traefik/dynamic.yaml"}) {
 		t.Errorf("RoutingOutput = %q, want %q", cfg.RoutingOutput, "/tmp/traefik/dynamic.yaml")
 	}
 	if cfg.APIAddr != "127.0.0.1:9090" {
@@ -36,7 +40,7 @@ func TestLoadMissingFile(t *testing.T) {
 	if err != nil {
 		t.Fatalf("expected no error for missing file, got: %v", err)
 	}
-	if cfg.RoutingOutput != "" {
+	if len(cfg.RoutingOutput) != 0 {
 		t.Errorf("RoutingOutput = %q, want empty", cfg.RoutingOutput)
 	}
 	if cfg.APIAddr != "" {
@@ -57,7 +61,7 @@ func TestLoadEmptyFile(t *testing.T) {
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
-	if cfg.RoutingOutput != "" {
+	if len(cfg.RoutingOutput) != 0 {
 		t.Errorf("RoutingOutput = %q, want empty", cfg.RoutingOutput)
 	}
 	if cfg.APIAddr != "" {
@@ -80,7 +84,7 @@ func TestLoadPartialConfig(t *testing.T) {
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
-	if cfg.RoutingOutput != "/tmp/traefik/dynamic.yaml" {
+	if !slices.Equal(cfg.RoutingOutput, Outputs{"/tmp/traefik/dynamic.yaml"}) {
 		t.Errorf("RoutingOutput = %q, want %q", cfg.RoutingOutput, "/tmp/traefik/dynamic.yaml")
 	}
 	if cfg.APIAddr != "" {
@@ -96,6 +100,8 @@ func TestLoadExpandsEnvVars(t *testing.T) {
 
 	content := `routing_output: ${AURELIA_ROOT}/traefik/dynamic/aurelia.yaml
 api_addr: 127.0.0.1:9090
+spec_dirs:
+  - ${AURELIA_ROOT}/site/services
 `
 	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
 		t.Fatal(err)
@@ -105,14 +111,78 @@ api_addr: 127.0.0.1:9090
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
-	if cfg.RoutingOutput != "/opt/aurelia/traefik/dynamic/aurelia.yaml" {
+	if !slices.Equal(cfg.RoutingOutput, Outputs{"/opt/aurelia/traefik/dynamic/aurelia.yaml"}) {
 		t.Errorf("RoutingOutput = %q, want expanded path", cfg.RoutingOutput)
 	}
+	if len(cfg.SpecDirs) != 1 || cfg.SpecDirs[0] != "/opt/aurelia/site/services" {
+		t.Errorf("SpecDirs = %q, want expanded path", cfg.SpecDirs)
+	}
 	if cfg.APIAddr != "127.0.0.1:9090" {
 		t.Errorf("APIAddr = %q, want unchanged", cfg.APIAddr)
 	}
 }
 
+func TestLoadAudit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := `audit:
+  max_size: 1MB
+  max_age: 168h
+  keep: 3
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if a := cfg.Audit; a == nil || a.MaxSize != 1<<20 || a.MaxAge != 168*time.Hour || a.Keep != 3 {
+		t.Errorf("unexpected audit block: %+v", cfg.Audit)
+	}
+	if n := len(cfg.Audit.Options()); n != 3 {
+		t.Errorf("expected 3 logger options, got %d", n)
+	}
+	if opts := (*Audit)(nil).Options(); opts != nil {
+		t.Errorf("expected no options without an audit block, got %d", len(opts))
+	}
+}
+
+func TestLoadRoutingOutputList(t *testing.T) {
+	t.Setenv("AURELIA_ROOT", "/opt/aurelia")
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := `routing_output:
+  - ${AURELIA_ROOT}/traefik/a.yaml
+  - /etc/traefik/b.yaml
+  - stdout
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := Outputs{"/opt/aurelia/traefik/a.yaml", "/etc/traefik/b.yaml", "stdout"}
+	if !slices.Equal(cfg.RoutingOutput, want) {
+		t.Errorf("RoutingOutput = %q, want %q", cfg.RoutingOutput, want)
+	}
+
+	data, err := yaml.Marshal(&Config{RoutingOutput: Outputs{"/tmp/a.yaml"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var raw map[string]any
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		t.Fatal(err)
+	}
+	if raw["routing_output"] != "/tmp/a.yaml" {
+		t.Errorf("single output marshaled as %#v, want a string", raw["routing_output"])
+	}
+}
+
 func TestLoadNodesConfig(t *testing.T) {
 	t.Parallel()
 	dir := t.TempDir()
@@ -226,6 +296,7 @@ tls:
   cert: /etc/aurelia/tls/server.crt
   key: /etc/aurelia/tls/server.key
   ca: /etc/aurelia/tls/ca.crt
+  require_client_cert: true
 `
 	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
 		t.Fatal(err)
@@ -250,6 +321,9 @@ tls:
 	if !cfg.TLS.Configured() {
 		t.Error("expected TLS.Configured() to return true")
 	}
+	if !cfg.TLS.RequireClientCert {
+		t.Error("expected TLS.RequireClientCert to be true")
+	}
 }
 
 func TestTLSConfiguredPartial(t *testing.T) {
@@ -492,10 +566,82 @@ func TestLoadCommentsOnly(t *testing.T) {
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
-	if cfg.RoutingOutput != "" {
+	if len(cfg.RoutingOutput) != 0 {
 		t.Errorf("RoutingOutput = %q, want empty", cfg.RoutingOutput)
 	}
 	if cfg.APIAddr != "" {
 		t.Errorf("APIAddr = %q, want empty", cfg.APIAddr)
 	}
 }
+
+func TestLoadPortExclusions(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+
+	content := `port_exclusions: [20080, 20443]
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(cfg.PortExclusions) != 2 || cfg.PortExclusions[0] != 20080 || cfg.PortExclusions[1] != 20443 {
+		t.Errorf("PortExclusions = %v, want [20080 20443]", cfg.PortExclusions)
+	}
+}
+
+func TestLoadRoutingEntryPoints(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+
+	content := `routing_output: /tmp/traefik/aurelia.yaml
+routing_entrypoint: http
+routing_entrypoint_tls: https
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.RoutingEntryPoint != "http" || cfg.RoutingEntryPointTLS != "https" {
+		t.Errorf("entrypoints = %q/%q, want http/https", cfg.RoutingEntryPoint, cfg.RoutingEntryPointTLS)
+	}
+}
+
+func TestLoadStartupHealthWait(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+
+	if err := os.WriteFile(path, []byte("startup_health_wait: false\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.StartupHealthWait == nil || *cfg.StartupHealthWait {
+		t.Errorf("StartupHealthWait = %v, want false", cfg.StartupHealthWait)
+	}
+
+	empty := filepath.Join(dir, "empty.yaml")
+	if err := os.WriteFile(empty, []byte("api_addr: 127.0.0.1:9090\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err = Load(empty)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.StartupHealthWait != nil {
+		t.Errorf("StartupHealthWait = %v, want unset", *cfg.StartupHealthWait)
+	}
+}
@@ -0,0 +1,246 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// keyKind is how a settable key's value is parsed and validated.
+type keyKind int
+
+const (
+	kindString keyKind = iota
+	kindAddr           // host:port
+	kindCount          // non-negative integer
+	kindBool
+	kindPorts    // comma-separated port numbers
+	kindLevel    // slog level name
+	kindDuration // non-negative Go duration
+	kindPrefix   // Docker container name prefix
+	kindOutputs  // comma-separated paths, or a single one
+)
+
+// Keys lists the top-level config keys `aurelia config get` and `set` work
+// with, in the order they are listed. Nested settings (nodes, tls, openbao,
+// ...) are edited in the file directly.
+var Keys = []string{
+	"routing_output",
+	"api_addr",
+	"api_read_timeout",
+	"node_name",
+	"lamina_root",
+	"spec_source",
+	"max_parallel_starts",
+	"startup_health_wait",
+	"external_health_wait",
+	"strict_specs",
+	"routing_entrypoint",
+	"routing_entrypoint_tls",
+	"port_exclusions",
+	"log_level",
+	"watch_specs",
+	"watch_debounce",
+	"spec_override",
+	"container_prefix",
+}
+
+var keyKinds = map[string]keyKind{
+	"routing_output":         kindOutputs,
+	"api_addr":               kindAddr,
+	"api_read_timeout":       kindDuration,
+	"node_name":              kindString,
+	"lamina_root":            kindString,
+	"spec_source":            kindString,
+	"max_parallel_starts":    kindCount,
+	"startup_health_wait":    kindBool,
+	"external_health_wait":   kindDuration,
+	"strict_specs":           kindBool,
+	"routing_entrypoint":     kindString,
+	"routing_entrypoint_tls": kindString,
+	"port_exclusions":        kindPorts,
+	"log_level":              kindLevel,
+	"watch_specs":            kindBool,
+	"watch_debounce":         kindDuration,
+	"spec_override":          kindBool,
+	"container_prefix":       kindPrefix,
+}
+
+// ErrUnknownKey is returned by Get and Set for a key not in Keys.
+var ErrUnknownKey = errors.New("unknown config key")
+
+// Get returns the value of key in the config file at path as written, with
+// environment variables unexpanded, and false if it isn't set. Lists are
+// returned comma-separated, the form Set takes.
+func Get(path, key string) (string, bool, error) {
+	if _, ok := keyKinds[key]; !ok {
+		return "", false, fmt.Errorf("%w %q", ErrUnknownKey, key)
+	}
+	doc, err := readDoc(path)
+	if err != nil {
+		return "", false, err
+	}
+	_, v := lookup(doc.Content[0], key)
+	if v == nil {
+		return "", false, nil
+	}
+	if v.Kind == yaml.SequenceNode {
+		items := make([]string, len(v.Content))
+		for i, item := range v.Content {
+			items[i] = item.Value
+		}
+		return strings.Join(items, ","), true, nil
+	}
+	return v.Value, true, nil
+}
+
+// Set validates value for key and writes it to the config file at path,
+// creating the file if needed. Other keys and comments are kept. An empty
+// value removes the key, restoring its default.
+func Set(path, key, value string) error {
+	kind, ok := keyKinds[key]
+	if !ok {
+		return fmt.Errorf("%w %q", ErrUnknownKey, key)
+	}
+	doc, err := readDoc(path)
+	if err != nil {
+		return err
+	}
+	m := doc.Content[0]
+	i, _ := lookup(m, key)
+
+	if value == "" {
+		if i >= 0 {
+			m.Content = append(m.Content[:i], m.Content[i+2:]...)
+		}
+	} else {
+		v, err := valueNode(kind, value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		if i >= 0 {
+			m.Content[i+1] = v
+		} else {
+			m.Content = append(m.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, v)
+		}
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return fmt.Errorf("marshaling config: %w", err)
+	}
+	if err := yaml.Unmarshal(buf.Bytes(), &Config{}); err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("creating config directory: %w", err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0600); err != nil {
+		return fmt.Errorf("writing config: %w", err)
+	}
+	return nil
+}
+
+// readDoc parses the config file at path as a YAML document whose root is a
+// mapping. A missing or empty file gives an empty mapping.
+func readDoc(path string) (*yaml.Node, error) {
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("reading config: %w", err)
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if len(doc.Content) == 0 {
+		doc.Kind = yaml.DocumentNode
+		doc.Content = []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}
+	}
+	if doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("parsing %s: expected a mapping at the top level", path)
+	}
+	return &doc, nil
+}
+
+// lookup returns the index of key's key node in mapping m and its value
+// node, or -1 and nil if m doesn't have key.
+func lookup(m *yaml.Node, key string) (int, *yaml.Node) {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return i, m.Content[i+1]
+		}
+	}
+	return -1, nil
+}
+
+// valueNode parses value as kind into a YAML node.
+func valueNode(kind keyKind, value string) (*yaml.Node, error) {
+	scalar := func(tag, v string) *yaml.Node {
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: v}
+	}
+	switch kind {
+	case kindAddr:
+		if _, port, err := net.SplitHostPort(value); err != nil || port == "" {
+			return nil, fmt.Errorf("expected host:port, got %q", value)
+		}
+	case kindCount:
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("expected a non-negative integer, got %q", value)
+		}
+		return scalar("!!int", strconv.Itoa(n)), nil
+	case kindBool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("expected true or false, got %q", value)
+		}
+		return scalar("!!bool", strconv.FormatBool(b)), nil
+	case kindLevel:
+		if _, err := (&Config{LogLevel: value}).Level(); err != nil {
+			return nil, fmt.Errorf("expected debug, info, warn or error, got %q", value)
+		}
+	case kindPrefix:
+		if err := (&Config{ContainerPrefix: value}).ValidateContainerPrefix(); err != nil {
+			return nil, fmt.Errorf("expected letters, digits, '_', '.' or '-', starting with a letter or digit, got %q", value)
+		}
+	case kindDuration:
+		if d, err := time.ParseDuration(value); err != nil || d < 0 {
+			return nil, fmt.Errorf("expected a duration such as 500ms or 2s, got %q", value)
+		}
+	case kindOutputs:
+		outputs := strings.Split(value, ",")
+		if len(outputs) == 1 {
+			break
+		}
+		seq := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Style: yaml.FlowStyle}
+		for _, o := range outputs {
+			if o = strings.TrimSpace(o); o == "" {
+				return nil, fmt.Errorf("expected comma-separated paths, got %q", value)
+			}
+			seq.Content = append(seq.Content, scalar("!!str", o))
+		}
+		return seq, nil
+	case kindPorts:
+		seq := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Style: yaml.FlowStyle}
+		for _, f := range strings.Split(value, ",") {
+			p, err := strconv.Atoi(strings.TrimSpace(f))
+			if err != nil || p < 1 || p > 65535 {
+				return nil, fmt.Errorf("expected comma-separated ports, got %q", f)
+			}
+			seq.Content = append(seq.Content, scalar("!!int", strconv.Itoa(p)))
+		}
+		return seq, nil
+	}
+	return scalar("!!str", value), nil
+}
@@ -0,0 +1,115 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSetGetRoundTrip(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "aurelia", "config.yaml") // directory doesn't exist yet
+
+	tests := []struct{ key, value, want string }{
+		{"api_addr", "127.0.0.1:9090", "127.0.0.1:9090"},
+		{"routing_output", "$HOME/traefik/dynamic.yaml, stdout", "$HOME/traefik/dynamic.yaml,stdout"},
+		{"max_parallel_starts", "4", "4"},
+		{"strict_specs", "FALSE", "false"},
+		{"port_exclusions", "20100, 20101", "20100,20101"},
+		{"log_level", "debug", "debug"},
+		{"watch_debounce", "1s", "1s"},
+		{"api_read_timeout", "1m", "1m"},
+		{"external_health_wait", "2m", "2m"},
+		{"container_prefix", "studio-", "studio-"},
+	}
+	for _, tt := range tests {
+		if err := Set(path, tt.key, tt.value); err != nil {
+			t.Fatalf("Set(%s, %q): %v", tt.key, tt.value, err)
+		}
+	}
+	for _, tt := range tests {
+		got, ok, err := Get(path, tt.key)
+		if err != nil || !ok || got != tt.want {
+			t.Errorf("Get(%s) = %q, %v, %v; want %q", tt.key, got, ok, err, tt.want)
+		}
+	}
+
+	// The file loads with the values typed
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.MaxParallelStarts != 4 || cfg.WatchDebounce != time.Second || cfg.APIReadTimeout != time.Minute || cfg.ExternalHealthWait != 2*time.Minute || cfg.ContainerPrefix != "studio-" || cfg.StrictSpecs == nil || *cfg.StrictSpecs || !slices.Equal(cfg.PortExclusions, []int{20100, 20101}) {
+		t.Errorf("unexpected loaded config: %+v", cfg)
+	}
+
+	// Overwriting replaces the value, and an empty value removes the key
+	if err := Set(path, "max_parallel_starts", "1"); err != nil {
+		t.Fatal(err)
+	}
+	if got, _, _ := Get(path, "max_parallel_starts"); got != "1" {
+		t.Errorf("after overwrite got %q, want 1", got)
+	}
+	if err := Set(path, "api_addr", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ := Get(path, "api_addr"); ok {
+		t.Error("expected api_addr removed")
+	}
+}
+
+func TestSetKeepsOtherContent(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := "# peers\nnodes:\n  - name: limen\n    addr: limen.local:9090\napi_addr: 127.0.0.1:9090\n"
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Set(path, "node_name", "adyton"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	for _, want := range []string{"# peers", "name: limen", "api_addr: 127.0.0.1:9090", "node_name: adyton"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("expected %q in config, got:\n%s", want, data)
+		}
+	}
+}
+
+func TestSetRejectsInvalid(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+
+	if err := Set(path, "routing_outptu", "/tmp/x"); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("expected ErrUnknownKey for a typo, got %v", err)
+	}
+	if _, _, err := Get(path, "nodes"); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("expected ErrUnknownKey for a nested key, got %v", err)
+	}
+
+	tests := []struct{ key, value string }{
+		{"api_addr", "9090"},
+		{"max_parallel_starts", "-1"},
+		{"startup_health_wait", "sometimes"},
+		{"port_exclusions", "80,http"},
+		{"routing_output", "/tmp/a.yaml,"},
+		{"log_level", "verbose"},
+		{"watch_debounce", "500"},
+		{"watch_debounce", "-1s"},
+		{"container_prefix", "-studio"},
+		{"container_prefix", "studio/"},
+	}
+	for _, tt := range tests {
+		if err := Set(path, tt.key, tt.value); err == nil {
+			t.Errorf("Set(%s, %q): expected error", tt.key, tt.value)
+		}
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("rejected values shouldn't create the file, stat err = %v", err)
+	}
+}
@@ -0,0 +1,65 @@
+package config
+
+import (
+	"context"
+	"log/slog"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDebounce collapses the burst of events an editor's save produces
+// into one reload.
+var watchDebounce = 200 * time.Millisecond
+
+// Watch reloads the config file at path whenever it changes and calls
+// onChange with the previous and new config. A file that no longer loads is
+// logged and skipped, keeping the previous config. The directory is watched
+// rather than the file so editors that save by renaming, and a file created
+// after the daemon started, are both seen. Watch blocks until ctx is done.
+func Watch(ctx context.Context, path string, current *Config, onChange func(old, cur *Config)) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	if err := watcher.Add(filepath.Dir(path)); err != nil {
+		return err
+	}
+	path = filepath.Clean(path)
+
+	var debounce <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if filepath.Clean(event.Name) != path || event.Op&(fsnotify.Create|fsnotify.Write|fsnotify.Rename) == 0 {
+				continue
+			}
+			debounce = time.After(watchDebounce)
+
+		case <-debounce:
+			debounce = nil
+			cfg, err := Load(path)
+			if err != nil {
+				slog.Error("config reload failed, keeping previous config", "path", path, "error", err)
+				continue
+			}
+			onChange(current, cfg)
+			current = cfg
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			slog.Error("config watcher error", "error", err)
+		}
+	}
+}
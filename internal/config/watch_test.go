@@ -0,0 +1,58 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatchReloadsOnChange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("api_addr: 127.0.0.1:9090\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes := make(chan [2]*Config, 4)
+	go Watch(ctx, path, cfg, func(old, cur *Config) { changes <- [2]*Config{old, cur} })
+	time.Sleep(100 * time.Millisecond) // let the watcher register
+
+	// A file that no longer parses is skipped
+	if err := os.WriteFile(path, []byte("api_addr: [\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case c := <-changes:
+		t.Fatalf("unexpected reload of invalid config: %+v", c[1])
+	case <-time.After(2 * watchDebounce):
+	}
+
+	if err := os.WriteFile(path, []byte("api_addr: 127.0.0.1:9191\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case c := <-changes:
+		if c[0].APIAddr != "127.0.0.1:9090" || c[1].APIAddr != "127.0.0.1:9191" {
+			t.Errorf("onChange(%q, %q), want 127.0.0.1:9090 -> 127.0.0.1:9191", c[0].APIAddr, c[1].APIAddr)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("change not seen")
+	}
+
+	// Other files in the directory are ignored
+	if err := os.WriteFile(filepath.Join(filepath.Dir(path), "other.yaml"), []byte("x: 1\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-changes:
+		t.Error("reloaded for an unrelated file")
+	case <-time.After(2 * watchDebounce):
+	}
+}
@@ -0,0 +1,165 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/benaskins/aurelia/internal/spec"
+)
+
+// ApplySpecs replaces the service specs in the spec directory with docs (one
+// YAML spec per element) and reloads, so the running services match the
+// given set: new services start, changed ones restart and services missing
+// from the set stop. Applying the same set again changes nothing.
+//
+// The whole set is validated before the spec directory is touched — each
+// spec, duplicate names and the dependency graph across the set — so an
+// invalid member aborts the apply with no side effects. The specs are staged
+// in a temporary directory and swapped in; if the swap or the reload fails,
+// the previous spec files are put back. The defaults file and archive/ are
+// left alone, as are extra spec directories, whose specs are validated along
+// with the set.
+func (d *Daemon) ApplySpecs(ctx context.Context, docs [][]byte) (*ReloadResult, error) {
+	d.specMu.Lock()
+	defer d.specMu.Unlock()
+
+	// Staging inside the spec dir keeps the swap to renames on one filesystem.
+	// LoadDir only reads files, so the staging directory is never loaded.
+	staged, err := os.MkdirTemp(d.specDir, ".apply-")
+	if err != nil {
+		return nil, fmt.Errorf("creating staging directory: %w", err)
+	}
+	defer os.RemoveAll(staged)
+
+	names, err := d.stageSpecs(staged, docs)
+	if err != nil {
+		return nil, err
+	}
+
+	previous, err := os.MkdirTemp(d.specDir, ".previous-")
+	if err != nil {
+		return nil, fmt.Errorf("creating backup directory: %w", err)
+	}
+	defer os.RemoveAll(previous)
+
+	restore, err := d.swapSpecs(staged, previous, names)
+	if err != nil {
+		return nil, err
+	}
+
+	// Reload fails before changing any service, so restoring the files is
+	// enough to leave the previous state untouched.
+	result, err := d.Reload(ctx)
+	if err != nil {
+		restore()
+		return nil, err
+	}
+	d.logger.Info("applied specs", "count", len(names))
+	return result, nil
+}
+
+// stageSpecs validates docs as a complete set of specs and writes each to
+// <name>.yaml in dir, returning the file names. The spec directory's
+// defaults files are copied alongside so the set loads exactly as it will
+// once swapped in.
+func (d *Daemon) stageSpecs(dir string, docs [][]byte) ([]string, error) {
+	for _, name := range []string{spec.DefaultsFile, "defaults.yml"} {
+		data, err := os.ReadFile(filepath.Join(d.specDir, name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading defaults: %w", err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			return nil, fmt.Errorf("staging defaults: %w", err)
+		}
+	}
+
+	var names []string
+	seen := make(map[string]int) // service name -> index of the doc declaring it
+	for i, doc := range docs {
+		s, err := spec.Parse(doc, d.specDir, d.specOptions()...)
+		if err != nil {
+			return nil, fmt.Errorf("spec %d: %w", i, err)
+		}
+		name := s.Service.Name // validated, so safe to use as a file name
+		if prev, ok := seen[name]; ok {
+			return nil, fmt.Errorf("spec %d: duplicate service name %q (also spec %d)", i, name, prev)
+		}
+		seen[name] = i
+
+		file := name + ".yaml"
+		if err := os.WriteFile(filepath.Join(dir, file), doc, 0644); err != nil {
+			return nil, fmt.Errorf("staging spec %q: %w", name, err)
+		}
+		names = append(names, file)
+	}
+
+	specs, err := spec.LoadDirs(append([]string{dir}, d.extraSpecDirs...), d.specOptions()...)
+	if err != nil {
+		return nil, err
+	}
+	g := newDepGraph(specs)
+	if err := g.checkRequires(); err != nil {
+		return nil, fmt.Errorf("dependency resolution: %w", err)
+	}
+	if err := g.checkConditions(); err != nil {
+		return nil, fmt.Errorf("dependency resolution: %w", err)
+	}
+	if _, err := g.startOrder(); err != nil {
+		return nil, fmt.Errorf("dependency resolution: %w", err)
+	}
+	return names, nil
+}
+
+// swapSpecs moves the spec files currently in the spec directory to previous
+// and the staged files named by names into their place. On failure it puts
+// everything back; on success it returns a func that does the same.
+func (d *Daemon) swapSpecs(staged, previous string, names []string) (restore func(), err error) {
+	current, err := filepath.Glob(filepath.Join(d.specDir, "*.yaml"))
+	if err != nil {
+		return nil, fmt.Errorf("listing specs: %w", err)
+	}
+	yml, err := filepath.Glob(filepath.Join(d.specDir, "*.yml"))
+	if err != nil {
+		return nil, fmt.Errorf("listing specs: %w", err)
+	}
+	current = append(current, yml...)
+
+	var movedOut, movedIn []string
+	restore = func() {
+		for _, file := range movedIn {
+			if err := os.Remove(filepath.Join(d.specDir, file)); err != nil {
+				d.logger.Error("apply: failed to remove spec during restore", "file", file, "error", err)
+			}
+		}
+		for _, file := range movedOut {
+			if err := os.Rename(filepath.Join(previous, file), filepath.Join(d.specDir, file)); err != nil {
+				d.logger.Error("apply: failed to restore spec", "file", file, "error", err)
+			}
+		}
+	}
+
+	for _, path := range current {
+		if spec.IsDefaultsFile(path) {
+			continue
+		}
+		file := filepath.Base(path)
+		if err := os.Rename(path, filepath.Join(previous, file)); err != nil {
+			restore()
+			return nil, fmt.Errorf("moving aside spec %s: %w", file, err)
+		}
+		movedOut = append(movedOut, file)
+	}
+	for _, file := range names {
+		if err := os.Rename(filepath.Join(staged, file), filepath.Join(d.specDir, file)); err != nil {
+			restore()
+			return nil, fmt.Errorf("installing spec %s: %w", file, err)
+		}
+		movedIn = append(movedIn, file)
+	}
+	return restore, nil
+}
@@ -0,0 +1,221 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/benaskins/aurelia/internal/driver"
+	"github.com/benaskins/aurelia/internal/spec"
+	"github.com/benaskins/aurelia/internal/sysinfo"
+)
+
+const (
+	defaultAutoscaleInterval = 15 * time.Second
+
+	// autoscaleTolerance is how far a service's use may stray from its
+	// target, as a fraction of it, before the replica count changes.
+	autoscaleTolerance = 0.1
+)
+
+// StatsSource samples a process's CPU and memory use.
+// *sysinfo.ProcessSampler implements it.
+type StatsSource interface {
+	Sample(pid int) (sysinfo.ProcessUsage, error)
+}
+
+// WithStatsSource replaces the process sampler autoscaling reads CPU and
+// memory use from.
+func WithStatsSource(src StatsSource) Option {
+	return func(d *Daemon) {
+		d.stats = src
+	}
+}
+
+// WithAutoscaleInterval sets how often services with autoscale are
+// evaluated. Values <= 0 use the default (15s).
+func WithAutoscaleInterval(interval time.Duration) Option {
+	return func(d *Daemon) {
+		d.autoscaleInterval = interval
+	}
+}
+
+// startAutoscaler evaluates the services with autoscale every interval
+// until ctx is done.
+func (d *Daemon) startAutoscaler(ctx context.Context) {
+	interval := d.autoscaleInterval
+	if interval <= 0 {
+		interval = defaultAutoscaleInterval
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case now := <-ticker.C:
+				d.autoscale(now)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// autoscale adjusts the replica count of every running service with
+// autoscale to its current use.
+func (d *Daemon) autoscale(now time.Time) {
+	d.mu.RLock()
+	var targets []*ManagedService
+	for _, ms := range d.services {
+		if ms.spec.Autoscale != nil && ms.spec.IsEnabled() {
+			targets = append(targets, ms)
+		}
+	}
+	d.mu.RUnlock()
+
+	for _, ms := range targets {
+		d.autoscaleService(ms, now)
+	}
+}
+
+// autoscaleService sizes ms's replicas so their average CPU and memory use
+// comes back towards the targets: replicas × use / target, within
+// autoscale.min and max. Nothing changes within the cooldown of the last
+// change, while any replica is down, or while another operation holds the
+// service; the first look at a service starts its cooldown, so replicas
+// settle after starting before they are judged.
+func (d *Daemon) autoscaleService(ms *ManagedService, now time.Time) {
+	a := ms.spec.Autoscale
+	name := ms.spec.Service.Name
+	if d.InMaintenance() || !ms.supervised() {
+		return
+	}
+	unlock, err := d.LockService(name)
+	if err != nil {
+		return
+	}
+	defer unlock()
+
+	ms.mu.Lock()
+	scaledAt := ms.scaledAt
+	if scaledAt.IsZero() {
+		ms.scaledAt = now
+	}
+	ms.mu.Unlock()
+	if scaledAt.IsZero() || now.Sub(scaledAt) < a.CooldownOrDefault() {
+		return
+	}
+
+	instances := append([]*ManagedService{ms}, ms.replicaList()...)
+	var cpu float64
+	var mem int64
+	for _, inst := range instances {
+		st := inst.instanceState()
+		if st.State != driver.StateRunning {
+			return
+		}
+		if st.PID <= 0 {
+			d.logger.Info("autoscale: no process to sample", "service", name, "instance", st.Name)
+			return
+		}
+		usage, err := d.stats.Sample(st.PID)
+		if err != nil {
+			d.logger.Info("autoscale: sampling failed", "service", name, "instance", st.Name, "error", err)
+			return
+		}
+		cpu += usage.CPUPercent
+		mem += usage.MemoryBytes
+	}
+
+	current := len(instances)
+	cpu /= float64(current)
+	mem /= int64(current)
+	want := desiredReplicas(a, current, cpu, mem)
+	if want == current {
+		return
+	}
+
+	d.logger.Info("autoscaling", "service", name, "from", current, "to", want, "cpu_percent", cpu, "memory_bytes", mem)
+	for n := current; n < want; n++ {
+		if err := d.addReplica(ms); err != nil {
+			d.logger.Error("autoscale: adding replica failed", "service", name, "error", err)
+			break
+		}
+	}
+	for n := current; n > want; n-- {
+		if err := d.removeReplica(ms); err != nil {
+			d.logger.Warn("autoscale: stopping replica failed", "service", name, "error", err)
+		}
+	}
+	scaled := len(ms.replicaList()) + 1
+	if scaled == current {
+		return
+	}
+	d.recordEvent(name, EventScaled, fmt.Sprintf("%d -> %d replicas (cpu %.0f%%, memory %s per replica)", current, scaled, cpu, spec.ByteSize(mem)))
+
+	ms.mu.Lock()
+	ms.scaledAt = now
+	ms.mu.Unlock()
+}
+
+// desiredReplicas returns how many replicas bring the average use per
+// replica, cpu percent and mem bytes, to a's targets, going by the busier
+// of the two, within a's bounds. Use within autoscaleTolerance of the
+// target keeps the current count.
+func desiredReplicas(a *spec.Autoscale, current int, cpu float64, mem int64) int {
+	var ratio float64
+	if a.TargetCPUPercent > 0 {
+		ratio = cpu / a.TargetCPUPercent
+	}
+	if a.TargetMemory > 0 {
+		ratio = max(ratio, float64(mem)/float64(a.TargetMemory))
+	}
+	want := current
+	if math.Abs(ratio-1) > autoscaleTolerance {
+		want = int(math.Ceil(float64(current) * ratio))
+	}
+	return min(max(want, a.Min), a.Max)
+}
+
+// addReplica starts one more replica of the service whose first instance
+// is ms, on a newly allocated port. The replica joins ms before it starts,
+// so the routing its start triggers includes it.
+func (d *Daemon) addReplica(ms *ManagedService) error {
+	r, err := d.newReplica(ms, len(ms.replicaList())+1)
+	if err != nil {
+		return err
+	}
+	ms.mu.Lock()
+	ms.replicas = append(ms.replicas, r)
+	ms.mu.Unlock()
+
+	if err := r.startInstance(d.ctx); err != nil {
+		ms.mu.Lock()
+		ms.replicas = slices.DeleteFunc(ms.replicas, func(x *ManagedService) bool { return x == r })
+		ms.mu.Unlock()
+		d.ports.Release(r.instanceName())
+		return err
+	}
+	d.regenerateRouting()
+	return nil
+}
+
+// removeReplica stops the last replica of the service whose first instance
+// is ms, taking it out of routing first so no new requests reach it.
+func (d *Daemon) removeReplica(ms *ManagedService) error {
+	ms.mu.Lock()
+	if len(ms.replicas) == 0 {
+		ms.mu.Unlock()
+		return nil
+	}
+	r := ms.replicas[len(ms.replicas)-1]
+	ms.replicas = slices.Clip(ms.replicas[:len(ms.replicas)-1])
+	ms.mu.Unlock()
+
+	d.regenerateRouting()
+	err := r.stopInstance(DefaultStopTimeout, true, ReasonManualStop)
+	d.ports.Release(r.instanceName())
+	return err
+}
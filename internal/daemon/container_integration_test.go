@@ -3,9 +3,17 @@
 package daemon
 
 import (
+	"archive/tar"
+	"bytes"
 	"context"
+	"io"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/docker/docker/api/types/build"
+	dockerclient "github.com/docker/docker/client"
 )
 
 func TestDaemonContainerService(t *testing.T) {
@@ -96,3 +104,213 @@ restart:
 
 	d.Stop(10 * time.Second)
 }
+
+// buildVersionedImage builds a throwaway alpine image tagged tag whose ID
+// differs per version.
+func buildVersionedImage(t *testing.T, cli *dockerclient.Client, tag, version string) {
+	t.Helper()
+	dockerfile := "FROM alpine:latest\n" +
+		"LABEL aurelia.test.version=" + version + "\n" +
+		"CMD [\"sleep\", \"60\"]\n"
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	tw.WriteHeader(&tar.Header{Name: "Dockerfile", Mode: 0644, Size: int64(len(dockerfile))})
+	tw.Write([]byte(dockerfile))
+	tw.Close()
+
+	resp, err := cli.ImageBuild(context.Background(), &buf, build.ImageBuildOptions{Tags: []string{tag}, Remove: true})
+	if err != nil {
+		t.Fatalf("building image: %v", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+}
+
+func TestDaemonContainerRollback(t *testing.T) {
+	cli, err := dockerclient.NewClientWithOpts(dockerclient.FromEnv, dockerclient.WithAPIVersionNegotiation())
+	if err != nil {
+		t.Fatalf("docker client: %v", err)
+	}
+	defer cli.Close()
+
+	const current = "aurelia-test-rollback:current"
+	buildVersionedImage(t, cli, "aurelia-test-rollback:v1", "1")
+	buildVersionedImage(t, cli, "aurelia-test-rollback:v2", "2")
+	if err := cli.ImageTag(context.Background(), "aurelia-test-rollback:v1", current); err != nil {
+		t.Fatalf("tagging v1: %v", err)
+	}
+
+	dir := t.TempDir()
+	writeSpec(t, dir, "app.yaml", `
+service:
+  name: test-rollback
+  type: container
+  image: `+current+`
+  network_mode: bridge
+network:
+  port: 0
+routing:
+  hostname: rollback.test.internal
+`)
+
+	d := NewDaemon(dir, WithStateDir(t.TempDir()), WithRouting(filepath.Join(t.TempDir(), "routing.yaml")), WithPortRange(27800, 27900))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := d.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer d.Stop(10 * time.Second)
+
+	waitUntil(t, func() bool {
+		s, _ := d.ServiceState("test-rollback")
+		return s.State == "running"
+	}, 30*time.Second, "container to become running")
+
+	record := func() ServiceRecord {
+		t.Helper()
+		records, err := d.state.load()
+		if err != nil {
+			t.Fatalf("load state: %v", err)
+		}
+		return records["test-rollback"]
+	}
+	v1 := record().Image
+	if v1 == "" {
+		t.Fatal("expected the running image ID to be recorded")
+	}
+
+	// Point the tag at a new build and deploy it
+	if err := cli.ImageTag(context.Background(), "aurelia-test-rollback:v2", current); err != nil {
+		t.Fatalf("tagging v2: %v", err)
+	}
+	if err := d.DeployService(context.Background(), "test-rollback", 100*time.Millisecond); err != nil {
+		t.Fatalf("DeployService: %v", err)
+	}
+	rec := record()
+	v2 := rec.Image
+	if v2 == "" || v2 == v1 {
+		t.Fatalf("expected deploy to run a new image, got %q (was %q)", v2, v1)
+	}
+	if rec.Previous == nil || rec.Previous.Image != v1 {
+		t.Fatalf("expected previous image %q, got %+v", v1, rec.Previous)
+	}
+
+	result, err := d.RollbackService("test-rollback", 100*time.Millisecond)
+	if err != nil {
+		t.Fatalf("RollbackService: %v", err)
+	}
+	if result.Status != "rolled_back" || result.Image != v1 {
+		t.Errorf("unexpected result: %+v", result)
+	}
+
+	rec = record()
+	if rec.Image != v1 {
+		t.Errorf("expected rolled-back image %q, got %q", v1, rec.Image)
+	}
+	if rec.Previous == nil || rec.Previous.Image != v2 {
+		t.Errorf("expected previous image %q after rollback, got %+v", v2, rec.Previous)
+	}
+	if s, _ := d.ServiceState("test-rollback"); s.State != "running" {
+		t.Errorf("expected running after rollback, got %v", s.State)
+	}
+}
+
+func TestDaemonContainerImageDrift(t *testing.T) {
+	cli, err := dockerclient.NewClientWithOpts(dockerclient.FromEnv, dockerclient.WithAPIVersionNegotiation())
+	if err != nil {
+		t.Fatalf("docker client: %v", err)
+	}
+	defer cli.Close()
+
+	const current = "aurelia-test-drift:current"
+	buildVersionedImage(t, cli, "aurelia-test-drift:v1", "1")
+	buildVersionedImage(t, cli, "aurelia-test-drift:v2", "2")
+	if err := cli.ImageTag(context.Background(), "aurelia-test-drift:v1", current); err != nil {
+		t.Fatalf("tagging v1: %v", err)
+	}
+
+	dir := t.TempDir()
+	writeSpec(t, dir, "app.yaml", `
+service:
+  name: test-drift
+  type: container
+  image: `+current+`
+  network_mode: bridge
+`)
+
+	d := NewDaemon(dir, WithStateDir(t.TempDir()))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := d.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer d.Stop(10 * time.Second)
+
+	waitUntil(t, func() bool {
+		s, _ := d.ServiceState("test-drift")
+		return s.State == "running"
+	}, 30*time.Second, "container to become running")
+
+	// Nothing has moved yet
+	result, err := d.Reload(ctx)
+	if err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if len(result.ImageDrift) != 0 {
+		t.Errorf("expected no drift, got %v", result.ImageDrift)
+	}
+
+	// Point the tag at a new build: reload flags it but leaves it running
+	if err := cli.ImageTag(context.Background(), "aurelia-test-drift:v2", current); err != nil {
+		t.Fatalf("tagging v2: %v", err)
+	}
+	result, err = d.Reload(ctx)
+	if err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if len(result.ImageDrift) != 1 || result.ImageDrift[0] != "test-drift" || len(result.Restarted) != 0 {
+		t.Errorf("expected drift flagged for test-drift without a restart, got %+v", result)
+	}
+	if st, _ := d.ServiceState("test-drift"); !st.ImageDrift {
+		t.Error("expected image_drift in the service state")
+	}
+}
+
+func TestDaemonContainerRecordsImageDigest(t *testing.T) {
+	dir := t.TempDir()
+	writeSpec(t, dir, "app.yaml", `
+service:
+  name: test-digest
+  type: container
+  image: alpine:latest
+  command: sleep 60
+  network_mode: bridge
+`)
+
+	d := NewDaemon(dir, WithStateDir(t.TempDir()))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := d.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer d.Stop(10 * time.Second)
+
+	var st ServiceState
+	waitUntil(t, func() bool {
+		st, _ = d.ServiceState("test-digest")
+		return st.State == "running"
+	}, 30*time.Second, "container to become running")
+
+	if !strings.Contains(st.ImageDigest, "alpine@sha256:") {
+		t.Errorf("expected the running digest in the service state, got %q", st.ImageDigest)
+	}
+	records, err := d.state.load()
+	if err != nil {
+		t.Fatalf("load state: %v", err)
+	}
+	if got := records["test-digest"].ImageDigest; got != st.ImageDigest {
+		t.Errorf("expected digest %q in the state file, got %q", st.ImageDigest, got)
+	}
+}
@@ -1,54 +1,102 @@
 package daemon
 
 import (
+	"cmp"
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/benaskins/aurelia/internal/audit"
 	"github.com/benaskins/aurelia/internal/driver"
+	"github.com/benaskins/aurelia/internal/gpu"
 	"github.com/benaskins/aurelia/internal/health"
 	"github.com/benaskins/aurelia/internal/keychain"
+	"github.com/benaskins/aurelia/internal/logbuf"
 	"github.com/benaskins/aurelia/internal/node"
 	"github.com/benaskins/aurelia/internal/port"
 	"github.com/benaskins/aurelia/internal/routing"
 	"github.com/benaskins/aurelia/internal/spec"
+	"github.com/benaskins/aurelia/internal/sysinfo"
 )
 
 const (
 	// DefaultStopTimeout is the default graceful shutdown timeout for services.
 	DefaultStopTimeout = 30 * time.Second
 
-	// defaultPortMin is the lower bound of the dynamic port allocation range.
-	defaultPortMin = 20000
+	// DefaultReadyTimeout is how long WaitForReady waits when the caller
+	// asks to wait without giving a timeout.
+	DefaultReadyTimeout = 60 * time.Second
 
-	// defaultPortMax is the upper bound of the dynamic port allocation range.
-	defaultPortMax = 32000
+	// DefaultPortMin is the lower bound of the dynamic port allocation range
+	// unless WithPortRange sets another.
+	DefaultPortMin = 20000
+
+	// DefaultPortMax is the upper bound of the dynamic port allocation range.
+	DefaultPortMax = 32000
+
+	// stickyPortWindow is how long a released dynamic port stays reserved
+	// for the same service, so reload-driven restarts keep their port.
+	stickyPortWindow = 30 * time.Second
+
+	// defaultMaxParallelStarts bounds how many services in the same dependency
+	// level are started concurrently during daemon startup.
+	defaultMaxParallelStarts = 4
 )
 
 // Daemon is the top-level process supervisor.
 type Daemon struct {
 	specDir            string
+	extraSpecDirs      []string // read-only spec directories loaded after specDir
+	specOverride       bool     // later spec directories replace same-named services
 	stateDir           string
 	specSource         string // optional: source spec directory for drift detection
 	secrets            keychain.Store
+	auditLog           *audit.Logger // lifecycle audit log (nil = not audited)
 	routing            *routing.TraefikGenerator
+	routingMu          sync.Mutex // orders routing generations so a stale one can't land last
+	entryPoints        [2]string  // Traefik entrypoints (plain, TLS) overriding the defaults
 	ports              *port.Allocator
+	portMin, portMax   int   // dynamic port range, applied when ports is built
+	portExclusions     []int // ports never handed out by the allocator
 	services           map[string]*ManagedService
 	deps               *depGraph
 	state              *stateFile
 	mu                 sync.RWMutex
+	specMu             sync.Mutex      // serializes writes to the spec directory
+	busy               map[string]bool // services with a lifecycle operation in flight; see LockService
+	busyMu             sync.Mutex
 	logger             *slog.Logger
 	ctx                context.Context         // daemon lifecycle context, set in Start()
 	adopted            []string                // services adopted during crash recovery, pending redeploy
+	recoveredRestarts  map[string]int          // restart counts from the state file, taken as services are recreated after a crash
 	redeployWait       time.Duration           // delay before redeploying adopted services (default 10s)
 	peers              map[string]*node.Client // remote daemon peers
 	peerStatus         map[string]bool         // peer name -> reachable
 	certRenewal        *CertRenewal            // automatic node cert renewal (nil = disabled)
 	serviceCertRenewal *ServiceCertRenewal     // automatic service cert renewal (nil = disabled)
+	maxParallelStarts  int                     // concurrent starts per dependency level (0 = default)
+	noHealthWait       bool                    // start dependents without waiting for dependency health
+	externalWait       time.Duration           // how long startup waits for an external dependency's health (0 = DefaultReadyTimeout)
+	lenientSpecs       bool                    // ignore unknown keys in spec files
+	defaultRestart     *spec.RestartPolicy     // restart policy for specs without a restart block
+	containerPrefix    string                  // container name prefix, empty for the driver default
+	globalEnv          map[string]string       // env given to every service beneath its own
+	gpu                GPUObserver             // VRAM source for gpu.requires_vram (nil = no gating)
+	stats              StatsSource             // process CPU and memory for autoscale
+	autoscaleInterval  time.Duration           // how often autoscaled services are evaluated (0 = default)
+	noWatch            bool                    // don't reload on spec file changes
+	watchDebounce      time.Duration           // quiet period before a watcher reload (0 = default)
+	maintenance        bool                    // services released; see EnterMaintenance
+	startedAt          time.Time               // set in Start()
+	deployEvents       deployEventHub          // deploy progress for WatchDeploy
+	events             eventLog                // recent lifecycle events for Events and WatchEvents
 }
 
 // NewDaemon creates a new daemon that manages services from the given spec directory.
@@ -57,15 +105,25 @@ func NewDaemon(specDir string, opts ...Option) *Daemon {
 	d := &Daemon{
 		specDir:    specDir,
 		stateDir:   specDir, // default: same as spec dir
-		ports:      port.NewAllocator(defaultPortMin, defaultPortMax),
+		portMin:    DefaultPortMin,
+		portMax:    DefaultPortMax,
 		services:   make(map[string]*ManagedService),
+		busy:       make(map[string]bool),
 		peers:      make(map[string]*node.Client),
 		peerStatus: make(map[string]bool),
 		logger:     slog.With("component", "daemon"),
+
+		defaultRestart: DefaultRestartPolicy(),
+		stats:          sysinfo.NewProcessSampler(),
 	}
 	for _, opt := range opts {
 		opt(d)
 	}
+	d.ports = port.NewAllocator(d.portMin, d.portMax, d.portExclusions...)
+	if d.routing != nil {
+		d.routing.SetEntryPoints(d.entryPoints[0], d.entryPoints[1])
+	}
+	d.ports.SetStickyWindow(stickyPortWindow)
 	d.state = newStateFile(d.stateDir)
 	return d
 }
@@ -80,6 +138,14 @@ func WithSecrets(s keychain.Store) Option {
 	}
 }
 
+// WithAuditLog records starts, stops, restarts, deploys and reloads, with the
+// actor that requested them, to l.
+func WithAuditLog(l *audit.Logger) Option {
+	return func(d *Daemon) {
+		d.auditLog = l
+	}
+}
+
 // WithStateDir sets the directory for the daemon state file.
 func WithStateDir(dir string) Option {
 	return func(d *Daemon) {
@@ -90,14 +156,32 @@ func WithStateDir(dir string) Option {
 // WithPortRange sets the dynamic port allocation range.
 func WithPortRange(min, max int) Option {
 	return func(d *Daemon) {
-		d.ports = port.NewAllocator(min, max)
+		d.portMin, d.portMax = min, max
+	}
+}
+
+// WithPortExclusions keeps the given ports out of dynamic allocation, even
+// when they are free. Applies to the default range or one set by WithPortRange.
+func WithPortExclusions(ports []int) Option {
+	return func(d *Daemon) {
+		d.portExclusions = ports
+	}
+}
+
+// WithRouting enables Traefik config generation, written to each of the
+// given outputs: file paths, or "stdout" to echo it to standard output.
+func WithRouting(outputs ...string) Option {
+	return func(d *Daemon) {
+		d.routing = routing.NewTraefikGenerator(outputs...)
 	}
 }
 
-// WithRouting enables Traefik config generation at the given output path.
-func WithRouting(outputPath string) Option {
+// WithRoutingEntryPoints sets the Traefik entrypoints used for plain and TLS
+// routes that don't name their own. Empty values keep the defaults ("web" and
+// "websecure"). Only takes effect together with WithRouting.
+func WithRoutingEntryPoints(plain, tls string) Option {
 	return func(d *Daemon) {
-		d.routing = routing.NewTraefikGenerator(outputPath)
+		d.entryPoints = [2]string{plain, tls}
 	}
 }
 
@@ -110,16 +194,147 @@ func WithSpecSource(dir string) Option {
 	}
 }
 
+// WithMaxParallelStarts bounds how many independent services are started
+// concurrently during daemon startup. Values <= 0 use the default (4);
+// 1 starts services strictly sequentially.
+func WithMaxParallelStarts(n int) Option {
+	return func(d *Daemon) {
+		d.maxParallelStarts = n
+	}
+}
+
+// WithStartupHealthWait controls whether daemon startup blocks on the health
+// of services that others wait for (the default). Disabling it starts every
+// level as soon as the previous one has started, so a slow or down dependency
+// can't hold up the rest — at the cost of the ordering guarantee.
+func WithStartupHealthWait(enabled bool) Option {
+	return func(d *Daemon) {
+		d.noHealthWait = !enabled
+	}
+}
+
+// WithExternalHealthWait sets how long daemon startup waits for an external
+// service that others depend on to report healthy before starting them
+// anyway. Default: DefaultReadyTimeout.
+func WithExternalHealthWait(timeout time.Duration) Option {
+	return func(d *Daemon) {
+		d.externalWait = timeout
+	}
+}
+
+// WithStrictSpecs controls whether spec files with keys that match no spec
+// field are rejected (the default). Disabling it ignores such keys, so specs
+// written for a newer aurelia still load.
+func WithStrictSpecs(strict bool) Option {
+	return func(d *Daemon) {
+		d.lenientSpecs = !strict
+	}
+}
+
+// DefaultRestartPolicy returns the restart policy for services whose spec has
+// no restart block unless WithDefaultRestart says otherwise: restart a
+// process that exits non-zero, up to three times.
+func DefaultRestartPolicy() *spec.RestartPolicy {
+	return &spec.RestartPolicy{Policy: "on-failure", MaxAttempts: 3}
+}
+
+// WithDefaultRestart gives services whose spec has no restart block, even
+// after the spec directory's defaults file is merged in, this restart policy
+// instead of DefaultRestartPolicy. A nil policy leaves them without one, so
+// they are never restarted.
+func WithDefaultRestart(policy *spec.RestartPolicy) Option {
+	return func(d *Daemon) {
+		d.defaultRestart = policy
+	}
+}
+
+// WithContainerPrefix names container services' Docker containers prefix
+// followed by the service name, instead of "aurelia-" followed by it, so
+// daemons sharing a Docker host don't take each other's containers.
+func WithContainerPrefix(prefix string) Option {
+	return func(d *Daemon) {
+		d.containerPrefix = prefix
+	}
+}
+
+// WithGlobalEnv gives every service env's variables, at the lowest
+// precedence: a service's own env and secrets, and the PORT, HOST and
+// BIND_ADDRESS aurelia injects, override a global of the same name.
+func WithGlobalEnv(env map[string]string) Option {
+	return func(d *Daemon) {
+		d.globalEnv = env
+	}
+}
+
+// GPUObserver reports the GPU's current state. *gpu.Observer implements it.
+type GPUObserver interface {
+	Info() gpu.Info
+}
+
+// WithGPU lets the daemon hold back services with gpu.requires_vram until
+// the observer reports enough free VRAM. Without it they start straight away.
+func WithGPU(obs GPUObserver) Option {
+	return func(d *Daemon) {
+		d.gpu = obs
+	}
+}
+
+// WithExtraSpecDirs adds directories to load specs from after the spec
+// directory, in order. They are only read: specs created, removed or
+// applied through the API go to the spec directory. A service declared in
+// two directories is an error unless WithSpecOverride is set.
+func WithExtraSpecDirs(dirs ...string) Option {
+	return func(d *Daemon) {
+		d.extraSpecDirs = append(d.extraSpecDirs, dirs...)
+	}
+}
+
+// WithSpecOverride lets a spec in a later spec directory replace a service
+// of the same name from an earlier one, instead of failing the load.
+func WithSpecOverride(enabled bool) Option {
+	return func(d *Daemon) {
+		d.specOverride = enabled
+	}
+}
+
+// specDirs returns every directory specs are loaded from, in load order.
+func (d *Daemon) specDirs() []string {
+	return append([]string{d.specDir}, d.extraSpecDirs...)
+}
+
+// loadSpecs loads every spec in the spec directories.
+func (d *Daemon) loadSpecs() ([]*spec.ServiceSpec, error) {
+	return spec.LoadDirs(d.specDirs(), d.specOptions()...)
+}
+
+// specOptions returns the options specs are loaded with.
+func (d *Daemon) specOptions() []spec.LoadOption {
+	var opts []spec.LoadOption
+	if d.lenientSpecs {
+		opts = append(opts, spec.AllowUnknownFields())
+	}
+	if d.specOverride {
+		opts = append(opts, spec.OverrideDuplicates())
+	}
+	if d.defaultRestart != nil {
+		opts = append(opts, spec.DefaultRestart(d.defaultRestart))
+	}
+	return opts
+}
+
 // Start loads all specs and starts all services in dependency order.
+// Services in the same dependency level (no after/requires edge between them)
+// are started concurrently, bounded by the max-parallel-starts limit.
 func (d *Daemon) Start(ctx context.Context) error {
 	d.ctx = ctx
+	d.startedAt = time.Now()
 
-	specs, err := spec.LoadDir(d.specDir)
+	specs, err := d.loadSpecs()
 	if err != nil {
 		return fmt.Errorf("loading specs: %w", err)
 	}
 
-	d.logger.Info("loaded service specs", "count", len(specs), "dir", d.specDir)
+	d.logger.Info("loaded service specs", "count", len(specs), "dirs", d.specDirs())
 
 	// Check for stale specs if a source directory is configured
 	if d.specSource != "" {
@@ -142,16 +357,22 @@ func (d *Daemon) Start(ctx context.Context) error {
 	}
 
 	g := newDepGraph(specs)
+	if err := g.checkConditions(); err != nil {
+		return fmt.Errorf("dependency resolution: %w", err)
+	}
 	d.mu.Lock()
 	d.deps = g
 	d.mu.Unlock()
 
-	order, err := g.startOrder()
+	levels, err := g.startLevels()
 	if err != nil {
 		return fmt.Errorf("dependency resolution: %w", err)
 	}
 
-	d.logger.Info("start order resolved", "order", order)
+	d.logger.Info("start order resolved", "levels", levels)
+	if d.noHealthWait {
+		d.logger.Warn("startup health waits disabled, dependencies may not be healthy when dependents start")
+	}
 
 	// Load previous state for crash recovery
 	prevState, err := d.state.load()
@@ -159,7 +380,15 @@ func (d *Daemon) Start(ctx context.Context) error {
 		d.logger.Warn("failed to load previous state", "error", err)
 	}
 
-	// Restore port allocations from previous state
+	// Restore port allocations and restart counts from previous state
+	d.mu.Lock()
+	d.recoveredRestarts = make(map[string]int)
+	for name, rec := range prevState {
+		if rec.RestartCount > 0 {
+			d.recoveredRestarts[name] = rec.RestartCount
+		}
+	}
+	d.mu.Unlock()
 	for name, rec := range prevState {
 		if rec.Port > 0 {
 			if err := d.ports.Reserve(name, rec.Port); err != nil {
@@ -168,136 +397,205 @@ func (d *Daemon) Start(ctx context.Context) error {
 		}
 	}
 
-	for _, name := range order {
-		s := g.specs[name]
-
-		// Try to adopt a previously-running process
-		if rec, ok := prevState[name]; ok && rec.Type == "native" && rec.PID > 0 {
-			// Verify the PID still belongs to the expected process (guard against PID reuse).
-			// Try the spec command first, then the observed process name (handles
-			// exec-replaced scripts where the binary name differs from the command).
-			verified := driver.VerifyProcess(rec.PID, rec.Command, rec.StartTime)
-			if !verified && rec.ProcessName != "" {
-				verified = driver.VerifyProcess(rec.PID, rec.ProcessName, rec.StartTime)
+	for _, level := range levels {
+		d.startLevel(ctx, g, level, prevState)
+	}
+
+	// Generate initial routing config
+	d.regenerateRouting()
+
+	// Start peer liveness checking
+	d.startPeerLiveness(ctx)
+
+	// Adjust the replica counts of services with autoscale
+	d.startAutoscaler(ctx)
+
+	// Redeploy adopted services in the background to restore log capture
+	go d.redeployAdopted()
+
+	// Start file watcher for auto-reload
+	if d.noWatch {
+		d.logger.Info("spec file watcher disabled")
+	} else {
+		go func() {
+			if err := d.StartWatcher(ctx); err != nil {
+				d.logger.Error("spec file watcher failed", "error", err)
 			}
-			if !verified {
-				// AURELIA_SERVICE env tag survives exec and reparenting — definitive proof.
-				if tag := driver.AureliaServiceTag(rec.PID); tag == name {
-					verified = true
-				}
+		}()
+	}
+
+	return nil
+}
+
+// startLevel starts every service in one dependency level concurrently,
+// bounded by maxParallelStarts, and returns once all of them have started
+// (including any health waits for services with required dependents).
+func (d *Daemon) startLevel(ctx context.Context, g *depGraph, names []string, prevState map[string]ServiceRecord) {
+	limit := d.maxParallelStarts
+	if limit <= 0 {
+		limit = defaultMaxParallelStarts
+	}
+
+	sem := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	for _, name := range names {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(name string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			d.startOne(ctx, g, name, prevState)
+		}(name)
+	}
+	wg.Wait()
+}
+
+// startOne adopts a previously-running process for the named service if one
+// can be verified, otherwise starts it fresh. If other services require this
+// one, it blocks until the service is healthy, unless startup health waits
+// are disabled.
+func (d *Daemon) startOne(ctx context.Context, g *depGraph, name string, prevState map[string]ServiceRecord) {
+	s := g.specs[name]
+
+	if d.staysStopped(s) {
+		if err := d.addStoppedService(s); err != nil {
+			d.logger.Error("failed to add service", "service", name, "error", err)
+		}
+		return
+	}
+
+	// Try to adopt a previously-running process
+	if rec, ok := prevState[name]; ok && rec.Type == "native" && rec.PID > 0 {
+		// Verify the PID still belongs to the expected process (guard against PID reuse).
+		// Try the spec command first, then the observed process name (handles
+		// exec-replaced scripts where the binary name differs from the command).
+		verified := driver.VerifyProcess(rec.PID, rec.Command, rec.StartTime)
+		if !verified && rec.ProcessName != "" {
+			verified = driver.VerifyProcess(rec.PID, rec.ProcessName, rec.StartTime)
+		}
+		if !verified {
+			// AURELIA_SERVICE env tag survives exec and reparenting — definitive proof.
+			if tag := driver.AureliaServiceTag(rec.PID); tag == name {
+				verified = true
 			}
-			if !verified {
-				d.logger.Warn("PID reuse detected, searching for orphaned process",
-					"service", name, "pid", rec.PID,
-					"expected_command", rec.Command, "process_name", rec.ProcessName)
-
-				// Search for the actual orphaned process by command pattern and
-				// observed process name (handles exec-replaced scripts).
-				if orphanPID := driver.FindProcessByCommand(rec.Command, rec.PID, rec.ProcessName); orphanPID > 0 {
-					d.logger.Info("found orphaned process by command match",
-						"service", name, "orphan_pid", orphanPID, "command", rec.Command)
-					adopted, err := driver.NewAdopted(orphanPID)
-					if err == nil {
-						if err := d.adoptService(ctx, s, adopted); err != nil {
-							d.logger.Error("failed to adopt orphaned process", "service", name, "error", err)
-						} else {
-							d.adopted = append(d.adopted, name)
-							continue
-						}
+		}
+		if !verified {
+			d.logger.Warn("PID reuse detected, searching for orphaned process",
+				"service", name, "pid", rec.PID,
+				"expected_command", rec.Command, "process_name", rec.ProcessName)
+
+			// Search for the actual orphaned process by command pattern and
+			// observed process name (handles exec-replaced scripts).
+			if orphanPID := driver.FindProcessByCommand(rec.Command, rec.PID, rec.ProcessName); orphanPID > 0 {
+				d.logger.Info("found orphaned process by command match",
+					"service", name, "orphan_pid", orphanPID, "command", rec.Command)
+				adopted, err := driver.NewAdopted(orphanPID)
+				if err == nil {
+					if err := d.adoptService(ctx, s, adopted); err != nil {
+						d.logger.Error("failed to adopt orphaned process", "service", name, "error", err)
 					} else {
-						d.logger.Warn("orphaned process disappeared before adoption",
-							"service", name, "orphan_pid", orphanPID)
+						d.markAdopted(name)
+						return
 					}
 				} else {
-					// Command-based search failed — try port-based detection.
-					// This catches exec-replaced processes where the stored
-					// process name doesn't match the running binary.
-					adopted := false
-					if rec.Port > 0 {
-						if portPID := driver.FindPIDOnPort(rec.Port); portPID > 0 && portPID != rec.PID {
-							d.logger.Info("found orphaned process by port match",
-								"service", name, "orphan_pid", portPID, "port", rec.Port)
-							drv, err := driver.NewAdopted(portPID)
-							if err == nil {
-								if err := d.adoptService(ctx, s, drv); err != nil {
-									d.logger.Error("failed to adopt port-matched process", "service", name, "error", err)
-								} else {
-									d.adopted = append(d.adopted, name)
-									adopted = true
-								}
+					d.logger.Warn("orphaned process disappeared before adoption",
+						"service", name, "orphan_pid", orphanPID)
+				}
+			} else {
+				// Command-based search failed — try port-based detection.
+				// This catches exec-replaced processes where the stored
+				// process name doesn't match the running binary.
+				adopted := false
+				if rec.Port > 0 {
+					if portPID := driver.FindPIDOnPort(rec.Port); portPID > 0 && portPID != rec.PID {
+						d.logger.Info("found orphaned process by port match",
+							"service", name, "orphan_pid", portPID, "port", rec.Port)
+						drv, err := driver.NewAdopted(portPID)
+						if err == nil {
+							if err := d.adoptService(ctx, s, drv); err != nil {
+								d.logger.Error("failed to adopt port-matched process", "service", name, "error", err)
+							} else {
+								d.markAdopted(name)
+								adopted = true
 							}
 						}
 					}
-					if adopted {
-						continue
-					}
-					d.logger.Info("no orphaned process found, will start fresh",
-						"service", name, "stale_pid", rec.PID)
 				}
-			} else {
-				adopted, err := driver.NewAdopted(rec.PID)
-				if err == nil {
-					d.logger.Info("recovering running process", "service", name, "pid", rec.PID)
-					if err := d.adoptService(ctx, s, adopted); err != nil {
-						d.logger.Error("failed to adopt service", "service", name, "error", err)
-					} else {
-						d.adopted = append(d.adopted, name)
-						continue
-					}
+				if adopted {
+					return
+				}
+				d.logger.Info("no orphaned process found, will start fresh",
+					"service", name, "stale_pid", rec.PID)
+			}
+		} else {
+			adopted, err := driver.NewAdopted(rec.PID)
+			if err == nil {
+				d.logger.Info("recovering running process", "service", name, "pid", rec.PID)
+				if err := d.adoptService(ctx, s, adopted); err != nil {
+					d.logger.Error("failed to adopt service", "service", name, "error", err)
 				} else {
-					d.logger.Info("previous process not running", "service", name, "pid", rec.PID)
+					d.markAdopted(name)
+					return
 				}
+			} else {
+				d.logger.Info("previous process not running", "service", name, "pid", rec.PID)
 			}
 		}
+	}
 
-		if err := d.startService(ctx, s); err != nil {
-			// Check if the failure is due to an orphaned process holding a port
-			var knownProcessName string
-			if rec, ok := prevState[name]; ok {
-				knownProcessName = rec.ProcessName
-			}
-			if d.recoverOrphanedPort(ctx, s, knownProcessName, err) {
-				continue
-			}
-			d.logger.Error("failed to start service", "service", name, "error", err)
-			continue
+	if err := d.startService(ctx, s); err != nil {
+		// Check if the failure is due to an orphaned process holding a port
+		var knownProcessName string
+		if rec, ok := prevState[name]; ok {
+			knownProcessName = rec.ProcessName
 		}
-
-		// Wait for health if other services require this one
-		if g.hasRequiredDependents(name) && s.Health != nil {
-			d.mu.RLock()
-			ms := d.services[name]
-			d.mu.RUnlock()
-
-			port := ms.EffectivePort()
-			d.logger.Info("waiting for dependency to become healthy", "service", name)
-			if err := d.waitForHealthy(ms, port); err != nil {
-				d.logger.Error("dependency failed health check", "service", name, "error", err)
-			}
+		if d.recoverOrphanedPort(ctx, s, knownProcessName, err) {
+			return
 		}
+		d.logger.Error("failed to start service", "service", name, "error", err)
+		return
 	}
 
-	// Generate initial routing config
-	d.regenerateRouting()
-
-	// Start peer liveness checking
-	d.startPeerLiveness(ctx)
-
-	// Redeploy adopted services in the background to restore log capture
-	go d.redeployAdopted()
+	// Wait for health if other services start only once this one is healthy
+	if !d.noHealthWait && g.waitsForHealth(name) && s.Health != nil {
+		d.mu.RLock()
+		ms := d.services[name]
+		d.mu.RUnlock()
 
-	// Start file watcher for auto-reload
-	go func() {
-		if err := d.StartWatcher(ctx); err != nil {
-			d.logger.Error("spec file watcher failed", "error", err)
+		d.logger.Info("waiting for dependency to become healthy", "service", name)
+		var err error
+		if ms.IsExternal() {
+			// Nothing was started to probe; wait on the health monitor,
+			// which may take a while to see a service started elsewhere
+			wait := d.externalWait
+			if wait <= 0 {
+				wait = DefaultReadyTimeout
+			}
+			_, err = d.WaitForReady(ctx, name, wait)
+		} else {
+			err = d.waitForHealthy(ms, ms.EffectivePort(), nil)
+		}
+		if err != nil {
+			d.logger.Error("dependency failed health check", "service", name, "error", err)
 		}
-	}()
+	}
+}
 
-	return nil
+// markAdopted records a service adopted during crash recovery so it can be
+// redeployed once startup completes.
+func (d *Daemon) markAdopted(name string) {
+	d.mu.Lock()
+	d.adopted = append(d.adopted, name)
+	d.mu.Unlock()
 }
 
-// Stop gracefully stops all services in reverse dependency order.
+// Stop gracefully stops all services in reverse dependency order. In
+// maintenance mode the services are already released and are left running.
 func (d *Daemon) Stop(timeout time.Duration) {
+	if d.InMaintenance() {
+		d.logger.Info("in maintenance mode, leaving released services running")
+		return
+	}
 	d.mu.RLock()
 	g := d.deps
 	d.mu.RUnlock()
@@ -319,7 +617,7 @@ func (d *Daemon) Stop(timeout time.Duration) {
 				}
 			}
 			d.logger.Info("all services stopped")
-			if err := d.state.save(map[string]ServiceRecord{}); err != nil {
+			if err := d.state.clearRunning(); err != nil {
 				d.logger.Warn("failed to clear state on shutdown", "error", err)
 			}
 			return
@@ -348,7 +646,7 @@ func (d *Daemon) Stop(timeout time.Duration) {
 	wg.Wait()
 
 	d.logger.Info("all services stopped")
-	if err := d.state.save(map[string]ServiceRecord{}); err != nil {
+	if err := d.state.clearRunning(); err != nil {
 		d.logger.Warn("failed to clear state on shutdown", "error", err)
 	}
 }
@@ -356,8 +654,13 @@ func (d *Daemon) Stop(timeout time.Duration) {
 // Shutdown exits gracefully without killing native processes, preserving the
 // state file so the next daemon instance can adopt them. Container services
 // are stopped (Docker manages their lifecycle independently). This is used
-// for SIGTERM / launchctl stop to enable zero-downtime restarts.
+// for SIGTERM / launchctl stop to enable zero-downtime restarts. In
+// maintenance mode containers are left running too.
 func (d *Daemon) Shutdown(timeout time.Duration) {
+	if d.InMaintenance() {
+		d.logger.Info("in maintenance mode, leaving released services running")
+		return
+	}
 	d.mu.RLock()
 	g := d.deps
 	d.mu.RUnlock()
@@ -420,7 +723,7 @@ func (d *Daemon) getService(name string) (*ManagedService, error) {
 	ms, ok := d.services[name]
 	d.mu.RUnlock()
 	if !ok {
-		return nil, fmt.Errorf("service %q not found", name)
+		return nil, fmt.Errorf("%w: %s", ErrServiceNotFound, name)
 	}
 	return ms, nil
 }
@@ -440,24 +743,159 @@ func (d *Daemon) IsExternal(name string) bool {
 	return err == nil && ms.IsExternal()
 }
 
-// StartService starts a single service by name.
-func (d *Daemon) StartService(ctx context.Context, name string) error {
+// ErrServiceDisabled is returned for starting, restarting or deploying a
+// service whose spec sets enabled: false.
+var ErrServiceDisabled = errors.New("service is disabled")
+
+// getEnabledService is getService for operations that run the service,
+// failing with ErrServiceDisabled if its spec disables it.
+func (d *Daemon) getEnabledService(name string) (*ManagedService, error) {
 	ms, err := d.getService(name)
 	if err != nil {
+		return nil, err
+	}
+	if !ms.spec.IsEnabled() {
+		return nil, fmt.Errorf("%w: %s", ErrServiceDisabled, name)
+	}
+	return ms, nil
+}
+
+// StartService starts a single service by name. The start is audited as
+// the actor in ctx.
+func (d *Daemon) StartService(ctx context.Context, name string) error {
+	err := d.startByName(ctx, name)
+	d.audit(ctx, audit.ActionServiceStart, name, err)
+	return err
+}
+
+func (d *Daemon) startByName(ctx context.Context, name string) error {
+	if err := d.checkMaintenance(); err != nil {
 		return err
 	}
-	return ms.Start(ctx)
+	ms, err := d.getEnabledService(name)
+	if err != nil {
+		return err
+	}
+	if err := d.state.setManuallyStopped(name, ms.spec.Service.Type, false); err != nil {
+		d.logger.Warn("failed to clear manual stop", "service", name, "error", err)
+	}
+	// Supervision outlives the caller (typically an API request) and ends
+	// with the daemon, so prefer the daemon's lifecycle context
+	svcCtx := d.ctx
+	if svcCtx == nil {
+		svcCtx = context.WithoutCancel(ctx)
+	}
+	return ms.Start(svcCtx)
+}
+
+// audit records a lifecycle action on service, or on the daemon when service
+// is empty, as the actor in ctx. Actions the daemon takes on its own, such as
+// a reload on a spec change, have the actor "daemon".
+func (d *Daemon) audit(ctx context.Context, action audit.Action, service string, err error) {
+	if d.auditLog == nil {
+		return
+	}
+	actor := audit.ActorFrom(ctx)
+	if actor == "" {
+		actor = "daemon"
+	}
+	entry := audit.Entry{Action: action, Service: service, Actor: actor}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	if lerr := d.auditLog.Log(entry); lerr != nil {
+		d.logger.Warn("failed to write audit entry", "action", action, "service", service, "error", lerr)
+	}
+}
+
+// ErrAuditDisabled is returned by AuditEntries when the daemon has no audit
+// log.
+var ErrAuditDisabled = errors.New("audit log not configured")
+
+// AuditEntries returns the audit log's entries matching f, oldest first,
+// including those in rotated files.
+func (d *Daemon) AuditEntries(f audit.Filter) ([]audit.Entry, error) {
+	if d.auditLog == nil {
+		return nil, ErrAuditDisabled
+	}
+	return audit.Read(d.auditLog.Path(), f)
+}
+
+// ErrServiceDown is returned by WaitForReady when the service has failed,
+// stopped or completed and won't come back without being started again.
+var ErrServiceDown = errors.New("service is down")
+
+// WaitForReady blocks until the named service is running and, if it has a
+// health check, healthy — or until timeout elapses or ctx ends. It returns
+// early with ErrServiceDown if the service goes down for good. It returns
+// the last observed state either way.
+func (d *Daemon) WaitForReady(ctx context.Context, name string, timeout time.Duration) (ServiceState, error) {
+	ms, err := d.getService(name)
+	if err != nil {
+		return ServiceState{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		st, err := d.ServiceState(name)
+		if err != nil {
+			return st, err
+		}
+		if st.State == driver.StateRunning && (ms.spec.Health == nil || st.Health == health.StatusHealthy) {
+			return st, nil
+		}
+		if serviceDown(st) {
+			if st.StateReason != "" {
+				return st, fmt.Errorf("%w: %q is %s (%s)", ErrServiceDown, name, st.State, st.StateReason)
+			}
+			return st, fmt.Errorf("%w: %q is %s", ErrServiceDown, name, st.State)
+		}
+		select {
+		case <-ctx.Done():
+			return st, fmt.Errorf("service %q not ready after %s (state %s, health %s)", name, timeout, st.State, st.Health)
+		case <-ticker.C:
+		}
+	}
+}
+
+// serviceDown reports whether st is a service that is down and won't come
+// back on its own: disabled, completed, or failed or stopped with a reason,
+// which is only set once supervision has given up. A service between
+// restarts has no reason yet.
+func serviceDown(st ServiceState) bool {
+	switch st.State {
+	case driver.StateDisabled, driver.StateCompleted:
+		return true
+	case driver.StateFailed, driver.StateStopped:
+		return st.StateReason != ""
+	}
+	return false
 }
 
 // StopService stops a single service by name, cascading to hard dependents.
-func (d *Daemon) StopService(name string, timeout time.Duration) error {
+// The stop is recorded as operator-initiated, so an unless-stopped service
+// stays down until started again. The stop is audited as the actor in ctx.
+func (d *Daemon) StopService(ctx context.Context, name string, timeout time.Duration) error {
+	err := d.checkMaintenance()
+	if err == nil {
+		err = d.stopService(name, timeout, true)
+	}
+	d.audit(ctx, audit.ActionServiceStop, name, err)
+	return err
+}
+
+func (d *Daemon) stopService(name string, timeout time.Duration, manual bool) error {
 	d.mu.RLock()
 	ms, ok := d.services[name]
 	g := d.deps
 	d.mu.RUnlock()
 
 	if !ok {
-		return fmt.Errorf("service %q not found", name)
+		return fmt.Errorf("%w: %s", ErrServiceNotFound, name)
 	}
 
 	// Cascade stop: first stop services that hard-depend on this one
@@ -469,7 +907,7 @@ func (d *Daemon) StopService(name string, timeout time.Duration) error {
 			d.mu.RUnlock()
 			if exists {
 				d.logger.Info("cascade stopping dependent", "service", dep, "because", name)
-				if err := depMs.Stop(timeout); err != nil {
+				if err := depMs.stop(timeout, true, ReasonDependencyStopped); err != nil {
 					d.logger.Error("error cascade stopping", "service", dep, "error", err)
 				}
 			}
@@ -477,14 +915,105 @@ func (d *Daemon) StopService(name string, timeout time.Duration) error {
 	}
 
 	err := ms.Stop(timeout)
+	if manual && !ms.IsExternal() {
+		if serr := d.state.setManuallyStopped(name, ms.spec.Service.Type, true); serr != nil {
+			d.logger.Warn("failed to record manual stop", "service", name, "error", serr)
+		}
+	}
 	d.regenerateRouting()
 	return err
 }
 
+// ErrServiceBusy is returned by LockService while another lifecycle operation
+// holds the service.
+var ErrServiceBusy = errors.New("another operation is in progress")
+
+// LockService claims name for a lifecycle operation — start, stop, restart,
+// deploy and the like — so two of them never race on the same service. It
+// fails with ErrServiceBusy instead of waiting if the service is already
+// claimed; otherwise the caller must call unlock when the operation is done.
+func (d *Daemon) LockService(name string) (unlock func(), err error) {
+	d.busyMu.Lock()
+	defer d.busyMu.Unlock()
+	if d.busy[name] {
+		return nil, fmt.Errorf("%w for %q", ErrServiceBusy, name)
+	}
+	d.busy[name] = true
+	return func() {
+		d.busyMu.Lock()
+		delete(d.busy, name)
+		d.busyMu.Unlock()
+	}, nil
+}
+
+// ErrServiceExists is returned by CreateService when a service with the
+// spec's name is already defined.
+var ErrServiceExists = errors.New("service already exists")
+
+// ErrServiceNotFound is returned for operations on a service the daemon does
+// not manage.
+var ErrServiceNotFound = errors.New("service not found")
+
+// CreateService validates a YAML service spec, writes it to <name>.yaml in
+// the spec directory and reloads so the new service starts. The spec is
+// checked the same way as the files already there, including the defaults
+// file. If the reload fails the file is removed again.
+func (d *Daemon) CreateService(ctx context.Context, data []byte) (*spec.ServiceSpec, error) {
+	d.specMu.Lock()
+	defer d.specMu.Unlock()
+
+	s, err := spec.Parse(data, d.specDir, d.specOptions()...)
+	if err != nil {
+		return nil, err
+	}
+	name := s.Service.Name // validated, so safe to use as a file name
+
+	d.mu.RLock()
+	_, exists := d.services[name]
+	d.mu.RUnlock()
+	if exists {
+		return nil, fmt.Errorf("%w: %s", ErrServiceExists, name)
+	}
+	if _, err := os.Stat(filepath.Join(d.specDir, name+".yml")); err == nil {
+		return nil, fmt.Errorf("%w: %s", ErrServiceExists, name)
+	}
+
+	path := filepath.Join(d.specDir, name+".yaml")
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if errors.Is(err, os.ErrExist) {
+		return nil, fmt.Errorf("%w: %s", ErrServiceExists, name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("creating spec file: %w", err)
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(path)
+		return nil, fmt.Errorf("writing spec file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(path)
+		return nil, fmt.Errorf("writing spec file: %w", err)
+	}
+	d.logger.Info("created spec file", "service", name, "path", path)
+
+	if _, err := d.Reload(ctx); err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+	return s, nil
+}
+
 // RemoveService stops a service, archives its spec file, and removes it from the daemon.
 func (d *Daemon) RemoveService(name string, timeout time.Duration) error {
+	d.specMu.Lock()
+	defer d.specMu.Unlock()
+
 	// Stop the service first (includes cascade logic)
-	if err := d.StopService(name, timeout); err != nil {
+	if err := d.checkMaintenance(); err != nil {
+		return err
+	}
+	if err := d.stopService(name, timeout, true); err != nil {
 		return err
 	}
 
@@ -511,7 +1040,9 @@ func (d *Daemon) RemoveService(name string, timeout time.Duration) error {
 	}
 
 	// Remove from in-memory state
-	d.ports.Release(name)
+	if ms, ok := d.services[name]; ok {
+		d.releasePorts(ms)
+	}
 	delete(d.services, name)
 	if d.deps != nil {
 		d.deps.remove(name)
@@ -524,6 +1055,7 @@ func (d *Daemon) RemoveService(name string, timeout time.Duration) error {
 
 	d.regenerateRoutingLocked(nil)
 	d.logger.Info("removed service", "service", name)
+	d.recordEvent(name, EventRemoved, "")
 	return nil
 }
 
@@ -531,7 +1063,36 @@ func (d *Daemon) RemoveService(name string, timeout time.Duration) error {
 // It uses the daemon's lifecycle context (not the caller's) so the new
 // service outlives short-lived request contexts.
 // After the target restarts, any cascade-stopped dependents are also restarted.
-func (d *Daemon) RestartService(name string, timeout time.Duration) error {
+// The restart is audited as the actor in ctx.
+func (d *Daemon) RestartService(ctx context.Context, name string, timeout time.Duration) error {
+	err := d.checkMaintenance()
+	if err == nil {
+		err = d.restartService(name, timeout, false)
+	}
+	d.audit(ctx, audit.ActionServiceRestart, name, err)
+	return err
+}
+
+// RestartServiceWithDeps restarts a service and its hard dependents
+// (transitively) in dependency order. Unlike RestartService, which starts the
+// cascade-stopped dependents as soon as the service has started, it waits for
+// the service to be ready and then starts each dependent in start order,
+// waiting for it to be ready before the next. Only dependents that were
+// running beforehand are brought back.
+func (d *Daemon) RestartServiceWithDeps(ctx context.Context, name string, timeout time.Duration) error {
+	err := d.checkMaintenance()
+	if err == nil {
+		err = d.restartService(name, timeout, true)
+	}
+	d.audit(ctx, audit.ActionServiceRestart, name, err)
+	return err
+}
+
+func (d *Daemon) restartService(name string, timeout time.Duration, withDeps bool) error {
+	if _, err := d.getEnabledService(name); err != nil {
+		return err
+	}
+
 	// Collect cascade targets before stopping — these will need restarting.
 	var cascadeTargets []string
 	d.mu.RLock()
@@ -540,6 +1101,20 @@ func (d *Daemon) RestartService(name string, timeout time.Duration) error {
 	if g != nil {
 		cascadeTargets = g.cascadeStopTargets(name)
 	}
+	var runningDeps []string
+	if withDeps {
+		for _, dep := range cascadeTargets {
+			d.mu.RLock()
+			depMs, exists := d.services[dep]
+			d.mu.RUnlock()
+			if !exists {
+				continue
+			}
+			if st := depMs.State().State; st == driver.StateRunning || st == driver.StateStarting {
+				runningDeps = append(runningDeps, dep)
+			}
+		}
+	}
 
 	// Capture the OS-observed process name before stopping so we can match
 	// exec-replaced processes (whose running name differs from the spec command).
@@ -560,7 +1135,7 @@ func (d *Daemon) RestartService(name string, timeout time.Duration) error {
 		ms.mu.Unlock()
 	}
 
-	if err := d.StopService(name, timeout); err != nil {
+	if err := d.stopService(name, timeout, false); err != nil {
 		return err
 	}
 
@@ -584,9 +1159,12 @@ func (d *Daemon) RestartService(name string, timeout time.Duration) error {
 		d.killOrphanOnPort(ms.spec, knownProcessName)
 	}
 
-	if err := d.StartService(d.ctx, name); err != nil {
+	if err := d.startByName(d.ctx, name); err != nil {
 		return err
 	}
+	if withDeps {
+		return d.restartDependents(name, g, runningDeps)
+	}
 
 	// Restart cascade-stopped dependents
 	for _, dep := range cascadeTargets {
@@ -603,7 +1181,7 @@ func (d *Daemon) RestartService(name string, timeout time.Duration) error {
 			depMs.mu.Lock()
 			depMs.restartCount = 0
 			depMs.mu.Unlock()
-			if err := d.StartService(d.ctx, dep); err != nil {
+			if err := d.startByName(d.ctx, dep); err != nil {
 				d.logger.Error("error cascade restarting", "service", dep, "error", err)
 			}
 		}
@@ -612,6 +1190,118 @@ func (d *Daemon) RestartService(name string, timeout time.Duration) error {
 	return nil
 }
 
+// restartDependents starts deps, the cascade-stopped dependents of name, in
+// start order once name is ready, waiting for each to be ready in turn so
+// every service comes up against running dependencies.
+func (d *Daemon) restartDependents(name string, g *depGraph, deps []string) error {
+	if len(deps) == 0 {
+		return nil
+	}
+	order, err := g.startOrder()
+	if err != nil {
+		return fmt.Errorf("dependency resolution: %w", err)
+	}
+	ctx := d.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if _, err := d.WaitForReady(ctx, name, DefaultReadyTimeout); err != nil {
+		return fmt.Errorf("waiting for %q before restarting dependents: %w", name, err)
+	}
+	for _, dep := range order {
+		if !slices.Contains(deps, dep) {
+			continue
+		}
+		d.mu.RLock()
+		depMs, exists := d.services[dep]
+		d.mu.RUnlock()
+		if !exists {
+			continue
+		}
+		d.logger.Info("cascade restarting dependent", "service", dep, "because", name)
+		depMs.mu.Lock()
+		depMs.restartCount = 0
+		depMs.mu.Unlock()
+		if err := d.startByName(ctx, dep); err != nil {
+			return fmt.Errorf("restarting dependent %q: %w", dep, err)
+		}
+		if _, err := d.WaitForReady(ctx, dep, DefaultReadyTimeout); err != nil {
+			return fmt.Errorf("restarting dependent %q: %w", dep, err)
+		}
+	}
+	return nil
+}
+
+// RollingRestartService restarts a service one replica at a time, waiting
+// for each to be running and healthy again before moving to the next, so
+// the others keep serving its routes throughout. Dependents are left
+// running. The restart is audited as the actor in ctx.
+func (d *Daemon) RollingRestartService(ctx context.Context, name string, timeout time.Duration) error {
+	err := d.checkMaintenance()
+	if err == nil {
+		err = d.rollingRestart(name, timeout)
+	}
+	d.audit(ctx, audit.ActionServiceRestart, name, err)
+	return err
+}
+
+func (d *Daemon) rollingRestart(name string, timeout time.Duration) error {
+	ms, err := d.getEnabledService(name)
+	if err != nil {
+		return err
+	}
+	if ms.IsExternal() {
+		return d.restartService(name, timeout, false)
+	}
+	ctx := d.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	for _, inst := range append([]*ManagedService{ms}, ms.replicaList()...) {
+		instName := inst.instanceName()
+		d.logger.Info("rolling restart", "service", name, "instance", instName)
+		if err := inst.stopInstance(timeout, true, ReasonManualStop); err != nil {
+			return fmt.Errorf("stopping %s: %w", instName, err)
+		}
+		d.regenerateRouting()
+
+		inst.mu.Lock()
+		inst.restartCount = 0
+		inst.mu.Unlock()
+		if err := inst.startInstance(ctx); err != nil {
+			return fmt.Errorf("starting %s: %w", instName, err)
+		}
+		if err := d.waitInstanceReady(ctx, inst, DefaultReadyTimeout); err != nil {
+			return err
+		}
+		d.regenerateRouting()
+	}
+	return nil
+}
+
+// waitInstanceReady blocks until a single service instance is running and,
+// if it has a health check, healthy, or until timeout elapses or ctx ends.
+func (d *Daemon) waitInstanceReady(ctx context.Context, ms *ManagedService, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		st := ms.instanceState()
+		if st.State == driver.StateRunning && (ms.spec.Health == nil || st.Health == health.StatusHealthy) {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%s not ready after %s (state %s, health %s)", st.Name, timeout, st.State, st.Health)
+		case <-ticker.C:
+		}
+	}
+}
+
 // killOrphanOnPort kills any OS process holding s's port before a restart.
 // Called from RestartService between StopService and StartService to prevent
 // "address already in use" when the previously-supervised process survived.
@@ -687,11 +1377,130 @@ func (d *Daemon) ServiceStates() []ServiceState {
 
 	states := make([]ServiceState, 0, len(d.services))
 	for _, ms := range d.services {
-		states = append(states, ms.State())
+		states = append(states, d.stateWithDepsLocked(ms))
 	}
 	return states
 }
 
+// stateWithDepsLocked returns ms.State() annotated with the health of its
+// hard dependencies. Reporting only — dependents are not restarted.
+// Caller must hold d.mu (read or write).
+func (d *Daemon) stateWithDepsLocked(ms *ManagedService) ServiceState {
+	st := ms.State()
+	if ms.spec.Dependencies == nil {
+		return st
+	}
+	for _, dep := range ms.spec.Dependencies.Requires {
+		dh := DependencyHealth{Name: dep, Health: health.StatusUnknown}
+		if depMs, ok := d.services[dep]; ok {
+			dh.Health = depMs.Health()
+		}
+		if dh.Health == health.StatusUnhealthy {
+			st.Degraded = true
+		}
+		st.DependencyHealth = append(st.DependencyHealth, dh)
+	}
+	return st
+}
+
+// Info describes the running daemon: when it started, where it loads specs
+// from, which optional subsystems are enabled, and how many services are in
+// each state. Version and TCPAPI are filled in by the API server.
+type Info struct {
+	Version        string               `json:"version"`
+	StartedAt      time.Time            `json:"started_at"`
+	Uptime         string               `json:"uptime"`
+	UptimeSeconds  float64              `json:"uptime_seconds"`
+	SpecDir        string               `json:"spec_dir"`
+	Services       map[driver.State]int `json:"services"`
+	RoutingEnabled bool                 `json:"routing_enabled"`
+	TCPAPIEnabled  bool                 `json:"tcp_api_enabled"`
+	PortMin        int                  `json:"port_min"`
+	PortMax        int                  `json:"port_max"`
+	Maintenance    bool                 `json:"maintenance"`
+}
+
+// Info returns daemon-level runtime information.
+func (d *Daemon) Info() Info {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	uptime := time.Since(d.startedAt)
+	info := Info{
+		StartedAt:      d.startedAt,
+		Uptime:         uptime.Truncate(time.Second).String(),
+		UptimeSeconds:  uptime.Seconds(),
+		SpecDir:        d.specDir,
+		Services:       make(map[driver.State]int),
+		RoutingEnabled: d.routing != nil,
+		Maintenance:    d.maintenance,
+	}
+	info.PortMin, info.PortMax = d.ports.Range()
+	for _, ms := range d.services {
+		info.Services[ms.State().State]++
+	}
+	return info
+}
+
+// RoutingInfo is the routing config the daemon last generated for Traefik.
+type RoutingInfo struct {
+	Enabled    bool                   `json:"enabled"`
+	OutputPath string                 `json:"output_path,omitempty"` // first of Outputs
+	Outputs    []string               `json:"outputs,omitempty"`
+	Routes     []routing.ServiceRoute `json:"routes"`
+}
+
+// Routing returns the routes of all running, routable services, sorted by
+// name, as they are written to the Traefik config.
+func (d *Daemon) Routing() RoutingInfo {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	info := RoutingInfo{Routes: []routing.ServiceRoute{}}
+	if d.routing == nil {
+		return info
+	}
+	info.Enabled = true
+	info.Outputs = d.routing.Outputs()
+	if len(info.Outputs) > 0 {
+		info.OutputPath = info.Outputs[0]
+	}
+	if routes := d.collectRoutesLocked(nil); routes != nil {
+		info.Routes = routes
+	}
+	slices.SortFunc(info.Routes, func(a, b routing.ServiceRoute) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return info
+}
+
+// ErrRoutingDisabled is returned when changing the routing config of a
+// daemon started without routing; enabling it needs a restart.
+var ErrRoutingDisabled = errors.New("routing is not enabled")
+
+// SetRoutingOutput moves the generated Traefik config to outputs and writes
+// it there. Files at old paths are left for the operator to remove.
+func (d *Daemon) SetRoutingOutput(outputs ...string) error {
+	if d.routing == nil {
+		return ErrRoutingDisabled
+	}
+	d.routing.SetOutputs(outputs...)
+	d.regenerateRouting()
+	return nil
+}
+
+// SetRoutingEntryPoints changes the Traefik entrypoints for routes that
+// don't name their own and rewrites the config. Empty values restore the
+// defaults ("web" and "websecure").
+func (d *Daemon) SetRoutingEntryPoints(plain, tls string) error {
+	if d.routing == nil {
+		return ErrRoutingDisabled
+	}
+	d.routing.SetEntryPoints(cmp.Or(plain, routing.DefaultEntryPoint), cmp.Or(tls, routing.DefaultEntryPointTLS))
+	d.regenerateRouting()
+	return nil
+}
+
 // ServiceLogs returns the last n log lines for a service.
 func (d *Daemon) ServiceLogs(name string, n int) ([]string, error) {
 	ms, err := d.getService(name)
@@ -701,13 +1510,45 @@ func (d *Daemon) ServiceLogs(name string, n int) ([]string, error) {
 	return ms.Logs(n), nil
 }
 
+// QueryServiceLogs returns the lines from a service's log buffer selected by q.
+func (d *Daemon) QueryServiceLogs(name string, q logbuf.Query) ([]logbuf.Line, error) {
+	ms, err := d.getService(name)
+	if err != nil {
+		return nil, err
+	}
+	return ms.QueryLogs(q), nil
+}
+
+// FollowServiceLogs streams a service's log lines as they are written until
+// ctx is done, when the channel is closed.
+func (d *Daemon) FollowServiceLogs(ctx context.Context, name string) (<-chan logbuf.Line, error) {
+	ms, err := d.getService(name)
+	if err != nil {
+		return nil, err
+	}
+	return ms.FollowLogs(ctx), nil
+}
+
 // ServiceState returns the state of a single service.
 func (d *Daemon) ServiceState(name string) (ServiceState, error) {
-	ms, err := d.getService(name)
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	ms, ok := d.services[name]
+	if !ok {
+		return ServiceState{}, fmt.Errorf("%w: %s", ErrServiceNotFound, name)
+	}
+	return d.stateWithDepsLocked(ms), nil
+}
+
+// OrphanedChildren returns the processes a native service left running when
+// it was last stopped and that are still alive, or nil if there are none.
+func (d *Daemon) OrphanedChildren(name string) ([]int, error) {
+	st, err := d.ServiceState(name)
 	if err != nil {
-		return ServiceState{}, err
+		return nil, err
 	}
-	return ms.State(), nil
+	return st.OrphanedChildren, nil
 }
 
 // InspectService returns the full resolved config and runtime state of a service.
@@ -719,6 +1560,18 @@ func (d *Daemon) InspectService(name string) (ServiceInspect, error) {
 	return ms.Inspect(), nil
 }
 
+// ServiceSpec returns the spec the daemon loaded for a service, after
+// defaults, the daemon's default restart policy and environment expansion
+// were applied. Secrets are the references from the spec, never their
+// values. The spec is shared with the daemon and must not be modified.
+func (d *Daemon) ServiceSpec(name string) (*spec.ServiceSpec, error) {
+	ms, err := d.getService(name)
+	if err != nil {
+		return nil, err
+	}
+	return ms.spec, nil
+}
+
 // ServiceDeps returns dependency information for a service.
 type ServiceDeps struct {
 	After         []string `json:"after"`
@@ -733,7 +1586,7 @@ func (d *Daemon) ServiceDeps(name string) (ServiceDeps, error) {
 	defer d.mu.RUnlock()
 
 	if _, ok := d.services[name]; !ok {
-		return ServiceDeps{}, fmt.Errorf("service %q not found", name)
+		return ServiceDeps{}, fmt.Errorf("%w: %s", ErrServiceNotFound, name)
 	}
 
 	result := ServiceDeps{}
@@ -825,9 +1678,18 @@ func (d *Daemon) CheckSpecDrift() ([]spec.DriftResult, error) {
 
 // Reload re-reads specs and reconciles: start new, stop removed, restart changed.
 // It uses the daemon's lifecycle context for starting services so they outlive
-// short-lived request contexts.
-func (d *Daemon) Reload(_ context.Context) (*ReloadResult, error) {
-	specs, err := spec.LoadDir(d.specDir)
+// short-lived request contexts. The reload is audited as the actor in ctx.
+func (d *Daemon) Reload(ctx context.Context) (*ReloadResult, error) {
+	result, err := d.reload()
+	d.audit(ctx, audit.ActionReload, "", err)
+	return result, err
+}
+
+func (d *Daemon) reload() (*ReloadResult, error) {
+	if err := d.checkMaintenance(); err != nil {
+		return nil, err
+	}
+	specs, err := d.loadSpecs()
 	if err != nil {
 		return nil, fmt.Errorf("loading specs: %w", err)
 	}
@@ -836,12 +1698,33 @@ func (d *Daemon) Reload(_ context.Context) (*ReloadResult, error) {
 
 	// Rebuild dependency graph
 	g := newDepGraph(specs)
+	if err := g.checkConditions(); err != nil {
+		return nil, fmt.Errorf("dependency resolution: %w", err)
+	}
 
 	newSpecs := make(map[string]*spec.ServiceSpec)
 	for _, s := range specs {
 		newSpecs[s.Service.Name] = s
 	}
 
+	// Image lookups go to Docker, so they run after d.mu is released rather
+	// than holding up every reader for them
+	for _, ms := range d.reconcile(g, newSpecs, result) {
+		if d.imageDrifted(ms) {
+			d.logger.Info("image tag points to a newer image, redeploy to pick it up",
+				"service", ms.spec.Service.Name, "image", ms.spec.Service.Image)
+			result.ImageDrift = append(result.ImageDrift, ms.spec.Service.Name)
+		}
+	}
+
+	d.recordEvent("", EventReloaded, result.summary())
+	return result, nil
+}
+
+// reconcile brings the running services in line with newSpecs under d.mu,
+// recording what changed in result. It returns the unchanged container
+// services, whose images may have drifted.
+func (d *Daemon) reconcile(g *depGraph, newSpecs map[string]*spec.ServiceSpec, result *ReloadResult) []*ManagedService {
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
@@ -852,7 +1735,7 @@ func (d *Daemon) Reload(_ context.Context) (*ReloadResult, error) {
 		if _, exists := newSpecs[name]; !exists {
 			d.logger.Info("removing service", "service", name)
 			ms.Stop(DefaultStopTimeout)
-			d.ports.Release(name)
+			d.releasePorts(ms)
 			delete(d.services, name)
 			result.Removed = append(result.Removed, name)
 		}
@@ -862,6 +1745,14 @@ func (d *Daemon) Reload(_ context.Context) (*ReloadResult, error) {
 	for name, s := range newSpecs {
 		if _, exists := d.services[name]; !exists {
 			d.logger.Info("adding service", "service", name)
+			if d.staysStopped(s) {
+				if err := d.addStoppedServiceLocked(s); err != nil {
+					d.logger.Error("failed to add service", "service", name, "error", err)
+				} else {
+					result.Added = append(result.Added, name)
+				}
+				continue
+			}
 			if err := d.startServiceLocked(d.ctx, s); err != nil {
 				d.logger.Error("failed to start new service", "service", name, "error", err)
 			} else {
@@ -871,6 +1762,7 @@ func (d *Daemon) Reload(_ context.Context) (*ReloadResult, error) {
 	}
 
 	// Restart changed services (spec content differs)
+	var unchanged []*ManagedService
 	for name, ms := range d.services {
 		newSpec, exists := newSpecs[name]
 		if !exists {
@@ -878,23 +1770,39 @@ func (d *Daemon) Reload(_ context.Context) (*ReloadResult, error) {
 		}
 		newHash := newSpec.Hash()
 		if ms.specHash == newHash {
+			if newSpec.Service.Type == "container" {
+				unchanged = append(unchanged, ms)
+			}
 			continue // unchanged
 		}
+		unlock, err := d.LockService(name)
+		if err != nil {
+			d.logger.Warn("changed service is busy, leaving it for the next reload", "service", name)
+			result.Busy = append(result.Busy, name)
+			continue
+		}
 		d.logger.Info("restarting changed service", "service", name)
 		ms.Stop(DefaultStopTimeout)
-		d.ports.Release(name)
+		d.releasePorts(ms)
 		delete(d.services, name)
+		if d.staysStopped(newSpec) {
+			if err := d.addStoppedServiceLocked(newSpec); err != nil {
+				d.logger.Error("failed to update stopped service", "service", name, "error", err)
+			}
+			unlock()
+			continue
+		}
 		if err := d.startServiceLocked(d.ctx, newSpec); err != nil {
 			d.logger.Error("failed to restart changed service", "service", name, "error", err)
 		} else {
 			result.Restarted = append(result.Restarted, name)
 		}
+		unlock()
 	}
 
 	// Regenerate routing after reconciliation (write lock is held, use locked variant)
 	d.regenerateRoutingLocked(nil)
-
-	return result, nil
+	return unchanged
 }
 
 // ReloadResult summarizes what changed during a reload.
@@ -902,6 +1810,43 @@ type ReloadResult struct {
 	Added     []string `json:"added,omitempty"`
 	Removed   []string `json:"removed,omitempty"`
 	Restarted []string `json:"restarted,omitempty"`
+
+	// ImageDrift lists unchanged container services whose image tag now
+	// points to a different image than the one running. They are left
+	// alone; a deploy picks the new image up.
+	ImageDrift []string `json:"image_drift,omitempty"`
+
+	// Busy lists changed services left on their old spec because another
+	// operation held them. A later reload restarts them.
+	Busy []string `json:"busy,omitempty"`
+}
+
+// imageDriftTimeout bounds the Docker lookup behind each drift check.
+const imageDriftTimeout = 5 * time.Second
+
+// imageDrifted reports whether ms's image tag has moved on from the image
+// its container runs.
+func (d *Daemon) imageDrifted(ms *ManagedService) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), imageDriftTimeout)
+	defer cancel()
+	return ms.checkImageDrift(ctx)
+}
+
+// summary describes the changes in one line, e.g. "added api; restarted web".
+func (r *ReloadResult) summary() string {
+	var parts []string
+	for _, c := range []struct {
+		verb  string
+		names []string
+	}{{"added", r.Added}, {"removed", r.Removed}, {"restarted", r.Restarted}, {"image changed for", r.ImageDrift}, {"busy, not restarted", r.Busy}} {
+		if len(c.names) > 0 {
+			parts = append(parts, c.verb+" "+strings.Join(c.names, ", "))
+		}
+	}
+	if len(parts) == 0 {
+		return "no changes"
+	}
+	return strings.Join(parts, "; ")
 }
 
 func (d *Daemon) startService(ctx context.Context, s *spec.ServiceSpec) error {
@@ -911,12 +1856,69 @@ func (d *Daemon) startService(ctx context.Context, s *spec.ServiceSpec) error {
 }
 
 func (d *Daemon) startServiceLocked(ctx context.Context, s *spec.ServiceSpec) error {
-	ms, err := NewManagedService(s, d.secrets)
+	ms, err := d.newServiceLocked(s)
+	if err != nil {
+		return err
+	}
+
+	if err := ms.Start(ctx); err != nil {
+		return err
+	}
+
+	d.services[s.Service.Name] = ms
+	d.logger.Info("started service", "service", s.Service.Name, "type", s.Service.Type)
+	return nil
+}
+
+func (d *Daemon) addStoppedService(s *spec.ServiceSpec) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.addStoppedServiceLocked(s)
+}
+
+// addStoppedServiceLocked registers s without starting it, for a disabled
+// service or an unless-stopped service the operator stopped.
+func (d *Daemon) addStoppedServiceLocked(s *spec.ServiceSpec) error {
+	ms, err := d.newServiceLocked(s)
 	if err != nil {
 		return err
 	}
+	d.services[s.Service.Name] = ms
+	if !s.IsEnabled() {
+		d.logger.Info("service is disabled, not starting it", "service", s.Service.Name)
+		return nil
+	}
+	ms.reason = ReasonManualStop
+	d.logger.Info("leaving service stopped, it was stopped by the operator", "service", s.Service.Name)
+	return nil
+}
+
+// staysStopped reports whether s is disabled, or an unless-stopped service
+// that the operator last stopped, which startup and reload leave down.
+func (d *Daemon) staysStopped(s *spec.ServiceSpec) bool {
+	if !s.IsEnabled() {
+		return true
+	}
+	return s.Restart != nil && s.Restart.Policy == "unless-stopped" && d.state.manuallyStopped(s.Service.Name)
+}
+
+// newServiceLocked creates the managed service for s, allocating its dynamic
+// port and wiring state persistence, without starting it.
+func (d *Daemon) newServiceLocked(s *spec.ServiceSpec) (*ManagedService, error) {
+	ms, err := NewManagedService(s, d.secrets)
+	if err != nil {
+		return nil, err
+	}
 
 	name := s.Service.Name
+	ms.onEvent = d.serviceEvents(name)
+	ms.containerPrefix = d.containerPrefix
+	ms.containerFiles = d.containerFilesDir()
+	ms.globalEnv = d.globalEnv
+	if d.gpu != nil {
+		ms.gpuInfo = d.gpu.Info
+	}
+	ms.restartCount = d.takeRecoveredRestartsLocked(name)
 
 	// External services skip port allocation and state persistence
 	if s.Service.Type != "external" {
@@ -924,35 +1926,126 @@ func (d *Daemon) startServiceLocked(ctx context.Context, s *spec.ServiceSpec) er
 		if s.NeedsDynamicPort() {
 			p, err := d.ports.Allocate(name)
 			if err != nil {
-				return fmt.Errorf("allocating port for %s: %w", name, err)
+				return nil, fmt.Errorf("allocating port for %s: %w", name, err)
 			}
 			ms.allocatedPort = p
 			d.logger.Info("allocated dynamic port", "service", name, "port", p)
 		}
 
-		ms.onStarted = func(pid int) {
-			rec := newServiceRecord(s.Service.Type, pid, ms.allocatedPort, s.Service.Command)
-			if st, err := driver.ProcessStartTime(pid); err == nil {
-				rec.StartTime = st
-			}
-			rec.ProcessName = resolveProcessName(pid)
+		ms.onStarted = func(drv driver.Driver) {
+			rec := processRecord(s.Service.Type, drv, ms.allocatedPort, s.Service.Command)
 			if err := d.state.set(name, rec); err != nil {
 				d.logger.Warn("failed to save service state", "service", name, "error", err)
 			}
 			d.regenerateRouting()
 		}
+		ms.onTransition = d.persistTransition(s)
 	}
 
-	if err := ms.Start(ctx); err != nil {
-		return err
+	ms.specHash = s.Hash()
+	if err := d.newReplicas(ms); err != nil {
+		d.ports.Release(name)
+		return nil, err
 	}
+	return ms, nil
+}
 
-	ms.specHash = s.Hash()
-	d.services[s.Service.Name] = ms
-	d.logger.Info("started service", "service", s.Service.Name, "type", s.Service.Type)
+// newReplicas creates the further replicas of the service whose first
+// instance is ms, per service.replicas, each on its own dynamic port. Their
+// events are recorded as <name>.<n>; the state file holds only the first
+// instance, so they aren't adopted after a daemon restart.
+func (d *Daemon) newReplicas(ms *ManagedService) error {
+	for i := 1; i < ms.spec.ReplicaCount(); i++ {
+		r, err := d.newReplica(ms, i)
+		if err != nil {
+			d.releaseReplicaPorts(ms)
+			return err
+		}
+		ms.replicas = append(ms.replicas, r)
+	}
 	return nil
 }
 
+// newReplica creates replica i of the service whose first instance is ms
+// and allocates its port.
+func (d *Daemon) newReplica(ms *ManagedService, i int) (*ManagedService, error) {
+	s := ms.spec
+	r, err := NewManagedService(s, d.secrets)
+	if err != nil {
+		return nil, err
+	}
+	r.replica = i
+	name := r.instanceName()
+	r.logger = slog.With("service", s.Service.Name, "replica", i+1)
+	r.onEvent = d.serviceEvents(name)
+	r.containerPrefix = d.containerPrefix
+	r.containerFiles = d.containerFilesDir()
+	r.globalEnv = d.globalEnv
+	r.gpuInfo = ms.gpuInfo
+	r.specHash = ms.specHash
+	r.onStarted = func(driver.Driver) { d.regenerateRouting() }
+
+	p, err := d.ports.Allocate(name)
+	if err != nil {
+		return nil, fmt.Errorf("allocating port for %s: %w", name, err)
+	}
+	r.allocatedPort = p
+	d.logger.Info("allocated dynamic port", "service", s.Service.Name, "replica", i+1, "port", p)
+	return r, nil
+}
+
+// containerFilesDir is where container services write their secret files,
+// one directory per container. It is under the state dir so the files of a
+// container the previous daemon left running are replaced, not leaked, when
+// the service next starts.
+func (d *Daemon) containerFilesDir() string {
+	return filepath.Join(d.stateDir, "container-files")
+}
+
+// releasePorts releases the dynamic ports of a service and its replicas.
+func (d *Daemon) releasePorts(ms *ManagedService) {
+	d.ports.Release(ms.spec.Service.Name)
+	d.releaseReplicaPorts(ms)
+}
+
+func (d *Daemon) releaseReplicaPorts(ms *ManagedService) {
+	for _, r := range ms.replicaList() {
+		d.ports.Release(r.instanceName())
+	}
+}
+
+// persistTransition returns the onTransition callback that keeps a service's
+// state file record in step with its supervision state, so that after a
+// crash recovery and inspection see where it had got to.
+func (d *Daemon) persistTransition(s *spec.ServiceSpec) func(ServiceState) {
+	name := s.Service.Name
+	return func(st ServiceState) {
+		err := d.state.update(name, func(rec *ServiceRecord) {
+			rec.Type = s.Service.Type
+			rec.State = string(st.State)
+			rec.StateReason = st.StateReason
+			rec.RestartCount = st.RestartCount
+			rec.LastExitCode = st.LastExitCode
+			rec.LastError = st.LastError
+			rec.UpdatedAt = time.Now().Unix()
+			if st.State != driver.StateRunning {
+				rec.PID = 0 // nothing left to adopt
+			}
+		})
+		if err != nil {
+			d.logger.Warn("failed to save service state", "service", name, "error", err)
+		}
+	}
+}
+
+// takeRecoveredRestartsLocked returns the restart count name had before the
+// daemon crashed, the first time it is asked for. Caller must hold d.mu.
+func (d *Daemon) takeRecoveredRestartsLocked(name string) int {
+	n := d.recoveredRestarts[name]
+	delete(d.recoveredRestarts, name)
+	return n
+}
+
 // regenerateRouting collects routing info from all running services and
 // writes a Traefik dynamic config file. No-op if routing is not configured.
 // It acquires RLock internally and is safe to call without any lock held.
@@ -975,43 +2068,63 @@ func (d *Daemon) regenerateRoutingLocked(portOverrides map[string]int) {
 		return
 	}
 
+	d.routingMu.Lock()
+	defer d.routingMu.Unlock()
+	routes := d.collectRoutesLocked(portOverrides)
+	if err := d.routing.Generate(routes); err != nil {
+		d.logger.Error("failed to regenerate routing config", "error", err)
+	} else {
+		d.logger.Info("regenerated routing config", "routes", len(routes), "outputs", d.routing.Outputs())
+	}
+}
+
+// collectRoutesLocked builds the routes for every running, routable service
+// that is healthy, has no health check or routing.route_unhealthy set, or
+// is within its routing.unhealthy_grace, with a backend for each of its
+// replicas that is. portOverrides substitutes ports for services mid-deploy.
+// Caller must hold d.mu.
+func (d *Daemon) collectRoutesLocked(portOverrides map[string]int) []routing.ServiceRoute {
 	var routes []routing.ServiceRoute
+	now := time.Now()
 	for _, ms := range d.services {
 		if ms.spec.Routing == nil {
 			continue
 		}
-		// Only include running services
-		state := ms.State()
-		if state.State != driver.StateRunning {
-			continue
-		}
 
-		port := ms.EffectivePort()
-		if port == 0 && ms.spec.Health != nil {
-			port = ms.spec.Health.Port
+		var ports []int
+		if port := ms.routePort(now); port != 0 {
+			if override, ok := portOverrides[ms.spec.Service.Name]; ok {
+				port = override
+			}
+			ports = append(ports, port)
 		}
-		if port == 0 {
-			continue
+		for _, r := range ms.replicaList() {
+			if port := r.routePort(now); port != 0 {
+				ports = append(ports, port)
+			}
 		}
-
-		if override, ok := portOverrides[ms.spec.Service.Name]; ok {
-			port = override
+		if len(ports) == 0 {
+			continue
 		}
 
 		routes = append(routes, routing.ServiceRoute{
-			Name:       ms.spec.Service.Name,
-			Hostname:   ms.spec.Routing.Hostname,
-			Port:       port,
-			TLS:        ms.spec.Routing.TLS,
-			TLSOptions: ms.spec.Routing.TLSOptions,
+			Name:        ms.spec.Service.Name,
+			Hostname:    ms.spec.Routing.PrimaryHostname(),
+			Hostnames:   ms.spec.Routing.AllHostnames(),
+			Port:        ports[0],
+			Ports:       ports[1:],
+			TLS:         ms.spec.Routing.TLS,
+			TLSOptions:  ms.spec.Routing.TLSOptions,
+			PathPrefix:  ms.spec.Routing.PathPrefix,
+			StripPrefix: ms.spec.Routing.StripPrefix,
+			Middlewares: ms.spec.Routing.Middlewares,
+			Headers:     ms.spec.Routing.Headers,
+			Protocol:    ms.spec.Routing.Protocol,
+			EntryPoint:  ms.spec.Routing.EntryPoint,
+			Host:        ms.spec.Network.ReachableHost(),
 		})
 	}
-
-	if err := d.routing.Generate(routes); err != nil {
-		d.logger.Error("failed to regenerate routing config", "error", err)
-	} else {
-		d.logger.Info("regenerated routing config", "routes", len(routes), "path", d.routing.OutputPath())
-	}
+	return routes
 }
 
 func (d *Daemon) adoptService(ctx context.Context, s *spec.ServiceSpec, drv driver.Driver) error {
@@ -1022,6 +2135,14 @@ func (d *Daemon) adoptService(ctx context.Context, s *spec.ServiceSpec, drv driv
 
 	name := s.Service.Name
 	ms.adoptedDrv = drv
+	ms.onEvent = d.serviceEvents(name)
+	ms.containerPrefix = d.containerPrefix
+	ms.containerFiles = d.containerFilesDir()
+	ms.globalEnv = d.globalEnv
+	ms.onTransition = d.persistTransition(s)
+	d.mu.Lock()
+	ms.restartCount = d.takeRecoveredRestartsLocked(name)
+	d.mu.Unlock()
 
 	// Restore dynamic port from allocator (reserved during state load)
 	if s.NeedsDynamicPort() {
@@ -1030,29 +2151,31 @@ func (d *Daemon) adoptService(ctx context.Context, s *spec.ServiceSpec, drv driv
 		}
 	}
 
-	ms.onStarted = func(pid int) {
-		rec := newServiceRecord(s.Service.Type, pid, ms.allocatedPort, s.Service.Command)
-		if st, err := driver.ProcessStartTime(pid); err == nil {
-			rec.StartTime = st
-		}
-		rec.ProcessName = resolveProcessName(pid)
+	ms.onStarted = func(drv driver.Driver) {
+		rec := processRecord(s.Service.Type, drv, ms.allocatedPort, s.Service.Command)
 		if err := d.state.set(name, rec); err != nil {
 			d.logger.Warn("failed to save service state", "service", name, "error", err)
 		}
 		d.regenerateRouting()
 	}
 
-	if err := ms.Start(ctx); err != nil {
+	// Only the first replica is adopted; the others start afresh with it
+	ms.specHash = s.Hash()
+	if err := d.newReplicas(ms); err != nil {
 		return err
 	}
 
-	ms.specHash = s.Hash()
+	if err := ms.Start(ctx); err != nil {
+		d.releaseReplicaPorts(ms)
+		return err
+	}
 
 	d.mu.Lock()
 	d.services[s.Service.Name] = ms
 	d.mu.Unlock()
 
 	d.logger.Info("adopted service", "service", s.Service.Name, "pid", drv.Info().PID)
+	d.recordEvent(name, EventAdopted, fmt.Sprintf("pid %d", drv.Info().PID))
 	return nil
 }
 
@@ -1082,7 +2205,7 @@ func (d *Daemon) redeployAdopted() {
 			return
 		}
 		d.logger.Info("redeploying adopted service", "service", name)
-		if err := d.DeployService(name, DefaultStopTimeout); err != nil {
+		if err := d.deployLocked(d.ctx, name, DefaultStopTimeout); err != nil {
 			d.logger.Error("failed to redeploy adopted service", "service", name, "error", err)
 		} else {
 			d.logger.Info("adopted service redeployed", "service", name)
@@ -1137,7 +2260,7 @@ func (d *Daemon) recoverOrphanedPort(ctx context.Context, s *spec.ServiceSpec, k
 			if err := d.adoptService(ctx, s, adopted); err != nil {
 				d.logger.Error("failed to adopt orphaned process", "service", name, "error", err)
 			} else {
-				d.adopted = append(d.adopted, name)
+				d.markAdopted(name)
 				return true
 			}
 		}
@@ -1182,6 +2305,22 @@ func (d *Daemon) recoverOrphanedPort(ctx context.Context, s *spec.ServiceSpec, k
 	return true
 }
 
+// processRecord builds the state record for a process that has just started
+// or been adopted, with what adoption needs to recognise it after a crash:
+// the OS start time guards against PID reuse, and the observed process name
+// against exec-replaced commands.
+func processRecord(serviceType string, drv driver.Driver, port int, command string) ServiceRecord {
+	pid := drv.Info().PID
+	rec := newServiceRecord(serviceType, pid, port, command)
+	rec.Image = containerImageID(drv)
+	rec.ImageDigest = containerImageDigest(drv)
+	if st, err := driver.ProcessStartTime(pid); err == nil {
+		rec.StartTime = st
+	}
+	rec.ProcessName = resolveProcessName(pid)
+	return rec
+}
+
 // resolveProcessName polls briefly to capture the post-exec process name.
 // Shell scripts that use exec to replace themselves will initially report the
 // shell name; after a few milliseconds the kernel reports the replacement binary.
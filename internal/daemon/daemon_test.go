@@ -2,6 +2,7 @@ package daemon
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
 	"net/http"
@@ -10,12 +11,17 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"testing"
 	"time"
 
 	"github.com/benaskins/aurelia/internal/driver"
+	"github.com/benaskins/aurelia/internal/gpu"
+	"github.com/benaskins/aurelia/internal/health"
 	"github.com/benaskins/aurelia/internal/spec"
+	"github.com/benaskins/aurelia/internal/sysinfo"
 )
 
 func writeSpec(t *testing.T, dir, name, content string) {
@@ -109,7 +115,7 @@ service:
 	time.Sleep(100 * time.Millisecond)
 
 	// Stop it
-	if err := d.StopService("managed", 5*time.Second); err != nil {
+	if err := d.StopService(context.Background(), "managed", 5*time.Second); err != nil {
 		t.Fatalf("StopService: %v", err)
 	}
 
@@ -272,6 +278,287 @@ service:
 	}
 }
 
+func TestLockServiceRejectsConcurrentOperation(t *testing.T) {
+	d := NewDaemon(t.TempDir())
+
+	unlock, err := d.LockService("svc")
+	if err != nil {
+		t.Fatalf("LockService: %v", err)
+	}
+	if _, err := d.LockService("svc"); !errors.Is(err, ErrServiceBusy) {
+		t.Errorf("second LockService = %v, want ErrServiceBusy", err)
+	}
+	other, err := d.LockService("other")
+	if err != nil {
+		t.Errorf("LockService on a different service: %v", err)
+	} else {
+		other()
+	}
+
+	unlock()
+	again, err := d.LockService("svc")
+	if err != nil {
+		t.Fatalf("LockService after unlock: %v", err)
+	}
+	again()
+}
+
+func TestDaemonReloadSkipsBusyService(t *testing.T) {
+	dir := t.TempDir()
+	writeSpec(t, dir, "svc.yaml", "service:\n  name: svc\n  type: native\n  command: \"sleep 10\"\n\nenv:\n  FOO: bar\n")
+
+	d := NewDaemon(dir, WithSpecWatch(false))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := d.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer d.Stop(5 * time.Second)
+
+	unlock, err := d.LockService("svc")
+	if err != nil {
+		t.Fatalf("LockService: %v", err)
+	}
+	writeSpec(t, dir, "svc.yaml", "service:\n  name: svc\n  type: native\n  command: \"sleep 10\"\n\nenv:\n  FOO: baz\n")
+
+	result, err := d.Reload(ctx)
+	if err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if len(result.Restarted) != 0 || len(result.Busy) != 1 || result.Busy[0] != "svc" {
+		t.Errorf("expected svc busy and not restarted, got restarted=%v busy=%v", result.Restarted, result.Busy)
+	}
+
+	unlock()
+	result, err = d.Reload(ctx)
+	if err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if len(result.Restarted) != 1 || result.Restarted[0] != "svc" {
+		t.Errorf("expected the next reload to restart svc, got %v", result.Restarted)
+	}
+}
+
+func TestCreateServiceWaitsForSpecLock(t *testing.T) {
+	dir := t.TempDir()
+	d := NewDaemon(dir, WithSpecWatch(false))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := d.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer d.Stop(5 * time.Second)
+
+	// Holding specMu stands in for an apply in progress
+	d.specMu.Lock()
+	done := make(chan error, 1)
+	go func() {
+		_, err := d.CreateService(ctx, []byte("service:\n  name: created\n  type: native\n  command: \"sleep 10\"\n"))
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		d.specMu.Unlock()
+		t.Fatalf("CreateService returned while the spec dir was locked: %v", err)
+	case <-time.After(200 * time.Millisecond):
+	}
+	if _, err := os.Stat(filepath.Join(dir, "created.yaml")); err == nil {
+		t.Error("CreateService wrote its spec while the spec dir was locked")
+	}
+
+	d.specMu.Unlock()
+	if err := <-done; err != nil {
+		t.Fatalf("CreateService: %v", err)
+	}
+	if _, err := d.ServiceState("created"); err != nil {
+		t.Errorf("created service not loaded: %v", err)
+	}
+}
+
+func TestDaemonDefaultRestart(t *testing.T) {
+	dir := t.TempDir()
+	writeSpec(t, dir, "crash.yaml", "service:\n  name: crash\n  type: native\n  command: \"sh -c 'exit 1'\"\n")
+	writeSpec(t, dir, "once.yaml", "service:\n  name: once\n  type: native\n  command: \"sh -c 'exit 1'\"\nrestart:\n  policy: never\n")
+
+	d := NewDaemon(dir, WithStateDir(t.TempDir()), WithSpecWatch(false),
+		WithDefaultRestart(&spec.RestartPolicy{
+			Policy:      "on-failure",
+			MaxAttempts: 2,
+			Delay:       spec.Duration{Duration: 10 * time.Millisecond},
+		}))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := d.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer d.Stop(5 * time.Second)
+
+	// No restart block: inherits the default, so it is restarted on failure
+	// until max_attempts is used up
+	waitUntil(t, func() bool {
+		st, _ := d.ServiceState("crash")
+		return st.StateReason == ReasonPolicyExhausted
+	}, 5*time.Second, "crash to use up the default policy's attempts")
+	if st, _ := d.ServiceState("crash"); st.RestartCount != 2 {
+		t.Errorf("expected 2 restarts under the default policy, got %d", st.RestartCount)
+	}
+
+	// Its own restart block wins
+	waitUntil(t, func() bool {
+		st, _ := d.ServiceState("once")
+		return st.StateReason == ReasonRestartNever
+	}, 5*time.Second, "once to fail without restarting")
+	if st, _ := d.ServiceState("once"); st.RestartCount != 0 {
+		t.Errorf("expected no restarts under restart.policy never, got %d", st.RestartCount)
+	}
+}
+
+func TestDaemonBuiltinDefaultRestart(t *testing.T) {
+	dir := t.TempDir()
+	writeSpec(t, dir, "crash.yaml", "service:\n  name: crash\n  type: native\n  command: \"sh -c 'exit 1'\"\n")
+
+	d := NewDaemon(dir, WithStateDir(t.TempDir()), WithSpecWatch(false))
+	specs, err := d.loadSpecs()
+	if err != nil {
+		t.Fatalf("loadSpecs: %v", err)
+	}
+	if r := specs[0].Restart; r == nil || *r != *DefaultRestartPolicy() {
+		t.Fatalf("expected a spec without a restart block to get the default policy, got %+v", r)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := d.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer d.Stop(5 * time.Second)
+
+	// A crash is restarted rather than left failed
+	waitUntil(t, func() bool {
+		st, _ := d.ServiceState("crash")
+		return st.RestartCount == 1
+	}, 3*time.Second, "crash to be restarted under the default policy")
+	if st, _ := d.ServiceState("crash"); st.StateReason != "" {
+		t.Errorf("expected crash to be restarting, got reason %q", st.StateReason)
+	}
+
+	// A nil default leaves such services without a policy
+	d2 := NewDaemon(dir, WithStateDir(t.TempDir()), WithSpecWatch(false), WithDefaultRestart(nil))
+	if err := d2.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer d2.Stop(5 * time.Second)
+	waitUntil(t, func() bool {
+		st, _ := d2.ServiceState("crash")
+		return st.StateReason == ReasonRestartNever
+	}, 3*time.Second, "crash to stop without a restart policy")
+}
+
+func TestDaemonDisabledService(t *testing.T) {
+	dir := t.TempDir()
+	writeSpec(t, dir, "svc.yaml", "service:\n  name: svc\n  type: native\n  command: \"sleep 10\"\n")
+	writeSpec(t, dir, "off.yaml", "enabled: false\nservice:\n  name: off\n  type: native\n  command: \"sleep 10\"\n")
+
+	d := NewDaemon(dir, WithStateDir(t.TempDir()), WithSpecWatch(false))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := d.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer d.Stop(5 * time.Second)
+
+	st, err := d.ServiceState("off")
+	if err != nil {
+		t.Fatalf("ServiceState: %v", err)
+	}
+	if st.State != driver.StateDisabled || st.PID != 0 {
+		t.Errorf("expected off disabled with no process, got %s pid %d", st.State, st.PID)
+	}
+	if err := d.StartService(ctx, "off"); !errors.Is(err, ErrServiceDisabled) {
+		t.Errorf("StartService on a disabled service = %v, want ErrServiceDisabled", err)
+	}
+	if err := d.RestartService(context.Background(), "off", time.Second); !errors.Is(err, ErrServiceDisabled) {
+		t.Errorf("RestartService on a disabled service = %v, want ErrServiceDisabled", err)
+	}
+
+	running := func() bool {
+		st, _ := d.ServiceState("svc")
+		return st.State == driver.StateRunning
+	}
+
+	// Disabling a running service and reloading stops it
+	waitUntil(t, running, 2*time.Second, "svc to start")
+	st, _ = d.ServiceState("svc")
+	pid := st.PID
+	writeSpec(t, dir, "svc.yaml", "enabled: false\nservice:\n  name: svc\n  type: native\n  command: \"sleep 10\"\n")
+	if _, err := d.Reload(ctx); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if st, _ := d.ServiceState("svc"); st.State != driver.StateDisabled {
+		t.Errorf("expected svc disabled after reload, got %s", st.State)
+	}
+	if syscall.Kill(pid, 0) == nil {
+		t.Errorf("expected process %d to be stopped once disabled", pid)
+	}
+
+	// Enabling it again starts it
+	writeSpec(t, dir, "svc.yaml", "enabled: true\nservice:\n  name: svc\n  type: native\n  command: \"sleep 10\"\n")
+	result, err := d.Reload(ctx)
+	if err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if len(result.Restarted) != 1 || result.Restarted[0] != "svc" {
+		t.Errorf("expected svc restarted once enabled, got %v", result.Restarted)
+	}
+	waitUntil(t, running, 2*time.Second, "svc to start once enabled")
+}
+
+func TestDaemonExtraSpecDirs(t *testing.T) {
+	dir, extra := t.TempDir(), t.TempDir()
+	writeSpec(t, dir, "api.yaml", "service:\n  name: api\n  type: native\n  command: \"sleep 10\"\n")
+	writeSpec(t, extra, "worker.yaml", "service:\n  name: worker\n  type: native\n  command: \"sleep 10\"\n")
+
+	d := NewDaemon(dir, WithStateDir(t.TempDir()), WithExtraSpecDirs(extra), WithSpecWatch(false))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := d.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer d.Stop(5 * time.Second)
+
+	for _, name := range []string{"api", "worker"} {
+		if _, err := d.ServiceState(name); err != nil {
+			t.Errorf("expected %s loaded: %v", name, err)
+		}
+	}
+
+	// A name declared in both directories fails the reload and keeps the
+	// running set
+	writeSpec(t, extra, "api.yaml", "service:\n  name: api\n  type: native\n  command: \"sleep 20\"\n")
+	if _, err := d.Reload(ctx); err == nil || !strings.Contains(err.Error(), `duplicate service name "api"`) {
+		t.Errorf("expected duplicate name error, got %v", err)
+	}
+	if len(d.ServiceStates()) != 2 {
+		t.Errorf("expected 2 services after the failed reload, got %d", len(d.ServiceStates()))
+	}
+}
+
+func TestDaemonSpecOverride(t *testing.T) {
+	dir, extra := t.TempDir(), t.TempDir()
+	writeSpec(t, dir, "api.yaml", "service:\n  name: api\n  type: native\n  command: \"sleep 10\"\n")
+	writeSpec(t, extra, "api.yaml", "service:\n  name: api\n  type: native\n  command: \"sleep 20\"\n")
+
+	d := NewDaemon(dir, WithStateDir(t.TempDir()), WithExtraSpecDirs(extra), WithSpecOverride(true), WithSpecWatch(false))
+	specs, err := d.loadSpecs()
+	if err != nil {
+		t.Fatalf("loadSpecs: %v", err)
+	}
+	if len(specs) != 1 || specs[0].Service.Command != "sleep 20" {
+		t.Errorf("expected api from the extra directory, got %+v", specs)
+	}
+}
+
 func TestDaemonRoutingGeneration(t *testing.T) {
 	dir := t.TempDir()
 	routingPath := filepath.Join(t.TempDir(), "traefik", "aurelia.yaml")
@@ -325,59 +612,51 @@ service:
 	}
 }
 
-func containsAll(s string, substrs ...string) bool {
-	for _, sub := range substrs {
-		if !strings.Contains(s, sub) {
-			return false
-		}
-	}
-	return true
-}
-
-func TestDaemonDynamicPort(t *testing.T) {
+func TestDaemonRoutingEntryPoints(t *testing.T) {
 	dir := t.TempDir()
-	writeSpec(t, dir, "dynamic.yaml", `
+	routingPath := filepath.Join(t.TempDir(), "aurelia.yaml")
+
+	writeSpec(t, dir, "chat.yaml", `
 service:
-  name: dynamic-svc
+  name: chat
   type: native
-  command: "sleep 10"
+  command: "sleep 30"
 
 network:
-  port: 0
-`)
+  port: 8090
 
-	d := NewDaemon(dir, WithPortRange(25000, 25100))
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+routing:
+  hostname: chat.example.local
+  tls: true
+`)
 
-	if err := d.Start(ctx); err != nil {
+	d := NewDaemon(dir, WithRouting(routingPath), WithRoutingEntryPoints("http", "https"))
+	if err := d.Start(context.Background()); err != nil {
 		t.Fatalf("Start: %v", err)
 	}
 	defer d.Stop(5 * time.Second)
 
-	// Wait for process to start
-	time.Sleep(100 * time.Millisecond)
+	time.Sleep(200 * time.Millisecond)
 
-	state, err := d.ServiceState("dynamic-svc")
+	data, err := os.ReadFile(routingPath)
 	if err != nil {
-		t.Fatalf("ServiceState: %v", err)
-	}
-
-	if state.Port < 25000 || state.Port > 25100 {
-		t.Errorf("expected port in range 25000-25100, got %d", state.Port)
+		t.Fatalf("routing config not written: %v", err)
 	}
-	if state.State != "running" {
-		t.Errorf("expected running, got %v", state.State)
+	if content := string(data); !strings.Contains(content, "- https") || strings.Contains(content, "websecure") {
+		t.Errorf("expected router on the configured https entrypoint:\n%s", content)
 	}
 }
 
-func TestDaemonDynamicPortRouting(t *testing.T) {
+func TestDaemonRoutingFollowsHealth(t *testing.T) {
 	dir := t.TempDir()
-	routingPath := filepath.Join(t.TempDir(), "traefik", "aurelia.yaml")
+	routingPath := filepath.Join(t.TempDir(), "aurelia.yaml")
+	healthyFile := filepath.Join(t.TempDir(), "healthy")
 
-	writeSpec(t, dir, "dynamic-routed.yaml", `
+	// gated is healthy while healthyFile exists. always never passes its
+	// check but is routed regardless
+	writeSpec(t, dir, "gated.yaml", fmt.Sprintf(`
 service:
-  name: dynamic-routed
+  name: gated
   type: native
   command: "sleep 30"
 
@@ -385,82 +664,355 @@ network:
   port: 0
 
 routing:
-  hostname: dynamic.example.local
-  tls: true
+  hostname: gated.example.local
+
+health:
+  type: exec
+  command: "test -f %s"
+  interval: 100ms
+  timeout: 1s
+  unhealthy_threshold: 1
+
+restart:
+  policy: always
+  delay: 100ms
+`, healthyFile))
+	writeSpec(t, dir, "always.yaml", `
+service:
+  name: always
+  type: native
+  command: "sleep 30"
+
+network:
+  port: 0
+
+routing:
+  hostname: always.example.local
+  route_unhealthy: true
+
+health:
+  type: exec
+  command: "false"
+  interval: 100ms
+  timeout: 1s
+  unhealthy_threshold: 1000
 `)
 
-	d := NewDaemon(dir, WithRouting(routingPath), WithPortRange(26000, 26100))
+	d := NewDaemon(dir, WithRouting(routingPath), WithPortRange(26200, 26300))
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
-
 	if err := d.Start(ctx); err != nil {
 		t.Fatalf("Start: %v", err)
 	}
 	defer d.Stop(5 * time.Second)
 
-	// Wait for onStarted callback to fire and routing to generate
-	time.Sleep(200 * time.Millisecond)
+	waitForRouting := func(want bool, why string) {
+		t.Helper()
+		var content string
+		for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(50 * time.Millisecond) {
+			data, _ := os.ReadFile(routingPath)
+			content = string(data)
+			if strings.Contains(content, "always.example.local") && strings.Contains(content, "gated.example.local") == want {
+				return
+			}
+		}
+		t.Fatalf("expected always routed and gated routed=%v %s:\n%s", want, why, content)
+	}
 
-	state, err := d.ServiceState("dynamic-routed")
-	if err != nil {
-		t.Fatalf("ServiceState: %v", err)
+	waitForRouting(false, "before its health check passes")
+
+	if err := os.WriteFile(healthyFile, nil, 0644); err != nil {
+		t.Fatal(err)
 	}
+	waitForRouting(true, "once healthy")
 
-	// Verify routing config was generated with the allocated port
-	data, err := os.ReadFile(routingPath)
-	if err != nil {
-		t.Fatalf("routing config not written: %v", err)
+	if err := os.Remove(healthyFile); err != nil {
+		t.Fatal(err)
 	}
+	waitForRouting(false, "while unhealthy")
 
-	content := string(data)
-	portStr := fmt.Sprintf("%d", state.Port)
-	if !containsAll(content, "dynamic.example.local", portStr) {
-		t.Errorf("routing config missing hostname or allocated port %d:\n%s", state.Port, content)
+	if err := os.WriteFile(healthyFile, nil, 0644); err != nil {
+		t.Fatal(err)
 	}
+	waitForRouting(true, "after recovering")
 }
 
-func TestDaemonExternalServiceShowsHealth(t *testing.T) {
+func TestDaemonRoutingUnhealthyGrace(t *testing.T) {
 	dir := t.TempDir()
-	writeSpec(t, dir, "ext.yaml", `
+	routingPath := filepath.Join(t.TempDir(), "aurelia.yaml")
+	healthyFile := filepath.Join(t.TempDir(), "healthy")
+	if err := os.WriteFile(healthyFile, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	writeSpec(t, dir, "blip.yaml", fmt.Sprintf(`
 service:
-  name: ext-svc
-  type: external
+  name: blip
+  type: native
+  command: "sleep 30"
+
+network:
+  port: 0
+
+routing:
+  hostname: blip.example.local
+  unhealthy_grace: 2s
 
 health:
-  type: tcp
-  port: 19999
+  type: exec
+  command: "test -f %s"
   interval: 100ms
-  timeout: 50ms
-  unhealthy_threshold: 2
-`)
+  timeout: 1s
+  unhealthy_threshold: 1
 
-	d := NewDaemon(dir)
+restart:
+  policy: always
+  delay: 100ms
+`, healthyFile))
+
+	d := NewDaemon(dir, WithRouting(routingPath), WithPortRange(26300, 26400))
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
-
 	if err := d.Start(ctx); err != nil {
 		t.Fatalf("Start: %v", err)
 	}
 	defer d.Stop(5 * time.Second)
 
-	// Wait for health checks to run
-	time.Sleep(500 * time.Millisecond)
-
-	state, err := d.ServiceState("ext-svc")
-	if err != nil {
-		t.Fatalf("ServiceState: %v", err)
+	routed := func() bool {
+		data, _ := os.ReadFile(routingPath)
+		return strings.Contains(string(data), "blip.example.local")
+	}
+	for deadline := time.Now().Add(5 * time.Second); !routed(); time.Sleep(50 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("expected blip to be routed once healthy")
+		}
 	}
 
-	if state.Type != "external" {
-		t.Errorf("expected type 'external', got %q", state.Type)
+	// A blip shorter than the grace keeps the route throughout
+	if err := os.Remove(healthyFile); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(300 * time.Millisecond)
+	if err := os.WriteFile(healthyFile, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	for end := time.Now().Add(2500 * time.Millisecond); time.Now().Before(end); time.Sleep(50 * time.Millisecond) {
+		if !routed() {
+			t.Fatal("expected the route to be kept through a brief health blip")
+		}
+	}
+
+	// A failure outlasting the grace removes it
+	if err := os.Remove(healthyFile); err != nil {
+		t.Fatal(err)
+	}
+	removed := time.Now()
+	for routed() {
+		if time.Since(removed) > 5*time.Second {
+			t.Fatal("expected the route to be removed after a sustained failure")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if elapsed := time.Since(removed); elapsed < time.Second {
+		t.Errorf("route removed %v after the failure, want it kept for the grace", elapsed)
+	}
+}
+
+func containsAll(s string, substrs ...string) bool {
+	for _, sub := range substrs {
+		if !strings.Contains(s, sub) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestDaemonDynamicPort(t *testing.T) {
+	dir := t.TempDir()
+	writeSpec(t, dir, "dynamic.yaml", `
+service:
+  name: dynamic-svc
+  type: native
+  command: "sleep 10"
+
+network:
+  port: 0
+`)
+
+	d := NewDaemon(dir, WithPortRange(25000, 25100))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := d.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer d.Stop(5 * time.Second)
+
+	// Wait for process to start
+	time.Sleep(100 * time.Millisecond)
+
+	state, err := d.ServiceState("dynamic-svc")
+	if err != nil {
+		t.Fatalf("ServiceState: %v", err)
+	}
+
+	if state.Port < 25000 || state.Port > 25100 {
+		t.Errorf("expected port in range 25000-25100, got %d", state.Port)
 	}
 	if state.State != "running" {
-		t.Errorf("expected state 'running' for external service, got %q", state.State)
+		t.Errorf("expected running, got %v", state.State)
+	}
+}
+
+func TestDaemonReloadKeepsDynamicPort(t *testing.T) {
+	dir := t.TempDir()
+	writeSpec(t, dir, "dynamic-svc.yaml", `
+service:
+  name: dynamic-svc
+  type: native
+  command: "sleep 10"
+
+network:
+  port: 0
+
+env:
+  VERSION: "1"
+`)
+
+	d := NewDaemon(dir, WithPortRange(25200, 25300))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := d.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer d.Stop(5 * time.Second)
+
+	before, err := d.ServiceState("dynamic-svc")
+	if err != nil {
+		t.Fatalf("ServiceState: %v", err)
+	}
+
+	writeSpec(t, dir, "dynamic-svc.yaml", `
+service:
+  name: dynamic-svc
+  type: native
+  command: "sleep 10"
+
+network:
+  port: 0
+
+env:
+  VERSION: "2"
+`)
+	result, err := d.Reload(ctx)
+	if err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if len(result.Restarted) != 1 {
+		t.Fatalf("expected dynamic-svc to restart, got %+v", result)
+	}
+
+	after, err := d.ServiceState("dynamic-svc")
+	if err != nil {
+		t.Fatalf("ServiceState: %v", err)
+	}
+	if after.Port != before.Port {
+		t.Errorf("expected restart to keep port %d, got %d", before.Port, after.Port)
+	}
+}
+
+func TestDaemonDynamicPortRouting(t *testing.T) {
+	dir := t.TempDir()
+	routingPath := filepath.Join(t.TempDir(), "traefik", "aurelia.yaml")
+
+	writeSpec(t, dir, "dynamic-routed.yaml", `
+service:
+  name: dynamic-routed
+  type: native
+  command: "sleep 30"
+
+network:
+  port: 0
+
+routing:
+  hostname: dynamic.example.local
+  tls: true
+`)
+
+	d := NewDaemon(dir, WithRouting(routingPath), WithPortRange(26000, 26100))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := d.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer d.Stop(5 * time.Second)
+
+	// Wait for onStarted callback to fire and routing to generate
+	time.Sleep(200 * time.Millisecond)
+
+	state, err := d.ServiceState("dynamic-routed")
+	if err != nil {
+		t.Fatalf("ServiceState: %v", err)
+	}
+
+	// Verify routing config was generated with the allocated port
+	data, err := os.ReadFile(routingPath)
+	if err != nil {
+		t.Fatalf("routing config not written: %v", err)
+	}
+
+	content := string(data)
+	portStr := fmt.Sprintf("%d", state.Port)
+	if !containsAll(content, "dynamic.example.local", portStr) {
+		t.Errorf("routing config missing hostname or allocated port %d:\n%s", state.Port, content)
+	}
+}
+
+func TestDaemonExternalServiceShowsHealth(t *testing.T) {
+	dir := t.TempDir()
+	writeSpec(t, dir, "ext.yaml", `
+service:
+  name: ext-svc
+  type: external
+
+health:
+  type: tcp
+  port: 19999
+  interval: 100ms
+  timeout: 50ms
+  unhealthy_threshold: 2
+`)
+
+	d := NewDaemon(dir)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := d.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer d.Stop(5 * time.Second)
+
+	// Wait for health checks to run
+	time.Sleep(500 * time.Millisecond)
+
+	state, err := d.ServiceState("ext-svc")
+	if err != nil {
+		t.Fatalf("ServiceState: %v", err)
+	}
+
+	if state.Type != "external" {
+		t.Errorf("expected type 'external', got %q", state.Type)
 	}
-	// Nothing listening on 19999 so health should be unhealthy
+	// Nothing listening on 19999 so health should be unhealthy and the
+	// service reported as unreachable
 	if state.Health != "unhealthy" {
 		t.Errorf("expected health 'unhealthy', got %q", state.Health)
 	}
+	if state.State != driver.StateUnreachable {
+		t.Errorf("expected state 'unreachable' for unhealthy external service, got %q", state.State)
+	}
 	if state.PID != 0 {
 		t.Errorf("expected no PID for external service, got %d", state.PID)
 	}
@@ -469,6 +1021,56 @@ health:
 	}
 }
 
+func TestDaemonExternalServiceRunningWhenHealthy(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	dir := t.TempDir()
+	writeSpec(t, dir, "ext.yaml", fmt.Sprintf(`
+service:
+  name: ext-svc
+  type: external
+
+health:
+  type: tcp
+  port: %d
+  interval: 100ms
+  timeout: 50ms
+  unhealthy_threshold: 2
+`, ln.Addr().(*net.TCPAddr).Port))
+
+	d := NewDaemon(dir)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := d.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer d.Stop(5 * time.Second)
+
+	waitUntil(t, func() bool {
+		st, _ := d.ServiceState("ext-svc")
+		return st.Health == health.StatusHealthy
+	}, 2*time.Second, "external service to become healthy")
+
+	state, _ := d.ServiceState("ext-svc")
+	if state.State != driver.StateRunning {
+		t.Errorf("expected state 'running' for healthy external service, got %q", state.State)
+	}
+}
+
 func TestDaemonExternalServiceInDeps(t *testing.T) {
 	dir := t.TempDir()
 	writeSpec(t, dir, "ext.yaml", `
@@ -823,7 +1425,7 @@ dependencies:
 	time.Sleep(100 * time.Millisecond)
 
 	// Stopping db should cascade to api and web via requires
-	if err := d.StopService("db", 5*time.Second); err != nil {
+	if err := d.StopService(context.Background(), "db", 5*time.Second); err != nil {
 		t.Fatalf("StopService(db): %v", err)
 	}
 
@@ -838,6 +1440,12 @@ dependencies:
 		if state.State == "running" {
 			t.Errorf("expected %s to be stopped after cascade, got %s", name, state.State)
 		}
+		if state.StateReason != ReasonDependencyStopped {
+			t.Errorf("expected %s reason %q, got %q", name, ReasonDependencyStopped, state.StateReason)
+		}
+	}
+	if state, _ := d.ServiceState("db"); state.StateReason != ReasonManualStop {
+		t.Errorf("expected db reason %q, got %q", ReasonManualStop, state.StateReason)
 	}
 
 	// Clean up
@@ -922,32 +1530,378 @@ dependencies:
 	}
 }
 
-func TestDaemonStartSkipsHealthWaitForLeafService(t *testing.T) {
-	// A service with a health check but NO dependents should not be health-waited.
-	// This test verifies startup completes quickly even if the health check would fail.
-	dir := t.TempDir()
-
-	writeSpec(t, dir, "leaf.yaml", `
+func TestDaemonStartDependencyConditions(t *testing.T) {
+	// db never becomes healthy, so a healthy gate holds startup for the
+	// full health wait (10 attempts at 100ms) while a started gate does not.
+	tests := []struct {
+		condition string
+		slow      bool
+	}{
+		{spec.ConditionStarted, false},
+		{spec.ConditionHealthy, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.condition, func(t *testing.T) {
+			dir := t.TempDir()
+			writeSpec(t, dir, "db.yaml", `
 service:
-  name: leaf
+  name: db
   type: native
   command: "sleep 10"
 
 health:
-  type: http
-  path: /health
-  port: 19996
+  type: exec
+  command: "false"
   interval: 100ms
-  timeout: 100ms
-  grace_period: 1s
-  unhealthy_threshold: 3
+  timeout: 500ms
+  grace_period: 0s
+  unhealthy_threshold: 1
 `)
+			writeSpec(t, dir, "app.yaml", fmt.Sprintf(`
+service:
+  name: app
+  type: native
+  command: "sleep 10"
 
-	d := NewDaemon(dir)
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
-	start := time.Now()
+dependencies:
+  after: [db]
+  requires: [db]
+  conditions:
+    db: %s
+`, tt.condition))
+
+			d := NewDaemon(dir)
+			start := time.Now()
+			if err := d.Start(context.Background()); err != nil {
+				t.Fatalf("Start: %v", err)
+			}
+			elapsed := time.Since(start)
+			defer d.Stop(5 * time.Second)
+
+			if tt.slow && elapsed < 900*time.Millisecond {
+				t.Errorf("expected startup to wait for db health, took %v", elapsed)
+			}
+			if !tt.slow && elapsed > 700*time.Millisecond {
+				t.Errorf("expected startup not to wait for db health, took %v", elapsed)
+			}
+
+			waitUntil(t, func() bool {
+				st, _ := d.ServiceState("app")
+				return st.State == driver.StateRunning
+			}, 2*time.Second, "app to be running")
+		})
+	}
+}
+
+func TestDaemonStartWithoutHealthWait(t *testing.T) {
+	// db's long grace period would hold startup for 30s if its health
+	// were waited on
+	dir := t.TempDir()
+	writeSpec(t, dir, "db.yaml", `
+service:
+  name: db
+  type: native
+  command: "sleep 10"
+
+health:
+  type: exec
+  command: "false"
+  interval: 100ms
+  timeout: 500ms
+  grace_period: 30s
+`)
+	writeSpec(t, dir, "app.yaml", `
+service:
+  name: app
+  type: native
+  command: "sleep 10"
+
+dependencies:
+  after: [db]
+  requires: [db]
+`)
+
+	d := NewDaemon(dir, WithStartupHealthWait(false))
+	start := time.Now()
+	if err := d.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	elapsed := time.Since(start)
+	defer d.Stop(5 * time.Second)
+
+	if elapsed > 2*time.Second {
+		t.Errorf("expected startup not to wait for db health, took %v", elapsed)
+	}
+	waitUntil(t, func() bool {
+		st, _ := d.ServiceState("app")
+		return st.State == driver.StateRunning
+	}, 2*time.Second, "app to be running")
+}
+
+func TestDaemonStrictSpecs(t *testing.T) {
+	dir := t.TempDir()
+	writeSpec(t, dir, "web.yaml", `
+service:
+  name: web
+  type: native
+  command: "sleep 10"
+  future_option: true
+`)
+
+	d := NewDaemon(dir, WithStateDir(t.TempDir()))
+	err := d.Start(context.Background())
+	if err == nil {
+		d.Stop(5 * time.Second)
+		t.Fatal("expected Start to reject the unknown field")
+	}
+	if !strings.Contains(err.Error(), `unknown field "future_option"`) {
+		t.Errorf("expected unknown field error, got: %v", err)
+	}
+
+	d = NewDaemon(dir, WithStateDir(t.TempDir()), WithStrictSpecs(false))
+	if err := d.Start(context.Background()); err != nil {
+		t.Fatalf("Start with strict specs disabled: %v", err)
+	}
+	defer d.Stop(5 * time.Second)
+	if _, err := d.ServiceState("web"); err != nil {
+		t.Errorf("expected web to be loaded: %v", err)
+	}
+}
+
+func TestDaemonStartRejectsHealthyConditionWithoutHealthCheck(t *testing.T) {
+	dir := t.TempDir()
+	writeSpec(t, dir, "db.yaml", `
+service:
+  name: db
+  type: native
+  command: "sleep 10"
+`)
+	writeSpec(t, dir, "app.yaml", `
+service:
+  name: app
+  type: native
+  command: "sleep 10"
+
+dependencies:
+  after: [db]
+  conditions:
+    db: healthy
+`)
+
+	d := NewDaemon(dir)
+	if err := d.Start(context.Background()); err == nil {
+		d.Stop(5 * time.Second)
+		t.Fatal("expected Start to reject a healthy condition on a service without a health check")
+	}
+}
+
+func TestDaemonStartWaitsForExternalDependency(t *testing.T) {
+	// db comes up 1.5s into startup: past the 10 checks 100ms apart that a
+	// managed dependency gets, but inside the external health wait
+	for _, tc := range []struct {
+		name     string
+		wait     time.Duration
+		wantWait bool
+	}{
+		{"waits", 10 * time.Second, true},
+		{"times out", 200 * time.Millisecond, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatalf("listen: %v", err)
+			}
+			dbPort := ln.Addr().(*net.TCPAddr).Port
+			ln.Close()
+
+			writeSpec(t, dir, "db.yaml", fmt.Sprintf(`
+service:
+  name: db
+  type: external
+
+health:
+  type: tcp
+  port: %d
+  interval: 100ms
+  timeout: 500ms
+  unhealthy_threshold: 1
+`, dbPort))
+			writeSpec(t, dir, "app.yaml", `
+service:
+  name: app
+  type: native
+  command: "sleep 10"
+
+dependencies:
+  after: [db]
+  requires: [db]
+`)
+
+			var dbUp atomic.Bool
+			var dbLn net.Listener
+			opened := make(chan struct{})
+			timer := time.AfterFunc(1500*time.Millisecond, func() {
+				defer close(opened)
+				l, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", dbPort))
+				if err == nil {
+					dbLn = l
+					dbUp.Store(true)
+				}
+			})
+			t.Cleanup(func() {
+				if !timer.Stop() {
+					<-opened
+				}
+				if dbLn != nil {
+					dbLn.Close()
+				}
+			})
+
+			d := NewDaemon(dir, WithStateDir(t.TempDir()), WithExternalHealthWait(tc.wait))
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			if err := d.Start(ctx); err != nil {
+				t.Fatalf("Start: %v", err)
+			}
+			defer d.Stop(5 * time.Second)
+
+			// Start returns once every level has started
+			if dbUp.Load() != tc.wantWait {
+				t.Errorf("db up when app started = %v, want %v", dbUp.Load(), tc.wantWait)
+			}
+			waitUntil(t, func() bool {
+				st, _ := d.ServiceState("app")
+				return st.State == driver.StateRunning
+			}, 3*time.Second, "app to be running")
+		})
+	}
+}
+
+func TestDaemonDependentDegradedWhenRequirementUnhealthy(t *testing.T) {
+	dir := t.TempDir()
+
+	var dbHealthy atomic.Bool
+	dbHealthy.Store(true)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !dbHealthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})}
+	go srv.Serve(ln)
+	t.Cleanup(func() { srv.Close() })
+
+	writeSpec(t, dir, "db.yaml", fmt.Sprintf(`
+service:
+  name: db
+  type: external
+
+health:
+  type: http
+  path: /health
+  port: %d
+  interval: 100ms
+  timeout: 500ms
+  grace_period: 0s
+  unhealthy_threshold: 1
+`, ln.Addr().(*net.TCPAddr).Port))
+	writeSpec(t, dir, "app.yaml", `
+service:
+  name: app
+  type: native
+  command: "sleep 10"
+
+health:
+  type: exec
+  command: "true"
+  interval: 100ms
+  timeout: 1s
+  grace_period: 0s
+
+dependencies:
+  after: [db]
+  requires: [db]
+`)
+
+	d := NewDaemon(dir)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := d.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer d.Stop(5 * time.Second)
+
+	waitUntil(t, func() bool {
+		st, _ := d.ServiceState("app")
+		return st.Health == health.StatusHealthy
+	}, 3*time.Second, "app to become healthy")
+
+	st, _ := d.ServiceState("app")
+	if st.Degraded {
+		t.Error("expected app not degraded while db is healthy")
+	}
+	if len(st.DependencyHealth) != 1 || st.DependencyHealth[0].Name != "db" {
+		t.Fatalf("expected dependency health for db, got %+v", st.DependencyHealth)
+	}
+
+	dbHealthy.Store(false)
+	waitUntil(t, func() bool {
+		st, _ := d.ServiceState("app")
+		return st.Degraded
+	}, 3*time.Second, "app to be marked degraded")
+
+	st, _ = d.ServiceState("app")
+	if st.DependencyHealth[0].Health != health.StatusUnhealthy {
+		t.Errorf("expected db unhealthy, got %q", st.DependencyHealth[0].Health)
+	}
+	if st.Health != health.StatusHealthy || st.State != driver.StateRunning {
+		t.Errorf("expected app itself healthy and running, got %s/%s", st.State, st.Health)
+	}
+	if st.RestartCount != 0 {
+		t.Errorf("expected app not restarted, got %d restarts", st.RestartCount)
+	}
+
+	// Services without hard dependencies carry no dependency health.
+	dbState, _ := d.ServiceState("db")
+	if dbState.Degraded || dbState.DependencyHealth != nil {
+		t.Errorf("expected no dependency health on db, got %+v", dbState.DependencyHealth)
+	}
+}
+
+func TestDaemonStartSkipsHealthWaitForLeafService(t *testing.T) {
+	// A service with a health check but NO dependents should not be health-waited.
+	// This test verifies startup completes quickly even if the health check would fail.
+	dir := t.TempDir()
+
+	writeSpec(t, dir, "leaf.yaml", `
+service:
+  name: leaf
+  type: native
+  command: "sleep 10"
+
+health:
+  type: http
+  path: /health
+  port: 19996
+  interval: 100ms
+  timeout: 100ms
+  grace_period: 1s
+  unhealthy_threshold: 3
+`)
+
+	d := NewDaemon(dir)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	start := time.Now()
 	if err := d.Start(ctx); err != nil {
 		t.Fatalf("Start: %v", err)
 	}
@@ -961,6 +1915,118 @@ health:
 	}
 }
 
+// writeDiamondSpecs writes root -> {left, right} -> top, where left and right
+// are required by top and so are health-waited (1s grace) during startup.
+func writeDiamondSpecs(t *testing.T, dir string) {
+	t.Helper()
+	writeSpec(t, dir, "root.yaml", `
+service:
+  name: root
+  type: native
+  command: "sleep 10"
+`)
+	for _, name := range []string{"left", "right"} {
+		writeSpec(t, dir, name+".yaml", fmt.Sprintf(`
+service:
+  name: %s
+  type: native
+  command: "sleep 10"
+
+health:
+  type: exec
+  command: "true"
+  interval: 100ms
+  timeout: 500ms
+  grace_period: 1s
+
+dependencies:
+  after: [root]
+`, name))
+	}
+	writeSpec(t, dir, "top.yaml", `
+service:
+  name: top
+  type: native
+  command: "sleep 10"
+
+dependencies:
+  after: [left, right]
+  requires: [left, right]
+`)
+}
+
+func startedAt(t *testing.T, d *Daemon, name string) time.Time {
+	t.Helper()
+	ms, err := d.getService(name)
+	if err != nil {
+		t.Fatalf("getService(%s): %v", name, err)
+	}
+	var started time.Time
+	waitUntil(t, func() bool {
+		ms.mu.Lock()
+		defer ms.mu.Unlock()
+		if ms.drv == nil {
+			return false
+		}
+		started = ms.drv.Info().StartedAt
+		return !started.IsZero()
+	}, 2*time.Second, name+" never started")
+	return started
+}
+
+func TestDaemonStartParallelLevels(t *testing.T) {
+	dir := t.TempDir()
+	writeDiamondSpecs(t, dir)
+
+	d := NewDaemon(dir, WithMaxParallelStarts(4))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	start := time.Now()
+	if err := d.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer d.Stop(5 * time.Second)
+	elapsed := time.Since(start)
+
+	// left and right each wait out a 1s grace period. Sequentially that is
+	// at least 2s; concurrently it is roughly 1s.
+	if elapsed >= 1800*time.Millisecond {
+		t.Errorf("startup took %v — independent services should start concurrently", elapsed)
+	}
+
+	left, right, top := startedAt(t, d, "left"), startedAt(t, d, "right"), startedAt(t, d, "top")
+	if diff := left.Sub(right).Abs(); diff > 500*time.Millisecond {
+		t.Errorf("left and right started %v apart, expected concurrent start", diff)
+	}
+	// top waits out its dependencies' 1s grace periods. The start times are
+	// taken after each process is spawned, so allow for the spawn taking
+	// longer for left and right than for top.
+	const slack = 200 * time.Millisecond
+	if top.Sub(left) < time.Second-slack || top.Sub(right) < time.Second-slack {
+		t.Errorf("top started before its dependencies were healthy (left=%v right=%v top=%v)", left, right, top)
+	}
+}
+
+func TestDaemonStartSequentialWhenLimitOne(t *testing.T) {
+	dir := t.TempDir()
+	writeDiamondSpecs(t, dir)
+
+	d := NewDaemon(dir, WithMaxParallelStarts(1))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	start := time.Now()
+	if err := d.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer d.Stop(5 * time.Second)
+
+	if elapsed := time.Since(start); elapsed < 2*time.Second {
+		t.Errorf("startup took %v — with max_parallel_starts=1 grace periods should run back to back", elapsed)
+	}
+}
+
 func TestDaemonStopServiceNotFound(t *testing.T) {
 	dir := t.TempDir()
 	writeSpec(t, dir, "echo.yaml", `
@@ -979,7 +2045,7 @@ service:
 	}
 	defer d.Stop(5 * time.Second)
 
-	err := d.StopService("nonexistent", 5*time.Second)
+	err := d.StopService(context.Background(), "nonexistent", 5*time.Second)
 	if err == nil {
 		t.Fatal("expected error for nonexistent service")
 	}
@@ -1724,3 +2790,840 @@ network:
 		t.Errorf("expected port %d to be free after killOrphanOnPort, still held by PID %d", port, pid)
 	}
 }
+
+func TestDaemonWaitForReadyHealthy(t *testing.T) {
+	dir := t.TempDir()
+	writeSpec(t, dir, "app.yaml", `
+service:
+  name: app
+  type: native
+  command: "sleep 10"
+
+health:
+  type: exec
+  command: "true"
+  interval: 100ms
+  timeout: 1s
+  grace_period: 200ms
+`)
+
+	d := NewDaemon(dir)
+	if err := d.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer d.Stop(5 * time.Second)
+
+	if err := d.RestartService(context.Background(), "app", 5*time.Second); err != nil {
+		t.Fatalf("RestartService: %v", err)
+	}
+	st, err := d.WaitForReady(context.Background(), "app", 5*time.Second)
+	if err != nil {
+		t.Fatalf("WaitForReady: %v", err)
+	}
+	if st.State != driver.StateRunning || st.Health != health.StatusHealthy {
+		t.Errorf("expected running and healthy, got %s/%s", st.State, st.Health)
+	}
+}
+
+func TestDaemonWaitForReadyTimesOut(t *testing.T) {
+	dir := t.TempDir()
+	writeSpec(t, dir, "app.yaml", `
+service:
+  name: app
+  type: native
+  command: "sleep 10"
+
+health:
+  type: exec
+  command: "false"
+  interval: 100ms
+  timeout: 1s
+  grace_period: 0s
+`)
+
+	d := NewDaemon(dir)
+	if err := d.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer d.Stop(5 * time.Second)
+
+	start := time.Now()
+	st, err := d.WaitForReady(context.Background(), "app", 300*time.Millisecond)
+	if err == nil {
+		t.Fatal("expected timeout for a service that never becomes healthy")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("timeout not enforced, waited %v", elapsed)
+	}
+	if st.Name != "app" || st.Health == health.StatusHealthy {
+		t.Errorf("expected last unhealthy state of app, got %+v", st)
+	}
+
+	if _, err := d.WaitForReady(context.Background(), "missing", time.Second); err == nil {
+		t.Error("expected error for unknown service")
+	}
+}
+
+func TestDaemonWaitForReadyFailsFastWhenDown(t *testing.T) {
+	dir := t.TempDir()
+	writeSpec(t, dir, "app.yaml", `
+service:
+  name: app
+  type: native
+  command: "sh -c 'exit 3'"
+
+restart:
+  policy: never
+`)
+
+	d := NewDaemon(dir)
+	if err := d.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer d.Stop(5 * time.Second)
+
+	start := time.Now()
+	st, err := d.WaitForReady(context.Background(), "app", 10*time.Second)
+	if !errors.Is(err, ErrServiceDown) {
+		t.Fatalf("expected ErrServiceDown, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("waited %v for a service that had already failed", elapsed)
+	}
+	if st.StateReason != ReasonRestartNever {
+		t.Errorf("expected reason %s, got %+v", ReasonRestartNever, st)
+	}
+}
+
+func TestDaemonUnlessStoppedStaysStopped(t *testing.T) {
+	dir := t.TempDir()
+	stateDir := t.TempDir()
+	writeSpec(t, dir, "svc.yaml", `
+service:
+  name: svc
+  type: native
+  command: "sleep 30"
+
+restart:
+  policy: unless-stopped
+`)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	d := NewDaemon(dir, WithStateDir(stateDir))
+	if err := d.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if err := d.StopService(context.Background(), "svc", 5*time.Second); err != nil {
+		t.Fatalf("StopService: %v", err)
+	}
+
+	// A spec change picked up by reload must not bring it back
+	writeSpec(t, dir, "svc.yaml", `
+service:
+  name: svc
+  type: native
+  command: "sleep 31"
+
+restart:
+  policy: unless-stopped
+`)
+	if _, err := d.Reload(ctx); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if st, _ := d.ServiceState("svc"); st.State != driver.StateStopped {
+		t.Errorf("expected stopped after reload, got %v", st.State)
+	}
+
+	// Nor must a daemon restart
+	d.Stop(5 * time.Second)
+	d = NewDaemon(dir, WithStateDir(stateDir))
+	if err := d.Start(ctx); err != nil {
+		t.Fatalf("Start after restart: %v", err)
+	}
+	defer d.Stop(5 * time.Second)
+
+	st, err := d.ServiceState("svc")
+	if err != nil {
+		t.Fatalf("ServiceState: %v", err)
+	}
+	if st.State != driver.StateStopped {
+		t.Errorf("expected stopped after daemon restart, got %v", st.State)
+	}
+
+	// An explicit start clears the flag
+	if err := d.StartService(ctx, "svc"); err != nil {
+		t.Fatalf("StartService: %v", err)
+	}
+	waitUntil(t, func() bool {
+		st, _ := d.ServiceState("svc")
+		return st.State == driver.StateRunning
+	}, 2*time.Second, "service to run after explicit start")
+	if d.state.manuallyStopped("svc") {
+		t.Error("expected manual stop flag to be cleared by StartService")
+	}
+}
+
+func TestDaemonRestartServiceWithDeps(t *testing.T) {
+	dir := t.TempDir()
+	writeSpec(t, dir, "db.yaml", `
+service:
+  name: db
+  type: native
+  command: "sleep 10"
+
+health:
+  type: exec
+  command: "true"
+  interval: 100ms
+  timeout: 500ms
+`)
+	writeSpec(t, dir, "api.yaml", `
+service:
+  name: api
+  type: native
+  command: "sleep 10"
+
+dependencies:
+  after: [db]
+  requires: [db]
+`)
+	writeSpec(t, dir, "web.yaml", `
+service:
+  name: web
+  type: native
+  command: "sleep 10"
+
+dependencies:
+  after: [api]
+  requires: [api]
+`)
+	writeSpec(t, dir, "idle.yaml", `
+service:
+  name: idle
+  type: native
+  command: "sleep 10"
+
+dependencies:
+  after: [db]
+  requires: [db]
+`)
+
+	d := NewDaemon(dir)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := d.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer d.Stop(5 * time.Second)
+
+	for _, name := range []string{"api", "web", "idle"} {
+		waitUntil(t, func() bool {
+			st, _ := d.ServiceState(name)
+			return st.State == driver.StateRunning
+		}, 2*time.Second, name+" to be running")
+	}
+	// A dependent that was stopped beforehand must stay stopped
+	if err := d.StopService(context.Background(), "idle", 5*time.Second); err != nil {
+		t.Fatalf("StopService(idle): %v", err)
+	}
+	pids := make(map[string]int)
+	for _, name := range []string{"db", "api", "web"} {
+		st, _ := d.ServiceState(name)
+		pids[name] = st.PID
+	}
+	mark := len(d.Events())
+
+	if err := d.RestartServiceWithDeps(context.Background(), "db", 5*time.Second); err != nil {
+		t.Fatalf("RestartServiceWithDeps: %v", err)
+	}
+
+	for _, name := range []string{"db", "api", "web"} {
+		st, _ := d.ServiceState(name)
+		if st.State != driver.StateRunning {
+			t.Errorf("expected %s running after restart, got %s", name, st.State)
+		}
+		if st.PID == pids[name] {
+			t.Errorf("expected %s to be restarted, PID unchanged (%d)", name, st.PID)
+		}
+	}
+	if st, _ := d.ServiceState("idle"); st.State == driver.StateRunning {
+		t.Error("idle was stopped before the restart and should stay stopped")
+	}
+
+	// db must be healthy before api starts, and api up before web starts
+	pos := make(map[string]int)
+	for i, ev := range d.Events()[mark:] {
+		key := ev.Service + " " + ev.Type
+		if ev.Type == EventHealth {
+			key += " " + ev.Detail
+		}
+		if _, seen := pos[key]; !seen {
+			pos[key] = i
+		}
+	}
+	order := []string{"db started", "db health healthy", "api started", "web started"}
+	for i, key := range order {
+		if _, ok := pos[key]; !ok {
+			t.Fatalf("missing %q event after restart", key)
+		}
+		if i > 0 && pos[order[i-1]] > pos[key] {
+			t.Errorf("expected %q before %q", order[i-1], key)
+		}
+	}
+}
+
+// fakeGPU reports a fixed amount of allocated VRAM until changed.
+type fakeGPU struct {
+	mu        sync.Mutex
+	allocated uint64
+}
+
+func (f *fakeGPU) Info() gpu.Info {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return gpu.Info{Name: "fake", AllocatedBytes: f.allocated, RecommendedMax: 16 << 30}
+}
+
+func (f *fakeGPU) setAllocated(n uint64) {
+	f.mu.Lock()
+	f.allocated = n
+	f.mu.Unlock()
+}
+
+func TestDaemonWaitsForVRAM(t *testing.T) {
+	oldMin, oldMax := vramRetryMin, vramRetryMax
+	vramRetryMin, vramRetryMax = 10*time.Millisecond, 20*time.Millisecond
+	t.Cleanup(func() { vramRetryMin, vramRetryMax = oldMin, oldMax })
+
+	dir := t.TempDir()
+	writeSpec(t, dir, "llm.yaml", `
+service:
+  name: llm
+  type: native
+  command: "sleep 10"
+
+gpu:
+  requires_vram: 8GB
+`)
+
+	// 12 of 16 GB in use leaves 4 GB free
+	fake := &fakeGPU{allocated: 12 << 30}
+	d := NewDaemon(dir, WithGPU(fake))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := d.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer d.Stop(5 * time.Second)
+
+	waitUntil(t, func() bool {
+		st, _ := d.ServiceState("llm")
+		return st.Waiting != ""
+	}, 2*time.Second, "llm to report waiting for VRAM")
+
+	st, _ := d.ServiceState("llm")
+	if st.State != driver.StateStarting || st.PID != 0 {
+		t.Errorf("expected llm held in starting with no process, got %s pid %d", st.State, st.PID)
+	}
+	if !strings.Contains(st.Waiting, "VRAM: 8.0 GB needed, 4.0 GB free") {
+		t.Errorf("unexpected waiting reason %q", st.Waiting)
+	}
+	// Still held back after several rechecks
+	time.Sleep(100 * time.Millisecond)
+	if st, _ := d.ServiceState("llm"); st.PID != 0 {
+		t.Fatalf("llm started without enough VRAM (pid %d)", st.PID)
+	}
+
+	fake.setAllocated(6 << 30)
+	waitUntil(t, func() bool {
+		st, _ := d.ServiceState("llm")
+		return st.State == driver.StateRunning && st.PID > 0
+	}, 2*time.Second, "llm to start once VRAM is free")
+	if st, _ := d.ServiceState("llm"); st.Waiting != "" {
+		t.Errorf("expected waiting cleared after start, got %q", st.Waiting)
+	}
+
+	var waited bool
+	for _, ev := range d.Events() {
+		if ev.Service == "llm" && ev.Type == EventWaiting {
+			waited = true
+		}
+	}
+	if !waited {
+		t.Error("expected a waiting event for llm")
+	}
+}
+
+func TestDaemonWaitingForVRAMStops(t *testing.T) {
+	dir := t.TempDir()
+	writeSpec(t, dir, "llm.yaml", `
+service:
+  name: llm
+  type: native
+  command: "sleep 10"
+
+gpu:
+  requires_vram: 32GB
+`)
+
+	d := NewDaemon(dir, WithGPU(&fakeGPU{}))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := d.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer d.Stop(5 * time.Second)
+
+	waitUntil(t, func() bool {
+		st, _ := d.ServiceState("llm")
+		return st.Waiting != ""
+	}, 2*time.Second, "llm to report waiting for VRAM")
+
+	// Stopping a service that is waiting for VRAM ends the wait
+	if err := d.StopService(context.Background(), "llm", time.Second); err != nil {
+		t.Fatalf("StopService: %v", err)
+	}
+	st, _ := d.ServiceState("llm")
+	if st.State != driver.StateStopped || st.Waiting != "" {
+		t.Errorf("expected llm stopped and no longer waiting, got %s (%q)", st.State, st.Waiting)
+	}
+}
+
+func TestDaemonReportsOrphanedChildren(t *testing.T) {
+	if _, err := exec.LookPath("setsid"); err != nil {
+		t.Skip("setsid not available")
+	}
+	dir := t.TempDir()
+	script := filepath.Join(t.TempDir(), "run.sh")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\nsetsid sleep 30 &\nexec sleep 30\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeSpec(t, dir, "app.yaml", fmt.Sprintf(`
+service:
+  name: app
+  type: native
+  command: %s
+`, script))
+
+	d := NewDaemon(dir, WithStateDir(t.TempDir()))
+	if err := d.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer d.Stop(5 * time.Second)
+	time.Sleep(200 * time.Millisecond) // let the child escape
+
+	if err := d.StopService(context.Background(), "app", 5*time.Second); err != nil {
+		t.Fatalf("StopService: %v", err)
+	}
+	orphans, err := d.OrphanedChildren("app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		for _, pid := range orphans {
+			syscall.Kill(pid, syscall.SIGKILL)
+		}
+	})
+	if len(orphans) != 1 {
+		t.Fatalf("expected 1 orphaned child, got %v", orphans)
+	}
+	if st, _ := d.ServiceState("app"); len(st.OrphanedChildren) != 1 {
+		t.Errorf("expected the orphan in the service state, got %v", st.OrphanedChildren)
+	}
+
+	// Once it's gone it's no longer reported
+	syscall.Kill(orphans[0], syscall.SIGKILL)
+	waitUntil(t, func() bool {
+		orphans, _ := d.OrphanedChildren("app")
+		return len(orphans) == 0
+	}, 3*time.Second, "killed orphan to drop out")
+}
+
+func TestDaemonPersistsFailedServiceState(t *testing.T) {
+	dir := t.TempDir()
+	stateDir := t.TempDir()
+	writeSpec(t, dir, "crasher.yaml", `
+service:
+  name: crasher
+  type: native
+  command: "false"
+
+restart:
+  policy: on-failure
+  max_attempts: 2
+  delay: 10ms
+`)
+
+	d := NewDaemon(dir, WithStateDir(stateDir))
+	if err := d.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer d.Stop(5 * time.Second)
+
+	// The state file has the outcome as it would be after a daemon crash
+	sf := newStateFile(stateDir)
+	var rec ServiceRecord
+	waitUntil(t, func() bool {
+		records, _ := sf.load()
+		rec = records["crasher"]
+		return rec.StateReason != ""
+	}, 5*time.Second, "crasher's outcome in the state file")
+	if rec.State != string(driver.StateFailed) || rec.StateReason != ReasonPolicyExhausted {
+		t.Errorf("expected failed (%s) in state file, got %q (%q)", ReasonPolicyExhausted, rec.State, rec.StateReason)
+	}
+	if rec.RestartCount != 2 || rec.LastExitCode != 1 || rec.LastError == "" {
+		t.Errorf("expected 2 restarts and exit 1 with an error, got %+v", rec)
+	}
+	if rec.PID != 0 {
+		t.Errorf("expected no PID for a process that isn't running, got %d", rec.PID)
+	}
+}
+
+func TestDaemonRecoversRestartCount(t *testing.T) {
+	dir := t.TempDir()
+	stateDir := t.TempDir()
+	writeSpec(t, dir, "svc.yaml", `
+service:
+  name: svc
+  type: native
+  command: "sleep 30"
+`)
+	// Left behind by a daemon that crashed after svc had restarted 3 times
+	if err := newStateFile(stateDir).set("svc", ServiceRecord{Type: "native", State: "failed", RestartCount: 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	d := NewDaemon(dir, WithStateDir(stateDir))
+	if err := d.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer d.Stop(5 * time.Second)
+
+	st, err := d.ServiceState("svc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.RestartCount != 3 {
+		t.Errorf("expected restart count 3 carried over, got %d", st.RestartCount)
+	}
+}
+
+func TestDaemonRejectsRecycledPID(t *testing.T) {
+	dir := t.TempDir()
+	stateDir := t.TempDir()
+
+	// A copy of sleep under its own name, so the search for the original
+	// process by command can't turn up some other sleep on the machine
+	sleepPath, err := exec.LookPath("sleep")
+	if err != nil {
+		t.Skip("sleep not available")
+	}
+	data, err := os.ReadFile(sleepPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bin := filepath.Join(t.TempDir(), "recyclesleep")
+	if err := os.WriteFile(bin, data, 0755); err != nil {
+		t.Fatal(err)
+	}
+	command := bin + " 300"
+	writeSpec(t, dir, "sleeper.yaml", fmt.Sprintf(`
+service:
+  name: sleeper
+  type: native
+  command: %s
+`, command))
+
+	// A process running the same command now holds the recorded PID, but it
+	// started later than the one the previous daemon was managing
+	cmd := exec.Command(bin, "300")
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("starting sleep process: %v", err)
+	}
+	go cmd.Wait()
+	t.Cleanup(func() { cmd.Process.Kill() })
+	recycledPID := cmd.Process.Pid
+	startTime, err := driver.ProcessStartTime(recycledPID)
+	if err != nil {
+		t.Fatalf("ProcessStartTime: %v", err)
+	}
+
+	if err := newStateFile(stateDir).set("sleeper", ServiceRecord{
+		Type:      "native",
+		PID:       recycledPID,
+		Command:   command,
+		StartTime: startTime - 60,
+	}); err != nil {
+		t.Fatalf("writing state: %v", err)
+	}
+
+	d := NewDaemon(dir, WithStateDir(stateDir))
+	if err := d.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer d.Stop(5 * time.Second)
+
+	if len(d.adopted) != 0 {
+		t.Errorf("expected the recycled PID not to be adopted, adopted %v", d.adopted)
+	}
+	waitUntil(t, func() bool {
+		st, _ := d.ServiceState("sleeper")
+		return st.State == driver.StateRunning
+	}, 3*time.Second, "sleeper to be started fresh")
+	if st, _ := d.ServiceState("sleeper"); st.PID == recycledPID {
+		t.Errorf("expected a fresh process, got the recycled PID %d", st.PID)
+	}
+	if err := cmd.Process.Signal(syscall.Signal(0)); err != nil {
+		t.Errorf("the unrelated process should be left alone: %v", err)
+	}
+}
+
+func TestDaemonReplicas(t *testing.T) {
+	dir := t.TempDir()
+	routingPath := filepath.Join(t.TempDir(), "aurelia.yaml")
+
+	writeSpec(t, dir, "pool.yaml", `
+service:
+  name: pool
+  type: native
+  command: "sleep 30"
+  replicas: 2
+
+network:
+  port: 0
+
+routing:
+  hostname: pool.example.local
+`)
+
+	d := NewDaemon(dir, WithRouting(routingPath), WithPortRange(26400, 26500))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := d.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer d.Stop(5 * time.Second)
+
+	state := func() ServiceState {
+		t.Helper()
+		st, err := d.ServiceState("pool")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return st
+	}
+	running := func(st ServiceState) bool {
+		return st.State == driver.StateRunning && len(st.Replicas) == 1 && st.Replicas[0].State == driver.StateRunning
+	}
+	st := state()
+	for deadline := time.Now().Add(5 * time.Second); !running(st); st = state() {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected both replicas running, got %+v", st)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	if len(st.Replicas) != 1 || st.Replicas[0].Name != "pool.2" {
+		t.Fatalf("expected one further replica named pool.2, got %+v", st.Replicas)
+	}
+	ports := []int{st.Port, st.Replicas[0].Port}
+	if ports[0] == 0 || ports[1] == 0 || ports[0] == ports[1] {
+		t.Fatalf("expected two distinct allocated ports, got %v", ports)
+	}
+	if st.PID == st.Replicas[0].PID {
+		t.Errorf("expected separate processes, both have PID %d", st.PID)
+	}
+
+	routed := func() bool {
+		data, _ := os.ReadFile(routingPath)
+		for _, p := range ports {
+			if !strings.Contains(string(data), fmt.Sprintf("http://127.0.0.1:%d", p)) {
+				return false
+			}
+		}
+		return true
+	}
+	for deadline := time.Now().Add(5 * time.Second); !routed(); time.Sleep(50 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			data, _ := os.ReadFile(routingPath)
+			t.Fatalf("expected both ports %v as backends, got:\n%s", ports, data)
+		}
+	}
+
+	// A rolling restart replaces each process but keeps the ports
+	if err := d.RollingRestartService(ctx, "pool", 5*time.Second); err != nil {
+		t.Fatalf("RollingRestartService: %v", err)
+	}
+	after := state()
+	if !running(after) {
+		t.Fatalf("expected both replicas running after the roll, got %+v", after)
+	}
+	if after.PID == st.PID || after.Replicas[0].PID == st.Replicas[0].PID {
+		t.Errorf("expected new processes, PIDs before %d/%d after %d/%d", st.PID, st.Replicas[0].PID, after.PID, after.Replicas[0].PID)
+	}
+	if after.Port != ports[0] || after.Replicas[0].Port != ports[1] {
+		t.Errorf("expected ports %v kept, got %d/%d", ports, after.Port, after.Replicas[0].Port)
+	}
+	if !routed() {
+		t.Error("expected both ports still routed after the roll")
+	}
+}
+
+// fakeStats reports the same usage for every process until changed.
+type fakeStats struct {
+	mu    sync.Mutex
+	usage sysinfo.ProcessUsage
+}
+
+func (f *fakeStats) Sample(pid int) (sysinfo.ProcessUsage, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.usage, nil
+}
+
+func (f *fakeStats) setCPU(percent float64) {
+	f.mu.Lock()
+	f.usage.CPUPercent = percent
+	f.mu.Unlock()
+}
+
+func TestDesiredReplicas(t *testing.T) {
+	t.Parallel()
+	cpu := &spec.Autoscale{Min: 1, Max: 5, TargetCPUPercent: 50}
+	both := &spec.Autoscale{Min: 2, Max: 8, TargetCPUPercent: 50, TargetMemory: 100 << 20}
+
+	tests := []struct {
+		name    string
+		a       *spec.Autoscale
+		current int
+		cpu     float64
+		mem     int64
+		want    int
+	}{
+		{"above target", cpu, 2, 100, 0, 4},
+		{"below target", cpu, 4, 20, 0, 2},
+		{"within tolerance", cpu, 3, 54, 0, 3},
+		{"capped at max", cpu, 3, 400, 0, 5},
+		{"floored at min", cpu, 2, 0, 0, 1},
+		{"memory busier", both, 2, 25, 300 << 20, 6},
+		{"cpu busier", both, 4, 75, 50 << 20, 6},
+		{"both idle", both, 4, 5, 10 << 20, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := desiredReplicas(tt.a, tt.current, tt.cpu, tt.mem); got != tt.want {
+				t.Errorf("desiredReplicas(%d, cpu %.0f, mem %d) = %d, want %d", tt.current, tt.cpu, tt.mem, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDaemonAutoscale(t *testing.T) {
+	dir := t.TempDir()
+	routingPath := filepath.Join(t.TempDir(), "aurelia.yaml")
+
+	writeSpec(t, dir, "pool.yaml", `
+service:
+  name: pool
+  type: native
+  command: "sleep 30"
+
+network:
+  port: 0
+
+routing:
+  hostname: pool.example.local
+
+autoscale:
+  min: 1
+  max: 3
+  target_cpu_percent: 50
+  cooldown: 1m
+`)
+
+	stats := &fakeStats{}
+	d := NewDaemon(dir, WithStatsSource(stats), WithAutoscaleInterval(time.Hour),
+		WithRouting(routingPath), WithPortRange(26500, 26600))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := d.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer d.Stop(5 * time.Second)
+
+	// running reports whether n instances are running
+	running := func(n int) bool {
+		st, err := d.ServiceState("pool")
+		if err != nil || st.State != driver.StateRunning || len(st.Replicas) != n-1 {
+			return false
+		}
+		for _, r := range st.Replicas {
+			if r.State != driver.StateRunning {
+				return false
+			}
+		}
+		return true
+	}
+	instances := func() int {
+		st, _ := d.ServiceState("pool")
+		return len(st.Replicas) + 1
+	}
+	waitUntil(t, func() bool { return running(1) }, 5*time.Second, "pool to start with autoscale.min replicas")
+
+	// The first look starts the cooldown, and nothing changes within it
+	stats.setCPU(200)
+	t0 := time.Now()
+	d.autoscale(t0)
+	d.autoscale(t0.Add(30 * time.Second))
+	if n := instances(); n != 1 {
+		t.Fatalf("expected no scaling within the cooldown, got %d instances", n)
+	}
+
+	// Above target scales up, capped at max
+	d.autoscale(t0.Add(time.Minute))
+	waitUntil(t, func() bool { return running(3) }, 5*time.Second, "pool to scale up to autoscale.max")
+	st, _ := d.ServiceState("pool")
+	ports := []int{st.Port, st.Replicas[0].Port, st.Replicas[1].Port}
+	waitUntil(t, func() bool {
+		data, _ := os.ReadFile(routingPath)
+		for _, p := range ports {
+			if !strings.Contains(string(data), fmt.Sprintf("http://127.0.0.1:%d", p)) {
+				return false
+			}
+		}
+		return true
+	}, 5*time.Second, "every replica routed")
+
+	// Below target scales down, but not within the cooldown of scaling up
+	stats.setCPU(10)
+	d.autoscale(t0.Add(90 * time.Second))
+	if n := instances(); n != 3 {
+		t.Fatalf("expected no scaling within the cooldown, got %d instances", n)
+	}
+	d.autoscale(t0.Add(2 * time.Minute))
+	if n := instances(); n != 1 {
+		t.Fatalf("expected scale down to 1 instance, got %d", n)
+	}
+	if !running(1) {
+		t.Error("expected the remaining instance to keep running")
+	}
+
+	// Idle stays at min
+	stats.setCPU(0)
+	d.autoscale(t0.Add(5 * time.Minute))
+	if n := instances(); n != 1 {
+		t.Errorf("expected autoscale.min to be kept, got %d instances", n)
+	}
+
+	var scaled []string
+	for _, e := range d.Events() {
+		if e.Service == "pool" && e.Type == EventScaled {
+			scaled = append(scaled, e.Detail)
+		}
+	}
+	if len(scaled) != 2 || !strings.HasPrefix(scaled[0], "1 -> 3 replicas") || !strings.HasPrefix(scaled[1], "3 -> 1 replicas") {
+		t.Errorf("unexpected scaled events %q", scaled)
+	}
+}
@@ -2,11 +2,14 @@ package daemon
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
+	"github.com/benaskins/aurelia/internal/audit"
 	"github.com/benaskins/aurelia/internal/driver"
 	"github.com/benaskins/aurelia/internal/health"
+	"github.com/benaskins/aurelia/internal/spec"
 )
 
 const (
@@ -21,17 +24,24 @@ const (
 // It starts a new instance on a temporary port, verifies health, switches routing,
 // drains the old instance, then promotes the new one.
 // For services without routing config, it falls back to restart behavior.
-func (d *Daemon) DeployService(name string, drainTimeout time.Duration) error {
-	ms, err := d.getService(name)
+// The deploy is audited as the actor in ctx.
+func (d *Daemon) DeployService(ctx context.Context, name string, drainTimeout time.Duration) error {
+	err := d.deployService(name, drainTimeout)
+	d.audit(ctx, audit.ActionServiceDeploy, name, err)
+	return err
+}
+
+func (d *Daemon) deployService(name string, drainTimeout time.Duration) error {
+	if err := d.checkMaintenance(); err != nil {
+		return err
+	}
+	ms, err := d.getEnabledService(name)
 	if err != nil {
 		return err
 	}
 
-	// Concurrent deploy guard: reject if a deploy is already in progress.
-	// The "__" separator is safe because service names are validated against
-	// ^[a-zA-Z0-9][a-zA-Z0-9._-]{0,63}$ — underscores are not permitted.
-	if existing := d.ports.Port(name + "__" + deploySuffix); existing != 0 {
-		return fmt.Errorf("deploy already in progress for %q (temp port %d)", name, existing)
+	if err := d.checkNoDeployInProgress(name); err != nil {
+		return err
 	}
 
 	// For services without routing, fall back to restart.
@@ -42,7 +52,8 @@ func (d *Daemon) DeployService(name string, drainTimeout time.Duration) error {
 		if ms.spec.NeedsDynamicPort() {
 			d.ports.Release(name)
 		}
-		return d.RestartService(name, DefaultStopTimeout)
+		d.publishDeploy(name, DeployStepRestarting, 0, 0)
+		return d.restartService(name, DefaultStopTimeout, false)
 	}
 
 	// Services with a fixed port cannot use blue-green deploy — the new
@@ -50,10 +61,54 @@ func (d *Daemon) DeployService(name string, drainTimeout time.Duration) error {
 	// restart, which stops the old instance first.
 	if !ms.spec.NeedsDynamicPort() {
 		d.logger.Info("fixed port service, falling back to restart", "service", name)
-		return d.RestartService(name, DefaultStopTimeout)
+		d.publishDeploy(name, DeployStepRestarting, 0, 0)
+		return d.restartService(name, DefaultStopTimeout, false)
+	}
+
+	// Replicated services roll instead: the replicas still up keep serving
+	// while each is restarted in turn.
+	if ms.spec.MaxReplicas() > 1 {
+		d.logger.Info("replicated service, rolling restart", "service", name)
+		d.publishDeploy(name, DeployStepRestarting, 0, 0)
+		return d.rollingRestart(name, DefaultStopTimeout)
 	}
 
 	d.logger.Info("starting blue-green deploy", "service", name)
+	return d.blueGreenDeploy(name, ms, drainTimeout)
+}
+
+// ErrDeployInProgress is returned when a deploy is requested for a service
+// that is already being deployed.
+var ErrDeployInProgress = errors.New("deploy already in progress")
+
+// checkNoDeployInProgress rejects a deploy while another one is in progress.
+// The "__" separator is safe because service names are validated against
+// ^[a-zA-Z0-9][a-zA-Z0-9._-]{0,63}$ — underscores are not permitted.
+func (d *Daemon) checkNoDeployInProgress(name string) error {
+	if existing := d.ports.Port(name + "__" + deploySuffix); existing != 0 {
+		return fmt.Errorf("%w for %q (temp port %d)", ErrDeployInProgress, name, existing)
+	}
+	return nil
+}
+
+// blueGreenDeploy starts a new instance from ms's spec alongside the running
+// one, switches routing to it and drains the old instance. The record of the
+// replaced instance is kept as the previous deploy for rollback.
+func (d *Daemon) blueGreenDeploy(name string, ms *ManagedService, drainTimeout time.Duration) (err error) {
+	d.recordEvent(name, EventDeploying, "")
+	defer func() {
+		if err != nil {
+			d.recordEvent(name, EventDeployFailed, err.Error())
+		}
+	}()
+
+	var previous *ServiceRecord
+	if records, err := d.state.load(); err == nil {
+		if rec, ok := records[name]; ok && !rec.ManuallyStopped {
+			rec.Previous = nil
+			previous = &rec
+		}
+	}
 
 	// Step 1: Allocate temporary port and start new instance
 	tempPort, newDrv, err := d.deployStartNew(name, ms)
@@ -73,12 +128,17 @@ func (d *Daemon) DeployService(name string, drainTimeout time.Duration) error {
 		rollback()
 		return err
 	}
+	d.publishDeploy(name, DeployStepHealthy, tempPort, newDrv.Info().PID)
+	if err := ms.runPostStart(d.ctx, newDrv, nil, tempPort); err != nil {
+		rollback()
+		return fmt.Errorf("new instance: %w", err)
+	}
 
 	// Step 3: Switch routing and drain old instance
 	d.deployDrainOld(name, tempPort, drainTimeout)
 
 	// Step 4: Promote new instance and clean up
-	return d.deployPromote(name, ms, tempPort, newDrv)
+	return d.deployPromote(name, ms, tempPort, newDrv, previous)
 }
 
 // deployStartNew allocates a temporary port and starts the new process.
@@ -88,21 +148,45 @@ func (d *Daemon) deployStartNew(name string, ms *ManagedService) (int, driver.Dr
 		return 0, nil, fmt.Errorf("allocating temporary port: %w", err)
 	}
 	d.logger.Info("allocated deploy port", "service", name, "port", tempPort)
+	d.publishDeploy(name, DeployStepPortAllocated, tempPort, 0)
 
-	newDrv := ms.createDriverWithPort(tempPort)
+	newDrv := ms.createDriverWithPort(tempPort, d.deployContainerName(name))
 	if err := newDrv.Start(d.ctx); err != nil {
 		d.ports.ReleaseTemporary(name, deploySuffix)
 		return 0, nil, fmt.Errorf("starting new instance: %w", err)
 	}
 	d.logger.Info("new instance started", "service", name, "port", tempPort, "pid", newDrv.Info().PID)
+	d.publishDeploy(name, DeployStepStarted, tempPort, newDrv.Info().PID)
 
 	return tempPort, newDrv, nil
 }
 
+// deployContainerName picks the container name for a deploy's new instance.
+// It alternates between "<name>-deploy" and "<name>" so that a service already
+// running from an earlier deploy isn't replaced by name before it is drained.
+func (d *Daemon) deployContainerName(name string) string {
+	deployName := name + "-deploy"
+
+	d.mu.RLock()
+	live := d.services[name]
+	d.mu.RUnlock()
+	if live == nil {
+		return deployName
+	}
+
+	live.mu.Lock()
+	cd, ok := live.drv.(*driver.ContainerDriver)
+	live.mu.Unlock()
+	if ok && cd != nil && cd.Name() == deployName {
+		return name
+	}
+	return deployName
+}
+
 // deployVerifyHealth runs health checks or waits for the new instance to settle.
 func (d *Daemon) deployVerifyHealth(name string, ms *ManagedService, tempPort int, newDrv driver.Driver) error {
 	if ms.spec.Health != nil {
-		if err := d.waitForHealthy(ms, tempPort); err != nil {
+		if err := d.waitForHealthy(ms, tempPort, newDrv); err != nil {
 			d.logger.Error("new instance unhealthy, rolling back", "service", name, "error", err)
 			return fmt.Errorf("new instance failed health check: %w", err)
 		}
@@ -125,24 +209,32 @@ func (d *Daemon) deployDrainOld(name string, tempPort int, drainTimeout time.Dur
 	d.regenerateRoutingLocked(map[string]int{name: tempPort})
 	d.mu.RUnlock()
 	d.logger.Info("routing switched to new instance", "service", name, "port", tempPort)
+	d.publishDeploy(name, DeployStepRoutingSwitched, tempPort, 0)
 
-	// Wait drain period for in-flight requests on old instance
-	d.logger.Info("draining old instance", "service", name, "drain", drainTimeout)
-	time.Sleep(drainTimeout)
-
-	// Stop old instance — use Stop() which handles detach + driver shutdown
 	d.mu.RLock()
 	oldMs := d.services[name]
 	d.mu.RUnlock()
 
-	if err := oldMs.Stop(DefaultStopTimeout); err != nil {
+	// Run pre_stop before draining so the old instance can deregister itself
+	// while it still serves in-flight requests
+	oldMs.runPreStop()
+
+	// Wait drain period for in-flight requests on old instance
+	d.logger.Info("draining old instance", "service", name, "drain", drainTimeout)
+	d.publishDeploy(name, DeployStepDraining, 0, 0)
+	time.Sleep(drainTimeout)
+
+	// Stop old instance — stop() handles detach + driver shutdown; pre_stop already ran
+	if err := oldMs.stop(DefaultStopTimeout, false, ReasonManualStop); err != nil {
 		d.logger.Warn("error stopping old instance during deploy", "service", name, "error", err)
 	}
 	d.logger.Info("old instance stopped", "service", name)
+	d.publishDeploy(name, DeployStepOldStopped, 0, 0)
 }
 
 // deployPromote creates a new ManagedService wrapping the new driver and installs it.
-func (d *Daemon) deployPromote(name string, ms *ManagedService, tempPort int, newDrv driver.Driver) error {
+// previous is the record of the replaced instance, or nil if none was recorded.
+func (d *Daemon) deployPromote(name string, ms *ManagedService, tempPort int, newDrv driver.Driver, previous *ServiceRecord) error {
 	newMs, err := NewManagedService(ms.spec, ms.secrets)
 	if err != nil {
 		d.ports.ReleaseTemporary(name, deploySuffix)
@@ -151,11 +243,15 @@ func (d *Daemon) deployPromote(name string, ms *ManagedService, tempPort int, ne
 	newMs.allocatedPort = tempPort
 	newMs.drv = newDrv
 	newMs.specHash = ms.specHash
+	newMs.onEvent = d.serviceEvents(name)
+	newMs.gpuInfo = ms.gpuInfo
+	newMs.containerPrefix = ms.containerPrefix
+	newMs.containerFiles = ms.containerFiles
+	newMs.globalEnv = ms.globalEnv
 
 	// Set up the onStarted callback for state persistence
-	newMs.onStarted = func(pid int) {
-		rec := newServiceRecord(ms.spec.Service.Type, pid, tempPort, ms.spec.Service.Command)
-		rec.ProcessName = resolveProcessName(pid)
+	newMs.onStarted = func(drv driver.Driver) {
+		rec := processRecord(ms.spec.Service.Type, drv, tempPort, ms.spec.Service.Command)
 		if err := d.state.set(name, rec); err != nil {
 			d.logger.Warn("failed to save service state", "service", name, "error", err)
 		}
@@ -167,8 +263,10 @@ func (d *Daemon) deployPromote(name string, ms *ManagedService, tempPort int, ne
 	newMs.cancel = cancel
 	newMs.stopped = make(chan struct{})
 
-	// Start health monitoring for the promoted instance
-	monitor := newMs.startHealthMonitor(d.ctx)
+	// Start health monitoring for the promoted instance. It passed its
+	// checks before routing switched to it, so it starts healthy and stays
+	// routed
+	monitor := newMs.startHealthMonitor(d.ctx, true)
 	newMs.monitor = monitor
 
 	// Start supervision loop that watches the new process
@@ -181,8 +279,8 @@ func (d *Daemon) deployPromote(name string, ms *ManagedService, tempPort int, ne
 	}
 
 	// Update state file
-	rec := newServiceRecord(ms.spec.Service.Type, newDrv.Info().PID, tempPort, ms.spec.Service.Command)
-	rec.ProcessName = resolveProcessName(newDrv.Info().PID)
+	rec := processRecord(ms.spec.Service.Type, newDrv, tempPort, ms.spec.Service.Command)
+	rec.Previous = previous
 	if err := d.state.set(name, rec); err != nil {
 		d.logger.Warn("failed to save service state after deploy", "service", name, "error", err)
 	}
@@ -196,26 +294,41 @@ func (d *Daemon) deployPromote(name string, ms *ManagedService, tempPort int, ne
 	d.regenerateRouting()
 
 	d.logger.Info("deploy complete", "service", name, "port", tempPort, "pid", newDrv.Info().PID)
+	d.publishDeploy(name, DeployStepPromoted, tempPort, newDrv.Info().PID)
+	d.recordEvent(name, EventDeployed, fmt.Sprintf("pid %d on port %d", newDrv.Info().PID, tempPort))
 	return nil
 }
 
 // waitForHealthy runs health checks in a loop until the service is healthy
-// or the grace period + unhealthy threshold is exceeded.
-func (d *Daemon) waitForHealthy(ms *ManagedService, port int) error {
+// or the grace period + unhealthy threshold is exceeded, or the startup
+// probe's budget when the spec has one. port is the one the instance listens
+// on, checked unless the spec sets health.port. drv is the instance
+// inspected by docker health checks; nil means the service's current process.
+func (d *Daemon) waitForHealthy(ms *ManagedService, port int, drv driver.Driver) error {
 	h := ms.spec.Health
-
-	// Use the spec's explicit health port if set, otherwise use the deploy port
-	healthPort := port
-	if h.Port != 0 {
-		healthPort = h.Port
+	cfg := health.Config{
+		Type:       h.Type,
+		Path:       h.Path,
+		Method:     h.Method,
+		Headers:    h.Headers,
+		Body:       h.Body,
+		Port:       ms.healthCheckPort(port),
+		UnixSocket: h.UnixSocket,
+		Command:    h.Command,
+		Timeout:    h.Timeout.Duration,
+		Host:       ms.spec.Network.ReachableHost(),
+	}
+	if h.Type == "docker" {
+		cfg.DockerStatus = ms.dockerHealth
+		if drv != nil {
+			cfg.DockerStatus = func(ctx context.Context) (string, error) {
+				return containerHealth(ctx, drv)
+			}
+		}
 	}
 
-	cfg := health.Config{
-		Type:    h.Type,
-		Path:    h.Path,
-		Port:    healthPort,
-		Command: h.Command,
-		Timeout: h.Timeout.Duration,
+	if h.Startup != nil {
+		return waitForStartup(cfg, h)
 	}
 
 	interval := h.Interval.Duration
@@ -223,7 +336,9 @@ func (d *Daemon) waitForHealthy(ms *ManagedService, port int) error {
 		interval = 500 * time.Millisecond
 	}
 
-	gracePeriod := h.GracePeriod.Duration
+	// Failures inside the grace period don't count, so there's no point
+	// checking before it ends (or before initial_delay, if later)
+	gracePeriod := max(h.GracePeriod.Duration, h.InitialDelay.Duration)
 	if gracePeriod > 0 {
 		d.logger.Info("waiting for grace period", "service", ms.spec.Service.Name, "grace", gracePeriod)
 		time.Sleep(gracePeriod)
@@ -249,3 +364,103 @@ func (d *Daemon) waitForHealthy(ms *ManagedService, port int) error {
 
 	return fmt.Errorf("health check failed after %d attempts", maxAttempts)
 }
+
+// waitForStartup runs the startup probe: a check every startup interval until
+// one passes, giving up after the probe's threshold of failed checks or once
+// max_duration has passed, whichever comes first.
+func waitForStartup(cfg health.Config, h *spec.HealthCheck) error {
+	st := h.Startup
+	interval := st.Interval.Duration
+	if interval <= 0 {
+		interval = h.Interval.Duration
+	}
+	if st.Timeout.Duration > 0 {
+		cfg.Timeout = st.Timeout.Duration
+	}
+	var deadline time.Time
+	if st.MaxDuration.Duration > 0 {
+		deadline = time.Now().Add(st.MaxDuration.Duration)
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := health.SingleCheck(cfg)
+		if err == nil {
+			return nil
+		}
+		if st.Threshold > 0 && attempt >= st.Threshold {
+			return fmt.Errorf("startup probe failed after %d attempts: %w", attempt, err)
+		}
+		if !deadline.IsZero() && time.Now().Add(interval).After(deadline) {
+			return fmt.Errorf("startup probe failed within %s: %w", st.MaxDuration.Duration, err)
+		}
+		time.Sleep(interval)
+	}
+}
+
+// DeployStep records the outcome of deploying one service in a DeployAll run.
+type DeployStep struct {
+	Service string `json:"service"`
+	Status  string `json:"status"` // "deployed" | "failed" | "skipped"
+	Error   string `json:"error,omitempty"`
+}
+
+// DeployAllResult is the outcome of deploying every routed service.
+type DeployAllResult struct {
+	Services []DeployStep `json:"services"`
+	Success  bool         `json:"success"`
+}
+
+// DeployAll deploys every managed service with routing config, in dependency
+// order. By default it stops at the first failure and marks the remaining
+// services as skipped; with continueOnError it attempts every service.
+// External and disabled services are never deployed. Each deploy is audited
+// as the actor in ctx.
+func (d *Daemon) DeployAll(ctx context.Context, drainTimeout time.Duration, continueOnError bool) (*DeployAllResult, error) {
+	if err := d.checkMaintenance(); err != nil {
+		return nil, err
+	}
+	d.mu.RLock()
+	g := d.deps
+	var targets []string
+	if g != nil {
+		order, err := g.startOrder()
+		if err != nil {
+			d.mu.RUnlock()
+			return nil, fmt.Errorf("dependency resolution: %w", err)
+		}
+		for _, name := range order {
+			ms, ok := d.services[name]
+			if ok && ms.spec.Routing != nil && !ms.IsExternal() && ms.spec.IsEnabled() {
+				targets = append(targets, name)
+			}
+		}
+	}
+	d.mu.RUnlock()
+
+	result := &DeployAllResult{Success: true}
+	for _, name := range targets {
+		if !result.Success && !continueOnError {
+			result.Services = append(result.Services, DeployStep{Service: name, Status: "skipped"})
+			continue
+		}
+		if err := d.deployLocked(ctx, name, drainTimeout); err != nil {
+			d.logger.Error("deploy failed", "service", name, "error", err)
+			result.Services = append(result.Services, DeployStep{Service: name, Status: "failed", Error: err.Error()})
+			result.Success = false
+			continue
+		}
+		result.Services = append(result.Services, DeployStep{Service: name, Status: "deployed"})
+	}
+	return result, nil
+}
+
+// deployLocked deploys name while holding its operation lock, failing with
+// ErrServiceBusy if another operation already holds it.
+func (d *Daemon) deployLocked(ctx context.Context, name string, drainTimeout time.Duration) error {
+	unlock, err := d.LockService(name)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return d.DeployService(ctx, name, drainTimeout)
+}
@@ -0,0 +1,85 @@
+package daemon
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+// Deploy steps reported as DeployEvents, in the order a blue-green deploy
+// passes through them. Deploys that fall back to restart report only
+// DeployStepRestarting.
+const (
+	DeployStepPortAllocated   = "allocated port"
+	DeployStepStarted         = "new instance started"
+	DeployStepHealthy         = "healthy"
+	DeployStepRoutingSwitched = "routing switched"
+	DeployStepDraining        = "draining"
+	DeployStepOldStopped      = "old stopped"
+	DeployStepPromoted        = "promoted"
+	DeployStepRestarting      = "restarting"
+)
+
+// deployEventBuffer is how many events a watcher can fall behind by before
+// further events are dropped for it.
+const deployEventBuffer = 32
+
+// DeployEvent reports that a deploy has reached a step.
+type DeployEvent struct {
+	Service string    `json:"service"`
+	Step    string    `json:"step"`
+	Port    int       `json:"port,omitempty"`
+	PID     int       `json:"pid,omitempty"`
+	Time    time.Time `json:"time"`
+}
+
+// deployEventHub fans deploy events out to the watchers of each service.
+// The zero value is ready to use.
+type deployEventHub struct {
+	mu       sync.Mutex
+	watchers map[string][]chan DeployEvent
+}
+
+func (h *deployEventHub) watch(name string) (<-chan DeployEvent, func()) {
+	ch := make(chan DeployEvent, deployEventBuffer)
+
+	h.mu.Lock()
+	if h.watchers == nil {
+		h.watchers = make(map[string][]chan DeployEvent)
+	}
+	h.watchers[name] = append(h.watchers[name], ch)
+	h.mu.Unlock()
+
+	cancel := func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		h.watchers[name] = slices.DeleteFunc(h.watchers[name], func(c chan DeployEvent) bool { return c == ch })
+		if len(h.watchers[name]) == 0 {
+			delete(h.watchers, name)
+		}
+	}
+	return ch, cancel
+}
+
+func (h *deployEventHub) publish(ev DeployEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, ch := range h.watchers[ev.Service] {
+		select {
+		case ch <- ev:
+		default: // slow watcher; drop rather than stall the deploy
+		}
+	}
+}
+
+// WatchDeploy subscribes to the deploy events of the named service. Events
+// are delivered for every deploy or rollback until cancel is called. A watcher
+// that falls behind misses events rather than slowing the deploy.
+func (d *Daemon) WatchDeploy(name string) (events <-chan DeployEvent, cancel func()) {
+	return d.deployEvents.watch(name)
+}
+
+// publishDeploy reports that the named service's deploy reached step.
+func (d *Daemon) publishDeploy(name, step string, port, pid int) {
+	d.deployEvents.publish(DeployEvent{Service: name, Step: step, Port: port, PID: pid, Time: time.Now()})
+}
@@ -6,10 +6,13 @@ import (
 	"net"
 	"net/http"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/benaskins/aurelia/internal/driver"
 )
 
 func TestDeployServiceBasic(t *testing.T) {
@@ -55,7 +58,7 @@ routing:
 	portBefore := stateBefore.Port
 
 	// Deploy
-	if err := d.DeployService("chat", 50*time.Millisecond); err != nil {
+	if err := d.DeployService(context.Background(), "chat", 50*time.Millisecond); err != nil {
 		t.Fatalf("DeployService: %v", err)
 	}
 
@@ -158,7 +161,7 @@ health:
 	}, 2*time.Second, "web to become running")
 
 	// Deploy should succeed — health check against the real server
-	if err := d.DeployService("web", 50*time.Millisecond); err != nil {
+	if err := d.DeployService(context.Background(), "web", 50*time.Millisecond); err != nil {
 		t.Fatalf("DeployService: %v", err)
 	}
 
@@ -202,7 +205,7 @@ routing:
 	// Manually allocate the deploy temp port to simulate an in-progress deploy
 	d.ports.AllocateTemporary("svc", deploySuffix)
 
-	err := d.DeployService("svc", 1*time.Second)
+	err := d.DeployService(context.Background(), "svc", 1*time.Second)
 	if err == nil {
 		t.Error("expected error for concurrent deploy")
 	}
@@ -241,7 +244,7 @@ service:
 	pidBefore, _ := d.ServiceState("worker")
 
 	// Deploy should fall back to restart (no routing)
-	if err := d.DeployService("worker", 50*time.Millisecond); err != nil {
+	if err := d.DeployService(context.Background(), "worker", 50*time.Millisecond); err != nil {
 		t.Fatalf("DeployService: %v", err)
 	}
 
@@ -287,7 +290,7 @@ routing:
 	pidBefore, _ := d.ServiceState("fixed")
 
 	// Deploy with fixed port should fall back to restart (not blue-green)
-	if err := d.DeployService("fixed", 50*time.Millisecond); err != nil {
+	if err := d.DeployService(context.Background(), "fixed", 50*time.Millisecond); err != nil {
 		t.Fatalf("DeployService: %v", err)
 	}
 
@@ -312,8 +315,391 @@ func TestDeployServiceNotFound(t *testing.T) {
 	d.Start(ctx)
 	defer d.Stop(5 * time.Second)
 
-	err := d.DeployService("nonexistent", 1*time.Second)
+	err := d.DeployService(context.Background(), "nonexistent", 1*time.Second)
 	if err == nil {
 		t.Error("expected error for nonexistent service")
 	}
 }
+
+func TestDeployAll(t *testing.T) {
+	dir := t.TempDir()
+
+	// alpha starts after zeta, so dependency order differs from name order.
+	writeSpec(t, dir, "alpha.yaml", `
+service:
+  name: alpha
+  type: native
+  command: "sleep 30"
+network:
+  port: 0
+routing:
+  hostname: alpha.test.internal
+dependencies:
+  after: [zeta]
+`)
+	writeSpec(t, dir, "zeta.yaml", `
+service:
+  name: zeta
+  type: native
+  command: "sleep 30"
+network:
+  port: 0
+routing:
+  hostname: zeta.test.internal
+`)
+	writeSpec(t, dir, "worker.yaml", `
+service:
+  name: worker
+  type: native
+  command: "sleep 30"
+`)
+
+	d := NewDaemon(dir, WithRouting(filepath.Join(t.TempDir(), "routing.yaml")), WithPortRange(27200, 27300))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := d.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer d.Stop(5 * time.Second)
+
+	before := make(map[string]int)
+	for _, name := range []string{"alpha", "zeta", "worker"} {
+		waitUntil(t, func() bool {
+			s, _ := d.ServiceState(name)
+			return s.State == "running"
+		}, 2*time.Second, name+" to become running")
+		s, _ := d.ServiceState(name)
+		before[name] = s.PID
+	}
+
+	result, err := d.DeployAll(context.Background(), 50*time.Millisecond, false)
+	if err != nil {
+		t.Fatalf("DeployAll: %v", err)
+	}
+	if !result.Success {
+		t.Fatalf("expected success, got %+v", result.Services)
+	}
+
+	var order []string
+	for _, step := range result.Services {
+		if step.Status != "deployed" {
+			t.Errorf("%s: expected deployed, got %s (%s)", step.Service, step.Status, step.Error)
+		}
+		order = append(order, step.Service)
+	}
+	if strings.Join(order, ",") != "zeta,alpha" {
+		t.Errorf("expected deploy order zeta,alpha, got %v", order)
+	}
+
+	for _, name := range []string{"alpha", "zeta"} {
+		s, _ := d.ServiceState(name)
+		if s.State != "running" || s.PID == before[name] {
+			t.Errorf("%s: expected running with new PID (before %d), got %v pid %d", name, before[name], s.State, s.PID)
+		}
+	}
+
+	// Services without routing are not part of a deploy-all
+	if s, _ := d.ServiceState("worker"); s.PID != before["worker"] {
+		t.Errorf("worker should not be redeployed: PID %d -> %d", before["worker"], s.PID)
+	}
+}
+
+func TestDeployAllStopsOnFailure(t *testing.T) {
+	dir := t.TempDir()
+
+	// The script runs normally until the marker exists, so the initial start
+	// succeeds but the deploy's new instance exits immediately.
+	marker := filepath.Join(t.TempDir(), "broken")
+	script := filepath.Join(t.TempDir(), "app.sh")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\n[ -f "+marker+" ] && exit 1\nexec sleep 30\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	writeSpec(t, dir, "first.yaml", `
+service:
+  name: first
+  type: native
+  command: "`+script+`"
+network:
+  port: 0
+routing:
+  hostname: first.test.internal
+`)
+	writeSpec(t, dir, "second.yaml", `
+service:
+  name: second
+  type: native
+  command: "sleep 30"
+network:
+  port: 0
+routing:
+  hostname: second.test.internal
+dependencies:
+  after: [first]
+`)
+
+	d := NewDaemon(dir, WithRouting(filepath.Join(t.TempDir(), "routing.yaml")), WithPortRange(27300, 27400))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := d.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer d.Stop(5 * time.Second)
+
+	for _, name := range []string{"first", "second"} {
+		waitUntil(t, func() bool {
+			s, _ := d.ServiceState(name)
+			return s.State == "running"
+		}, 2*time.Second, name+" to become running")
+	}
+	secondBefore, _ := d.ServiceState("second")
+
+	if err := os.WriteFile(marker, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := d.DeployAll(context.Background(), 50*time.Millisecond, false)
+	if err != nil {
+		t.Fatalf("DeployAll: %v", err)
+	}
+	if result.Success {
+		t.Fatal("expected deploy-all to fail")
+	}
+	if len(result.Services) != 2 {
+		t.Fatalf("expected 2 steps, got %+v", result.Services)
+	}
+	if result.Services[0].Status != "failed" || result.Services[0].Error == "" {
+		t.Errorf("first: expected failed with error, got %+v", result.Services[0])
+	}
+	if result.Services[1].Status != "skipped" {
+		t.Errorf("second: expected skipped, got %+v", result.Services[1])
+	}
+	if s, _ := d.ServiceState("second"); s.PID != secondBefore.PID {
+		t.Errorf("second should not be redeployed: PID %d -> %d", secondBefore.PID, s.PID)
+	}
+
+	// With continueOnError the remaining services are still deployed
+	result, err = d.DeployAll(context.Background(), 50*time.Millisecond, true)
+	if err != nil {
+		t.Fatalf("DeployAll: %v", err)
+	}
+	if result.Success || result.Services[1].Status != "deployed" {
+		t.Errorf("expected first to fail and second to deploy, got %+v", result.Services)
+	}
+}
+
+func TestDeployServiceRestartFallbackEvent(t *testing.T) {
+	dir := t.TempDir()
+	writeSpec(t, dir, "worker.yaml", `
+service:
+  name: worker
+  type: native
+  command: "sleep 30"
+`)
+
+	d := NewDaemon(dir)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := d.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer d.Stop(5 * time.Second)
+
+	waitUntil(t, func() bool {
+		s, _ := d.ServiceState("worker")
+		return s.State == "running"
+	}, 2*time.Second, "worker to become running")
+
+	events, stop := d.WatchDeploy("worker")
+	defer stop()
+
+	if err := d.DeployService(context.Background(), "worker", 50*time.Millisecond); err != nil {
+		t.Fatalf("DeployService: %v", err)
+	}
+
+	select {
+	case ev := <-events:
+		if ev.Service != "worker" || ev.Step != DeployStepRestarting {
+			t.Errorf("expected restarting event, got %+v", ev)
+		}
+	default:
+		t.Fatal("expected a deploy event")
+	}
+	select {
+	case ev := <-events:
+		t.Errorf("unexpected extra event %+v", ev)
+	default:
+	}
+}
+
+func TestDeployServiceStartupProbe(t *testing.T) {
+	if _, err := exec.LookPath("python3"); err != nil {
+		t.Skip("python3 not available")
+	}
+
+	// The first instance listens straight away; the deployed one takes 3s,
+	// longer than the liveness budget of a 1s grace period and 15 checks
+	// 100ms apart. The grace period also keeps a slow start of the first
+	// instance from being health-restarted into the slow branch
+	for _, withStartup := range []bool{false, true} {
+		t.Run(fmt.Sprintf("startup=%v", withStartup), func(t *testing.T) {
+			dir := t.TempDir()
+			scratch := t.TempDir()
+			marker := filepath.Join(scratch, "started")
+			script := filepath.Join(scratch, "serve.sh")
+			body := fmt.Sprintf("#!/bin/bash\nif [ -e %s ]; then sleep 3; fi\ntouch %s\nexec python3 -m http.server \"$PORT\" --bind 127.0.0.1\n", marker, marker)
+			if err := os.WriteFile(script, []byte(body), 0755); err != nil {
+				t.Fatal(err)
+			}
+			startup := ""
+			if withStartup {
+				startup = "\n  startup:\n    interval: 100ms\n    max_duration: 10s"
+			}
+			writeSpec(t, dir, "slow.yaml", fmt.Sprintf(`
+service:
+  name: slow
+  type: native
+  command: %s
+
+network:
+  port: 0
+
+routing:
+  hostname: slow.example.local
+
+health:
+  type: tcp
+  interval: 100ms
+  timeout: 1s
+  grace_period: 1s
+  unhealthy_threshold: 5%s
+`, script, startup))
+
+			routingPath := filepath.Join(t.TempDir(), "traefik", "aurelia.yaml")
+			d := NewDaemon(dir, WithRouting(routingPath), WithPortRange(28200, 28300), WithStateDir(t.TempDir()))
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			if err := d.Start(ctx); err != nil {
+				t.Fatalf("Start: %v", err)
+			}
+			defer d.Stop(5 * time.Second)
+
+			waitUntil(t, func() bool {
+				s, _ := d.ServiceState("slow")
+				return s.State == "running" && s.Health == "healthy"
+			}, 5*time.Second, "slow to become healthy")
+
+			err := d.DeployService(context.Background(), "slow", 50*time.Millisecond)
+			if withStartup && err != nil {
+				t.Fatalf("DeployService with a startup probe: %v", err)
+			}
+			if !withStartup && err == nil {
+				t.Fatal("expected the deploy to fail within the liveness budget")
+			}
+		})
+	}
+}
+
+func TestDeployServiceHealthChecksTempPort(t *testing.T) {
+	if _, err := exec.LookPath("python3"); err != nil {
+		t.Skip("python3 not available")
+	}
+
+	// The first instance answers /health with 404, and isn't restarted when
+	// its monitor gives up on it; the deployed one, on its temporary port,
+	// serves it. The deploy only passes if the check, which names no port,
+	// follows the new instance.
+	dir := t.TempDir()
+	scratch := t.TempDir()
+	oldRoot, newRoot := filepath.Join(scratch, "old"), filepath.Join(scratch, "new")
+	for _, d := range []string{oldRoot, newRoot} {
+		if err := os.Mkdir(d, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(newRoot, "health"), []byte("ok"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	marker := filepath.Join(scratch, "started")
+	script := filepath.Join(scratch, "serve.sh")
+	body := fmt.Sprintf("#!/bin/bash\ncd %s\nif [ -e %s ]; then cd %s; fi\ntouch %s\nexec python3 -m http.server \"$PORT\" --bind 127.0.0.1\n", oldRoot, marker, newRoot, marker)
+	if err := os.WriteFile(script, []byte(body), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeSpec(t, dir, "web.yaml", fmt.Sprintf(`
+service:
+  name: web
+  type: native
+  command: %s
+
+network:
+  port: 0
+
+routing:
+  hostname: web.example.local
+
+health:
+  type: http
+  path: /health
+  interval: 100ms
+  timeout: 1s
+  unhealthy_threshold: 50
+
+restart:
+  policy: never
+`, script))
+
+	routingPath := filepath.Join(t.TempDir(), "traefik", "aurelia.yaml")
+	d := NewDaemon(dir, WithRouting(routingPath), WithPortRange(28400, 28500), WithStateDir(t.TempDir()))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := d.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer d.Stop(5 * time.Second)
+
+	waitUntil(t, func() bool {
+		_, err := os.Stat(marker)
+		return err == nil
+	}, 5*time.Second, "web to start")
+	before, _ := d.ServiceState("web")
+
+	if err := d.DeployService(context.Background(), "web", 50*time.Millisecond); err != nil {
+		t.Fatalf("DeployService: %v", err)
+	}
+	after, _ := d.ServiceState("web")
+	if after.Port == before.Port {
+		t.Errorf("expected the deployed instance on a new port, still %d", after.Port)
+	}
+	waitUntil(t, func() bool {
+		s, _ := d.ServiceState("web")
+		return s.Health == "healthy"
+	}, 5*time.Second, "the promoted instance's monitor to check its port")
+}
+
+func TestDeployServiceRecordsStartTime(t *testing.T) {
+	d := startRoutedSleep(t, 27800)
+
+	if err := d.DeployService(context.Background(), "chat", 50*time.Millisecond); err != nil {
+		t.Fatalf("DeployService: %v", err)
+	}
+
+	// The promoted instance can be told apart from a recycled PID after a crash
+	st, _ := d.ServiceState("chat")
+	want, err := driver.ProcessStartTime(st.PID)
+	if err != nil {
+		t.Fatalf("ProcessStartTime: %v", err)
+	}
+	records, err := d.state.load()
+	if err != nil {
+		t.Fatalf("load state: %v", err)
+	}
+	if rec := records["chat"]; rec.PID != st.PID || rec.StartTime != want {
+		t.Errorf("expected PID %d started at %d in state, got PID %d started at %d", st.PID, want, rec.PID, rec.StartTime)
+	}
+}
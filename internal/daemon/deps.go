@@ -2,6 +2,7 @@ package daemon
 
 import (
 	"fmt"
+	"maps"
 	"slices"
 
 	"github.com/benaskins/aurelia/internal/spec"
@@ -10,7 +11,8 @@ import (
 // depGraph builds a dependency-ordered startup sequence and reverse-ordered shutdown.
 type depGraph struct {
 	specs map[string]*spec.ServiceSpec
-	// after[A] = [B, C] means A must start after B and C
+	// after[A] = [B, C] means A must start after B and C, whether A lists
+	// them in after or they list A in before
 	after map[string][]string
 	// requires[A] = [B] means A hard-depends on B (cascade stop)
 	requires map[string][]string
@@ -38,6 +40,19 @@ func newDepGraph(specs []*spec.ServiceSpec) *depGraph {
 		}
 	}
 
+	// B before A is A after B. Unknown services are skipped, as in startOrder.
+	for _, s := range specs {
+		if s.Dependencies == nil {
+			continue
+		}
+		for _, later := range s.Dependencies.Before {
+			if _, ok := g.specs[later]; !ok || slices.Contains(g.after[later], s.Service.Name) {
+				continue
+			}
+			g.after[later] = append(slices.Clip(g.after[later]), s.Service.Name)
+		}
+	}
+
 	return g
 }
 
@@ -105,6 +120,37 @@ func (g *depGraph) startOrder() ([]string, error) {
 	return order, nil
 }
 
+// startLevels groups the start order into levels. Services within a level have
+// no ordering constraint between them and may start concurrently; every
+// after/requires dependency of a service sits in an earlier level.
+func (g *depGraph) startLevels() ([][]string, error) {
+	order, err := g.startOrder()
+	if err != nil {
+		return nil, err
+	}
+
+	level := make(map[string]int, len(order))
+	var levels [][]string
+	for _, name := range order {
+		l := 0
+		for _, dep := range slices.Concat(g.after[name], g.requires[name]) {
+			if dl, ok := level[dep]; ok && dl+1 > l {
+				l = dl + 1
+			}
+		}
+		level[name] = l
+		for len(levels) <= l {
+			levels = append(levels, nil)
+		}
+		levels[l] = append(levels[l], name)
+	}
+
+	for _, names := range levels {
+		slices.Sort(names)
+	}
+	return levels, nil
+}
+
 // stopOrder returns services in reverse dependency order (dependents first).
 func (g *depGraph) stopOrder() ([]string, error) {
 	order, err := g.startOrder()
@@ -120,6 +166,69 @@ func (g *depGraph) hasRequiredDependents(name string) bool {
 	return len(g.dependents[name]) > 0
 }
 
+// waitsForHealth reports whether some service has name as a dependency with
+// the healthy condition, so startup must wait for it to pass its health check.
+func (g *depGraph) waitsForHealth(name string) bool {
+	for _, s := range g.specs {
+		deps := s.Dependencies
+		if deps == nil || !slices.Contains(slices.Concat(deps.After, deps.Requires), name) {
+			continue
+		}
+		if deps.Condition(name) == spec.ConditionHealthy {
+			return true
+		}
+	}
+	return false
+}
+
+// checkConditions verifies that no enabled service requires a disabled one,
+// which could never be satisfied, and that every explicit healthy condition
+// targets a loaded service that has a health check to wait on.
+func (g *depGraph) checkConditions() error {
+	names := slices.Sorted(maps.Keys(g.specs))
+	for _, name := range names {
+		deps := g.specs[name].Dependencies
+		if deps == nil {
+			continue
+		}
+		if g.specs[name].IsEnabled() {
+			for _, dep := range deps.Requires {
+				if target, ok := g.specs[dep]; ok && !target.IsEnabled() {
+					return fmt.Errorf("service %q requires %q, which is disabled", name, dep)
+				}
+			}
+		}
+		for _, dep := range slices.Sorted(maps.Keys(deps.Conditions)) {
+			if deps.Conditions[dep] != spec.ConditionHealthy {
+				continue
+			}
+			target, ok := g.specs[dep]
+			if !ok {
+				continue // unknown deps are skipped, as in startOrder
+			}
+			if target.Health == nil {
+				return fmt.Errorf("service %q requires %q to be healthy, but %q has no health check", name, dep, dep)
+			}
+		}
+	}
+	return nil
+}
+
+// checkRequires verifies that every hard dependency names a loaded service.
+// Unlike startOrder, which skips unknown deps, it is for validating a set of
+// specs that is meant to be complete.
+func (g *depGraph) checkRequires() error {
+	names := slices.Sorted(maps.Keys(g.specs))
+	for _, name := range names {
+		for _, dep := range g.requires[name] {
+			if _, ok := g.specs[dep]; !ok {
+				return fmt.Errorf("service %q requires %q, which is not defined", name, dep)
+			}
+		}
+	}
+	return nil
+}
+
 // cascadeStopTargets returns all services that should be stopped when
 // the given service stops (hard dependents via requires).
 func (g *depGraph) cascadeStopTargets(name string) []string {
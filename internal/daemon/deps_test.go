@@ -1,6 +1,7 @@
 package daemon
 
 import (
+	"strings"
 	"testing"
 	"time"
 
@@ -101,6 +102,100 @@ func TestStartOrderCycleDetected(t *testing.T) {
 	}
 }
 
+func TestStartLevelsDiamond(t *testing.T) {
+	// d depends on b and c, both depend on a
+	g := newDepGraph([]*spec.ServiceSpec{
+		makeSpec("a", nil, nil),
+		makeSpec("b", []string{"a"}, nil),
+		makeSpec("c", []string{"a"}, []string{"a"}),
+		makeSpec("d", []string{"b", "c"}, nil),
+	})
+
+	levels, err := g.startLevels()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := [][]string{{"a"}, {"b", "c"}, {"d"}}
+	if len(levels) != len(want) {
+		t.Fatalf("expected %d levels, got %v", len(want), levels)
+	}
+	for i := range want {
+		if strings.Join(levels[i], ",") != strings.Join(want[i], ",") {
+			t.Errorf("level %d: expected %v, got %v", i, want[i], levels[i])
+		}
+	}
+}
+
+func TestBeforeMatchesAfter(t *testing.T) {
+	before := func(name string, later ...string) *spec.ServiceSpec {
+		s := makeSpec(name, nil, nil)
+		s.Dependencies = &spec.Dependencies{Before: later}
+		return s
+	}
+	levelsOf := func(specs ...*spec.ServiceSpec) string {
+		t.Helper()
+		levels, err := newDepGraph(specs).startLevels()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var parts []string
+		for _, l := range levels {
+			parts = append(parts, strings.Join(l, ","))
+		}
+		return strings.Join(parts, " | ")
+	}
+
+	// The diamond from TestStartLevelsDiamond, as after, as before, and mixed
+	withAfter := levelsOf(
+		makeSpec("a", nil, nil),
+		makeSpec("b", []string{"a"}, nil),
+		makeSpec("c", []string{"a"}, nil),
+		makeSpec("d", []string{"b", "c"}, nil),
+	)
+	withBefore := levelsOf(
+		before("a", "b", "c"),
+		before("b", "d"),
+		before("c", "d"),
+		makeSpec("d", nil, nil),
+	)
+	mixed := levelsOf(
+		before("a", "b", "c"),
+		makeSpec("b", nil, nil),
+		before("c", "d"),
+		makeSpec("d", []string{"b"}, nil),
+	)
+	if withAfter != "a | b,c | d" {
+		t.Fatalf("unexpected levels with after: %s", withAfter)
+	}
+	if withBefore != withAfter || mixed != withAfter {
+		t.Errorf("expected before to order like after: after %s, before %s, mixed %s", withAfter, withBefore, mixed)
+	}
+
+	// Unknown services in before are skipped
+	g := newDepGraph([]*spec.ServiceSpec{before("a", "consumer"), makeSpec("b", nil, nil)})
+	if _, ok := g.after["consumer"]; ok {
+		t.Errorf("expected no edges for an unknown service, got %v", g.after)
+	}
+
+	// A before B and B before A is a cycle
+	g = newDepGraph([]*spec.ServiceSpec{before("a", "b"), before("b", "a")})
+	if _, err := g.startOrder(); err == nil {
+		t.Error("expected cycle error, got nil")
+	}
+}
+
+func TestStartLevelsCycleDetected(t *testing.T) {
+	g := newDepGraph([]*spec.ServiceSpec{
+		makeSpec("a", []string{"b"}, nil),
+		makeSpec("b", []string{"a"}, nil),
+	})
+
+	if _, err := g.startLevels(); err == nil {
+		t.Fatal("expected cycle error")
+	}
+}
+
 func TestStopOrderReverseOfStart(t *testing.T) {
 	g := newDepGraph([]*spec.ServiceSpec{
 		makeSpec("a", nil, nil),
@@ -213,6 +308,75 @@ func TestHasRequiredDependents(t *testing.T) {
 	}
 }
 
+func TestWaitsForHealth(t *testing.T) {
+	// b requires a (healthy by default), c only starts after a,
+	// d asks for c to be healthy, e requires d but only needs it started
+	d := makeSpec("d", []string{"c"}, nil)
+	d.Dependencies.Conditions = map[string]string{"c": spec.ConditionHealthy}
+	e := makeSpec("e", []string{"d"}, []string{"d"})
+	e.Dependencies.Conditions = map[string]string{"d": spec.ConditionStarted}
+	g := newDepGraph([]*spec.ServiceSpec{
+		makeSpec("a", nil, nil),
+		makeSpec("b", []string{"a"}, []string{"a"}),
+		makeSpec("c", []string{"a"}, nil),
+		d,
+		e,
+	})
+
+	for name, want := range map[string]bool{"a": true, "b": false, "c": true, "d": false, "e": false} {
+		if got := g.waitsForHealth(name); got != want {
+			t.Errorf("waitsForHealth(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestCheckConditions(t *testing.T) {
+	app := makeSpec("app", []string{"db"}, nil)
+	app.Dependencies.Conditions = map[string]string{"db": spec.ConditionHealthy}
+	db := makeSpec("db", nil, nil)
+
+	g := newDepGraph([]*spec.ServiceSpec{app, db})
+	if err := g.checkConditions(); err == nil {
+		t.Error("expected error for healthy condition on a service without a health check")
+	}
+
+	db.Health = &spec.HealthCheck{Type: "exec", Command: "true"}
+	g = newDepGraph([]*spec.ServiceSpec{app, db})
+	if err := g.checkConditions(); err != nil {
+		t.Errorf("expected healthy condition on a checked service to pass, got: %v", err)
+	}
+
+	app.Dependencies.Conditions["db"] = spec.ConditionStarted
+	db.Health = nil
+	g = newDepGraph([]*spec.ServiceSpec{app, db})
+	if err := g.checkConditions(); err != nil {
+		t.Errorf("expected started condition without a health check to pass, got: %v", err)
+	}
+}
+
+func TestCheckConditionsDisabledRequirement(t *testing.T) {
+	disabled := false
+	db := makeSpec("db", nil, nil)
+	db.Enabled = &disabled
+	app := makeSpec("app", []string{"db"}, []string{"db"})
+
+	g := newDepGraph([]*spec.ServiceSpec{app, db})
+	if err := g.checkConditions(); err == nil || !strings.Contains(err.Error(), "disabled") {
+		t.Errorf("expected error for requiring a disabled service, got: %v", err)
+	}
+
+	// A disabled dependent is fine, and so is ordering after a disabled service
+	app.Enabled = &disabled
+	g = newDepGraph([]*spec.ServiceSpec{app, db})
+	if err := g.checkConditions(); err != nil {
+		t.Errorf("expected a disabled dependent to pass, got: %v", err)
+	}
+	g = newDepGraph([]*spec.ServiceSpec{makeSpec("app", []string{"db"}, nil), db})
+	if err := g.checkConditions(); err != nil {
+		t.Errorf("expected after on a disabled service to pass, got: %v", err)
+	}
+}
+
 func TestStartOrderSkipsUnknownDeps(t *testing.T) {
 	// b depends on "external" which isn't in the graph — should be skipped
 	g := newDepGraph([]*spec.ServiceSpec{
@@ -228,3 +392,20 @@ func TestStartOrderSkipsUnknownDeps(t *testing.T) {
 		t.Fatalf("expected 2 services, got %d", len(order))
 	}
 }
+
+func TestCheckRequires(t *testing.T) {
+	g := newDepGraph([]*spec.ServiceSpec{
+		makeSpec("db", nil, nil),
+		makeSpec("app", []string{"db"}, []string{"db"}),
+	})
+	if err := g.checkRequires(); err != nil {
+		t.Errorf("expected loaded requirement to pass, got: %v", err)
+	}
+
+	g = newDepGraph([]*spec.ServiceSpec{
+		makeSpec("app", []string{"db"}, []string{"db"}),
+	})
+	if err := g.checkRequires(); err == nil {
+		t.Error("expected error for a requirement that is not loaded")
+	}
+}
@@ -0,0 +1,166 @@
+package daemon
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/benaskins/aurelia/internal/health"
+)
+
+// Event types recorded in the daemon event log.
+const (
+	EventStarted      = "started"
+	EventStartFailed  = "start_failed"
+	EventWaiting      = "waiting"
+	EventExited       = "exited"
+	EventRestarting   = "restarting"
+	EventStopped      = "stopped"
+	EventAdopted      = "adopted"
+	EventRemoved      = "removed"
+	EventHealth       = "health"
+	EventReloaded     = "reloaded"
+	EventDeploying    = "deploying"
+	EventDeployed     = "deployed"
+	EventDeployFailed = "deploy_failed"
+	EventScaled       = "scaled"
+)
+
+const (
+	// eventLogSize is how many events the daemon keeps; older ones are dropped.
+	eventLogSize = 1000
+
+	// eventWatchBuffer is how many events a watcher can fall behind by before
+	// further events are dropped for it.
+	eventWatchBuffer = 64
+)
+
+// Event is one entry in the daemon event log. Service is empty for
+// daemon-wide events such as reloads.
+type Event struct {
+	Time    time.Time `json:"time"`
+	Service string    `json:"service,omitempty"`
+	Type    string    `json:"type"`
+	Detail  string    `json:"detail,omitempty"`
+}
+
+// eventLog keeps the most recent events in memory and fans new ones out to
+// watchers. The zero value is ready to use.
+type eventLog struct {
+	mu       sync.Mutex
+	events   []Event
+	watchers []chan Event
+}
+
+func (l *eventLog) record(ev Event) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.events = append(l.events, ev)
+	if len(l.events) > eventLogSize {
+		l.events = l.events[1:]
+	}
+	for _, ch := range l.watchers {
+		select {
+		case ch <- ev:
+		default: // slow watcher; drop rather than stall the caller
+		}
+	}
+}
+
+func (l *eventLog) snapshot() []Event {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return slices.Clone(l.events)
+}
+
+func (l *eventLog) watch() (<-chan Event, func()) {
+	ch := make(chan Event, eventWatchBuffer)
+
+	l.mu.Lock()
+	l.watchers = append(l.watchers, ch)
+	l.mu.Unlock()
+
+	cancel := func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		l.watchers = slices.DeleteFunc(l.watchers, func(c chan Event) bool { return c == ch })
+	}
+	return ch, cancel
+}
+
+// Events returns the recorded events, oldest first. Only the most recent
+// events are kept.
+func (d *Daemon) Events() []Event {
+	return d.events.snapshot()
+}
+
+// WatchEvents subscribes to events as they are recorded until cancel is
+// called. A watcher that falls behind misses events rather than slowing the
+// daemon.
+func (d *Daemon) WatchEvents() (events <-chan Event, cancel func()) {
+	return d.events.watch()
+}
+
+// recordEvent adds an event to the daemon event log.
+func (d *Daemon) recordEvent(service, eventType, detail string) {
+	d.events.record(Event{Time: time.Now(), Service: service, Type: eventType, Detail: detail})
+}
+
+// serviceEvents returns the onEvent callback that records a managed
+// service's events under its name, regenerating routing when its health
+// changes.
+func (d *Daemon) serviceEvents(name string) func(eventType, detail string) {
+	return func(eventType, detail string) {
+		d.recordEvent(name, eventType, detail)
+		if eventType == EventHealth {
+			// Routes follow health. Regenerate off the monitor's goroutine:
+			// stopping the monitor waits for it, and a caller doing that may
+			// hold d.mu
+			go func() {
+				d.regenerateRouting()
+				if detail != string(health.StatusHealthy) {
+					d.regenerateRoutingAfterGrace(name)
+				}
+			}()
+		}
+	}
+}
+
+// regenerateRoutingAfterGrace regenerates routing once a service or replica
+// that has stopped passing its health check is past its
+// routing.unhealthy_grace, to drop the route the grace kept.
+func (d *Daemon) regenerateRoutingAfterGrace(name string) {
+	ms := d.instance(name)
+	if ms == nil {
+		return
+	}
+	left := ms.routingGraceLeft(time.Now())
+	if left == 0 {
+		return
+	}
+	ctx := d.ctx
+	time.AfterFunc(left, func() {
+		if ctx == nil || ctx.Err() == nil {
+			d.regenerateRouting()
+		}
+	})
+}
+
+// instance returns the service or replica whose instance name is name, or
+// nil if there is none.
+func (d *Daemon) instance(name string) *ManagedService {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if ms, ok := d.services[name]; ok {
+		return ms
+	}
+	for _, ms := range d.services {
+		for _, r := range ms.replicaList() {
+			if r.instanceName() == name {
+				return r
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package daemon
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/benaskins/aurelia/internal/driver"
+)
+
+// eventTypes returns the types of the recorded events for service, oldest first.
+func eventTypes(d *Daemon, service string) []string {
+	var types []string
+	for _, ev := range d.Events() {
+		if ev.Service == service {
+			types = append(types, ev.Type)
+		}
+	}
+	return types
+}
+
+func TestEventsStartStopCycle(t *testing.T) {
+	dir := t.TempDir()
+	writeSpec(t, dir, "web.yaml", `
+service:
+  name: web
+  type: native
+  command: "sleep 10"
+
+health:
+  type: exec
+  command: "true"
+  interval: 50ms
+  timeout: 1s
+`)
+
+	d := NewDaemon(dir, WithStateDir(t.TempDir()))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := d.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer d.Stop(5 * time.Second)
+
+	waitUntil(t, func() bool {
+		return slices.Contains(eventTypes(d, "web"), EventHealth)
+	}, 3*time.Second, "health transition event")
+
+	if err := d.StopService(context.Background(), "web", 5*time.Second); err != nil {
+		t.Fatalf("StopService: %v", err)
+	}
+	if err := d.StartService(ctx, "web"); err != nil {
+		t.Fatalf("StartService: %v", err)
+	}
+	waitUntil(t, func() bool {
+		st, _ := d.ServiceState("web")
+		return st.State == driver.StateRunning && len(eventTypes(d, "web")) >= 4
+	}, 3*time.Second, "web to restart")
+
+	want := []string{EventStarted, EventHealth, EventStopped, EventStarted}
+	if got := eventTypes(d, "web")[:4]; !slices.Equal(got, want) {
+		t.Errorf("events = %v, want %v", got, want)
+	}
+
+	for _, ev := range d.Events() {
+		if ev.Service == "web" && ev.Type == EventHealth && ev.Detail != "healthy" {
+			t.Errorf("health event detail = %q, want healthy", ev.Detail)
+		}
+		if ev.Time.IsZero() {
+			t.Errorf("event %+v has no timestamp", ev)
+		}
+	}
+}
+
+func TestEventsExitAndReload(t *testing.T) {
+	dir := t.TempDir()
+	writeSpec(t, dir, "job.yaml", `
+service:
+  name: job
+  type: native
+  command: "false"
+
+restart:
+  policy: never
+`)
+
+	d := NewDaemon(dir, WithStateDir(t.TempDir()))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := d.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer d.Stop(5 * time.Second)
+
+	waitUntil(t, func() bool {
+		return slices.Contains(eventTypes(d, "job"), EventExited)
+	}, 3*time.Second, "exit event")
+
+	for _, ev := range d.Events() {
+		if ev.Type == EventExited && ev.Detail != "exit code 1" {
+			t.Errorf("exit event detail = %q, want %q", ev.Detail, "exit code 1")
+		}
+	}
+
+	writeSpec(t, dir, "worker.yaml", `
+service:
+  name: worker
+  type: native
+  command: "sleep 10"
+`)
+	events, cancelWatch := d.WatchEvents()
+	defer cancelWatch()
+	if _, err := d.Reload(ctx); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+
+	timeout := time.After(3 * time.Second)
+	for {
+		select {
+		case ev := <-events:
+			if ev.Type != EventReloaded {
+				continue
+			}
+			if ev.Service != "" || ev.Detail != "added worker" {
+				t.Errorf("reload event = %+v, want daemon-wide with detail %q", ev, "added worker")
+			}
+			return
+		case <-timeout:
+			t.Fatal("timed out waiting for reload event")
+		}
+	}
+}
+
+func TestEventLogIsBounded(t *testing.T) {
+	var l eventLog
+	for i := range eventLogSize + 10 {
+		l.record(Event{Type: EventStarted, Detail: string(rune('a' + i%26))})
+	}
+	events := l.snapshot()
+	if len(events) != eventLogSize {
+		t.Fatalf("kept %d events, want %d", len(events), eventLogSize)
+	}
+	// The oldest 10 were dropped
+	if events[0].Detail != string(rune('a'+10%26)) {
+		t.Errorf("oldest event = %q, want %q", events[0].Detail, string(rune('a'+10%26)))
+	}
+}
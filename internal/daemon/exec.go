@@ -0,0 +1,60 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"time"
+
+	"github.com/benaskins/aurelia/internal/driver"
+)
+
+// ExecInService runs argv in the context of the named service, writing its
+// combined output to out as it is produced, and returns the exit code.
+// Container services exec inside the running container; native services run
+// argv on the host with the service's env and working directory.
+func (d *Daemon) ExecInService(ctx context.Context, name string, argv []string, out io.Writer) (int, error) {
+	ms, err := d.getService(name)
+	if err != nil {
+		return -1, err
+	}
+	return ms.Exec(ctx, argv, out)
+}
+
+// Exec runs argv in the service's context. See Daemon.ExecInService.
+func (ms *ManagedService) Exec(ctx context.Context, argv []string, out io.Writer) (int, error) {
+	if len(argv) == 0 {
+		return -1, fmt.Errorf("command is required")
+	}
+	name := ms.spec.Service.Name
+
+	switch ms.spec.Service.Type {
+	case "container":
+		ms.mu.Lock()
+		drv := ms.drv
+		ms.mu.Unlock()
+		cd, ok := drv.(*driver.ContainerDriver)
+		if !ok || cd == nil || cd.Info().State != driver.StateRunning {
+			return -1, fmt.Errorf("service %q has no running container", name)
+		}
+		return cd.Exec(ctx, argv, out)
+
+	case "native":
+		cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+		cmd.Env = ms.hookEnv(ms.envPort())
+		cmd.Dir = ms.spec.Service.WorkingDir
+		cmd.Stdout = out
+		cmd.Stderr = out
+		cmd.WaitDelay = time.Second
+
+		err := cmd.Run()
+		if cmd.ProcessState == nil {
+			return -1, fmt.Errorf("running %s: %w", argv[0], err)
+		}
+		return cmd.ProcessState.ExitCode(), nil
+
+	default:
+		return -1, fmt.Errorf("cannot exec in %s service %q", ms.spec.Service.Type, name)
+	}
+}
@@ -0,0 +1,82 @@
+package daemon
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/benaskins/aurelia/internal/spec"
+)
+
+func TestExecInServiceNativeInheritsEnvAndWorkingDir(t *testing.T) {
+	dir := t.TempDir()
+	workDir := t.TempDir()
+	writeSpec(t, dir, "web.yaml", `
+service:
+  name: web
+  type: native
+  command: "sleep 30"
+  working_dir: `+workDir+`
+
+network:
+  port: 0
+
+env:
+  GREETING: hello
+`)
+
+	d := NewDaemon(dir, WithPortRange(46300, 46400))
+	if err := d.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer d.Stop(5 * time.Second)
+
+	var out bytes.Buffer
+	code, err := d.ExecInService(context.Background(), "web", []string{"sh", "-c", `echo "$GREETING $PORT"; pwd; exit 7`}, &out)
+	if err != nil {
+		t.Fatalf("ExecInService: %v", err)
+	}
+	if code != 7 {
+		t.Errorf("expected exit code 7, got %d", code)
+	}
+
+	st, _ := d.ServiceState("web")
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines of output, got %q", out.String())
+	}
+	if want := "hello " + strconv.Itoa(st.Port); lines[0] != want {
+		t.Errorf("expected env %q, got %q", want, lines[0])
+	}
+	// Resolve symlinks (macOS /var -> /private/var)
+	wantDir, _ := filepath.EvalSymlinks(workDir)
+	if gotDir, _ := filepath.EvalSymlinks(lines[1]); gotDir != wantDir {
+		t.Errorf("expected working dir %q, got %q", wantDir, lines[1])
+	}
+}
+
+func TestExecInServiceRejectsExternal(t *testing.T) {
+	s := &spec.ServiceSpec{
+		Service: spec.Service{Name: "db", Type: "external"},
+		Health:  &spec.HealthCheck{Type: "tcp", Port: 5432},
+	}
+	ms, err := NewManagedService(s, nil)
+	if err != nil {
+		t.Fatalf("NewManagedService: %v", err)
+	}
+	if _, err := ms.Exec(context.Background(), []string{"true"}, os.Stdout); err == nil {
+		t.Error("expected error exec'ing in an external service")
+	}
+}
+
+func TestExecInServiceUnknownService(t *testing.T) {
+	d := NewDaemon(t.TempDir())
+	if _, err := d.ExecInService(context.Background(), "missing", []string{"true"}, os.Stdout); err == nil {
+		t.Error("expected error for unknown service")
+	}
+}
@@ -0,0 +1,135 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/benaskins/aurelia/internal/driver"
+	"github.com/benaskins/aurelia/internal/health"
+)
+
+const (
+	// defaultPostStartTimeout bounds a lifecycle post_start command when the
+	// spec does not set post_start_timeout.
+	defaultPostStartTimeout = 30 * time.Second
+
+	// defaultPreStopTimeout bounds a lifecycle pre_stop command when the spec
+	// does not set pre_stop_timeout.
+	defaultPreStopTimeout = 10 * time.Second
+)
+
+// runPostStart runs the spec's lifecycle post_start command, if any, for a
+// process that has just been started on port. With a health monitor it first
+// waits for the process to become healthy; if the process exits, turns
+// unhealthy, or ctx ends first, the hook is skipped and supervision handles
+// the outcome. A failing hook returns an error only when post_start_required
+// is set; otherwise it is logged.
+func (ms *ManagedService) runPostStart(ctx context.Context, drv driver.Driver, monitor *health.Monitor, port int) error {
+	lc := ms.spec.Lifecycle
+	if lc == nil || lc.PostStart == "" {
+		return nil
+	}
+
+	if monitor != nil && !ms.awaitHealthy(ctx, drv, monitor) {
+		ms.logger.Warn("skipping post_start hook, service did not become healthy")
+		return nil
+	}
+
+	timeout := lc.PostStartTimeout.Duration
+	if timeout <= 0 {
+		timeout = defaultPostStartTimeout
+	}
+
+	ms.logger.Info("running post_start hook", "timeout", timeout)
+	output, err := ms.runLifecycleCommand(ctx, lc.PostStart, timeout, port)
+	if err != nil {
+		if lc.PostStartRequired {
+			ms.logger.Error("post_start hook failed", "error", err, "output", output)
+			return fmt.Errorf("post_start hook failed: %w", err)
+		}
+		ms.logger.Warn("post_start hook failed, continuing", "error", err, "output", output)
+		return nil
+	}
+	ms.logger.Info("post_start hook completed", "output", output)
+	return nil
+}
+
+// awaitHealthy polls monitor until it reports healthy. Returns false if the
+// monitor reports unhealthy, the process exits, or ctx is cancelled first.
+func (ms *ManagedService) awaitHealthy(ctx context.Context, drv driver.Driver, monitor *health.Monitor) bool {
+	exited := ms.waitForExit(drv)
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		switch monitor.CurrentStatus() {
+		case health.StatusHealthy:
+			return true
+		case health.StatusUnhealthy:
+			return false
+		}
+		select {
+		case <-exited:
+			return false
+		case <-ctx.Done():
+			return false
+		case <-ticker.C:
+		}
+	}
+}
+
+// runPreStop executes the spec's lifecycle pre_stop command, if any, in the
+// service's environment. Failures are logged and otherwise ignored — the
+// caller proceeds with termination regardless.
+func (ms *ManagedService) runPreStop() {
+	lc := ms.spec.Lifecycle
+	if lc == nil || lc.PreStop == "" {
+		return
+	}
+
+	timeout := lc.PreStopTimeout.Duration
+	if timeout <= 0 {
+		timeout = defaultPreStopTimeout
+	}
+
+	ms.logger.Info("running pre_stop hook", "timeout", timeout)
+	output, err := ms.runLifecycleCommand(context.Background(), lc.PreStop, timeout, ms.envPort())
+	if err != nil {
+		ms.logger.Warn("pre_stop hook failed, proceeding with stop", "error", err, "output", output)
+		return
+	}
+	ms.logger.Info("pre_stop hook completed", "output", output)
+}
+
+// runLifecycleCommand runs command via sh -c with the service's env (for the
+// instance listening on port) and working directory, returning its trimmed
+// combined output.
+func (ms *ManagedService) runLifecycleCommand(ctx context.Context, command string, timeout time.Duration, port int) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Env = ms.hookEnv(port)
+	cmd.Dir = ms.spec.Service.WorkingDir
+	// Don't let a backgrounded grandchild holding the output pipe keep us
+	// waiting past the timeout.
+	cmd.WaitDelay = time.Second
+
+	out, err := cmd.CombinedOutput()
+	return strings.TrimSpace(string(out)), err
+}
+
+// hookEnv is the environment for lifecycle commands: the service's own env
+// (port, interpolated env block, secrets) layered over the host env, which
+// container services don't otherwise inherit.
+func (ms *ManagedService) hookEnv(port int) []string {
+	env := ms.buildEnvWithPort(port)
+	if ms.spec.Service.Type == "native" {
+		return env
+	}
+	return append(os.Environ(), env...)
+}
@@ -0,0 +1,249 @@
+package daemon
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/benaskins/aurelia/internal/driver"
+	"github.com/benaskins/aurelia/internal/spec"
+)
+
+// syncBuffer is a bytes.Buffer safe for concurrent log writes.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// writeTrapScript writes a long-running script that appends "signalled" to
+// $MARKER when it receives SIGTERM.
+func writeTrapScript(t *testing.T, dir string) string {
+	t.Helper()
+	script := filepath.Join(dir, "trap.sh")
+	content := "#!/bin/sh\ntrap 'echo signalled >> \"$MARKER\"; exit 0' TERM\nwhile :; do sleep 0.05; done\n"
+	if err := os.WriteFile(script, []byte(content), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return script
+}
+
+func readMarker(t *testing.T, path string) []string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading marker: %v", err)
+	}
+	return strings.Fields(string(data))
+}
+
+func TestManagedServicePreStopRunsBeforeSignal(t *testing.T) {
+	dir := t.TempDir()
+	marker := filepath.Join(dir, "marker")
+
+	s := &spec.ServiceSpec{
+		Service: spec.Service{
+			Name:    "drain-svc",
+			Type:    "native",
+			Command: writeTrapScript(t, dir),
+		},
+		Env: map[string]string{"MARKER": marker},
+		Lifecycle: &spec.Lifecycle{
+			PreStop: `echo "deregistered $AURELIA_SERVICE"; echo pre_stop >> "$MARKER"`,
+		},
+	}
+
+	ms, err := NewManagedService(s, nil)
+	if err != nil {
+		t.Fatalf("NewManagedService: %v", err)
+	}
+	logs := &syncBuffer{}
+	ms.logger = slog.New(slog.NewTextHandler(logs, nil))
+
+	if err := ms.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	waitUntil(t, func() bool {
+		return ms.State().State == driver.StateRunning
+	}, 2*time.Second, "service to start")
+
+	if err := ms.Stop(5 * time.Second); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+
+	got := readMarker(t, marker)
+	if len(got) != 2 || got[0] != "pre_stop" || got[1] != "signalled" {
+		t.Errorf("expected pre_stop before signal, marker = %v", got)
+	}
+	if !strings.Contains(logs.String(), "deregistered drain-svc") {
+		t.Errorf("expected pre_stop output in service logs, got:\n%s", logs.String())
+	}
+}
+
+func TestManagedServicePreStopFailureStillStops(t *testing.T) {
+	dir := t.TempDir()
+	marker := filepath.Join(dir, "marker")
+
+	s := &spec.ServiceSpec{
+		Service: spec.Service{
+			Name:    "drain-fail",
+			Type:    "native",
+			Command: writeTrapScript(t, dir),
+		},
+		Env: map[string]string{"MARKER": marker},
+		Lifecycle: &spec.Lifecycle{
+			PreStop:        "sleep 5",
+			PreStopTimeout: spec.Duration{Duration: 200 * time.Millisecond},
+		},
+	}
+
+	ms, err := NewManagedService(s, nil)
+	if err != nil {
+		t.Fatalf("NewManagedService: %v", err)
+	}
+	if err := ms.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	waitUntil(t, func() bool {
+		return ms.State().State == driver.StateRunning
+	}, 2*time.Second, "service to start")
+
+	start := time.Now()
+	if err := ms.Stop(5 * time.Second); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("pre_stop timeout not enforced, stop took %v", elapsed)
+	}
+	if got := readMarker(t, marker); len(got) != 1 || got[0] != "signalled" {
+		t.Errorf("expected process to be signalled after failed pre_stop, marker = %v", got)
+	}
+	if st := ms.State().State; st != driver.StateStopped {
+		t.Errorf("expected stopped, got %v", st)
+	}
+}
+
+func TestManagedServicePostStartRunsOnceAfterHealthy(t *testing.T) {
+	dir := t.TempDir()
+	marker := filepath.Join(dir, "marker")
+	healthy := filepath.Join(dir, "healthy")
+
+	s := &spec.ServiceSpec{
+		Service: spec.Service{
+			Name:    "warm-svc",
+			Type:    "native",
+			Command: "sleep 60",
+		},
+		Health: &spec.HealthCheck{
+			Type:     "exec",
+			Command:  "touch " + healthy,
+			Interval: spec.Duration{Duration: 50 * time.Millisecond},
+			Timeout:  spec.Duration{Duration: time.Second},
+		},
+		Env: map[string]string{"MARKER": marker},
+		Lifecycle: &spec.Lifecycle{
+			// Records whether the health check had already passed when it ran
+			PostStart: `if [ -f "` + healthy + `" ]; then echo after_healthy >> "$MARKER"; else echo too_early >> "$MARKER"; fi`,
+		},
+	}
+
+	ms, err := NewManagedService(s, nil)
+	if err != nil {
+		t.Fatalf("NewManagedService: %v", err)
+	}
+	if err := ms.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer ms.Stop(5 * time.Second)
+
+	waitUntil(t, func() bool {
+		_, err := os.Stat(marker)
+		return err == nil
+	}, 3*time.Second, "post_start hook to run")
+
+	// Further health checks must not re-run the hook
+	time.Sleep(300 * time.Millisecond)
+	if got := readMarker(t, marker); len(got) != 1 || got[0] != "after_healthy" {
+		t.Errorf("expected a single post_start run after healthy, marker = %v", got)
+	}
+	if st := ms.State().State; st != driver.StateRunning {
+		t.Errorf("expected running, got %v", st)
+	}
+}
+
+func TestManagedServicePostStartRequiredFailureFailsStart(t *testing.T) {
+	s := &spec.ServiceSpec{
+		Service: spec.Service{
+			Name:    "warm-fail",
+			Type:    "native",
+			Command: "sleep 60",
+		},
+		Restart: &spec.RestartPolicy{Policy: "always", MaxAttempts: 1, Delay: spec.Duration{Duration: 10 * time.Millisecond}},
+		Lifecycle: &spec.Lifecycle{
+			PostStart:         "exit 3",
+			PostStartRequired: true,
+		},
+	}
+
+	ms, err := NewManagedService(s, nil)
+	if err != nil {
+		t.Fatalf("NewManagedService: %v", err)
+	}
+	if err := ms.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer ms.Stop(5 * time.Second)
+
+	// One restart is allowed, then supervision gives up with the process stopped
+	waitUntil(t, func() bool {
+		st := ms.State()
+		return st.RestartCount == 1 && st.State != driver.StateRunning && st.State != driver.StateStarting
+	}, 5*time.Second, "service to give up after failed post_start")
+
+	time.Sleep(200 * time.Millisecond)
+	if st := ms.State(); st.RestartCount != 1 || st.State == driver.StateRunning {
+		t.Errorf("expected service stopped after 1 restart, got %s with %d restarts", st.State, st.RestartCount)
+	}
+}
+
+func TestManagedServicePostStartOptionalFailureKeepsRunning(t *testing.T) {
+	s := &spec.ServiceSpec{
+		Service: spec.Service{
+			Name:    "warm-optional",
+			Type:    "native",
+			Command: "sleep 60",
+		},
+		Lifecycle: &spec.Lifecycle{PostStart: "exit 3"},
+	}
+
+	ms, err := NewManagedService(s, nil)
+	if err != nil {
+		t.Fatalf("NewManagedService: %v", err)
+	}
+	if err := ms.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer ms.Stop(5 * time.Second)
+
+	time.Sleep(300 * time.Millisecond)
+	if st := ms.State(); st.State != driver.StateRunning || st.RestartCount != 0 {
+		t.Errorf("expected running with no restarts, got %s with %d restarts", st.State, st.RestartCount)
+	}
+}
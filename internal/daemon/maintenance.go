@@ -0,0 +1,60 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// EventMaintenance is recorded when the daemon enters maintenance mode.
+const EventMaintenance = "maintenance"
+
+// ErrMaintenance is returned for operations that would start, stop or
+// change services while the daemon is in maintenance mode.
+var ErrMaintenance = errors.New("daemon is in maintenance mode")
+
+// EnterMaintenance releases every service — supervision and health checks
+// stop but the processes and containers keep running — and leaves their
+// state records in place, so the next daemon to start adopts them. The API
+// keeps serving, but operations that would change services fail with
+// ErrMaintenance, and stopping the daemon no longer touches the processes.
+// Maintenance mode lasts until the daemon restarts; entering it twice is a
+// no-op.
+func (d *Daemon) EnterMaintenance(timeout time.Duration) error {
+	d.mu.Lock()
+	if d.maintenance {
+		d.mu.Unlock()
+		return nil
+	}
+	d.maintenance = true
+	services := make([]*ManagedService, 0, len(d.services))
+	for _, ms := range d.services {
+		services = append(services, ms)
+	}
+	d.mu.Unlock()
+
+	d.logger.Info("entering maintenance mode, releasing all services", "services", len(services))
+	var errs []error
+	for _, ms := range services {
+		if err := ms.Release(timeout); err != nil {
+			errs = append(errs, fmt.Errorf("releasing %s: %w", ms.spec.Service.Name, err))
+		}
+	}
+	d.recordEvent("", EventMaintenance, fmt.Sprintf("released %d services", len(services)))
+	return errors.Join(errs...)
+}
+
+// InMaintenance reports whether the daemon is in maintenance mode.
+func (d *Daemon) InMaintenance() bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.maintenance
+}
+
+// checkMaintenance returns ErrMaintenance if the daemon is in maintenance mode.
+func (d *Daemon) checkMaintenance() error {
+	if d.InMaintenance() {
+		return ErrMaintenance
+	}
+	return nil
+}
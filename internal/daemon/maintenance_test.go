@@ -0,0 +1,81 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestEnterMaintenanceKeepsProcesses(t *testing.T) {
+	dir := t.TempDir()
+	stateDir := t.TempDir()
+	writeSpec(t, dir, "sleeper.yaml", `
+service:
+  name: sleeper
+  type: native
+  command: "sleep 300"
+`)
+
+	d := NewDaemon(dir, WithStateDir(stateDir), WithSpecWatch(false))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := d.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	// Wait for the process to start and state to be persisted
+	time.Sleep(200 * time.Millisecond)
+	state, err := d.ServiceState("sleeper")
+	if err != nil || state.PID == 0 {
+		t.Fatalf("expected a running sleeper, got %+v, %v", state, err)
+	}
+	pid := state.PID
+	proc, err := os.FindProcess(pid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proc.Kill()
+
+	if err := d.EnterMaintenance(5 * time.Second); err != nil {
+		t.Fatalf("EnterMaintenance: %v", err)
+	}
+	if !d.Info().Maintenance {
+		t.Error("expected Info to report maintenance")
+	}
+
+	// Operations that would change services are refused
+	if err := d.StopService(context.Background(), "sleeper", time.Second); !errors.Is(err, ErrMaintenance) {
+		t.Errorf("StopService: expected ErrMaintenance, got %v", err)
+	}
+	if _, err := d.Reload(ctx); !errors.Is(err, ErrMaintenance) {
+		t.Errorf("Reload: expected ErrMaintenance, got %v", err)
+	}
+
+	// Stopping the daemon leaves the released process alone
+	d.Stop(5 * time.Second)
+	if err := proc.Signal(syscall.Signal(0)); err != nil {
+		t.Fatalf("process %d not alive after maintenance and stop: %v", pid, err)
+	}
+
+	// and its state record stays for the next daemon to adopt
+	records, err := newStateFile(stateDir).load()
+	if err != nil {
+		t.Fatalf("loading state: %v", err)
+	}
+	if rec, ok := records["sleeper"]; !ok || rec.PID != pid {
+		t.Errorf("expected state record with PID %d, got %+v (present %v)", pid, rec, ok)
+	}
+
+	// A new daemon adopts the process
+	d2 := NewDaemon(dir, WithStateDir(stateDir), WithSpecWatch(false))
+	if err := d2.Start(ctx); err != nil {
+		t.Fatalf("Start after maintenance: %v", err)
+	}
+	defer d2.Stop(5 * time.Second)
+	if len(d2.adopted) != 1 || d2.adopted[0] != "sleeper" {
+		t.Errorf("expected sleeper adopted, got adopted=%v", d2.adopted)
+	}
+}
@@ -0,0 +1,95 @@
+package daemon
+
+import (
+	"fmt"
+	"time"
+)
+
+// RollbackResult is the outcome of rolling a service back to its previous deploy.
+type RollbackResult struct {
+	Service string `json:"service"`
+	Status  string `json:"status"` // "rolled_back" | "unchanged"
+	Image   string `json:"image,omitempty"`
+	Command string `json:"command,omitempty"`
+	Warning string `json:"warning,omitempty"`
+}
+
+// RollbackService redeploys the instance replaced by the service's last
+// blue-green deploy — the previous image for containers, the previous command
+// for native services — and switches routing back to it. The rolled-back
+// instance keeps running from that image or command until the next deploy or
+// spec change; rolling back again returns to the build that was replaced.
+func (d *Daemon) RollbackService(name string, drainTimeout time.Duration) (*RollbackResult, error) {
+	if err := d.checkMaintenance(); err != nil {
+		return nil, err
+	}
+	ms, err := d.getEnabledService(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := d.checkNoDeployInProgress(name); err != nil {
+		return nil, err
+	}
+
+	if ms.spec.Routing == nil || !ms.spec.NeedsDynamicPort() {
+		return nil, fmt.Errorf("service %q cannot be rolled back: rollback requires routing and a dynamic port", name)
+	}
+	if ms.spec.MaxReplicas() > 1 {
+		return nil, fmt.Errorf("service %q cannot be rolled back: replicated services are not deployed blue-green", name)
+	}
+
+	records, err := d.state.load()
+	if err != nil {
+		return nil, fmt.Errorf("loading state: %w", err)
+	}
+	current := records[name]
+	prev := current.Previous
+	if prev == nil {
+		return nil, fmt.Errorf("no previous deploy recorded for %q", name)
+	}
+
+	result := &RollbackResult{Service: name}
+	rolled := *ms.spec
+	switch ms.spec.Service.Type {
+	case "container":
+		if prev.Image == "" {
+			return nil, fmt.Errorf("no previous image recorded for %q", name)
+		}
+		result.Image = prev.Image
+		if prev.Image == current.Image {
+			result.Status = "unchanged"
+			result.Warning = "previous deploy ran the same image"
+			d.logger.Warn("rollback skipped, previous deploy ran the same image", "service", name, "image", prev.Image)
+			return result, nil
+		}
+		rolled.Service.Image = prev.Image
+	case "native":
+		result.Command = prev.Command
+		if prev.Command == ms.spec.Service.Command {
+			result.Status = "unchanged"
+			result.Warning = "previous deploy ran the same command; native builds are not versioned"
+			d.logger.Warn("rollback skipped, previous deploy ran the same command", "service", name, "command", prev.Command)
+			return result, nil
+		}
+		rolled.Service.Command = prev.Command
+	default:
+		return nil, fmt.Errorf("service %q cannot be rolled back: unsupported type %q", name, ms.spec.Service.Type)
+	}
+
+	target, err := NewManagedService(&rolled, ms.secrets)
+	if err != nil {
+		return nil, fmt.Errorf("creating managed service wrapper: %w", err)
+	}
+	// Keep the spec file's hash so a reload doesn't undo the rollback
+	target.specHash = ms.specHash
+	target.containerPrefix = ms.containerPrefix
+	target.containerFiles = ms.containerFiles
+	target.globalEnv = ms.globalEnv
+
+	d.logger.Info("starting rollback", "service", name, "image", result.Image, "command", result.Command)
+	if err := d.blueGreenDeploy(name, target, drainTimeout); err != nil {
+		return nil, fmt.Errorf("rollback: %w", err)
+	}
+	result.Status = "rolled_back"
+	return result, nil
+}
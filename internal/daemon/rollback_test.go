@@ -0,0 +1,120 @@
+package daemon
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func startRoutedSleep(t *testing.T, portMin int) *Daemon {
+	t.Helper()
+	dir := t.TempDir()
+	writeSpec(t, dir, "chat.yaml", `
+service:
+  name: chat
+  type: native
+  command: "sleep 30"
+network:
+  port: 0
+routing:
+  hostname: chat.test.internal
+`)
+
+	d := NewDaemon(dir, WithStateDir(t.TempDir()), WithRouting(filepath.Join(t.TempDir(), "routing.yaml")), WithPortRange(portMin, portMin+100))
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	if err := d.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(func() { d.Stop(5 * time.Second) })
+
+	waitUntil(t, func() bool {
+		s, _ := d.ServiceState("chat")
+		return s.State == "running"
+	}, 2*time.Second, "chat to become running")
+	// The state record is written once the process has started, after the
+	// state reads running; a deploy takes the previous deploy from it
+	waitUntil(t, func() bool {
+		s, _ := d.ServiceState("chat")
+		records, err := d.state.load()
+		return err == nil && records["chat"].PID == s.PID
+	}, 2*time.Second, "chat's state record")
+	return d
+}
+
+func TestRollbackServiceWithoutDeploy(t *testing.T) {
+	d := startRoutedSleep(t, 27500)
+
+	_, err := d.RollbackService("chat", 50*time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), "no previous deploy") {
+		t.Errorf("expected no previous deploy error, got %v", err)
+	}
+}
+
+func TestRollbackServiceNativeUnchanged(t *testing.T) {
+	d := startRoutedSleep(t, 27600)
+
+	if err := d.DeployService(context.Background(), "chat", 50*time.Millisecond); err != nil {
+		t.Fatalf("DeployService: %v", err)
+	}
+	before, _ := d.ServiceState("chat")
+
+	result, err := d.RollbackService("chat", 50*time.Millisecond)
+	if err != nil {
+		t.Fatalf("RollbackService: %v", err)
+	}
+	if result.Status != "unchanged" || result.Warning == "" {
+		t.Errorf("expected unchanged with warning, got %+v", result)
+	}
+	if after, _ := d.ServiceState("chat"); after.PID != before.PID {
+		t.Errorf("expected no redeploy, PID %d -> %d", before.PID, after.PID)
+	}
+}
+
+func TestRollbackServiceNativeCommand(t *testing.T) {
+	d := startRoutedSleep(t, 27700)
+
+	if err := d.DeployService(context.Background(), "chat", 50*time.Millisecond); err != nil {
+		t.Fatalf("DeployService: %v", err)
+	}
+
+	// Pretend the replaced instance ran a different command
+	records, err := d.state.load()
+	if err != nil {
+		t.Fatalf("load state: %v", err)
+	}
+	rec := records["chat"]
+	if rec.Previous == nil || rec.Previous.Command != "sleep 30" {
+		t.Fatalf("expected deploy to record the previous instance, got %+v", rec)
+	}
+	rec.Previous.Command = "sleep 31"
+	if err := d.state.set("chat", rec); err != nil {
+		t.Fatalf("set state: %v", err)
+	}
+	before, _ := d.ServiceState("chat")
+
+	result, err := d.RollbackService("chat", 50*time.Millisecond)
+	if err != nil {
+		t.Fatalf("RollbackService: %v", err)
+	}
+	if result.Status != "rolled_back" || result.Command != "sleep 31" {
+		t.Errorf("unexpected result: %+v", result)
+	}
+
+	after, _ := d.ServiceState("chat")
+	if after.State != "running" || after.PID == before.PID {
+		t.Errorf("expected running with new PID (before %d), got %v pid %d", before.PID, after.State, after.PID)
+	}
+
+	records, _ = d.state.load()
+	rec = records["chat"]
+	if rec.Command != "sleep 31" {
+		t.Errorf("expected rolled-back command in state, got %q", rec.Command)
+	}
+	if rec.Previous == nil || rec.Previous.Command != "sleep 30" {
+		t.Errorf("expected rolled-back-from instance as previous, got %+v", rec.Previous)
+	}
+}
@@ -2,15 +2,23 @@ package daemon
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"math/rand"
 	"os"
+	"slices"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/benaskins/aurelia/internal/driver"
+	"github.com/benaskins/aurelia/internal/gpu"
 	"github.com/benaskins/aurelia/internal/health"
 	"github.com/benaskins/aurelia/internal/keychain"
+	"github.com/benaskins/aurelia/internal/logbuf"
 	"github.com/benaskins/aurelia/internal/spec"
 )
 
@@ -27,6 +35,67 @@ type ServiceState struct {
 	LastExitCode int           `json:"last_exit_code,omitempty"`
 	LastError    string        `json:"last_error,omitempty"`
 	Node         string        `json:"node,omitempty"`
+
+	// StateReason says why a service that isn't running is down, as one of
+	// the Reason* values. Empty while the service is running or restarting.
+	StateReason string `json:"state_reason,omitempty"`
+
+	// Warming is set while the health monitor is in its grace period, or past
+	// it without a check having passed yet.
+	Warming bool `json:"warming,omitempty"`
+
+	// Flapping is set while the health check keeps switching between
+	// healthy and unhealthy, per health.flap_threshold and flap_window.
+	Flapping bool `json:"flapping,omitempty"`
+
+	// Waiting says what a service is waiting for before it can start, e.g.
+	// "VRAM: 8.0 GB needed, 2.5 GB free". State is starting meanwhile.
+	Waiting string `json:"waiting,omitempty"`
+
+	// CircuitOpenUntil is set (RFC 3339) while restarts are suspended because
+	// the service exceeded restart.max_per_window.
+	CircuitOpenUntil string `json:"circuit_open_until,omitempty"`
+
+	// DependencyHealth lists each hard (requires) dependency and its current
+	// health. Degraded is set when any of them is unhealthy.
+	DependencyHealth []DependencyHealth `json:"dependency_health,omitempty"`
+	Degraded         bool               `json:"degraded,omitempty"`
+
+	// OrphanedChildren lists processes a native service left running when it
+	// was last stopped, such as children that escaped its process group.
+	// Only PIDs still alive are reported.
+	OrphanedChildren []int `json:"orphaned_children,omitempty"`
+
+	// ImageDigest is the registry digest of the image a container service is
+	// running, empty for images that were only built locally. ImageDrift is
+	// set when the last reload found its image tag pointing at a different
+	// image, which a redeploy would pick up.
+	ImageDigest string `json:"image_digest,omitempty"`
+	ImageDrift  bool   `json:"image_drift,omitempty"`
+
+	// Replicas is the state of each further replica of a service with
+	// service.replicas above one, named <service>.<n>. The fields above
+	// describe the first.
+	Replicas []ServiceState `json:"replicas,omitempty"`
+}
+
+// Reasons a service is down, reported as ServiceState.StateReason.
+const (
+	ReasonManualStop        = "manual_stop"        // stopped by the operator or daemon shutdown
+	ReasonDependencyStopped = "dependency_stopped" // cascade-stopped along with a hard dependency
+	ReasonCleanExit         = "clean_exit"         // exited 0 under restart policy on-failure or never
+	ReasonRestartNever      = "restart_never"      // exited non-zero under restart policy never, or without a policy
+	ReasonPolicyExhausted   = "policy_exhausted"   // restart.max_attempts used up
+	ReasonHealthFailure     = "health_failure"     // killed for failing health checks and not restarted
+	ReasonStartFailed       = "start_failed"       // launch or required post_start failed and not retried
+	ReasonStartTimeout      = "start_timeout"      // not healthy within health.max_start_duration and not retried
+	ReasonRestartsSuspended = "restarts_suspended" // restart.max_per_window exceeded; resumes after a cooldown
+)
+
+// DependencyHealth is the observed health of one hard dependency.
+type DependencyHealth struct {
+	Name   string        `json:"name"`
+	Health health.Status `json:"health"`
 }
 
 // ServiceInspect is the full resolved config and runtime state of a managed service.
@@ -40,6 +109,8 @@ type ServiceInspect struct {
 	Port         int          `json:"port,omitempty"`
 	Uptime       string       `json:"uptime,omitempty"`
 	RestartCount int          `json:"restart_count"`
+	ImageDigest  string       `json:"image_digest,omitempty"`
+	ImageDrift   bool         `json:"image_drift,omitempty"`
 
 	// Resolved spec
 	Command      string              `json:"command,omitempty"`
@@ -50,6 +121,7 @@ type ServiceInspect struct {
 	HealthCheck  *spec.HealthCheck   `json:"health_check,omitempty"`
 	Dependencies *spec.Dependencies  `json:"dependencies,omitempty"`
 	Restart      *spec.RestartPolicy `json:"restart,omitempty"`
+	Lifecycle    *spec.Lifecycle     `json:"lifecycle,omitempty"`
 	Source       *spec.Source        `json:"source,omitempty"`
 	SpecHash     string              `json:"spec_hash,omitempty"`
 }
@@ -67,18 +139,69 @@ type ManagedService struct {
 	cancel       context.CancelFunc
 	stopped      chan struct{}
 	// onStarted is called after a process starts successfully (for state persistence)
-	onStarted func(pid int)
+	onStarted func(drv driver.Driver)
+	// onTransition is called with the service's state after each supervision
+	// phase (for state persistence)
+	onTransition func(st ServiceState)
+	// onEvent records lifecycle events in the daemon event log (nil = not recorded)
+	onEvent func(eventType, detail string)
 
 	// unhealthyCh signals the supervision loop to restart due to health failure
 	unhealthyCh chan struct{}
+	// flaps is the health transition history, kept across the monitors of
+	// successive runs so restarts for failing health count towards flapping
+	flaps *health.Flaps
 	// adoptedDrv is set when recovering a previously-running process
 	adoptedDrv driver.Driver
 	// allocatedPort is set when the service uses dynamic port allocation
 	allocatedPort int
+	// containerPrefix is prepended to container names; empty means
+	// driver.DefaultContainerPrefix
+	containerPrefix string
+	// containerFiles is the host directory a container's secret files are
+	// written under; empty means a new temporary directory each start
+	containerFiles string
+	// globalEnv is the daemon's global_env, added beneath the service's env
+	globalEnv map[string]string
+	// replica numbers this instance among the service's replicas from 0;
+	// the first instance, the one in d.services, holds the others in
+	// replicas (guarded by mu, as autoscaling changes them) and starts and
+	// stops them with itself
+	replica  int
+	replicas []*ManagedService
+	// scaledAt is when autoscaling last changed the replica count, or first
+	// looked at the service, for autoscale.cooldown (guarded by mu)
+	scaledAt time.Time
+	// lastHealthy is when the health check last passed or stopped passing,
+	// in Unix nanoseconds (zero = never healthy), for routing.unhealthy_grace
+	lastHealthy atomic.Int64
+
 	// specHash is the SHA-256 hash of the spec at startup, used for change detection on reload
 	specHash string
 	// monitoring is true when a oneshot service is in health-monitoring phase (no process)
 	monitoring bool
+	// restartTimes holds restarts within the current restart.window, oldest first
+	restartTimes []time.Time
+	// circuitOpenUntil is non-zero while restarts are suspended by restart.max_per_window
+	circuitOpenUntil time.Time
+	// gpuInfo reports GPU state for the gpu.requires_vram gate (nil = no gating)
+	gpuInfo func() gpu.Info
+	// waiting is set while the start is held back, e.g. for free VRAM
+	waiting string
+	// reason is why supervision ended (a Reason* value), cleared on Start
+	reason string
+	// healthKilled is set when the current process was stopped for failing
+	// health checks, so giving up on it is reported as a health failure
+	healthKilled bool
+	// startDeadline is when a freshly started process must have passed a
+	// health check by, per health.max_start_duration (zero = no deadline)
+	startDeadline time.Time
+	// orphans are processes found still running after the native process
+	// was last stopped
+	orphans []int
+	// tagImage is the image ID a container's image tag pointed to at the
+	// last reload, for reporting drift from the running image
+	tagImage string
 }
 
 // NewManagedService creates a managed service from a spec.
@@ -91,12 +214,16 @@ func NewManagedService(s *spec.ServiceSpec, secrets keychain.Store) (*ManagedSer
 		return nil, fmt.Errorf("unsupported service type %q (expected native, container, external, or remote)", s.Service.Type)
 	}
 
-	return &ManagedService{
+	ms := &ManagedService{
 		spec:        s,
 		secrets:     secrets,
 		logger:      slog.With("service", s.Service.Name),
 		unhealthyCh: make(chan struct{}, 1),
-	}, nil
+	}
+	if h := s.Health; h != nil {
+		ms.flaps = health.NewFlaps(h.FlapThreshold, h.FlapWindow.Duration)
+	}
+	return ms, nil
 }
 
 // IsExternal returns true for external (unmanaged) services.
@@ -121,9 +248,36 @@ func (ms *ManagedService) EffectivePort() int {
 	return 0
 }
 
-// Start begins running the service with restart supervision.
+// healthCheckPort returns the port the spec's health check targets for an
+// instance listening on port: health.port when set, otherwise port. The
+// monitor passes EffectivePort, a deploy the new instance's temporary port.
+func (ms *ManagedService) healthCheckPort(port int) int {
+	if h := ms.spec.Health; h != nil && h.Port != 0 {
+		return h.Port
+	}
+	return port
+}
+
+// Start begins running the service, and any of its replicas not already
+// running, with restart supervision.
 // For external services, it starts health monitoring only (no process supervision).
 func (ms *ManagedService) Start(ctx context.Context) error {
+	if err := ms.startInstance(ctx); err != nil {
+		return err
+	}
+	for _, r := range ms.replicaList() {
+		if r.supervised() {
+			continue
+		}
+		if err := r.startInstance(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// startInstance starts this instance alone, leaving other replicas as they are.
+func (ms *ManagedService) startInstance(ctx context.Context) error {
 	ms.mu.Lock()
 	if ms.cancel != nil {
 		ms.mu.Unlock()
@@ -133,9 +287,10 @@ func (ms *ManagedService) Start(ctx context.Context) error {
 	svcCtx, cancel := context.WithCancel(ctx)
 	ms.cancel = cancel
 	ms.stopped = make(chan struct{})
+	ms.reason = ""
 
 	if ms.IsExternal() {
-		monitor := ms.startHealthMonitor(svcCtx)
+		monitor := ms.startHealthMonitor(svcCtx, false)
 		ms.monitor = monitor
 		ms.mu.Unlock()
 		go func() {
@@ -168,7 +323,7 @@ func (ms *ManagedService) Start(ctx context.Context) error {
 		}
 
 		ms.mu.Lock()
-		monitor := ms.startHealthMonitor(svcCtx)
+		monitor := ms.startHealthMonitor(svcCtx, false)
 		ms.monitor = monitor
 		ms.mu.Unlock()
 
@@ -192,12 +347,41 @@ func (ms *ManagedService) Start(ctx context.Context) error {
 }
 
 // Stop gracefully stops the service and its supervision loop.
+// The lifecycle pre_stop command, if any, runs before the process is signalled.
 // For external services, it stops health monitoring only.
 func (ms *ManagedService) Stop(timeout time.Duration) error {
+	return ms.stop(timeout, true, ReasonManualStop)
+}
+
+// stop implements Stop, stopping the service's replicas alongside it.
+// preStop is false when the caller has already run the pre_stop hook (deploy
+// drain runs it before the drain period). reason is reported in State once
+// the service is down.
+func (ms *ManagedService) stop(timeout time.Duration, preStop bool, reason string) error {
+	replicas := ms.replicaList()
+	errs := make([]error, len(replicas)+1)
+	var wg sync.WaitGroup
+	for i, r := range replicas {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i+1] = r.stopInstance(timeout, preStop, reason)
+		}()
+	}
+	errs[0] = ms.stopInstance(timeout, preStop, reason)
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// stopInstance stops this instance alone, leaving other replicas running.
+func (ms *ManagedService) stopInstance(timeout time.Duration, preStop bool, reason string) error {
 	// Cancel first to prevent restarts during shutdown
 	if err := ms.detach(timeout + 5*time.Second); err != nil {
 		return err
 	}
+	ms.setReason(reason)
+	// A stopped service gets no routing grace from its last health failure
+	ms.lastHealthy.Store(0)
 
 	// Stop the final driver — read ms.drv after supervision exits since the
 	// loop may have swapped in a new driver before seeing the cancellation
@@ -205,18 +389,45 @@ func (ms *ManagedService) Stop(timeout time.Duration) error {
 	drv := ms.drv
 	ms.mu.Unlock()
 	if drv != nil {
+		if preStop && drv.Info().State == driver.StateRunning {
+			ms.runPreStop()
+		}
+		running := drv.Info().State == driver.StateRunning
 		if err := drv.Stop(context.Background(), timeout); err != nil {
 			ms.logger.Warn("error stopping service", "error", err)
 		}
+		if running {
+			ms.emit(EventStopped, "")
+		}
 	}
 
 	return nil
 }
 
+// setReason records why supervision ended.
+func (ms *ManagedService) setReason(reason string) {
+	ms.mu.Lock()
+	ms.reason = reason
+	ms.mu.Unlock()
+}
+
+// giveUp records reason and ends supervision.
+func (ms *ManagedService) giveUp(reason string) supervisionPhase {
+	ms.logger.Info("restart policy exhausted, giving up", "reason", reason)
+	ms.setReason(reason)
+	return phaseStopped
+}
+
 // Release detaches supervision without killing the underlying process.
 // Unlike Stop(), it does NOT call drv.Stop() — the process is left running.
+// Only the first replica is recorded for the next daemon to adopt, so any
+// others are stopped rather than left running unsupervised.
 func (ms *ManagedService) Release(timeout time.Duration) error {
-	return ms.detach(timeout)
+	var errs []error
+	for _, r := range ms.replicaList() {
+		errs = append(errs, r.stopInstance(timeout, true, ReasonManualStop))
+	}
+	return errors.Join(append(errs, ms.detach(timeout))...)
 }
 
 // detach cancels the supervision loop, stops health monitoring, and waits
@@ -258,26 +469,107 @@ func (ms *ManagedService) Logs(n int) []string {
 	return drv.LogLines(n)
 }
 
-// State returns the current service state.
-// For external services, state is always "running" — we observe health, not lifecycle.
+// QueryLogs returns the lines from the service log buffer selected by q.
+func (ms *ManagedService) QueryLogs(q logbuf.Query) []logbuf.Line {
+	ms.mu.Lock()
+	drv := ms.drv
+	ms.mu.Unlock()
+
+	if drv == nil {
+		return nil
+	}
+	return drv.QueryLogs(q)
+}
+
+// logFollowInterval is how often FollowLogs checks whether the service has
+// moved to a new driver, e.g. after a restart or deploy.
+const logFollowInterval = 500 * time.Millisecond
+
+// FollowLogs sends lines to the returned channel as the service logs them,
+// until ctx is done. It follows the service across restarts and deploys;
+// lines logged by a new process before it is picked up are only in the
+// buffer.
+func (ms *ManagedService) FollowLogs(ctx context.Context) <-chan logbuf.Line {
+	out := make(chan logbuf.Line)
+	go func() {
+		defer close(out)
+		ticker := time.NewTicker(logFollowInterval)
+		defer ticker.Stop()
+
+		var drv driver.Driver
+		var lines <-chan logbuf.Line
+		cancel := func() {}
+		defer func() { cancel() }()
+		for {
+			ms.mu.Lock()
+			cur := ms.drv
+			ms.mu.Unlock()
+			if cur != drv {
+				cancel()
+				drv, lines, cancel = cur, nil, func() {}
+				if drv != nil {
+					lines, cancel = drv.WatchLogs()
+				}
+			}
+
+			select {
+			case line := <-lines:
+				select {
+				case out <- line:
+				case <-ctx.Done():
+					return
+				}
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// State returns the current service state, with that of its other replicas
+// in Replicas.
+// For external services, state is "running" unless the health check has gone
+// unhealthy, in which case it is "unreachable" — we observe health, not lifecycle.
 func (ms *ManagedService) State() ServiceState {
+	st := ms.instanceState()
+	for _, r := range ms.replicaList() {
+		st.Replicas = append(st.Replicas, r.instanceState())
+	}
+	return st
+}
+
+// instanceState returns the state of this instance alone.
+func (ms *ManagedService) instanceState() ServiceState {
 	ms.mu.Lock()
 	defer ms.mu.Unlock()
 
 	st := ServiceState{
-		Name:         ms.spec.Service.Name,
+		Name:         ms.instanceName(),
 		Type:         ms.spec.Service.Type,
 		Port:         ms.EffectivePort(),
 		RestartCount: ms.restartCount,
 		Health:       health.StatusUnknown,
+		StateReason:  ms.reason,
 	}
 
 	if ms.monitor != nil {
 		st.Health = ms.monitor.CurrentStatus()
+		st.Warming = ms.monitor.Warming()
+		st.Flapping = ms.monitor.Flapping()
+	}
+
+	if !ms.spec.IsEnabled() {
+		st.State = driver.StateDisabled
+		return st
 	}
 
 	if ms.IsExternal() {
 		st.State = driver.StateRunning
+		if st.Health == health.StatusUnhealthy {
+			st.State = driver.StateUnreachable
+		}
 		if ms.spec.Health != nil {
 			st.Port = ms.spec.Health.Port
 		}
@@ -312,6 +604,28 @@ func (ms *ManagedService) State() ServiceState {
 		st.State = driver.StateStopped
 	}
 
+	// A clean exit that won't be restarted finished its job rather than failed
+	if ms.reason == ReasonCleanExit && (st.State == driver.StateFailed || st.State == driver.StateStopped) {
+		st.State = driver.StateCompleted
+	}
+
+	if ms.waiting != "" {
+		st.State = driver.StateStarting
+		st.Waiting = ms.waiting
+	}
+
+	st.OrphanedChildren = liveProcesses(ms.orphans)
+	if id := containerImageID(ms.drv); id != "" {
+		st.ImageDigest = containerImageDigest(ms.drv)
+		st.ImageDrift = ms.tagImage != "" && ms.tagImage != id
+	}
+
+	if !ms.circuitOpenUntil.IsZero() {
+		st.State = driver.StateFailed
+		st.CircuitOpenUntil = ms.circuitOpenUntil.Format(time.RFC3339)
+		st.StateReason = ReasonRestartsSuspended
+	}
+
 	return st
 }
 
@@ -329,6 +643,8 @@ func (ms *ManagedService) Inspect() ServiceInspect {
 		Port:         st.Port,
 		Uptime:       st.Uptime,
 		RestartCount: st.RestartCount,
+		ImageDigest:  st.ImageDigest,
+		ImageDrift:   st.ImageDrift,
 		Command:      ms.spec.Service.Command,
 		Image:        ms.spec.Service.Image,
 		Env:          ms.spec.Env,
@@ -336,6 +652,7 @@ func (ms *ManagedService) Inspect() ServiceInspect {
 		HealthCheck:  ms.spec.Health,
 		Dependencies: ms.spec.Dependencies,
 		Restart:      ms.spec.Restart,
+		Lifecycle:    ms.spec.Lifecycle,
 		Source:       ms.spec.Service.Source,
 		SpecHash:     ms.specHash,
 	}
@@ -356,6 +673,17 @@ func (ms *ManagedService) Inspect() ServiceInspect {
 	return si
 }
 
+// Health returns the current health status, or unknown if no monitor is running.
+func (ms *ManagedService) Health() health.Status {
+	ms.mu.Lock()
+	monitor := ms.monitor
+	ms.mu.Unlock()
+	if monitor == nil {
+		return health.StatusUnknown
+	}
+	return monitor.CurrentStatus()
+}
+
 // HealthHistory returns recent health check records from the monitor.
 // Returns nil if no monitor is running.
 func (ms *ManagedService) HealthHistory() []health.CheckRecord {
@@ -404,9 +732,20 @@ func (ms *ManagedService) supervise(ctx context.Context) {
 		case phaseMonitoring:
 			phase = ms.handleMonitoring(ctx)
 		}
+		ms.transitioned(ctx)
 	}
 }
 
+// transitioned passes the service's state after a supervision phase to
+// onTransition. Supervision cancelled by a stop or daemon shutdown is left to
+// whoever cancelled it to record.
+func (ms *ManagedService) transitioned(ctx context.Context) {
+	if ms.onTransition == nil || ctx.Err() != nil {
+		return
+	}
+	ms.onTransition(ms.State())
+}
+
 // superviseExisting enters the supervision loop with an already-running process.
 // Used after blue-green deploy promotion to monitor the new instance.
 func (ms *ManagedService) superviseExisting(ctx context.Context, drv driver.Driver) {
@@ -432,6 +771,7 @@ func (ms *ManagedService) superviseExisting(ctx context.Context, drv driver.Driv
 		case phaseMonitoring:
 			phase = ms.handleMonitoring(ctx)
 		}
+		ms.transitioned(ctx)
 	}
 }
 
@@ -445,7 +785,9 @@ func (ms *ManagedService) handleStarting(ctx context.Context) (driver.Driver, su
 		ms.mu.Unlock()
 		ms.logger.Info("adopted running process", "pid", drv.Info().PID)
 
-		monitor := ms.startHealthMonitor(ctx)
+		// The process was serving before the daemon restarted; keep it
+		// routed until a check says otherwise
+		monitor := ms.startHealthMonitor(ctx, true)
 		ms.mu.Lock()
 		ms.drv = drv
 		ms.monitor = monitor
@@ -454,51 +796,183 @@ func (ms *ManagedService) handleStarting(ctx context.Context) (driver.Driver, su
 	}
 	ms.mu.Unlock()
 
+	if !ms.waitForVRAM(ctx) {
+		return nil, phaseStopped
+	}
+
 	drv := ms.createDriver()
 	ms.mu.Lock()
 	ms.drv = drv
+	// A fresh process has no health verdict until its monitor starts;
+	// don't keep reporting the previous process's status
+	ms.monitor = nil
 	ms.mu.Unlock()
 
 	ms.logger.Info("starting process")
 	if err := drv.Start(ctx); err != nil {
 		ms.logger.Error("failed to start", "error", err)
+		ms.emit(EventStartFailed, err.Error())
 
 		if ctx.Err() != nil {
 			return drv, phaseStopped
 		}
 		if !ms.shouldRestart() {
-			ms.logger.Info("restart policy exhausted, giving up")
-			return drv, phaseStopped
+			return drv, ms.giveUp(ReasonStartFailed)
 		}
 		return drv, phaseRestarting
 	}
 
 	if ms.onStarted != nil {
-		ms.onStarted(drv.Info().PID)
+		ms.onStarted(drv)
 	}
+	ms.emit(EventStarted, fmt.Sprintf("pid %d", drv.Info().PID))
 
-	monitor := ms.startHealthMonitor(ctx)
+	monitor := ms.startHealthMonitor(ctx, false)
 	ms.mu.Lock()
 	ms.monitor = monitor
 	ms.mu.Unlock()
+	ms.startDeadline = time.Time{}
+	if h := ms.spec.Health; h != nil && h.MaxStartDuration.Duration > 0 {
+		ms.startDeadline = time.Now().Add(h.MaxStartDuration.Duration)
+	}
+
+	// A required post_start failure fails the start like a launch error,
+	// except it counts against max_attempts since the process did run
+	if err := ms.runPostStart(ctx, drv, monitor, ms.envPort()); err != nil {
+		ms.stopMonitor()
+		drv.Stop(context.Background(), DefaultStopTimeout)
+		drv.Wait()
+
+		if ctx.Err() != nil {
+			return drv, phaseStopped
+		}
+		if !ms.shouldRestart() {
+			return drv, ms.giveUp(ReasonStartFailed)
+		}
+		ms.mu.Lock()
+		ms.restartCount++
+		ms.mu.Unlock()
+		return drv, phaseRestarting
+	}
 
 	return drv, phaseRunning
 }
 
-// handleRunning waits for the process to exit or a health check to trigger restart.
+// Backoff between VRAM checks while a service waits for enough free VRAM.
+// Vars so tests can shorten them.
+var (
+	vramRetryMin = 1 * time.Second
+	vramRetryMax = 30 * time.Second
+)
+
+// waitForVRAM holds back the start until the GPU has the spec's
+// gpu.requires_vram free, rechecking with backoff and reporting the wait in
+// State. It returns false if ctx ends first. Without a requirement, or without
+// GPU info to check against, it returns straight away.
+func (ms *ManagedService) waitForVRAM(ctx context.Context) bool {
+	if ms.spec.GPU == nil || ms.gpuInfo == nil {
+		return true
+	}
+	need := ms.spec.GPU.RequiresVRAM
+	defer func() {
+		ms.mu.Lock()
+		ms.waiting = ""
+		ms.mu.Unlock()
+	}()
+
+	delay := vramRetryMin
+	for {
+		info := ms.gpuInfo()
+		if info.RecommendedMax == 0 {
+			ms.logger.Warn("no GPU memory info, starting without VRAM check", "requires_vram", need.String())
+			return true
+		}
+		var free uint64
+		if info.RecommendedMax > info.AllocatedBytes {
+			free = info.RecommendedMax - info.AllocatedBytes
+		}
+		if free >= uint64(need) {
+			return true
+		}
+
+		waiting := fmt.Sprintf("VRAM: %.1f GB needed, %.1f GB free", need.GB(), float64(free)/(1<<30))
+		ms.mu.Lock()
+		first := ms.waiting == ""
+		ms.waiting = waiting
+		ms.mu.Unlock()
+		if first {
+			ms.logger.Info("waiting for VRAM", "needed_gb", need.GB(), "free_gb", float64(free)/(1<<30))
+			ms.emit(EventWaiting, waiting)
+		}
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, vramRetryMax)
+	}
+}
+
+// handleRunning waits for the process to exit or a health check to trigger
+// restart, or for a fresh process to miss its health.max_start_duration.
 func (ms *ManagedService) handleRunning(ctx context.Context, drv driver.Driver) supervisionPhase {
-	select {
-	case <-ms.waitForExit(drv):
-		ms.stopMonitor()
-	case <-ms.unhealthyCh:
-		ms.logger.Warn("restarting due to health check failure")
-		ms.stopMonitor()
-		drv.Stop(ctx, 30*time.Second)
-		drv.Wait()
-	case <-ctx.Done():
+	ms.healthKilled = false
+	var startTimeout <-chan time.Time
+	if !ms.startDeadline.IsZero() {
+		startTimeout = time.After(time.Until(ms.startDeadline))
+		ms.startDeadline = time.Time{}
+	}
+	exited := ms.waitForExit(drv)
+
+	for {
+		select {
+		case <-exited:
+			ms.stopMonitor()
+		case <-ms.unhealthyCh:
+			ms.logger.Warn("restarting due to health check failure")
+			ms.stopMonitor()
+			drv.Stop(ctx, 30*time.Second)
+			drv.Wait()
+			ms.healthKilled = true
+		case <-startTimeout:
+			ms.mu.Lock()
+			monitor := ms.monitor
+			ms.mu.Unlock()
+			if monitor == nil || monitor.CurrentStatus() != health.StatusUnknown {
+				// A check has passed, or enough failed to mark it
+				// unhealthy, which restarts it anyway
+				startTimeout = nil
+				continue
+			}
+			return ms.failStartTimeout(ctx, drv)
+		case <-ctx.Done():
+			return phaseStopped
+		}
+		return phaseEvaluating
+	}
+}
+
+// failStartTimeout stops a process that never became healthy within
+// health.max_start_duration, counting it as a failed start.
+func (ms *ManagedService) failStartTimeout(ctx context.Context, drv driver.Driver) supervisionPhase {
+	timeout := ms.spec.Health.MaxStartDuration.Duration
+	ms.logger.Warn("not healthy within max_start_duration, failing the start", "max_start_duration", timeout)
+	ms.emit(EventStartFailed, fmt.Sprintf("not healthy within %s", timeout))
+	ms.stopMonitor()
+	drv.Stop(ctx, 30*time.Second)
+	drv.Wait()
+
+	if ctx.Err() != nil {
 		return phaseStopped
 	}
-	return phaseEvaluating
+	if !ms.shouldRestart() {
+		return ms.giveUp(ReasonStartTimeout)
+	}
+	ms.mu.Lock()
+	ms.restartCount++
+	ms.mu.Unlock()
+	return phaseRestarting
 }
 
 // handleEvaluating checks the exit code and restart policy to decide the next phase.
@@ -510,28 +984,50 @@ func (ms *ManagedService) handleEvaluating(ctx context.Context, drv driver.Drive
 	}
 
 	ms.logger.Info("process exited", "exit_code", exitCode)
-
-	if !ms.shouldRestart() {
-		ms.logger.Info("restart policy exhausted, giving up")
+	ms.emit(EventExited, fmt.Sprintf("exit code %d", exitCode))
+
+	// Specs loaded by the daemon always have a policy, if only the default;
+	// a service built without one is treated as never.
+	if r := ms.spec.Restart; r == nil || r.Policy == "never" {
+		policy := "none"
+		if r != nil {
+			policy = r.Policy
+		}
+		switch {
+		case ms.healthKilled:
+			ms.logger.Warn("process killed for failing health checks, not restarting", "restart_policy", policy)
+			ms.setReason(ReasonHealthFailure)
+		case exitCode == 0:
+			ms.logger.Info("process exited cleanly, not restarting", "restart_policy", policy)
+			ms.setReason(ReasonCleanExit)
+		default:
+			ms.logger.Warn("process failed, not restarting", "exit_code", exitCode, "restart_policy", policy)
+			ms.setReason(ReasonRestartNever)
+		}
 		return phaseStopped
 	}
 
-	policy := "on-failure"
-	if ms.spec.Restart != nil {
-		policy = ms.spec.Restart.Policy
+	if !ms.shouldRestart() {
+		if ms.healthKilled {
+			return ms.giveUp(ReasonHealthFailure)
+		}
+		return ms.giveUp(ReasonPolicyExhausted)
 	}
 
-	switch policy {
-	case "never":
-		ms.logger.Info("restart policy is 'never', stopping")
-		return phaseStopped
+	switch ms.spec.Restart.Policy {
 	case "on-failure":
 		if exitCode == 0 {
 			ms.logger.Info("process exited cleanly, not restarting (policy: on-failure)")
+			if ms.healthKilled {
+				ms.setReason(ReasonHealthFailure)
+			} else {
+				ms.setReason(ReasonCleanExit)
+			}
 			return phaseStopped
 		}
-	case "always":
-		// Continue to restart
+	case "always", "unless-stopped":
+		// Continue to restart. An operator stop cancels ctx, so it never
+		// reaches here; unless-stopped differs only in surviving daemon restarts.
 	case "oneshot":
 		if exitCode == 0 {
 			ms.logger.Info("oneshot command completed, entering health monitoring")
@@ -549,8 +1045,27 @@ func (ms *ManagedService) handleEvaluating(ctx context.Context, drv driver.Drive
 
 // handleRestarting waits for the restart delay before transitioning back to starting.
 func (ms *ManagedService) handleRestarting(ctx context.Context) supervisionPhase {
+	if cooldown := ms.restartCircuit(time.Now()); cooldown > 0 {
+		ms.logger.Warn("restart rate limit exceeded, suspending restarts",
+			"max_per_window", ms.spec.Restart.MaxPerWindow, "cooldown", cooldown)
+		select {
+		case <-time.After(cooldown):
+			ms.mu.Lock()
+			ms.circuitOpenUntil = time.Time{}
+			ms.restartTimes = []time.Time{time.Now()}
+			ms.mu.Unlock()
+			ms.logger.Info("restart cooldown elapsed, resuming restarts")
+		case <-ctx.Done():
+			ms.mu.Lock()
+			ms.circuitOpenUntil = time.Time{}
+			ms.mu.Unlock()
+			return phaseStopped
+		}
+	}
+
 	delay := ms.restartDelay()
 	ms.logger.Info("restarting after delay", "delay", delay, "restart_count", ms.restartCount)
+	ms.emit(EventRestarting, fmt.Sprintf("in %s", delay))
 
 	select {
 	case <-time.After(delay):
@@ -570,7 +1085,7 @@ func (ms *ManagedService) handleMonitoring(ctx context.Context) supervisionPhase
 	ms.mu.Unlock()
 
 	// Start a fresh health monitor for the monitoring phase
-	monitor := ms.startHealthMonitor(ctx)
+	monitor := ms.startHealthMonitor(ctx, false)
 	ms.mu.Lock()
 	ms.monitor = monitor
 	ms.mu.Unlock()
@@ -612,34 +1127,59 @@ func (ms *ManagedService) waitForExit(drv driver.Driver) <-chan struct{} {
 	return ch
 }
 
-func (ms *ManagedService) startHealthMonitor(ctx context.Context) *health.Monitor {
+// startHealthMonitor starts the spec's health check against the service's
+// current port, or returns nil if it has none. With assumeHealthy the monitor
+// starts healthy rather than unknown, for an instance already checked.
+func (ms *ManagedService) startHealthMonitor(ctx context.Context, assumeHealthy bool) *health.Monitor {
 	if ms.spec.Health == nil {
 		return nil
 	}
 
 	h := ms.spec.Health
-	port := h.Port
-	if port == 0 {
-		port = ms.EffectivePort()
-	}
-
 	cfg := health.Config{
 		Type:               h.Type,
 		Path:               h.Path,
-		Port:               port,
+		Method:             h.Method,
+		Headers:            h.Headers,
+		Body:               h.Body,
+		Port:               ms.healthCheckPort(ms.EffectivePort()),
+		UnixSocket:         h.UnixSocket,
 		Command:            h.Command,
 		Interval:           h.Interval.Duration,
 		Timeout:            h.Timeout.Duration,
+		InitialDelay:       h.InitialDelay.Duration,
 		GracePeriod:        h.GracePeriod.Duration,
 		UnhealthyThreshold: h.UnhealthyThreshold,
+		HealthyThreshold:   h.HealthyThreshold,
+		Jitter:             h.Jitter,
+		FlapThreshold:      h.FlapThreshold,
+		FlapWindow:         h.FlapWindow.Duration,
+		Flaps:              ms.flaps,
+		Host:               ms.spec.Network.ReachableHost(),
+		OnUnhealthyCommand: h.OnUnhealthy,
+		Service:            ms.spec.Service.Name,
+		AssumeHealthy:      assumeHealthy,
+	}
+	if h.Type == "docker" {
+		cfg.DockerStatus = ms.dockerHealth
+	}
+	cfg.OnTransition = func(from, to health.Status) {
+		if from == health.StatusHealthy || to == health.StatusHealthy {
+			ms.lastHealthy.Store(time.Now().UnixNano())
+		}
+		ms.emit(EventHealth, string(to))
 	}
 
-	if ms.spec.Routing != nil && h.Type == "http" && ms.spec.Routing.TLSOptions == "" {
+	if r := ms.spec.Routing; r != nil && r.IsHTTP() && h.Type == "http" && r.TLSOptions == "" {
 		scheme := "http"
-		if ms.spec.Routing.TLS {
+		if r.TLS {
 			scheme = "https"
 		}
-		cfg.RouteURL = fmt.Sprintf("%s://%s", scheme, ms.spec.Routing.Hostname)
+		cfg.RouteURL = fmt.Sprintf("%s://%s", scheme, r.PrimaryHostname())
+		if r.StripPrefix {
+			// The health path is the service's own; reach it through the prefix
+			cfg.RouteURL += r.PathPrefix
+		}
 	}
 
 	monitor := health.NewMonitor(cfg, ms.logger, func() {
@@ -655,28 +1195,181 @@ func (ms *ManagedService) startHealthMonitor(ctx context.Context) *health.Monito
 	return monitor
 }
 
+// emit records a lifecycle event for the service, if an event log is attached.
+func (ms *ManagedService) emit(eventType, detail string) {
+	if ms.onEvent != nil {
+		ms.onEvent(eventType, detail)
+	}
+}
+
+// routePort returns the port traffic for this instance should be routed to,
+// or zero while it shouldn't get any.
+func (ms *ManagedService) routePort(now time.Time) int {
+	// Within routing.unhealthy_grace of failing its health check, a
+	// supervised service keeps its route through the failure and the
+	// restart it triggers, so a brief blip doesn't drop traffic
+	state := ms.instanceState()
+	graced := ms.routingGraceLeft(now) > 0 && state.State != driver.StateFailed && ms.supervised()
+	// Only include running services. Unreachable externals keep their
+	// route: aurelia doesn't own their lifecycle, so it won't flap routing.
+	if state.State != driver.StateRunning && state.State != driver.StateUnreachable && !graced {
+		return 0
+	}
+	// Keep traffic off a service until its health check passes, and
+	// take it off again while the check fails
+	if ms.spec.Health != nil && !ms.IsExternal() && !ms.spec.Routing.RouteUnhealthy && state.Health != health.StatusHealthy && !graced {
+		return 0
+	}
+
+	port := ms.EffectivePort()
+	if port == 0 && ms.spec.Health != nil {
+		port = ms.spec.Health.Port
+	}
+	return port
+}
+
+// supervised reports whether the supervision loop is running, restarting the
+// service as its policy says, rather than the service having been stopped
+// or given up on.
+func (ms *ManagedService) supervised() bool {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	return ms.cancel != nil
+}
+
+// routingGraceLeft returns how much of routing.unhealthy_grace is left at
+// now since the service was last healthy, or zero once it has run out. While
+// it hasn't, the service keeps its route.
+func (ms *ManagedService) routingGraceLeft(now time.Time) time.Duration {
+	r := ms.spec.Routing
+	last := ms.lastHealthy.Load()
+	if r == nil || r.UnhealthyGrace.Duration <= 0 || last == 0 {
+		return 0
+	}
+	return max(r.UnhealthyGrace.Duration-now.Sub(time.Unix(0, last)), 0)
+}
+
+// dockerHealth reports the HEALTHCHECK status of the service's current container.
+func (ms *ManagedService) dockerHealth(ctx context.Context) (string, error) {
+	ms.mu.Lock()
+	drv := ms.drv
+	ms.mu.Unlock()
+	return containerHealth(ctx, drv)
+}
+
+func containerHealth(ctx context.Context, drv driver.Driver) (string, error) {
+	cd, ok := drv.(*driver.ContainerDriver)
+	if !ok || cd == nil {
+		return "", fmt.Errorf("no running container")
+	}
+	return cd.Health(ctx)
+}
+
+// containerImageID returns the image ID a container driver was started from,
+// or "" for other drivers.
+func containerImageID(drv driver.Driver) string {
+	cd, ok := drv.(*driver.ContainerDriver)
+	if !ok || cd == nil {
+		return ""
+	}
+	return cd.ImageID()
+}
+
+// containerImageDigest returns the registry digest of the image a container
+// driver was started from, or "" for other drivers.
+func containerImageDigest(drv driver.Driver) string {
+	cd, ok := drv.(*driver.ContainerDriver)
+	if !ok || cd == nil {
+		return ""
+	}
+	return cd.ImageDigest()
+}
+
+// checkImageDrift reports whether a running container's image tag now points
+// to a different image than the one it was started from, e.g. after a pull.
+func (ms *ManagedService) checkImageDrift(ctx context.Context) bool {
+	ms.mu.Lock()
+	cd, ok := ms.drv.(*driver.ContainerDriver)
+	ms.mu.Unlock()
+	if !ok || cd == nil || cd.Info().State != driver.StateRunning || cd.ImageID() == "" {
+		return false
+	}
+	current, err := cd.ResolveImage(ctx)
+	if err != nil {
+		ms.logger.Warn("failed to check image for drift", "error", err)
+		return false
+	}
+	ms.mu.Lock()
+	ms.tagImage = current
+	ms.mu.Unlock()
+	return current != cd.ImageID()
+}
+
 // createDriverWithPort creates a driver configured to listen on the given port.
-// Used during blue-green deploys where the container gets a "-deploy" suffix.
-func (ms *ManagedService) createDriverWithPort(port int) driver.Driver {
-	return ms.createDriverInternal(ms.buildEnvWithPort(port), ms.spec.Service.Name+"-deploy")
+// Used during blue-green deploys, where the new container must not take the
+// name of the container it replaces.
+func (ms *ManagedService) createDriverWithPort(port int, containerName string) driver.Driver {
+	return ms.createDriverInternal(port, containerName)
 }
 
 func (ms *ManagedService) createDriver() driver.Driver {
-	return ms.createDriverInternal(ms.buildEnv(), ms.spec.Service.Name)
+	return ms.createDriverInternal(ms.envPort(), ms.instanceName())
+}
+
+// replicaList returns the service's replicas after the first.
+func (ms *ManagedService) replicaList() []*ManagedService {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	return slices.Clone(ms.replicas)
 }
 
-func (ms *ManagedService) createDriverInternal(env []string, containerName string) driver.Driver {
+// instanceName is the service's name, or <name>.<n> for its nth replica
+// after the first: the key for its port and the name of its container.
+func (ms *ManagedService) instanceName() string {
+	if ms.replica == 0 {
+		return ms.spec.Service.Name
+	}
+	return fmt.Sprintf("%s.%d", ms.spec.Service.Name, ms.replica+1)
+}
+
+func (ms *ManagedService) createDriverInternal(port int, containerName string) driver.Driver {
 	switch ms.spec.Service.Type {
 	case "container":
-		d, err := driver.NewContainer(driver.ContainerConfig{
+		// Inside a container with published ports the service listens on
+		// the container side of the binding
+		ports := ms.publishedPorts(port)
+		envPort := port
+		if cp, ok := ports[port]; ok {
+			envPort = cp
+		}
+		binds, named, tmpfs := ms.containerMounts()
+		cfg := driver.ContainerConfig{
 			Name:        containerName,
 			Image:       ms.spec.Service.Image,
-			Env:         env,
+			Env:         ms.buildEnvWithPort(envPort),
 			Cmd:         ms.spec.Args,
 			NetworkMode: ms.spec.Service.NetworkMode,
 			Privileged:  ms.spec.Service.Privileged,
-			Volumes:     ms.spec.Volumes,
-		})
+			Volumes:     binds,
+			NamedVols:   named,
+			Tmpfs:       tmpfs,
+			Files:       ms.dockerSecretFiles(),
+			Ports:       ports,
+			Timestamps:  ms.spec.Service.LogTimestamps,
+			TTY:         ms.spec.Service.TTY,
+			StdinOpen:   ms.spec.Service.StdinOpen,
+			Labels:      ms.spec.Service.Labels,
+			Service:     ms.spec.Service.Name,
+			NamePrefix:  ms.containerPrefix,
+			FilesDir:    ms.containerFiles,
+		}
+		if sec := ms.spec.Security; sec != nil {
+			cfg.ReadOnly = sec.ReadOnly
+			cfg.CapDrop = sec.CapDrop
+			cfg.CapAdd = sec.CapAdd
+			cfg.NoNewPrivs = sec.NoNewPrivileges
+		}
+		d, err := driver.NewContainer(cfg)
 		if err != nil {
 			ms.logger.Error("failed to create container driver", "error", err)
 			return driver.NewNative(driver.NativeConfig{Command: "false"})
@@ -694,14 +1387,109 @@ func (ms *ManagedService) createDriverInternal(env []string, containerName strin
 		}
 		return driver.NewRemote(cfg)
 	default:
+		if ms.spec.Service.OOMScoreAdj != 0 && !driver.OOMScoreAdjSupported {
+			ms.logger.Warn("oom_score_adj is not supported on this platform, ignoring", "oom_score_adj", ms.spec.Service.OOMScoreAdj)
+		}
 		return driver.NewNative(driver.NativeConfig{
-			Command:    ms.spec.Service.Command,
-			Env:        env,
-			WorkingDir: ms.spec.Service.WorkingDir,
+			Command:     ms.spec.Service.Command,
+			Env:         ms.buildEnvWithPort(port),
+			WorkingDir:  ms.spec.Service.WorkingDir,
+			Priority:    ms.spec.Service.Priority,
+			OOMScoreAdj: ms.spec.Service.OOMScoreAdj,
+			KillOrphans: ms.spec.Service.KillOrphans,
+			OnOrphans:   ms.recordOrphans,
 		})
 	}
 }
 
+// recordOrphans is called by the native driver when a stop left processes
+// behind, typically children that double-forked out of the process group.
+func (ms *ManagedService) recordOrphans(pids []int) {
+	if ms.spec.Service.KillOrphans {
+		ms.logger.Warn("orphaned children survived stop, killed them", "pids", pids)
+	} else {
+		ms.logger.Warn("orphaned children survived stop", "pids", pids)
+	}
+	ms.mu.Lock()
+	ms.orphans = pids
+	ms.mu.Unlock()
+}
+
+// liveProcesses returns the pids that still exist.
+func liveProcesses(pids []int) []int {
+	var live []int
+	for _, pid := range pids {
+		if syscall.Kill(pid, 0) == nil {
+			live = append(live, pid)
+		}
+	}
+	return live
+}
+
+// publishedPorts maps hostPort to the port the service listens on inside its
+// container, for network modes that need ports published to be reachable from
+// the host. Returns nil for host networking, modes without their own network
+// stack, or when the service has no port.
+func (ms *ManagedService) publishedPorts(hostPort int) map[int]int {
+	if hostPort == 0 || ms.spec.Network == nil {
+		return nil
+	}
+	nm := ms.spec.Service.NetworkMode
+	if nm == "" || nm == "host" || nm == "none" || strings.HasPrefix(nm, "container:") {
+		return nil
+	}
+	containerPort := hostPort
+	if cp := ms.spec.Network.ContainerPort; cp != 0 {
+		containerPort = cp
+	}
+	return map[int]int{hostPort: containerPort}
+}
+
+// containerMounts splits the spec's volumes into host bind mounts and named
+// Docker volumes, and returns its tmpfs mounts keyed by path.
+func (ms *ManagedService) containerMounts() (binds, named map[string]string, tmpfs map[string]int64) {
+	for source, target := range ms.spec.Volumes {
+		if spec.IsNamedVolume(source) {
+			if named == nil {
+				named = make(map[string]string)
+			}
+			named[source] = target
+			continue
+		}
+		if binds == nil {
+			binds = make(map[string]string)
+		}
+		binds[source] = target
+	}
+	if len(ms.spec.Tmpfs) > 0 {
+		tmpfs = make(map[string]int64, len(ms.spec.Tmpfs))
+		for _, t := range ms.spec.Tmpfs {
+			tmpfs[t.Path] = int64(t.Size)
+		}
+	}
+	return binds, named, tmpfs
+}
+
+// dockerSecretFiles resolves the spec's docker_secrets to file contents keyed
+// by their path under /run/secrets. A secret that can't be read is skipped,
+// like one injected into the environment.
+func (ms *ManagedService) dockerSecretFiles() map[string][]byte {
+	if ms.secrets == nil || len(ms.spec.DockerSecrets) == 0 {
+		return nil
+	}
+	files := make(map[string][]byte, len(ms.spec.DockerSecrets))
+	for name, ref := range ms.spec.DockerSecrets {
+		val, err := ms.secrets.Get(ref.Key())
+		if err != nil {
+			ms.logger.Warn("secret not found, skipping", "docker_secret", name, "secret_key", ref.Key(), "error", err)
+			continue
+		}
+		files["/run/secrets/"+name] = []byte(val)
+		ms.logger.Info("mounted secret", "docker_secret", name)
+	}
+	return files
+}
+
 // buildEnvWithPort builds the environment with an explicit port override.
 // Used during blue-green deploys to start a new instance on a temporary port.
 func (ms *ManagedService) buildEnvWithPort(port int) []string {
@@ -718,6 +1506,10 @@ func (ms *ManagedService) buildEnvWithPort(port int) []string {
 	if port != 0 {
 		env = append(env, fmt.Sprintf("PORT=%d", port))
 	}
+	bind := ms.bindAddress()
+	if bind != "" {
+		env = append(env, "HOST="+bind, "BIND_ADDRESS="+bind)
+	}
 
 	// Build runtime variables for interpolation within env values.
 	// This allows specs like: SERVER_PORT: "${PORT}"
@@ -727,6 +1519,9 @@ func (ms *ManagedService) buildEnvWithPort(port int) []string {
 	if port != 0 {
 		runtimeVars["PORT"] = fmt.Sprintf("%d", port)
 	}
+	if bind != "" {
+		runtimeVars["HOST"] = bind
+	}
 
 	interpolatedEnv := spec.InterpolateRuntimeVars(ms.spec.Env, runtimeVars)
 	for k, v := range interpolatedEnv {
@@ -746,15 +1541,54 @@ func (ms *ManagedService) buildEnvWithPort(port int) []string {
 		}
 	}
 
+	return ms.withGlobalEnv(env, runtimeVars)
+}
+
+// withGlobalEnv adds the daemon's global_env to env at the lowest
+// precedence: a variable set by the service's env or secrets, or injected by
+// aurelia, wins over the global of the same name. Globals do override the
+// host environment inherited by native services, which comes first in env.
+func (ms *ManagedService) withGlobalEnv(env []string, runtimeVars map[string]string) []string {
+	if len(ms.globalEnv) == 0 {
+		return env
+	}
+	own := map[string]bool{"AURELIA_SERVICE": true}
+	if _, ok := runtimeVars["PORT"]; ok {
+		own["PORT"] = true
+	}
+	if _, ok := runtimeVars["HOST"]; ok {
+		own["HOST"], own["BIND_ADDRESS"] = true, true
+	}
+	for k := range ms.spec.Env {
+		own[k] = true
+	}
+	for k := range ms.spec.Secrets {
+		own[k] = true
+	}
+	for k, v := range spec.InterpolateRuntimeVars(ms.globalEnv, runtimeVars) {
+		if !own[k] {
+			env = append(env, k+"="+v)
+		}
+	}
 	return env
 }
 
-func (ms *ManagedService) buildEnv() []string {
+// bindAddress is the address injected as HOST and BIND_ADDRESS, or "".
+func (ms *ManagedService) bindAddress() string {
+	if ms.spec.Network == nil {
+		return ""
+	}
+	return ms.spec.Network.BindAddress
+}
+
+// envPort is the port injected as PORT: the allocated dynamic port, else the
+// spec's static port.
+func (ms *ManagedService) envPort() int {
 	port := ms.allocatedPort
 	if port == 0 && ms.spec.Network != nil {
 		port = ms.spec.Network.Port
 	}
-	return ms.buildEnvWithPort(port)
+	return port
 }
 
 func (ms *ManagedService) shouldRestart() bool {
@@ -774,21 +1608,61 @@ func (ms *ManagedService) shouldRestart() bool {
 	return count < maxAttempts
 }
 
+// restartCircuit records a restart at now against restart.max_per_window and
+// returns how long restarts must be suspended: zero while the number of
+// restarts within the sliding window stays under the limit, otherwise one full
+// window, during which the circuit is open and the service reports failed.
+func (ms *ManagedService) restartCircuit(now time.Time) time.Duration {
+	r := ms.spec.Restart
+	if r == nil || r.MaxPerWindow <= 0 {
+		return 0
+	}
+	window := r.Window.Duration
+	if window <= 0 {
+		window = time.Minute
+	}
+
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	recent := ms.restartTimes[:0]
+	for _, t := range ms.restartTimes {
+		if now.Sub(t) < window {
+			recent = append(recent, t)
+		}
+	}
+	ms.restartTimes = recent
+
+	if len(recent) >= r.MaxPerWindow {
+		ms.restartTimes = nil
+		ms.circuitOpenUntil = now.Add(window)
+		return window
+	}
+	ms.restartTimes = append(ms.restartTimes, now)
+	return 0
+}
+
 func (ms *ManagedService) restartDelay() time.Duration {
 	if ms.spec.Restart == nil {
 		return 5 * time.Second
 	}
 
+	ms.mu.Lock()
+	count := ms.restartCount
+	ms.mu.Unlock()
+
+	// restartCount is bumped before the delay is taken, so the first restart
+	// sees 1 (or 0 if the count was reset in between).
+	if initial := ms.spec.Restart.InitialDelay; initial != nil && count <= 1 {
+		return restartJitter(initial.Duration, ms.spec.Restart.Jitter, rand.Float64())
+	}
+
 	delay := ms.spec.Restart.Delay.Duration
 	if delay <= 0 {
 		delay = 5 * time.Second
 	}
 
 	if ms.spec.Restart.Backoff == "exponential" {
-		ms.mu.Lock()
-		count := ms.restartCount
-		ms.mu.Unlock()
-
 		for i := 0; i < count; i++ {
 			delay *= 2
 			if delay <= 0 { // overflow
@@ -802,5 +1676,20 @@ func (ms *ManagedService) restartDelay() time.Duration {
 		}
 	}
 
-	return delay
+	return restartJitter(delay, ms.spec.Restart.Jitter, rand.Float64())
+}
+
+// restartJitter randomizes a restart delay d so services that crash together
+// don't restart in lockstep, with r in [0, 1) picking the point. "full"
+// picks anywhere in [0, d), "equal" keeps half and randomizes the rest,
+// giving [d/2, d). Any other mode leaves d as is.
+func restartJitter(d time.Duration, mode string, r float64) time.Duration {
+	switch mode {
+	case "full":
+		return time.Duration(r * float64(d))
+	case "equal":
+		half := d / 2
+		return half + time.Duration(r*float64(d-half))
+	}
+	return d
 }
@@ -2,11 +2,17 @@ package daemon
 
 import (
 	"context"
+	"fmt"
+	"maps"
+	"net"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/benaskins/aurelia/internal/driver"
+	"github.com/benaskins/aurelia/internal/health"
 	"github.com/benaskins/aurelia/internal/keychain"
 	"github.com/benaskins/aurelia/internal/spec"
 )
@@ -179,6 +185,203 @@ func TestManagedServiceAlwaysRestart(t *testing.T) {
 	}
 }
 
+func TestManagedServiceUnlessStoppedRestart(t *testing.T) {
+	s := &spec.ServiceSpec{
+		Service: spec.Service{
+			Name:    "test-unless-stopped",
+			Type:    "native",
+			Command: "true", // exits cleanly
+		},
+		Restart: &spec.RestartPolicy{
+			Policy:      "unless-stopped",
+			MaxAttempts: 2,
+			Delay:       spec.Duration{Duration: 10 * time.Millisecond},
+		},
+	}
+
+	ms, err := NewManagedService(s, nil)
+	if err != nil {
+		t.Fatalf("failed to create: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := ms.Start(ctx); err != nil {
+		t.Fatalf("failed to start: %v", err)
+	}
+
+	waitUntil(t, func() bool {
+		return ms.State().RestartCount >= 1
+	}, 2*time.Second, "at least 1 restart with 'unless-stopped' policy")
+
+	cancel()
+	waitUntil(t, func() bool {
+		s := ms.State().State
+		return s == driver.StateStopped || s == driver.StateFailed
+	}, 2*time.Second, "service to stop after cancel")
+}
+
+func TestManagedServiceRestartCircuitOpens(t *testing.T) {
+	s := &spec.ServiceSpec{
+		Service: spec.Service{
+			Name:    "test-circuit",
+			Type:    "native",
+			Command: "false", // crashes immediately
+		},
+		Restart: &spec.RestartPolicy{
+			Policy:       "on-failure",
+			Delay:        spec.Duration{Duration: 10 * time.Millisecond},
+			MaxPerWindow: 3,
+			Window:       spec.Duration{Duration: 500 * time.Millisecond},
+		},
+	}
+
+	ms, err := NewManagedService(s, nil)
+	if err != nil {
+		t.Fatalf("failed to create: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := ms.Start(ctx); err != nil {
+		t.Fatalf("failed to start: %v", err)
+	}
+
+	waitUntil(t, func() bool {
+		return ms.State().CircuitOpenUntil != ""
+	}, 2*time.Second, "circuit to open after rapid crashes")
+
+	state := ms.State()
+	if state.State != driver.StateFailed {
+		t.Errorf("expected failed while circuit is open, got %v", state.State)
+	}
+	// Three restarts fit in the window; the fourth is held behind the circuit
+	if state.RestartCount != 4 {
+		t.Errorf("expected the 4th restart to open the circuit, got count %d", state.RestartCount)
+	}
+
+	// No restarts happen while the circuit is open
+	time.Sleep(200 * time.Millisecond)
+	if got := ms.State().RestartCount; got != 4 {
+		t.Errorf("expected restarts to be suspended, count went to %d", got)
+	}
+
+	// After the window the service is retried
+	waitUntil(t, func() bool {
+		return ms.State().RestartCount > 4
+	}, 2*time.Second, "restarts to resume after the window")
+
+	cancel()
+	waitUntil(t, func() bool {
+		return ms.State().CircuitOpenUntil == "" && ms.State().State != driver.StateRunning
+	}, 2*time.Second, "service to stop after cancel")
+}
+
+func TestRestartDelayJitter(t *testing.T) {
+	delays := func(jitter string, maxDelay time.Duration, restarts int) []time.Duration {
+		t.Helper()
+		ms, err := NewManagedService(&spec.ServiceSpec{
+			Service: spec.Service{Name: "test-jitter", Type: "native", Command: "true"},
+			Restart: &spec.RestartPolicy{
+				Policy:   "always",
+				Delay:    spec.Duration{Duration: time.Second},
+				Backoff:  "exponential",
+				MaxDelay: spec.Duration{Duration: maxDelay},
+				Jitter:   jitter,
+			},
+		}, nil)
+		if err != nil {
+			t.Fatalf("failed to create: %v", err)
+		}
+		ms.restartCount = restarts
+		var got []time.Duration
+		for range 50 {
+			got = append(got, ms.restartDelay())
+		}
+		return got
+	}
+	varies := func(ds []time.Duration) bool {
+		for _, d := range ds[1:] {
+			if d != ds[0] {
+				return true
+			}
+		}
+		return false
+	}
+
+	// Without jitter the delay is deterministic: 1s doubled 3 times, capped
+	for _, jitter := range []string{"", "none"} {
+		for _, d := range delays(jitter, 5*time.Second, 3) {
+			if d != 5*time.Second {
+				t.Fatalf("jitter %q: expected a fixed 5s, got %v", jitter, d)
+			}
+		}
+	}
+
+	// 1s doubled twice is 4s, under max_delay
+	full := delays("full", 5*time.Second, 2)
+	for _, d := range full {
+		if d < 0 || d > 4*time.Second {
+			t.Errorf("full jitter: %v outside [0, 4s]", d)
+		}
+	}
+	if !varies(full) {
+		t.Errorf("full jitter: expected delays to vary, got %v", full)
+	}
+
+	// Capped at max_delay before jittering
+	equal := delays("equal", 5*time.Second, 3)
+	for _, d := range equal {
+		if d < 2500*time.Millisecond || d > 5*time.Second {
+			t.Errorf("equal jitter: %v outside [2.5s, 5s]", d)
+		}
+	}
+	if !varies(equal) {
+		t.Errorf("equal jitter: expected delays to vary, got %v", equal)
+	}
+
+	// The overflow guard still bounds the delay
+	for _, d := range delays("full", 0, 100) {
+		if d < 0 || d > 24*time.Hour {
+			t.Errorf("overflowed backoff: %v outside [0, 24h]", d)
+		}
+	}
+}
+
+func TestRestartDelayInitialDelay(t *testing.T) {
+	ms, err := NewManagedService(&spec.ServiceSpec{
+		Service: spec.Service{Name: "test-initial-delay", Type: "native", Command: "true"},
+		Restart: &spec.RestartPolicy{
+			Policy:       "always",
+			InitialDelay: &spec.Duration{},
+			Delay:        spec.Duration{Duration: time.Second},
+			Backoff:      "exponential",
+			MaxDelay:     spec.Duration{Duration: 10 * time.Second},
+		},
+	}, nil)
+	if err != nil {
+		t.Fatalf("failed to create: %v", err)
+	}
+
+	// restartCount is already 1 when the first restart's delay is taken
+	want := []time.Duration{0, 0, 4 * time.Second, 8 * time.Second, 10 * time.Second}
+	for count, w := range want {
+		ms.restartCount = count
+		if got := ms.restartDelay(); got != w {
+			t.Errorf("restart count %d: expected %v, got %v", count, w, got)
+		}
+	}
+
+	// Unset keeps the normal curve for the first restart
+	ms.spec.Restart.InitialDelay = nil
+	ms.restartCount = 1
+	if got := ms.restartDelay(); got != 2*time.Second {
+		t.Errorf("without initial_delay: expected 2s, got %v", got)
+	}
+}
+
 func TestManagedServiceNeverRestart(t *testing.T) {
 	s := &spec.ServiceSpec{
 		Service: spec.Service{
@@ -211,6 +414,192 @@ func TestManagedServiceNeverRestart(t *testing.T) {
 	}
 }
 
+func TestManagedServiceNeverCompleted(t *testing.T) {
+	tests := []struct {
+		command string
+		want    driver.State
+	}{
+		{"true", driver.StateCompleted},
+		{"false", driver.StateFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			ms, err := NewManagedService(&spec.ServiceSpec{
+				Service: spec.Service{Name: "test-oneshot", Type: "native", Command: tt.command},
+				Restart: &spec.RestartPolicy{Policy: "never"},
+			}, nil)
+			if err != nil {
+				t.Fatalf("failed to create: %v", err)
+			}
+			if err := ms.Start(context.Background()); err != nil {
+				t.Fatalf("failed to start: %v", err)
+			}
+
+			waitUntil(t, func() bool {
+				return ms.State().StateReason != ""
+			}, 2*time.Second, "process to exit")
+			if got := ms.State(); got.State != tt.want {
+				t.Errorf("expected %s after %q exits, got %s (%s)", tt.want, tt.command, got.State, got.StateReason)
+			}
+
+			// Starting it again clears the completed state
+			if err := ms.Start(context.Background()); err != nil {
+				t.Fatalf("failed to restart: %v", err)
+			}
+			if got := ms.State().State; got == driver.StateCompleted {
+				t.Errorf("expected a fresh start to clear completed, got %s", got)
+			}
+		})
+	}
+}
+
+func TestManagedServiceStateReason(t *testing.T) {
+	delay := spec.Duration{Duration: 10 * time.Millisecond}
+	failingHealth := &spec.HealthCheck{
+		Type:               "tcp",
+		Port:               19877, // nothing listening
+		Interval:           spec.Duration{Duration: 50 * time.Millisecond},
+		Timeout:            spec.Duration{Duration: 100 * time.Millisecond},
+		UnhealthyThreshold: 1,
+	}
+
+	tests := []struct {
+		name    string
+		command string
+		health  *spec.HealthCheck
+		restart *spec.RestartPolicy
+		want    string
+	}{
+		{"clean exit", "true", nil, &spec.RestartPolicy{Policy: "on-failure", MaxAttempts: 3, Delay: delay}, ReasonCleanExit},
+		{"restart never", "false", nil, &spec.RestartPolicy{Policy: "never"}, ReasonRestartNever},
+		{"clean exit never", "true", nil, &spec.RestartPolicy{Policy: "never"}, ReasonCleanExit},
+		{"no restart policy", "false", nil, nil, ReasonRestartNever},
+		{"policy exhausted", "false", nil, &spec.RestartPolicy{Policy: "on-failure", MaxAttempts: 1, Delay: delay}, ReasonPolicyExhausted},
+		{"start failed", "/nonexistent/aurelia-test-binary", nil, nil, ReasonStartFailed},
+		{"health failure", "sleep 60", failingHealth, &spec.RestartPolicy{Policy: "never"}, ReasonHealthFailure},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ms, err := NewManagedService(&spec.ServiceSpec{
+				Service: spec.Service{Name: "test-reason", Type: "native", Command: tt.command},
+				Health:  tt.health,
+				Restart: tt.restart,
+			}, nil)
+			if err != nil {
+				t.Fatalf("failed to create: %v", err)
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if err := ms.Start(ctx); err != nil {
+				t.Fatalf("failed to start: %v", err)
+			}
+
+			waitUntil(t, func() bool {
+				return ms.State().StateReason != ""
+			}, 3*time.Second, "a state reason")
+			if got := ms.State(); got.StateReason != tt.want {
+				t.Errorf("StateReason = %q, want %q (state %s)", got.StateReason, tt.want, got.State)
+			}
+		})
+	}
+}
+
+func TestManagedServiceMaxStartDuration(t *testing.T) {
+	// The check never passes, and never fails often enough to go unhealthy
+	ms, err := NewManagedService(&spec.ServiceSpec{
+		Service: spec.Service{Name: "test-start-timeout", Type: "native", Command: "sleep 60"},
+		Health: &spec.HealthCheck{
+			Type:               "tcp",
+			Port:               19878, // nothing listening
+			Interval:           spec.Duration{Duration: 50 * time.Millisecond},
+			Timeout:            spec.Duration{Duration: 100 * time.Millisecond},
+			UnhealthyThreshold: 1000,
+			MaxStartDuration:   spec.Duration{Duration: 300 * time.Millisecond},
+		},
+		Restart: &spec.RestartPolicy{Policy: "always", MaxAttempts: 1, Delay: spec.Duration{Duration: 10 * time.Millisecond}},
+	}, nil)
+	if err != nil {
+		t.Fatalf("failed to create: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := ms.Start(ctx); err != nil {
+		t.Fatalf("failed to start: %v", err)
+	}
+
+	// Two starts of 300ms each, the second not retried
+	waitUntil(t, func() bool {
+		return ms.State().StateReason != ""
+	}, 3*time.Second, "the start to fail")
+	st := ms.State()
+	if st.StateReason != ReasonStartTimeout || st.State != driver.StateStopped {
+		t.Errorf("got state %s, reason %q; want stopped, %q", st.State, st.StateReason, ReasonStartTimeout)
+	}
+	if st.RestartCount != 1 {
+		t.Errorf("RestartCount = %d, want the timed out start counted once", st.RestartCount)
+	}
+}
+
+func TestManagedServiceMaxStartDurationHealthy(t *testing.T) {
+	ms, err := NewManagedService(&spec.ServiceSpec{
+		Service: spec.Service{Name: "test-start-ok", Type: "native", Command: "sleep 60"},
+		Health: &spec.HealthCheck{
+			Type:             "exec",
+			Command:          "true",
+			Interval:         spec.Duration{Duration: 50 * time.Millisecond},
+			Timeout:          spec.Duration{Duration: time.Second},
+			MaxStartDuration: spec.Duration{Duration: 300 * time.Millisecond},
+		},
+	}, nil)
+	if err != nil {
+		t.Fatalf("failed to create: %v", err)
+	}
+	if err := ms.Start(context.Background()); err != nil {
+		t.Fatalf("failed to start: %v", err)
+	}
+	defer ms.Stop(5 * time.Second)
+
+	time.Sleep(600 * time.Millisecond)
+	if st := ms.State(); st.State != driver.StateRunning || st.RestartCount != 0 {
+		t.Errorf("expected a healthy service to keep running past max_start_duration, got %s with %d restarts", st.State, st.RestartCount)
+	}
+}
+
+func TestManagedServiceStateReasonManualStop(t *testing.T) {
+	ms, err := NewManagedService(&spec.ServiceSpec{
+		Service: spec.Service{Name: "test-reason", Type: "native", Command: "sleep 60"},
+		Restart: &spec.RestartPolicy{Policy: "always"},
+	}, nil)
+	if err != nil {
+		t.Fatalf("failed to create: %v", err)
+	}
+	if err := ms.Start(context.Background()); err != nil {
+		t.Fatalf("failed to start: %v", err)
+	}
+	waitUntil(t, func() bool {
+		return ms.State().State == driver.StateRunning
+	}, 2*time.Second, "service to run")
+	if r := ms.State().StateReason; r != "" {
+		t.Errorf("expected no reason while running, got %q", r)
+	}
+
+	if err := ms.Stop(5 * time.Second); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	if r := ms.State().StateReason; r != ReasonManualStop {
+		t.Errorf("StateReason = %q, want %q", r, ReasonManualStop)
+	}
+
+	// Starting again clears it
+	if err := ms.Start(context.Background()); err != nil {
+		t.Fatalf("restart: %v", err)
+	}
+	defer ms.Stop(5 * time.Second)
+	if r := ms.State().StateReason; r != "" {
+		t.Errorf("expected reason cleared on start, got %q", r)
+	}
+}
+
 func TestManagedServiceExponentialBackoff(t *testing.T) {
 	if testing.Short() {
 		t.Skip("slow: exercises real backoff timing")
@@ -297,6 +686,99 @@ func TestManagedServiceHealthState(t *testing.T) {
 	}, 2*time.Second, "service to stop after cancel")
 }
 
+func TestManagedServiceWarmingDuringGracePeriod(t *testing.T) {
+	s := &spec.ServiceSpec{
+		Service: spec.Service{
+			Name:    "test-warming",
+			Type:    "native",
+			Command: "sleep 60",
+		},
+		Health: &spec.HealthCheck{
+			Type:        "exec",
+			Command:     "true",
+			Interval:    spec.Duration{Duration: 50 * time.Millisecond},
+			Timeout:     spec.Duration{Duration: time.Second},
+			GracePeriod: spec.Duration{Duration: 300 * time.Millisecond},
+		},
+	}
+
+	ms, err := NewManagedService(s, nil)
+	if err != nil {
+		t.Fatalf("failed to create: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := ms.Start(ctx); err != nil {
+		t.Fatalf("failed to start: %v", err)
+	}
+	defer ms.Stop(5 * time.Second)
+
+	waitUntil(t, func() bool {
+		st := ms.State()
+		return st.State == driver.StateRunning && st.Warming
+	}, time.Second, "service to report warming during grace")
+
+	waitUntil(t, func() bool {
+		st := ms.State()
+		return st.Health == "healthy" && !st.Warming
+	}, 2*time.Second, "warming to clear after the first passing check")
+}
+
+func TestManagedServiceFlappingAcrossRestarts(t *testing.T) {
+	// Each run passes its check briefly, then fails it and is restarted by
+	// the health monitor, so every cycle is unhealthy -> restart -> healthy
+	dir := t.TempDir()
+	ready := filepath.Join(dir, "ready")
+	script := filepath.Join(dir, "run.sh")
+	body := fmt.Sprintf("#!/bin/sh\ntouch %s\nsleep 0.3\nrm -f %s\nexec sleep 60\n", ready, ready)
+	if err := os.WriteFile(script, []byte(body), 0755); err != nil {
+		t.Fatal(err)
+	}
+	s := &spec.ServiceSpec{
+		Service: spec.Service{
+			Name:    "test-flapping",
+			Type:    "native",
+			Command: script,
+		},
+		Health: &spec.HealthCheck{
+			Type:               "exec",
+			Command:            "test -f " + ready,
+			Interval:           spec.Duration{Duration: 50 * time.Millisecond},
+			InitialDelay:       spec.Duration{Duration: 100 * time.Millisecond},
+			Timeout:            spec.Duration{Duration: time.Second},
+			UnhealthyThreshold: 1,
+			FlapThreshold:      4,
+			FlapWindow:         spec.Duration{Duration: time.Minute},
+		},
+		Restart: &spec.RestartPolicy{
+			Policy: "always",
+			Delay:  spec.Duration{Duration: 50 * time.Millisecond},
+		},
+	}
+
+	ms, err := NewManagedService(s, nil)
+	if err != nil {
+		t.Fatalf("failed to create: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := ms.Start(ctx); err != nil {
+		t.Fatalf("failed to start: %v", err)
+	}
+	defer ms.Stop(5 * time.Second)
+
+	waitUntil(t, func() bool {
+		return ms.State().Flapping
+	}, 10*time.Second, "service to be flagged as flapping across health restarts")
+	if n := ms.State().RestartCount; n < 2 {
+		t.Errorf("expected flapping to take at least two restarts, got %d", n)
+	}
+}
+
 func TestManagedServiceRejectsUnknownType(t *testing.T) {
 	s := &spec.ServiceSpec{
 		Service: spec.Service{
@@ -405,6 +887,123 @@ func TestManagedServiceStaticPortInjection(t *testing.T) {
 	}
 }
 
+func TestManagedServiceBindAddressInjection(t *testing.T) {
+	s := &spec.ServiceSpec{
+		Service: spec.Service{
+			Name:    "test-bind-address",
+			Type:    "native",
+			Command: "printenv HOST BIND_ADDRESS LISTEN",
+		},
+		Network: &spec.Network{Port: 8080, BindAddress: "127.0.0.1"},
+		Env:     map[string]string{"LISTEN": "${HOST}:${PORT}"},
+		Restart: &spec.RestartPolicy{Policy: "never"},
+	}
+
+	ms, err := NewManagedService(s, nil)
+	if err != nil {
+		t.Fatalf("failed to create: %v", err)
+	}
+	if err := ms.Start(context.Background()); err != nil {
+		t.Fatalf("failed to start: %v", err)
+	}
+	waitUntil(t, func() bool {
+		ms.mu.Lock()
+		drv := ms.drv
+		ms.mu.Unlock()
+		return drv != nil && len(drv.LogLines(3)) == 3
+	}, 2*time.Second, "process to produce log output")
+	ms.Stop(5 * time.Second)
+
+	lines := ms.drv.LogLines(10)
+	if got := strings.Join(lines, " "); got != "127.0.0.1 127.0.0.1 127.0.0.1:8080" {
+		t.Errorf("expected HOST, BIND_ADDRESS and interpolated ${HOST} in env, log output: %v", lines)
+	}
+}
+
+func TestManagedServiceGlobalEnv(t *testing.T) {
+	t.Setenv("AURELIA_TEST_FROM_HOST", "host")
+	s := &spec.ServiceSpec{
+		Service: spec.Service{
+			Name:    "test-global-env",
+			Type:    "native",
+			Command: "printenv SHARED OVERRIDDEN AURELIA_TEST_FROM_HOST PORT WHERE",
+		},
+		Network: &spec.Network{Port: 8080},
+		Env:     map[string]string{"OVERRIDDEN": "spec"},
+		Restart: &spec.RestartPolicy{Policy: "never"},
+	}
+
+	ms, err := NewManagedService(s, nil)
+	if err != nil {
+		t.Fatalf("failed to create: %v", err)
+	}
+	ms.globalEnv = map[string]string{
+		"SHARED":                 "global",
+		"OVERRIDDEN":             "global",
+		"AURELIA_TEST_FROM_HOST": "global",
+		"PORT":                   "1",
+		"WHERE":                  "${SERVICE_NAME}:${PORT}",
+	}
+	if err := ms.Start(context.Background()); err != nil {
+		t.Fatalf("failed to start: %v", err)
+	}
+	waitUntil(t, func() bool {
+		ms.mu.Lock()
+		drv := ms.drv
+		ms.mu.Unlock()
+		return drv != nil && len(drv.LogLines(5)) == 5
+	}, 2*time.Second, "process to produce log output")
+	ms.Stop(5 * time.Second)
+
+	lines := ms.drv.LogLines(10)
+	if got := strings.Join(lines, " "); got != "global spec global 8080 test-global-env:8080" {
+		t.Errorf("expected globals beneath spec env and PORT but over the host env, log output: %v", lines)
+	}
+}
+
+func TestManagedServiceHealthCheckUsesBindAddress(t *testing.T) {
+	// Listen only on the IPv6 loopback, where the default 127.0.0.1 health
+	// target can't reach
+	ln, err := net.Listen("tcp", "[::1]:0")
+	if err != nil {
+		t.Skipf("IPv6 loopback unavailable: %v", err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	port := ln.Addr().(*net.TCPAddr).Port
+
+	s := &spec.ServiceSpec{
+		Service: spec.Service{Name: "test-bind-health", Type: "native", Command: "sleep 10"},
+		Network: &spec.Network{Port: port, BindAddress: "::1"},
+		Health: &spec.HealthCheck{
+			Type:     "tcp",
+			Interval: spec.Duration{Duration: 50 * time.Millisecond},
+			Timeout:  spec.Duration{Duration: time.Second},
+		},
+	}
+
+	ms, err := NewManagedService(s, nil)
+	if err != nil {
+		t.Fatalf("failed to create: %v", err)
+	}
+	if err := ms.Start(context.Background()); err != nil {
+		t.Fatalf("failed to start: %v", err)
+	}
+	defer ms.Stop(5 * time.Second)
+
+	waitUntil(t, func() bool {
+		return ms.Health() == health.StatusHealthy
+	}, 3*time.Second, "health check against [::1] to pass")
+}
+
 func TestManagedServiceSecretInjection(t *testing.T) {
 	secrets := keychain.NewMemoryStore()
 	secrets.Set("chat/database-url", "postgres://secret@localhost/db")
@@ -552,6 +1151,41 @@ func TestManagedServiceEnvVarServiceName(t *testing.T) {
 	}
 }
 
+func TestManagedServicePublishedPorts(t *testing.T) {
+	tests := []struct {
+		name        string
+		networkMode string
+		network     *spec.Network
+		hostPort    int
+		want        map[int]int
+	}{
+		{"host networking", "", &spec.Network{Port: 8080}, 8080, nil},
+		{"explicit host", "host", &spec.Network{Port: 8080}, 8080, nil},
+		{"no network stack", "none", &spec.Network{Port: 8080}, 8080, nil},
+		{"shared container network", "container:other", &spec.Network{Port: 8080}, 8080, nil},
+		{"no network block", "bridge", nil, 0, nil},
+		{"bridge same port", "bridge", &spec.Network{Port: 8080}, 8080, map[int]int{8080: 8080}},
+		{"bridge dynamic port", "bridge", &spec.Network{Port: 0}, 27001, map[int]int{27001: 27001}},
+		{"bridge container_port", "bridge", &spec.Network{Port: 0, ContainerPort: 80}, 27001, map[int]int{27001: 80}},
+		{"user network", "mynet", &spec.Network{Port: 8080, ContainerPort: 80}, 8080, map[int]int{8080: 80}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ms, err := NewManagedService(&spec.ServiceSpec{
+				Service: spec.Service{Name: "web", Type: "container", Image: "nginx", NetworkMode: tt.networkMode},
+				Network: tt.network,
+			}, nil)
+			if err != nil {
+				t.Fatalf("NewManagedService: %v", err)
+			}
+			if got := ms.publishedPorts(tt.hostPort); !maps.Equal(got, tt.want) {
+				t.Errorf("publishedPorts(%d) = %v, want %v", tt.hostPort, got, tt.want)
+			}
+		})
+	}
+}
+
 func TestManagedServiceStopNotRunning(t *testing.T) {
 	s := &spec.ServiceSpec{
 		Service: spec.Service{
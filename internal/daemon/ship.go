@@ -1,6 +1,7 @@
 package daemon
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
 	"os/exec"
@@ -24,7 +25,8 @@ type ShipResult struct {
 }
 
 // ShipService runs the fetch → build → deploy → notify pipeline for a service.
-func (d *Daemon) ShipService(name string) (*ShipResult, error) {
+// The deploy is audited as the actor in ctx.
+func (d *Daemon) ShipService(ctx context.Context, name string) (*ShipResult, error) {
 	ms, err := d.getService(name)
 	if err != nil {
 		return nil, err
@@ -55,7 +57,7 @@ func (d *Daemon) ShipService(name string) (*ShipResult, error) {
 
 	// Step 3: Deploy
 	start := time.Now()
-	deployErr := d.DeployService(name, 5*time.Second)
+	deployErr := d.DeployService(ctx, name, 5*time.Second)
 	dur := time.Since(start).Truncate(time.Millisecond).String()
 	if deployErr != nil {
 		result.Steps = append(result.Steps, ShipStep{
@@ -26,6 +26,29 @@ type ServiceRecord struct {
 	Command     string `json:"command,omitempty"`      // process command for PID reuse detection
 	StartTime   int64  `json:"start_time,omitempty"`   // OS-reported process start time for PID reuse detection
 	ProcessName string `json:"process_name,omitempty"` // OS-reported executable name (may differ from command after exec)
+
+	// ManuallyStopped records that the operator stopped the service, so an
+	// unless-stopped service stays down across reloads and daemon restarts.
+	ManuallyStopped bool `json:"manually_stopped,omitempty"`
+
+	// Image is the ID of the image a container service was started from, and
+	// ImageDigest its registry digest if it has one.
+	Image       string `json:"image,omitempty"`
+	ImageDigest string `json:"image_digest,omitempty"`
+
+	// Previous is the record of the instance replaced by the last blue-green
+	// deploy, kept so the deploy can be rolled back.
+	Previous *ServiceRecord `json:"previous,omitempty"`
+
+	// The service's state as of its last supervision transition, for crash
+	// recovery and post-crash inspection. PID is cleared once the process
+	// is no longer running.
+	State        string `json:"state,omitempty"`
+	StateReason  string `json:"state_reason,omitempty"`
+	RestartCount int    `json:"restart_count,omitempty"`
+	LastExitCode int    `json:"last_exit_code,omitempty"`
+	LastError    string `json:"last_error,omitempty"`
+	UpdatedAt    int64  `json:"updated_at,omitempty"` // Unix timestamp
 }
 
 // newServiceRecord creates a ServiceRecord with the common fields populated.
@@ -84,6 +107,8 @@ func (sf *stateFile) save(records map[string]ServiceRecord) error {
 	return os.Rename(tmpPath, sf.path)
 }
 
+// set replaces the record for name. The previous deploy record is kept
+// across restarts unless rec carries its own.
 func (sf *stateFile) set(name string, rec ServiceRecord) error {
 	sf.mu.Lock()
 	defer sf.mu.Unlock()
@@ -92,6 +117,26 @@ func (sf *stateFile) set(name string, rec ServiceRecord) error {
 	if err != nil || records == nil {
 		records = make(map[string]ServiceRecord)
 	}
+	if rec.Previous == nil {
+		rec.Previous = records[name].Previous
+	}
+	records[name] = rec
+
+	return sf.saveUnsafe(records)
+}
+
+// update applies fn to the record for name, or to an empty record if there
+// is none, and saves the result.
+func (sf *stateFile) update(name string, fn func(rec *ServiceRecord)) error {
+	sf.mu.Lock()
+	defer sf.mu.Unlock()
+
+	records, err := sf.loadUnsafe()
+	if err != nil || records == nil {
+		records = make(map[string]ServiceRecord)
+	}
+	rec := records[name]
+	fn(&rec)
 	records[name] = rec
 
 	return sf.saveUnsafe(records)
@@ -109,6 +154,52 @@ func (sf *stateFile) remove(name string) error {
 	return sf.saveUnsafe(records)
 }
 
+// setManuallyStopped records or clears an operator stop for name. Recording
+// one replaces any process record, since the process is no longer running;
+// clearing one leaves records of running processes alone.
+func (sf *stateFile) setManuallyStopped(name, serviceType string, stopped bool) error {
+	sf.mu.Lock()
+	defer sf.mu.Unlock()
+
+	records, err := sf.loadUnsafe()
+	if err != nil || records == nil {
+		records = make(map[string]ServiceRecord)
+	}
+	if stopped {
+		records[name] = ServiceRecord{Type: serviceType, ManuallyStopped: true}
+	} else if rec, ok := records[name]; ok && rec.ManuallyStopped {
+		delete(records, name)
+	} else {
+		return nil
+	}
+	return sf.saveUnsafe(records)
+}
+
+// manuallyStopped reports whether the operator stopped name.
+func (sf *stateFile) manuallyStopped(name string) bool {
+	records, err := sf.load()
+	if err != nil {
+		return false
+	}
+	return records[name].ManuallyStopped
+}
+
+// clearRunning drops all process records on shutdown, keeping only
+// operator stops so they outlive the daemon.
+func (sf *stateFile) clearRunning() error {
+	sf.mu.Lock()
+	defer sf.mu.Unlock()
+
+	records, _ := sf.loadUnsafe()
+	kept := make(map[string]ServiceRecord)
+	for name, rec := range records {
+		if rec.ManuallyStopped {
+			kept[name] = rec
+		}
+	}
+	return sf.saveUnsafe(kept)
+}
+
 // loadUnsafe reads without locking — caller must hold sf.mu.
 func (sf *stateFile) loadUnsafe() (map[string]ServiceRecord, error) {
 	data, err := os.ReadFile(sf.path)
@@ -51,3 +51,26 @@ func TestStateFileRoundTrip(t *testing.T) {
 		t.Errorf("expected path %s, got %s", expected, sf.path)
 	}
 }
+
+func TestStateFileKeepsPreviousAcrossSet(t *testing.T) {
+	sf := newStateFile(t.TempDir())
+
+	prev := &ServiceRecord{Type: "container", Image: "sha256:old"}
+	if err := sf.set("svc", ServiceRecord{Type: "container", Image: "sha256:new", Previous: prev}); err != nil {
+		t.Fatalf("set: %v", err)
+	}
+
+	// A restart writes a fresh record without a previous deploy
+	if err := sf.set("svc", ServiceRecord{Type: "container", Image: "sha256:new", PID: 1}); err != nil {
+		t.Fatalf("set: %v", err)
+	}
+
+	records, err := sf.load()
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	rec := records["svc"]
+	if rec.PID != 1 || rec.Previous == nil || rec.Previous.Image != "sha256:old" {
+		t.Errorf("expected previous deploy to be kept, got %+v", rec)
+	}
+}
@@ -0,0 +1,57 @@
+package daemon
+
+import (
+	"sort"
+
+	"github.com/benaskins/aurelia/internal/driver"
+	"github.com/benaskins/aurelia/internal/health"
+)
+
+// StatusSummary rolls the state of every service up into counts, for
+// dashboards that only need to know whether anything needs attention.
+// ThermalState is filled in by the API server when GPU info is available.
+type StatusSummary struct {
+	Total        int      `json:"total"`
+	Running      int      `json:"running"`
+	Stopped      int      `json:"stopped"`
+	Completed    int      `json:"completed"`
+	Disabled     int      `json:"disabled"`
+	Failed       int      `json:"failed"`
+	Unhealthy    int      `json:"unhealthy"`
+	Attention    []string `json:"attention"` // failed or unhealthy services, sorted
+	ThermalState string   `json:"thermal_state,omitempty"`
+	OK           bool     `json:"ok"`
+}
+
+// StatusSummary returns counts over all services' current states.
+func (d *Daemon) StatusSummary() StatusSummary {
+	return summarize(d.ServiceStates())
+}
+
+// summarize counts states. A service needs attention if it has failed, or is
+// running with a failing health check; the summary is ok when none do.
+func summarize(states []ServiceState) StatusSummary {
+	sum := StatusSummary{Total: len(states), Attention: []string{}}
+	for _, st := range states {
+		switch st.State {
+		case driver.StateRunning:
+			sum.Running++
+			if st.Health == health.StatusUnhealthy {
+				sum.Unhealthy++
+				sum.Attention = append(sum.Attention, st.Name)
+			}
+		case driver.StateStopped:
+			sum.Stopped++
+		case driver.StateCompleted:
+			sum.Completed++
+		case driver.StateDisabled:
+			sum.Disabled++
+		case driver.StateFailed:
+			sum.Failed++
+			sum.Attention = append(sum.Attention, st.Name)
+		}
+	}
+	sort.Strings(sum.Attention)
+	sum.OK = len(sum.Attention) == 0
+	return sum
+}
@@ -0,0 +1,45 @@
+package daemon
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/benaskins/aurelia/internal/driver"
+	"github.com/benaskins/aurelia/internal/health"
+)
+
+func TestSummarize(t *testing.T) {
+	t.Parallel()
+
+	states := []ServiceState{
+		{Name: "api", State: driver.StateRunning, Health: health.StatusHealthy},
+		{Name: "worker", State: driver.StateRunning, Health: health.StatusUnknown},
+		{Name: "web", State: driver.StateRunning, Health: health.StatusUnhealthy},
+		{Name: "db", State: driver.StateFailed, StateReason: ReasonPolicyExhausted},
+		{Name: "batch", State: driver.StateStopped, StateReason: ReasonCleanExit},
+		{Name: "cache", State: driver.StateStarting},
+		{Name: "migrate", State: driver.StateCompleted, StateReason: ReasonCleanExit},
+		{Name: "legacy", State: driver.StateDisabled},
+	}
+	sum := summarize(states)
+	if sum.Total != 8 || sum.Running != 3 || sum.Stopped != 1 || sum.Completed != 1 || sum.Disabled != 1 || sum.Failed != 1 || sum.Unhealthy != 1 {
+		t.Errorf("unexpected counts: %+v", sum)
+	}
+	if sum.OK {
+		t.Error("expected not ok with a failed and an unhealthy service")
+	}
+	if !slices.Equal(sum.Attention, []string{"db", "web"}) {
+		t.Errorf("expected db and web to need attention, got %v", sum.Attention)
+	}
+
+	// Healthy, unchecked and deliberately stopped services are all fine
+	sum = summarize(states[:2])
+	if !sum.OK || sum.Total != 2 || sum.Running != 2 || len(sum.Attention) != 0 {
+		t.Errorf("expected ok with two running, got %+v", sum)
+	}
+
+	sum = summarize(nil)
+	if !sum.OK || sum.Total != 0 || sum.Attention == nil {
+		t.Errorf("expected ok and an empty attention list with no services, got %+v", sum)
+	}
+}
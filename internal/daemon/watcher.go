@@ -2,15 +2,36 @@ package daemon
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
 )
 
-const watcherDebounce = 500 * time.Millisecond
+const defaultWatchDebounce = 500 * time.Millisecond
 
-// StartWatcher watches the spec directory for changes and triggers Reload on modifications.
+// WithSpecWatch controls whether the daemon watches the spec directory and
+// reloads when spec files change (the default). Without it, specs are only
+// re-read on an explicit reload.
+func WithSpecWatch(enabled bool) Option {
+	return func(d *Daemon) {
+		d.noWatch = !enabled
+	}
+}
+
+// WithWatchDebounce sets how long the spec watcher waits after the last file
+// event before reloading, so a burst of saves causes a single reload. Values
+// <= 0 use the default (500ms).
+func WithWatchDebounce(window time.Duration) Option {
+	return func(d *Daemon) {
+		d.watchDebounce = window
+	}
+}
+
+// StartWatcher watches the spec directories for changes and triggers Reload on modifications.
+// Events are coalesced: the reload runs once no event has arrived for the
+// debounce window, on the watcher goroutine, so reloads never overlap.
 // It blocks until the context is cancelled.
 func (d *Daemon) StartWatcher(ctx context.Context) error {
 	watcher, err := fsnotify.NewWatcher()
@@ -19,20 +40,24 @@ func (d *Daemon) StartWatcher(ctx context.Context) error {
 	}
 	defer watcher.Close()
 
-	if err := watcher.Add(d.specDir); err != nil {
-		return err
+	for _, dir := range d.specDirs() {
+		if err := watcher.Add(dir); err != nil {
+			return fmt.Errorf("watching %s: %w", dir, err)
+		}
 	}
 
-	d.logger.Info("watching spec directory for changes", "dir", d.specDir)
+	window := d.watchDebounce
+	if window <= 0 {
+		window = defaultWatchDebounce
+	}
+	d.logger.Info("watching spec directories for changes", "dirs", d.specDirs(), "debounce", window)
 
-	var debounceTimer *time.Timer
+	// debounce is nil while no reload is pending
+	var debounce <-chan time.Time
 
 	for {
 		select {
 		case <-ctx.Done():
-			if debounceTimer != nil {
-				debounceTimer.Stop()
-			}
 			return nil
 
 		case event, ok := <-watcher.Events:
@@ -43,30 +68,11 @@ func (d *Daemon) StartWatcher(ctx context.Context) error {
 				continue
 			}
 			d.logger.Debug("spec file changed", "file", event.Name, "op", event.Op)
+			debounce = time.After(window)
 
-			// Debounce: reset timer on each event
-			if debounceTimer != nil {
-				debounceTimer.Stop()
-			}
-			debounceTimer = time.AfterFunc(watcherDebounce, func() {
-				if ctx.Err() != nil {
-					return // context already cancelled, skip reload
-				}
-				d.logger.Info("reloading specs after file change")
-				result, err := d.Reload(ctx)
-				if err != nil {
-					d.logger.Error("auto-reload failed", "error", err)
-					return
-				}
-				if len(result.Added) > 0 || len(result.Removed) > 0 || len(result.Restarted) > 0 {
-					d.logger.Info("auto-reload complete",
-						"added", result.Added,
-						"removed", result.Removed,
-						"restarted", result.Restarted)
-				} else {
-					d.logger.Debug("auto-reload: no changes detected")
-				}
-			})
+		case <-debounce:
+			debounce = nil
+			d.autoReload(ctx)
 
 		case err, ok := <-watcher.Errors:
 			if !ok {
@@ -76,3 +82,21 @@ func (d *Daemon) StartWatcher(ctx context.Context) error {
 		}
 	}
 }
+
+// autoReload reloads specs after the watcher saw them change.
+func (d *Daemon) autoReload(ctx context.Context) {
+	d.logger.Info("reloading specs after file change")
+	result, err := d.Reload(ctx)
+	if err != nil {
+		d.logger.Error("auto-reload failed", "error", err)
+		return
+	}
+	if len(result.Added) > 0 || len(result.Removed) > 0 || len(result.Restarted) > 0 {
+		d.logger.Info("auto-reload complete",
+			"added", result.Added,
+			"removed", result.Removed,
+			"restarted", result.Restarted)
+	} else {
+		d.logger.Debug("auto-reload: no changes detected")
+	}
+}
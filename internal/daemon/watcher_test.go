@@ -0,0 +1,74 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestWatcherDebouncesRapidChanges(t *testing.T) {
+	dir := t.TempDir()
+	// The state file goes elsewhere so writing it doesn't trigger the watcher
+	d := NewDaemon(dir, WithStateDir(t.TempDir()), WithWatchDebounce(300*time.Millisecond))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := d.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer d.Stop(5 * time.Second)
+	time.Sleep(100 * time.Millisecond) // let the watcher register
+
+	events, stop := d.WatchEvents()
+	defer stop()
+
+	// A burst of saves, each inside the debounce window of the last
+	for i := range 5 {
+		writeSpec(t, dir, "burst.yaml", fmt.Sprintf("service:\n  name: burst\n  type: native\n  command: \"sleep %d\"\n", 10+i))
+		time.Sleep(50 * time.Millisecond)
+	}
+	burstEnd := time.Now()
+
+	reloads := 0
+	timeout := time.After(2 * time.Second)
+	for done := false; !done; {
+		select {
+		case ev := <-events:
+			if ev.Type != EventReloaded {
+				continue
+			}
+			reloads++
+			if elapsed := ev.Time.Sub(burstEnd); elapsed < 250*time.Millisecond {
+				t.Errorf("reload %v after the last change, want at least the debounce window", elapsed)
+			}
+		case <-timeout:
+			done = true
+		}
+	}
+	if reloads != 1 {
+		t.Errorf("expected 1 reload for the burst, got %d", reloads)
+	}
+	if _, err := d.ServiceState("burst"); err != nil {
+		t.Errorf("expected burst service after reload: %v", err)
+	}
+}
+
+func TestWatcherDisabled(t *testing.T) {
+	dir := t.TempDir()
+	d := NewDaemon(dir, WithSpecWatch(false), WithWatchDebounce(50*time.Millisecond))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := d.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer d.Stop(5 * time.Second)
+
+	writeSpec(t, dir, "added.yaml", "service:\n  name: added\n  type: native\n  command: \"sleep 10\"\n")
+	time.Sleep(500 * time.Millisecond)
+
+	if _, err := d.ServiceState("added"); err == nil {
+		t.Error("expected no reload with the watcher disabled")
+	}
+}
@@ -8,6 +8,8 @@ import (
 	"sync"
 	"syscall"
 	"time"
+
+	"github.com/benaskins/aurelia/internal/logbuf"
 )
 
 // AdoptedDriver monitors an existing process by PID (crash recovery).
@@ -167,6 +169,14 @@ func (d *AdoptedDriver) LogLines(n int) []string {
 	return nil
 }
 
+func (d *AdoptedDriver) QueryLogs(q logbuf.Query) []logbuf.Line {
+	return nil
+}
+
+func (d *AdoptedDriver) WatchLogs() (<-chan logbuf.Line, func()) {
+	return nil, func() {}
+}
+
 // VerifyProcess checks whether the process at the given PID matches the expected
 // command name and start time. This guards against PID reuse: if the OS recycled
 // the PID for a different process, the command or start time won't match and
@@ -5,12 +5,20 @@ package driver
 import (
 	"context"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
+	cerrdefs "github.com/containerd/errdefs"
 	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
 	dockerclient "github.com/docker/docker/client"
 	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/docker/go-connections/nat"
 
 	"github.com/benaskins/aurelia/internal/logbuf"
 )
@@ -23,8 +31,90 @@ type ContainerConfig struct {
 	Cmd         []string          // command/args to pass to the container
 	NetworkMode string            // "host", "bridge", etc. Default: "host"
 	Privileged  bool              // run container in privileged mode
+	ReadOnly    bool              // mount the root filesystem read-only
+	CapDrop     []string          // Linux capabilities to drop
+	CapAdd      []string          // Linux capabilities to add
+	NoNewPrivs  bool              // set no-new-privileges
 	Volumes     map[string]string // host:container mount mappings
+	NamedVols   map[string]string // Docker volume name -> container path[:ro]
+	Tmpfs       map[string]int64  // container path -> tmpfs size in bytes (0 = Docker's default)
+	Files       map[string][]byte // container path -> contents, mounted read-only from a 0600 host file removed once the container exits
+	Ports       map[int]int       // host:container TCP port bindings (ignored with host networking)
 	BufSize     int               // log ring buffer size (lines)
+	Timestamps  bool              // prefix log lines with Docker's RFC 3339 timestamps
+	TTY         bool              // allocate a pseudo-terminal; logs are then a raw stream
+	StdinOpen   bool              // keep stdin open with nothing attached
+	Labels      map[string]string // extra container labels
+	Service     string            // aurelia service name for the aurelia.service label. Default: Name
+	NamePrefix  string            // prepended to Name for the container name. Default: DefaultContainerPrefix
+	FilesDir    string            // host directory for Files, in a subdirectory named after the container. Default: a new temporary directory
+}
+
+// containerLabels returns the user's labels plus the ones aurelia uses to
+// find the containers it manages.
+func containerLabels(cfg ContainerConfig) map[string]string {
+	labels := make(map[string]string, len(cfg.Labels)+2)
+	for k, v := range cfg.Labels {
+		labels[k] = v
+	}
+	service := cfg.Service
+	if service == "" {
+		service = cfg.Name
+	}
+	labels["managed-by"] = "aurelia"
+	labels["aurelia.service"] = service
+	return labels
+}
+
+// volumeMounts returns mounts for named Docker volumes, given as volume
+// name -> "/container/path[:ro]".
+func volumeMounts(volumes map[string]string) []mount.Mount {
+	mounts := make([]mount.Mount, 0, len(volumes))
+	for name, target := range volumes {
+		path, mode, _ := strings.Cut(target, ":")
+		mounts = append(mounts, mount.Mount{
+			Type:     mount.TypeVolume,
+			Source:   name,
+			Target:   path,
+			ReadOnly: mode == "ro",
+		})
+	}
+	return mounts
+}
+
+// writeFiles writes each of files to its own 0600 file in the private
+// directory dir, or a new temporary one if dir is empty, and returns the
+// directory and read-only bind mounts of the files at their container
+// paths. Whatever a previous run left in dir is replaced: it is named after
+// the container, so files a daemon that stopped uncleanly left behind are
+// removed by the next start.
+func writeFiles(dir string, files map[string][]byte) (string, []mount.Mount, error) {
+	var err error
+	if dir == "" {
+		dir, err = os.MkdirTemp("", "aurelia-files-")
+	} else if err = os.RemoveAll(dir); err == nil {
+		err = os.MkdirAll(dir, 0700)
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("creating directory for container files: %w", err)
+	}
+	mounts := make([]mount.Mount, 0, len(files))
+	i := 0
+	for target, data := range files {
+		source := filepath.Join(dir, strconv.Itoa(i))
+		i++
+		if err := os.WriteFile(source, data, 0600); err != nil {
+			os.RemoveAll(dir)
+			return "", nil, fmt.Errorf("writing container file %s: %w", target, err)
+		}
+		mounts = append(mounts, mount.Mount{
+			Type:     mount.TypeBind,
+			Source:   source,
+			Target:   target,
+			ReadOnly: true,
+		})
+	}
+	return dir, mounts, nil
 }
 
 // ContainerDriver manages a Docker container lifecycle.
@@ -35,12 +125,32 @@ type ContainerDriver struct {
 	closeOnce   sync.Once
 	client      *dockerclient.Client
 	containerID string
+	imageID     string
+	imageDigest string
 	state       State
 	startedAt   time.Time
 	exitCode    int
 	exitErr     string
 	buf         *logbuf.Ring
 	done        chan struct{}
+	filesDir    string // host directory holding cfg.Files while the container runs
+}
+
+// PingDocker checks that the Docker daemon is reachable, using the same
+// client settings as container drivers.
+func PingDocker(ctx context.Context) error {
+	cli, err := dockerclient.NewClientWithOpts(
+		dockerclient.FromEnv,
+		dockerclient.WithAPIVersionNegotiation(),
+	)
+	if err != nil {
+		return fmt.Errorf("creating docker client: %w", err)
+	}
+	defer cli.Close()
+	if _, err := cli.Ping(ctx); err != nil {
+		return fmt.Errorf("pinging docker: %w", err)
+	}
+	return nil
 }
 
 // NewContainer creates a new Docker container driver.
@@ -81,25 +191,53 @@ func (d *ContainerDriver) Start(ctx context.Context) error {
 	d.state = StateStarting
 
 	// Build container config
-	containerName := fmt.Sprintf("aurelia-%s", d.cfg.Name)
+	containerName := d.containerName()
 
-	// Remove any existing container with the same name
-	d.client.ContainerRemove(ctx, containerName, container.RemoveOptions{Force: true})
+	// Remove a leftover container of ours with the same name, but never one
+	// aurelia doesn't manage
+	if err := d.removeStale(ctx, containerName); err != nil {
+		d.state = StateFailed
+		d.exitErr = err.Error()
+		return err
+	}
 
 	config := &container.Config{
-		Image: d.cfg.Image,
-		Env:   d.cfg.Env,
-		Cmd:   d.cfg.Cmd,
+		Image:       d.cfg.Image,
+		Env:         d.cfg.Env,
+		Cmd:         d.cfg.Cmd,
+		Labels:      containerLabels(d.cfg),
+		Tty:         d.cfg.TTY,
+		OpenStdin:   d.cfg.StdinOpen,
+		AttachStdin: d.cfg.StdinOpen,
 	}
 
 	hostConfig := &container.HostConfig{
-		NetworkMode: container.NetworkMode(d.cfg.NetworkMode),
-		Privileged:  d.cfg.Privileged,
+		NetworkMode:    container.NetworkMode(d.cfg.NetworkMode),
+		Privileged:     d.cfg.Privileged,
+		ReadonlyRootfs: d.cfg.ReadOnly,
+		CapDrop:        d.cfg.CapDrop,
+		CapAdd:         d.cfg.CapAdd,
 		RestartPolicy: container.RestartPolicy{
 			Name: container.RestartPolicyDisabled, // aurelia handles restarts
 		},
 	}
 
+	if d.cfg.NoNewPrivs {
+		hostConfig.SecurityOpt = []string{"no-new-privileges:true"}
+	}
+
+	// Publish ports so the service is reachable from the host. With host
+	// networking the container already listens on the host's interfaces.
+	if len(d.cfg.Ports) > 0 && d.cfg.NetworkMode != "host" {
+		config.ExposedPorts = nat.PortSet{}
+		hostConfig.PortBindings = nat.PortMap{}
+		for hostPort, containerPort := range d.cfg.Ports {
+			p := nat.Port(fmt.Sprintf("%d/tcp", containerPort))
+			config.ExposedPorts[p] = struct{}{}
+			hostConfig.PortBindings[p] = append(hostConfig.PortBindings[p], nat.PortBinding{HostPort: strconv.Itoa(hostPort)})
+		}
+	}
+
 	// Volume mounts
 	if len(d.cfg.Volumes) > 0 {
 		binds := make([]string, 0, len(d.cfg.Volumes))
@@ -108,12 +246,40 @@ func (d *ContainerDriver) Start(ctx context.Context) error {
 		}
 		hostConfig.Binds = binds
 	}
+	if len(d.cfg.NamedVols) > 0 {
+		hostConfig.Mounts = volumeMounts(d.cfg.NamedVols)
+	}
+	if len(d.cfg.Files) > 0 {
+		var dir string
+		if d.cfg.FilesDir != "" {
+			dir = filepath.Join(d.cfg.FilesDir, containerName)
+		}
+		dir, mounts, err := writeFiles(dir, d.cfg.Files)
+		if err != nil {
+			d.state = StateFailed
+			d.exitErr = err.Error()
+			return err
+		}
+		d.filesDir = dir
+		hostConfig.Mounts = append(hostConfig.Mounts, mounts...)
+	}
+	if len(d.cfg.Tmpfs) > 0 {
+		hostConfig.Tmpfs = make(map[string]string, len(d.cfg.Tmpfs))
+		for path, size := range d.cfg.Tmpfs {
+			var opts string
+			if size > 0 {
+				opts = fmt.Sprintf("size=%d", size)
+			}
+			hostConfig.Tmpfs[path] = opts
+		}
+	}
 
 	// Create container
 	resp, err := d.client.ContainerCreate(ctx, config, hostConfig, nil, nil, containerName)
 	if err != nil {
 		d.state = StateFailed
 		d.exitErr = err.Error()
+		d.removeFiles()
 		return fmt.Errorf("creating container: %w", err)
 	}
 	d.containerID = resp.ID
@@ -124,9 +290,19 @@ func (d *ContainerDriver) Start(ctx context.Context) error {
 		d.exitErr = err.Error()
 		// Clean up created container
 		d.client.ContainerRemove(ctx, d.containerID, container.RemoveOptions{Force: true})
+		d.removeFiles()
 		return fmt.Errorf("starting container: %w", err)
 	}
 
+	// Record the image the container actually runs, so a mutable tag can be
+	// resolved back to this exact build later
+	if info, err := d.client.ContainerInspect(ctx, d.containerID); err == nil {
+		d.imageID = info.Image
+		if img, err := d.client.ImageInspect(ctx, info.Image); err == nil {
+			d.imageDigest = repoDigest(img.RepoDigests, d.cfg.Image)
+		}
+	}
+
 	d.state = StateRunning
 	d.startedAt = time.Now()
 	d.done = make(chan struct{})
@@ -210,11 +386,20 @@ func (d *ContainerDriver) LogLines(n int) []string {
 	return d.buf.Last(n)
 }
 
+func (d *ContainerDriver) QueryLogs(q logbuf.Query) []logbuf.Line {
+	return d.buf.Query(q)
+}
+
+func (d *ContainerDriver) WatchLogs() (<-chan logbuf.Line, func()) {
+	return d.buf.Watch()
+}
+
 func (d *ContainerDriver) streamLogs(ctx context.Context) {
 	opts := container.LogsOptions{
 		ShowStdout: true,
 		ShowStderr: true,
 		Follow:     true,
+		Timestamps: d.cfg.Timestamps,
 	}
 
 	reader, err := d.client.ContainerLogs(ctx, d.containerID, opts)
@@ -223,11 +408,27 @@ func (d *ContainerDriver) streamLogs(ctx context.Context) {
 	}
 	defer reader.Close()
 
+	// With a TTY, stdout and stderr are one raw stream, CRLF line endings
+	// and all; the ring buffer drops the CRs
+	if d.cfg.TTY {
+		io.Copy(d.buf, reader)
+		return
+	}
+
 	// Docker multiplexes stdout/stderr with 8-byte frame headers.
 	// StdCopy strips those headers, writing clean output to the ring buffer.
+	// Timestamps, when enabled, are part of each frame's payload and survive.
 	stdcopy.StdCopy(d.buf, d.buf, reader)
 }
 
+// removeFiles removes the host copies of cfg.Files, if written.
+func (d *ContainerDriver) removeFiles() {
+	if d.filesDir != "" {
+		os.RemoveAll(d.filesDir)
+		d.filesDir = ""
+	}
+}
+
 func (d *ContainerDriver) waitForExit() {
 	statusCh, errCh := d.client.ContainerWait(
 		context.Background(),
@@ -247,6 +448,7 @@ func (d *ContainerDriver) waitForExit() {
 		if err != nil {
 			d.exitErr = err.Error()
 		}
+		d.removeFiles()
 		close(d.done)
 		d.mu.Unlock()
 		// On natural exit (not triggered by Stop), close the client here since
@@ -269,6 +471,7 @@ func (d *ContainerDriver) waitForExit() {
 		if status.Error != nil {
 			d.exitErr = status.Error.Message
 		}
+		d.removeFiles()
 		close(d.done)
 		d.mu.Unlock()
 		// On natural exit (not triggered by Stop), close the client here since
@@ -279,9 +482,150 @@ func (d *ContainerDriver) waitForExit() {
 	}
 }
 
+// Health returns the status reported by the image's Docker HEALTHCHECK:
+// "starting", "healthy" or "unhealthy".
+func (d *ContainerDriver) Health(ctx context.Context) (string, error) {
+	d.mu.Lock()
+	containerID := d.containerID
+	d.mu.Unlock()
+	if containerID == "" {
+		return "", fmt.Errorf("container not started")
+	}
+
+	info, err := d.client.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return "", fmt.Errorf("inspecting container: %w", err)
+	}
+	if info.State == nil || info.State.Health == nil {
+		return "", fmt.Errorf("image %s defines no HEALTHCHECK", d.cfg.Image)
+	}
+	return info.State.Health.Status, nil
+}
+
+// Exec runs argv inside the running container (like docker exec), copying
+// its stdout and stderr to out as they are produced, and returns its exit code.
+func (d *ContainerDriver) Exec(ctx context.Context, argv []string, out io.Writer) (int, error) {
+	d.mu.Lock()
+	containerID := d.containerID
+	running := d.state == StateRunning
+	d.mu.Unlock()
+	if !running {
+		return -1, fmt.Errorf("container not running")
+	}
+
+	created, err := d.client.ContainerExecCreate(ctx, containerID, container.ExecOptions{
+		Cmd:          argv,
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+	if err != nil {
+		return -1, fmt.Errorf("creating exec: %w", err)
+	}
+
+	attach, err := d.client.ContainerExecAttach(ctx, created.ID, container.ExecAttachOptions{})
+	if err != nil {
+		return -1, fmt.Errorf("attaching to exec: %w", err)
+	}
+	defer attach.Close()
+
+	if _, err := stdcopy.StdCopy(out, out, attach.Reader); err != nil {
+		return -1, fmt.Errorf("reading exec output: %w", err)
+	}
+
+	result, err := d.client.ContainerExecInspect(ctx, created.ID)
+	if err != nil {
+		return -1, fmt.Errorf("inspecting exec: %w", err)
+	}
+	return result.ExitCode, nil
+}
+
+// containerName returns the Docker container name: the name prefix followed
+// by the configured name.
+func (d *ContainerDriver) containerName() string {
+	prefix := d.cfg.NamePrefix
+	if prefix == "" {
+		prefix = DefaultContainerPrefix
+	}
+	return prefix + d.cfg.Name
+}
+
+// removeStale force-removes an existing container called name if it carries
+// aurelia's managed-by label, such as one left behind by a crashed daemon. A
+// container by that name without the label belongs to someone else and is
+// left alone, failing the start.
+func (d *ContainerDriver) removeStale(ctx context.Context, name string) error {
+	info, err := d.client.ContainerInspect(ctx, name)
+	if cerrdefs.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("inspecting existing container %s: %w", name, err)
+	}
+	if info.Config == nil || info.Config.Labels["managed-by"] != "aurelia" {
+		return fmt.Errorf("container name %s is taken by a container aurelia doesn't manage", name)
+	}
+	if err := d.client.ContainerRemove(ctx, info.ID, container.RemoveOptions{Force: true}); err != nil && !cerrdefs.IsNotFound(err) {
+		return fmt.Errorf("removing existing container %s: %w", name, err)
+	}
+	return nil
+}
+
 // ContainerID returns the Docker container ID (for external inspection).
 func (d *ContainerDriver) ContainerID() string {
 	d.mu.Lock()
 	defer d.mu.Unlock()
 	return d.containerID
 }
+
+// Name returns the container name without the name prefix.
+func (d *ContainerDriver) Name() string {
+	return d.cfg.Name
+}
+
+// ImageID returns the ID of the image the container was started from, or ""
+// if it has not started.
+func (d *ContainerDriver) ImageID() string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.imageID
+}
+
+// ImageDigest returns the registry digest of the image the container was
+// started from, e.g. "ghcr.io/org/chat@sha256:...", or "" if it has not
+// started or the image was built locally and never pushed or pulled.
+func (d *ContainerDriver) ImageDigest() string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.imageDigest
+}
+
+// ResolveImage returns the ID of the local image the configured image
+// reference points to now. For a mutable tag this differs from ImageID once
+// a newer image has been pulled or built under the tag.
+func (d *ContainerDriver) ResolveImage(ctx context.Context) (string, error) {
+	img, err := d.client.ImageInspect(ctx, d.cfg.Image)
+	if err != nil {
+		return "", fmt.Errorf("inspecting image %s: %w", d.cfg.Image, err)
+	}
+	return img.ID, nil
+}
+
+// repoDigest picks the digest for image's repository from an image's repo
+// digests, falling back to the first when none matches (an image pulled
+// under several names).
+func repoDigest(digests []string, image string) string {
+	if len(digests) == 0 {
+		return ""
+	}
+	repo, _, _ := strings.Cut(image, "@")
+	if i := strings.LastIndex(repo, ":"); i > strings.LastIndex(repo, "/") {
+		repo = repo[:i] // drop the tag, not a registry port
+	}
+	for _, d := range digests {
+		name, _, _ := strings.Cut(d, "@")
+		if name == repo || strings.HasSuffix(name, "/"+repo) {
+			return d
+		}
+	}
+	return digests[0]
+}
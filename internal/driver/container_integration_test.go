@@ -3,9 +3,21 @@
 package driver
 
 import (
+	"archive/tar"
+	"bytes"
 	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/docker/docker/api/types/build"
+	"github.com/docker/docker/api/types/container"
+	dockerclient "github.com/docker/docker/client"
 )
 
 // Integration tests require a running Docker/OrbStack daemon.
@@ -125,3 +137,520 @@ func TestContainerWait(t *testing.T) {
 	// but with docker stop it may be 0 or the signal code
 	_ = exitCode
 }
+
+// buildHealthcheckImage builds a throwaway alpine image whose HEALTHCHECK
+// passes once /tmp/ready exists.
+func buildHealthcheckImage(t *testing.T) string {
+	t.Helper()
+	const tag = "aurelia-test-healthcheck:latest"
+	dockerfile := "FROM alpine:latest\n" +
+		"HEALTHCHECK --interval=1s --timeout=1s --retries=1 CMD test -f /tmp/ready\n" +
+		"CMD [\"sh\", \"-c\", \"sleep 2; touch /tmp/ready; sleep 60\"]\n"
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	tw.WriteHeader(&tar.Header{Name: "Dockerfile", Mode: 0644, Size: int64(len(dockerfile))})
+	tw.Write([]byte(dockerfile))
+	tw.Close()
+
+	cli, err := dockerclient.NewClientWithOpts(dockerclient.FromEnv, dockerclient.WithAPIVersionNegotiation())
+	if err != nil {
+		t.Fatalf("docker client: %v", err)
+	}
+	defer cli.Close()
+
+	resp, err := cli.ImageBuild(context.Background(), &buf, build.ImageBuildOptions{Tags: []string{tag}, Remove: true})
+	if err != nil {
+		t.Fatalf("building image: %v", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	return tag
+}
+
+func TestContainerHealthFromDockerHealthcheck(t *testing.T) {
+	d, err := NewContainer(ContainerConfig{
+		Name:        "test-healthcheck",
+		Image:       buildHealthcheckImage(t),
+		NetworkMode: "bridge",
+	})
+	if err != nil {
+		t.Fatalf("NewContainer: %v", err)
+	}
+
+	ctx := context.Background()
+	if _, err := d.Health(ctx); err == nil {
+		t.Error("expected error before the container is started")
+	}
+	if err := d.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer d.Stop(ctx, 5*time.Second)
+
+	status, err := d.Health(ctx)
+	if err != nil {
+		t.Fatalf("Health: %v", err)
+	}
+	if status != "starting" {
+		t.Errorf("expected starting right after start, got %q", status)
+	}
+
+	deadline := time.Now().Add(15 * time.Second)
+	for status != "healthy" {
+		if time.Now().After(deadline) {
+			t.Fatalf("container never became healthy, last status %q", status)
+		}
+		time.Sleep(500 * time.Millisecond)
+		if status, err = d.Health(ctx); err != nil {
+			t.Fatalf("Health: %v", err)
+		}
+	}
+}
+
+func TestContainerHealthWithoutHealthcheck(t *testing.T) {
+	d, err := NewContainer(ContainerConfig{
+		Name:        "test-no-healthcheck",
+		Image:       "alpine:latest",
+		Cmd:         []string{"sleep", "60"},
+		NetworkMode: "bridge",
+	})
+	if err != nil {
+		t.Fatalf("NewContainer: %v", err)
+	}
+
+	ctx := context.Background()
+	if err := d.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer d.Stop(ctx, 5*time.Second)
+
+	if _, err := d.Health(ctx); err == nil {
+		t.Error("expected error for an image without a HEALTHCHECK")
+	}
+}
+
+func TestContainerLogTimestamps(t *testing.T) {
+	for _, timestamps := range []bool{true, false} {
+		t.Run(fmt.Sprintf("timestamps=%v", timestamps), func(t *testing.T) {
+			d, err := NewContainer(ContainerConfig{
+				Name:        "test-log-timestamps",
+				Image:       "alpine:latest",
+				Cmd:         []string{"sh", "-c", "echo first; echo second >&2; sleep 30"},
+				NetworkMode: "bridge",
+				Timestamps:  timestamps,
+			})
+			if err != nil {
+				t.Fatalf("NewContainer: %v", err)
+			}
+
+			ctx := context.Background()
+			if err := d.Start(ctx); err != nil {
+				t.Fatalf("Start: %v", err)
+			}
+			defer d.Stop(ctx, 5*time.Second)
+
+			var lines []string
+			deadline := time.Now().Add(5 * time.Second)
+			for time.Now().Before(deadline) {
+				if lines = d.LogLines(10); len(lines) >= 2 {
+					break
+				}
+				time.Sleep(100 * time.Millisecond)
+			}
+			if len(lines) < 2 {
+				t.Fatalf("expected stdout and stderr lines, got %q", lines)
+			}
+
+			for _, line := range lines {
+				ts, msg, _ := strings.Cut(line, " ")
+				_, parseErr := time.Parse(time.RFC3339Nano, ts)
+				if timestamps {
+					if parseErr != nil {
+						t.Errorf("expected RFC 3339 timestamp prefix, got %q", line)
+					}
+					if msg != "first" && msg != "second" {
+						t.Errorf("expected demuxed output after timestamp, got %q", line)
+					}
+				} else if parseErr == nil {
+					t.Errorf("expected no timestamp prefix, got %q", line)
+				}
+			}
+		})
+	}
+}
+
+func TestContainerTTYLogs(t *testing.T) {
+	// A TTY merges stdout and stderr into one raw stream with no frame
+	// headers; demuxing it would garble or drop the output
+	d, err := NewContainer(ContainerConfig{
+		Name:        "test-tty-logs",
+		Image:       "alpine:latest",
+		Cmd:         []string{"sh", "-c", "echo first; echo second >&2; [ -t 0 ] && echo tty; cat"},
+		NetworkMode: "bridge",
+		TTY:         true,
+		StdinOpen:   true,
+	})
+	if err != nil {
+		t.Fatalf("NewContainer: %v", err)
+	}
+
+	ctx := context.Background()
+	if err := d.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer d.Stop(ctx, 5*time.Second)
+
+	var lines []string
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if lines = d.LogLines(10); len(lines) >= 3 {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if want := []string{"first", "second", "tty"}; strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Errorf("expected raw lines %q, got %q", want, lines)
+	}
+
+	// cat blocks on the open stdin rather than exiting at EOF
+	if info := d.Info(); info.State != StateRunning {
+		t.Errorf("expected the container still running with stdin open, got %v", info.State)
+	}
+}
+
+func TestContainerPublishedPortReachable(t *testing.T) {
+	// Find a free host port to publish on
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hostPort := l.Addr().(*net.TCPAddr).Port
+	l.Close()
+
+	d, err := NewContainer(ContainerConfig{
+		Name:        "test-published-port",
+		Image:       "alpine:latest",
+		Cmd:         []string{"sh", "-c", "while true; do echo hello | nc -l -p 8080; done"},
+		NetworkMode: "bridge",
+		Ports:       map[int]int{hostPort: 8080},
+	})
+	if err != nil {
+		t.Fatalf("NewContainer: %v", err)
+	}
+
+	ctx := context.Background()
+	if err := d.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer d.Stop(ctx, 5*time.Second)
+
+	addr := fmt.Sprintf("127.0.0.1:%d", hostPort)
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		conn, err := net.DialTimeout("tcp", addr, time.Second)
+		if err == nil {
+			conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+			buf := make([]byte, 16)
+			n, _ := conn.Read(buf)
+			conn.Close()
+			if strings.TrimSpace(string(buf[:n])) == "hello" {
+				return
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("published port %s not reachable from host: %v", addr, err)
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+}
+
+func TestContainerLabels(t *testing.T) {
+	d, err := NewContainer(ContainerConfig{
+		Name:        "test-labels-deploy",
+		Service:     "test-labels",
+		Image:       "alpine:latest",
+		Cmd:         []string{"sleep", "30"},
+		NetworkMode: "bridge",
+		Labels:      map[string]string{"team": "infra"},
+	})
+	if err != nil {
+		t.Fatalf("NewContainer: %v", err)
+	}
+
+	ctx := context.Background()
+	if err := d.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer d.Stop(ctx, 5*time.Second)
+
+	cli, err := dockerclient.NewClientWithOpts(dockerclient.FromEnv, dockerclient.WithAPIVersionNegotiation())
+	if err != nil {
+		t.Fatalf("docker client: %v", err)
+	}
+	defer cli.Close()
+
+	inspect, err := cli.ContainerInspect(ctx, d.ContainerID())
+	if err != nil {
+		t.Fatalf("inspecting container: %v", err)
+	}
+	want := map[string]string{
+		"team":            "infra",
+		"managed-by":      "aurelia",
+		"aurelia.service": "test-labels",
+	}
+	for k, v := range want {
+		if got := inspect.Config.Labels[k]; got != v {
+			t.Errorf("label %s = %q, want %q", k, got, v)
+		}
+	}
+}
+
+func TestContainerLeavesForeignNameCollision(t *testing.T) {
+	ctx := context.Background()
+	cli, err := dockerclient.NewClientWithOpts(dockerclient.FromEnv, dockerclient.WithAPIVersionNegotiation())
+	if err != nil {
+		t.Fatalf("docker client: %v", err)
+	}
+	defer cli.Close()
+
+	// A container aurelia didn't create, under the name aurelia would use
+	const foreignName = "aurelia-test-collision"
+	cli.ContainerRemove(ctx, foreignName, container.RemoveOptions{Force: true})
+	foreign, err := cli.ContainerCreate(ctx, &container.Config{
+		Image: "alpine:latest",
+		Cmd:   []string{"sleep", "30"},
+	}, nil, nil, nil, foreignName)
+	if err != nil {
+		t.Fatalf("creating foreign container: %v", err)
+	}
+	defer cli.ContainerRemove(ctx, foreign.ID, container.RemoveOptions{Force: true})
+
+	d, err := NewContainer(ContainerConfig{
+		Name:        "test-collision",
+		Image:       "alpine:latest",
+		Cmd:         []string{"sleep", "30"},
+		NetworkMode: "bridge",
+	})
+	if err != nil {
+		t.Fatalf("NewContainer: %v", err)
+	}
+	if err := d.Start(ctx); err == nil || !strings.Contains(err.Error(), "doesn't manage") {
+		d.Stop(ctx, 5*time.Second)
+		t.Fatalf("expected Start to refuse the foreign container's name, got %v", err)
+	}
+	if _, err := cli.ContainerInspect(ctx, foreign.ID); err != nil {
+		t.Fatalf("expected the foreign container to survive, got %v", err)
+	}
+
+	// Under another prefix the names don't collide
+	d, err = NewContainer(ContainerConfig{
+		Name:        "test-collision",
+		NamePrefix:  "aurelia-alt-",
+		Image:       "alpine:latest",
+		Cmd:         []string{"sleep", "30"},
+		NetworkMode: "bridge",
+	})
+	if err != nil {
+		t.Fatalf("NewContainer: %v", err)
+	}
+	if err := d.Start(ctx); err != nil {
+		t.Fatalf("Start with a prefix: %v", err)
+	}
+	defer d.Stop(ctx, 5*time.Second)
+	inspect, err := cli.ContainerInspect(ctx, d.ContainerID())
+	if err != nil {
+		t.Fatalf("inspecting container: %v", err)
+	}
+	if inspect.Name != "/aurelia-alt-test-collision" {
+		t.Errorf("container name = %q, want /aurelia-alt-test-collision", inspect.Name)
+	}
+}
+
+func TestContainerTmpfsMount(t *testing.T) {
+	d, err := NewContainer(ContainerConfig{
+		Name:        "test-tmpfs",
+		Image:       "alpine:latest",
+		Cmd:         []string{"sh", "-c", "grep ' /scratch ' /proc/mounts; sleep 30"},
+		NetworkMode: "bridge",
+		Tmpfs:       map[string]int64{"/scratch": 16 << 20},
+	})
+	if err != nil {
+		t.Fatalf("NewContainer: %v", err)
+	}
+
+	ctx := context.Background()
+	if err := d.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer d.Stop(ctx, 5*time.Second)
+
+	var lines []string
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if lines = d.LogLines(10); len(lines) > 0 {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if len(lines) == 0 {
+		t.Fatal("expected /scratch in /proc/mounts, got no output")
+	}
+	if fields := strings.Fields(lines[0]); len(fields) < 4 || fields[2] != "tmpfs" || !strings.Contains(fields[3], "size=16384k") {
+		t.Errorf("expected a 16MB tmpfs at /scratch, got %q", lines[0])
+	}
+}
+
+func TestContainerFiles(t *testing.T) {
+	d, err := NewContainer(ContainerConfig{
+		Name:        "test-files",
+		Image:       "alpine:latest",
+		Cmd:         []string{"sh", "-c", "stat -c %a /run/secrets/db-password; cat /run/secrets/db-password; echo; sleep 30"},
+		NetworkMode: "bridge",
+		Files:       map[string][]byte{"/run/secrets/db-password": []byte("hunter2")},
+	})
+	if err != nil {
+		t.Fatalf("NewContainer: %v", err)
+	}
+
+	ctx := context.Background()
+	if err := d.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	dir := d.filesDir
+	if info, err := os.Stat(dir); err != nil || info.Mode().Perm() != 0700 {
+		t.Errorf("expected a private host directory for the files, got %v, %v", info, err)
+	}
+
+	var lines []string
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if lines = d.LogLines(10); len(lines) >= 2 {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if got := strings.Join(lines, " "); got != "600 hunter2" {
+		t.Errorf("expected a 0600 /run/secrets/db-password holding the secret, got %q", got)
+	}
+
+	if err := d.Stop(ctx, 5*time.Second); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("expected %s to be removed on stop, got %v", dir, err)
+	}
+}
+
+func TestContainerFilesDirReplacesLeftovers(t *testing.T) {
+	root := t.TempDir()
+	d, err := NewContainer(ContainerConfig{
+		Name:        "test-files-dir",
+		Image:       "alpine:latest",
+		Cmd:         []string{"sleep", "30"},
+		NetworkMode: "bridge",
+		Files:       map[string][]byte{"/run/secrets/db-password": []byte("hunter2")},
+		FilesDir:    root,
+	})
+	if err != nil {
+		t.Fatalf("NewContainer: %v", err)
+	}
+
+	// A previous daemon that didn't stop cleanly left its copy behind
+	dir := filepath.Join(root, d.containerName())
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "stale"), []byte("old secret"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	if err := d.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if d.filesDir != dir {
+		t.Errorf("expected the files under %s, got %s", dir, d.filesDir)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "stale")); !os.IsNotExist(err) {
+		t.Errorf("expected the leftover file to be removed on start, got %v", err)
+	}
+
+	if err := d.Stop(ctx, 5*time.Second); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("expected %s to be removed on stop, got %v", dir, err)
+	}
+}
+
+func TestContainerSecurityOptions(t *testing.T) {
+	d, err := NewContainer(ContainerConfig{
+		Name:        "test-security",
+		Image:       "alpine:latest",
+		Cmd:         []string{"sleep", "30"},
+		NetworkMode: "bridge",
+		ReadOnly:    true,
+		CapDrop:     []string{"NET_RAW", "MKNOD"},
+		NoNewPrivs:  true,
+	})
+	if err != nil {
+		t.Fatalf("NewContainer: %v", err)
+	}
+
+	ctx := context.Background()
+	if err := d.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer d.Stop(ctx, 5*time.Second)
+
+	cli, err := dockerclient.NewClientWithOpts(dockerclient.FromEnv, dockerclient.WithAPIVersionNegotiation())
+	if err != nil {
+		t.Fatalf("docker client: %v", err)
+	}
+	defer cli.Close()
+
+	inspect, err := cli.ContainerInspect(ctx, d.ContainerID())
+	if err != nil {
+		t.Fatalf("inspecting container: %v", err)
+	}
+	hc := inspect.HostConfig
+	if !hc.ReadonlyRootfs {
+		t.Error("expected a read-only root filesystem")
+	}
+	if got := strings.Join(hc.CapDrop, ","); got != "NET_RAW,MKNOD" {
+		t.Errorf("CapDrop = %q, want NET_RAW,MKNOD", got)
+	}
+	if got := strings.Join(hc.SecurityOpt, ","); got != "no-new-privileges:true" {
+		t.Errorf("SecurityOpt = %q, want no-new-privileges:true", got)
+	}
+}
+
+func TestContainerImageDigest(t *testing.T) {
+	d, err := NewContainer(ContainerConfig{
+		Name:        "test-image-digest",
+		Image:       "alpine:latest",
+		Cmd:         []string{"sleep", "30"},
+		NetworkMode: "bridge",
+	})
+	if err != nil {
+		t.Fatalf("NewContainer: %v", err)
+	}
+
+	ctx := context.Background()
+	if err := d.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer d.Stop(ctx, 5*time.Second)
+
+	// alpine:latest was pulled from a registry, so it has a digest
+	if digest := d.ImageDigest(); !strings.Contains(digest, "alpine@sha256:") {
+		t.Errorf("expected an alpine repo digest, got %q", digest)
+	}
+	id, err := d.ResolveImage(ctx)
+	if err != nil {
+		t.Fatalf("ResolveImage: %v", err)
+	}
+	if id != d.ImageID() {
+		t.Errorf("expected the tag to resolve to the running image %q, got %q", d.ImageID(), id)
+	}
+}
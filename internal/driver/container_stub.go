@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"io"
 	"time"
+
+	"github.com/benaskins/aurelia/internal/logbuf"
 )
 
 // ContainerConfig holds configuration for a Docker container.
@@ -17,13 +19,32 @@ type ContainerConfig struct {
 	Cmd         []string          // command/args to pass to the container
 	NetworkMode string            // "host", "bridge", etc. Default: "host"
 	Privileged  bool              // run container in privileged mode
+	ReadOnly    bool              // mount the root filesystem read-only
+	CapDrop     []string          // Linux capabilities to drop
+	CapAdd      []string          // Linux capabilities to add
+	NoNewPrivs  bool              // set no-new-privileges
 	Volumes     map[string]string // host:container mount mappings
+	NamedVols   map[string]string // Docker volume name -> container path[:ro]
+	Tmpfs       map[string]int64  // container path -> tmpfs size in bytes (0 = Docker's default)
+	Files       map[string][]byte // container path -> contents, mounted read-only from a 0600 host file removed once the container exits
+	Ports       map[int]int       // host:container TCP port bindings (ignored with host networking)
 	BufSize     int               // log ring buffer size (lines)
+	Timestamps  bool              // prefix log lines with Docker's RFC 3339 timestamps
+	TTY         bool              // allocate a pseudo-terminal; logs are then a raw stream
+	StdinOpen   bool              // keep stdin open with nothing attached
+	Labels      map[string]string // extra container labels
+	Service     string            // aurelia service name for the aurelia.service label. Default: Name
+	NamePrefix  string            // prepended to Name for the container name. Default: DefaultContainerPrefix
 }
 
 // ContainerDriver is a stub when container support is excluded.
 type ContainerDriver struct{}
 
+// PingDocker returns an error when built with the nocontainer tag.
+func PingDocker(ctx context.Context) error {
+	return fmt.Errorf("container support excluded (built with nocontainer tag)")
+}
+
 // NewContainer returns an error when built with the nocontainer tag.
 func NewContainer(cfg ContainerConfig) (*ContainerDriver, error) {
 	return nil, fmt.Errorf("container support excluded (built with nocontainer tag)")
@@ -37,4 +58,18 @@ func (d *ContainerDriver) Info() ProcessInfo                               { ret
 func (d *ContainerDriver) Wait() (int, error)                              { return -1, fmt.Errorf("container support excluded") }
 func (d *ContainerDriver) Stdout() io.Reader                               { return nil }
 func (d *ContainerDriver) LogLines(n int) []string                         { return nil }
+func (d *ContainerDriver) QueryLogs(q logbuf.Query) []logbuf.Line          { return nil }
+func (d *ContainerDriver) WatchLogs() (<-chan logbuf.Line, func())         { return nil, func() {} }
 func (d *ContainerDriver) ContainerID() string                             { return "" }
+func (d *ContainerDriver) Name() string                                    { return "" }
+func (d *ContainerDriver) ImageID() string                                 { return "" }
+func (d *ContainerDriver) ImageDigest() string                             { return "" }
+func (d *ContainerDriver) ResolveImage(ctx context.Context) (string, error) {
+	return "", fmt.Errorf("container support excluded")
+}
+func (d *ContainerDriver) Exec(ctx context.Context, argv []string, out io.Writer) (int, error) {
+	return -1, fmt.Errorf("container support excluded")
+}
+func (d *ContainerDriver) Health(ctx context.Context) (string, error) {
+	return "", fmt.Errorf("container support excluded")
+}
@@ -3,6 +3,8 @@ package driver
 import (
 	"context"
 	"time"
+
+	"github.com/benaskins/aurelia/internal/logbuf"
 )
 
 // State represents the lifecycle state of a managed process.
@@ -14,8 +16,26 @@ const (
 	StateRunning  State = "running"
 	StateStopping State = "stopping"
 	StateFailed   State = "failed"
+
+	// StateUnreachable is reported for external services whose health check
+	// has been failing past its unhealthy threshold. Aurelia does not manage
+	// their lifecycle, so this reflects observation only.
+	StateUnreachable State = "unreachable"
+
+	// StateCompleted is reported for services whose process exited 0 and
+	// that their restart policy doesn't restart, such as one-shot tasks
+	// under policy never. Drivers themselves never report it.
+	StateCompleted State = "completed"
+
+	// StateDisabled is reported for services whose spec sets enabled: false.
+	// The daemon knows them but never starts them.
+	StateDisabled State = "disabled"
 )
 
+// DefaultContainerPrefix is prepended to a container service's name to name
+// its Docker container, unless ContainerConfig.NamePrefix says otherwise.
+const DefaultContainerPrefix = "aurelia-"
+
 // ProcessInfo holds runtime information about a managed process.
 type ProcessInfo struct {
 	PID       int
@@ -44,4 +64,12 @@ type Driver interface {
 
 	// LogLines returns the last n lines from the log buffer.
 	LogLines(n int) []string
+
+	// QueryLogs returns the lines in the log buffer selected by q.
+	QueryLogs(q logbuf.Query) []logbuf.Line
+
+	// WatchLogs subscribes to lines as they are added to the log buffer
+	// until cancel is called. Drivers without log capture return a nil
+	// channel, which never delivers.
+	WatchLogs() (lines <-chan logbuf.Line, cancel func())
 }
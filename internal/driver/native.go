@@ -2,7 +2,9 @@ package driver
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"os"
 	"os/exec"
 	"strings"
 	"sync"
@@ -12,12 +14,23 @@ import (
 	"github.com/benaskins/aurelia/internal/logbuf"
 )
 
+// instanceEnv is set in every native process's environment to a value unique
+// to that start, so children that leave the process group can still be found.
+const instanceEnv = "AURELIA_INSTANCE"
+
+// pipeWaitDelay is how long to keep reading output after the process exits.
+const pipeWaitDelay = 500 * time.Millisecond
+
 // NativeDriver manages a native (fork/exec) process.
 type NativeDriver struct {
-	command    string
-	args       []string
-	env        []string
-	workingDir string
+	command     string
+	args        []string
+	env         []string
+	workingDir  string
+	priority    int
+	oomScoreAdj int
+	killOrphans bool
+	onOrphans   func(pids []int)
 
 	mu        sync.Mutex
 	cmd       *exec.Cmd
@@ -27,14 +40,23 @@ type NativeDriver struct {
 	exitErr   string
 	buf       *logbuf.Ring
 	done      chan struct{}
+	instance  string
+	orphans   []int
 }
 
 // NativeConfig holds configuration for a native process.
 type NativeConfig struct {
-	Command    string
-	Env        []string
-	WorkingDir string
-	BufSize    int // log ring buffer size (lines), 0 for default
+	Command     string
+	Env         []string
+	WorkingDir  string
+	BufSize     int // log ring buffer size (lines), 0 for default
+	Priority    int // nice value, 0 to leave unchanged
+	OOMScoreAdj int // Linux OOM score adjustment, 0 to leave unchanged; see OOMScoreAdjSupported
+
+	// KillOrphans SIGKILLs processes that outlive Stop, see Orphans.
+	KillOrphans bool
+	// OnOrphans, if set, is called after a Stop that left processes behind.
+	OnOrphans func(pids []int)
 }
 
 // NewNative creates a new native process driver.
@@ -53,12 +75,16 @@ func NewNative(cfg NativeConfig) *NativeDriver {
 	}
 
 	return &NativeDriver{
-		command:    command,
-		args:       args,
-		env:        cfg.Env,
-		workingDir: cfg.WorkingDir,
-		state:      StateStopped,
-		buf:        logbuf.New(bufSize),
+		command:     command,
+		args:        args,
+		env:         cfg.Env,
+		workingDir:  cfg.WorkingDir,
+		priority:    cfg.Priority,
+		oomScoreAdj: cfg.OOMScoreAdj,
+		killOrphans: cfg.KillOrphans,
+		onOrphans:   cfg.OnOrphans,
+		state:       StateStopped,
+		buf:         logbuf.New(bufSize),
 	}
 }
 
@@ -76,7 +102,13 @@ func (d *NativeDriver) Start(ctx context.Context) error {
 	// next daemon instance. Process termination is handled explicitly by
 	// NativeDriver.Stop() and the supervision loop.
 	d.cmd = exec.Command(d.command, d.args...)
-	d.cmd.Env = d.env
+	env := d.env
+	if env == nil {
+		env = os.Environ() // what a nil cmd.Env would have inherited
+	}
+	d.instance = fmt.Sprintf("%d-%d", os.Getpid(), time.Now().UnixNano())
+	d.cmd.Env = append(env[:len(env):len(env)], instanceEnv+"="+d.instance)
+	d.orphans = nil
 	if d.workingDir != "" {
 		d.cmd.Dir = d.workingDir
 	}
@@ -84,6 +116,9 @@ func (d *NativeDriver) Start(ctx context.Context) error {
 	// Capture stdout and stderr into the ring buffer
 	d.cmd.Stdout = d.buf
 	d.cmd.Stderr = d.buf
+	// Children that outlive the process keep its output pipe open; don't let
+	// them hold up Wait, and so Stop, for longer than this
+	d.cmd.WaitDelay = pipeWaitDelay
 
 	// Set process group so we can kill the whole tree
 	d.cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
@@ -96,6 +131,15 @@ func (d *NativeDriver) Start(ctx context.Context) error {
 		return fmt.Errorf("starting process: %w", err)
 	}
 
+	if err := d.applyPriority(d.cmd.Process.Pid); err != nil {
+		// Don't leave a process running at the wrong priority
+		_ = syscall.Kill(-d.cmd.Process.Pid, syscall.SIGKILL)
+		_ = d.cmd.Wait()
+		d.state = StateFailed
+		d.exitErr = err.Error()
+		return err
+	}
+
 	d.state = StateRunning
 	d.startedAt = time.Now()
 	d.done = make(chan struct{})
@@ -103,6 +147,9 @@ func (d *NativeDriver) Start(ctx context.Context) error {
 	// Wait for process exit in background
 	go func() {
 		err := d.cmd.Wait()
+		if errors.Is(err, exec.ErrWaitDelay) {
+			err = nil // exited cleanly, orphans still had the pipe open
+		}
 		d.mu.Lock()
 		defer d.mu.Unlock()
 
@@ -128,6 +175,23 @@ func (d *NativeDriver) Start(ctx context.Context) error {
 	return nil
 }
 
+// applyPriority sets the configured nice value and OOM score adjustment on a
+// freshly started process. The nice value is set on the whole process group
+// so children forked straight away get it too.
+func (d *NativeDriver) applyPriority(pid int) error {
+	if d.priority != 0 {
+		if err := syscall.Setpriority(syscall.PRIO_PGRP, pid, d.priority); err != nil {
+			return fmt.Errorf("setting priority %d: %w", d.priority, err)
+		}
+	}
+	if d.oomScoreAdj != 0 && OOMScoreAdjSupported {
+		if err := setOOMScoreAdj(pid, d.oomScoreAdj); err != nil {
+			return fmt.Errorf("setting oom_score_adj %d: %w", d.oomScoreAdj, err)
+		}
+	}
+	return nil
+}
+
 func (d *NativeDriver) Stop(ctx context.Context, timeout time.Duration) error {
 	d.mu.Lock()
 
@@ -138,8 +202,17 @@ func (d *NativeDriver) Stop(ctx context.Context, timeout time.Duration) error {
 
 	d.state = StateStopping
 	pid := d.cmd.Process.Pid
+	instance := d.instance
 	d.mu.Unlock()
 
+	err := d.terminate(ctx, pid, timeout)
+	d.checkOrphans(pid, instance)
+	return err
+}
+
+// terminate sends SIGTERM to the process group and waits for the process to
+// exit, escalating to SIGKILL after timeout or when ctx is cancelled.
+func (d *NativeDriver) terminate(ctx context.Context, pid int, timeout time.Duration) error {
 	// Send SIGTERM to the process group (may already be exited)
 	_ = syscall.Kill(-pid, syscall.SIGTERM)
 
@@ -177,6 +250,37 @@ func (d *NativeDriver) Stop(ctx context.Context, timeout time.Duration) error {
 	}
 }
 
+// checkOrphans looks for processes from the stopped instance that are still
+// alive: children in the process group that survived the signals, and
+// children that double-forked or called setsid to leave the group. Those are
+// SIGKILLed if KillOrphans is set, and reported through Orphans and OnOrphans
+// either way.
+func (d *NativeDriver) checkOrphans(pgid int, instance string) {
+	pids := groupSurvivors(pgid, instance)
+	if d.killOrphans {
+		for _, pid := range pids {
+			_ = syscall.Kill(pid, syscall.SIGKILL) // may already be exited
+		}
+	}
+
+	d.mu.Lock()
+	d.orphans = pids
+	d.mu.Unlock()
+
+	if len(pids) > 0 && d.onOrphans != nil {
+		d.onOrphans(pids)
+	}
+}
+
+// Orphans returns the processes found still running after the last Stop, or
+// nil if it left nothing behind. Some may have exited since, or been killed
+// by KillOrphans.
+func (d *NativeDriver) Orphans() []int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.orphans
+}
+
 func (d *NativeDriver) Info() ProcessInfo {
 	d.mu.Lock()
 	defer d.mu.Unlock()
@@ -212,3 +316,11 @@ func (d *NativeDriver) Wait() (int, error) {
 func (d *NativeDriver) LogLines(n int) []string {
 	return d.buf.Last(n)
 }
+
+func (d *NativeDriver) QueryLogs(q logbuf.Query) []logbuf.Line {
+	return d.buf.Query(q)
+}
+
+func (d *NativeDriver) WatchLogs() (<-chan logbuf.Line, func()) {
+	return d.buf.Watch()
+}
@@ -2,7 +2,14 @@ package driver
 
 import (
 	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
 	"strings"
+	"syscall"
 	"testing"
 	"time"
 )
@@ -269,3 +276,100 @@ func TestNativeStopContextCancelled(t *testing.T) {
 		t.Errorf("expected stopped or failed state, got %v", info.State)
 	}
 }
+
+func TestNativePriority(t *testing.T) {
+	d := NewNative(NativeConfig{
+		Command:  "sleep 5",
+		Priority: 7,
+	})
+	if err := d.Start(context.Background()); err != nil {
+		t.Fatalf("failed to start: %v", err)
+	}
+	defer d.Stop(context.Background(), 2*time.Second)
+
+	out, err := exec.Command("ps", "-o", "nice=", "-p", strconv.Itoa(d.Info().PID)).Output()
+	if err != nil {
+		t.Fatalf("ps: %v", err)
+	}
+	if nice := strings.TrimSpace(string(out)); nice != "7" {
+		t.Errorf("expected nice 7, got %q", nice)
+	}
+}
+
+func TestNativeOOMScoreAdj(t *testing.T) {
+	if !OOMScoreAdjSupported {
+		t.Skipf("oom_score_adj is not supported on %s", runtime.GOOS)
+	}
+	d := NewNative(NativeConfig{
+		Command:     "sleep 5",
+		OOMScoreAdj: 500, // raising the score needs no privileges
+	})
+	if err := d.Start(context.Background()); err != nil {
+		t.Fatalf("failed to start: %v", err)
+	}
+	defer d.Stop(context.Background(), 2*time.Second)
+
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/oom_score_adj", d.Info().PID))
+	if err != nil {
+		t.Fatalf("reading oom_score_adj: %v", err)
+	}
+	if adj := strings.TrimSpace(string(data)); adj != "500" {
+		t.Errorf("expected oom_score_adj 500, got %q", adj)
+	}
+}
+
+func TestNativeStopDetectsOrphans(t *testing.T) {
+	if _, err := exec.LookPath("setsid"); err != nil {
+		t.Skip("setsid not available")
+	}
+	// One child escapes the process group with setsid, another stays in it
+	// but ignores SIGTERM; the main process exits on SIGTERM
+	script := filepath.Join(t.TempDir(), "run.sh")
+	content := "#!/bin/sh\nsetsid sleep 30 &\n(trap '' TERM; exec sleep 30) &\nexec sleep 30\n"
+	if err := os.WriteFile(script, []byte(content), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, kill := range []bool{false, true} {
+		t.Run(fmt.Sprintf("kill_orphans=%v", kill), func(t *testing.T) {
+			var reported []int
+			d := NewNative(NativeConfig{
+				Command:     script,
+				KillOrphans: kill,
+				OnOrphans:   func(pids []int) { reported = pids },
+			})
+			if err := d.Start(context.Background()); err != nil {
+				t.Fatalf("failed to start: %v", err)
+			}
+			pgid := d.Info().PID
+			time.Sleep(200 * time.Millisecond) // let the children start
+
+			if err := d.Stop(context.Background(), 2*time.Second); err != nil {
+				t.Fatalf("stop: %v", err)
+			}
+			orphans := d.Orphans()
+			t.Cleanup(func() {
+				for _, pid := range orphans {
+					syscall.Kill(pid, syscall.SIGKILL)
+				}
+			})
+
+			if len(orphans) != 2 || len(reported) != 2 {
+				t.Fatalf("expected 2 orphans reported, got %v (callback %v)", orphans, reported)
+			}
+
+			// Killed orphans may linger as zombies, which don't count
+			left := groupSurvivors(pgid, d.instance)
+			for deadline := time.Now().Add(2 * time.Second); kill && len(left) > 0 && time.Now().Before(deadline); {
+				time.Sleep(20 * time.Millisecond)
+				left = groupSurvivors(pgid, d.instance)
+			}
+			if kill && len(left) != 0 {
+				t.Errorf("expected orphans killed, found %v still running", left)
+			}
+			if !kill && len(left) != 2 {
+				t.Errorf("expected orphans %v left running, found %v", orphans, left)
+			}
+		})
+	}
+}
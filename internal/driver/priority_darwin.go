@@ -0,0 +1,12 @@
+//go:build darwin
+
+package driver
+
+// OOMScoreAdjSupported reports whether NativeConfig.OOMScoreAdj has an
+// effect on this platform. macOS has no per-process OOM score, so it is
+// ignored.
+const OOMScoreAdjSupported = false
+
+func setOOMScoreAdj(pid, adj int) error {
+	return nil
+}
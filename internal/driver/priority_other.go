@@ -0,0 +1,19 @@
+//go:build !darwin
+
+package driver
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// OOMScoreAdjSupported reports whether NativeConfig.OOMScoreAdj has an
+// effect on this platform.
+const OOMScoreAdjSupported = true
+
+// setOOMScoreAdj writes /proc/<pid>/oom_score_adj. Lowering the score below
+// its current value needs CAP_SYS_RESOURCE.
+func setOOMScoreAdj(pid, adj int) error {
+	return os.WriteFile(fmt.Sprintf("/proc/%d/oom_score_adj", pid), []byte(strconv.Itoa(adj)), 0)
+}
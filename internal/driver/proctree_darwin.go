@@ -0,0 +1,48 @@
+//go:build darwin
+
+package driver
+
+import (
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// groupSurvivors returns the live processes, other than this one, that are
+// still in process group pgid or carry AURELIA_INSTANCE=instance in their
+// environment. The environment match catches children that left the group
+// with setsid or setpgid; ps only shows the environment of processes owned
+// by our user, which covers everything a service started.
+func groupSurvivors(pgid int, instance string) []int {
+	// -E appends the environment to the command, -ww stops it being cut
+	out, err := exec.Command("ps", "-A", "-E", "-ww", "-o", "pid=,pgid=,stat=,command=").Output()
+	if err != nil {
+		return nil
+	}
+	marker := instanceEnv + "=" + instance
+	self := os.Getpid()
+
+	var pids []int
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 4 {
+			continue
+		}
+		pid, err := strconv.Atoi(fields[0])
+		if err != nil || pid == self || strings.HasPrefix(fields[2], "Z") {
+			continue
+		}
+		if pg, _ := strconv.Atoi(fields[1]); pg == pgid {
+			pids = append(pids, pid)
+			continue
+		}
+		for _, f := range fields[3:] {
+			if f == marker {
+				pids = append(pids, pid)
+				break
+			}
+		}
+	}
+	return pids
+}
@@ -0,0 +1,63 @@
+//go:build !darwin
+
+package driver
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// groupSurvivors returns the live processes, other than this one, that are
+// still in process group pgid or carry AURELIA_INSTANCE=instance in their
+// environment. The environment match catches children that left the group
+// with setsid or setpgid; it only sees processes we may read, which covers
+// everything a service started as our user.
+func groupSurvivors(pgid int, instance string) []int {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil
+	}
+	marker := []byte(instanceEnv + "=" + instance)
+	self := os.Getpid()
+
+	var pids []int
+	for _, e := range entries {
+		pid, err := strconv.Atoi(e.Name())
+		if err != nil || pid == self {
+			continue
+		}
+		stat, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+		if err != nil {
+			continue // exited while we were looking
+		}
+		// The command name is in parentheses and may contain spaces, so
+		// the fields after it are counted from the last ')':
+		// state ppid pgrp ...
+		i := bytes.LastIndexByte(stat, ')')
+		if i < 0 {
+			continue
+		}
+		fields := strings.Fields(string(stat[i+1:]))
+		if len(fields) < 3 || fields[0] == "Z" {
+			continue
+		}
+		if pg, _ := strconv.Atoi(fields[2]); pg == pgid {
+			pids = append(pids, pid)
+			continue
+		}
+		environ, err := os.ReadFile(fmt.Sprintf("/proc/%d/environ", pid))
+		if err != nil {
+			continue
+		}
+		for _, kv := range bytes.Split(environ, []byte{0}) {
+			if bytes.Equal(kv, marker) {
+				pids = append(pids, pid)
+				break
+			}
+		}
+	}
+	return pids
+}
@@ -6,6 +6,8 @@ import (
 	"os/exec"
 	"sync"
 	"time"
+
+	"github.com/benaskins/aurelia/internal/logbuf"
 )
 
 // RemoteConfig holds configuration for a remote service driver.
@@ -117,6 +119,16 @@ func (d *RemoteDriver) LogLines(n int) []string {
 	return nil
 }
 
+// QueryLogs returns nil — remote services don't have local log capture.
+func (d *RemoteDriver) QueryLogs(q logbuf.Query) []logbuf.Line {
+	return nil
+}
+
+// WatchLogs returns a nil channel — remote services don't have local log capture.
+func (d *RemoteDriver) WatchLogs() (<-chan logbuf.Line, func()) {
+	return nil, func() {}
+}
+
 func runHook(ctx context.Context, command string) error {
 	cmd := exec.CommandContext(ctx, "sh", "-c", command)
 	return cmd.Run()
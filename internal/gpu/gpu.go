@@ -1,11 +1,13 @@
 // Package gpu provides GPU observability for Apple Silicon Macs.
 //
 // Exposes VRAM usage via Metal framework and thermal state via IOKit.
-// Polled periodically and cached — not real-time.
+// Polled periodically and cached — not real-time — with a bounded history
+// of recent samples.
 package gpu
 
 import (
 	"context"
+	"slices"
 	"sync"
 	"time"
 )
@@ -21,6 +23,13 @@ type Info struct {
 	Timestamp        time.Time `json:"timestamp"`
 }
 
+// Series is a run of samples from an Observer, as served by the daemon's GPU
+// history endpoint.
+type Series struct {
+	Interval string `json:"interval"` // time between samples, e.g. "5s"
+	Samples  []Info `json:"samples"`  // oldest first
+}
+
 // AllocatedGB returns allocated memory in gigabytes.
 func (i Info) AllocatedGB() float64 {
 	return float64(i.AllocatedBytes) / (1024 * 1024 * 1024)
@@ -31,19 +40,60 @@ func (i Info) RecommendedMaxGB() float64 {
 	return float64(i.RecommendedMax) / (1024 * 1024 * 1024)
 }
 
-// Observer periodically polls GPU state and caches the result.
+// DefaultHistory is how far back an Observer keeps samples for History
+// unless set with WithHistory.
+const DefaultHistory = 15 * time.Minute
+
+// Observer periodically polls GPU state and caches the result, keeping a
+// bounded history of past samples.
 type Observer struct {
 	mu       sync.RWMutex
 	info     Info
+	history  []Info // oldest first, at most maxSamples
 	interval time.Duration
 	cancel   context.CancelFunc
+
+	maxSamples int
+	sample     func() Info
+}
+
+// ObserverOption configures an Observer.
+type ObserverOption func(*Observer)
+
+// WithHistory sets how far back the observer keeps samples. The number kept
+// is the window divided by the polling interval.
+func WithHistory(window time.Duration) ObserverOption {
+	return func(o *Observer) {
+		o.maxSamples = 1
+		if o.interval > 0 {
+			o.maxSamples = max(1, int(window/o.interval))
+		}
+	}
+}
+
+// withSampler replaces the GPU query, for tests.
+func withSampler(f func() Info) ObserverOption {
+	return func(o *Observer) {
+		o.sample = f
+	}
 }
 
 // NewObserver creates a GPU observer that polls at the given interval.
-func NewObserver(interval time.Duration) *Observer {
-	return &Observer{
+func NewObserver(interval time.Duration, opts ...ObserverOption) *Observer {
+	o := &Observer{
 		interval: interval,
+		sample:   QueryNow,
+	}
+	WithHistory(DefaultHistory)(o)
+	for _, opt := range opts {
+		opt(o)
 	}
+	return o
+}
+
+// Interval returns how often the observer polls.
+func (o *Observer) Interval() time.Duration {
+	return o.interval
 }
 
 // Start begins polling GPU state in the background.
@@ -88,6 +138,18 @@ func (o *Observer) Info() Info {
 	return o.info
 }
 
+// History returns the retained samples taken after since, oldest first. A
+// zero since returns them all.
+func (o *Observer) History(since time.Time) []Info {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	i := 0
+	for i < len(o.history) && !o.history[i].Timestamp.After(since) {
+		i++
+	}
+	return slices.Clone(o.history[i:])
+}
+
 // QueryNow returns a one-shot GPU info snapshot.
 func QueryNow() Info {
 	info := queryGPU()
@@ -96,9 +158,13 @@ func QueryNow() Info {
 }
 
 func (o *Observer) poll() {
-	info := QueryNow()
+	info := o.sample()
 
 	o.mu.Lock()
 	o.info = info
+	o.history = append(o.history, info)
+	if len(o.history) > o.maxSamples {
+		o.history = o.history[len(o.history)-o.maxSamples:]
+	}
 	o.mu.Unlock()
 }
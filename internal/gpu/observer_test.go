@@ -0,0 +1,86 @@
+package gpu
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeSampler returns samples with increasing allocation and timestamps.
+type fakeSampler struct {
+	mu sync.Mutex
+	n  int
+}
+
+func (f *fakeSampler) sample() Info {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.n++
+	return Info{
+		Name:           "fake",
+		AllocatedBytes: uint64(f.n),
+		ThermalState:   "nominal",
+		Timestamp:      time.Unix(int64(f.n), 0),
+	}
+}
+
+func TestObserverHistoryAccumulates(t *testing.T) {
+	f := &fakeSampler{}
+	o := NewObserver(time.Second, withSampler(f.sample))
+	for range 3 {
+		o.poll()
+	}
+
+	history := o.History(time.Time{})
+	if len(history) != 3 {
+		t.Fatalf("expected 3 samples, got %d", len(history))
+	}
+	for i, info := range history {
+		if info.AllocatedBytes != uint64(i+1) {
+			t.Errorf("sample %d: expected allocated %d, got %d", i, i+1, info.AllocatedBytes)
+		}
+	}
+	if got := o.Info().AllocatedBytes; got != 3 {
+		t.Errorf("expected Info to be the latest sample, got allocated %d", got)
+	}
+
+	since := o.History(time.Unix(1, 0))
+	if len(since) != 2 || since[0].AllocatedBytes != 2 {
+		t.Errorf("expected samples after the first, got %+v", since)
+	}
+}
+
+func TestObserverHistoryBounded(t *testing.T) {
+	f := &fakeSampler{}
+	// 5s of history at a 1s interval keeps 5 samples
+	o := NewObserver(time.Second, WithHistory(5*time.Second), withSampler(f.sample))
+	for range 12 {
+		o.poll()
+	}
+
+	history := o.History(time.Time{})
+	if len(history) != 5 {
+		t.Fatalf("expected history bounded to 5 samples, got %d", len(history))
+	}
+	if first, last := history[0].AllocatedBytes, history[4].AllocatedBytes; first != 8 || last != 12 {
+		t.Errorf("expected the most recent samples 8..12, got %d..%d", first, last)
+	}
+}
+
+func TestObserverPollsInBackground(t *testing.T) {
+	f := &fakeSampler{}
+	o := NewObserver(10*time.Millisecond, withSampler(f.sample))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	o.Start(ctx)
+	defer o.Stop()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(o.History(time.Time{})) < 3 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected samples to accumulate while running")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
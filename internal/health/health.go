@@ -3,11 +3,17 @@ package health
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"math/rand"
 	"net"
 	"net/http"
+	"os"
 	"os/exec"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 )
@@ -23,18 +29,51 @@ const (
 
 // Config holds health check configuration, mapped from the spec.
 type Config struct {
-	Type               string        // "http" | "tcp" | "exec"
+	Type               string        // "http" | "tcp" | "exec" | "docker"
 	Path               string        // http only
 	Port               int           // http and tcp
+	UnixSocket         string        // http and tcp: dial this socket instead of Host and Port
 	Host               string        // target host (default "127.0.0.1")
 	Command            string        // exec only
 	Interval           time.Duration // time between checks
 	Timeout            time.Duration // max time per check
-	GracePeriod        time.Duration // delay before first check
+	InitialDelay       time.Duration // delay before the first check runs
+	GracePeriod        time.Duration // from start, checks run but don't change the status
 	UnhealthyThreshold int           // consecutive failures before unhealthy
+	HealthyThreshold   int           // consecutive successes before an unhealthy service recovers
+	Jitter             int           // randomize each interval by up to ± this percent
+	FlapThreshold      int           // healthy↔unhealthy transitions within FlapWindow that count as flapping
+	FlapWindow         time.Duration // window for FlapThreshold
+	Flaps              *Flaps        // transition history to record into (nil = one of the monitor's own)
 	RouteURL           string        // base URL for route health check (e.g. "https://chat.studio.internal")
+
+	// Method, Headers and Body shape the http check's request. An empty
+	// Method means GET.
+	Method  string
+	Headers map[string]string
+	Body    string
+
+	// DockerStatus reports the container's Docker HEALTHCHECK status
+	// ("starting", "healthy", "unhealthy"). docker only.
+	DockerStatus func(ctx context.Context) (string, error)
+
+	// AssumeHealthy starts the monitor healthy rather than unknown, for an
+	// instance that has already been verified or is already serving.
+	AssumeHealthy bool
+
+	// OnTransition, if set, is called when the status changes.
+	OnTransition func(from, to Status)
+
+	// OnUnhealthyCommand, if set, is run with sh -c on each transition to
+	// unhealthy, in the background. Service is passed to it in the env.
+	OnUnhealthyCommand string
+	Service            string
 }
 
+// onUnhealthyTimeout bounds an OnUnhealthyCommand run, long enough for
+// diagnostics like a heap dump.
+const onUnhealthyTimeout = 5 * time.Minute
+
 // Result is the outcome of a single health check.
 type Result struct {
 	Status  Status
@@ -51,20 +90,32 @@ type CheckRecord struct {
 
 const historySize = 50
 
+// Flap detection defaults: five healthy↔unhealthy transitions within ten
+// minutes mark a service as flapping.
+const (
+	defaultFlapThreshold = 5
+	defaultFlapWindow    = 10 * time.Minute
+)
+
 // Monitor runs periodic health checks and tracks state.
 type Monitor struct {
 	cfg        Config
 	logger     *slog.Logger
 	httpClient *http.Client
 
-	mu               sync.Mutex
-	status           Status
-	consecutiveFails int
-	cancel           context.CancelFunc
-	done             chan struct{}
-	history          []CheckRecord
-	historyIdx       int
-	historyFull      bool
+	mu                   sync.Mutex
+	status               Status
+	consecutiveFails     int
+	consecutiveSuccesses int
+	cancel               context.CancelFunc
+	done                 chan struct{}
+	history              []CheckRecord
+	historyIdx           int
+	historyFull          bool
+	warming              bool      // in the grace period, or past it with no conclusive check yet
+	graceUntil           time.Time // checks before this are recorded but not acted on
+
+	flaps *Flaps
 
 	// onUnhealthy is called when the service transitions to unhealthy.
 	onUnhealthy func()
@@ -75,16 +126,35 @@ func NewMonitor(cfg Config, logger *slog.Logger, onUnhealthy func()) *Monitor {
 	if cfg.UnhealthyThreshold <= 0 {
 		cfg.UnhealthyThreshold = 3
 	}
+	if cfg.HealthyThreshold <= 0 {
+		cfg.HealthyThreshold = 1
+	}
 	if cfg.Host == "" {
 		cfg.Host = "127.0.0.1"
 	}
+	if cfg.FlapThreshold <= 0 {
+		cfg.FlapThreshold = defaultFlapThreshold
+	}
+	if cfg.FlapWindow <= 0 {
+		cfg.FlapWindow = defaultFlapWindow
+	}
+	flaps := cfg.Flaps
+	if flaps == nil {
+		flaps = NewFlaps(cfg.FlapThreshold, cfg.FlapWindow)
+	}
+	status := StatusUnknown
+	if cfg.AssumeHealthy {
+		status = StatusHealthy
+		flaps.observe(status, time.Now())
+	}
 	return &Monitor{
 		cfg:         cfg,
 		logger:      logger,
-		httpClient:  &http.Client{Timeout: cfg.Timeout},
-		status:      StatusUnknown,
+		httpClient:  newHTTPClient(cfg),
+		status:      status,
 		onUnhealthy: onUnhealthy,
 		history:     make([]CheckRecord, historySize),
+		flaps:       flaps,
 	}
 }
 
@@ -94,6 +164,8 @@ func (m *Monitor) Start(ctx context.Context) {
 	m.mu.Lock()
 	m.cancel = cancel
 	m.done = make(chan struct{})
+	m.warming = m.cfg.GracePeriod > 0
+	m.graceUntil = time.Now().Add(m.cfg.GracePeriod)
 	m.mu.Unlock()
 
 	go m.run(ctx)
@@ -119,6 +191,15 @@ func (m *Monitor) CurrentStatus() Status {
 	return m.status
 }
 
+// Warming reports whether the service is still warming up: the monitor is in
+// its grace period, or past it without a check having passed yet. It clears on
+// the first passing check, or when the service is marked unhealthy.
+func (m *Monitor) Warming() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.warming
+}
+
 // History returns the recent health check records in chronological order (oldest first).
 func (m *Monitor) History() []CheckRecord {
 	m.mu.Lock()
@@ -137,6 +218,79 @@ func (m *Monitor) History() []CheckRecord {
 	return result
 }
 
+// Flapping reports whether the service has switched between healthy and
+// unhealthy at least FlapThreshold times within the last FlapWindow. It
+// clears by itself once the transitions age out of the window.
+func (m *Monitor) Flapping() bool {
+	return m.flaps.Flapping()
+}
+
+// Flaps is a service's history of healthy↔unhealthy transitions. A service
+// restarted for failing its health check gets a new Monitor, which starts
+// unknown; sharing one Flaps between them carries the history across the
+// restart and counts the new monitor's first result against the last
+// status the old one reached, so unhealthy→restart→healthy cycles add up.
+type Flaps struct {
+	mu        sync.Mutex
+	threshold int
+	window    time.Duration
+	last      Status // the latest known status, unknown before the first
+
+	// times is a ring of the last threshold transition times, oldest at
+	// idx once full
+	times []time.Time
+	idx   int
+	full  bool
+}
+
+// NewFlaps returns an empty transition history that counts threshold
+// transitions within window as flapping. Values <= 0 use the defaults
+// (five transitions in ten minutes).
+func NewFlaps(threshold int, window time.Duration) *Flaps {
+	if threshold <= 0 {
+		threshold = defaultFlapThreshold
+	}
+	if window <= 0 {
+		window = defaultFlapWindow
+	}
+	return &Flaps{threshold: threshold, window: window, last: StatusUnknown, times: make([]time.Time, threshold)}
+}
+
+// Flapping reports whether threshold transitions fall within the window.
+func (f *Flaps) Flapping() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.flappingLocked(time.Now())
+}
+
+func (f *Flaps) flappingLocked(now time.Time) bool {
+	if !f.full {
+		return false
+	}
+	return now.Sub(f.times[f.idx]) <= f.window
+}
+
+// observe notes that the service reached status s at t, recording a
+// transition if it differs from the latest known status, and reports
+// whether that made the service start flapping.
+func (f *Flaps) observe(s Status, t time.Time) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	last := f.last
+	f.last = s
+	if last == StatusUnknown || last == s {
+		return false
+	}
+	was := f.flappingLocked(t)
+	f.times[f.idx] = t
+	f.idx++
+	if f.idx >= len(f.times) {
+		f.idx = 0
+		f.full = true
+	}
+	return !was && f.flappingLocked(t)
+}
+
 func (m *Monitor) recordCheck(record CheckRecord) {
 	m.history[m.historyIdx] = record
 	m.historyIdx++
@@ -150,28 +304,47 @@ func (m *Monitor) run(ctx context.Context) {
 	defer func() {
 		m.mu.Lock()
 		m.cancel = nil
+		m.warming = false
 		close(m.done)
 		m.mu.Unlock()
 	}()
 
-	// Grace period
-	if m.cfg.GracePeriod > 0 {
+	if m.cfg.InitialDelay > 0 {
 		select {
-		case <-time.After(m.cfg.GracePeriod):
+		case <-time.After(m.cfg.InitialDelay):
 		case <-ctx.Done():
 			return
 		}
 	}
 
-	ticker := time.NewTicker(m.cfg.Interval)
-	defer ticker.Stop()
+	// A timer rather than a ticker so each wait can be jittered; it is reset
+	// before the check so a slow check doesn't stretch the interval
+	timer := time.NewTimer(jittered(m.cfg.Interval, m.cfg.Jitter, rand.Float64()))
+	defer timer.Stop()
+
+	// Check again as soon as the grace period ends rather than up to an
+	// interval later, so a healthy service is marked healthy promptly
+	m.mu.Lock()
+	graceLeft := time.Until(m.graceUntil)
+	m.mu.Unlock()
+	var graceEnd <-chan time.Time
+	if graceLeft > 0 {
+		graceTimer := time.NewTimer(graceLeft)
+		defer graceTimer.Stop()
+		graceEnd = graceTimer.C
+	}
 
 	// Run first check immediately
 	m.check(ctx)
 
 	for {
 		select {
-		case <-ticker.C:
+		case <-timer.C:
+			timer.Reset(jittered(m.cfg.Interval, m.cfg.Jitter, rand.Float64()))
+			m.check(ctx)
+		case <-graceEnd:
+			graceEnd = nil
+			timer.Reset(jittered(m.cfg.Interval, m.cfg.Jitter, rand.Float64()))
 			m.check(ctx)
 		case <-ctx.Done():
 			return
@@ -179,6 +352,16 @@ func (m *Monitor) run(ctx context.Context) {
 	}
 }
 
+// jittered spreads d by up to ± percent, with r in [0, 1) picking where in
+// that range it lands, so monitors sharing an interval drift out of step.
+func jittered(d time.Duration, percent int, r float64) time.Duration {
+	if percent <= 0 {
+		return d
+	}
+	spread := float64(d) * float64(percent) / 100
+	return d + time.Duration(spread*(2*r-1))
+}
+
 func (m *Monitor) check(ctx context.Context) {
 	checkCtx, cancel := context.WithTimeout(ctx, m.cfg.Timeout)
 	defer cancel()
@@ -194,6 +377,8 @@ func (m *Monitor) check(ctx context.Context) {
 		err = m.checkTCP(checkCtx)
 	case "exec":
 		err = m.checkExec(checkCtx)
+	case "docker":
+		err = checkDocker(checkCtx, m.cfg)
 	default:
 		err = fmt.Errorf("unknown health check type: %s", m.cfg.Type)
 	}
@@ -204,6 +389,29 @@ func (m *Monitor) check(ctx context.Context) {
 		return
 	}
 
+	// Docker is still inside the image's start period — neither a pass
+	// nor a failure, so the status and failure count are left alone
+	if errors.Is(err, errDockerStarting) {
+		m.mu.Lock()
+		m.recordCheck(CheckRecord{Timestamp: start, Status: StatusUnknown, Latency: latency})
+		m.mu.Unlock()
+		return
+	}
+
+	// Within the grace period the result is only recorded: the status
+	// stays unknown and failures don't count towards the threshold
+	m.mu.Lock()
+	if time.Now().Before(m.graceUntil) {
+		record := CheckRecord{Timestamp: start, Status: StatusUnknown, Latency: latency}
+		if err != nil {
+			record.Error = err.Error()
+		}
+		m.recordCheck(record)
+		m.mu.Unlock()
+		return
+	}
+	m.mu.Unlock()
+
 	if err != nil {
 		result.Status = StatusUnhealthy
 		result.Message = err.Error()
@@ -227,18 +435,38 @@ func (m *Monitor) check(ctx context.Context) {
 
 	if result.Status == StatusHealthy {
 		m.consecutiveFails = 0
-		m.status = StatusHealthy
+		m.consecutiveSuccesses++
+		// An unhealthy service needs HealthyThreshold passes in a row to
+		// recover, so a flapping backend stays unhealthy
+		if m.status != StatusUnhealthy || m.consecutiveSuccesses >= m.cfg.HealthyThreshold {
+			m.status = StatusHealthy
+		}
 	} else {
+		m.consecutiveSuccesses = 0
 		m.consecutiveFails++
 		if m.consecutiveFails >= m.cfg.UnhealthyThreshold {
 			m.status = StatusUnhealthy
 		}
 	}
 
+	if m.status != StatusUnknown {
+		m.warming = false
+	}
 	newStatus := m.status
 	consecutiveFails := m.consecutiveFails
+	startedFlapping := false
+	if newStatus != StatusUnknown && newStatus != prevStatus {
+		startedFlapping = m.flaps.observe(newStatus, start)
+	}
 	m.mu.Unlock()
 
+	if startedFlapping {
+		m.logger.Warn("health is flapping",
+			"transitions", m.flaps.threshold,
+			"window", m.flaps.window,
+		)
+	}
+
 	if result.Status != StatusHealthy {
 		m.logger.Warn("health check failed",
 			"error", result.Message,
@@ -247,13 +475,40 @@ func (m *Monitor) check(ctx context.Context) {
 		)
 	}
 
+	if prevStatus != newStatus && m.cfg.OnTransition != nil {
+		m.cfg.OnTransition(prevStatus, newStatus)
+	}
+
 	// Fire callback on transition to unhealthy
 	if prevStatus != StatusUnhealthy && newStatus == StatusUnhealthy {
 		m.logger.Error("service is unhealthy", "consecutive_fails", consecutiveFails)
 		if m.onUnhealthy != nil {
 			m.onUnhealthy()
 		}
+		if m.cfg.OnUnhealthyCommand != "" {
+			go m.runOnUnhealthyCommand(consecutiveFails)
+		}
+	}
+}
+
+// runOnUnhealthyCommand runs the on_unhealthy command with the service name
+// and consecutive failure count in AURELIA_SERVICE and
+// AURELIA_CONSECUTIVE_FAILS. A failing command is only logged.
+func (m *Monitor) runOnUnhealthyCommand(consecutiveFails int) {
+	ctx, cancel := context.WithTimeout(context.Background(), onUnhealthyTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", m.cfg.OnUnhealthyCommand)
+	cmd.Env = append(os.Environ(),
+		"AURELIA_SERVICE="+m.cfg.Service,
+		"AURELIA_CONSECUTIVE_FAILS="+strconv.Itoa(consecutiveFails),
+	)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		m.logger.Warn("on_unhealthy command failed", "error", err, "output", strings.TrimSpace(string(out)))
+		return
 	}
+	m.logger.Info("on_unhealthy command ran")
 }
 
 // SingleCheck runs one health check with the given config and returns nil if healthy.
@@ -269,6 +524,8 @@ func SingleCheck(cfg Config) error {
 		return checkTCP(ctx, cfg)
 	case "exec":
 		return checkExec(ctx, cfg)
+	case "docker":
+		return checkDocker(ctx, cfg)
 	default:
 		return fmt.Errorf("unknown health check type: %s", cfg.Type)
 	}
@@ -280,13 +537,11 @@ func checkHTTP(ctx context.Context, cfg Config) error {
 	if host == "" {
 		host = "127.0.0.1"
 	}
-	url := fmt.Sprintf("http://%s:%d%s", host, cfg.Port, cfg.Path)
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	req, err := newHTTPRequest(ctx, cfg, checkURL(cfg, host))
 	if err != nil {
-		return fmt.Errorf("creating request: %w", err)
+		return err
 	}
-	client := &http.Client{Timeout: cfg.Timeout}
-	resp, err := client.Do(req)
+	resp, err := newHTTPClient(cfg).Do(req)
 	if err != nil {
 		return fmt.Errorf("request failed: %w", err)
 	}
@@ -297,17 +552,83 @@ func checkHTTP(ctx context.Context, cfg Config) error {
 	return nil
 }
 
+// checkURL returns the URL an http check requests on host. Over a Unix
+// socket the host part is only a placeholder.
+func checkURL(cfg Config, host string) string {
+	if cfg.UnixSocket != "" {
+		return "http://localhost" + cfg.Path
+	}
+	return "http://" + net.JoinHostPort(host, strconv.Itoa(cfg.Port)) + cfg.Path
+}
+
+// newHTTPClient returns the client for cfg's http checks, connecting to its
+// Unix socket when it has one.
+func newHTTPClient(cfg Config) *http.Client {
+	client := &http.Client{Timeout: cfg.Timeout}
+	if cfg.UnixSocket != "" {
+		client.Transport = &http.Transport{
+			DisableKeepAlives: true,
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", cfg.UnixSocket)
+			},
+		}
+	}
+	return client
+}
+
+// dialCheck connects to cfg's Unix socket if set, otherwise to host and
+// cfg.Port over TCP, for a tcp check.
+func dialCheck(ctx context.Context, cfg Config, host string) (net.Conn, error) {
+	dialer := net.Dialer{Timeout: cfg.Timeout}
+	if cfg.UnixSocket != "" {
+		conn, err := dialer.DialContext(ctx, "unix", cfg.UnixSocket)
+		if err != nil {
+			return nil, fmt.Errorf("unix socket connect failed: %w", err)
+		}
+		return conn, nil
+	}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(host, strconv.Itoa(cfg.Port)))
+	if err != nil {
+		return nil, fmt.Errorf("tcp connect failed: %w", err)
+	}
+	return conn, nil
+}
+
+// newHTTPRequest builds an http check's request to url with cfg's method,
+// headers and body, defaulting to a plain GET.
+func newHTTPRequest(ctx context.Context, cfg Config, url string) (*http.Request, error) {
+	method := cfg.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	var body io.Reader
+	if cfg.Body != "" {
+		body = strings.NewReader(cfg.Body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	for k, v := range cfg.Headers {
+		req.Header.Set(k, v)
+	}
+	// Host is a field of the request, not a header, so set it explicitly
+	if host := req.Header.Get("Host"); host != "" {
+		req.Host = host
+	}
+	return req, nil
+}
+
 // checkTCP performs a single TCP health check (standalone version).
 func checkTCP(ctx context.Context, cfg Config) error {
 	host := cfg.Host
 	if host == "" {
 		host = "127.0.0.1"
 	}
-	addr := fmt.Sprintf("%s:%d", host, cfg.Port)
-	dialer := net.Dialer{Timeout: cfg.Timeout}
-	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	conn, err := dialCheck(ctx, cfg, host)
 	if err != nil {
-		return fmt.Errorf("tcp connect failed: %w", err)
+		return err
 	}
 	conn.Close()
 	return nil
@@ -322,12 +643,33 @@ func checkExec(ctx context.Context, cfg Config) error {
 	return nil
 }
 
-func (m *Monitor) checkHTTP(ctx context.Context) error {
-	url := fmt.Sprintf("http://%s:%d%s", m.cfg.Host, m.cfg.Port, m.cfg.Path)
+// errDockerStarting is returned while the container's HEALTHCHECK is still
+// in its start period.
+var errDockerStarting = errors.New("container health is starting")
 
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+// checkDocker delegates to the container's own HEALTHCHECK.
+func checkDocker(ctx context.Context, cfg Config) error {
+	if cfg.DockerStatus == nil {
+		return fmt.Errorf("no container to inspect")
+	}
+	status, err := cfg.DockerStatus(ctx)
+	if err != nil {
+		return err
+	}
+	switch status {
+	case "healthy":
+		return nil
+	case "starting":
+		return errDockerStarting
+	default:
+		return fmt.Errorf("container health is %s", status)
+	}
+}
+
+func (m *Monitor) checkHTTP(ctx context.Context) error {
+	req, err := newHTTPRequest(ctx, m.cfg, checkURL(m.cfg, m.cfg.Host))
 	if err != nil {
-		return fmt.Errorf("creating request: %w", err)
+		return err
 	}
 
 	resp, err := m.httpClient.Do(req)
@@ -352,10 +694,11 @@ func (m *Monitor) checkHTTP(ctx context.Context) error {
 func (m *Monitor) checkRoute(ctx context.Context) error {
 	url := m.cfg.RouteURL + m.cfg.Path
 
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	req, err := newHTTPRequest(ctx, m.cfg, url)
 	if err != nil {
-		return fmt.Errorf("creating request: %w", err)
+		return err
 	}
+	req.Host = "" // the route is found by its own hostname, not the service's Host header
 
 	client := &http.Client{
 		Timeout: m.cfg.Timeout,
@@ -378,11 +721,9 @@ func (m *Monitor) checkRoute(ctx context.Context) error {
 }
 
 func (m *Monitor) checkTCP(ctx context.Context) error {
-	addr := fmt.Sprintf("%s:%d", m.cfg.Host, m.cfg.Port)
-	dialer := net.Dialer{Timeout: m.cfg.Timeout}
-	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	conn, err := dialCheck(ctx, m.cfg, m.cfg.Host)
 	if err != nil {
-		return fmt.Errorf("tcp connect failed: %w", err)
+		return err
 	}
 	conn.Close()
 	return nil
@@ -3,10 +3,17 @@ package health
 import (
 	"context"
 	"fmt"
+	"io"
 	"log/slog"
 	"net"
 	"net/http"
+	"net/http/httptest"
 	neturl "net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -273,6 +280,93 @@ func TestGracePeriod(t *testing.T) {
 	}
 }
 
+func TestWarmingUntilFirstPassingCheck(t *testing.T) {
+	cfg := Config{
+		Type:               "exec",
+		Command:            "true",
+		Interval:           50 * time.Millisecond,
+		Timeout:            2 * time.Second,
+		GracePeriod:        200 * time.Millisecond,
+		UnhealthyThreshold: 3,
+	}
+
+	m := NewMonitor(cfg, testLogger(), nil)
+	if m.Warming() {
+		t.Error("expected not warming before start")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	m.Start(ctx)
+	defer m.Stop()
+
+	time.Sleep(100 * time.Millisecond)
+	if !m.Warming() {
+		t.Error("expected warming during grace period")
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	if m.Warming() {
+		t.Error("expected warming to clear after the first passing check")
+	}
+}
+
+func TestWarmingClearsWhenUnhealthy(t *testing.T) {
+	cfg := Config{
+		Type:               "exec",
+		Command:            "false",
+		Interval:           20 * time.Millisecond,
+		Timeout:            2 * time.Second,
+		GracePeriod:        50 * time.Millisecond,
+		UnhealthyThreshold: 2,
+	}
+
+	m := NewMonitor(cfg, testLogger(), nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	m.Start(ctx)
+	defer m.Stop()
+
+	time.Sleep(200 * time.Millisecond)
+	if m.CurrentStatus() != StatusUnhealthy {
+		t.Fatalf("expected unhealthy, got %v", m.CurrentStatus())
+	}
+	if m.Warming() {
+		t.Error("expected warming to clear once unhealthy")
+	}
+}
+
+func TestAssumeHealthyStartsHealthy(t *testing.T) {
+	cfg := Config{
+		Type:               "exec",
+		Command:            "false",
+		Interval:           20 * time.Millisecond,
+		Timeout:            2 * time.Second,
+		UnhealthyThreshold: 2,
+		AssumeHealthy:      true,
+	}
+
+	m := NewMonitor(cfg, testLogger(), nil)
+	if m.CurrentStatus() != StatusHealthy {
+		t.Fatalf("expected healthy before any check, got %v", m.CurrentStatus())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	m.Start(ctx)
+	defer m.Stop()
+
+	// Failing checks still take it down
+	time.Sleep(200 * time.Millisecond)
+	if m.CurrentStatus() != StatusUnhealthy {
+		t.Errorf("expected unhealthy after failing checks, got %v", m.CurrentStatus())
+	}
+}
+
 func TestUnhealthyThreshold(t *testing.T) {
 	// Server that fails after 2 successful checks
 	var checkCount atomic.Int32
@@ -382,6 +476,45 @@ func TestRecoveryFromUnhealthy(t *testing.T) {
 	}
 }
 
+func TestOnTransition(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "healthy")
+
+	var mu sync.Mutex
+	var transitions []string
+	cfg := Config{
+		Type:               "exec",
+		Command:            "test -f " + marker,
+		Interval:           50 * time.Millisecond,
+		Timeout:            2 * time.Second,
+		UnhealthyThreshold: 1,
+		OnTransition: func(from, to Status) {
+			mu.Lock()
+			transitions = append(transitions, string(from)+"->"+string(to))
+			mu.Unlock()
+		},
+	}
+
+	m := NewMonitor(cfg, testLogger(), nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	m.Start(ctx)
+	time.Sleep(150 * time.Millisecond)
+	if err := os.WriteFile(marker, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(150 * time.Millisecond)
+	m.Stop()
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{"unknown->unhealthy", "unhealthy->healthy"}
+	if !slices.Equal(transitions, want) {
+		t.Errorf("transitions = %v, want %v", transitions, want)
+	}
+}
+
 func TestRouteCheckFailureMarksUnhealthy(t *testing.T) {
 	// Direct check server — always healthy
 	directMux := http.NewServeMux()
@@ -574,6 +707,128 @@ func TestSingleCheckHTTPUnhealthy(t *testing.T) {
 	}
 }
 
+func TestHTTPHealthCheckMethodHeadersBody(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Method != http.MethodPost || r.Header.Get("Authorization") != "Bearer s3cret" ||
+			r.Header.Get("Accept") != "application/json" || string(body) != `{"probe":true}` {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.WriteHeader(200)
+	})
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	srv := &http.Server{Handler: mux}
+	go srv.Serve(listener)
+	defer srv.Close()
+
+	configured := Config{
+		Type:    "http",
+		Path:    "/health",
+		Port:    port,
+		Timeout: 2 * time.Second,
+		Method:  http.MethodPost,
+		Headers: map[string]string{"Authorization": "Bearer s3cret", "Accept": "application/json"},
+		Body:    `{"probe":true}`,
+	}
+	if err := SingleCheck(configured); err != nil {
+		t.Errorf("expected healthy with method, headers and body set, got error: %v", err)
+	}
+
+	wrong := map[string]func(*Config){
+		"default GET":   func(c *Config) { c.Method = "" },
+		"missing token": func(c *Config) { c.Headers = map[string]string{"Accept": "application/json"} },
+		"wrong body":    func(c *Config) { c.Body = "" },
+	}
+	for name, mutate := range wrong {
+		cfg := configured
+		mutate(&cfg)
+		if err := SingleCheck(cfg); err == nil {
+			t.Errorf("%s: expected check to fail", name)
+		}
+	}
+
+	// The monitor sends the same request
+	cfg := configured
+	cfg.Interval = 50 * time.Millisecond
+	cfg.UnhealthyThreshold = 1
+	m := NewMonitor(cfg, testLogger(), nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	m.Start(ctx)
+	time.Sleep(200 * time.Millisecond)
+	m.Stop()
+	if m.CurrentStatus() != StatusHealthy {
+		t.Errorf("expected monitor healthy, got %v", m.CurrentStatus())
+	}
+
+	cfg.Headers = nil
+	m = NewMonitor(cfg, testLogger(), nil)
+	m.Start(ctx)
+	time.Sleep(200 * time.Millisecond)
+	m.Stop()
+	if m.CurrentStatus() != StatusUnhealthy {
+		t.Errorf("expected monitor unhealthy without headers, got %v", m.CurrentStatus())
+	}
+}
+
+func TestUnixSocketHealthCheck(t *testing.T) {
+	dir, err := os.MkdirTemp("", "health")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	sock := filepath.Join(dir, "api.sock") // short enough for a unix socket
+
+	ln, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/health" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(200)
+	}))
+	srv.Listener = ln
+	srv.Start()
+	defer srv.Close()
+
+	for _, typ := range []string{"http", "tcp"} {
+		cfg := Config{Type: typ, Path: "/health", UnixSocket: sock, Timeout: 2 * time.Second}
+		if err := SingleCheck(cfg); err != nil {
+			t.Errorf("%s over unix socket: expected healthy, got error: %v", typ, err)
+		}
+
+		cfg.Interval = 50 * time.Millisecond
+		m := NewMonitor(cfg, testLogger(), nil)
+		ctx, cancel := context.WithCancel(context.Background())
+		m.Start(ctx)
+		time.Sleep(200 * time.Millisecond)
+		m.Stop()
+		cancel()
+		if m.CurrentStatus() != StatusHealthy {
+			t.Errorf("%s over unix socket: expected monitor healthy, got %v", typ, m.CurrentStatus())
+		}
+	}
+
+	if err := SingleCheck(Config{Type: "http", Path: "/missing", UnixSocket: sock, Timeout: 2 * time.Second}); err == nil {
+		t.Error("expected error for a 404 over the unix socket")
+	}
+	missing := filepath.Join(dir, "gone.sock")
+	for _, typ := range []string{"http", "tcp"} {
+		if err := SingleCheck(Config{Type: typ, Path: "/health", UnixSocket: missing, Timeout: 2 * time.Second}); err == nil {
+			t.Errorf("%s: expected error for a socket nobody listens on", typ)
+		}
+	}
+}
+
 func TestSingleCheckTCP(t *testing.T) {
 	listener, err := net.Listen("tcp", "127.0.0.1:0")
 	if err != nil {
@@ -825,3 +1080,436 @@ func TestHistoryRingBufferCapacity(t *testing.T) {
 		}
 	}
 }
+
+func TestDockerHealthCheckFollowsContainerStatus(t *testing.T) {
+	var status atomic.Value
+	status.Store("starting")
+
+	cfg := Config{
+		Type:               "docker",
+		Interval:           30 * time.Millisecond,
+		Timeout:            time.Second,
+		UnhealthyThreshold: 2,
+		DockerStatus: func(ctx context.Context) (string, error) {
+			return status.Load().(string), nil
+		},
+	}
+
+	var unhealthy atomic.Int32
+	m := NewMonitor(cfg, testLogger(), func() { unhealthy.Add(1) })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	m.Start(ctx)
+	defer m.Stop()
+
+	// The start period is neither a pass nor a failure
+	time.Sleep(150 * time.Millisecond)
+	if got := m.CurrentStatus(); got != StatusUnknown {
+		t.Errorf("expected unknown while starting, got %v", got)
+	}
+
+	status.Store("healthy")
+	time.Sleep(100 * time.Millisecond)
+	if got := m.CurrentStatus(); got != StatusHealthy {
+		t.Errorf("expected healthy, got %v", got)
+	}
+
+	status.Store("unhealthy")
+	time.Sleep(150 * time.Millisecond)
+	if got := m.CurrentStatus(); got != StatusUnhealthy {
+		t.Errorf("expected unhealthy, got %v", got)
+	}
+	if unhealthy.Load() != 1 {
+		t.Errorf("expected one unhealthy callback, got %d", unhealthy.Load())
+	}
+}
+
+func TestSingleCheckDocker(t *testing.T) {
+	cfg := Config{Type: "docker", Timeout: time.Second}
+	if err := SingleCheck(cfg); err == nil {
+		t.Error("expected error without a container to inspect")
+	}
+
+	cfg.DockerStatus = func(ctx context.Context) (string, error) { return "healthy", nil }
+	if err := SingleCheck(cfg); err != nil {
+		t.Errorf("expected healthy, got %v", err)
+	}
+
+	cfg.DockerStatus = func(ctx context.Context) (string, error) { return "", fmt.Errorf("image defines no HEALTHCHECK") }
+	if err := SingleCheck(cfg); err == nil {
+		t.Error("expected inspect error to fail the check")
+	}
+}
+
+func TestOnUnhealthyCommandRunsOncePerTransition(t *testing.T) {
+	dir := t.TempDir()
+	marker := filepath.Join(dir, "healthy")
+	out := filepath.Join(dir, "runs")
+
+	cfg := Config{
+		Type:               "exec",
+		Command:            "test -f " + marker,
+		Interval:           30 * time.Millisecond,
+		Timeout:            2 * time.Second,
+		UnhealthyThreshold: 2,
+		OnUnhealthyCommand: `echo "$AURELIA_SERVICE $AURELIA_CONSECUTIVE_FAILS" >> ` + out,
+		Service:            "api",
+	}
+	m := NewMonitor(cfg, testLogger(), nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	m.Start(ctx)
+
+	runs := func() []string {
+		data, _ := os.ReadFile(out)
+		return strings.Fields(strings.ReplaceAll(string(data), " ", "_"))
+	}
+	waitFor := func(n int) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for len(runs()) < n && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	// Many failing checks past the threshold still run the command once
+	waitFor(1)
+	time.Sleep(200 * time.Millisecond)
+	if got := runs(); !slices.Equal(got, []string{"api_2"}) {
+		t.Fatalf("after first unhealthy transition, runs = %v, want [api_2]", got)
+	}
+
+	// Recover, then fail again: one more run
+	if err := os.WriteFile(marker, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for m.CurrentStatus() != StatusHealthy && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := os.Remove(marker); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(2)
+	time.Sleep(200 * time.Millisecond)
+	m.Stop()
+
+	if got := runs(); !slices.Equal(got, []string{"api_2", "api_2"}) {
+		t.Errorf("after second unhealthy transition, runs = %v, want [api_2 api_2]", got)
+	}
+}
+
+func TestOnUnhealthyCommandFailureIsIgnored(t *testing.T) {
+	cfg := Config{
+		Type:               "exec",
+		Command:            "false",
+		Interval:           30 * time.Millisecond,
+		Timeout:            2 * time.Second,
+		UnhealthyThreshold: 1,
+		OnUnhealthyCommand: "exit 3",
+	}
+	var restarts atomic.Int32
+	m := NewMonitor(cfg, testLogger(), func() { restarts.Add(1) })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	m.Start(ctx)
+	time.Sleep(150 * time.Millisecond)
+	m.Stop()
+
+	if m.CurrentStatus() != StatusUnhealthy || restarts.Load() != 1 {
+		t.Errorf("expected unhealthy with one callback despite the failing command, got %v and %d", m.CurrentStatus(), restarts.Load())
+	}
+}
+
+func TestHealthyThreshold(t *testing.T) {
+	// The backend fails until told to flap, then alternates pass/fail, then
+	// passes steadily
+	var mode atomic.Int32 // 0 failing, 1 flapping, 2 stable
+	var n atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		switch mode.Load() {
+		case 1:
+			if n.Add(1)%2 == 0 {
+				w.WriteHeader(500)
+				return
+			}
+		case 0:
+			w.WriteHeader(500)
+			return
+		}
+		w.WriteHeader(200)
+	})
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &http.Server{Handler: mux}
+	go srv.Serve(listener)
+	defer srv.Close()
+
+	var mu sync.Mutex
+	var transitions []string
+	var m *Monitor
+	streak := 0 // passes in a row when the monitor recovered
+	cfg := Config{
+		Type:               "http",
+		Path:               "/health",
+		Port:               listener.Addr().(*net.TCPAddr).Port,
+		Interval:           20 * time.Millisecond,
+		Timeout:            2 * time.Second,
+		UnhealthyThreshold: 1,
+		HealthyThreshold:   3,
+		OnTransition: func(from, to Status) {
+			mu.Lock()
+			transitions = append(transitions, string(from)+"->"+string(to))
+			mu.Unlock()
+			if to == StatusHealthy {
+				history := m.History()
+				for i := len(history) - 1; i >= 0 && history[i].Status == StatusHealthy; i-- {
+					streak++
+				}
+			}
+		},
+	}
+	m = NewMonitor(cfg, testLogger(), nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	m.Start(ctx)
+
+	time.Sleep(100 * time.Millisecond)
+	if m.CurrentStatus() != StatusUnhealthy {
+		t.Fatalf("expected unhealthy while failing, got %v", m.CurrentStatus())
+	}
+
+	// Alternating passes never reach three in a row
+	mode.Store(1)
+	time.Sleep(300 * time.Millisecond)
+	if m.CurrentStatus() != StatusUnhealthy {
+		t.Fatalf("expected to stay unhealthy while flapping, got %v", m.CurrentStatus())
+	}
+
+	mode.Store(2)
+	time.Sleep(300 * time.Millisecond)
+	m.Stop()
+
+	if m.CurrentStatus() != StatusHealthy {
+		t.Errorf("expected recovery once stable, got %v", m.CurrentStatus())
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{"unknown->unhealthy", "unhealthy->healthy"}
+	if !slices.Equal(transitions, want) {
+		t.Errorf("transitions = %v, want %v", transitions, want)
+	}
+	if streak != 3 {
+		t.Errorf("expected recovery on the third pass in a row, got %d", streak)
+	}
+}
+
+func TestFlapDetection(t *testing.T) {
+	var status atomic.Value
+	cfg := Config{
+		Type:               "docker",
+		Timeout:            time.Second,
+		UnhealthyThreshold: 1,
+		FlapThreshold:      4,
+		FlapWindow:         time.Minute,
+		DockerStatus: func(ctx context.Context) (string, error) {
+			return status.Load().(string), nil
+		},
+	}
+	m := NewMonitor(cfg, testLogger(), nil)
+	ctx := context.Background()
+
+	// The first result is a transition from unknown, which doesn't count
+	status.Store("healthy")
+	m.check(ctx)
+	for i, next := range []string{"unhealthy", "healthy", "unhealthy"} {
+		status.Store(next)
+		m.check(ctx)
+		if m.Flapping() {
+			t.Fatalf("expected no flapping after %d transitions", i+1)
+		}
+	}
+
+	// Repeated results aren't transitions
+	m.check(ctx)
+	if m.Flapping() {
+		t.Fatal("expected no flapping from a repeated result")
+	}
+
+	status.Store("healthy")
+	m.check(ctx)
+	if !m.Flapping() {
+		t.Fatalf("expected flapping after 4 transitions within the window, history %v", m.History())
+	}
+
+	// It clears once the transitions age out of the window
+	m.flaps.mu.Lock()
+	later := m.flaps.flappingLocked(time.Now().Add(2 * time.Minute))
+	m.flaps.mu.Unlock()
+	if later {
+		t.Error("expected flapping to clear after the window")
+	}
+}
+
+func TestFlapDetectionAcrossMonitors(t *testing.T) {
+	var status atomic.Value
+	flaps := NewFlaps(3, time.Minute)
+	newMonitor := func() *Monitor {
+		return NewMonitor(Config{
+			Type:               "docker",
+			Timeout:            time.Second,
+			UnhealthyThreshold: 1,
+			Flaps:              flaps,
+			DockerStatus: func(ctx context.Context) (string, error) {
+				return status.Load().(string), nil
+			},
+		}, testLogger(), nil)
+	}
+	ctx := context.Background()
+
+	// Each restart brings a new monitor that starts unknown; its first
+	// result is compared with the last status the previous one reached
+	for i := range 2 {
+		m := newMonitor()
+		status.Store("healthy")
+		m.check(ctx)
+		status.Store("unhealthy")
+		m.check(ctx)
+		if want := i == 1; m.Flapping() != want {
+			t.Fatalf("cycle %d: flapping = %v, want %v", i+1, m.Flapping(), want)
+		}
+	}
+}
+
+func TestJittered(t *testing.T) {
+	d := time.Second
+	if got := jittered(d, 0, 0.9); got != d {
+		t.Errorf("no jitter: got %v, want %v", got, d)
+	}
+	if got := jittered(d, 10, 0); got != 900*time.Millisecond {
+		t.Errorf("r=0: got %v, want 900ms", got)
+	}
+	if got := jittered(d, 10, 0.5); got != d {
+		t.Errorf("r=0.5: got %v, want %v", got, d)
+	}
+	for _, r := range []float64{0, 0.1, 0.5, 0.9, 0.999} {
+		if got := jittered(d, 20, r); got < 800*time.Millisecond || got > 1200*time.Millisecond {
+			t.Errorf("r=%v: %v outside ±20%%", r, got)
+		}
+	}
+}
+
+func TestJitterSpreadsIntervals(t *testing.T) {
+	cfg := Config{
+		Type:     "exec",
+		Command:  "true",
+		Interval: 50 * time.Millisecond,
+		Timeout:  2 * time.Second,
+		Jitter:   40,
+	}
+	m := NewMonitor(cfg, testLogger(), nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	m.Start(ctx)
+	time.Sleep(700 * time.Millisecond)
+	m.Stop()
+
+	history := m.History()
+	if len(history) < 6 {
+		t.Fatalf("expected several checks, got %d", len(history))
+	}
+	// ±40% of 50ms is 30–70ms; allow for scheduling delay on the upper side
+	lo, hi := time.Hour, time.Duration(0)
+	for i := 1; i < len(history); i++ {
+		gap := history[i].Timestamp.Sub(history[i-1].Timestamp)
+		if gap < 25*time.Millisecond || gap > 100*time.Millisecond {
+			t.Errorf("interval %d = %v, outside the jitter bound", i, gap)
+		}
+		lo, hi = min(lo, gap), max(hi, gap)
+	}
+	if hi-lo < 5*time.Millisecond {
+		t.Errorf("expected intervals to vary, all within %v..%v", lo, hi)
+	}
+}
+
+func TestInitialDelayAndGracePeriod(t *testing.T) {
+	var restarts atomic.Int32
+	cfg := Config{
+		Type:               "exec",
+		Command:            "false",
+		Interval:           20 * time.Millisecond,
+		Timeout:            2 * time.Second,
+		InitialDelay:       100 * time.Millisecond,
+		GracePeriod:        250 * time.Millisecond,
+		UnhealthyThreshold: 1,
+	}
+	m := NewMonitor(cfg, testLogger(), func() { restarts.Add(1) })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	m.Start(ctx)
+	defer m.Stop()
+
+	// Before initial_delay nothing runs
+	time.Sleep(60 * time.Millisecond)
+	if n := len(m.History()); n != 0 {
+		t.Fatalf("expected no checks before initial_delay, got %d", n)
+	}
+
+	// After initial_delay but within grace: failures are recorded as unknown
+	time.Sleep(120 * time.Millisecond)
+	history := m.History()
+	if len(history) == 0 {
+		t.Fatal("expected checks to run after initial_delay")
+	}
+	for _, rec := range history {
+		if rec.Status != StatusUnknown || rec.Error == "" {
+			t.Errorf("expected unknown with error during grace, got %+v", rec)
+		}
+	}
+	if m.CurrentStatus() != StatusUnknown || !m.Warming() || restarts.Load() != 0 {
+		t.Fatalf("expected unknown and warming with no callback during grace, got %v warming=%v callbacks=%d",
+			m.CurrentStatus(), m.Warming(), restarts.Load())
+	}
+
+	// Once grace ends the failures count
+	time.Sleep(150 * time.Millisecond)
+	if m.CurrentStatus() != StatusUnhealthy || restarts.Load() != 1 {
+		t.Errorf("expected unhealthy with one callback after grace, got %v and %d", m.CurrentStatus(), restarts.Load())
+	}
+}
+
+func TestGracePeriodChecksImmediately(t *testing.T) {
+	cfg := Config{
+		Type:        "exec",
+		Command:     "true",
+		Interval:    time.Hour,
+		Timeout:     2 * time.Second,
+		GracePeriod: 100 * time.Millisecond,
+	}
+	m := NewMonitor(cfg, testLogger(), nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	m.Start(ctx)
+	defer m.Stop()
+
+	// Without initial_delay the first check runs straight away, but the
+	// status waits for the check at the end of the grace period
+	time.Sleep(50 * time.Millisecond)
+	if n := len(m.History()); n != 1 || m.CurrentStatus() != StatusUnknown {
+		t.Fatalf("expected one uncounted check during grace, got %d checks, status %v", n, m.CurrentStatus())
+	}
+	time.Sleep(150 * time.Millisecond)
+	if m.CurrentStatus() != StatusHealthy {
+		t.Errorf("expected healthy after grace despite the long interval, got %v", m.CurrentStatus())
+	}
+}
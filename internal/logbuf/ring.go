@@ -3,31 +3,48 @@ package logbuf
 import (
 	"bytes"
 	"io"
+	"slices"
 	"strings"
 	"sync"
+	"time"
 )
 
 // DefaultMaxLineBytes is the default maximum size of a single log line in bytes.
 // Lines longer than this are truncated to prevent unbounded memory usage.
 const DefaultMaxLineBytes = 8192
 
+// watchBuffer is how many lines a watcher can fall behind by before further
+// lines are dropped for it.
+const watchBuffer = 256
+
+// Line is a log line and when it was written to the buffer.
+type Line struct {
+	Time time.Time `json:"ts"`
+	Text string    `json:"line"`
+}
+
 // Ring is a thread-safe ring buffer that stores the last N lines of output.
 // It implements io.Writer so it can be used as stdout/stderr for a process.
 type Ring struct {
 	mu           sync.Mutex
 	lines        []string
+	times        []time.Time // when each line in lines was written
+	now          func() time.Time
 	size         int
 	pos          int
 	full         bool
 	maxLineBytes int
 	// partial holds an incomplete line (no trailing newline yet)
-	partial bytes.Buffer
+	partial  bytes.Buffer
+	watchers []chan Line
 }
 
 // New creates a ring buffer that stores the last n lines.
 func New(n int) *Ring {
 	return &Ring{
 		lines:        make([]string, n),
+		times:        make([]time.Time, n),
+		now:          time.Now,
 		size:         n,
 		maxLineBytes: DefaultMaxLineBytes,
 	}
@@ -41,6 +58,8 @@ func NewWithMaxLineBytes(n int, maxBytes int) *Ring {
 	}
 	return &Ring{
 		lines:        make([]string, n),
+		times:        make([]time.Time, n),
+		now:          time.Now,
 		size:         n,
 		maxLineBytes: maxBytes,
 	}
@@ -61,8 +80,9 @@ func (r *Ring) Write(p []byte) (int, error) {
 			r.partial.WriteString(line)
 			break
 		}
-		// Store complete line (without trailing newline)
-		r.addLine(strings.TrimRight(line, "\n"))
+		// Store complete line (without trailing newline, or the CR of a
+		// terminal's CRLF)
+		r.addLine(strings.TrimRight(line, "\r\n"))
 	}
 
 	return len(p), nil
@@ -72,11 +92,36 @@ func (r *Ring) addLine(line string) {
 	if len(line) > r.maxLineBytes {
 		line = line[:r.maxLineBytes] + "... (truncated)"
 	}
+	now := r.now()
 	r.lines[r.pos] = line
+	r.times[r.pos] = now
 	r.pos = (r.pos + 1) % r.size
 	if r.pos == 0 {
 		r.full = true
 	}
+	for _, ch := range r.watchers {
+		select {
+		case ch <- Line{Time: now, Text: line}:
+		default: // slow watcher; drop rather than stall the process
+		}
+	}
+}
+
+// Watch subscribes to lines as they are written until cancel is called. A
+// watcher that falls behind misses lines rather than blocking writes.
+func (r *Ring) Watch() (lines <-chan Line, cancel func()) {
+	ch := make(chan Line, watchBuffer)
+
+	r.mu.Lock()
+	r.watchers = append(r.watchers, ch)
+	r.mu.Unlock()
+
+	cancel = func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.watchers = slices.DeleteFunc(r.watchers, func(c chan Line) bool { return c == ch })
+	}
+	return ch, cancel
 }
 
 // Lines returns all stored lines in order, oldest first.
@@ -105,6 +150,42 @@ func (r *Ring) Last(n int) []string {
 	return all[len(all)-n:]
 }
 
+// Query selects lines from the buffer. Zero fields don't filter.
+type Query struct {
+	Since time.Time              // only lines written at or after Since
+	Match func(line string) bool // only lines for which Match returns true
+	Limit int                    // only the last Limit matching lines
+}
+
+// Query returns the stored lines selected by q, oldest first.
+func (r *Ring) Query(q Query) []Line {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	count, start := r.pos, 0
+	if r.full {
+		count, start = r.size, r.pos
+	}
+
+	// Walk newest to oldest so Since and Limit can stop early
+	var result []Line
+	for i := count - 1; i >= 0; i-- {
+		idx := (start + i) % r.size
+		if !q.Since.IsZero() && r.times[idx].Before(q.Since) {
+			break
+		}
+		if q.Match != nil && !q.Match(r.lines[idx]) {
+			continue
+		}
+		result = append(result, Line{Time: r.times[idx], Text: r.lines[idx]})
+		if q.Limit > 0 && len(result) == q.Limit {
+			break
+		}
+	}
+	slices.Reverse(result)
+	return result
+}
+
 // Reader returns an io.Reader over the current buffer contents.
 func (r *Ring) Reader() io.Reader {
 	lines := r.Lines()
@@ -1,7 +1,10 @@
 package logbuf
 
 import (
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestRingBasicWrite(t *testing.T) {
@@ -48,6 +51,18 @@ func TestRingPartialWrites(t *testing.T) {
 	}
 }
 
+func TestRingStripsCRLF(t *testing.T) {
+	t.Parallel()
+	r := New(5)
+	// A TTY's line endings, split across writes
+	r.Write([]byte("first\r"))
+	r.Write([]byte("\nsecond\r\n"))
+
+	if lines := r.Lines(); !reflect.DeepEqual(lines, []string{"first", "second"}) {
+		t.Errorf("expected [first second], got %q", lines)
+	}
+}
+
 func TestRingLast(t *testing.T) {
 	t.Parallel()
 	r := New(10)
@@ -141,3 +156,84 @@ func TestRingTruncatesAtExactLimit(t *testing.T) {
 		t.Errorf("expected %q, got %q", expected, lines2[0])
 	}
 }
+
+func TestRingQuery(t *testing.T) {
+	t.Parallel()
+	base := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	clock := base
+	r := New(4)
+	r.now = func() time.Time { return clock }
+
+	// Overflow so the query has to walk a wrapped buffer
+	for i, line := range []string{"boot", "GET /a 200", "GET /b 500", "POST /c 200", "GET /d 200"} {
+		clock = base.Add(time.Duration(i) * time.Minute)
+		r.Write([]byte(line + "\n"))
+	}
+
+	if got := texts(r.Query(Query{})); !reflect.DeepEqual(got, []string{"GET /a 200", "GET /b 500", "POST /c 200", "GET /d 200"}) {
+		t.Errorf("unfiltered query = %v", got)
+	}
+
+	get := func(line string) bool { return strings.HasPrefix(line, "GET") }
+	if got := texts(r.Query(Query{Match: get})); !reflect.DeepEqual(got, []string{"GET /a 200", "GET /b 500", "GET /d 200"}) {
+		t.Errorf("match query = %v", got)
+	}
+	if got := texts(r.Query(Query{Match: get, Limit: 2})); !reflect.DeepEqual(got, []string{"GET /b 500", "GET /d 200"}) {
+		t.Errorf("limited match query = %v", got)
+	}
+
+	// Lines at exactly Since are included
+	if got := texts(r.Query(Query{Since: base.Add(3 * time.Minute)})); !reflect.DeepEqual(got, []string{"POST /c 200", "GET /d 200"}) {
+		t.Errorf("since query = %v", got)
+	}
+	if got := r.Query(Query{Since: base.Add(time.Hour)}); len(got) != 0 {
+		t.Errorf("expected nothing after the last write, got %v", got)
+	}
+
+	// Each line carries the time it was written
+	if got := r.Query(Query{Limit: 1}); len(got) != 1 || !got[0].Time.Equal(base.Add(4*time.Minute)) {
+		t.Errorf("expected the last line written at %v, got %v", base.Add(4*time.Minute), got)
+	}
+}
+
+func texts(lines []Line) []string {
+	result := make([]string, len(lines))
+	for i, l := range lines {
+		result[i] = l.Text
+	}
+	return result
+}
+
+func TestRingWatch(t *testing.T) {
+	t.Parallel()
+	r := New(5)
+	r.Write([]byte("before\n"))
+
+	lines, cancel := r.Watch()
+	r.Write([]byte("one\ntw"))
+	r.Write([]byte("o\n"))
+	for _, want := range []string{"one", "two"} {
+		if got := <-lines; got.Text != want || got.Time.IsZero() {
+			t.Errorf("got %+v, want %q with its time", got, want)
+		}
+	}
+
+	// A cancelled watcher gets nothing more
+	cancel()
+	r.Write([]byte("three\n"))
+	select {
+	case got := <-lines:
+		t.Errorf("got %q after cancel", got.Text)
+	default:
+	}
+
+	// A watcher that falls behind drops lines instead of blocking writes
+	lines, cancel = r.Watch()
+	defer cancel()
+	for i := 0; i < watchBuffer+10; i++ {
+		r.Write([]byte("x\n"))
+	}
+	if len(lines) != watchBuffer {
+		t.Errorf("expected %d buffered lines, got %d", watchBuffer, len(lines))
+	}
+}
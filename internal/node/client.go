@@ -11,6 +11,8 @@ import (
 	"net/http"
 	"strconv"
 	"time"
+
+	"github.com/benaskins/aurelia/internal/logbuf"
 )
 
 // Client talks to a remote aurelia daemon over its TCP API.
@@ -163,6 +165,21 @@ func (c *Client) DeployService(name string) error {
 	return c.post("/v1/services/" + name + "/deploy")
 }
 
+// RollbackService rolls a service back to its previous deploy on the remote daemon.
+func (c *Client) RollbackService(name string) (json.RawMessage, error) {
+	body, err := c.postReturnBody("/v1/services/" + name + "/rollback")
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("reading rollback result from %s: %w", c.Name, err)
+	}
+	return json.RawMessage(data), nil
+}
+
 // ReloadService triggers a spec reload on the remote daemon.
 func (c *Client) ReloadService() error {
 	return c.post("/v1/reload")
@@ -185,6 +202,24 @@ func (c *Client) Logs(name string, n int) ([]string, error) {
 	return resp.Lines, nil
 }
 
+// LogEntries returns the last n log lines for a service on the remote daemon
+// with the time each was written.
+func (c *Client) LogEntries(name string, n int) ([]logbuf.Line, error) {
+	body, err := c.get("/v1/services/" + name + "/logs?timestamps=true&n=" + strconv.Itoa(n))
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	var resp struct {
+		Entries []logbuf.Line `json:"entries"`
+	}
+	if err := json.NewDecoder(body).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("decoding logs from %s: %w", c.Name, err)
+	}
+	return resp.Entries, nil
+}
+
 // Ship triggers the fetch → build → deploy → notify pipeline on the remote daemon.
 func (c *Client) Ship(name string) (json.RawMessage, error) {
 	body, err := c.postReturnBody("/v1/services/" + name + "/ship")
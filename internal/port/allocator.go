@@ -5,6 +5,7 @@ import (
 	"math/rand"
 	"net"
 	"sync"
+	"time"
 )
 
 // Allocator manages dynamic port allocation for services.
@@ -14,18 +15,65 @@ type Allocator struct {
 	maxPort   int
 	allocated map[string]int // service name → port
 	usedPorts map[int]string // port → service name
+	excluded  map[int]bool   // ports in range that Allocate never hands out
+
+	// Sticky reservations: a released port is held for its previous owner
+	// for stickyWindow so a quick stop/start cycle gets the same port back.
+	stickyWindow time.Duration
+	sticky       map[string]stickyPort // service name → recently released port
+	now          func() time.Time
+}
+
+// stickyPort is a released port held for its previous owner until expires.
+type stickyPort struct {
+	port    int
+	expires time.Time
 }
 
 // NewAllocator creates a port allocator for the given range [min, max].
-func NewAllocator(minPort, maxPort int) *Allocator {
-	return &Allocator{
+// Excluded ports are never returned by Allocate, even when free; exclusions
+// outside the range are ignored.
+func NewAllocator(minPort, maxPort int, exclude ...int) *Allocator {
+	a := &Allocator{
 		minPort:   minPort,
 		maxPort:   maxPort,
 		allocated: make(map[string]int),
 		usedPorts: make(map[int]string),
+		excluded:  make(map[int]bool),
+		sticky:    make(map[string]stickyPort),
+		now:       time.Now,
+	}
+	for _, p := range exclude {
+		if p >= minPort && p <= maxPort {
+			a.excluded[p] = true
+		}
+	}
+	return a
+}
+
+// SetStickyWindow enables sticky re-allocation: after Release, the port is
+// held for the same service for window, and other services only receive it
+// when no other port in the range is free. A zero window disables it.
+func (a *Allocator) SetStickyWindow(window time.Duration) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.stickyWindow = window
+	if window <= 0 {
+		clear(a.sticky)
 	}
 }
 
+// Range returns the inclusive port range the allocator draws from.
+func (a *Allocator) Range() (minPort, maxPort int) {
+	return a.minPort, a.maxPort
+}
+
+// Capacity returns how many ports the allocator can hand out when none are in
+// use: the size of the range less its exclusions.
+func (a *Allocator) Capacity() int {
+	return a.maxPort - a.minPort + 1 - len(a.excluded)
+}
+
 // Allocate picks an available port for the named service.
 // Idempotent: returns the same port if already allocated.
 func (a *Allocator) Allocate(serviceName string) (int, error) {
@@ -36,8 +84,12 @@ func (a *Allocator) Allocate(serviceName string) (int, error) {
 		return port, nil
 	}
 
+	if port, ok := a.claimStickyLocked(serviceName); ok {
+		return port, nil
+	}
+
 	rangeSize := a.maxPort - a.minPort + 1
-	if len(a.usedPorts) >= rangeSize {
+	if a.unavailableLocked() >= rangeSize {
 		return 0, fmt.Errorf("port range exhausted (%d-%d)", a.minPort, a.maxPort)
 	}
 
@@ -51,7 +103,7 @@ func (a *Allocator) Allocate(serviceName string) (int, error) {
 	//    adding significant complexity for a rare edge case
 	for attempts := 0; attempts < rangeSize*2; attempts++ {
 		port := a.minPort + rand.Intn(rangeSize)
-		if _, taken := a.usedPorts[port]; taken {
+		if _, taken := a.usedPorts[port]; taken || a.excluded[port] || a.heldLocked(port) {
 			continue
 		}
 		if !isPortAvailable(port) {
@@ -62,20 +114,73 @@ func (a *Allocator) Allocate(serviceName string) (int, error) {
 		return port, nil
 	}
 
-	// Exhaustive scan as fallback
-	for port := a.minPort; port <= a.maxPort; port++ {
-		if _, taken := a.usedPorts[port]; taken {
-			continue
+	// Exhaustive scan as fallback. The second pass hands out ports held for
+	// another service's sticky reservation: the range is under pressure.
+	for _, allowHeld := range []bool{false, true} {
+		for port := a.minPort; port <= a.maxPort; port++ {
+			if _, taken := a.usedPorts[port]; taken || a.excluded[port] {
+				continue
+			}
+			if !allowHeld && a.heldLocked(port) {
+				continue
+			}
+			if !isPortAvailable(port) {
+				continue
+			}
+			a.allocated[serviceName] = port
+			a.usedPorts[port] = serviceName
+			return port, nil
 		}
-		if !isPortAvailable(port) {
+	}
+
+	return 0, fmt.Errorf("no available ports in range %d-%d", a.minPort, a.maxPort)
+}
+
+// claimStickyLocked re-grants a service's recently released port if the
+// reservation is still live and the port is free. Caller must hold a.mu.
+func (a *Allocator) claimStickyLocked(serviceName string) (int, bool) {
+	sp, ok := a.sticky[serviceName]
+	if !ok {
+		return 0, false
+	}
+	delete(a.sticky, serviceName)
+	if a.now().After(sp.expires) {
+		return 0, false
+	}
+	if _, taken := a.usedPorts[sp.port]; taken || a.excluded[sp.port] || !isPortAvailable(sp.port) {
+		return 0, false
+	}
+	a.allocated[serviceName] = sp.port
+	a.usedPorts[sp.port] = serviceName
+	return sp.port, true
+}
+
+// heldLocked reports whether port is under a live sticky reservation.
+// Expired reservations are pruned as they are found. Caller must hold a.mu.
+func (a *Allocator) heldLocked(port int) bool {
+	now := a.now()
+	for name, sp := range a.sticky {
+		if now.After(sp.expires) {
+			delete(a.sticky, name)
 			continue
 		}
-		a.allocated[serviceName] = port
-		a.usedPorts[port] = serviceName
-		return port, nil
+		if sp.port == port {
+			return true
+		}
 	}
+	return false
+}
 
-	return 0, fmt.Errorf("no available ports in range %d-%d", a.minPort, a.maxPort)
+// unavailableLocked counts in-range ports that Allocate cannot hand out:
+// excluded ports plus allocated ones. Caller must hold a.mu.
+func (a *Allocator) unavailableLocked() int {
+	n := len(a.excluded)
+	for port := range a.usedPorts {
+		if port >= a.minPort && port <= a.maxPort && !a.excluded[port] {
+			n++
+		}
+	}
+	return n
 }
 
 // Reserve restores a previously allocated port (e.g., from persisted state).
@@ -88,6 +193,7 @@ func (a *Allocator) Reserve(serviceName string, port int) error {
 		return fmt.Errorf("port %d already allocated to %q", port, existing)
 	}
 
+	delete(a.sticky, serviceName)
 	a.allocated[serviceName] = port
 	a.usedPorts[port] = serviceName
 	return nil
@@ -101,6 +207,9 @@ func (a *Allocator) Release(serviceName string) {
 	if port, ok := a.allocated[serviceName]; ok {
 		delete(a.usedPorts, port)
 		delete(a.allocated, serviceName)
+		if a.stickyWindow > 0 {
+			a.sticky[serviceName] = stickyPort{port: port, expires: a.now().Add(a.stickyWindow)}
+		}
 	}
 }
 
@@ -1,7 +1,10 @@
 package port
 
 import (
+	"fmt"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestAllocateInRange(t *testing.T) {
@@ -15,6 +18,16 @@ func TestAllocateInRange(t *testing.T) {
 	}
 }
 
+func TestCapacity(t *testing.T) {
+	if got := NewAllocator(20000, 20100).Capacity(); got != 101 {
+		t.Errorf("expected 101 ports, got %d", got)
+	}
+	// Exclusions outside the range don't count against it
+	if got := NewAllocator(20000, 20100, 20050, 20051, 30000).Capacity(); got != 99 {
+		t.Errorf("expected 99 ports after exclusions, got %d", got)
+	}
+}
+
 func TestAllocateIdempotent(t *testing.T) {
 	a := NewAllocator(20000, 20100)
 	p1, err := a.Allocate("svc")
@@ -201,3 +214,120 @@ func TestRangeExhaustion(t *testing.T) {
 		t.Error("expected error when range is exhausted")
 	}
 }
+
+func TestAllocateSkipsExcludedPorts(t *testing.T) {
+	a := NewAllocator(20000, 20004, 20002)
+	seen := make(map[int]bool)
+	for i := 0; i < 4; i++ {
+		port, err := a.Allocate(fmt.Sprintf("svc-%d", i))
+		if err != nil {
+			t.Fatalf("Allocate %d: %v", i, err)
+		}
+		if port == 20002 {
+			t.Fatalf("allocated excluded port %d", port)
+		}
+		seen[port] = true
+	}
+	if len(seen) != 4 {
+		t.Errorf("expected 4 distinct ports, got %v", seen)
+	}
+}
+
+func TestExhaustionAccountsForExclusions(t *testing.T) {
+	a := NewAllocator(20000, 20004, 20002)
+	for port := 20000; port <= 20004; port++ {
+		if port == 20002 {
+			continue
+		}
+		if err := a.Reserve(fmt.Sprintf("svc-%d", port), port); err != nil {
+			t.Fatalf("Reserve %d: %v", port, err)
+		}
+	}
+
+	// Only the excluded port is left free; it must not be handed out.
+	port, err := a.Allocate("extra")
+	if err == nil {
+		t.Fatalf("expected exhaustion error, got port %d", port)
+	}
+	if !strings.Contains(err.Error(), "exhausted") {
+		t.Errorf("expected exhaustion error, got %v", err)
+	}
+}
+
+func TestExclusionsOutsideRangeIgnored(t *testing.T) {
+	a := NewAllocator(20000, 20000, 19999, 20001)
+	port, err := a.Allocate("svc")
+	if err != nil {
+		t.Fatalf("Allocate: %v", err)
+	}
+	if port != 20000 {
+		t.Errorf("expected 20000, got %d", port)
+	}
+}
+
+func TestStickyReallocationReturnsSamePort(t *testing.T) {
+	a := NewAllocator(20000, 20100)
+	a.SetStickyWindow(time.Minute)
+
+	p1, err := a.Allocate("svc")
+	if err != nil {
+		t.Fatalf("Allocate: %v", err)
+	}
+	a.Release("svc")
+	p2, err := a.Allocate("svc")
+	if err != nil {
+		t.Fatalf("re-Allocate: %v", err)
+	}
+	if p1 != p2 {
+		t.Errorf("expected sticky port %d, got %d", p1, p2)
+	}
+}
+
+func TestStickyPortHeldFromOtherServices(t *testing.T) {
+	a := NewAllocator(20000, 20001)
+	a.SetStickyWindow(time.Minute)
+
+	held, _ := a.Allocate("svc-a")
+	a.Release("svc-a")
+
+	other, err := a.Allocate("svc-b")
+	if err != nil {
+		t.Fatalf("Allocate svc-b: %v", err)
+	}
+	if other == held {
+		t.Errorf("svc-b was given port %d held for svc-a", held)
+	}
+	if p, _ := a.Allocate("svc-a"); p != held {
+		t.Errorf("expected svc-a to get %d back, got %d", held, p)
+	}
+}
+
+func TestStickyReservationExpires(t *testing.T) {
+	a := NewAllocator(20000, 20001)
+	a.SetStickyWindow(time.Minute)
+	now := time.Now()
+	a.now = func() time.Time { return now }
+
+	held, _ := a.Allocate("svc-a")
+	a.Release("svc-a")
+	now = now.Add(2 * time.Minute)
+
+	// With the reservation expired, the port is no longer held for svc-a.
+	p1, _ := a.Allocate("svc-b")
+	p2, _ := a.Allocate("svc-c")
+	if p1 != held && p2 != held {
+		t.Errorf("expired sticky port %d was never reallocated (got %d, %d)", held, p1, p2)
+	}
+	if _, ok := a.sticky["svc-a"]; ok {
+		t.Error("expected expired reservation to be pruned")
+	}
+}
+
+func TestStickyDisabledByDefault(t *testing.T) {
+	a := NewAllocator(20000, 20001)
+	a.Allocate("svc-a")
+	a.Release("svc-a")
+	if len(a.sticky) != 0 {
+		t.Errorf("expected no sticky reservations without a window, got %v", a.sticky)
+	}
+}
@@ -1,10 +1,16 @@
 package routing
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"net"
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
+	"strconv"
+	"strings"
 	"sync"
 
 	"gopkg.in/yaml.v3"
@@ -12,29 +18,72 @@ import (
 
 var sanitizeRe = regexp.MustCompile(`[^a-zA-Z0-9-]`)
 
+// Default Traefik entrypoints for plain and TLS routes.
+const (
+	DefaultEntryPoint    = "web"
+	DefaultEntryPointTLS = "websecure"
+)
+
+// StdoutOutput is the output that writes the config to standard output
+// instead of a file.
+const StdoutOutput = "stdout"
+
 // TraefikGenerator writes Traefik dynamic config from service state.
 type TraefikGenerator struct {
-	outputPath string
-	mu         sync.Mutex
+	outputs       []string
+	stdout        io.Writer
+	entryPoint    string
+	entryPointTLS string
+	mu            sync.Mutex
 }
 
-// NewTraefikGenerator creates a generator that writes to the given path.
-func NewTraefikGenerator(outputPath string) *TraefikGenerator {
-	return &TraefikGenerator{outputPath: outputPath}
+// NewTraefikGenerator creates a generator that writes to each of the given
+// outputs: file paths, or StdoutOutput.
+func NewTraefikGenerator(outputs ...string) *TraefikGenerator {
+	return &TraefikGenerator{
+		outputs:       slices.Clone(outputs),
+		stdout:        os.Stdout,
+		entryPoint:    DefaultEntryPoint,
+		entryPointTLS: DefaultEntryPointTLS,
+	}
+}
+
+// SetEntryPoints overrides the entrypoints routes use when they don't name
+// their own. Empty values keep the current setting.
+func (g *TraefikGenerator) SetEntryPoints(plain, tls string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if plain != "" {
+		g.entryPoint = plain
+	}
+	if tls != "" {
+		g.entryPointTLS = tls
+	}
 }
 
 // ServiceRoute describes a running service that needs routing.
 type ServiceRoute struct {
-	Name       string
-	Hostname   string
-	Port       int
-	TLS        bool
-	TLSOptions string // e.g. "mtls" — references a TLS options block in Traefik's static config
-	Host       string // backend host (default "127.0.0.1" for local services)
+	Name        string   `json:"name"`
+	Hostname    string   `json:"hostname"`            // primary hostname
+	Hostnames   []string `json:"hostnames,omitempty"` // every hostname, primary first; defaults to Hostname
+	Port        int      `json:"port"`
+	Ports       []int    `json:"ports,omitempty"` // further backends behind Port, e.g. a service's other replicas
+	TLS         bool     `json:"tls"`
+	TLSOptions  string   `json:"tls_options,omitempty"`  // e.g. "mtls" — references a TLS options block in Traefik's static config
+	Host        string   `json:"host,omitempty"`         // backend host (default "127.0.0.1" for local services)
+	PathPrefix  string   `json:"path_prefix,omitempty"`  // e.g. "/api" — match only this path on Hostname
+	StripPrefix bool     `json:"strip_prefix,omitempty"` // remove PathPrefix before forwarding
+
+	Middlewares []string          `json:"middlewares,omitempty"` // references to middlewares defined elsewhere, e.g. "auth@file"
+	Headers     map[string]string `json:"headers,omitempty"`     // custom request headers sent to the backend
+
+	Protocol   string `json:"protocol,omitempty"`    // "http" (default), "tcp", or "udp"
+	EntryPoint string `json:"entry_point,omitempty"` // Traefik entrypoint, overriding the generator default
 }
 
-// Generate writes a Traefik dynamic config file for the given routes.
-// Services that are not running or have no routing config are excluded.
+// Generate writes a Traefik dynamic config file for the given routes to
+// every output. Services that are not running or have no routing config are
+// excluded. A failing output doesn't stop the others from being written.
 func (g *TraefikGenerator) Generate(routes []ServiceRoute) error {
 	g.mu.Lock()
 	defer g.mu.Unlock()
@@ -50,39 +99,102 @@ func (g *TraefikGenerator) Generate(routes []ServiceRoute) error {
 	out := []byte("# Auto-generated by aurelia — do not edit\n")
 	out = append(out, data...)
 
-	if err := os.MkdirAll(filepath.Dir(g.outputPath), 0700); err != nil {
-		return fmt.Errorf("creating output dir: %w", err)
+	var errs []error
+	for _, output := range g.outputs {
+		if output == StdoutOutput {
+			if _, err := g.stdout.Write(out); err != nil {
+				errs = append(errs, fmt.Errorf("writing traefik config to stdout: %w", err))
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(output), 0700); err != nil {
+			errs = append(errs, fmt.Errorf("creating output dir: %w", err))
+			continue
+		}
+		if err := writeAtomic(output, out); err != nil {
+			errs = append(errs, err)
+		}
 	}
+	return errors.Join(errs...)
+}
 
-	tmpPath := g.outputPath + ".tmp"
-	if err := os.WriteFile(tmpPath, out, 0600); err != nil {
+// writeAtomic replaces path with data by writing a temp file beside it and
+// renaming it into place, so Traefik's file watcher only ever sees the old
+// config or the complete new one, never a partial write. The temp file is
+// synced before the rename so a crash can't leave an empty config behind.
+func writeAtomic(path string, data []byte) error {
+	tmpPath := path + ".tmp"
+	f, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("writing traefik config: %w", err)
+	}
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmpPath)
 		return fmt.Errorf("writing traefik config: %w", err)
 	}
-	return os.Rename(tmpPath, g.outputPath)
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("replacing traefik config: %w", err)
+	}
+	return nil
 }
 
-// OutputPath returns the path where config is written.
-func (g *TraefikGenerator) OutputPath() string {
-	return g.outputPath
+// Outputs returns where config is written.
+func (g *TraefikGenerator) Outputs() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return slices.Clone(g.outputs)
+}
+
+// SetOutputs changes where the next Generate writes. Files at old paths are
+// left in place.
+func (g *TraefikGenerator) SetOutputs(outputs ...string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.outputs = slices.Clone(outputs)
 }
 
 // traefikConfig is the top-level Traefik dynamic config structure.
 type traefikConfig struct {
 	HTTP *traefikHTTP `yaml:"http,omitempty"`
+	TCP  *traefikTCP  `yaml:"tcp,omitempty"`
+	UDP  *traefikUDP  `yaml:"udp,omitempty"`
 }
 
 type traefikHTTP struct {
-	Routers  map[string]*traefikRouter  `yaml:"routers,omitempty"`
-	Services map[string]*traefikService `yaml:"services,omitempty"`
+	Routers     map[string]*traefikRouter     `yaml:"routers,omitempty"`
+	Services    map[string]*traefikService    `yaml:"services,omitempty"`
+	Middlewares map[string]*traefikMiddleware `yaml:"middlewares,omitempty"`
 }
 
 type traefikRouter struct {
 	Rule        string            `yaml:"rule"`
 	EntryPoints []string          `yaml:"entryPoints"`
+	Middlewares []string          `yaml:"middlewares,omitempty"`
 	Service     string            `yaml:"service"`
 	TLS         *traefikRouterTLS `yaml:"tls,omitempty"`
 }
 
+type traefikMiddleware struct {
+	StripPrefix *traefikStripPrefix `yaml:"stripPrefix,omitempty"`
+	Headers     *traefikHeaders     `yaml:"headers,omitempty"`
+}
+
+type traefikHeaders struct {
+	CustomRequestHeaders map[string]string `yaml:"customRequestHeaders"`
+}
+
+type traefikStripPrefix struct {
+	Prefixes []string `yaml:"prefixes"`
+}
+
 type traefikRouterTLS struct {
 	Options string `yaml:"options,omitempty"`
 }
@@ -99,6 +211,45 @@ type traefikServer struct {
 	URL string `yaml:"url"`
 }
 
+type traefikTCP struct {
+	Routers  map[string]*traefikTCPRouter `yaml:"routers,omitempty"`
+	Services map[string]*traefikL4Service `yaml:"services,omitempty"`
+}
+
+type traefikTCPRouter struct {
+	Rule        string               `yaml:"rule"`
+	EntryPoints []string             `yaml:"entryPoints"`
+	Service     string               `yaml:"service"`
+	TLS         *traefikTCPRouterTLS `yaml:"tls,omitempty"`
+}
+
+type traefikTCPRouterTLS struct {
+	Passthrough bool `yaml:"passthrough"`
+}
+
+type traefikUDP struct {
+	Routers  map[string]*traefikUDPRouter `yaml:"routers,omitempty"`
+	Services map[string]*traefikL4Service `yaml:"services,omitempty"`
+}
+
+type traefikUDPRouter struct {
+	EntryPoints []string `yaml:"entryPoints"`
+	Service     string   `yaml:"service"`
+}
+
+// traefikL4Service is a TCP or UDP service: servers are addresses, not URLs.
+type traefikL4Service struct {
+	LoadBalancer *traefikL4LoadBalancer `yaml:"loadBalancer"`
+}
+
+type traefikL4LoadBalancer struct {
+	Servers []traefikL4Server `yaml:"servers"`
+}
+
+type traefikL4Server struct {
+	Address string `yaml:"address"`
+}
+
 func (g *TraefikGenerator) buildConfig(routes []ServiceRoute) traefikConfig {
 	if len(routes) == 0 {
 		return traefikConfig{}
@@ -106,48 +257,161 @@ func (g *TraefikGenerator) buildConfig(routes []ServiceRoute) traefikConfig {
 
 	routers := make(map[string]*traefikRouter)
 	services := make(map[string]*traefikService)
+	middlewares := make(map[string]*traefikMiddleware)
+	var cfg traefikConfig
 
 	for _, r := range routes {
 		routerName := sanitizeName(r.Name)
 		serviceName := sanitizeName(r.Name)
 
+		switch r.Protocol {
+		case "tcp":
+			if cfg.TCP == nil {
+				cfg.TCP = &traefikTCP{
+					Routers:  make(map[string]*traefikTCPRouter),
+					Services: make(map[string]*traefikL4Service),
+				}
+			}
+			cfg.TCP.Routers[routerName], cfg.TCP.Services[serviceName] = g.buildTCPRoute(r, serviceName)
+			continue
+		case "udp":
+			if cfg.UDP == nil {
+				cfg.UDP = &traefikUDP{
+					Routers:  make(map[string]*traefikUDPRouter),
+					Services: make(map[string]*traefikL4Service),
+				}
+			}
+			cfg.UDP.Routers[routerName] = &traefikUDPRouter{
+				EntryPoints: []string{r.EntryPoint},
+				Service:     serviceName,
+			}
+			cfg.UDP.Services[serviceName] = l4Service(r)
+			continue
+		}
+
 		router := &traefikRouter{
-			Rule:    fmt.Sprintf("Host(`%s`)", r.Hostname),
+			Rule:    hostRule("Host", r.hostnames()),
 			Service: serviceName,
 		}
+		if r.PathPrefix != "" {
+			if len(r.hostnames()) > 1 {
+				router.Rule = "(" + router.Rule + ")"
+			}
+			router.Rule += fmt.Sprintf(" && PathPrefix(`%s`)", r.PathPrefix)
+			if r.StripPrefix {
+				mwName := routerName + "-strip-prefix"
+				middlewares[mwName] = &traefikMiddleware{
+					StripPrefix: &traefikStripPrefix{Prefixes: []string{r.PathPrefix}},
+				}
+				router.Middlewares = append(router.Middlewares, mwName)
+			}
+		}
+		if len(r.Headers) > 0 {
+			mwName := routerName + "-headers"
+			middlewares[mwName] = &traefikMiddleware{
+				Headers: &traefikHeaders{CustomRequestHeaders: r.Headers},
+			}
+			router.Middlewares = append(router.Middlewares, mwName)
+		}
+		router.Middlewares = append(router.Middlewares, r.Middlewares...)
 
+		entryPoint := g.entryPoint
 		if r.TLS {
-			router.EntryPoints = []string{"websecure"}
+			entryPoint = g.entryPointTLS
 			router.TLS = &traefikRouterTLS{}
 			if r.TLSOptions != "" {
 				router.TLS.Options = r.TLSOptions + "@file"
 			}
-		} else {
-			router.EntryPoints = []string{"web"}
 		}
+		if r.EntryPoint != "" {
+			entryPoint = r.EntryPoint
+		}
+		router.EntryPoints = []string{entryPoint}
 
 		routers[routerName] = router
 
-		scheme := "http"
-		host := r.Host
-		if host == "" {
-			host = "127.0.0.1"
+		var servers []traefikServer
+		for _, addr := range r.addrs() {
+			servers = append(servers, traefikServer{URL: "http://" + addr})
 		}
 		services[serviceName] = &traefikService{
-			LoadBalancer: &traefikLoadBalancer{
-				Servers: []traefikServer{
-					{URL: fmt.Sprintf("%s://%s:%d", scheme, host, r.Port)},
-				},
-			},
+			LoadBalancer: &traefikLoadBalancer{Servers: servers},
 		}
 	}
 
-	return traefikConfig{
-		HTTP: &traefikHTTP{
-			Routers:  routers,
-			Services: services,
-		},
+	if len(routers) > 0 {
+		cfg.HTTP = &traefikHTTP{
+			Routers:     routers,
+			Services:    services,
+			Middlewares: middlewares,
+		}
+	}
+	return cfg
+}
+
+// buildTCPRoute routes a TCP service. With a hostname, the router matches the
+// TLS SNI and passes the encrypted stream through to the service untouched;
+// without one it takes every connection on its entrypoint.
+func (g *TraefikGenerator) buildTCPRoute(r ServiceRoute, serviceName string) (*traefikTCPRouter, *traefikL4Service) {
+	router := &traefikTCPRouter{
+		Rule:    "HostSNI(`*`)",
+		Service: serviceName,
+	}
+	entryPoint := r.EntryPoint
+	if hosts := r.hostnames(); len(hosts) > 0 {
+		router.Rule = hostRule("HostSNI", hosts)
+		router.TLS = &traefikTCPRouterTLS{Passthrough: true}
+		if entryPoint == "" {
+			entryPoint = g.entryPointTLS
+		}
+	}
+	router.EntryPoints = []string{entryPoint}
+	return router, l4Service(r)
+}
+
+func l4Service(r ServiceRoute) *traefikL4Service {
+	var servers []traefikL4Server
+	for _, addr := range r.addrs() {
+		servers = append(servers, traefikL4Server{Address: addr})
+	}
+	return &traefikL4Service{
+		LoadBalancer: &traefikL4LoadBalancer{Servers: servers},
+	}
+}
+
+// addrs returns the host:port of each of the route's backends: Port, then
+// Ports.
+func (r ServiceRoute) addrs() []string {
+	host := r.Host
+	if host == "" {
+		host = "127.0.0.1"
+	}
+	addrs := make([]string, 0, 1+len(r.Ports))
+	for _, p := range append([]int{r.Port}, r.Ports...) {
+		addrs = append(addrs, net.JoinHostPort(host, strconv.Itoa(p)))
+	}
+	return addrs
+}
+
+// hostnames returns every hostname the route answers on.
+func (r ServiceRoute) hostnames() []string {
+	if len(r.Hostnames) > 0 {
+		return r.Hostnames
+	}
+	if r.Hostname != "" {
+		return []string{r.Hostname}
+	}
+	return nil
+}
+
+// hostRule builds a rule matching any of hosts with the given matcher,
+// e.g. "Host(`a`) || Host(`b`)".
+func hostRule(matcher string, hosts []string) string {
+	parts := make([]string, len(hosts))
+	for i, h := range hosts {
+		parts[i] = fmt.Sprintf("%s(`%s`)", matcher, h)
 	}
+	return strings.Join(parts, " || ")
 }
 
 // sanitizeName converts a service name to a Traefik-safe identifier.
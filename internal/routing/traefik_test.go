@@ -1,10 +1,15 @@
 package routing
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
 	"testing"
+
+	"gopkg.in/yaml.v3"
 )
 
 func TestGenerateEmpty(t *testing.T) {
@@ -110,6 +115,206 @@ func TestGenerateMTLSService(t *testing.T) {
 	}
 }
 
+func TestGeneratePathPrefixService(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dynamic.yaml")
+	g := NewTraefikGenerator(path)
+
+	routes := []ServiceRoute{
+		{Name: "api", Hostname: "app.example.local", Port: 8080, PathPrefix: "/api", StripPrefix: true},
+		{Name: "docs", Hostname: "app.example.local", Port: 8081, PathPrefix: "/docs"},
+	}
+
+	if err := g.Generate(routes); err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading output: %v", err)
+	}
+
+	content := string(data)
+	if !strings.Contains(content, "Host(`app.example.local`) && PathPrefix(`/api`)") {
+		t.Errorf("expected host and path prefix rule, got:\n%s", content)
+	}
+	if !strings.Contains(content, "Host(`app.example.local`) && PathPrefix(`/docs`)") {
+		t.Errorf("expected docs path prefix rule, got:\n%s", content)
+	}
+	if !strings.Contains(content, "api-strip-prefix") || !strings.Contains(content, "stripPrefix:") {
+		t.Errorf("expected strip prefix middleware for api, got:\n%s", content)
+	}
+	if strings.Contains(content, "docs-strip-prefix") {
+		t.Error("docs did not request strip_prefix, should have no middleware")
+	}
+}
+
+func TestGenerateMiddlewaresAndHeaders(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dynamic.yaml")
+	g := NewTraefikGenerator(path)
+
+	routes := []ServiceRoute{
+		{
+			Name:        "api",
+			Hostname:    "api.example.local",
+			Port:        8080,
+			Middlewares: []string{"auth@file"},
+			Headers:     map[string]string{"X-Served-By": "aurelia"},
+		},
+		{Name: "plain", Hostname: "plain.example.local", Port: 8081},
+	}
+
+	if err := g.Generate(routes); err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading output: %v", err)
+	}
+
+	var cfg traefikConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		t.Fatalf("parsing output: %v", err)
+	}
+
+	mw, ok := cfg.HTTP.Middlewares["api-headers"]
+	if !ok || mw.Headers == nil || mw.Headers.CustomRequestHeaders["X-Served-By"] != "aurelia" {
+		t.Errorf("expected api-headers middleware with custom header, got %+v", cfg.HTTP.Middlewares)
+	}
+	if got := cfg.HTTP.Routers["api"].Middlewares; !slices.Equal(got, []string{"api-headers", "auth@file"}) {
+		t.Errorf("expected router to reference headers and auth middlewares, got %v", got)
+	}
+	if got := cfg.HTTP.Routers["plain"].Middlewares; len(got) != 0 {
+		t.Errorf("expected no middlewares on plain router, got %v", got)
+	}
+}
+
+func TestGenerateTCPRoutes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dynamic.yaml")
+	g := NewTraefikGenerator(path)
+
+	routes := []ServiceRoute{
+		{Name: "postgres", Hostname: "db.example.local", Port: 5432, Protocol: "tcp"},
+		{Name: "redis", Port: 6379, Protocol: "tcp", EntryPoint: "redis"},
+		{Name: "dns", Port: 5353, Protocol: "udp", EntryPoint: "dns"},
+	}
+
+	if err := g.Generate(routes); err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading output: %v", err)
+	}
+
+	var cfg traefikConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		t.Fatalf("parsing output: %v", err)
+	}
+	if cfg.HTTP != nil {
+		t.Errorf("expected no http section for layer-4 routes, got %+v", cfg.HTTP)
+	}
+	if cfg.TCP == nil || cfg.UDP == nil {
+		t.Fatalf("expected tcp and udp sections:\n%s", data)
+	}
+
+	pg := cfg.TCP.Routers["postgres"]
+	if pg == nil || pg.Rule != "HostSNI(`db.example.local`)" || pg.TLS == nil || !pg.TLS.Passthrough {
+		t.Errorf("expected SNI passthrough router for postgres, got %+v", pg)
+	}
+	if pg != nil && !slices.Equal(pg.EntryPoints, []string{"websecure"}) {
+		t.Errorf("expected SNI router on websecure, got %v", pg.EntryPoints)
+	}
+	if addr := cfg.TCP.Services["postgres"].LoadBalancer.Servers[0].Address; addr != "127.0.0.1:5432" {
+		t.Errorf("expected postgres backend address, got %q", addr)
+	}
+
+	rd := cfg.TCP.Routers["redis"]
+	if rd == nil || rd.Rule != "HostSNI(`*`)" || rd.TLS != nil || !slices.Equal(rd.EntryPoints, []string{"redis"}) {
+		t.Errorf("expected catch-all router on the redis entrypoint, got %+v", rd)
+	}
+
+	dns := cfg.UDP.Routers["dns"]
+	if dns == nil || !slices.Equal(dns.EntryPoints, []string{"dns"}) || dns.Service != "dns" {
+		t.Errorf("expected udp router on the dns entrypoint, got %+v", dns)
+	}
+}
+
+func TestGenerateCustomEntryPoints(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dynamic.yaml")
+	g := NewTraefikGenerator(path)
+	g.SetEntryPoints("http", "https")
+
+	routes := []ServiceRoute{
+		{Name: "plain", Hostname: "plain.example.local", Port: 3000},
+		{Name: "secure", Hostname: "secure.example.local", Port: 3001, TLS: true},
+		{Name: "admin", Hostname: "admin.example.local", Port: 3002, TLS: true, EntryPoint: "internal"},
+		{Name: "db", Hostname: "db.example.local", Port: 5432, Protocol: "tcp"},
+	}
+
+	if err := g.Generate(routes); err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading output: %v", err)
+	}
+
+	var cfg traefikConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		t.Fatalf("parsing output: %v", err)
+	}
+
+	for name, want := range map[string]string{"plain": "http", "secure": "https", "admin": "internal"} {
+		if got := cfg.HTTP.Routers[name].EntryPoints; !slices.Equal(got, []string{want}) {
+			t.Errorf("%s: expected entrypoint %q, got %v", name, want, got)
+		}
+	}
+	if got := cfg.TCP.Routers["db"].EntryPoints; !slices.Equal(got, []string{"https"}) {
+		t.Errorf("expected SNI router on the TLS entrypoint, got %v", got)
+	}
+	if strings.Contains(string(data), "websecure") {
+		t.Error("default TLS entrypoint should not appear once overridden")
+	}
+}
+
+func TestGenerateMultipleHostnames(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dynamic.yaml")
+	g := NewTraefikGenerator(path)
+
+	routes := []ServiceRoute{
+		{Name: "site", Hostname: "example.com", Hostnames: []string{"example.com", "www.example.com"}, Port: 8080},
+		{Name: "api", Hostname: "a.example.com", Hostnames: []string{"a.example.com", "b.example.com"}, Port: 8081, PathPrefix: "/api"},
+		{Name: "db", Hostname: "db.example.com", Hostnames: []string{"db.example.com", "pg.example.com"}, Port: 5432, Protocol: "tcp"},
+	}
+
+	if err := g.Generate(routes); err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading output: %v", err)
+	}
+
+	var cfg traefikConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		t.Fatalf("parsing output: %v", err)
+	}
+
+	if got, want := cfg.HTTP.Routers["site"].Rule, "Host(`example.com`) || Host(`www.example.com`)"; got != want {
+		t.Errorf("site rule = %q, want %q", got, want)
+	}
+	if got, want := cfg.HTTP.Routers["api"].Rule, "(Host(`a.example.com`) || Host(`b.example.com`)) && PathPrefix(`/api`)"; got != want {
+		t.Errorf("api rule = %q, want %q", got, want)
+	}
+	if got, want := cfg.TCP.Routers["db"].Rule, "HostSNI(`db.example.com`) || HostSNI(`pg.example.com`)"; got != want {
+		t.Errorf("db rule = %q, want %q", got, want)
+	}
+}
+
 func TestGenerateMultipleServices(t *testing.T) {
 	path := filepath.Join(t.TempDir(), "dynamic.yaml")
 	g := NewTraefikGenerator(path)
@@ -180,6 +385,163 @@ func TestGenerateOverwritesPrevious(t *testing.T) {
 	}
 }
 
+func TestGenerateNeverLeavesPartialFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dynamic.yaml")
+	g := NewTraefikGenerator(path)
+
+	routes := func(n int) []ServiceRoute {
+		var rs []ServiceRoute
+		for i := range n {
+			name := fmt.Sprintf("svc%d", i)
+			rs = append(rs, ServiceRoute{Name: name, Hostname: name + ".example.local", Port: 8000 + i, TLS: true})
+		}
+		return rs
+	}
+
+	// check fails unless the file is a whole config: every router has its
+	// service and every service its server
+	check := func(data []byte) {
+		t.Helper()
+		if !strings.HasPrefix(string(data), "# Auto-generated by aurelia") {
+			t.Fatalf("config missing its header:\n%s", data)
+		}
+		var cfg traefikConfig
+		if err := yaml.Unmarshal(data, &cfg); err != nil {
+			t.Fatalf("config is not valid YAML: %v\n%s", err, data)
+		}
+		if cfg.HTTP == nil {
+			return
+		}
+		if len(cfg.HTTP.Routers) != len(cfg.HTTP.Services) {
+			t.Fatalf("%d routers but %d services:\n%s", len(cfg.HTTP.Routers), len(cfg.HTTP.Services), data)
+		}
+		for name, svc := range cfg.HTTP.Services {
+			if svc.LoadBalancer == nil || len(svc.LoadBalancer.Servers) != 1 || svc.LoadBalancer.Servers[0].URL == "" {
+				t.Fatalf("service %s is incomplete:\n%s", name, data)
+			}
+		}
+	}
+
+	if err := g.Generate(routes(1)); err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+
+	// Writers regenerate with varying route counts while the test reads
+	// the file as Traefik would
+	var wg sync.WaitGroup
+	for w := range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 50 {
+				if err := g.Generate(routes((w*50 + i) % 40)); err != nil {
+					t.Errorf("Generate: %v", err)
+					return
+				}
+			}
+		}()
+	}
+	done := make(chan struct{})
+	go func() { wg.Wait(); close(done) }()
+
+	for reading := true; reading; {
+		select {
+		case <-done:
+			reading = false
+		default:
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("reading output: %v", err)
+		}
+		check(data)
+	}
+
+	for i := range 20 {
+		if err := g.Generate(routes(i)); err != nil {
+			t.Fatalf("Generate: %v", err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("reading output: %v", err)
+		}
+		check(data)
+	}
+
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("expected no temp file left behind, stat err = %v", err)
+	}
+}
+
+func TestGenerateFailureRemovesTempFile(t *testing.T) {
+	// A directory at the output path makes the rename fail
+	path := filepath.Join(t.TempDir(), "dynamic.yaml")
+	if err := os.MkdirAll(filepath.Join(path, "occupied"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	g := NewTraefikGenerator(path)
+
+	if err := g.Generate([]ServiceRoute{{Name: "test", Hostname: "test.local", Port: 8080}}); err == nil {
+		t.Fatal("expected Generate to fail when the output path is a directory")
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("expected the temp file to be removed, stat err = %v", err)
+	}
+}
+
+func TestGenerateMultipleOutputs(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "a", "dynamic.yaml")
+	second := filepath.Join(dir, "b", "dynamic.yaml")
+	g := NewTraefikGenerator(first, second, StdoutOutput)
+	var stdout strings.Builder
+	g.stdout = &stdout
+
+	if err := g.Generate([]ServiceRoute{{Name: "test", Hostname: "test.local", Port: 8080}}); err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+
+	want, err := os.ReadFile(first)
+	if err != nil {
+		t.Fatalf("reading first output: %v", err)
+	}
+	if !strings.Contains(string(want), "test.local") {
+		t.Fatalf("expected route in output, got:\n%s", want)
+	}
+	got, err := os.ReadFile(second)
+	if err != nil {
+		t.Fatalf("reading second output: %v", err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("outputs differ:\n%s\nvs:\n%s", want, got)
+	}
+	if stdout.String() != string(want) {
+		t.Errorf("stdout differs from file output:\n%s", stdout.String())
+	}
+}
+
+func TestGenerateFailingOutputDoesNotBlockOthers(t *testing.T) {
+	dir := t.TempDir()
+	blocked := filepath.Join(dir, "blocked")
+	if err := os.MkdirAll(filepath.Join(blocked, "occupied"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "dynamic.yaml")
+	g := NewTraefikGenerator(blocked, path)
+
+	if err := g.Generate([]ServiceRoute{{Name: "test", Hostname: "test.local", Port: 8080}}); err == nil {
+		t.Fatal("expected Generate to report the failing output")
+	}
+	if data, err := os.ReadFile(path); err != nil || !strings.Contains(string(data), "test.local") {
+		t.Errorf("expected the other output written, got %q, %v", data, err)
+	}
+
+	g.SetOutputs(path)
+	if got := g.Outputs(); !slices.Equal(got, []string{path}) {
+		t.Errorf("Outputs() = %v, want [%s]", got, path)
+	}
+}
+
 func TestGenerateRemoteHostURL(t *testing.T) {
 	path := filepath.Join(t.TempDir(), "dynamic.yaml")
 	g := NewTraefikGenerator(path)
@@ -206,6 +568,28 @@ func TestGenerateRemoteHostURL(t *testing.T) {
 	}
 }
 
+func TestGenerateIPv6HostURL(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dynamic.yaml")
+	g := NewTraefikGenerator(path)
+
+	routes := []ServiceRoute{
+		{Name: "v6-svc", Hostname: "svc.example.local", Port: 8080, Host: "::1"},
+	}
+
+	if err := g.Generate(routes); err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading output: %v", err)
+	}
+
+	if content := string(data); !strings.Contains(content, "http://[::1]:8080") {
+		t.Errorf("expected bracketed IPv6 URL, got:\n%s", content)
+	}
+}
+
 func TestGenerateDefaultsToLocalhost(t *testing.T) {
 	path := filepath.Join(t.TempDir(), "dynamic.yaml")
 	g := NewTraefikGenerator(path)
@@ -229,6 +613,34 @@ func TestGenerateDefaultsToLocalhost(t *testing.T) {
 	}
 }
 
+func TestGenerateSeveralBackends(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dynamic.yaml")
+	g := NewTraefikGenerator(path)
+
+	routes := []ServiceRoute{
+		{Name: "api", Hostname: "api.example.local", Port: 8080, Ports: []int{8081}},
+		{Name: "db", Protocol: "tcp", EntryPoint: "postgres", Port: 5432, Ports: []int{5433}},
+	}
+	if err := g.Generate(routes); err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading output: %v", err)
+	}
+	var cfg traefikConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		t.Fatalf("parsing output: %v", err)
+	}
+	if got := cfg.HTTP.Services["api"].LoadBalancer.Servers; len(got) != 2 || got[0].URL != "http://127.0.0.1:8080" || got[1].URL != "http://127.0.0.1:8081" {
+		t.Errorf("expected both api backends, got %+v", got)
+	}
+	if got := cfg.TCP.Services["db"].LoadBalancer.Servers; len(got) != 2 || got[0].Address != "127.0.0.1:5432" || got[1].Address != "127.0.0.1:5433" {
+		t.Errorf("expected both db backends, got %+v", got)
+	}
+}
+
 func TestSanitizeName(t *testing.T) {
 	if sanitizeName("my_service") != "my-service" {
 		t.Errorf("expected underscores replaced with hyphens")
@@ -0,0 +1,94 @@
+package spec
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultsFile is the name of the optional file in a spec directory whose
+// values are merged under every service spec in that directory. It is not
+// itself a service spec.
+const DefaultsFile = "defaults.yaml"
+
+// IsDefaultsFile reports whether path names a spec directory's defaults file
+// (defaults.yaml or defaults.yml).
+func IsDefaultsFile(path string) bool {
+	base := filepath.Base(path)
+	return base == DefaultsFile || base == "defaults.yml"
+}
+
+// loadDefaults reads the defaults file from dir. It returns nil if there is
+// none. When strict, keys that match no spec field are an error.
+func loadDefaults(dir string, strict bool) (map[string]any, error) {
+	for _, name := range []string{DefaultsFile, "defaults.yml"} {
+		path := filepath.Join(dir, name)
+		data, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading defaults %s: %w", path, err)
+		}
+
+		if strict {
+			if err := checkKnownFields(data); err != nil {
+				return nil, fmt.Errorf("parsing defaults %s: %w", path, err)
+			}
+		}
+
+		var defaults map[string]any
+		if err := yaml.Unmarshal(data, &defaults); err != nil {
+			return nil, fmt.Errorf("parsing defaults %s: %w", path, err)
+		}
+		if svc, ok := defaults["service"].(map[string]any); ok {
+			if _, ok := svc["name"]; ok {
+				return nil, fmt.Errorf("defaults %s: service.name cannot have a default", path)
+			}
+		}
+		return defaults, nil
+	}
+	return nil, nil
+}
+
+// parseWithDefaults parses a service spec from a YAML document with defaults
+// merged underneath it: nested maps are merged key by key, while scalars and
+// lists in the spec replace the default outright.
+func parseWithDefaults(doc *yaml.Node, defaults map[string]any) (ServiceSpec, error) {
+	var spec ServiceSpec
+	if len(defaults) == 0 {
+		err := doc.Decode(&spec)
+		return spec, err
+	}
+
+	var own map[string]any
+	if err := doc.Decode(&own); err != nil {
+		return spec, err
+	}
+	merged, err := yaml.Marshal(mergeDefaults(defaults, own))
+	if err != nil {
+		return spec, err
+	}
+	err = yaml.Unmarshal(merged, &spec)
+	return spec, err
+}
+
+// mergeDefaults returns defaults overlaid with values. Neither input is modified.
+func mergeDefaults(defaults, values map[string]any) map[string]any {
+	out := make(map[string]any, len(defaults)+len(values))
+	for k, v := range defaults {
+		out[k] = v
+	}
+	for k, v := range values {
+		vm, vIsMap := v.(map[string]any)
+		dm, dIsMap := out[k].(map[string]any)
+		if vIsMap && dIsMap {
+			out[k] = mergeDefaults(dm, vm)
+			continue
+		}
+		out[k] = v
+	}
+	return out
+}
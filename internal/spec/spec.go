@@ -1,11 +1,17 @@
 package spec
 
 import (
+	"bytes"
 	"crypto/sha256"
+	"errors"
 	"fmt"
+	"io"
+	"net"
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
@@ -16,21 +22,39 @@ var (
 	serviceNameRe = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]{0,63}$`)
 	hostnameRe    = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9.-]*[a-zA-Z0-9])?$`)
 	networkModeRe = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]*$`)
+	pathPrefixRe  = regexp.MustCompile(`^/[a-zA-Z0-9._~%/-]*$`)
+	middlewareRe  = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]*(@[a-zA-Z0-9]+)?$`)
+	headerNameRe  = regexp.MustCompile("^[!#$%&'*+.^_`|~0-9a-zA-Z-]+$")
+	entryPointRe  = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_-]*$`)
+	volumeNameRe  = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]+$`)
+	secretFileRe  = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
 )
 
 // ServiceSpec is the top-level structure for a service definition.
 type ServiceSpec struct {
+	// Enabled false keeps the service defined but not running: the daemon
+	// loads it and reports it as disabled without starting it. Default true.
+	Enabled      *bool                `yaml:"enabled,omitempty"`
 	Service      Service              `yaml:"service"`
 	Network      *Network             `yaml:"network,omitempty"`
 	Routing      *Routing             `yaml:"routing,omitempty"`
 	Health       *HealthCheck         `yaml:"health,omitempty"`
 	Restart      *RestartPolicy       `yaml:"restart,omitempty"`
 	Hooks        *Hooks               `yaml:"hooks,omitempty"`
+	Lifecycle    *Lifecycle           `yaml:"lifecycle,omitempty"`
 	Env          map[string]string    `yaml:"env,omitempty"`
 	Secrets      map[string]SecretRef `yaml:"secrets,omitempty"`
 	Volumes      map[string]string    `yaml:"volumes,omitempty"`
+	Tmpfs        []Tmpfs              `yaml:"tmpfs,omitempty"`
 	Dependencies *Dependencies        `yaml:"dependencies,omitempty"`
 	Args         []string             `yaml:"args,omitempty"`
+	GPU          *GPU                 `yaml:"gpu,omitempty"`
+	Security     *Security            `yaml:"security,omitempty"`
+	Autoscale    *Autoscale           `yaml:"autoscale,omitempty"`
+
+	// DockerSecrets maps file names to secrets written, read-only, to
+	// /run/secrets/<name> inside the container (container only).
+	DockerSecrets map[string]SecretRef `yaml:"docker_secrets,omitempty"`
 }
 
 type Service struct {
@@ -42,6 +66,127 @@ type Service struct {
 	NetworkMode string  `yaml:"network_mode,omitempty"` // container only, default "host"
 	Privileged  bool    `yaml:"privileged,omitempty"`   // container only
 	Source      *Source `yaml:"source,omitempty"`       // optional: where to fetch and build
+
+	// LogTimestamps prefixes each captured log line with Docker's RFC 3339
+	// timestamp (container only).
+	LogTimestamps bool `yaml:"log_timestamps,omitempty"`
+
+	// TTY runs the container with a pseudo-terminal, and StdinOpen keeps its
+	// stdin open with nothing written to it, for programs that need a
+	// terminal or exit when stdin closes (container only).
+	TTY       bool `yaml:"tty,omitempty"`
+	StdinOpen bool `yaml:"stdin_open,omitempty"`
+
+	// Labels are Docker labels set on the container, alongside the
+	// managed-by and aurelia.service labels aurelia adds (container only).
+	Labels map[string]string `yaml:"labels,omitempty"`
+
+	// Priority is the nice value the process runs at, from -20 (highest) to
+	// 19 (lowest); 0 leaves it unchanged (native only).
+	Priority int `yaml:"priority,omitempty"`
+
+	// OOMScoreAdj biases the Linux OOM killer for the process, from -1000
+	// (never kill) to 1000 (kill first); 0 leaves it unchanged. Ignored on
+	// macOS (native only).
+	OOMScoreAdj int `yaml:"oom_score_adj,omitempty"`
+
+	// KillOrphans SIGKILLs processes still running after the service is
+	// stopped, such as children that double-forked out of its process
+	// group. Without it they are only reported (native only).
+	KillOrphans bool `yaml:"kill_orphans,omitempty"`
+
+	// Replicas runs this many instances of the service, each on its own
+	// dynamic port and all routed, with Traefik balancing across them.
+	// Zero or one runs a single instance (native and container only).
+	Replicas int `yaml:"replicas,omitempty"`
+}
+
+// validateReplicas checks that a service with replicas can run several
+// instances side by side: each needs its own dynamic port, and its health
+// check must reach that port rather than a shared one.
+func (s *ServiceSpec) validateReplicas() error {
+	if s.Service.Replicas < 0 {
+		return fmt.Errorf("service.replicas must not be negative")
+	}
+	if err := s.validateAutoscale(); err != nil {
+		return err
+	}
+	if s.MaxReplicas() <= 1 {
+		return nil
+	}
+	if s.Service.Type != "native" && s.Service.Type != "container" {
+		return fmt.Errorf("service.replicas is only valid for native and container services")
+	}
+	if !s.NeedsDynamicPort() {
+		return fmt.Errorf("service.replicas requires a dynamic port (network.port: 0)")
+	}
+	if h := s.Health; h != nil && (h.Port != 0 || h.UnixSocket != "") {
+		return fmt.Errorf("service.replicas can't be combined with health.port or health.unix_socket, which every replica would share")
+	}
+	if s.Restart != nil && s.Restart.Policy == "oneshot" {
+		return fmt.Errorf("service.replicas is not valid for oneshot services")
+	}
+	return nil
+}
+
+// Autoscale lets the daemon vary a service's replica count between Min and
+// Max, adding replicas while their average CPU or memory use is above
+// target and removing them while it is below.
+type Autoscale struct {
+	Min int `yaml:"min"`
+	Max int `yaml:"max"`
+
+	// TargetCPUPercent is the average CPU use per replica to aim for, as a
+	// percentage of one core, and TargetMemory the average resident
+	// memory. At least one is required; with both, the busier decides.
+	TargetCPUPercent float64  `yaml:"target_cpu_percent,omitempty"`
+	TargetMemory     ByteSize `yaml:"target_memory,omitempty"`
+
+	// Cooldown is the least time between two changes to the replica
+	// count, so a spike doesn't make it thrash. Default 1m.
+	Cooldown Duration `yaml:"cooldown,omitempty"`
+}
+
+// DefaultAutoscaleCooldown is the cooldown for an autoscale block without one.
+const DefaultAutoscaleCooldown = time.Minute
+
+// CooldownOrDefault returns the autoscale cooldown, or
+// DefaultAutoscaleCooldown if none is set.
+func (a *Autoscale) CooldownOrDefault() time.Duration {
+	if a.Cooldown.Duration > 0 {
+		return a.Cooldown.Duration
+	}
+	return DefaultAutoscaleCooldown
+}
+
+func (s *ServiceSpec) validateAutoscale() error {
+	a := s.Autoscale
+	if a == nil {
+		return nil
+	}
+	if s.Service.Type != "native" {
+		// Only native processes have a PID to sample.
+		return fmt.Errorf("autoscale is only valid for native services")
+	}
+	if a.Min < 1 {
+		return fmt.Errorf("autoscale.min must be at least 1")
+	}
+	if a.Max < a.Min {
+		return fmt.Errorf("autoscale.max (%d) must not be below autoscale.min (%d)", a.Max, a.Min)
+	}
+	if a.TargetCPUPercent < 0 {
+		return fmt.Errorf("autoscale.target_cpu_percent must not be negative")
+	}
+	if a.TargetCPUPercent == 0 && a.TargetMemory == 0 {
+		return fmt.Errorf("autoscale needs target_cpu_percent or target_memory")
+	}
+	if a.Cooldown.Duration < 0 {
+		return fmt.Errorf("autoscale.cooldown must not be negative")
+	}
+	if r := s.Service.Replicas; r != 0 && (r < a.Min || r > a.Max) {
+		return fmt.Errorf("service.replicas (%d) must be between autoscale.min and autoscale.max", r)
+	}
+	return nil
 }
 
 // Source describes where a service's source code lives and how to build it.
@@ -50,27 +195,106 @@ type Source struct {
 	Build string `yaml:"build" json:"build"` // shell command to build the binary
 }
 
+// Tmpfs is an in-memory scratch mount inside a container.
+type Tmpfs struct {
+	Path string `yaml:"path"`
+
+	// Size caps the mount, e.g. "64MB". Unset leaves Docker's default of
+	// half the host's memory.
+	Size ByteSize `yaml:"size,omitempty"`
+}
+
+// IsNamedVolume reports whether a volumes key names a Docker volume rather
+// than a host path to bind mount.
+func IsNamedVolume(source string) bool {
+	return !strings.HasPrefix(source, "/") && !strings.HasPrefix(source, ".") && !strings.HasPrefix(source, "~")
+}
+
 type Network struct {
 	Port int `yaml:"port"`
+
+	// ContainerPort is the port the service listens on inside its container,
+	// when it differs from the host port. Container services on a non-host
+	// network only; defaults to the host port.
+	ContainerPort int `yaml:"container_port,omitempty"`
+
+	// BindAddress is the IP address the service should listen on, passed to
+	// it as HOST and BIND_ADDRESS. Unset leaves the choice to the service.
+	BindAddress string `yaml:"bind_address,omitempty"`
+}
+
+// ReachableHost returns the address the service can be reached on: its bind
+// address, or "" (loopback) when unset or a wildcard such as 0.0.0.0.
+func (n *Network) ReachableHost() string {
+	if n == nil {
+		return ""
+	}
+	ip := net.ParseIP(n.BindAddress)
+	if ip == nil || ip.IsUnspecified() {
+		return ""
+	}
+	return ip.String()
 }
 
 type HealthCheck struct {
-	Type               string   `yaml:"type"` // "http" | "tcp" | "exec"
+	Type               string   `yaml:"type"` // "http" | "tcp" | "exec" | "docker"
 	Path               string   `yaml:"path,omitempty"`
 	Port               int      `yaml:"port,omitempty"`
-	Command            string   `yaml:"command,omitempty"` // exec only
+	Command            string   `yaml:"command,omitempty"`     // exec only
+	UnixSocket         string   `yaml:"unix_socket,omitempty"` // http and tcp: check over this socket instead of port
 	Interval           Duration `yaml:"interval"`
 	Timeout            Duration `yaml:"timeout"`
+	InitialDelay       Duration `yaml:"initial_delay,omitempty"`
 	GracePeriod        Duration `yaml:"grace_period,omitempty"`
 	UnhealthyThreshold int      `yaml:"unhealthy_threshold,omitempty"`
+	HealthyThreshold   int      `yaml:"healthy_threshold,omitempty"` // successes to recover from unhealthy, default 1
+	Jitter             int      `yaml:"jitter,omitempty"`            // ± percent to randomize each interval by
+	OnUnhealthy        string   `yaml:"on_unhealthy,omitempty"`      // run via sh -c on each transition to unhealthy
+	FlapThreshold      int      `yaml:"flap_threshold,omitempty"`    // transitions within flap_window that mark flapping, default 5
+	FlapWindow         Duration `yaml:"flap_window,omitempty"`       // default 10m
+
+	// Method, Headers and Body shape an http check's request, for endpoints
+	// that need e.g. a POST or an auth header. The default is a plain GET.
+	Method  string            `yaml:"method,omitempty"`
+	Headers map[string]string `yaml:"headers,omitempty"`
+	Body    string            `yaml:"body,omitempty"`
+
+	// MaxStartDuration fails a freshly started process that hasn't passed a
+	// check within it, as a failed start. Zero means no limit.
+	MaxStartDuration Duration `yaml:"max_start_duration,omitempty"`
+
+	// Startup replaces the check's pacing while waiting for a new instance
+	// to first become healthy, during deploys and dependency waits at
+	// daemon start.
+	Startup *StartupProbe `yaml:"startup,omitempty"`
+}
+
+// StartupProbe is how long and how often to check a starting instance before
+// giving up on it. It runs the health block's check; the regular interval and
+// thresholds take over once the instance is healthy.
+type StartupProbe struct {
+	Interval    Duration `yaml:"interval,omitempty"`     // default: health.interval
+	Timeout     Duration `yaml:"timeout,omitempty"`      // per check, default: health.timeout
+	Threshold   int      `yaml:"threshold,omitempty"`    // failed checks before giving up
+	MaxDuration Duration `yaml:"max_duration,omitempty"` // time before giving up
 }
 
 type RestartPolicy struct {
-	Policy      string   `yaml:"policy"` // "always" | "on-failure" | "never"
+	Policy      string   `yaml:"policy"` // "always" | "unless-stopped" | "on-failure" | "never" | "oneshot"
 	MaxAttempts int      `yaml:"max_attempts,omitempty"`
 	Delay       Duration `yaml:"delay,omitempty"`
 	Backoff     string   `yaml:"backoff,omitempty"` // "fixed" | "exponential"
 	MaxDelay    Duration `yaml:"max_delay,omitempty"`
+	Jitter      string   `yaml:"jitter,omitempty"` // "none" | "full" | "equal"
+
+	// InitialDelay, when set, replaces Delay for the first restart only, so a
+	// service can retry at once (0s) and back off from Delay after that.
+	InitialDelay *Duration `yaml:"initial_delay,omitempty"`
+
+	// MaxPerWindow caps restarts within any sliding Window. Exceeding it opens
+	// a circuit: the service is held failed for one Window before retrying.
+	MaxPerWindow int      `yaml:"max_per_window,omitempty"`
+	Window       Duration `yaml:"window,omitempty"` // default 1m
 }
 
 // SecretRef identifies a secret in the configured secrets backend.
@@ -89,9 +313,102 @@ func (r SecretRef) Key() string {
 }
 
 type Routing struct {
+	// Protocol is "http" (default), "tcp", or "udp". TCP routes with a
+	// hostname match on TLS SNI and pass the stream through; TCP routes
+	// without one, and all UDP routes, own their EntryPoint outright.
+	// EntryPoint otherwise overrides the daemon's plain or TLS entrypoint.
+	Protocol   string `yaml:"protocol,omitempty"`
+	EntryPoint string `yaml:"entry_point,omitempty"`
 	Hostname   string `yaml:"hostname"`
 	TLS        bool   `yaml:"tls,omitempty"`
 	TLSOptions string `yaml:"tls_options,omitempty"` // e.g. "mtls" for mTLS enforcement
+	// Hostnames lists further names the service answers on (e.g. apex and
+	// www). Hostname, if set, is the primary and comes first.
+	Hostnames []string `yaml:"hostnames,omitempty"`
+	// PathPrefix limits the route to requests under this path on Hostname,
+	// e.g. "/api". StripPrefix removes it before forwarding to the service.
+	PathPrefix  string `yaml:"path_prefix,omitempty"`
+	StripPrefix bool   `yaml:"strip_prefix,omitempty"`
+	// Middlewares references Traefik middlewares defined elsewhere, e.g.
+	// "auth@file". Headers are set on every request forwarded to the service.
+	Middlewares []string          `yaml:"middlewares,omitempty"`
+	Headers     map[string]string `yaml:"headers,omitempty"`
+	// RouteUnhealthy keeps the route while the service's health check is
+	// failing or hasn't passed yet. By default only healthy services are
+	// routed.
+	RouteUnhealthy bool `yaml:"route_unhealthy,omitempty"`
+	// UnhealthyGrace keeps the route for this long after the health check
+	// starts failing, so a brief blip doesn't drop traffic. Zero removes
+	// the route straight away.
+	UnhealthyGrace Duration `yaml:"unhealthy_grace,omitempty"`
+}
+
+// AllHostnames returns Hostname followed by Hostnames, without duplicates.
+// The first entry is the primary hostname.
+func (r *Routing) AllHostnames() []string {
+	var names []string
+	for _, h := range append([]string{r.Hostname}, r.Hostnames...) {
+		if h != "" && !slices.Contains(names, h) {
+			names = append(names, h)
+		}
+	}
+	return names
+}
+
+// PrimaryHostname returns the first of AllHostnames, or "" if there are none.
+func (r *Routing) PrimaryHostname() string {
+	if names := r.AllHostnames(); len(names) > 0 {
+		return names[0]
+	}
+	return ""
+}
+
+// IsHTTP reports whether the route is an HTTP route (the default protocol).
+func (r *Routing) IsHTTP() bool {
+	return r.Protocol == "" || r.Protocol == "http"
+}
+
+// validateProtocol checks the fields that depend on the routing protocol.
+// HTTP routes need a hostname; TCP and UDP routes forward raw streams, so
+// the HTTP-only options do not apply to them.
+func (r *Routing) validateProtocol() error {
+	if r.EntryPoint != "" && !entryPointRe.MatchString(r.EntryPoint) {
+		return fmt.Errorf("routing.entry_point %q is invalid", r.EntryPoint)
+	}
+
+	hasHostname := len(r.AllHostnames()) > 0
+	if r.IsHTTP() {
+		if !hasHostname {
+			return fmt.Errorf("routing.hostname is required")
+		}
+		return nil
+	}
+	if r.Protocol != "tcp" && r.Protocol != "udp" {
+		return fmt.Errorf("routing.protocol must be \"http\", \"tcp\", or \"udp\", got %q", r.Protocol)
+	}
+
+	if r.PathPrefix != "" || r.StripPrefix || len(r.Middlewares) > 0 || len(r.Headers) > 0 || r.TLSOptions != "" {
+		return fmt.Errorf("routing.path_prefix, strip_prefix, middlewares, headers, and tls_options only apply to http routing")
+	}
+	if r.Protocol == "udp" {
+		if hasHostname || r.TLS {
+			return fmt.Errorf("routing.hostname and routing.tls are not supported for udp routing")
+		}
+		if r.EntryPoint == "" {
+			return fmt.Errorf("routing.entry_point is required for udp routing")
+		}
+		return nil
+	}
+	// tcp: a hostname means TLS passthrough routed on SNI
+	if !hasHostname {
+		if r.TLS {
+			return fmt.Errorf("routing.tls for tcp routing requires routing.hostname to match on SNI")
+		}
+		if r.EntryPoint == "" {
+			return fmt.Errorf("routing.entry_point is required for tcp routing without a hostname")
+		}
+	}
+	return nil
 }
 
 // Hooks defines shell commands for remote service lifecycle management.
@@ -103,9 +420,155 @@ type Hooks struct {
 	Logs    string `yaml:"logs,omitempty"`
 }
 
+// Lifecycle defines shell commands run around a managed process's lifecycle.
+// PostStart runs once each time the process starts, after it is healthy (or
+// immediately without a health check), e.g. to warm a cache. PreStop runs
+// before the process is signalled to stop (and before the drain period of a
+// blue-green deploy), e.g. to deregister from a load balancer.
+type Lifecycle struct {
+	PostStart         string   `yaml:"post_start,omitempty"`
+	PostStartTimeout  Duration `yaml:"post_start_timeout,omitempty"`  // default 30s
+	PostStartRequired bool     `yaml:"post_start_required,omitempty"` // failure fails the start
+	PreStop           string   `yaml:"pre_stop,omitempty"`
+	PreStopTimeout    Duration `yaml:"pre_stop_timeout,omitempty"` // default 10s
+}
+
 type Dependencies struct {
-	After    []string `yaml:"after,omitempty"`
+	After []string `yaml:"after,omitempty"`
+	// Before lists services that start after this one, as if each of them
+	// had this service in its After.
+	Before   []string `yaml:"before,omitempty"`
 	Requires []string `yaml:"requires,omitempty"`
+	// Conditions sets, per dependency in After, how far it must get before
+	// this service starts: "started" or "healthy".
+	Conditions map[string]string `yaml:"conditions,omitempty"`
+}
+
+// Dependency start conditions.
+const (
+	ConditionStarted = "started"
+	ConditionHealthy = "healthy"
+)
+
+// Condition returns the start condition for dependency name. Without an
+// explicit entry, required dependencies must be healthy and the rest only
+// started.
+func (d *Dependencies) Condition(name string) string {
+	if c, ok := d.Conditions[name]; ok {
+		return c
+	}
+	if slices.Contains(d.Requires, name) {
+		return ConditionHealthy
+	}
+	return ConditionStarted
+}
+
+// GPU declares what a service needs from the GPU.
+type GPU struct {
+	// RequiresVRAM is the free VRAM the service needs to start, e.g. "8GB".
+	// The daemon defers starting the service until that much is free.
+	RequiresVRAM ByteSize `yaml:"requires_vram"`
+}
+
+// Security hardens a container service.
+type Security struct {
+	ReadOnly        bool     `yaml:"read_only,omitempty"`         // mount the root filesystem read-only
+	CapDrop         []string `yaml:"cap_drop,omitempty"`          // Linux capabilities to drop, or ALL
+	CapAdd          []string `yaml:"cap_add,omitempty"`           // Linux capabilities to add
+	NoNewPrivileges bool     `yaml:"no_new_privileges,omitempty"` // block setuid and file capability escalation
+}
+
+// linuxCapabilities are the capability names Docker accepts, without the
+// CAP_ prefix.
+var linuxCapabilities = map[string]bool{
+	"AUDIT_CONTROL": true, "AUDIT_READ": true, "AUDIT_WRITE": true,
+	"BLOCK_SUSPEND": true, "BPF": true, "CHECKPOINT_RESTORE": true,
+	"CHOWN": true, "DAC_OVERRIDE": true, "DAC_READ_SEARCH": true,
+	"FOWNER": true, "FSETID": true, "IPC_LOCK": true, "IPC_OWNER": true,
+	"KILL": true, "LEASE": true, "LINUX_IMMUTABLE": true,
+	"MAC_ADMIN": true, "MAC_OVERRIDE": true, "MKNOD": true,
+	"NET_ADMIN": true, "NET_BIND_SERVICE": true, "NET_BROADCAST": true,
+	"NET_RAW": true, "PERFMON": true, "SETFCAP": true, "SETGID": true,
+	"SETPCAP": true, "SETUID": true, "SYS_ADMIN": true, "SYS_BOOT": true,
+	"SYS_CHROOT": true, "SYS_MODULE": true, "SYS_NICE": true,
+	"SYS_PACCT": true, "SYS_PTRACE": true, "SYS_RAWIO": true,
+	"SYS_RESOURCE": true, "SYS_TIME": true, "SYS_TTY_CONFIG": true,
+	"SYSLOG": true, "WAKE_ALARM": true,
+}
+
+// validCapability reports whether name is a Linux capability, written with
+// or without the CAP_ prefix in any case, or ALL.
+func validCapability(name string) bool {
+	name = strings.TrimPrefix(strings.ToUpper(name), "CAP_")
+	return name == "ALL" || linuxCapabilities[name]
+}
+
+// ByteSize is a size in bytes, written in YAML as a number and a unit such
+// as "512MB" or "8GB". Units are binary (1GB = 1024MB), as GPU memory is
+// reported.
+type ByteSize uint64
+
+var byteUnits = []struct {
+	suffix string
+	size   uint64
+}{
+	{"TB", 1 << 40},
+	{"GB", 1 << 30},
+	{"MB", 1 << 20},
+	{"KB", 1 << 10},
+	{"B", 1},
+}
+
+// ParseByteSize parses a size like "8GB", "1.5GB" or "512MB". The unit is
+// case-insensitive and may also be written as GiB, MiB and so on; a bare
+// number is bytes.
+func ParseByteSize(s string) (ByteSize, error) {
+	str := strings.ToUpper(strings.TrimSpace(s))
+	str = strings.Replace(str, "IB", "B", 1)
+	mult := uint64(1)
+	for _, u := range byteUnits {
+		if num, ok := strings.CutSuffix(str, u.suffix); ok {
+			str, mult = strings.TrimSpace(num), u.size
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(str, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q: want a number and a unit like 8GB", s)
+	}
+	return ByteSize(n * float64(mult)), nil
+}
+
+// GB returns the size in gigabytes.
+func (b ByteSize) GB() float64 {
+	return float64(b) / (1 << 30)
+}
+
+// String formats the size in the largest unit that divides it exactly.
+func (b ByteSize) String() string {
+	for _, u := range byteUnits {
+		if uint64(b) >= u.size && uint64(b)%u.size == 0 {
+			return fmt.Sprintf("%d%s", uint64(b)/u.size, u.suffix)
+		}
+	}
+	return "0B"
+}
+
+func (b *ByteSize) UnmarshalYAML(value *yaml.Node) error {
+	var s string
+	if err := value.Decode(&s); err != nil {
+		return err
+	}
+	parsed, err := ParseByteSize(s)
+	if err != nil {
+		return err
+	}
+	*b = parsed
+	return nil
+}
+
+func (b ByteSize) MarshalYAML() (any, error) {
+	return b.String(), nil
 }
 
 // Duration wraps time.Duration for YAML unmarshaling from strings like "10s", "5m".
@@ -130,6 +593,47 @@ func (d Duration) MarshalYAML() (any, error) {
 	return d.Duration.String(), nil
 }
 
+// Validate checks the policy's own fields. The oneshot policy also needs a
+// health block, which [ServiceSpec.Validate] checks.
+func (r *RestartPolicy) Validate() error {
+	switch r.Policy {
+	case "always", "on-failure", "never", "unless-stopped", "oneshot":
+		// ok
+	default:
+		return fmt.Errorf("restart.policy must be \"always\", \"unless-stopped\", \"on-failure\", \"never\", or \"oneshot\", got %q", r.Policy)
+	}
+
+	if r.Backoff != "" {
+		switch r.Backoff {
+		case "fixed", "exponential":
+			// ok
+		default:
+			return fmt.Errorf("restart.backoff must be \"fixed\" or \"exponential\", got %q", r.Backoff)
+		}
+	}
+
+	switch r.Jitter {
+	case "", "none", "full", "equal":
+		// ok
+	default:
+		return fmt.Errorf("restart.jitter must be \"none\", \"full\" or \"equal\", got %q", r.Jitter)
+	}
+
+	if r.InitialDelay != nil && r.InitialDelay.Duration < 0 {
+		return fmt.Errorf("restart.initial_delay must not be negative")
+	}
+	if r.MaxPerWindow < 0 {
+		return fmt.Errorf("restart.max_per_window must not be negative")
+	}
+	if r.Window.Duration < 0 {
+		return fmt.Errorf("restart.window must not be negative")
+	}
+	if r.Window.Duration > 0 && r.MaxPerWindow == 0 {
+		return fmt.Errorf("restart.window requires restart.max_per_window")
+	}
+	return nil
+}
+
 // ExpandEnv expands environment variables in path and value fields using os.ExpandEnv.
 // This supports $VAR and ${VAR} patterns, allowing specs to use e.g. ${AURELIA_ROOT}
 // instead of hardcoded absolute paths.
@@ -146,6 +650,13 @@ func (s *ServiceSpec) ExpandEnv() {
 		s.Hooks.Restart = os.ExpandEnv(s.Hooks.Restart)
 		s.Hooks.Logs = os.ExpandEnv(s.Hooks.Logs)
 	}
+	if s.Health != nil {
+		s.Health.UnixSocket = os.ExpandEnv(s.Health.UnixSocket)
+	}
+	if s.Lifecycle != nil {
+		s.Lifecycle.PostStart = os.ExpandEnv(s.Lifecycle.PostStart)
+		s.Lifecycle.PreStop = os.ExpandEnv(s.Lifecycle.PreStop)
+	}
 	for k, v := range s.Env {
 		s.Env[k] = os.ExpandEnv(v)
 	}
@@ -244,29 +755,202 @@ func expandRuntimeVars(s string, vars map[string]string) string {
 // which is owner-only (0700) and are written by the machine operator. Specs
 // can reference arbitrary binaries, bind ports, mount volumes, and inject
 // secrets — treat them like shell scripts. See issue #53.
-func Load(path string) (*ServiceSpec, error) {
+//
+// Values from the defaults file in the same directory (see [DefaultsFile])
+// are merged under the spec before validation.
+//
+// Keys that match no spec field are an error, so a typo like "comand" is
+// reported rather than silently dropped; see [AllowUnknownFields].
+//
+// The file must declare exactly one service; use [LoadFile] for files that
+// may hold several.
+func Load(path string, opts ...LoadOption) (*ServiceSpec, error) {
+	specs, err := LoadFile(path, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if len(specs) != 1 {
+		return nil, fmt.Errorf("spec %s declares %d services, expected one", path, len(specs))
+	}
+	return specs[0], nil
+}
+
+// LoadFile reads every service spec in a YAML file. A file may hold several
+// specs as "---"-separated documents; each is parsed and validated on its
+// own. See [Load] for defaults, unknown fields and the security model.
+func LoadFile(path string, opts ...LoadOption) ([]*ServiceSpec, error) {
+	o := newLoadOptions(opts)
+	defaults, err := loadDefaults(filepath.Dir(path), o.strict)
+	if err != nil {
+		return nil, err
+	}
+	loaded, err := load(path, defaults, o)
+	if err != nil {
+		return nil, err
+	}
+	specs := make([]*ServiceSpec, len(loaded))
+	for i, l := range loaded {
+		specs[i] = l.spec
+	}
+	return specs, nil
+}
+
+// Parse parses and validates a single service spec from YAML (or JSON, which
+// is valid YAML) as though it were a file in dir, merging dir's defaults file
+// under it. See [Load] for unknown fields.
+func Parse(data []byte, dir string, opts ...LoadOption) (*ServiceSpec, error) {
+	o := newLoadOptions(opts)
+	defaults, err := loadDefaults(dir, o.strict)
+	if err != nil {
+		return nil, err
+	}
+	loaded, err := parse(data, "", defaults, o)
+	if err != nil {
+		return nil, err
+	}
+	if len(loaded) != 1 {
+		return nil, fmt.Errorf("spec declares %d services, expected one", len(loaded))
+	}
+	return loaded[0].spec, nil
+}
+
+// LoadOption configures how spec files are loaded.
+type LoadOption func(*loadOptions)
+
+type loadOptions struct {
+	strict         bool           // reject keys that match no spec field
+	override       bool           // later directories replace same-named specs in LoadDirs
+	defaultRestart *RestartPolicy // restart policy for specs without a restart block
+}
+
+func newLoadOptions(opts []LoadOption) loadOptions {
+	o := loadOptions{strict: true}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// AllowUnknownFields ignores keys that match no spec field instead of
+// rejecting them, for specs written for a newer aurelia.
+func AllowUnknownFields() LoadOption {
+	return func(o *loadOptions) {
+		o.strict = false
+	}
+}
+
+// OverrideDuplicates lets [LoadDirs] take a service declared in more than
+// one directory from the last of them, instead of rejecting the duplicate.
+func OverrideDuplicates() LoadOption {
+	return func(o *loadOptions) {
+		o.override = true
+	}
+}
+
+// DefaultRestart gives specs that have no restart block, even after the
+// directory's defaults file is merged in, a copy of policy.
+func DefaultRestart(policy *RestartPolicy) LoadOption {
+	return func(o *loadOptions) {
+		o.defaultRestart = policy
+	}
+}
+
+// loadedSpec is a spec along with where it was declared: the file, plus the
+// document number for files holding several specs.
+type loadedSpec struct {
+	spec     *ServiceSpec
+	location string
+}
+
+func load(path string, defaults map[string]any, o loadOptions) ([]loadedSpec, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("reading spec %s: %w", path, err)
 	}
+	return parse(data, path, defaults, o)
+}
 
-	var spec ServiceSpec
-	if err := yaml.Unmarshal(data, &spec); err != nil {
-		return nil, fmt.Errorf("parsing spec %s: %w", path, err)
+// parse parses and validates every spec in data. source names where data
+// came from in errors, and may be empty.
+func parse(data []byte, source string, defaults map[string]any, o loadOptions) ([]loadedSpec, error) {
+	where := ""
+	if source != "" {
+		where = " " + source
 	}
 
-	spec.ExpandEnv()
+	if o.strict {
+		if err := checkKnownFields(data); err != nil {
+			return nil, fmt.Errorf("parsing spec%s: %w", where, err)
+		}
+	}
 
-	if err := spec.Validate(); err != nil {
-		return nil, fmt.Errorf("validating spec %s: %w", path, err)
+	docs, err := splitDocuments(data)
+	if err != nil {
+		return nil, fmt.Errorf("parsing spec%s: %w", where, err)
 	}
 
-	return &spec, nil
+	loaded := make([]loadedSpec, 0, len(docs))
+	for i, doc := range docs {
+		location := where
+		if len(docs) > 1 {
+			location = fmt.Sprintf("%s (document %d)", where, i+1)
+		}
+
+		spec, err := parseWithDefaults(doc, defaults)
+		if err != nil {
+			return nil, fmt.Errorf("parsing spec%s: %w", location, err)
+		}
+
+		spec.ExpandEnv()
+
+		if spec.Restart == nil && o.defaultRestart != nil {
+			restart := *o.defaultRestart
+			spec.Restart = &restart
+		}
+
+		if err := spec.Validate(); err != nil {
+			return nil, fmt.Errorf("validating spec%s: %w", location, err)
+		}
+		loaded = append(loaded, loadedSpec{spec: &spec, location: strings.TrimPrefix(location, " ")})
+	}
+
+	return loaded, nil
 }
 
-// LoadDir reads all YAML service specs from a directory.
+// splitDocuments parses data into its YAML documents, skipping empty ones
+// (e.g. after a trailing "---").
+func splitDocuments(data []byte) ([]*yaml.Node, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	var docs []*yaml.Node
+	for {
+		var doc yaml.Node
+		err := dec.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(doc.Content) == 0 || doc.Content[0].Tag == "!!null" {
+			continue
+		}
+		docs = append(docs, &doc)
+	}
+	if len(docs) == 0 {
+		return nil, errors.New("no service spec found")
+	}
+	return docs, nil
+}
+
+// LoadDir reads all YAML service specs from a directory, merging the
+// directory's defaults file (if any) under each one. Files may hold several
+// specs, as for [LoadFile].
+// Two specs declaring the same service.name is an error naming where both
+// were declared, rather than letting one silently shadow the other.
 // See [Load] for the security model — spec files are trusted input.
-func LoadDir(dir string) ([]*ServiceSpec, error) {
+func LoadDir(dir string, opts ...LoadOption) ([]*ServiceSpec, error) {
+	o := newLoadOptions(opts)
+
 	entries, err := filepath.Glob(filepath.Join(dir, "*.yaml"))
 	if err != nil {
 		return nil, fmt.Errorf("listing specs in %s: %w", dir, err)
@@ -279,18 +963,69 @@ func LoadDir(dir string) ([]*ServiceSpec, error) {
 	}
 	entries = append(entries, ymlEntries...)
 
+	defaults, err := loadDefaults(dir, o.strict)
+	if err != nil {
+		return nil, err
+	}
+
 	var specs []*ServiceSpec
+	seen := make(map[string]string) // service name -> where it was declared
 	for _, path := range entries {
-		spec, err := Load(path)
+		if IsDefaultsFile(path) {
+			continue
+		}
+		loaded, err := load(path, defaults, o)
 		if err != nil {
 			return nil, err
 		}
-		specs = append(specs, spec)
+		for _, l := range loaded {
+			name := l.spec.Service.Name
+			if prev, ok := seen[name]; ok {
+				return nil, fmt.Errorf("duplicate service name %q declared in %s and %s", name, prev, l.location)
+			}
+			seen[name] = l.location
+			specs = append(specs, l.spec)
+		}
 	}
 
 	return specs, nil
 }
 
+// LoadDirs loads the specs in each of dirs, in order, as for [LoadDir]; each
+// directory's own defaults file applies to its specs. A service name
+// declared in more than one directory is an error naming both, unless
+// [OverrideDuplicates] is given, in which case the later directory's spec
+// replaces the earlier one in place.
+func LoadDirs(dirs []string, opts ...LoadOption) ([]*ServiceSpec, error) {
+	o := newLoadOptions(opts)
+
+	var specs []*ServiceSpec
+	index := make(map[string]int)   // service name -> position in specs
+	from := make(map[string]string) // service name -> directory it came from
+	for _, dir := range dirs {
+		loaded, err := LoadDir(dir, opts...)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range loaded {
+			name := s.Service.Name
+			i, ok := index[name]
+			if !ok {
+				index[name] = len(specs)
+				from[name] = dir
+				specs = append(specs, s)
+				continue
+			}
+			if !o.override {
+				return nil, fmt.Errorf("duplicate service name %q declared in %s and %s", name, from[name], dir)
+			}
+			specs[i] = s
+			from[name] = dir
+		}
+	}
+	return specs, nil
+}
+
 // Hash returns a SHA-256 hex digest of the spec's canonical YAML representation.
 // Two specs with identical content produce the same hash regardless of field order.
 func (s *ServiceSpec) Hash() string {
@@ -302,12 +1037,37 @@ func (s *ServiceSpec) Hash() string {
 	return fmt.Sprintf("%x", sha256.Sum256(data))
 }
 
+// IsEnabled reports whether the service should run: true unless the spec
+// sets enabled: false.
+func (s *ServiceSpec) IsEnabled() bool {
+	return s.Enabled == nil || *s.Enabled
+}
+
 // NeedsDynamicPort returns true when the spec has a network block with port 0,
 // indicating the daemon should allocate a port at runtime.
 func (s *ServiceSpec) NeedsDynamicPort() bool {
 	return s.Network != nil && s.Network.Port == 0
 }
 
+// ReplicaCount returns how many instances of the service run, at least one.
+// With autoscale it is the count the service starts with: service.replicas
+// if set, otherwise autoscale.min.
+func (s *ServiceSpec) ReplicaCount() int {
+	if a := s.Autoscale; a != nil && s.Service.Replicas == 0 {
+		return max(a.Min, 1)
+	}
+	return max(s.Service.Replicas, 1)
+}
+
+// MaxReplicas returns the most instances of the service that can run:
+// autoscale.max, or ReplicaCount without autoscale.
+func (s *ServiceSpec) MaxReplicas() int {
+	if a := s.Autoscale; a != nil {
+		return max(a.Max, s.ReplicaCount())
+	}
+	return s.ReplicaCount()
+}
+
 // Validate checks that a service spec is well-formed.
 func (s *ServiceSpec) Validate() error {
 	if s.Service.Name == "" {
@@ -317,6 +1077,66 @@ func (s *ServiceSpec) Validate() error {
 		return fmt.Errorf("service.name %q is invalid: must match ^[a-zA-Z0-9][a-zA-Z0-9._-]{0,63}$", s.Service.Name)
 	}
 
+	if s.Service.LogTimestamps && s.Service.Type != "container" {
+		return fmt.Errorf("service.log_timestamps is only valid for container services")
+	}
+	if (s.Service.TTY || s.Service.StdinOpen) && s.Service.Type != "container" {
+		return fmt.Errorf("service.tty and service.stdin_open are only valid for container services")
+	}
+	if err := s.validateMounts(); err != nil {
+		return err
+	}
+	if len(s.Service.Labels) > 0 {
+		if s.Service.Type != "container" {
+			return fmt.Errorf("service.labels is only valid for container services")
+		}
+		for k := range s.Service.Labels {
+			if k == "" {
+				return fmt.Errorf("service.labels has an empty key")
+			}
+			if k == "managed-by" || k == "aurelia.service" {
+				return fmt.Errorf("service.labels: %q is set by aurelia", k)
+			}
+		}
+	}
+	if s.Service.KillOrphans && s.Service.Type != "native" {
+		return fmt.Errorf("service.kill_orphans is only valid for native services")
+	}
+	if s.Service.Priority != 0 || s.Service.OOMScoreAdj != 0 {
+		if s.Service.Type != "native" {
+			return fmt.Errorf("service.priority and service.oom_score_adj are only valid for native services")
+		}
+		if s.Service.Priority < -20 || s.Service.Priority > 19 {
+			return fmt.Errorf("service.priority must be between -20 and 19, got %d", s.Service.Priority)
+		}
+		if s.Service.OOMScoreAdj < -1000 || s.Service.OOMScoreAdj > 1000 {
+			return fmt.Errorf("service.oom_score_adj must be between -1000 and 1000, got %d", s.Service.OOMScoreAdj)
+		}
+	}
+	if err := s.validateReplicas(); err != nil {
+		return err
+	}
+	if n := s.Network; n != nil && n.ContainerPort != 0 {
+		if s.Service.Type != "container" {
+			return fmt.Errorf("network.container_port is only valid for container services")
+		}
+		if n.ContainerPort < 1 || n.ContainerPort > 65535 {
+			return fmt.Errorf("network.container_port must be between 1 and 65535, got %d", n.ContainerPort)
+		}
+		if nm := s.Service.NetworkMode; nm == "" || nm == "host" {
+			return fmt.Errorf("network.container_port requires a network_mode other than host")
+		}
+	}
+
+	if n := s.Network; n != nil && n.BindAddress != "" {
+		if s.Service.Type != "native" && s.Service.Type != "container" {
+			return fmt.Errorf("network.bind_address is only valid for native and container services")
+		}
+		if net.ParseIP(n.BindAddress) == nil {
+			return fmt.Errorf("network.bind_address %q is not a valid IP address", n.BindAddress)
+		}
+	}
+
 	switch s.Service.Type {
 	case "native":
 		if s.Service.Command == "" {
@@ -353,6 +1173,9 @@ func (s *ServiceSpec) Validate() error {
 		if s.Routing != nil {
 			return fmt.Errorf("routing is not valid for external services")
 		}
+		if s.Lifecycle != nil {
+			return fmt.Errorf("lifecycle is not valid for external services")
+		}
 	case "remote":
 		if s.Service.Command != "" {
 			return fmt.Errorf("service.command is not valid for remote services")
@@ -379,14 +1202,41 @@ func (s *ServiceSpec) Validate() error {
 			if h.Path[0] != '/' {
 				return fmt.Errorf("health.path must start with /, got %q", h.Path)
 			}
+			switch h.Method {
+			case "", "GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "OPTIONS":
+				// ok
+			default:
+				return fmt.Errorf("health.method must be GET, HEAD, POST, PUT, PATCH, DELETE or OPTIONS, got %q", h.Method)
+			}
 		case "tcp":
 			// port is sufficient
 		case "exec":
 			if h.Command == "" {
 				return fmt.Errorf("health.command is required for exec health checks")
 			}
+		case "docker":
+			if s.Service.Type != "container" {
+				return fmt.Errorf("health.type docker is only valid for container services")
+			}
 		default:
-			return fmt.Errorf("health.type must be \"http\", \"tcp\", or \"exec\", got %q", h.Type)
+			return fmt.Errorf("health.type must be \"http\", \"tcp\", \"exec\", or \"docker\", got %q", h.Type)
+		}
+		if h.Type != "http" && (h.Method != "" || len(h.Headers) > 0 || h.Body != "") {
+			return fmt.Errorf("health.method, headers and body are only valid for http health checks")
+		}
+		if h.UnixSocket != "" {
+			if h.Type != "http" && h.Type != "tcp" {
+				return fmt.Errorf("health.unix_socket is only valid for http and tcp health checks")
+			}
+			if !filepath.IsAbs(h.UnixSocket) {
+				return fmt.Errorf("health.unix_socket must be an absolute path, got %q", h.UnixSocket)
+			}
+			if h.Port != 0 {
+				return fmt.Errorf("health.unix_socket and health.port are mutually exclusive")
+			}
+			if s.Routing != nil && s.NeedsDynamicPort() {
+				return fmt.Errorf("health.unix_socket can't be combined with routing and a dynamic port: a blue-green deploy would check the new instance on the socket the old one serves")
+			}
 		}
 
 		if h.Interval.Duration <= 0 {
@@ -395,36 +1245,79 @@ func (s *ServiceSpec) Validate() error {
 		if h.Timeout.Duration <= 0 {
 			return fmt.Errorf("health.timeout must be positive")
 		}
+		if h.InitialDelay.Duration < 0 || h.GracePeriod.Duration < 0 {
+			return fmt.Errorf("health.initial_delay and grace_period must not be negative")
+		}
+		if h.MaxStartDuration.Duration < 0 {
+			return fmt.Errorf("health.max_start_duration must not be negative")
+		}
+		if h.Jitter < 0 || h.Jitter > 50 {
+			return fmt.Errorf("health.jitter must be between 0 and 50 (percent), got %d", h.Jitter)
+		}
+		if h.FlapThreshold < 0 || h.FlapWindow.Duration < 0 {
+			return fmt.Errorf("health.flap_threshold and flap_window must not be negative")
+		}
+		if st := h.Startup; st != nil {
+			if st.Interval.Duration < 0 || st.Timeout.Duration < 0 || st.MaxDuration.Duration < 0 || st.Threshold < 0 {
+				return fmt.Errorf("health.startup values must not be negative")
+			}
+			if st.Threshold == 0 && st.MaxDuration.Duration == 0 {
+				return fmt.Errorf("health.startup needs a threshold or max_duration")
+			}
+		}
 	}
 
-	if r := s.Restart; r != nil {
-		switch r.Policy {
-		case "always", "on-failure", "never":
-			// ok
-		case "oneshot":
-			if s.Health == nil {
-				return fmt.Errorf("health block is required for oneshot restart policy")
-			}
-		default:
-			return fmt.Errorf("restart.policy must be \"always\", \"on-failure\", \"never\", or \"oneshot\", got %q", r.Policy)
+	if lc := s.Lifecycle; lc != nil {
+		if lc.PostStartTimeout.Duration < 0 {
+			return fmt.Errorf("lifecycle.post_start_timeout must not be negative")
+		}
+		if (lc.PostStartTimeout.Duration > 0 || lc.PostStartRequired) && lc.PostStart == "" {
+			return fmt.Errorf("lifecycle.post_start_timeout and post_start_required require lifecycle.post_start")
+		}
+		if lc.PreStopTimeout.Duration < 0 {
+			return fmt.Errorf("lifecycle.pre_stop_timeout must not be negative")
 		}
+		if lc.PreStopTimeout.Duration > 0 && lc.PreStop == "" {
+			return fmt.Errorf("lifecycle.pre_stop_timeout requires lifecycle.pre_stop")
+		}
+	}
 
-		if r.Backoff != "" {
-			switch r.Backoff {
-			case "fixed", "exponential":
-				// ok
-			default:
-				return fmt.Errorf("restart.backoff must be \"fixed\" or \"exponential\", got %q", r.Backoff)
-			}
+	if r := s.Restart; r != nil {
+		if err := r.Validate(); err != nil {
+			return err
+		}
+		if r.Policy == "oneshot" && s.Health == nil {
+			return fmt.Errorf("health block is required for oneshot restart policy")
 		}
 	}
 
 	if r := s.Routing; r != nil {
-		if r.Hostname == "" {
-			return fmt.Errorf("routing.hostname is required")
+		if err := r.validateProtocol(); err != nil {
+			return err
+		}
+		for _, h := range r.AllHostnames() {
+			if !hostnameRe.MatchString(h) {
+				return fmt.Errorf("routing.hostname %q is invalid: must be a valid hostname", h)
+			}
 		}
-		if !hostnameRe.MatchString(r.Hostname) {
-			return fmt.Errorf("routing.hostname %q is invalid: must be a valid hostname", r.Hostname)
+		if r.PathPrefix != "" && !pathPrefixRe.MatchString(r.PathPrefix) {
+			return fmt.Errorf("routing.path_prefix %q is invalid: must start with / and contain only URL path characters", r.PathPrefix)
+		}
+		if r.StripPrefix && r.PathPrefix == "" {
+			return fmt.Errorf("routing.strip_prefix requires routing.path_prefix")
+		}
+		for _, mw := range r.Middlewares {
+			if !middlewareRe.MatchString(mw) {
+				return fmt.Errorf("routing.middlewares: %q is not a valid middleware name", mw)
+			}
+		}
+		for name := range r.Headers {
+			if !headerNameRe.MatchString(name) {
+				return fmt.Errorf("routing.headers: %q is not a valid header name", name)
+			}
+		}
+		if r.UnhealthyGrace.Duration < 0 {
+			return fmt.Errorf("routing.unhealthy_grace must not be negative")
 		}
 		// Routing requires a port source: static network.port, dynamic (port 0
 		// with network block — resolved at runtime), or health.port.
@@ -441,6 +1334,31 @@ func (s *ServiceSpec) Validate() error {
 		}
 	}
 
+	if g := s.GPU; g != nil {
+		if s.Service.Type != "native" && s.Service.Type != "container" {
+			return fmt.Errorf("gpu is only valid for native and container services")
+		}
+		if g.RequiresVRAM == 0 {
+			return fmt.Errorf("gpu.requires_vram is required")
+		}
+	}
+
+	if sec := s.Security; sec != nil {
+		if s.Service.Type != "container" {
+			return fmt.Errorf("security is only valid for container services")
+		}
+		for _, c := range sec.CapDrop {
+			if !validCapability(c) {
+				return fmt.Errorf("security.cap_drop: unknown capability %q", c)
+			}
+		}
+		for _, c := range sec.CapAdd {
+			if !validCapability(c) {
+				return fmt.Errorf("security.cap_add: unknown capability %q", c)
+			}
+		}
+	}
+
 	if deps := s.Dependencies; deps != nil {
 		for _, req := range deps.Requires {
 			found := false
@@ -454,7 +1372,70 @@ func (s *ServiceSpec) Validate() error {
 				return fmt.Errorf("dependency %q is in requires but not in after — required services must also be in the start order", req)
 			}
 		}
+		for _, name := range deps.Before {
+			if name == s.Service.Name {
+				return fmt.Errorf("dependencies.before: a service can't start before itself")
+			}
+			if slices.Contains(deps.After, name) {
+				return fmt.Errorf("dependencies.before: %q is also in after", name)
+			}
+		}
+		for name, cond := range deps.Conditions {
+			if !slices.Contains(deps.After, name) {
+				return fmt.Errorf("dependencies.conditions: %q is not in after", name)
+			}
+			if cond != ConditionStarted && cond != ConditionHealthy {
+				return fmt.Errorf("dependencies.conditions: %q must be \"started\" or \"healthy\", got %q", name, cond)
+			}
+		}
 	}
 
 	return nil
 }
+
+// validateMounts checks named volumes, tmpfs mounts and docker_secrets. Bind mounts are
+// passed to Docker as written.
+func (s *ServiceSpec) validateMounts() error {
+	for source, target := range s.Volumes {
+		if !IsNamedVolume(source) {
+			continue
+		}
+		if !volumeNameRe.MatchString(source) {
+			return fmt.Errorf("volumes: %q is neither a host path nor a valid volume name", source)
+		}
+		path, mode, _ := strings.Cut(target, ":")
+		if !strings.HasPrefix(path, "/") {
+			return fmt.Errorf("volumes: %s must mount at an absolute path, got %q", source, path)
+		}
+		if mode != "" && mode != "ro" && mode != "rw" {
+			return fmt.Errorf("volumes: %s has invalid mode %q, want ro or rw", source, mode)
+		}
+	}
+
+	if len(s.Tmpfs) > 0 && s.Service.Type != "container" {
+		return fmt.Errorf("tmpfs is only valid for container services")
+	}
+	seen := make(map[string]bool, len(s.Tmpfs))
+	for _, t := range s.Tmpfs {
+		if !strings.HasPrefix(t.Path, "/") {
+			return fmt.Errorf("tmpfs.path must be absolute, got %q", t.Path)
+		}
+		if seen[t.Path] {
+			return fmt.Errorf("tmpfs.path %s is listed twice", t.Path)
+		}
+		seen[t.Path] = true
+	}
+
+	if len(s.DockerSecrets) > 0 && s.Service.Type != "container" {
+		return fmt.Errorf("docker_secrets is only valid for container services")
+	}
+	for name, ref := range s.DockerSecrets {
+		if !secretFileRe.MatchString(name) {
+			return fmt.Errorf("docker_secrets: name %q must be letters, digits, '_', '.' or '-', starting with a letter or digit", name)
+		}
+		if ref.Key() == "" {
+			return fmt.Errorf("docker_secrets: %s needs a secret key", name)
+		}
+	}
+	return nil
+}
@@ -1,8 +1,10 @@
 package spec
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 	"time"
@@ -116,6 +118,7 @@ health:
   timeout: 2s
   grace_period: 5s
   unhealthy_threshold: 3
+  healthy_threshold: 2
 
 restart:
   policy: on-failure
@@ -186,6 +189,9 @@ dependencies:
 	if spec.Health.UnhealthyThreshold != 3 {
 		t.Errorf("expected unhealthy_threshold 3, got %d", spec.Health.UnhealthyThreshold)
 	}
+	if spec.Health.HealthyThreshold != 2 {
+		t.Errorf("expected healthy_threshold 2, got %d", spec.Health.HealthyThreshold)
+	}
 	if spec.Restart.Policy != "on-failure" {
 		t.Errorf("expected restart policy 'on-failure', got %q", spec.Restart.Policy)
 	}
@@ -343,6 +349,90 @@ func TestValidateServiceSpec(t *testing.T) {
 				Service: Service{Name: "test", Type: "container", Image: "foo:bar", NetworkMode: "../escape"},
 			},
 		},
+		{
+			name: "log_timestamps on native service",
+			spec: &ServiceSpec{
+				Service: Service{Name: "test", Type: "native", Command: "echo", LogTimestamps: true},
+			},
+		},
+		{
+			name: "tty on native service",
+			spec: &ServiceSpec{
+				Service: Service{Name: "test", Type: "native", Command: "echo", TTY: true},
+			},
+		},
+		{
+			name: "stdin_open on native service",
+			spec: &ServiceSpec{
+				Service: Service{Name: "test", Type: "native", Command: "cat", StdinOpen: true},
+			},
+		},
+		{
+			name: "labels on native service",
+			spec: &ServiceSpec{
+				Service: Service{Name: "test", Type: "native", Command: "echo", Labels: map[string]string{"team": "infra"}},
+			},
+		},
+		{
+			name: "labels overriding aurelia.service",
+			spec: &ServiceSpec{
+				Service: Service{Name: "test", Type: "container", Image: "foo:bar", Labels: map[string]string{"aurelia.service": "other"}},
+			},
+		},
+		{
+			name: "priority on container service",
+			spec: &ServiceSpec{
+				Service: Service{Name: "test", Type: "container", Image: "foo:bar", Priority: 5},
+			},
+		},
+		{
+			name: "priority out of range",
+			spec: &ServiceSpec{
+				Service: Service{Name: "test", Type: "native", Command: "echo", Priority: 20},
+			},
+		},
+		{
+			name: "oom_score_adj out of range",
+			spec: &ServiceSpec{
+				Service: Service{Name: "test", Type: "native", Command: "echo", OOMScoreAdj: -1001},
+			},
+		},
+		{
+			name: "container_port on native service",
+			spec: &ServiceSpec{
+				Service: Service{Name: "test", Type: "native", Command: "echo"},
+				Network: &Network{Port: 8080, ContainerPort: 80},
+			},
+		},
+		{
+			name: "container_port with host networking",
+			spec: &ServiceSpec{
+				Service: Service{Name: "test", Type: "container", Image: "foo:bar"},
+				Network: &Network{Port: 8080, ContainerPort: 80},
+			},
+		},
+		{
+			name: "container_port out of range",
+			spec: &ServiceSpec{
+				Service: Service{Name: "test", Type: "container", Image: "foo:bar", NetworkMode: "bridge"},
+				Network: &Network{Port: 8080, ContainerPort: 70000},
+			},
+		},
+		{
+			name: "bind_address not an IP",
+			spec: &ServiceSpec{
+				Service: Service{Name: "test", Type: "native", Command: "echo"},
+				Network: &Network{Port: 8080, BindAddress: "localhost"},
+			},
+		},
+		{
+			name: "bind_address on remote service",
+			spec: &ServiceSpec{
+				Service: Service{Name: "test", Type: "remote"},
+				Hooks:   &Hooks{Start: "true"},
+				Network: &Network{Port: 8080, BindAddress: "127.0.0.1"},
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -355,6 +445,35 @@ func TestValidateServiceSpec(t *testing.T) {
 	}
 }
 
+func TestNetworkReachableHost(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		network *Network
+		want    string
+	}{
+		{nil, ""},
+		{&Network{Port: 8080}, ""},
+		{&Network{Port: 8080, BindAddress: "0.0.0.0"}, ""},
+		{&Network{Port: 8080, BindAddress: "::"}, ""},
+		{&Network{Port: 8080, BindAddress: "127.0.0.1"}, "127.0.0.1"},
+		{&Network{Port: 8080, BindAddress: "10.0.0.5"}, "10.0.0.5"},
+		{&Network{Port: 8080, BindAddress: "::1"}, "::1"},
+	}
+	for _, tt := range tests {
+		if got := tt.network.ReachableHost(); got != tt.want {
+			t.Errorf("ReachableHost(%+v) = %q, want %q", tt.network, got, tt.want)
+		}
+	}
+
+	valid := &ServiceSpec{
+		Service: Service{Name: "test", Type: "native", Command: "echo"},
+		Network: &Network{Port: 8080, BindAddress: "::1"},
+	}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("expected IPv6 bind_address to be valid, got: %v", err)
+	}
+}
+
 func TestValidateServiceName(t *testing.T) {
 	t.Parallel()
 
@@ -417,6 +536,136 @@ func TestValidateRoutingHostname(t *testing.T) {
 	})
 }
 
+func TestValidateRoutingPathPrefix(t *testing.T) {
+	t.Parallel()
+	base := ServiceSpec{
+		Service: Service{Name: "test", Type: "native", Command: "echo"},
+		Network: &Network{Port: 8080},
+	}
+
+	s := base
+	s.Routing = &Routing{Hostname: "app.example.local", PathPrefix: "/api/v1", StripPrefix: true}
+	if err := s.Validate(); err != nil {
+		t.Errorf("expected valid path prefix to pass, got: %v", err)
+	}
+
+	for _, prefix := range []string{"api", "/api`)", "/a b"} {
+		s = base
+		s.Routing = &Routing{Hostname: "app.example.local", PathPrefix: prefix}
+		if err := s.Validate(); err == nil {
+			t.Errorf("expected error for path prefix %q", prefix)
+		}
+	}
+
+	s = base
+	s.Routing = &Routing{Hostname: "app.example.local", StripPrefix: true}
+	if err := s.Validate(); err == nil {
+		t.Error("expected error for strip_prefix without path_prefix")
+	}
+}
+
+func TestValidateRoutingMiddlewaresAndHeaders(t *testing.T) {
+	t.Parallel()
+	base := ServiceSpec{
+		Service: Service{Name: "test", Type: "native", Command: "echo"},
+		Network: &Network{Port: 8080},
+	}
+
+	s := base
+	s.Routing = &Routing{
+		Hostname:    "app.example.local",
+		Middlewares: []string{"auth@file", "rate-limit"},
+		Headers:     map[string]string{"X-Frame-Options": "DENY"},
+	}
+	if err := s.Validate(); err != nil {
+		t.Errorf("expected middlewares and headers to pass, got: %v", err)
+	}
+
+	s = base
+	s.Routing = &Routing{Hostname: "app.example.local", Middlewares: []string{"bad name"}}
+	if err := s.Validate(); err == nil {
+		t.Error("expected error for invalid middleware name")
+	}
+
+	s = base
+	s.Routing = &Routing{Hostname: "app.example.local", Headers: map[string]string{"Bad Header:": "x"}}
+	if err := s.Validate(); err == nil {
+		t.Error("expected error for invalid header name")
+	}
+}
+
+func TestValidateRoutingProtocol(t *testing.T) {
+	t.Parallel()
+	base := ServiceSpec{
+		Service: Service{Name: "test", Type: "native", Command: "echo"},
+		Network: &Network{Port: 5432},
+	}
+
+	valid := []*Routing{
+		{Protocol: "tcp", Hostname: "db.example.local"},
+		{Protocol: "tcp", EntryPoint: "postgres"},
+		{Protocol: "udp", EntryPoint: "dns"},
+		{Protocol: "http", Hostname: "app.example.local"},
+		{Hostname: "app.example.local", TLS: true, EntryPoint: "https"},
+	}
+	for _, r := range valid {
+		s := base
+		s.Routing = r
+		if err := s.Validate(); err != nil {
+			t.Errorf("expected %+v to pass, got: %v", r, err)
+		}
+	}
+
+	invalid := map[string]*Routing{
+		"unknown protocol":         {Protocol: "quic", Hostname: "app.example.local"},
+		"tcp catch-all no entry":   {Protocol: "tcp"},
+		"tcp tls without hostname": {Protocol: "tcp", EntryPoint: "postgres", TLS: true},
+		"tcp with path prefix":     {Protocol: "tcp", Hostname: "db.example.local", PathPrefix: "/db"},
+		"udp with hostname":        {Protocol: "udp", Hostname: "dns.example.local", EntryPoint: "dns"},
+		"udp without entrypoint":   {Protocol: "udp"},
+		"invalid entrypoint":       {Protocol: "tcp", EntryPoint: "bad name"},
+	}
+	for name, r := range invalid {
+		s := base
+		s.Routing = r
+		if err := s.Validate(); err == nil {
+			t.Errorf("%s: expected validation error", name)
+		}
+	}
+}
+
+func TestValidateRoutingHostnames(t *testing.T) {
+	t.Parallel()
+	base := ServiceSpec{
+		Service: Service{Name: "test", Type: "native", Command: "echo"},
+		Network: &Network{Port: 8080},
+	}
+
+	s := base
+	s.Routing = &Routing{Hostname: "example.com", Hostnames: []string{"www.example.com", "example.com"}}
+	if err := s.Validate(); err != nil {
+		t.Fatalf("expected hostnames to pass, got: %v", err)
+	}
+	if got := s.Routing.AllHostnames(); !slices.Equal(got, []string{"example.com", "www.example.com"}) {
+		t.Errorf("AllHostnames = %v, want hostname first without duplicates", got)
+	}
+
+	s = base
+	s.Routing = &Routing{Hostnames: []string{"a.example.com", "b.example.com"}}
+	if err := s.Validate(); err != nil {
+		t.Errorf("expected hostnames without hostname to pass, got: %v", err)
+	}
+	if got := s.Routing.PrimaryHostname(); got != "a.example.com" {
+		t.Errorf("PrimaryHostname = %q, want a.example.com", got)
+	}
+
+	s = base
+	s.Routing = &Routing{Hostname: "example.com", Hostnames: []string{"bad`host"}}
+	if err := s.Validate(); err == nil {
+		t.Error("expected error for an invalid entry in hostnames")
+	}
+}
+
 func TestValidateHealthCheckTypes(t *testing.T) {
 	t.Parallel()
 	base := ServiceSpec{
@@ -457,6 +706,91 @@ func TestValidateHealthCheckTypes(t *testing.T) {
 	if err := s.Validate(); err != nil {
 		t.Errorf("expected http health check with valid path to pass, got: %v", err)
 	}
+
+	// http request shape
+	s = base
+	s.Health = &HealthCheck{Type: "http", Path: "/health", Method: "POST", Headers: map[string]string{"Authorization": "Bearer x"}, Body: "{}", Interval: Duration{10 * time.Second}, Timeout: Duration{2 * time.Second}}
+	if err := s.Validate(); err != nil {
+		t.Errorf("expected http health check with method, headers and body to pass, got: %v", err)
+	}
+	s.Health.Method = "post"
+	if err := s.Validate(); err == nil {
+		t.Error("expected error for lowercase health method")
+	}
+	s = base
+	s.Health = &HealthCheck{Type: "tcp", Headers: map[string]string{"Accept": "*/*"}, Interval: Duration{10 * time.Second}, Timeout: Duration{2 * time.Second}}
+	if err := s.Validate(); err == nil {
+		t.Error("expected error for headers on a tcp health check")
+	}
+
+	// unix socket checks
+	s = base
+	s.Health = &HealthCheck{Type: "http", Path: "/health", UnixSocket: "/tmp/api.sock", Interval: Duration{10 * time.Second}, Timeout: Duration{2 * time.Second}}
+	if err := s.Validate(); err != nil {
+		t.Errorf("expected http health check over a unix socket to pass, got: %v", err)
+	}
+	s.Health.UnixSocket = "api.sock"
+	if err := s.Validate(); err == nil {
+		t.Error("expected error for relative unix_socket")
+	}
+	s.Health.UnixSocket, s.Health.Port = "/tmp/api.sock", 8080
+	if err := s.Validate(); err == nil {
+		t.Error("expected error for unix_socket with port")
+	}
+	s.Health.Port = 0
+	s.Network, s.Routing = &Network{Port: 0}, &Routing{Hostname: "api.example.local"}
+	if err := s.Validate(); err == nil {
+		t.Error("expected error for unix_socket on a routed service with a dynamic port, which deploys blue-green")
+	}
+	s = base
+	s.Health = &HealthCheck{Type: "exec", Command: "true", UnixSocket: "/tmp/api.sock", Interval: Duration{10 * time.Second}, Timeout: Duration{2 * time.Second}}
+	if err := s.Validate(); err == nil {
+		t.Error("expected error for unix_socket on an exec health check")
+	}
+
+	// docker delegates to the image's HEALTHCHECK, so only containers have one
+	s = base
+	s.Health = &HealthCheck{Type: "docker", Interval: Duration{10 * time.Second}, Timeout: Duration{2 * time.Second}}
+	if err := s.Validate(); err == nil {
+		t.Error("expected error for docker health check on a native service")
+	}
+
+	s.Service = Service{Name: "test", Type: "container", Image: "nginx:latest"}
+	if err := s.Validate(); err != nil {
+		t.Errorf("expected docker health check on a container to pass, got: %v", err)
+	}
+
+	// jitter is a percentage of the interval, capped at 50
+	s = base
+	s.Health = &HealthCheck{Type: "tcp", Interval: Duration{10 * time.Second}, Timeout: Duration{2 * time.Second}, Jitter: 20}
+	if err := s.Validate(); err != nil {
+		t.Errorf("expected jitter 20 to pass, got: %v", err)
+	}
+	for _, j := range []int{-1, 51} {
+		s.Health.Jitter = j
+		if err := s.Validate(); err == nil {
+			t.Errorf("expected error for jitter %d", j)
+		}
+	}
+
+	// a startup probe needs a limit and no negative values
+	s = base
+	s.Health = &HealthCheck{Type: "tcp", Interval: Duration{10 * time.Second}, Timeout: Duration{2 * time.Second}}
+	s.Health.Startup = &StartupProbe{MaxDuration: Duration{5 * time.Minute}}
+	if err := s.Validate(); err != nil {
+		t.Errorf("expected a startup probe with max_duration to pass, got: %v", err)
+	}
+	for _, st := range []StartupProbe{
+		{},
+		{Interval: Duration{time.Second}},
+		{Threshold: -1, MaxDuration: Duration{time.Minute}},
+		{Threshold: 10, Timeout: Duration{-time.Second}},
+	} {
+		s.Health.Startup = &st
+		if err := s.Validate(); err == nil {
+			t.Errorf("expected error for startup probe %+v", st)
+		}
+	}
 }
 
 func TestValidateRestartPolicy(t *testing.T) {
@@ -471,212 +805,976 @@ func TestValidateRestartPolicy(t *testing.T) {
 		t.Error("expected error for invalid restart policy")
 	}
 
+	s = base
+	s.Restart = &RestartPolicy{Policy: "unless-stopped"}
+	if err := s.Validate(); err != nil {
+		t.Errorf("expected unless-stopped policy to pass, got: %v", err)
+	}
+
+	s = base
+	s.Restart = &RestartPolicy{Policy: "always", MaxPerWindow: 5, Window: Duration{Duration: time.Minute}}
+	if err := s.Validate(); err != nil {
+		t.Errorf("expected restart rate limit to pass, got: %v", err)
+	}
+
+	s = base
+	s.Restart = &RestartPolicy{Policy: "always", MaxPerWindow: -1}
+	if err := s.Validate(); err == nil {
+		t.Error("expected error for negative max_per_window")
+	}
+
+	s = base
+	s.Restart = &RestartPolicy{Policy: "always", Window: Duration{Duration: time.Minute}}
+	if err := s.Validate(); err == nil {
+		t.Error("expected error for window without max_per_window")
+	}
+
 	s = base
 	s.Restart = &RestartPolicy{Policy: "always", Backoff: "invalid"}
 	if err := s.Validate(); err == nil {
 		t.Error("expected error for invalid backoff type")
 	}
+
+	s = base
+	s.Restart = &RestartPolicy{Policy: "always", Jitter: "random"}
+	if err := s.Validate(); err == nil {
+		t.Error("expected error for invalid jitter mode")
+	}
+
+	s = base
+	s.Restart = &RestartPolicy{Policy: "always", InitialDelay: &Duration{Duration: -time.Second}}
+	if err := s.Validate(); err == nil {
+		t.Error("expected error for negative initial_delay")
+	}
+}
+
+func TestValidateReplicas(t *testing.T) {
+	t.Parallel()
+	base := ServiceSpec{
+		Service: Service{Name: "test", Type: "native", Command: "echo", Replicas: 2},
+		Network: &Network{Port: 0},
+	}
+
+	s := base
+	if err := s.Validate(); err != nil {
+		t.Errorf("expected replicas on a dynamic port to pass, got: %v", err)
+	}
+	if n := s.ReplicaCount(); n != 2 {
+		t.Errorf("ReplicaCount() = %d, want 2", n)
+	}
+
+	s = base
+	s.Service.Replicas = 0
+	if n := s.ReplicaCount(); n != 1 {
+		t.Errorf("ReplicaCount() with replicas unset = %d, want 1", n)
+	}
+
+	s = base
+	s.Service.Replicas = -1
+	if err := s.Validate(); err == nil {
+		t.Error("expected error for negative replicas")
+	}
+
+	s = base
+	s.Network = &Network{Port: 8080}
+	if err := s.Validate(); err == nil {
+		t.Error("expected error for replicas on a fixed port")
+	}
+
+	s = base
+	s.Health = &HealthCheck{Type: "tcp", Port: 9000}
+	if err := s.Validate(); err == nil {
+		t.Error("expected error for replicas sharing a health port")
+	}
+
+	s = base
+	s.Restart = &RestartPolicy{Policy: "oneshot"}
+	if err := s.Validate(); err == nil {
+		t.Error("expected error for replicated oneshot service")
+	}
+}
+
+func TestValidateAutoscale(t *testing.T) {
+	t.Parallel()
+	base := ServiceSpec{
+		Service:   Service{Name: "test", Type: "native", Command: "echo"},
+		Network:   &Network{Port: 0},
+		Autoscale: &Autoscale{Min: 2, Max: 5, TargetCPUPercent: 70},
+	}
+
+	s := base
+	if err := s.Validate(); err != nil {
+		t.Errorf("expected autoscale to pass, got: %v", err)
+	}
+	if n := s.ReplicaCount(); n != 2 {
+		t.Errorf("ReplicaCount() = %d, want autoscale.min 2", n)
+	}
+	if n := s.MaxReplicas(); n != 5 {
+		t.Errorf("MaxReplicas() = %d, want 5", n)
+	}
+	if d := s.Autoscale.CooldownOrDefault(); d != DefaultAutoscaleCooldown {
+		t.Errorf("CooldownOrDefault() = %v, want %v", d, DefaultAutoscaleCooldown)
+	}
+
+	s = base
+	s.Service.Replicas = 3
+	if n := s.ReplicaCount(); n != 3 {
+		t.Errorf("ReplicaCount() with replicas set = %d, want 3", n)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(*ServiceSpec)
+	}{
+		{"zero min", func(s *ServiceSpec) { s.Autoscale.Min = 0 }},
+		{"max below min", func(s *ServiceSpec) { s.Autoscale.Max = 1 }},
+		{"no target", func(s *ServiceSpec) { s.Autoscale.TargetCPUPercent = 0 }},
+		{"negative cooldown", func(s *ServiceSpec) { s.Autoscale.Cooldown = Duration{Duration: -time.Second} }},
+		{"replicas out of range", func(s *ServiceSpec) { s.Service.Replicas = 6 }},
+		{"fixed port", func(s *ServiceSpec) { s.Network = &Network{Port: 8080} }},
+		{"container", func(s *ServiceSpec) {
+			s.Service.Type = "container"
+			s.Service.Command = ""
+			s.Service.Image = "nginx:latest"
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := base
+			a := *base.Autoscale
+			s.Autoscale = &a
+			tt.modify(&s)
+			if err := s.Validate(); err == nil {
+				t.Error("expected validation error")
+			}
+		})
+	}
+}
+
+func TestValidateRoutingRequiresHostname(t *testing.T) {
+	t.Parallel()
+	spec := &ServiceSpec{
+		Service: Service{Name: "test", Type: "native", Command: "echo"},
+		Network: &Network{Port: 8080},
+		Routing: &Routing{TLS: true},
+	}
+	if err := spec.Validate(); err == nil {
+		t.Error("expected error for routing without hostname")
+	}
+}
+
+func TestValidateRoutingRequiresPort(t *testing.T) {
+	t.Parallel()
+	spec := &ServiceSpec{
+		Service: Service{Name: "test", Type: "native", Command: "echo"},
+		Routing: &Routing{Hostname: "test.example.local"},
+	}
+	if err := spec.Validate(); err == nil {
+		t.Error("expected error for routing without port")
+	}
+}
+
+func TestValidateRoutingAcceptsHealthPort(t *testing.T) {
+	t.Parallel()
+	spec := &ServiceSpec{
+		Service: Service{Name: "test", Type: "native", Command: "echo"},
+		Health:  &HealthCheck{Type: "http", Path: "/health", Port: 8080, Interval: Duration{10 * time.Second}, Timeout: Duration{2 * time.Second}},
+		Routing: &Routing{Hostname: "test.example.local"},
+	}
+	if err := spec.Validate(); err != nil {
+		t.Errorf("routing with health port should be valid: %v", err)
+	}
+}
+
+func TestValidateRoutingWithTLSOptions(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "signal.yaml")
+	data := `
+service:
+  name: signal-api
+  type: container
+  image: signal:latest
+
+network:
+  port: 8093
+
+routing:
+  hostname: signal-api.example.local
+  tls: true
+  tls_options: mtls
+`
+	os.WriteFile(path, []byte(data), 0644)
+
+	spec, err := Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if spec.Routing.TLSOptions != "mtls" {
+		t.Errorf("expected tls_options 'mtls', got %q", spec.Routing.TLSOptions)
+	}
+}
+
+func TestValidateRequiresMustBeInAfter(t *testing.T) {
+	t.Parallel()
+	spec := &ServiceSpec{
+		Service: Service{Name: "test", Type: "native", Command: "echo"},
+		Dependencies: &Dependencies{
+			After:    []string{"postgres"},
+			Requires: []string{"redis"}, // not in after
+		},
+	}
+	if err := spec.Validate(); err == nil {
+		t.Error("expected error when requires has entry not in after")
+	}
+}
+
+func TestValidateDependencyBefore(t *testing.T) {
+	t.Parallel()
+	deps := &Dependencies{Before: []string{"api", "worker"}}
+	s := &ServiceSpec{
+		Service:      Service{Name: "postgres", Type: "native", Command: "echo"},
+		Dependencies: deps,
+	}
+	if err := s.Validate(); err != nil {
+		t.Fatalf("expected before to pass, got: %v", err)
+	}
+
+	deps.After = []string{"api"}
+	if err := s.Validate(); err == nil {
+		t.Error("expected error for a service in both before and after")
+	}
+
+	deps.After = nil
+	deps.Before = []string{"postgres"}
+	if err := s.Validate(); err == nil {
+		t.Error("expected error for a service before itself")
+	}
+}
+
+func TestValidateDependencyConditions(t *testing.T) {
+	t.Parallel()
+	deps := &Dependencies{
+		After:      []string{"postgres", "redis"},
+		Requires:   []string{"postgres"},
+		Conditions: map[string]string{"postgres": "started", "redis": "healthy"},
+	}
+	s := &ServiceSpec{
+		Service:      Service{Name: "test", Type: "native", Command: "echo"},
+		Dependencies: deps,
+	}
+	if err := s.Validate(); err != nil {
+		t.Fatalf("expected valid conditions to pass, got: %v", err)
+	}
+	if c := deps.Condition("postgres"); c != ConditionStarted {
+		t.Errorf("expected explicit started condition, got %q", c)
+	}
+
+	deps.Conditions = nil
+	if c := deps.Condition("postgres"); c != ConditionHealthy {
+		t.Errorf("expected required dependency to default to healthy, got %q", c)
+	}
+	if c := deps.Condition("redis"); c != ConditionStarted {
+		t.Errorf("expected after-only dependency to default to started, got %q", c)
+	}
+
+	deps.Conditions = map[string]string{"postgres": "ready"}
+	if err := s.Validate(); err == nil {
+		t.Error("expected error for unknown condition")
+	}
+
+	deps.Conditions = map[string]string{"mysql": "healthy"}
+	if err := s.Validate(); err == nil {
+		t.Error("expected error for condition on a dependency not in after")
+	}
+}
+
+func TestValidateContainerNetworkMode(t *testing.T) {
+	t.Parallel()
+
+	validModes := []string{"host", "bridge", "none", "macvlan", "overlay", "my-network", "custom_net.1"}
+	for _, mode := range validModes {
+		mode := mode
+		t.Run("valid_"+mode, func(t *testing.T) {
+			t.Parallel()
+			spec := &ServiceSpec{
+				Service: Service{Name: "test", Type: "container", Image: "foo:bar", NetworkMode: mode},
+			}
+			if err := spec.Validate(); err != nil {
+				t.Errorf("expected network_mode %q to be valid, got: %v", mode, err)
+			}
+		})
+	}
+
+	t.Run("empty network_mode is valid", func(t *testing.T) {
+		t.Parallel()
+		spec := &ServiceSpec{
+			Service: Service{Name: "test", Type: "container", Image: "foo:bar"},
+		}
+		if err := spec.Validate(); err != nil {
+			t.Errorf("expected empty network_mode to be valid, got: %v", err)
+		}
+	})
+
+	invalidModes := []string{"../escape", "-dash", ".dot", "has space", "semi;colon"}
+	for _, mode := range invalidModes {
+		mode := mode
+		t.Run("invalid_"+mode, func(t *testing.T) {
+			t.Parallel()
+			spec := &ServiceSpec{
+				Service: Service{Name: "test", Type: "container", Image: "foo:bar", NetworkMode: mode},
+			}
+			if err := spec.Validate(); err == nil {
+				t.Errorf("expected validation error for network_mode %q", mode)
+			}
+		})
+	}
+}
+
+func TestNeedsDynamicPort(t *testing.T) {
+	t.Parallel()
+	// No network block
+	s := &ServiceSpec{Service: Service{Name: "test", Type: "native", Command: "echo"}}
+	if s.NeedsDynamicPort() {
+		t.Error("expected false when no network block")
+	}
+
+	// Static port
+	s.Network = &Network{Port: 8080}
+	if s.NeedsDynamicPort() {
+		t.Error("expected false for static port")
+	}
+
+	// Dynamic port (port 0)
+	s.Network = &Network{Port: 0}
+	if !s.NeedsDynamicPort() {
+		t.Error("expected true for port 0")
+	}
+}
+
+func TestValidateRoutingAllowsDynamicPort(t *testing.T) {
+	t.Parallel()
+	s := &ServiceSpec{
+		Service: Service{Name: "test", Type: "native", Command: "echo"},
+		Network: &Network{Port: 0},
+		Routing: &Routing{Hostname: "test.example.local"},
+	}
+	if err := s.Validate(); err != nil {
+		t.Errorf("routing with dynamic port (0) should be valid: %v", err)
+	}
+}
+
+func TestLoadDir(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+
+	chat := `
+service:
+  name: chat
+  type: container
+  image: chat:prod
+
+health:
+  type: http
+  path: /health
+  interval: 10s
+  timeout: 2s
+`
+	ollama := `
+service:
+  name: ollama
+  type: native
+  command: /usr/local/bin/ollama serve
+
+health:
+  type: http
+  path: /
+  interval: 15s
+  timeout: 3s
+`
+	os.WriteFile(filepath.Join(dir, "chat.yaml"), []byte(chat), 0644)
+	os.WriteFile(filepath.Join(dir, "ollama.yml"), []byte(ollama), 0644)
+
+	specs, err := LoadDir(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(specs) != 2 {
+		t.Fatalf("expected 2 specs, got %d", len(specs))
+	}
+
+	names := map[string]bool{}
+	for _, s := range specs {
+		names[s.Service.Name] = true
+	}
+	if !names["chat"] || !names["ollama"] {
+		t.Errorf("expected chat and ollama, got %v", names)
+	}
+}
+
+func TestLoadDirDuplicateName(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+
+	chat := `
+service:
+  name: chat
+  type: native
+  command: sleep 30
+`
+	os.WriteFile(filepath.Join(dir, "chat.yaml"), []byte(chat), 0644)
+	os.WriteFile(filepath.Join(dir, "chat-copy.yaml"), []byte(chat), 0644)
+
+	_, err := LoadDir(dir)
+	if err == nil {
+		t.Fatal("expected duplicate service name error")
+	}
+	msg := err.Error()
+	if !strings.Contains(msg, `duplicate service name "chat"`) {
+		t.Errorf("expected duplicate name in error, got %q", msg)
+	}
+	for _, f := range []string{"chat.yaml", "chat-copy.yaml"} {
+		if !strings.Contains(msg, filepath.Join(dir, f)) {
+			t.Errorf("expected error to mention %s, got %q", f, msg)
+		}
+	}
+}
+
+const multiDocSpecs = `
+service:
+  name: chat
+  type: native
+  command: sleep 30
+
+network:
+  port: 0
+---
+service:
+  name: ollama
+  type: external
+
+health:
+  type: tcp
+  port: 11434
+  interval: 10s
+  timeout: 2s
+`
+
+func TestLoadFileMultiDocument(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "services.yaml")
+	os.WriteFile(path, []byte(multiDocSpecs), 0644)
+
+	specs, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(specs) != 2 {
+		t.Fatalf("expected 2 specs, got %d", len(specs))
+	}
+	if specs[0].Service.Name != "chat" || !specs[0].NeedsDynamicPort() {
+		t.Errorf("first spec = %+v, want chat with a dynamic port", specs[0].Service)
+	}
+	if specs[1].Service.Name != "ollama" || specs[1].Health == nil || specs[1].Health.Port != 11434 {
+		t.Errorf("second spec = %+v, want ollama with its health check", specs[1].Service)
+	}
+
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "declares 2 services") {
+		t.Errorf("expected Load to reject a multi-service file, got %v", err)
+	}
+}
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, DefaultsFile), []byte("restart:\n  policy: always\n"), 0644)
+
+	s, err := Parse([]byte("service:\n  name: api\n  type: native\n  command: sleep 30\n"), dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s.Restart == nil || s.Restart.Policy != "always" {
+		t.Errorf("expected defaults merged from dir, got restart %+v", s.Restart)
+	}
+
+	// JSON is valid YAML
+	if _, err := Parse([]byte(`{"service": {"name": "api", "type": "native", "command": "sleep 30"}}`), dir); err != nil {
+		t.Errorf("JSON spec: unexpected error: %v", err)
+	}
+
+	if _, err := Parse([]byte(multiDocSpecs), dir); err == nil || !strings.Contains(err.Error(), "declares 2 services") {
+		t.Errorf("expected error for several services, got %v", err)
+	}
+	if _, err := Parse([]byte("service:\n  name: api\n  type: native\n"), dir); err == nil || !strings.HasPrefix(err.Error(), "validating spec: ") {
+		t.Errorf("expected validation error, got %v", err)
+	}
+}
+
+func TestParseEnabled(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+
+	s, err := Parse([]byte("service:\n  name: api\n  type: native\n  command: sleep 30\n"), dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !s.IsEnabled() {
+		t.Error("expected a spec without enabled to be enabled")
+	}
+
+	s, err = Parse([]byte("enabled: false\nservice:\n  name: api\n  type: native\n  command: sleep 30\n"), dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s.IsEnabled() {
+		t.Error("expected enabled: false to disable the service")
+	}
+}
+
+func TestParseByteSize(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		in   string
+		want ByteSize
+	}{
+		{"8GB", 8 << 30},
+		{"8gb", 8 << 30},
+		{"8GiB", 8 << 30},
+		{"1.5GB", 3 << 29},
+		{"512MB", 512 << 20},
+		{"2 TB", 2 << 40},
+		{"1024", 1024},
+	}
+	for _, tt := range tests {
+		got, err := ParseByteSize(tt.in)
+		if err != nil {
+			t.Errorf("ParseByteSize(%q): %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseByteSize(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+	for _, in := range []string{"", "GB", "8XB", "-1GB"} {
+		if _, err := ParseByteSize(in); err == nil {
+			t.Errorf("ParseByteSize(%q): expected error", in)
+		}
+	}
+	if s := ByteSize(8 << 30).String(); s != "8GB" {
+		t.Errorf("String() = %q, want 8GB", s)
+	}
+	if s := ByteSize(3 << 29).String(); s != "1536MB" {
+		t.Errorf("String() = %q, want 1536MB", s)
+	}
+}
+
+func TestParseGPURequirement(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+
+	s, err := Parse([]byte("service:\n  name: llm\n  type: native\n  command: sleep 30\ngpu:\n  requires_vram: 8GB\n"), dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s.GPU == nil || s.GPU.RequiresVRAM != 8<<30 {
+		t.Errorf("expected requires_vram of 8GB, got %+v", s.GPU)
+	}
+
+	if _, err := Parse([]byte("service:\n  name: llm\n  type: native\n  command: sleep 30\ngpu:\n  requires_vram: lots\n"), dir); err == nil {
+		t.Error("expected error for invalid size")
+	}
+	if _, err := Parse([]byte("service:\n  name: llm\n  type: native\n  command: sleep 30\ngpu: {}\n"), dir); err == nil {
+		t.Error("expected error for gpu block without requires_vram")
+	}
+	if _, err := Parse([]byte("service:\n  name: llm\n  type: external\nhealth:\n  type: tcp\n  port: 80\n  interval: 1s\n  timeout: 1s\ngpu:\n  requires_vram: 8GB\n"), dir); err == nil {
+		t.Error("expected error for gpu on an external service")
+	}
+}
+
+func TestParseMounts(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	base := "service:\n  name: web\n  type: container\n  image: nginx:latest\n"
+
+	s, err := Parse([]byte(base+"volumes:\n  /srv/web: /data:ro\n  web-cache: /cache\ntmpfs:\n  - path: /scratch\n    size: 64MB\n  - path: /run\n"), dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(s.Tmpfs) != 2 || s.Tmpfs[0] != (Tmpfs{Path: "/scratch", Size: 64 << 20}) || s.Tmpfs[1] != (Tmpfs{Path: "/run"}) {
+		t.Errorf("unexpected tmpfs: %+v", s.Tmpfs)
+	}
+	if IsNamedVolume("/srv/web") || !IsNamedVolume("web-cache") {
+		t.Error("expected /srv/web to be a bind mount and web-cache a named volume")
+	}
+
+	for name, body := range map[string]string{
+		"tmpfs size":          "tmpfs:\n  - path: /scratch\n    size: lots\n",
+		"tmpfs relative path": "tmpfs:\n  - path: scratch\n",
+		"tmpfs duplicate":     "tmpfs:\n  - path: /scratch\n  - path: /scratch\n",
+		"volume name":         "volumes:\n  web cache: /cache\n",
+		"volume target":       "volumes:\n  web-cache: cache\n",
+		"volume mode":         "volumes:\n  web-cache: /cache:rx\n",
+	} {
+		if _, err := Parse([]byte(base+body), dir); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+	if _, err := Parse([]byte("service:\n  name: web\n  type: native\n  command: sleep 30\ntmpfs:\n  - path: /scratch\n"), dir); err == nil {
+		t.Error("expected error for tmpfs on a native service")
+	}
+}
+
+func TestParseDockerSecrets(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	base := "service:\n  name: web\n  type: container\n  image: nginx:latest\n"
+
+	s, err := Parse([]byte(base+"docker_secrets:\n  db_password:\n    secret: web/db-password\n  api.key:\n    keychain: web/api-key\n"), dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s.DockerSecrets["db_password"].Key() != "web/db-password" || s.DockerSecrets["api.key"].Key() != "web/api-key" {
+		t.Errorf("unexpected docker_secrets: %+v", s.DockerSecrets)
+	}
+
+	for name, body := range map[string]string{
+		"slash":     "docker_secrets:\n  db/password:\n    secret: web/db\n",
+		"dotdot":    "docker_secrets:\n  ..:\n    secret: web/db\n",
+		"hidden":    "docker_secrets:\n  .env:\n    secret: web/db\n",
+		"empty ref": "docker_secrets:\n  db_password: {}\n",
+	} {
+		if _, err := Parse([]byte(base+body), dir); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+	if _, err := Parse([]byte("service:\n  name: web\n  type: native\n  command: sleep 30\ndocker_secrets:\n  db_password:\n    secret: web/db\n"), dir); err == nil {
+		t.Error("expected error for docker_secrets on a native service")
+	}
+}
+
+func TestParseSecurity(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	base := "service:\n  name: web\n  type: container\n  image: nginx:latest\n"
+
+	s, err := Parse([]byte(base+"security:\n  read_only: true\n  cap_drop: [ALL]\n  cap_add: [net_bind_service, CAP_CHOWN]\n  no_new_privileges: true\n"), dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sec := s.Security; sec == nil || !sec.ReadOnly || !sec.NoNewPrivileges || len(sec.CapDrop) != 1 || len(sec.CapAdd) != 2 {
+		t.Errorf("unexpected security: %+v", s.Security)
+	}
+
+	if _, err := Parse([]byte(base+"security:\n  cap_drop: [NET_WIZARD]\n"), dir); err == nil {
+		t.Error("expected error for an unknown capability")
+	}
+	if _, err := Parse([]byte(base+"security:\n  cap_add: [\"\"]\n"), dir); err == nil {
+		t.Error("expected error for an empty capability")
+	}
+	if _, err := Parse([]byte("service:\n  name: web\n  type: native\n  command: sleep 30\nsecurity:\n  read_only: true\n"), dir); err == nil {
+		t.Error("expected error for security on a native service")
+	}
 }
 
-func TestValidateRoutingRequiresHostname(t *testing.T) {
+func TestLoadFileValidatesEachDocument(t *testing.T) {
 	t.Parallel()
-	spec := &ServiceSpec{
-		Service: Service{Name: "test", Type: "native", Command: "echo"},
-		Network: &Network{Port: 8080},
-		Routing: &Routing{TLS: true},
+	dir := t.TempDir()
+	path := filepath.Join(dir, "services.yaml")
+	// The second document is missing its command
+	os.WriteFile(path, []byte("service:\n  name: chat\n  type: native\n  command: sleep 30\n---\nservice:\n  name: worker\n  type: native\n"), 0644)
+
+	_, err := LoadFile(path)
+	if err == nil {
+		t.Fatal("expected validation error for the second document")
 	}
-	if err := spec.Validate(); err == nil {
-		t.Error("expected error for routing without hostname")
+	if !strings.Contains(err.Error(), "(document 2)") {
+		t.Errorf("expected error to name document 2, got %q", err)
 	}
 }
 
-func TestValidateRoutingRequiresPort(t *testing.T) {
+func TestLoadDirs(t *testing.T) {
 	t.Parallel()
-	spec := &ServiceSpec{
-		Service: Service{Name: "test", Type: "native", Command: "echo"},
-		Routing: &Routing{Hostname: "test.example.local"},
+	base, site := t.TempDir(), t.TempDir()
+	write := func(dir, name, command string) {
+		t.Helper()
+		content := fmt.Sprintf("service:\n  name: %s\n  type: native\n  command: %s\n", name, command)
+		if err := os.WriteFile(filepath.Join(dir, name+".yaml"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
 	}
-	if err := spec.Validate(); err == nil {
-		t.Error("expected error for routing without port")
+	write(base, "api", "sleep 10")
+	write(base, "worker", "sleep 10")
+	write(site, "cache", "sleep 20")
+
+	specs, err := LoadDirs([]string{base, site})
+	if err != nil {
+		t.Fatalf("LoadDirs: %v", err)
+	}
+	var names []string
+	for _, s := range specs {
+		names = append(names, s.Service.Name)
+	}
+	slices.Sort(names)
+	if !slices.Equal(names, []string{"api", "cache", "worker"}) {
+		t.Errorf("expected the union of both directories, got %v", names)
 	}
-}
 
-func TestValidateRoutingAcceptsHealthPort(t *testing.T) {
-	t.Parallel()
-	spec := &ServiceSpec{
-		Service: Service{Name: "test", Type: "native", Command: "echo"},
-		Health:  &HealthCheck{Type: "http", Path: "/health", Port: 8080, Interval: Duration{10 * time.Second}, Timeout: Duration{2 * time.Second}},
-		Routing: &Routing{Hostname: "test.example.local"},
+	// The same name in both directories is an error naming them
+	write(site, "worker", "sleep 20")
+	_, err = LoadDirs([]string{base, site})
+	if err == nil || !strings.Contains(err.Error(), `duplicate service name "worker"`) ||
+		!strings.Contains(err.Error(), base) || !strings.Contains(err.Error(), site) {
+		t.Fatalf("expected duplicate error naming both directories, got %v", err)
 	}
-	if err := spec.Validate(); err != nil {
-		t.Errorf("routing with health port should be valid: %v", err)
+
+	// unless the later directory is allowed to override
+	specs, err = LoadDirs([]string{base, site}, OverrideDuplicates())
+	if err != nil {
+		t.Fatalf("LoadDirs with override: %v", err)
+	}
+	if len(specs) != 3 {
+		t.Fatalf("expected 3 specs, got %d", len(specs))
+	}
+	for _, s := range specs {
+		if s.Service.Name == "worker" && s.Service.Command != "sleep 20" {
+			t.Errorf("expected worker from the later directory, got command %q", s.Service.Command)
+		}
 	}
 }
 
-func TestValidateRoutingWithTLSOptions(t *testing.T) {
+func TestLoadDirMultiDocument(t *testing.T) {
 	t.Parallel()
 	dir := t.TempDir()
-	path := filepath.Join(dir, "signal.yaml")
-	data := `
-service:
-  name: signal-api
-  type: container
-  image: signal:latest
-
-network:
-  port: 8093
-
-routing:
-  hostname: signal-api.example.local
-  tls: true
-  tls_options: mtls
-`
-	os.WriteFile(path, []byte(data), 0644)
+	os.WriteFile(filepath.Join(dir, "services.yaml"), []byte(multiDocSpecs+"---\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "worker.yaml"), []byte("service:\n  name: worker\n  type: native\n  command: sleep 30\n"), 0644)
 
-	spec, err := Load(path)
+	specs, err := LoadDir(dir)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
-	if spec.Routing.TLSOptions != "mtls" {
-		t.Errorf("expected tls_options 'mtls', got %q", spec.Routing.TLSOptions)
+	var names []string
+	for _, s := range specs {
+		names = append(names, s.Service.Name)
+	}
+	slices.Sort(names)
+	if want := []string{"chat", "ollama", "worker"}; !slices.Equal(names, want) {
+		t.Errorf("names = %v, want %v", names, want)
 	}
 }
 
-func TestValidateRequiresMustBeInAfter(t *testing.T) {
+func TestLoadDirDuplicateNameAcrossDocuments(t *testing.T) {
 	t.Parallel()
-	spec := &ServiceSpec{
-		Service: Service{Name: "test", Type: "native", Command: "echo"},
-		Dependencies: &Dependencies{
-			After:    []string{"postgres"},
-			Requires: []string{"redis"}, // not in after
-		},
+	dir := t.TempDir()
+	chat := "service:\n  name: chat\n  type: native\n  command: sleep 30\n"
+	os.WriteFile(filepath.Join(dir, "services.yaml"), []byte(chat+"---\n"+chat), 0644)
+
+	_, err := LoadDir(dir)
+	if err == nil {
+		t.Fatal("expected duplicate service name error")
 	}
-	if err := spec.Validate(); err == nil {
-		t.Error("expected error when requires has entry not in after")
+	msg := err.Error()
+	if !strings.Contains(msg, `duplicate service name "chat"`) {
+		t.Errorf("expected duplicate name in error, got %q", msg)
+	}
+	for _, doc := range []string{"(document 1)", "(document 2)"} {
+		if !strings.Contains(msg, doc) {
+			t.Errorf("expected error to mention %s, got %q", doc, msg)
+		}
 	}
 }
 
-func TestValidateContainerNetworkMode(t *testing.T) {
+func TestLoadDirDefaultRestart(t *testing.T) {
 	t.Parallel()
+	policy := &RestartPolicy{Policy: "on-failure", MaxAttempts: 3}
 
-	validModes := []string{"host", "bridge", "none", "macvlan", "overlay", "my-network", "custom_net.1"}
-	for _, mode := range validModes {
-		mode := mode
-		t.Run("valid_"+mode, func(t *testing.T) {
-			t.Parallel()
-			spec := &ServiceSpec{
-				Service: Service{Name: "test", Type: "container", Image: "foo:bar", NetworkMode: mode},
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "plain.yaml"), []byte("service:\n  name: plain\n  type: native\n  command: sleep 30\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "own.yaml"), []byte("service:\n  name: own\n  type: native\n  command: sleep 30\nrestart:\n  policy: always\n"), 0644)
+
+	specs, err := LoadDir(dir, DefaultRestart(policy))
+	if err != nil {
+		t.Fatalf("LoadDir: %v", err)
+	}
+	for _, s := range specs {
+		switch s.Service.Name {
+		case "plain":
+			if s.Restart == nil || *s.Restart != *policy {
+				t.Errorf("expected plain to inherit the default restart policy, got %+v", s.Restart)
 			}
-			if err := spec.Validate(); err != nil {
-				t.Errorf("expected network_mode %q to be valid, got: %v", mode, err)
+			if s.Restart == policy {
+				t.Error("expected a copy of the default, not the shared policy")
 			}
-		})
+		case "own":
+			if s.Restart == nil || s.Restart.Policy != "always" || s.Restart.MaxAttempts != 0 {
+				t.Errorf("expected own to keep its restart block, got %+v", s.Restart)
+			}
+		}
 	}
 
-	t.Run("empty network_mode is valid", func(t *testing.T) {
-		t.Parallel()
-		spec := &ServiceSpec{
-			Service: Service{Name: "test", Type: "container", Image: "foo:bar"},
-		}
-		if err := spec.Validate(); err != nil {
-			t.Errorf("expected empty network_mode to be valid, got: %v", err)
-		}
-	})
+	// The directory's defaults file takes precedence over the default
+	os.WriteFile(filepath.Join(dir, DefaultsFile), []byte("restart:\n  policy: never\n"), 0644)
+	s, err := Load(filepath.Join(dir, "plain.yaml"), DefaultRestart(policy))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if s.Restart == nil || s.Restart.Policy != "never" {
+		t.Errorf("expected the defaults file's restart policy, got %+v", s.Restart)
+	}
 
-	invalidModes := []string{"../escape", "-dash", ".dot", "has space", "semi;colon"}
-	for _, mode := range invalidModes {
-		mode := mode
-		t.Run("invalid_"+mode, func(t *testing.T) {
-			t.Parallel()
-			spec := &ServiceSpec{
-				Service: Service{Name: "test", Type: "container", Image: "foo:bar", NetworkMode: mode},
-			}
-			if err := spec.Validate(); err == nil {
-				t.Errorf("expected validation error for network_mode %q", mode)
-			}
-		})
+	// Without the option a spec keeps no restart block
+	s, err = Parse([]byte("service:\n  name: bare\n  type: native\n  command: sleep 30\n"), t.TempDir())
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if s.Restart != nil {
+		t.Errorf("expected no restart block, got %+v", s.Restart)
 	}
 }
 
-func TestNeedsDynamicPort(t *testing.T) {
+func TestLoadDirMergesDefaults(t *testing.T) {
 	t.Parallel()
-	// No network block
-	s := &ServiceSpec{Service: Service{Name: "test", Type: "native", Command: "echo"}}
-	if s.NeedsDynamicPort() {
-		t.Error("expected false when no network block")
+	dir := t.TempDir()
+
+	defaults := `
+restart:
+  policy: always
+  max_attempts: 5
+  delay: 2s
+
+env:
+  LOG_LEVEL: info
+  REGION: local
+`
+	plain := `
+service:
+  name: plain
+  type: native
+  command: sleep 30
+`
+	custom := `
+service:
+  name: custom
+  type: native
+  command: sleep 30
+
+restart:
+  policy: on-failure
+
+env:
+  LOG_LEVEL: debug
+`
+	os.WriteFile(filepath.Join(dir, DefaultsFile), []byte(defaults), 0644)
+	os.WriteFile(filepath.Join(dir, "plain.yaml"), []byte(plain), 0644)
+	os.WriteFile(filepath.Join(dir, "custom.yaml"), []byte(custom), 0644)
+
+	specs, err := LoadDir(dir)
+	if err != nil {
+		t.Fatalf("LoadDir: %v", err)
+	}
+	if len(specs) != 2 {
+		t.Fatalf("expected 2 specs (defaults file is not a service), got %d", len(specs))
+	}
+	byName := make(map[string]*ServiceSpec)
+	for _, s := range specs {
+		byName[s.Service.Name] = s
 	}
 
-	// Static port
-	s.Network = &Network{Port: 8080}
-	if s.NeedsDynamicPort() {
-		t.Error("expected false for static port")
+	// No restart block: inherits the defaults wholesale
+	p := byName["plain"]
+	if p.Restart == nil || p.Restart.Policy != "always" || p.Restart.MaxAttempts != 5 || p.Restart.Delay.Duration != 2*time.Second {
+		t.Errorf("expected inherited restart policy, got %+v", p.Restart)
 	}
 
-	// Dynamic port (port 0)
-	s.Network = &Network{Port: 0}
-	if !s.NeedsDynamicPort() {
-		t.Error("expected true for port 0")
+	// Overrides win per key; unset keys in a map still come from defaults
+	c := byName["custom"]
+	if c.Restart == nil || c.Restart.Policy != "on-failure" || c.Restart.MaxAttempts != 5 {
+		t.Errorf("expected overridden policy with inherited max_attempts, got %+v", c.Restart)
+	}
+	if c.Env["LOG_LEVEL"] != "debug" || c.Env["REGION"] != "local" {
+		t.Errorf("expected merged env, got %v", c.Env)
 	}
-}
 
-func TestValidateRoutingAllowsDynamicPort(t *testing.T) {
-	t.Parallel()
-	s := &ServiceSpec{
-		Service: Service{Name: "test", Type: "native", Command: "echo"},
-		Network: &Network{Port: 0},
-		Routing: &Routing{Hostname: "test.example.local"},
+	// Load on a single file picks up the directory's defaults too, and the
+	// hash reflects the merged spec
+	single, err := Load(filepath.Join(dir, "plain.yaml"))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
 	}
-	if err := s.Validate(); err != nil {
-		t.Errorf("routing with dynamic port (0) should be valid: %v", err)
+	if single.Hash() != p.Hash() {
+		t.Error("expected Load and LoadDir to produce the same merged spec")
+	}
+	os.Remove(filepath.Join(dir, DefaultsFile))
+	bare, err := Load(filepath.Join(dir, "plain.yaml"))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if bare.Hash() == p.Hash() {
+		t.Error("expected hash to change when defaults no longer apply")
 	}
 }
 
-func TestLoadDir(t *testing.T) {
+func TestLoadDirRejectsDefaultServiceName(t *testing.T) {
 	t.Parallel()
 	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, DefaultsFile), []byte("service:\n  name: shared\n  type: native\n"), 0644)
 
-	chat := `
-service:
-  name: chat
-  type: container
-  image: chat:prod
+	if _, err := LoadDir(dir); err == nil {
+		t.Error("expected error for service.name in defaults")
+	}
+}
 
-health:
-  type: http
-  path: /health
-  interval: 10s
-  timeout: 2s
-`
-	ollama := `
-service:
-  name: ollama
-  type: native
-  command: /usr/local/bin/ollama serve
+func TestLoadRejectsUnknownField(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "chat.yaml")
+	os.WriteFile(path, []byte("service:\n  name: chat\n  type: native\n  comand: ./chat\n"), 0644)
 
-health:
-  type: http
-  path: /
-  interval: 15s
-  timeout: 3s
-`
-	os.WriteFile(filepath.Join(dir, "chat.yaml"), []byte(chat), 0644)
-	os.WriteFile(filepath.Join(dir, "ollama.yml"), []byte(ollama), 0644)
+	_, err := Load(path)
+	if err == nil {
+		t.Fatal("expected error for misspelled key")
+	}
+	for _, want := range []string{`unknown field "comand"`, "line 4", path} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not contain %q", err, want)
+		}
+	}
+	if strings.Contains(err.Error(), "command required") {
+		t.Errorf("error %q should report the unknown field, not the missing command", err)
+	}
+}
 
-	specs, err := LoadDir(dir)
+func TestLoadAllowUnknownFields(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "chat.yaml")
+	os.WriteFile(path, []byte("service:\n  name: chat\n  type: native\n  command: ./chat\n  future_option: true\n"), 0644)
+
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), `unknown field "future_option"`) {
+		t.Errorf("expected unknown field error by default, got %v", err)
+	}
+	s, err := Load(path, AllowUnknownFields())
 	if err != nil {
-		t.Fatalf("unexpected error: %v", err)
+		t.Fatalf("unexpected error with AllowUnknownFields: %v", err)
 	}
-
-	if len(specs) != 2 {
-		t.Fatalf("expected 2 specs, got %d", len(specs))
+	if s.Service.Command != "./chat" {
+		t.Errorf("command = %q, want ./chat", s.Service.Command)
 	}
+}
 
-	names := map[string]bool{}
-	for _, s := range specs {
-		names[s.Service.Name] = true
+func TestLoadDirRejectsUnknownDefaultsField(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, DefaultsFile), []byte("env:\n  LOG_LEVEL: info\nlabelz:\n  team: infra\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "chat.yaml"), []byte("service:\n  name: chat\n  type: native\n  command: ./chat\n"), 0644)
+
+	_, err := LoadDir(dir)
+	if err == nil || !strings.Contains(err.Error(), `unknown field "labelz"`) || !strings.Contains(err.Error(), DefaultsFile) {
+		t.Errorf("expected unknown field error naming the defaults file, got %v", err)
 	}
-	if !names["chat"] || !names["ollama"] {
-		t.Errorf("expected chat and ollama, got %v", names)
+	if _, err := LoadDir(dir, AllowUnknownFields()); err != nil {
+		t.Errorf("unexpected error with AllowUnknownFields: %v", err)
 	}
 }
 
@@ -1108,3 +2206,56 @@ func TestValidateContainerServiceAllowsArgs(t *testing.T) {
 		t.Errorf("expected container args to be valid, got: %v", err)
 	}
 }
+
+func TestValidateLifecycle(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name      string
+		lifecycle *Lifecycle
+		wantErr   string
+	}{
+		{"pre_stop only", &Lifecycle{PreStop: "curl -X POST localhost/deregister"}, ""},
+		{"pre_stop with timeout", &Lifecycle{PreStop: "true", PreStopTimeout: Duration{5 * time.Second}}, ""},
+		{"negative timeout", &Lifecycle{PreStop: "true", PreStopTimeout: Duration{-time.Second}}, "must not be negative"},
+		{"timeout without command", &Lifecycle{PreStopTimeout: Duration{5 * time.Second}}, "requires lifecycle.pre_stop"},
+		{"post_start required", &Lifecycle{PostStart: "./warm-cache", PostStartRequired: true}, ""},
+		{"negative post_start timeout", &Lifecycle{PostStart: "true", PostStartTimeout: Duration{-time.Second}}, "must not be negative"},
+		{"post_start_required without command", &Lifecycle{PostStartRequired: true}, "require lifecycle.post_start"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			s := &ServiceSpec{
+				Service:   Service{Name: "test", Type: "native", Command: "sleep 1"},
+				Lifecycle: tt.lifecycle,
+			}
+			err := s.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("expected valid, got: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateExternalServiceRejectsLifecycle(t *testing.T) {
+	t.Parallel()
+	s := &ServiceSpec{
+		Service: Service{Name: "ext", Type: "external"},
+		Health: &HealthCheck{
+			Type:     "tcp",
+			Port:     5432,
+			Interval: Duration{10 * time.Second},
+			Timeout:  Duration{2 * time.Second},
+		},
+		Lifecycle: &Lifecycle{PreStop: "true"},
+	}
+	if err := s.Validate(); err == nil {
+		t.Error("expected error for external service with lifecycle")
+	}
+}
@@ -0,0 +1,48 @@
+package spec
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// unknownFieldPattern matches yaml.v3's report of a key with no matching
+// struct field, e.g. "line 4: field comand not found in type spec.Service".
+var unknownFieldPattern = regexp.MustCompile(`^line (\d+): field (.+) not found in type \S+$`)
+
+// checkKnownFields decodes each document in data as a service spec and
+// reports any key that matches no spec field, naming the key and its line.
+func checkKnownFields(data []byte) error {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+
+	var err error
+	for err == nil {
+		var s ServiceSpec
+		err = dec.Decode(&s)
+	}
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+
+	var typeErr *yaml.TypeError
+	if !errors.As(err, &typeErr) {
+		return err
+	}
+	var unknown []string
+	for _, msg := range typeErr.Errors {
+		if m := unknownFieldPattern.FindStringSubmatch(msg); m != nil {
+			unknown = append(unknown, fmt.Sprintf("line %s: unknown field %q", m[1], m[2]))
+		}
+	}
+	if len(unknown) == 0 {
+		// Type mismatches are reported by the regular decode
+		return nil
+	}
+	return errors.New(strings.Join(unknown, "; "))
+}
@@ -0,0 +1,184 @@
+package sysinfo
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ProcessUsage is a process's resource use.
+type ProcessUsage struct {
+	CPUPercent  float64 `json:"cpu_percent"`  // percent of one core
+	MemoryBytes int64   `json:"memory_bytes"` // resident set size
+}
+
+// ProcessSampler samples the resource use of processes and their
+// descendants with ps. CPU use is averaged over the time since the previous
+// sample of the same process, or over its lifetime on the first, rather than
+// ps's own %cpu, which on Linux is the lifetime average every time.
+type ProcessSampler struct {
+	mu   sync.Mutex
+	last map[int]cpuSample
+}
+
+type cpuSample struct {
+	at  time.Time
+	cpu time.Duration
+}
+
+// psProcess is one process from a ps listing.
+type psProcess struct {
+	pid, ppid int
+	elapsed   time.Duration
+	cpu       time.Duration
+	rssKB     int64
+}
+
+// staleSample is how long a process's previous sample is kept without being
+// sampled again, after which the process is assumed gone.
+const staleSample = 10 * time.Minute
+
+// NewProcessSampler returns a sampler with no previous samples.
+func NewProcessSampler() *ProcessSampler {
+	return &ProcessSampler{last: make(map[int]cpuSample)}
+}
+
+// Sample returns the CPU use of pid and its descendants since pid was last
+// sampled and their current resident memory, summed. CPU used by
+// descendants that exited between samples isn't counted.
+func (s *ProcessSampler) Sample(pid int) (ProcessUsage, error) {
+	out, err := exec.Command("ps", "-A", "-o", "pid=,ppid=,etime=,time=,rss=").Output()
+	if err != nil {
+		return ProcessUsage{}, fmt.Errorf("ps: %w", err)
+	}
+	now := time.Now()
+	procs, err := parsePSList(string(out))
+	if err != nil {
+		return ProcessUsage{}, err
+	}
+	tree := processTree(procs, pid)
+	if len(tree) == 0 {
+		return ProcessUsage{}, fmt.Errorf("no process with pid %d", pid)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for p, prev := range s.last {
+		if now.Sub(prev.at) > staleSample {
+			delete(s.last, p)
+		}
+	}
+
+	// The window starts at pid's previous sample, or its start on the first
+	// look. Each process counts the CPU it used since its own previous
+	// sample, all of it if it has none, having started within the window.
+	// A previous sample from before a process started belongs to an earlier
+	// process with the same PID.
+	root := tree[0]
+	since := now.Add(-root.elapsed)
+	if prev, ok := s.last[pid]; ok && now.Sub(prev.at) < root.elapsed && root.cpu >= prev.cpu {
+		since = prev.at
+	}
+	var cpu time.Duration
+	var usage ProcessUsage
+	for _, p := range tree {
+		used := p.cpu
+		if prev, ok := s.last[p.pid]; ok && now.Sub(prev.at) < p.elapsed && p.cpu >= prev.cpu {
+			used -= prev.cpu
+		}
+		cpu += used
+		usage.MemoryBytes += p.rssKB * 1024
+		s.last[p.pid] = cpuSample{at: now, cpu: p.cpu}
+	}
+	if wall := now.Sub(since); wall > 0 {
+		usage.CPUPercent = 100 * float64(cpu) / float64(wall)
+	}
+	return usage, nil
+}
+
+// parsePSList parses the output of ps -o pid=,ppid=,etime=,time=,rss=.
+func parsePSList(out string) ([]psProcess, error) {
+	var procs []psProcess
+	for line := range strings.Lines(out) {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 5 {
+			return nil, fmt.Errorf("unexpected ps output: %q", line)
+		}
+		var p psProcess
+		var err error
+		if p.pid, err = strconv.Atoi(fields[0]); err != nil {
+			return nil, fmt.Errorf("unexpected ps pid %q", fields[0])
+		}
+		if p.ppid, err = strconv.Atoi(fields[1]); err != nil {
+			return nil, fmt.Errorf("unexpected ps ppid %q", fields[1])
+		}
+		if p.elapsed, err = parsePSTime(fields[2]); err != nil {
+			return nil, err
+		}
+		if p.cpu, err = parsePSTime(fields[3]); err != nil {
+			return nil, err
+		}
+		if p.rssKB, err = strconv.ParseInt(fields[4], 10, 64); err != nil {
+			return nil, fmt.Errorf("unexpected ps rss %q", fields[4])
+		}
+		procs = append(procs, p)
+	}
+	return procs, nil
+}
+
+// processTree returns pid and its descendants from procs, pid first, or
+// nil if pid isn't among them.
+func processTree(procs []psProcess, pid int) []psProcess {
+	children := make(map[int][]psProcess)
+	var tree []psProcess
+	for _, p := range procs {
+		if p.pid == pid {
+			tree = append(tree, p)
+		} else {
+			children[p.ppid] = append(children[p.ppid], p)
+		}
+	}
+	for i := 0; i < len(tree); i++ {
+		tree = append(tree, children[tree[i].pid]...)
+	}
+	return tree
+}
+
+// parsePSTime parses ps's elapsed and CPU time formats, [[dd-]hh:]mm:ss with
+// optional fractional seconds (macOS prints "0:01.25").
+func parsePSTime(s string) (time.Duration, error) {
+	var days int
+	rest := s
+	if d, r, ok := strings.Cut(s, "-"); ok {
+		n, err := strconv.Atoi(d)
+		if err != nil {
+			return 0, fmt.Errorf("unexpected ps time %q", s)
+		}
+		days, rest = n, r
+	}
+	parts := strings.Split(rest, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, fmt.Errorf("unexpected ps time %q", s)
+	}
+	secs, err := strconv.ParseFloat(parts[len(parts)-1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("unexpected ps time %q", s)
+	}
+	total := time.Duration(secs * float64(time.Second))
+	unit := time.Minute
+	for i := len(parts) - 2; i >= 0; i-- {
+		n, err := strconv.Atoi(parts[i])
+		if err != nil {
+			return 0, fmt.Errorf("unexpected ps time %q", s)
+		}
+		total += time.Duration(n) * unit
+		unit = time.Hour
+	}
+	return total + time.Duration(days)*24*time.Hour, nil
+}
@@ -1,7 +1,10 @@
 package sysinfo
 
 import (
+	"os"
+	"slices"
 	"testing"
+	"time"
 )
 
 func TestSnapshot(t *testing.T) {
@@ -38,3 +41,81 @@ func TestSnapshot(t *testing.T) {
 		t.Errorf("expected positive disk avail, got %d", snap.DiskAvailBytes)
 	}
 }
+
+func TestParsePSTime(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Duration
+	}{
+		{"00:07", 7 * time.Second},
+		{"0:01.25", 1250 * time.Millisecond},
+		{"12:34", 12*time.Minute + 34*time.Second},
+		{"01:02:03", time.Hour + 2*time.Minute + 3*time.Second},
+		{"2-03:04:05", 51*time.Hour + 4*time.Minute + 5*time.Second},
+	}
+	for _, tt := range tests {
+		got, err := parsePSTime(tt.in)
+		if err != nil {
+			t.Errorf("parsePSTime(%q): %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parsePSTime(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+	for _, bad := range []string{"", "7", "a:b", "x-00:01"} {
+		if _, err := parsePSTime(bad); err == nil {
+			t.Errorf("parsePSTime(%q): expected error", bad)
+		}
+	}
+}
+
+func TestProcessTree(t *testing.T) {
+	procs, err := parsePSList(`
+    1     0 10-00:00:00 00:01:00  1000
+  100     1    01:00:00 00:00:10  2000
+  101   100       30:00 00:00:05   300
+  102   101       10:00 00:00:01    40
+  103   100       05:00 00:00:00     5
+  200     1    02:00:00 00:00:20  6000
+`)
+	if err != nil {
+		t.Fatalf("parsePSList: %v", err)
+	}
+	tree := processTree(procs, 100)
+	var pids []int
+	var rss int64
+	for _, p := range tree {
+		pids = append(pids, p.pid)
+		rss += p.rssKB
+	}
+	if !slices.Equal(pids, []int{100, 101, 103, 102}) {
+		t.Errorf("tree of 100 = %v, want 100 first then its descendants", pids)
+	}
+	if rss != 2345 {
+		t.Errorf("tree rss = %d, want 2345", rss)
+	}
+	if tree := processTree(procs, 999); tree != nil {
+		t.Errorf("expected no tree for a missing pid, got %v", tree)
+	}
+	if _, err := parsePSList("100 1 00:01\n"); err == nil {
+		t.Error("expected an error for a short ps line")
+	}
+}
+
+func TestProcessSamplerSelf(t *testing.T) {
+	s := NewProcessSampler()
+	usage, err := s.Sample(os.Getpid())
+	if err != nil {
+		t.Fatalf("Sample: %v", err)
+	}
+	if usage.MemoryBytes <= 0 {
+		t.Errorf("expected positive resident memory, got %d", usage.MemoryBytes)
+	}
+	if usage.CPUPercent < 0 {
+		t.Errorf("expected non-negative CPU, got %.1f", usage.CPUPercent)
+	}
+	if _, err := s.Sample(os.Getpid()); err != nil {
+		t.Fatalf("second Sample: %v", err)
+	}
+}